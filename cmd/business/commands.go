@@ -0,0 +1,586 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/app"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/backup"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// command describes an administrative subcommand.
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error
+}
+
+// commands lists the administrative subcommands by name.
+var commands = map[string]command{
+	"migrate":              {summary: "apply, preview (-dry-run), list (-status) or revert (-down) migrations", run: runMigrate},
+	"create-admin":         {summary: "create an administrator account", run: runCreateAdmin},
+	"reset-admin-password": {summary: "reset an administrator password", run: runResetAdminPassword},
+	"disable-mfa":          {summary: "remove all MFA factors from an admin or user", run: runDisableMFA},
+	"create-user":          {summary: "create a user account", run: runCreateUser},
+	"issue-api-key":        {summary: "issue an API key", run: runIssueAPIKey},
+	"import-auth-files":    {summary: "import auth JSON files from a directory", run: runImportAuthFiles},
+	"grant-balance":        {summary: "credit balance to a user", run: runGrantBalance},
+	"list-settings":        {summary: "print all settings", run: runListSettings},
+	"set-setting":          {summary: "create or update a setting", run: runSetSetting},
+	"doctor":               {summary: "check config, database, JWT secret and Redis", run: runDoctor},
+	"backup":               {summary: "export all tables to a backup archive", run: runBackup},
+	"restore":              {summary: "replace all tables with a backup archive", run: runRestore},
+	"copy-db":              {summary: "copy all tables into another database and verify row counts", run: runCopyDB},
+}
+
+// errDoctorFailed reports that at least one doctor check failed.
+var errDoctorFailed = errors.New("doctor: one or more checks failed")
+
+// isCommand reports whether the first argument names a subcommand rather than a flag.
+func isCommand(args []string) bool {
+	return len(args) > 0 && !strings.HasPrefix(args[0], "-")
+}
+
+// runCommand dispatches a subcommand by name.
+func runCommand(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	name := args[0]
+	if name == "help" {
+		printCommands(stdout)
+		return nil
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		printCommands(stdout)
+		return fmt.Errorf("unknown command: %s", name)
+	}
+	return cmd.run(ctx, args[1:], stdin, stdout)
+}
+
+// printCommands writes the subcommand list.
+func printCommands(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	_, _ = fmt.Fprintln(w, "Commands (run without a command to start the server):")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "  %-22s %s\n", name, commands[name].summary)
+	}
+}
+
+// commandFlags wraps a flag set with the shared -config flag.
+type commandFlags struct {
+	*flag.FlagSet
+	configPath *string
+}
+
+// newCommandFlags creates a flag set for a subcommand.
+func newCommandFlags(name string, stdout io.Writer) commandFlags {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	return commandFlags{
+		FlagSet:    fs,
+		configPath: fs.String("config", "", "config file path (or env CONFIG_PATH)"),
+	}
+}
+
+// appConfig resolves the application config honoring the -config flag.
+func (f commandFlags) appConfig() (config.AppConfig, error) {
+	appCfg, err := config.LoadFromEnv()
+	if err != nil {
+		return config.AppConfig{}, err
+	}
+	if strings.TrimSpace(*f.configPath) != "" {
+		appCfg.ConfigPath = config.ResolveConfigPath(*f.configPath)
+	}
+	return appCfg, nil
+}
+
+// open resolves config and opens the migrated database.
+func (f commandFlags) open(ctx context.Context) (*gorm.DB, error) {
+	appCfg, err := f.appConfig()
+	if err != nil {
+		return nil, err
+	}
+	return app.OpenDatabase(ctx, appCfg)
+}
+
+// readPassword returns the flag value, or the first line of stdin when the flag is empty.
+func readPassword(value string, stdin io.Reader) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	line, errRead := bufio.NewReader(stdin).ReadString('\n')
+	if errRead != nil && !errors.Is(errRead, io.EOF) {
+		return "", fmt.Errorf("read password: %w", errRead)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is required (use -password or pipe it on stdin)")
+	}
+	return password, nil
+}
+
+// parseIDList parses a comma-separated list of numeric IDs.
+func parseIDList(value string) ([]uint64, error) {
+	var ids []uint64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, errParse := strconv.ParseUint(part, 10, 64)
+		if errParse != nil || id == 0 {
+			return nil, fmt.Errorf("invalid id: %s", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// runMigrate applies, previews, lists, or reverts versioned migrations.
+func runMigrate(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("migrate", stdout)
+	dryRun := fs.Bool("dry-run", false, "print the SQL pending migrations would run without applying them")
+	status := fs.Bool("status", false, "list migrations and whether they are applied")
+	down := fs.Int64("down", -1, "revert applied migrations above this version")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	appCfg, err := fs.appConfig()
+	if err != nil {
+		return err
+	}
+	dsn, errDSN := config.LoadDatabaseDSN(config.ResolveConfigPath(appCfg.ConfigPath))
+	if errDSN != nil {
+		return errDSN
+	}
+	conn, errOpen := db.Open(dsn)
+	if errOpen != nil {
+		return errOpen
+	}
+	conn = conn.WithContext(ctx)
+
+	switch {
+	case *status:
+		states, errStatus := db.MigrationStatus(conn)
+		if errStatus != nil {
+			return errStatus
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = "applied " + state.AppliedAt.Format(time.RFC3339)
+			}
+			reversible := ""
+			if !state.Reversible {
+				reversible = " (irreversible)"
+			}
+			_, _ = fmt.Fprintf(stdout, "%4d %-24s %s%s\n", state.Version, state.Name, applied, reversible)
+		}
+		return nil
+	case *dryRun:
+		steps, errPending := db.PendingSQL(conn)
+		if errPending != nil {
+			return errPending
+		}
+		if len(steps) == 0 {
+			_, _ = fmt.Fprintln(stdout, "no pending migrations")
+		}
+		for _, step := range steps {
+			_, _ = fmt.Fprintf(stdout, "-- %d %s\n", step.Version, step.Name)
+			for _, statement := range step.Statements {
+				_, _ = fmt.Fprintf(stdout, "%s;\n", statement)
+			}
+		}
+		return nil
+	case *down >= 0:
+		reverted, errDown := db.MigrateDown(conn, *down)
+		for _, migration := range reverted {
+			_, _ = fmt.Fprintf(stdout, "reverted %d %s\n", migration.Version, migration.Name)
+		}
+		return errDown
+	}
+
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		return errMigrate
+	}
+	_, _ = fmt.Fprintln(stdout, "migrations applied")
+	return nil
+}
+
+// runCreateAdmin creates an administrator account.
+func runCreateAdmin(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("create-admin", stdout)
+	username := fs.String("username", "", "admin username")
+	password := fs.String("password", "", "admin password (read from stdin when empty)")
+	superAdmin := fs.Bool("super", true, "grant super admin")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	pass, errPassword := readPassword(*password, stdin)
+	if errPassword != nil {
+		return errPassword
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	admin, errCreate := app.CreateAdmin(ctx, conn, *username, pass, *superAdmin)
+	if errCreate != nil {
+		return errCreate
+	}
+	_, _ = fmt.Fprintf(stdout, "created admin %s (id %d)\n", admin.Username, admin.ID)
+	return nil
+}
+
+// runResetAdminPassword resets an administrator password.
+func runResetAdminPassword(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("reset-admin-password", stdout)
+	username := fs.String("username", "", "admin username")
+	password := fs.String("password", "", "new password (read from stdin when empty)")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	pass, errPassword := readPassword(*password, stdin)
+	if errPassword != nil {
+		return errPassword
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	if errReset := app.ResetAdminPassword(ctx, conn, *username, pass); errReset != nil {
+		return errReset
+	}
+	_, _ = fmt.Fprintf(stdout, "password reset for admin %s\n", *username)
+	return nil
+}
+
+// runDisableMFA removes all MFA factors from an account.
+func runDisableMFA(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("disable-mfa", stdout)
+	adminName := fs.String("admin", "", "admin username")
+	userName := fs.String("user", "", "user username")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	kind, username := models.MFAOwnerAdmin, strings.TrimSpace(*adminName)
+	if strings.TrimSpace(*userName) != "" {
+		if username != "" {
+			return errors.New("use either -admin or -user")
+		}
+		kind, username = models.MFAOwnerUser, strings.TrimSpace(*userName)
+	}
+	if username == "" {
+		return errors.New("-admin or -user is required")
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	if errDisable := app.DisableMFA(ctx, conn, kind, username); errDisable != nil {
+		return errDisable
+	}
+	_, _ = fmt.Fprintf(stdout, "mfa disabled for %s %s\n", kind, username)
+	return nil
+}
+
+// runCreateUser creates a user account.
+func runCreateUser(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("create-user", stdout)
+	username := fs.String("username", "", "user username")
+	email := fs.String("email", "", "user email")
+	password := fs.String("password", "", "user password (read from stdin when empty)")
+	groups := fs.String("groups", "", "comma-separated user group IDs")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	groupIDs, errGroups := parseIDList(*groups)
+	if errGroups != nil {
+		return errGroups
+	}
+	pass, errPassword := readPassword(*password, stdin)
+	if errPassword != nil {
+		return errPassword
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	user, errCreate := app.CreateUser(ctx, conn, app.CreateUserParams{
+		Username:     *username,
+		Email:        *email,
+		Password:     pass,
+		UserGroupIDs: groupIDs,
+	})
+	if errCreate != nil {
+		return errCreate
+	}
+	_, _ = fmt.Fprintf(stdout, "created user %s (id %d)\n", user.Username, user.ID)
+	return nil
+}
+
+// runIssueAPIKey issues an API key and prints the token.
+func runIssueAPIKey(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("issue-api-key", stdout)
+	name := fs.String("name", "", "api key name")
+	username := fs.String("user", "", "owning user username")
+	admin := fs.Bool("admin", false, "issue an admin key")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	key, errIssue := app.IssueAPIKey(ctx, conn, app.CreateAPIKeyParams{
+		Name:     *name,
+		Username: *username,
+		Admin:    *admin,
+	})
+	if errIssue != nil {
+		return errIssue
+	}
+	_, _ = fmt.Fprintln(stdout, key.APIKey)
+	return nil
+}
+
+// runImportAuthFiles imports auth files from a directory.
+func runImportAuthFiles(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("import-auth-files", stdout)
+	dir := fs.String("dir", "", "directory containing auth JSON files")
+	groups := fs.String("groups", "", "comma-separated auth group IDs (default group when empty)")
+	proxyURL := fs.String("proxy-url", "", "proxy URL for files that do not declare one")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	if strings.TrimSpace(*dir) == "" {
+		return errors.New("-dir is required")
+	}
+	groupIDs, errGroups := parseIDList(*groups)
+	if errGroups != nil {
+		return errGroups
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	result, errImport := app.ImportAuthFiles(ctx, conn, *dir, groupIDs, *proxyURL)
+	if errImport != nil {
+		return errImport
+	}
+	files := make([]string, 0, len(result.Failed))
+	for file := range result.Failed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		_, _ = fmt.Fprintf(stdout, "failed %s: %s\n", file, result.Failed[file])
+	}
+	_, _ = fmt.Fprintf(stdout, "imported %d, failed %d\n", result.Imported, len(result.Failed))
+	return nil
+}
+
+// runGrantBalance credits balance to a user.
+func runGrantBalance(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("grant-balance", stdout)
+	username := fs.String("user", "", "user username")
+	amount := fs.Float64("amount", 0, "amount to credit")
+	validDays := fs.Int("valid-days", 0, "days until the balance expires (0 for never)")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	card, errGrant := app.GrantBalance(ctx, conn, *username, *amount, *validDays)
+	if errGrant != nil {
+		return errGrant
+	}
+	_, _ = fmt.Fprintf(stdout, "granted %g to %s (card %s)\n", card.Amount, *username, card.CardSN)
+	return nil
+}
+
+// runListSettings prints all settings as key=value lines.
+func runListSettings(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("list-settings", stdout)
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	rows, errList := app.ListSettings(ctx, conn)
+	if errList != nil {
+		return errList
+	}
+	for _, row := range rows {
+		_, _ = fmt.Fprintf(stdout, "%s=%s\n", row.Key, compactJSON(row.Value))
+	}
+	return nil
+}
+
+// runSetSetting creates or updates a setting.
+func runSetSetting(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("set-setting", stdout)
+	key := fs.String("key", "", "setting key")
+	value := fs.String("value", "", "setting value as JSON (plain text is stored as a string)")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	if errSet := app.SetSetting(ctx, conn, *key, *value); errSet != nil {
+		return errSet
+	}
+	_, _ = fmt.Fprintf(stdout, "setting %s updated\n", *key)
+	return nil
+}
+
+// runDoctor prints health checks and fails when any check fails.
+func runDoctor(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("doctor", stdout)
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	appCfg, err := fs.appConfig()
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, check := range app.RunDoctor(ctx, appCfg) {
+		_, _ = fmt.Fprintf(stdout, "[%s] %-8s %s\n", check.Status, check.Name, check.Detail)
+		if check.Status == app.DoctorFail {
+			failed = true
+		}
+	}
+	if failed {
+		return errDoctorFailed
+	}
+	return nil
+}
+
+// runBackup writes a backup archive to a file.
+func runBackup(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("backup", stdout)
+	out := fs.String("out", "", "archive path")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	if strings.TrimSpace(*out) == "" {
+		return errors.New("-out is required")
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	file, errCreate := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if errCreate != nil {
+		return fmt.Errorf("create archive: %w", errCreate)
+	}
+	counts, errExport := backup.Export(ctx, conn, file)
+	if errClose := file.Close(); errExport == nil && errClose != nil {
+		errExport = fmt.Errorf("close archive: %w", errClose)
+	}
+	if errExport != nil {
+		return errExport
+	}
+	printTableCounts(stdout, counts, false)
+	return nil
+}
+
+// runRestore replaces the database contents with a backup archive.
+func runRestore(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("restore", stdout)
+	in := fs.String("in", "", "archive path (stdin when empty)")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	conn, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	reader := stdin
+	if strings.TrimSpace(*in) != "" {
+		file, errOpenFile := os.Open(*in)
+		if errOpenFile != nil {
+			return fmt.Errorf("open archive: %w", errOpenFile)
+		}
+		defer func() { _ = file.Close() }()
+		reader = file
+	}
+	header, counts, errImport := backup.Import(ctx, conn, reader)
+	if errImport != nil {
+		return errImport
+	}
+	_, _ = fmt.Fprintf(stdout, "restored %s backup from %s\n", header.Dialect, header.CreatedAt.Format(time.RFC3339))
+	printTableCounts(stdout, counts, false)
+	return nil
+}
+
+// runCopyDB copies the configured database into a target DSN.
+func runCopyDB(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newCommandFlags("copy-db", stdout)
+	targetDSN := fs.String("target-dsn", "", "target database DSN (postgres:// URL or SQLite path)")
+	batchSize := fs.Int("batch", backup.DefaultBatchSize, "rows per insert statement")
+	if errParse := fs.Parse(args); errParse != nil {
+		return errParse
+	}
+	if strings.TrimSpace(*targetDSN) == "" {
+		return errors.New("-target-dsn is required")
+	}
+	src, errOpen := fs.open(ctx)
+	if errOpen != nil {
+		return errOpen
+	}
+	dst, errTarget := db.Open(*targetDSN)
+	if errTarget != nil {
+		return errTarget
+	}
+	if errMigrate := db.Migrate(dst); errMigrate != nil {
+		return errMigrate
+	}
+	counts, errCopy := backup.Copy(ctx, src, dst, *batchSize)
+	printTableCounts(stdout, counts, true)
+	return errCopy
+}
+
+// printTableCounts writes per-table row counts.
+func printTableCounts(w io.Writer, counts []backup.TableCount, withSource bool) {
+	for _, count := range counts {
+		if withSource {
+			_, _ = fmt.Fprintf(w, "%-28s source=%d target=%d\n", count.Table, count.Source, count.Target)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%-28s rows=%d\n", count.Table, count.Target)
+	}
+}
+
+// compactJSON renders a JSON value on a single line.
+func compactJSON(raw []byte) string {
+	var out bytes.Buffer
+	if errCompact := json.Compact(&out, raw); errCompact != nil {
+		return string(raw)
+	}
+	return out.String()
+}
@@ -23,8 +23,12 @@ func main() {
 	}
 }
 
-// run parses flags, loads config, and starts the init or main server.
+// run dispatches administrative subcommands, or parses flags, loads config, and starts the init or main server.
 func run(ctx context.Context, args []string) error {
+	if isCommand(args) {
+		return runCommand(ctx, args, os.Stdin, os.Stdout)
+	}
+
 	fs := flag.NewFlagSet("app", flag.ContinueOnError)
 	cfgPath := fs.String("config", "", "config file path (or env CONFIG_PATH)")
 	port := fs.Int("port", 8318, "server port (used for init server and initial config)")
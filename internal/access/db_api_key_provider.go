@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -22,6 +23,22 @@ const ProviderTypeDBAPIKey = "db-api-key"
 // ErrInsufficientBalance indicates the user has no valid quota or prepaid balance.
 var ErrInsufficientBalance = errors.New("insufficient balance")
 
+// ErrSpendLimitExceeded indicates an organization member reached their spend cap.
+var ErrSpendLimitExceeded = errors.New("spend limit exceeded")
+
+// billingQueryPathPrefixes lists key-authenticated billing routes that stay reachable with no balance left.
+var billingQueryPathPrefixes = []string{"/v1/dashboard/billing", "/v1/billing"}
+
+// IsBillingQueryPath reports whether a path queries billing data rather than consuming quota.
+func IsBillingQueryPath(path string) bool {
+	for _, prefix := range billingQueryPathPrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // DBAPIKeyProvider authenticates requests using API keys stored in the database.
 type DBAPIKeyProvider struct {
 	db *gorm.DB
@@ -112,6 +129,7 @@ func (p *DBAPIKeyProvider) Authenticate(ctx context.Context, r *http.Request) (*
 		}
 	}
 
+	organizationID := ""
 	token := extractToken(r, p.header, p.scheme, p.allowXAPIKey)
 	if token == "" {
 		return nil, sdkaccess.ErrNoCredentials
@@ -135,12 +153,28 @@ func (p *DBAPIKeyProvider) Authenticate(ctx context.Context, r *http.Request) (*
 			return nil, sdkaccess.ErrInvalidCredential
 		}
 		if apiKey.UserID != nil {
-			ok, errBalance := hasValidBillOrPrepaidBalance(ctx, p.db, *apiKey.UserID)
-			if errBalance != nil {
-				return nil, fmt.Errorf("db api key provider: balance check failed: %w", errBalance)
+			payer, errPayer := billing.ResolvePayer(ctx, p.db, *apiKey.UserID)
+			if errPayer != nil {
+				return nil, fmt.Errorf("db api key provider: resolve payer failed: %w", errPayer)
 			}
-			if !ok {
-				return nil, ErrInsufficientBalance
+			if !IsBillingQueryPath(path) {
+				withinLimit, errLimit := billing.MemberSpendWithinLimits(ctx, p.db, payer.Member, time.Now().UTC())
+				if errLimit != nil {
+					return nil, fmt.Errorf("db api key provider: spend limit check failed: %w", errLimit)
+				}
+				if !withinLimit {
+					return nil, ErrSpendLimitExceeded
+				}
+				ok, errBalance := hasValidBillOrPrepaidBalance(ctx, p.db, payer)
+				if errBalance != nil {
+					return nil, fmt.Errorf("db api key provider: balance check failed: %w", errBalance)
+				}
+				if !ok {
+					return nil, ErrInsufficientBalance
+				}
+			}
+			if payer.IsOrganization() {
+				organizationID = strconv.FormatUint(*payer.OrganizationID, 10)
 			}
 		}
 	}
@@ -158,6 +192,9 @@ func (p *DBAPIKeyProvider) Authenticate(ctx context.Context, r *http.Request) (*
 	if apiKey.UserID != nil {
 		meta["user_id"] = strconv.FormatUint(*apiKey.UserID, 10)
 	}
+	if organizationID != "" {
+		meta["organization_id"] = organizationID
+	}
 
 	return &sdkaccess.Result{
 		Provider:  p.name,
@@ -199,20 +236,20 @@ func extractToken(r *http.Request, header string, scheme string, allowXAPIKey bo
 	return ""
 }
 
-// hasValidBillOrPrepaidBalance checks if a user has active bill quota or prepaid balance.
-func hasValidBillOrPrepaidBalance(ctx context.Context, db *gorm.DB, userID uint64) (bool, error) {
-	okBill, errBill := hasValidBillQuota(ctx, db, userID)
+// hasValidBillOrPrepaidBalance checks if the payer has active bill quota or prepaid balance.
+func hasValidBillOrPrepaidBalance(ctx context.Context, db *gorm.DB, payer billing.Payer) (bool, error) {
+	okBill, errBill := hasValidBillQuota(ctx, db, payer)
 	if errBill != nil {
 		return false, errBill
 	}
 	if okBill {
 		return true, nil
 	}
-	return hasValidPrepaidBalance(ctx, db, userID)
+	return hasValidPrepaidBalance(ctx, db, payer)
 }
 
-// hasValidBillQuota checks if the user has paid bill quota remaining.
-func hasValidBillQuota(ctx context.Context, db *gorm.DB, userID uint64) (bool, error) {
+// hasValidBillQuota checks if the payer has paid bill quota remaining.
+func hasValidBillQuota(ctx context.Context, db *gorm.DB, payer billing.Payer) (bool, error) {
 	if db == nil {
 		return false, errors.New("nil db")
 	}
@@ -222,14 +259,13 @@ func hasValidBillQuota(ctx context.Context, db *gorm.DB, userID uint64) (bool, e
 		DailyQuota     float64 `gorm:"column:daily_quota"`     // Sum of daily quotas for limited plans.
 		UnlimitedDaily int64   `gorm:"column:unlimited_daily"` // Count of unlimited daily plans.
 	}
-	if errSummary := db.WithContext(ctx).
-		Model(&models.Bill{}).
+	if errSummary := payer.ScopeBills(db.WithContext(ctx).Model(&models.Bill{})).
 		Select(`
 			COALESCE(SUM(left_quota), 0) AS left_quota,
 			COALESCE(SUM(CASE WHEN daily_quota > 0 THEN daily_quota ELSE 0 END), 0) AS daily_quota,
 			COALESCE(SUM(CASE WHEN daily_quota <= 0 THEN 1 ELSE 0 END), 0) AS unlimited_daily
 		`).
-		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
+		Where("is_enabled = ? AND status = ? AND left_quota > 0", true, models.BillStatusPaid).
 		Where("period_start <= ? AND period_end >= ?", now, now).
 		Scan(&summary).Error; errSummary != nil {
 		return false, errSummary
@@ -240,15 +276,15 @@ func hasValidBillQuota(ctx context.Context, db *gorm.DB, userID uint64) (bool, e
 	if summary.UnlimitedDaily > 0 || summary.DailyQuota <= 0 {
 		return true, nil
 	}
-	usedToday, errUsage := loadTodayUsageAmount(ctx, db, userID, now)
+	usedToday, errUsage := loadTodayUsageAmount(ctx, db, payer, now)
 	if errUsage != nil {
 		return false, errUsage
 	}
 	return usedToday < summary.DailyQuota, nil
 }
 
-// loadTodayUsageAmount calculates today's usage cost for the user in local time.
-func loadTodayUsageAmount(ctx context.Context, db *gorm.DB, userID uint64, now time.Time) (float64, error) {
+// loadTodayUsageAmount calculates today's usage cost charged to the payer in local time.
+func loadTodayUsageAmount(ctx context.Context, db *gorm.DB, payer billing.Payer, now time.Time) (float64, error) {
 	if db == nil {
 		return 0, errors.New("nil db")
 	}
@@ -256,9 +292,8 @@ func loadTodayUsageAmount(ctx context.Context, db *gorm.DB, userID uint64, now t
 	localNow := now.In(loc)
 	todayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, loc)
 	var costMicros int64
-	if errSum := db.WithContext(ctx).
-		Model(&models.Usage{}).
-		Where("user_id = ? AND requested_at >= ?", userID, todayStart).
+	if errSum := payer.ScopeUsage(db.WithContext(ctx).Model(&models.Usage{})).
+		Where("requested_at >= ?", todayStart).
 		Select("COALESCE(SUM(cost_micros), 0)").
 		Scan(&costMicros).Error; errSum != nil {
 		return 0, errSum
@@ -266,16 +301,15 @@ func loadTodayUsageAmount(ctx context.Context, db *gorm.DB, userID uint64, now t
 	return float64(costMicros) / 1_000_000, nil
 }
 
-// hasValidPrepaidBalance checks if the user has redeemable prepaid card balance.
-func hasValidPrepaidBalance(ctx context.Context, db *gorm.DB, userID uint64) (bool, error) {
+// hasValidPrepaidBalance checks if the payer has redeemable prepaid card balance.
+func hasValidPrepaidBalance(ctx context.Context, db *gorm.DB, payer billing.Payer) (bool, error) {
 	if db == nil {
 		return false, errors.New("nil db")
 	}
 	now := time.Now().UTC()
 	var count int64
-	if err := db.WithContext(ctx).
-		Model(&models.PrepaidCard{}).
-		Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
+	if err := payer.ScopePrepaidCards(db.WithContext(ctx).Model(&models.PrepaidCard{})).
+		Where("is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", true).
 		Where("(expires_at IS NULL OR expires_at >= ?)", now).
 		Count(&count).Error; err != nil {
 		return false, err
@@ -0,0 +1,438 @@
+// Package anomaly detects unusual spend and usage of API keys and users by comparing their
+// last hour against their own baseline, and suspends keys and notifies people accordingly.
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Anomaly scopes.
+const (
+	// ScopeAPIKey flags a single API key.
+	ScopeAPIKey = "api_key"
+	// ScopeUser flags the combined usage of a user's keys.
+	ScopeUser = "user"
+)
+
+// Anomaly kinds.
+const (
+	// KindSpendSpike flags an hour whose spend far exceeds the baseline hourly average.
+	KindSpendSpike = "spend_spike"
+	// KindRequestSpike flags an hour whose request count far exceeds the baseline hourly average.
+	KindRequestSpike = "request_spike"
+	// KindNewIPRange flags client IP ranges absent from the baseline.
+	KindNewIPRange = "new_ip_range"
+	// KindNewModel flags models absent from the baseline.
+	KindNewModel = "new_model"
+)
+
+// detectorInterval is how often usage is checked.
+const detectorInterval = 5 * time.Minute
+
+// detectionWindow is the recent period compared against the baseline.
+const detectionWindow = time.Hour
+
+// alertCooldown suppresses repeated alerts for an open anomaly of the same subject and kind.
+const alertCooldown = 24 * time.Hour
+
+// alertWebhookTimeout bounds one webhook delivery.
+const alertWebhookTimeout = 10 * time.Second
+
+// Detector periodically checks usage for anomalies.
+type Detector struct {
+	db       *gorm.DB
+	client   *http.Client
+	sendMail func(ctx context.Context, to, subject, body string) error
+}
+
+// NewDetector constructs a Detector delivering alerts through HTTP webhooks and SMTP.
+func NewDetector(db *gorm.DB) *Detector {
+	if db == nil {
+		return nil
+	}
+	return &Detector{
+		db:       db,
+		client:   &http.Client{Timeout: alertWebhookTimeout},
+		sendMail: mail.Send,
+	}
+}
+
+// Start launches the detection loop in a background goroutine.
+func (d *Detector) Start(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go d.run(ctx)
+	log.Infof("spend anomaly detector started (interval=%s)", detectorInterval)
+}
+
+// run checks usage until ctx is done.
+func (d *Detector) run(ctx context.Context) {
+	ticker := time.NewTicker(detectorInterval)
+	defer ticker.Stop()
+	for {
+		if _, errCheck := d.Check(ctx, time.Now()); errCheck != nil {
+			log.WithError(errCheck).Warn("anomaly: check failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check detects anomalies in the hour before now, records the new ones, suspends the keys
+// they implicate when configured, and delivers alerts. It returns the recorded anomalies.
+func (d *Detector) Check(ctx context.Context, now time.Time) ([]models.SpendAnomaly, error) {
+	if d == nil {
+		return nil, nil
+	}
+	policy := internalsettings.LoadAnomalyPolicy()
+	if !policy.Enabled {
+		return nil, nil
+	}
+	p := period{
+		baselineStart: now.Add(-detectionWindow - policy.Baseline),
+		windowStart:   now.Add(-detectionWindow),
+		end:           now,
+	}
+
+	var findings []finding
+	for _, scope := range []string{ScopeAPIKey, ScopeUser} {
+		spikes, errSpikes := d.detectSpikes(ctx, policy, scope, p)
+		if errSpikes != nil {
+			return nil, errSpikes
+		}
+		findings = append(findings, spikes...)
+	}
+	if policy.DetectNewIPRanges {
+		ranges, errRanges := d.detectNewValues(ctx, KindNewIPRange, "client_ip", ipRange, p)
+		if errRanges != nil {
+			return nil, errRanges
+		}
+		findings = append(findings, ranges...)
+	}
+	if policy.DetectNewModels {
+		newModels, errModels := d.detectNewValues(ctx, KindNewModel, "model", nil, p)
+		if errModels != nil {
+			return nil, errModels
+		}
+		findings = append(findings, newModels...)
+	}
+	if len(findings) == 0 {
+		return nil, nil
+	}
+
+	subjects, errSubjects := d.loadSubjects(ctx, findings)
+	if errSubjects != nil {
+		return nil, errSubjects
+	}
+	var recorded []models.SpendAnomaly
+	var errs []error
+	for _, f := range findings {
+		row, isNew, errRecord := d.record(ctx, policy, subjects, f, now)
+		if errRecord != nil {
+			errs = append(errs, errRecord)
+			continue
+		}
+		if isNew {
+			recorded = append(recorded, row)
+		}
+	}
+	if len(recorded) > 0 {
+		if errNotify := d.notify(ctx, policy, subjects, recorded, now); errNotify != nil {
+			errs = append(errs, errNotify)
+		}
+	}
+	return recorded, errors.Join(errs...)
+}
+
+// period delimits the baseline and the detection window that follows it.
+type period struct {
+	baselineStart time.Time
+	windowStart   time.Time
+	end           time.Time
+}
+
+// finding is an anomaly detected but not yet recorded.
+type finding struct {
+	scope     string
+	subjectID uint64
+	kind      string
+	observed  float64
+	baseline  float64
+	details   []string
+}
+
+// totals aggregates the usage of one subject over a period.
+type totals struct {
+	SubjectID  uint64 // API key or user ID.
+	Requests   int64  // Request count.
+	CostMicros int64  // Spend in micros.
+}
+
+// subjectColumn returns the usage column identifying subjects of scope.
+func subjectColumn(scope string) string {
+	if scope == ScopeUser {
+		return "user_id"
+	}
+	return "api_key_id"
+}
+
+// loadTotals aggregates usage per subject in [from, to), limited to ids when non-nil.
+func (d *Detector) loadTotals(ctx context.Context, column string, from, to time.Time, ids []uint64) (map[uint64]totals, error) {
+	query := d.db.WithContext(ctx).Model(&models.Usage{}).
+		Select(column+" AS subject_id, COUNT(*) AS requests, COALESCE(SUM(cost_micros), 0) AS cost_micros").
+		Where(column+" IS NOT NULL AND requested_at >= ? AND requested_at < ?", from, to)
+	if ids != nil {
+		query = query.Where(column+" IN ?", ids)
+	}
+	var rows []totals
+	if errScan := query.Group(column).Scan(&rows).Error; errScan != nil {
+		return nil, fmt.Errorf("anomaly: load usage totals: %w", errScan)
+	}
+	out := make(map[uint64]totals, len(rows))
+	for _, row := range rows {
+		out[row.SubjectID] = row
+	}
+	return out, nil
+}
+
+// detectSpikes flags subjects of scope whose spend or request count in the detection window
+// exceeds the configured multiple of their baseline hourly average. Subjects without baseline
+// usage are skipped, as there is nothing to compare against.
+func (d *Detector) detectSpikes(ctx context.Context, policy internalsettings.AnomalyPolicy, scope string, p period) ([]finding, error) {
+	if policy.SpendMultiplier <= 0 && policy.RequestMultiplier <= 0 {
+		return nil, nil
+	}
+	column := subjectColumn(scope)
+	current, errCurrent := d.loadTotals(ctx, column, p.windowStart, p.end, nil)
+	if errCurrent != nil {
+		return nil, errCurrent
+	}
+	candidates := make([]uint64, 0)
+	for id, cur := range current {
+		if (policy.SpendMultiplier > 0 && cur.CostMicros >= policy.MinHourlySpendMicros && cur.CostMicros > 0) ||
+			(policy.RequestMultiplier > 0 && cur.Requests >= policy.MinHourlyRequests) {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+	baseline, errBaseline := d.loadTotals(ctx, column, p.baselineStart, p.windowStart, candidates)
+	if errBaseline != nil {
+		return nil, errBaseline
+	}
+	createdAt, errCreated := d.loadCreatedAt(ctx, scope, candidates)
+	if errCreated != nil {
+		return nil, errCreated
+	}
+
+	var out []finding
+	for _, id := range candidates {
+		base, ok := baseline[id]
+		if !ok || base.Requests == 0 {
+			continue
+		}
+		// Subjects created during the baseline are averaged over their lifetime only.
+		from := p.baselineStart
+		if created, okCreated := createdAt[id]; okCreated && created.After(from) {
+			from = created
+		}
+		hours := p.windowStart.Sub(from).Hours()
+		if hours < 1 {
+			hours = 1
+		}
+		cur := current[id]
+		avgSpend := float64(base.CostMicros) / hours
+		if policy.SpendMultiplier > 0 && cur.CostMicros > 0 && cur.CostMicros >= policy.MinHourlySpendMicros &&
+			float64(cur.CostMicros) > avgSpend*policy.SpendMultiplier {
+			out = append(out, finding{scope: scope, subjectID: id, kind: KindSpendSpike,
+				observed: float64(cur.CostMicros) / 1_000_000, baseline: avgSpend / 1_000_000})
+		}
+		avgRequests := float64(base.Requests) / hours
+		if policy.RequestMultiplier > 0 && cur.Requests >= policy.MinHourlyRequests &&
+			float64(cur.Requests) > avgRequests*policy.RequestMultiplier {
+			out = append(out, finding{scope: scope, subjectID: id, kind: KindRequestSpike,
+				observed: float64(cur.Requests), baseline: avgRequests})
+		}
+	}
+	return out, nil
+}
+
+// loadCreatedAt returns the creation time of the API keys or users of scope.
+func (d *Detector) loadCreatedAt(ctx context.Context, scope string, ids []uint64) (map[uint64]time.Time, error) {
+	// row holds the creation time of one subject.
+	type row struct {
+		ID        uint64
+		CreatedAt time.Time
+	}
+	var model any = &models.APIKey{}
+	if scope == ScopeUser {
+		model = &models.User{}
+	}
+	var rows []row
+	if errFind := d.db.WithContext(ctx).Model(model).Select("id, created_at").Where("id IN ?", ids).Scan(&rows).Error; errFind != nil {
+		return nil, fmt.Errorf("anomaly: load creation times: %w", errFind)
+	}
+	out := make(map[uint64]time.Time, len(rows))
+	for _, r := range rows {
+		out[r.ID] = r.CreatedAt
+	}
+	return out, nil
+}
+
+// detectNewValues flags API keys whose usage in the detection window carries values of column,
+// normalized by normalize when set, that never appear in their baseline. Keys with no baseline
+// values are skipped.
+func (d *Detector) detectNewValues(ctx context.Context, kind, column string, normalize func(string) string, p period) ([]finding, error) {
+	current, errCurrent := d.loadDistinct(ctx, column, normalize, p.windowStart, p.end, nil)
+	if errCurrent != nil {
+		return nil, errCurrent
+	}
+	if len(current) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint64, 0, len(current))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	baseline, errBaseline := d.loadDistinct(ctx, column, normalize, p.baselineStart, p.windowStart, ids)
+	if errBaseline != nil {
+		return nil, errBaseline
+	}
+
+	var out []finding
+	for _, id := range ids {
+		known := baseline[id]
+		if len(known) == 0 {
+			continue
+		}
+		var fresh []string
+		for value := range current[id] {
+			if _, ok := known[value]; !ok {
+				fresh = append(fresh, value)
+			}
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+		sort.Strings(fresh)
+		out = append(out, finding{scope: ScopeAPIKey, subjectID: id, kind: kind,
+			observed: float64(len(fresh)), baseline: float64(len(known)), details: fresh})
+	}
+	return out, nil
+}
+
+// loadDistinct returns the distinct non-empty values of column per API key in [from, to),
+// limited to ids when non-nil.
+func (d *Detector) loadDistinct(ctx context.Context, column string, normalize func(string) string, from, to time.Time, ids []uint64) (map[uint64]map[string]struct{}, error) {
+	// row holds one distinct value of one key.
+	type row struct {
+		APIKeyID uint64
+		Value    string
+	}
+	query := d.db.WithContext(ctx).Model(&models.Usage{}).
+		Distinct("api_key_id", column+" AS value").
+		Where("api_key_id IS NOT NULL AND requested_at >= ? AND requested_at < ?", from, to).
+		Where(column + " IS NOT NULL AND " + column + " <> ''")
+	if ids != nil {
+		query = query.Where("api_key_id IN ?", ids)
+	}
+	var rows []row
+	if errScan := query.Scan(&rows).Error; errScan != nil {
+		return nil, fmt.Errorf("anomaly: load distinct %s: %w", column, errScan)
+	}
+	out := make(map[uint64]map[string]struct{})
+	for _, r := range rows {
+		value := r.Value
+		if normalize != nil {
+			value = normalize(value)
+		}
+		if value == "" {
+			continue
+		}
+		if out[r.APIKeyID] == nil {
+			out[r.APIKeyID] = make(map[string]struct{})
+		}
+		out[r.APIKeyID][value] = struct{}{}
+	}
+	return out, nil
+}
+
+// record stores a finding unless an open anomaly of the same subject and kind was detected
+// within the cooldown, suspending the implicated API key first when configured. It reports
+// whether a new anomaly was stored.
+func (d *Detector) record(ctx context.Context, policy internalsettings.AnomalyPolicy, subjects subjects, f finding, now time.Time) (models.SpendAnomaly, bool, error) {
+	var open int64
+	if errCount := d.db.WithContext(ctx).Model(&models.SpendAnomaly{}).
+		Where("scope = ? AND subject_id = ? AND kind = ? AND resolved_at IS NULL AND detected_at >= ?",
+			f.scope, f.subjectID, f.kind, now.Add(-alertCooldown)).
+		Count(&open).Error; errCount != nil {
+		return models.SpendAnomaly{}, false, fmt.Errorf("anomaly: check open anomalies: %w", errCount)
+	}
+	if open > 0 {
+		return models.SpendAnomaly{}, false, nil
+	}
+
+	details, errMarshal := json.Marshal(f.details)
+	if errMarshal != nil || f.details == nil {
+		details = []byte("[]")
+	}
+	row := models.SpendAnomaly{
+		Scope:      f.scope,
+		SubjectID:  f.subjectID,
+		Kind:       f.kind,
+		Observed:   f.observed,
+		Baseline:   f.baseline,
+		Details:    details,
+		DetectedAt: now.UTC(),
+	}
+	if f.scope == ScopeAPIKey {
+		id := f.subjectID
+		row.APIKeyID = &id
+		if key, ok := subjects.keys[id]; ok {
+			row.UserID = key.UserID
+		}
+	} else {
+		id := f.subjectID
+		row.UserID = &id
+	}
+
+	if policy.AutoSuspend && f.scope == ScopeAPIKey && (f.kind == KindSpendSpike || f.kind == KindRequestSpike) {
+		res := d.db.WithContext(ctx).Model(&models.APIKey{}).
+			Where("id = ? AND active = ? AND revoked_at IS NULL", f.subjectID, true).
+			Updates(map[string]any{"active": false, "updated_at": now.UTC()})
+		if res.Error != nil {
+			return models.SpendAnomaly{}, false, fmt.Errorf("anomaly: suspend api key %d: %w", f.subjectID, res.Error)
+		}
+		row.Suspended = res.RowsAffected == 1
+		if row.Suspended {
+			log.WithFields(log.Fields{"api_key_id": f.subjectID, "kind": f.kind}).Warn("anomaly: api key suspended")
+		}
+	}
+	row.Message = subjects.describe(&row)
+	if errCreate := d.db.WithContext(ctx).Create(&row).Error; errCreate != nil {
+		return models.SpendAnomaly{}, false, fmt.Errorf("anomaly: record anomaly: %w", errCreate)
+	}
+	return row, true, nil
+}
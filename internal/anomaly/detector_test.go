@@ -0,0 +1,154 @@
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), name))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+// mailbox records messages instead of sending them.
+type mailbox struct {
+	mu sync.Mutex
+	to []string
+}
+
+func (m *mailbox) send(_ context.Context, to, _, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.to = append(m.to, to)
+	return nil
+}
+
+func TestIPRange(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.9":        "203.0.113.0/24",
+		"2001:db8:1:2::1":    "2001:db8:1::/48",
+		"::ffff:203.0.113.9": "203.0.113.0/24",
+		"not-an-ip":          "",
+	}
+	for ip, want := range cases {
+		if got := ipRange(ip); got != want {
+			t.Fatalf("ipRange(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestCheckFlagsSuspendsAndNotifiesOnce(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t, "anomaly.db")
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x", CreatedAt: now.AddDate(0, -1, 0)}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	leaked := models.APIKey{UserID: &user.ID, Name: "prod", APIKey: "sk-leaked", Active: true, CreatedAt: now.AddDate(0, -1, 0)}
+	fresh := models.APIKey{Name: "fresh", APIKey: "sk-fresh", Active: true, CreatedAt: now.Add(-30 * time.Minute)}
+	if errCreate := conn.Create(&[]*models.APIKey{&leaked, &fresh}).Error; errCreate != nil {
+		t.Fatalf("create keys: %v", errCreate)
+	}
+
+	var rows []models.Usage
+	for day := 1; day <= 7; day++ {
+		rows = append(rows, models.Usage{Provider: "claude", Model: "claude-x", UserID: &user.ID, APIKeyID: &leaked.ID,
+			RequestedAt: now.AddDate(0, 0, -day), CostMicros: 100_000, ClientIP: "203.0.113.5"})
+	}
+	for i := 0; i < 20; i++ {
+		rows = append(rows, models.Usage{Provider: "claude", Model: "claude-x", UserID: &user.ID, APIKeyID: &leaked.ID,
+			RequestedAt: now.Add(-time.Duration(i+1) * time.Minute), CostMicros: 100_000, ClientIP: "198.51.100.7"})
+		rows = append(rows, models.Usage{Provider: "claude", Model: "claude-x", APIKeyID: &fresh.ID,
+			RequestedAt: now.Add(-time.Duration(i+1) * time.Minute), CostMicros: 500_000, ClientIP: "192.0.2.1"})
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	var payloads []alertPayload
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload alertPayload
+		if errUnmarshal := json.Unmarshal(body, &payload); errUnmarshal != nil {
+			t.Errorf("decode webhook: %v", errUnmarshal)
+		}
+		payloads = append(payloads, payload)
+	}))
+	defer webhook.Close()
+
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.AnomalyDetectionEnabledKey: json.RawMessage("true"),
+		internalsettings.AnomalyAutoSuspendKey:      json.RawMessage("true"),
+		internalsettings.AnomalyAlertWebhookURLKey:  json.RawMessage(`"` + webhook.URL + `"`),
+		internalsettings.AnomalyAlertEmailsKey:      json.RawMessage(`["ops@example.com"]`),
+	})
+	defer internalsettings.StoreDBConfig(time.Now(), nil)
+
+	box := &mailbox{}
+	detector := NewDetector(conn)
+	detector.sendMail = box.send
+
+	recorded, errCheck := detector.Check(ctx, now)
+	if errCheck != nil {
+		t.Fatalf("check: %v", errCheck)
+	}
+	kinds := make(map[string]models.SpendAnomaly)
+	for _, row := range recorded {
+		kinds[row.Scope+"/"+row.Kind] = row
+	}
+	if len(recorded) != 3 {
+		t.Fatalf("expected 3 anomalies, got %+v", recorded)
+	}
+	spike, ok := kinds[ScopeAPIKey+"/"+KindSpendSpike]
+	if !ok || !spike.Suspended || *spike.APIKeyID != leaked.ID || spike.Observed != 2 {
+		t.Fatalf("unexpected key spend spike: %+v", spike)
+	}
+	if _, okUser := kinds[ScopeUser+"/"+KindSpendSpike]; !okUser {
+		t.Fatalf("expected user spend spike, got %+v", recorded)
+	}
+	if ranges := kinds[ScopeAPIKey+"/"+KindNewIPRange]; string(ranges.Details) != `["198.51.100.0/24"]` {
+		t.Fatalf("unexpected new ip ranges: %s", ranges.Details)
+	}
+
+	var stored models.APIKey
+	if errLoad := conn.First(&stored, leaked.ID).Error; errLoad != nil || stored.Active {
+		t.Fatalf("expected leaked key to be suspended: %+v %v", stored, errLoad)
+	}
+	var untouched models.APIKey
+	if errLoad := conn.First(&untouched, fresh.ID).Error; errLoad != nil || !untouched.Active {
+		t.Fatalf("expected key without baseline to stay active: %+v %v", untouched, errLoad)
+	}
+	if len(payloads) != 1 || len(payloads[0].Anomalies) != 3 || payloads[0].Event != alertEvent {
+		t.Fatalf("unexpected webhook payloads: %+v", payloads)
+	}
+	if strings.Join(box.to, ",") != "ops@example.com,alice@example.com" {
+		t.Fatalf("unexpected mail recipients: %v", box.to)
+	}
+
+	again, errAgain := detector.Check(ctx, now.Add(5*time.Minute))
+	if errAgain != nil || len(again) != 0 || len(payloads) != 1 {
+		t.Fatalf("expected open anomalies not to repeat, got %+v %v", again, errAgain)
+	}
+}
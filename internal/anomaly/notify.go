@@ -0,0 +1,238 @@
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/webhook"
+)
+
+// alertEvent names the webhook event carrying anomalies.
+const alertEvent = "spend.anomaly"
+
+// alertPayload is the JSON body posted to the alert webhook.
+type alertPayload struct {
+	Event       string  `json:"event"`        // Always alertEvent.
+	GeneratedAt string  `json:"generated_at"` // Detection time.
+	Anomalies   []alert `json:"anomalies"`    // Newly detected anomalies.
+}
+
+// alert describes one anomaly in webhook payloads.
+type alert struct {
+	ID         uint64          `json:"id"`                     // Anomaly ID.
+	Scope      string          `json:"scope"`                  // api_key or user.
+	Kind       string          `json:"kind"`                   // Anomaly kind.
+	APIKeyID   *uint64         `json:"api_key_id,omitempty"`   // Flagged API key.
+	APIKeyName string          `json:"api_key_name,omitempty"` // Flagged API key name.
+	UserID     *uint64         `json:"user_id,omitempty"`      // Owning or flagged user.
+	Username   string          `json:"username,omitempty"`     // Owning or flagged username.
+	Observed   float64         `json:"observed"`               // Value in the detection window.
+	Baseline   float64         `json:"baseline"`               // Baseline value.
+	Details    json.RawMessage `json:"details"`                // New IP ranges or models.
+	Message    string          `json:"message"`                // Human readable summary.
+	Suspended  bool            `json:"suspended"`              // Whether the API key was suspended.
+}
+
+// subjects holds the API keys and users referenced by findings.
+type subjects struct {
+	keys  map[uint64]models.APIKey
+	users map[uint64]models.User
+}
+
+// loadSubjects loads the API keys and users referenced by findings, including key owners.
+func (d *Detector) loadSubjects(ctx context.Context, findings []finding) (subjects, error) {
+	out := subjects{keys: make(map[uint64]models.APIKey), users: make(map[uint64]models.User)}
+	var keyIDs, userIDs []uint64
+	for _, f := range findings {
+		if f.scope == ScopeAPIKey {
+			keyIDs = append(keyIDs, f.subjectID)
+		} else {
+			userIDs = append(userIDs, f.subjectID)
+		}
+	}
+	if len(keyIDs) > 0 {
+		var keys []models.APIKey
+		if errFind := d.db.WithContext(ctx).Where("id IN ?", keyIDs).Find(&keys).Error; errFind != nil {
+			return out, fmt.Errorf("anomaly: load api keys: %w", errFind)
+		}
+		for _, key := range keys {
+			out.keys[key.ID] = key
+			if key.UserID != nil {
+				userIDs = append(userIDs, *key.UserID)
+			}
+		}
+	}
+	if len(userIDs) > 0 {
+		var users []models.User
+		if errFind := d.db.WithContext(ctx).Select("id", "username", "email").Where("id IN ?", userIDs).Find(&users).Error; errFind != nil {
+			return out, fmt.Errorf("anomaly: load users: %w", errFind)
+		}
+		for _, user := range users {
+			out.users[user.ID] = user
+		}
+	}
+	return out, nil
+}
+
+// label names the subject of an anomaly.
+func (s subjects) label(row *models.SpendAnomaly) string {
+	if row.Scope == ScopeUser {
+		if user, ok := s.users[row.SubjectID]; ok {
+			return fmt.Sprintf("User %q", user.Username)
+		}
+		return fmt.Sprintf("User #%d", row.SubjectID)
+	}
+	key, ok := s.keys[row.SubjectID]
+	if !ok {
+		return fmt.Sprintf("API key #%d", row.SubjectID)
+	}
+	label := fmt.Sprintf("API key %q", key.Name)
+	if key.UserID != nil {
+		if user, okUser := s.users[*key.UserID]; okUser {
+			label += fmt.Sprintf(" of user %q", user.Username)
+		}
+	}
+	return label
+}
+
+// describe renders the summary of an anomaly.
+func (s subjects) describe(row *models.SpendAnomaly) string {
+	var details []string
+	_ = json.Unmarshal(row.Details, &details)
+	var text string
+	switch row.Kind {
+	case KindSpendSpike:
+		text = fmt.Sprintf("%s spent $%.2f in the last hour against a baseline of $%.2f per hour", s.label(row), row.Observed, row.Baseline)
+	case KindRequestSpike:
+		text = fmt.Sprintf("%s made %.0f requests in the last hour against a baseline of %.1f per hour", s.label(row), row.Observed, row.Baseline)
+	case KindNewIPRange:
+		text = fmt.Sprintf("%s was used from new IP ranges: %s", s.label(row), strings.Join(details, ", "))
+	case KindNewModel:
+		text = fmt.Sprintf("%s called new models: %s", s.label(row), strings.Join(details, ", "))
+	default:
+		text = fmt.Sprintf("%s: %s", s.label(row), row.Kind)
+	}
+	if row.Suspended {
+		text += "; the key was suspended"
+	}
+	return text
+}
+
+// ownerID returns the user to notify about an anomaly, if any.
+func ownerID(row *models.SpendAnomaly) uint64 {
+	if row.UserID == nil {
+		return 0
+	}
+	return *row.UserID
+}
+
+// notify sends new anomalies to the admin webhook and emails, and to the owning users.
+func (d *Detector) notify(ctx context.Context, policy internalsettings.AnomalyPolicy, subjects subjects, rows []models.SpendAnomaly, now time.Time) error {
+	var errs []error
+	if policy.WebhookURL != "" {
+		payload := alertPayload{Event: alertEvent, GeneratedAt: now.UTC().Format(time.RFC3339), Anomalies: make([]alert, 0, len(rows))}
+		for i := range rows {
+			payload.Anomalies = append(payload.Anomalies, subjects.alert(&rows[i]))
+		}
+		if errPost := d.postWebhook(ctx, policy.WebhookURL, payload); errPost != nil {
+			errs = append(errs, errPost)
+		}
+	}
+	if len(policy.AlertEmails) > 0 {
+		subject := fmt.Sprintf("Spend anomalies detected: %d finding(s)", len(rows))
+		body := anomalyMessage("The following unusual activity was detected:", rows)
+		for _, to := range policy.AlertEmails {
+			if errSend := d.sendMail(ctx, to, subject, body); errSend != nil {
+				errs = append(errs, fmt.Errorf("anomaly: mail %s: %w", to, errSend))
+			}
+		}
+	}
+	if policy.NotifyOwners {
+		byOwner := make(map[uint64][]models.SpendAnomaly)
+		var owners []uint64
+		for _, row := range rows {
+			id := ownerID(&row)
+			if id == 0 {
+				continue
+			}
+			if _, seen := byOwner[id]; !seen {
+				owners = append(owners, id)
+			}
+			byOwner[id] = append(byOwner[id], row)
+		}
+		for _, id := range owners {
+			user, ok := subjects.users[id]
+			if !ok || strings.TrimSpace(user.Email) == "" {
+				continue
+			}
+			body := anomalyMessage("We detected unusual activity on your account:", byOwner[id]) +
+				"\nIf this was not you, revoke the affected API keys and create new ones. Contact an administrator to reactivate a suspended key.\n"
+			if errSend := d.sendMail(ctx, user.Email, "Unusual activity on your account", body); errSend != nil {
+				errs = append(errs, fmt.Errorf("anomaly: mail owner %d: %w", id, errSend))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// alert converts an anomaly into its webhook representation.
+func (s subjects) alert(row *models.SpendAnomaly) alert {
+	out := alert{
+		ID:        row.ID,
+		Scope:     row.Scope,
+		Kind:      row.Kind,
+		APIKeyID:  row.APIKeyID,
+		UserID:    row.UserID,
+		Observed:  row.Observed,
+		Baseline:  row.Baseline,
+		Details:   json.RawMessage(row.Details),
+		Message:   row.Message,
+		Suspended: row.Suspended,
+	}
+	if row.APIKeyID != nil {
+		out.APIKeyName = s.keys[*row.APIKeyID].Name
+	}
+	if row.UserID != nil {
+		out.Username = s.users[*row.UserID].Username
+	}
+	return out
+}
+
+// anomalyMessage renders a plain-text list of anomalies under intro.
+func anomalyMessage(intro string, rows []models.SpendAnomaly) string {
+	var b strings.Builder
+	b.WriteString(intro)
+	b.WriteString("\n\n")
+	for _, row := range rows {
+		fmt.Fprintf(&b, "- %s (detected %s)\n", row.Message, row.DetectedAt.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// postWebhook delivers the payload as JSON.
+func (d *Detector) postWebhook(ctx context.Context, targetURL string, payload alertPayload) error {
+	if errPost := webhook.Post(ctx, d.client, targetURL, payload); errPost != nil {
+		return fmt.Errorf("anomaly: %w", errPost)
+	}
+	return nil
+}
+
+// ipRange returns the /24 network of an IPv4 address or the /48 network of an IPv6 address,
+// or "" when ip is not an address.
+func ipRange(ip string) string {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
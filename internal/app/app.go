@@ -14,16 +14,22 @@ import (
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/anomaly"
 	internalauth "github.com/router-for-me/CLIProxyAPIBusiness/internal/auth"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	relayhttp "github.com/router-for-me/CLIProxyAPIBusiness/internal/http"
 	internalhttp "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/front"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/public"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelregistry"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/reports"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/store"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tracing"
 	internalusage "github.com/router-for-me/CLIProxyAPIBusiness/internal/usage"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/watcher"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/webui"
@@ -40,6 +46,7 @@ import (
 // CreateAPIKeyParams holds inputs for API key creation.
 type CreateAPIKeyParams struct {
 	Name                   string
+	Username               string
 	Admin                  bool
 	BillingRateMicrosPer1K int64
 	BillingCurrency        string
@@ -90,6 +97,23 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 	access.RegisterDBAPIKeyProvider(conn)
 
 	jwtConfig, _ := config.LoadJWTConfig(configPath)
+	metricsConfig := config.LoadMetricsConfig(configPath)
+	shutdownTracing, errTracing := tracing.Init(ctx, config.LoadTracingConfig(configPath))
+	if errTracing != nil {
+		return errTracing
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if errShutdown := shutdownTracing(shutdownCtx); errShutdown != nil {
+			log.WithError(errShutdown).Warn("tracing: shutdown failed")
+		}
+	}()
+	if sqlDB, errSQLDB := conn.DB(); errSQLDB == nil {
+		if errRegister := metrics.RegisterDBStats(sqlDB, db.DialectName(conn)); errRegister != nil {
+			log.WithError(errRegister).Warn("metrics: register db stats failed")
+		}
+	}
 
 	authStore := store.NewGormAuthStore(conn)
 	sdkAuth.RegisterTokenStore(authStore)
@@ -130,6 +154,16 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 	serverAccessMgr := sdkaccess.NewManager()
 
 	coreManager := coreauth.NewManager(authStore, internalauth.NewSelector(conn), internalauth.NewStatusCodeHook())
+	if errRegister := metrics.RegisterAuthSource(coreManager.List); errRegister != nil {
+		log.WithError(errRegister).Warn("metrics: register auth source failed")
+	}
+	metrics.RegisterModelSource(func(provider, model string) bool {
+		if modelStore.Has(provider, model) {
+			return true
+		}
+		_, _, mapped := modelmapping.LookupSelector(provider, model)
+		return mapped
+	})
 	distFS := webBundle.DistFS
 	fileServer := http.FileServer(http.FS(distFS))
 	builder := sdkcliproxy.NewBuilder().
@@ -155,12 +189,20 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 					c.Abort()
 				},
 				webUIRootMiddleware(webBundle.IndexHTML),
+				relayhttp.RelayTracingMiddleware(),
+				relayhttp.RelayMetricsMiddleware(),
+				relayhttp.RelayRequestLogMiddleware(conn),
 				relayhttp.CLIProxyAuthMiddleware(enforcementAccessMgr, coreCfg.WebsocketAuth),
+				relayhttp.ModelCatalogMiddleware(),
 				relayhttp.CLIProxyModelsMiddleware(conn, modelStore),
 			),
 			sdkapi.WithRouterConfigurator(func(engine *gin.Engine, baseHandler *sdkhandlers.BaseAPIHandler, cfg *sdkconfig.Config) {
 				internalhttp.RegisterAdminRoutes(engine, conn, jwtConfig, configPath, cfg, baseHandler)
 				front.RegisterFrontRoutes(engine, conn, jwtConfig, modelStore)
+				public.RegisterPublicRoutes(engine, conn)
+				if metricsConfig.Enabled {
+					engine.GET(metricsConfig.Path, metrics.Handler(metricsConfig.Token))
+				}
 				engine.StaticFS("/assets", webBundle.AssetsFS)
 				engine.GET("/v0/init/status", func(c *gin.Context) {
 					c.JSON(http.StatusOK, InitStatusResponse{Initialized: initState.Load()})
@@ -264,8 +306,11 @@ func RunServer(ctx context.Context, cfg config.AppConfig, defaultPort int) error
 	}
 	service.RegisterUsagePlugin(internalusage.NewGormUsagePlugin(conn))
 	if quotaPoller := quota.NewPoller(conn, coreManager); quotaPoller != nil {
+		quotaPoller.SetEndpoints(config.LoadQuotaConfig(configPath).EndpointMap())
 		quotaPoller.Start(ctx)
 	}
+	reports.NewScheduler(conn, coreManager).Start(ctx)
+	anomaly.NewDetector(conn).Start(ctx)
 
 	serverAccessMgr.SetProviders(nil)
 
@@ -0,0 +1,535 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authfiles"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+// minPasswordLength mirrors the minimum password length enforced by the init wizard.
+const minPasswordLength = 6
+
+var (
+	errMissingUsername  = errors.New("username is required")
+	errPasswordTooShort = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+)
+
+// OpenDatabase opens and migrates the configured database and loads the settings snapshot.
+func OpenDatabase(ctx context.Context, cfg config.AppConfig) (*gorm.DB, error) {
+	conn, err := openDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		return nil, errMigrate
+	}
+	if errLoad := LoadSettingsSnapshot(ctx, conn); errLoad != nil {
+		return nil, errLoad
+	}
+	return conn, nil
+}
+
+// openDatabase opens the configured database without migrating it.
+func openDatabase(cfg config.AppConfig) (*gorm.DB, error) {
+	dsn, err := config.LoadDatabaseDSN(config.ResolveConfigPath(cfg.ConfigPath))
+	if err != nil {
+		return nil, err
+	}
+	return db.Open(dsn)
+}
+
+// LoadSettingsSnapshot stores the DB-backed settings in the in-memory snapshot.
+func LoadSettingsSnapshot(ctx context.Context, conn *gorm.DB) error {
+	var rows []models.Setting
+	if errFind := conn.WithContext(ctx).
+		Select("key", "value", "updated_at").
+		Order("key ASC").
+		Find(&rows).Error; errFind != nil {
+		return fmt.Errorf("load settings: %w", errFind)
+	}
+
+	values := make(map[string]json.RawMessage, len(rows))
+	maxUpdatedAt := time.Time{}
+	for _, row := range rows {
+		key := strings.TrimSpace(row.Key)
+		if key == "" {
+			continue
+		}
+		values[key] = row.Value
+		if row.UpdatedAt.UTC().After(maxUpdatedAt) {
+			maxUpdatedAt = row.UpdatedAt.UTC()
+		}
+	}
+	internalsettings.StoreDBConfig(maxUpdatedAt, values)
+	return nil
+}
+
+// CreateAdmin creates an active administrator account.
+func CreateAdmin(ctx context.Context, conn *gorm.DB, username, password string, superAdmin bool) (models.Admin, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return models.Admin{}, errMissingUsername
+	}
+	hash, errHash := hashCLIPassword(password)
+	if errHash != nil {
+		return models.Admin{}, errHash
+	}
+
+	now := time.Now().UTC()
+	admin := models.Admin{
+		Username:     username,
+		Password:     hash,
+		Active:       true,
+		IsSuperAdmin: superAdmin,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if errCreate := conn.WithContext(ctx).Create(&admin).Error; errCreate != nil {
+		return models.Admin{}, fmt.Errorf("create admin: %w", errCreate)
+	}
+	return admin, nil
+}
+
+// ResetAdminPassword replaces an administrator password and re-enables the account.
+func ResetAdminPassword(ctx context.Context, conn *gorm.DB, username, password string) error {
+	admin, errFind := findAdminByUsername(ctx, conn, username)
+	if errFind != nil {
+		return errFind
+	}
+	hash, errHash := hashCLIPassword(password)
+	if errHash != nil {
+		return errHash
+	}
+	if errUpdate := conn.WithContext(ctx).Model(&models.Admin{}).Where("id = ?", admin.ID).Updates(map[string]any{
+		"password":   hash,
+		"active":     true,
+		"updated_at": time.Now().UTC(),
+	}).Error; errUpdate != nil {
+		return fmt.Errorf("update admin password: %w", errUpdate)
+	}
+	return nil
+}
+
+// DisableMFA removes every MFA factor from the named admin or user account.
+func DisableMFA(ctx context.Context, conn *gorm.DB, ownerKind, username string) error {
+	var ownerID uint64
+	switch ownerKind {
+	case models.MFAOwnerAdmin:
+		admin, errFind := findAdminByUsername(ctx, conn, username)
+		if errFind != nil {
+			return errFind
+		}
+		ownerID = admin.ID
+	case models.MFAOwnerUser:
+		user, errFind := findUserByUsername(ctx, conn, username)
+		if errFind != nil {
+			return errFind
+		}
+		ownerID = user.ID
+	default:
+		return fmt.Errorf("unknown account kind: %s", ownerKind)
+	}
+	return mfa.Reset(ctx, conn, ownerKind, ownerID)
+}
+
+// CreateUserParams holds inputs for user creation.
+type CreateUserParams struct {
+	Username     string
+	Email        string
+	Password     string
+	UserGroupIDs []uint64
+}
+
+// CreateUser creates an active end-user account.
+func CreateUser(ctx context.Context, conn *gorm.DB, params CreateUserParams) (models.User, error) {
+	username := strings.TrimSpace(params.Username)
+	if username == "" {
+		return models.User{}, errMissingUsername
+	}
+	hash, errHash := hashCLIPassword(params.Password)
+	if errHash != nil {
+		return models.User{}, errHash
+	}
+
+	groupIDs := make(models.UserGroupIDs, 0, len(params.UserGroupIDs))
+	for _, id := range params.UserGroupIDs {
+		idCopy := id
+		groupIDs = append(groupIDs, &idCopy)
+	}
+
+	now := time.Now().UTC()
+	user := models.User{
+		Username:    username,
+		Email:       strings.TrimSpace(params.Email),
+		Password:    hash,
+		UserGroupID: groupIDs.Clean(),
+		Active:      true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if errCreate := conn.WithContext(ctx).Create(&user).Error; errCreate != nil {
+		return models.User{}, fmt.Errorf("create user: %w", errCreate)
+	}
+	return user, nil
+}
+
+// IssueAPIKey creates an active API key, bound to Username when one is given.
+func IssueAPIKey(ctx context.Context, conn *gorm.DB, params CreateAPIKeyParams) (models.APIKey, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return models.APIKey{}, errors.New("api key name is required")
+	}
+	var userID *uint64
+	if strings.TrimSpace(params.Username) != "" {
+		user, errFind := findUserByUsername(ctx, conn, params.Username)
+		if errFind != nil {
+			return models.APIKey{}, errFind
+		}
+		userID = &user.ID
+	}
+	token, errGenerate := security.GenerateAPIKey()
+	if errGenerate != nil {
+		return models.APIKey{}, fmt.Errorf("generate api key: %w", errGenerate)
+	}
+
+	now := time.Now().UTC()
+	row := models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		APIKey:    token,
+		IsAdmin:   params.Admin,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if errCreate := conn.WithContext(ctx).Create(&row).Error; errCreate != nil {
+		return models.APIKey{}, fmt.Errorf("create api key: %w", errCreate)
+	}
+	return row, nil
+}
+
+// ImportAuthFilesResult reports the outcome of a directory import.
+type ImportAuthFilesResult struct {
+	Imported int               // Number of files stored.
+	Failed   map[string]string // Failure reason keyed by file name.
+}
+
+// ImportAuthFiles imports every *.json auth file in dir, using the default auth group when groupIDs is empty.
+func ImportAuthFiles(ctx context.Context, conn *gorm.DB, dir string, groupIDs []uint64, proxyURL string) (ImportAuthFilesResult, error) {
+	entries, errRead := os.ReadDir(dir)
+	if errRead != nil {
+		return ImportAuthFilesResult{}, fmt.Errorf("read auth directory: %w", errRead)
+	}
+
+	authGroupIDs := make(models.AuthGroupIDs, 0, len(groupIDs))
+	for _, id := range groupIDs {
+		idCopy := id
+		authGroupIDs = append(authGroupIDs, &idCopy)
+	}
+	authGroupIDs = authGroupIDs.Clean()
+	if len(authGroupIDs) == 0 {
+		defaultGroupIDs, errDefault := authfiles.DefaultGroupIDs(ctx, conn)
+		if errDefault != nil {
+			return ImportAuthFilesResult{}, fmt.Errorf("query default auth group: %w", errDefault)
+		}
+		authGroupIDs = defaultGroupIDs
+	}
+
+	result := ImportAuthFilesResult{Failed: make(map[string]string)}
+	now := time.Now().UTC()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		data, errFile := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if errFile != nil {
+			result.Failed[entry.Name()] = "read file failed"
+			continue
+		}
+		parsed, errParse := authfiles.Parse(entry.Name(), data)
+		if errParse != nil {
+			result.Failed[entry.Name()] = errParse.Error()
+			continue
+		}
+		fileProxyURL := parsed.ProxyURL
+		if fileProxyURL == "" {
+			fileProxyURL = strings.TrimSpace(proxyURL)
+		}
+		if errUpsert := authfiles.Upsert(ctx, conn, parsed, authGroupIDs, fileProxyURL, now); errUpsert != nil {
+			result.Failed[entry.Name()] = "import auth file failed"
+			continue
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+// GrantBalance credits a user by creating a prepaid card already redeemed to them.
+func GrantBalance(ctx context.Context, conn *gorm.DB, username string, amount float64, validDays int) (models.PrepaidCard, error) {
+	if amount <= 0 {
+		return models.PrepaidCard{}, errors.New("amount must be positive")
+	}
+	if validDays < 0 {
+		return models.PrepaidCard{}, errors.New("valid days cannot be negative")
+	}
+	user, errFind := findUserByUsername(ctx, conn, username)
+	if errFind != nil {
+		return models.PrepaidCard{}, errFind
+	}
+	cardSN, errSN := security.GenerateRandomString(16)
+	if errSN != nil {
+		return models.PrepaidCard{}, fmt.Errorf("generate card serial: %w", errSN)
+	}
+	password, errPassword := security.GenerateRandomString(16)
+	if errPassword != nil {
+		return models.PrepaidCard{}, fmt.Errorf("generate card password: %w", errPassword)
+	}
+
+	now := time.Now().UTC()
+	var expiresAt *time.Time
+	if validDays > 0 {
+		exp := now.AddDate(0, 0, validDays)
+		expiresAt = &exp
+	}
+	card := models.PrepaidCard{
+		Name:           "CLI grant",
+		CardSN:         "CLI-" + strings.ToUpper(cardSN),
+		Password:       password,
+		Amount:         amount,
+		Balance:        amount,
+		ValidDays:      validDays,
+		ExpiresAt:      expiresAt,
+		IsEnabled:      true,
+		RedeemedUserID: &user.ID,
+		CreatedAt:      now,
+		RedeemedAt:     &now,
+	}
+	if errCreate := conn.WithContext(ctx).Create(&card).Error; errCreate != nil {
+		return models.PrepaidCard{}, fmt.Errorf("create prepaid card: %w", errCreate)
+	}
+	return card, nil
+}
+
+// ListSettings returns all DB-backed settings ordered by key.
+func ListSettings(ctx context.Context, conn *gorm.DB) ([]models.Setting, error) {
+	var rows []models.Setting
+	if errFind := conn.WithContext(ctx).Order("key ASC").Find(&rows).Error; errFind != nil {
+		return nil, fmt.Errorf("list settings: %w", errFind)
+	}
+	return rows, nil
+}
+
+// SetSetting upserts a setting; values that are not valid JSON are stored as JSON strings.
+func SetSetting(ctx context.Context, conn *gorm.DB, key, value string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return errors.New("setting key is required")
+	}
+	raw := json.RawMessage(bytes.TrimSpace([]byte(value)))
+	if !json.Valid(raw) {
+		encoded, errMarshal := json.Marshal(value)
+		if errMarshal != nil {
+			return fmt.Errorf("encode setting value: %w", errMarshal)
+		}
+		raw = encoded
+	}
+
+	now := time.Now().UTC()
+	res := conn.WithContext(ctx).Model(&models.Setting{}).Where("key = ?", key).Updates(map[string]any{
+		"value":      raw,
+		"updated_at": now,
+	})
+	if res.Error != nil {
+		return fmt.Errorf("update setting: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	if errCreate := conn.WithContext(ctx).Create(&models.Setting{Key: key, Value: raw, UpdatedAt: now}).Error; errCreate != nil {
+		return fmt.Errorf("create setting: %w", errCreate)
+	}
+	return nil
+}
+
+// Doctor check statuses.
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+	DoctorSkip = "skip"
+)
+
+// DoctorCheck is the result of a single health check.
+type DoctorCheck struct {
+	Name   string // Short check name.
+	Status string // One of DoctorOK, DoctorWarn, DoctorFail, DoctorSkip.
+	Detail string // Human-readable explanation.
+}
+
+// RunDoctor checks config, database, JWT secret, pending migrations, and Redis reachability.
+// It never migrates the database.
+func RunDoctor(ctx context.Context, cfg config.AppConfig) []DoctorCheck {
+	configPath := config.ResolveConfigPath(cfg.ConfigPath)
+	checks := make([]DoctorCheck, 0, 5)
+
+	if ConfigExists(configPath) {
+		checks = append(checks, DoctorCheck{Name: "config", Status: DoctorOK, Detail: configPath})
+	} else if strings.TrimSpace(os.Getenv(config.EnvDBConnection)) != "" {
+		checks = append(checks, DoctorCheck{Name: "config", Status: DoctorWarn, Detail: "config file missing, using " + config.EnvDBConnection})
+	} else {
+		checks = append(checks, DoctorCheck{Name: "config", Status: DoctorFail, Detail: "config file not found: " + configPath})
+	}
+
+	checks = append(checks, checkJWTSecret(configPath))
+
+	conn, errOpen := openDatabase(cfg)
+	if errOpen != nil {
+		checks = append(checks,
+			DoctorCheck{Name: "database", Status: DoctorFail, Detail: errOpen.Error()},
+			DoctorCheck{Name: "migrate", Status: DoctorSkip, Detail: "database unavailable"},
+			DoctorCheck{Name: "redis", Status: DoctorSkip, Detail: "database unavailable"},
+		)
+		return checks
+	}
+	checks = append(checks, checkDatabase(ctx, conn))
+	checks = append(checks, checkMigrations(conn))
+	if !conn.Migrator().HasTable(&models.Setting{}) {
+		return append(checks, DoctorCheck{Name: "redis", Status: DoctorSkip, Detail: "settings table missing"})
+	}
+	if errLoad := LoadSettingsSnapshot(ctx, conn); errLoad != nil {
+		return append(checks, DoctorCheck{Name: "redis", Status: DoctorSkip, Detail: errLoad.Error()})
+	}
+	checks = append(checks, checkRedis(ctx))
+	return checks
+}
+
+// checkMigrations reports pending migrations, and migrations unknown to this binary, without applying them.
+func checkMigrations(conn *gorm.DB) DoctorCheck {
+	states, errStatus := db.MigrationStatus(conn)
+	if errStatus != nil {
+		return DoctorCheck{Name: "migrate", Status: DoctorFail, Detail: errStatus.Error()}
+	}
+	var pending []string
+	for _, state := range states {
+		if !state.Applied {
+			pending = append(pending, fmt.Sprintf("%d %s", state.Version, state.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return DoctorCheck{Name: "migrate", Status: DoctorWarn, Detail: fmt.Sprintf("%d pending: %s", len(pending), strings.Join(pending, ", "))}
+	}
+	return DoctorCheck{Name: "migrate", Status: DoctorOK, Detail: fmt.Sprintf("%d applied", len(states))}
+}
+
+// checkJWTSecret reports whether the JWT signing secret is present and strong enough.
+func checkJWTSecret(configPath string) DoctorCheck {
+	jwtCfg, _ := config.LoadJWTConfig(configPath)
+	secret := strings.TrimSpace(jwtCfg.Secret)
+	switch {
+	case secret == "":
+		return DoctorCheck{Name: "jwt", Status: DoctorFail, Detail: "jwt secret is empty"}
+	case len(secret) < 32:
+		return DoctorCheck{Name: "jwt", Status: DoctorWarn, Detail: "jwt secret is shorter than 32 characters"}
+	default:
+		return DoctorCheck{Name: "jwt", Status: DoctorOK, Detail: fmt.Sprintf("expiry %s", jwtCfg.Expiry)}
+	}
+}
+
+// checkDatabase pings the database and reports whether an admin exists.
+func checkDatabase(ctx context.Context, conn *gorm.DB) DoctorCheck {
+	sqlDB, errSQLDB := conn.DB()
+	if errSQLDB != nil {
+		return DoctorCheck{Name: "database", Status: DoctorFail, Detail: errSQLDB.Error()}
+	}
+	ctxPing, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if errPing := sqlDB.PingContext(ctxPing); errPing != nil {
+		return DoctorCheck{Name: "database", Status: DoctorFail, Detail: errPing.Error()}
+	}
+	initialized, errInit := HasAdminInitialized(conn)
+	if errInit != nil {
+		return DoctorCheck{Name: "database", Status: DoctorFail, Detail: errInit.Error()}
+	}
+	if !initialized {
+		return DoctorCheck{Name: "database", Status: DoctorWarn, Detail: db.DialectName(conn) + " reachable, no admin account"}
+	}
+	return DoctorCheck{Name: "database", Status: DoctorOK, Detail: db.DialectName(conn) + " reachable"}
+}
+
+// checkRedis pings the rate limit Redis server when it is enabled.
+func checkRedis(ctx context.Context) DoctorCheck {
+	rateCfg := ratelimit.LoadSettingsConfig()
+	if !rateCfg.RedisEnabled {
+		return DoctorCheck{Name: "redis", Status: DoctorSkip, Detail: "rate limit redis disabled"}
+	}
+	if rateCfg.RedisAddr == "" {
+		return DoctorCheck{Name: "redis", Status: DoctorFail, Detail: "rate limit redis enabled without an address"}
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     rateCfg.RedisAddr,
+		Password: rateCfg.RedisPassword,
+		DB:       rateCfg.RedisDB,
+	})
+	defer func() { _ = client.Close() }()
+	ctxPing, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if errPing := client.Ping(ctxPing).Err(); errPing != nil {
+		return DoctorCheck{Name: "redis", Status: DoctorFail, Detail: errPing.Error()}
+	}
+	return DoctorCheck{Name: "redis", Status: DoctorOK, Detail: rateCfg.RedisAddr}
+}
+
+// findAdminByUsername loads an administrator by login name.
+func findAdminByUsername(ctx context.Context, conn *gorm.DB, username string) (models.Admin, error) {
+	var admin models.Admin
+	errFind := conn.WithContext(ctx).Where("username = ?", strings.TrimSpace(username)).First(&admin).Error
+	if errors.Is(errFind, gorm.ErrRecordNotFound) {
+		return models.Admin{}, fmt.Errorf("admin not found: %s", username)
+	}
+	if errFind != nil {
+		return models.Admin{}, fmt.Errorf("query admin: %w", errFind)
+	}
+	return admin, nil
+}
+
+// findUserByUsername loads a user by login name.
+func findUserByUsername(ctx context.Context, conn *gorm.DB, username string) (models.User, error) {
+	var user models.User
+	errFind := conn.WithContext(ctx).Where("username = ?", strings.TrimSpace(username)).First(&user).Error
+	if errors.Is(errFind, gorm.ErrRecordNotFound) {
+		return models.User{}, fmt.Errorf("user not found: %s", username)
+	}
+	if errFind != nil {
+		return models.User{}, fmt.Errorf("query user: %w", errFind)
+	}
+	return user, nil
+}
+
+// hashCLIPassword validates and hashes a password supplied on the command line.
+func hashCLIPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", errPasswordTooShort
+	}
+	hash, errHash := security.HashPassword(password)
+	if errHash != nil {
+		return "", fmt.Errorf("hash password: %w", errHash)
+	}
+	return hash, nil
+}
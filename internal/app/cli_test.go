@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+)
+
+func TestCLIAccountCommands(t *testing.T) {
+	conn, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	ctx := context.Background()
+
+	if _, errCreate := CreateAdmin(ctx, conn, "root", "short", true); errCreate == nil {
+		t.Fatalf("expected short password to be rejected")
+	}
+	if _, errCreate := CreateAdmin(ctx, conn, "root", "password", true); errCreate != nil {
+		t.Fatalf("CreateAdmin: %v", errCreate)
+	}
+	if errReset := ResetAdminPassword(ctx, conn, "root", "new-password"); errReset != nil {
+		t.Fatalf("ResetAdminPassword: %v", errReset)
+	}
+	var admin models.Admin
+	if errFind := conn.Where("username = ?", "root").First(&admin).Error; errFind != nil {
+		t.Fatalf("find admin: %v", errFind)
+	}
+	if !security.CheckPassword(admin.Password, "new-password") {
+		t.Fatalf("expected password to be reset")
+	}
+
+	user, errUser := CreateUser(ctx, conn, CreateUserParams{Username: "alice", Email: "alice@example.com", Password: "password"})
+	if errUser != nil {
+		t.Fatalf("CreateUser: %v", errUser)
+	}
+	key, errKey := IssueAPIKey(ctx, conn, CreateAPIKeyParams{Name: "ci", Username: "alice"})
+	if errKey != nil || key.UserID == nil || *key.UserID != user.ID {
+		t.Fatalf("IssueAPIKey: %+v %v", key, errKey)
+	}
+
+	card, errGrant := GrantBalance(ctx, conn, "alice", 12.5, 30)
+	if errGrant != nil {
+		t.Fatalf("GrantBalance: %v", errGrant)
+	}
+	if card.RedeemedUserID == nil || *card.RedeemedUserID != user.ID || card.Balance != 12.5 || card.ExpiresAt == nil {
+		t.Fatalf("unexpected granted card: %+v", card)
+	}
+	if _, errGrant = GrantBalance(ctx, conn, "missing", 1, 0); errGrant == nil {
+		t.Fatalf("expected unknown user to fail")
+	}
+}
+
+func TestCLIImportAuthFilesAndSettings(t *testing.T) {
+	conn, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"codex.json":  `{"id":"codex-1","provider":"codex","proxy_url":"http://proxy:1"}`,
+		"gemini.json": `{"type":"gemini"}`,
+		"broken.json": `{`,
+		"notes.txt":   `ignored`,
+	}
+	for name, content := range files {
+		if errWrite := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); errWrite != nil {
+			t.Fatalf("write %s: %v", name, errWrite)
+		}
+	}
+
+	result, errImport := ImportAuthFiles(ctx, conn, dir, nil, "http://fallback:2")
+	if errImport != nil {
+		t.Fatalf("ImportAuthFiles: %v", errImport)
+	}
+	if result.Imported != 2 || len(result.Failed) != 1 || result.Failed["broken.json"] == "" {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+	var codex, gemini models.Auth
+	if errFind := conn.Where("key = ?", "codex-1").First(&codex).Error; errFind != nil {
+		t.Fatalf("find codex auth: %v", errFind)
+	}
+	if errFind := conn.Where("key = ?", "gemini.json").First(&gemini).Error; errFind != nil {
+		t.Fatalf("find gemini auth: %v", errFind)
+	}
+	if codex.ProxyURL != "http://proxy:1" || gemini.ProxyURL != "http://fallback:2" {
+		t.Fatalf("unexpected proxy urls: %q %q", codex.ProxyURL, gemini.ProxyURL)
+	}
+
+	if errSet := SetSetting(ctx, conn, "SITE_NAME", "My Relay"); errSet != nil {
+		t.Fatalf("SetSetting string: %v", errSet)
+	}
+	if errSet := SetSetting(ctx, conn, "RATE_LIMIT", "25"); errSet != nil {
+		t.Fatalf("SetSetting number: %v", errSet)
+	}
+	rows, errList := ListSettings(ctx, conn)
+	if errList != nil {
+		t.Fatalf("ListSettings: %v", errList)
+	}
+	values := make(map[string]string, len(rows))
+	for _, row := range rows {
+		values[row.Key] = string(row.Value)
+	}
+	var siteName string
+	if errUnmarshal := json.Unmarshal([]byte(values["SITE_NAME"]), &siteName); errUnmarshal != nil || siteName != "My Relay" {
+		t.Fatalf("unexpected SITE_NAME: %q", values["SITE_NAME"])
+	}
+	if values["RATE_LIMIT"] != "25" {
+		t.Fatalf("unexpected RATE_LIMIT: %q", values["RATE_LIMIT"])
+	}
+}
+
+func TestRunDoctorReportsPendingMigrationsWithoutMigrating(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "doctor.db")
+	t.Setenv(config.EnvDBConnection, dsn)
+	cfg := config.AppConfig{ConfigPath: filepath.Join(t.TempDir(), "missing.yaml")}
+
+	findCheck := func(checks []DoctorCheck, name string) DoctorCheck {
+		for _, check := range checks {
+			if check.Name == name {
+				return check
+			}
+		}
+		t.Fatalf("missing %s check in %+v", name, checks)
+		return DoctorCheck{}
+	}
+
+	check := findCheck(RunDoctor(context.Background(), cfg), "migrate")
+	if check.Status != DoctorWarn || !strings.Contains(check.Detail, "1 baseline") {
+		t.Fatalf("expected pending migrations, got %+v", check)
+	}
+	conn, errOpen := db.Open(dsn)
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if conn.Migrator().HasTable(&models.SchemaMigration{}) || conn.Migrator().HasTable(&models.User{}) {
+		t.Fatalf("doctor must not migrate the database")
+	}
+
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate: %v", errMigrate)
+	}
+	if check = findCheck(RunDoctor(context.Background(), cfg), "migrate"); check.Status != DoctorOK {
+		t.Fatalf("expected migrations applied, got %+v", check)
+	}
+}
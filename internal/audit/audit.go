@@ -0,0 +1,281 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// adminRoutePrefix is stripped from route templates before resolving entities.
+const adminRoutePrefix = "/v0/admin/"
+
+// settingEntityType is the entity type of settings rows.
+const settingEntityType = "setting"
+
+// maskedValue replaces secret values in snapshots and diffs.
+const maskedValue = "******"
+
+// Entity describes how to load the record targeted by an admin route.
+type Entity struct {
+	Type   string // Entity type label stored on the audit row.
+	Model  any    // GORM model used to resolve the table.
+	Column string // Lookup column.
+	Param  string // Route parameter carrying the lookup value.
+}
+
+// entities maps the first admin route segment to its entity.
+var entities = map[string]Entity{
+	"admins":            {Type: "admin", Model: &models.Admin{}, Column: "id", Param: "id"},
+	"admin-roles":       {Type: "admin_role", Model: &models.AdminRole{}, Column: "id", Param: "id"},
+	"api-keys":          {Type: "api_key", Model: &models.APIKey{}, Column: "id", Param: "id"},
+	"auth-files":        {Type: "auth_file", Model: &models.Auth{}, Column: "id", Param: "id"},
+	"auth-groups":       {Type: "auth_group", Model: &models.AuthGroup{}, Column: "id", Param: "id"},
+	"billing-rules":     {Type: "billing_rule", Model: &models.BillingRule{}, Column: "id", Param: "id"},
+	"bills":             {Type: "bill", Model: &models.Bill{}, Column: "id", Param: "id"},
+	"invite-codes":      {Type: "invite_code", Model: &models.InviteCode{}, Column: "id", Param: "id"},
+	"model-mappings":    {Type: "model_mapping", Model: &models.ModelMapping{}, Column: "id", Param: "id"},
+	"oidc-providers":    {Type: "oidc_provider", Model: &models.OIDCProvider{}, Column: "id", Param: "id"},
+	"plans":             {Type: "plan", Model: &models.Plan{}, Column: "id", Param: "id"},
+	"prepaid-cards":     {Type: "prepaid_card", Model: &models.PrepaidCard{}, Column: "id", Param: "id"},
+	"provider-api-keys": {Type: "provider_api_key", Model: &models.ProviderAPIKey{}, Column: "id", Param: "id"},
+	"proxies":           {Type: "proxy", Model: &models.Proxy{}, Column: "id", Param: "id"},
+	"settings":          {Type: settingEntityType, Model: &models.Setting{}, Column: "key", Param: "key"},
+	"spend-anomalies":   {Type: "spend_anomaly", Model: &models.SpendAnomaly{}, Column: "id", Param: "id"},
+	"user-groups":       {Type: "user_group", Model: &models.UserGroup{}, Column: "id", Param: "id"},
+	"users":             {Type: "user", Model: &models.User{}, Column: "id", Param: "id"},
+}
+
+// payloadRuleEntity covers routes nested under a model mapping.
+var payloadRuleEntity = Entity{Type: "model_payload_rule", Model: &models.ModelPayloadRule{}, Column: "id", Param: "rule_id"}
+
+// ResolveEntity returns the entity targeted by an admin route template.
+func ResolveEntity(route string) (Entity, bool) {
+	trimmed := strings.TrimPrefix(route, adminRoutePrefix)
+	if trimmed == route {
+		return Entity{}, false
+	}
+	segments := strings.Split(trimmed, "/")
+	if len(segments) >= 3 && segments[0] == "model-mappings" && segments[2] == "payload-rules" {
+		return payloadRuleEntity, true
+	}
+	if segments[0] == "tokens" {
+		return Entity{Type: "auth_token"}, true
+	}
+	entity, ok := entities[segments[0]]
+	return entity, ok
+}
+
+// Snapshot loads the masked column values of an entity, or nil when it does not exist.
+func Snapshot(ctx context.Context, db *gorm.DB, entity Entity, id string) map[string]any {
+	if db == nil || entity.Model == nil || strings.TrimSpace(id) == "" {
+		return nil
+	}
+	row := make(map[string]any)
+	errTake := db.WithContext(ctx).Model(entity.Model).Where(entity.Column+" = ?", id).Take(&row).Error
+	if errTake != nil || len(row) == 0 {
+		return nil
+	}
+	normalized := make(map[string]any, len(row))
+	for key, value := range row {
+		normalized[key] = normalizeValue(value)
+	}
+	masked, _ := Mask(normalized).(map[string]any)
+	// Settings are key/value rows, so secrets are identified by the setting key rather than
+	// the column name.
+	if entity.Type == settingEntityType {
+		if key, ok := masked["key"].(string); ok && internalsettings.IsSensitiveKey(key) && masked["value"] != nil {
+			masked["value"] = maskedValue
+		}
+	}
+	return masked
+}
+
+// normalizeValue converts driver values into JSON-friendly values.
+func normalizeValue(value any) any {
+	switch typed := value.(type) {
+	case []byte:
+		if json.Valid(typed) {
+			var decoded any
+			if errUnmarshal := json.Unmarshal(typed, &decoded); errUnmarshal == nil {
+				return decoded
+			}
+		}
+		return string(typed)
+	case string:
+		trimmed := strings.TrimSpace(typed)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var decoded any
+			if errUnmarshal := json.Unmarshal([]byte(trimmed), &decoded); errUnmarshal == nil {
+				return decoded
+			}
+		}
+		return typed
+	case time.Time:
+		return typed.UTC().Format(time.RFC3339Nano)
+	default:
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return normalizeValue(rv.Bytes())
+		}
+		return value
+	}
+}
+
+// sensitiveFragments match field names that hold secrets anywhere in the name.
+var sensitiveFragments = []string{
+	"password",
+	"secret",
+	"private_key",
+	"cookie",
+	"credential",
+	"passkey",
+	"authorization",
+}
+
+// sensitiveSuffixes match token and key fields without catching token counters or prices.
+var sensitiveSuffixes = []string{
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"session_token",
+	"api_key",
+	"apikey",
+}
+
+// IsSensitiveKey reports whether a field name holds secret material.
+func IsSensitiveKey(key string) bool {
+	normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+	if normalized == "token" {
+		return true
+	}
+	for _, fragment := range sensitiveFragments {
+		if strings.Contains(normalized, fragment) {
+			return true
+		}
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(normalized, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask returns a copy of value with sensitive fields replaced.
+func Mask(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for key, item := range typed {
+			if IsSensitiveKey(key) && item != nil && item != "" {
+				out[key] = maskedValue
+				continue
+			}
+			out[key] = Mask(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(typed))
+		for i, item := range typed {
+			out[i] = Mask(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// ignoredDiffKeys are bookkeeping columns excluded from diffs.
+var ignoredDiffKeys = map[string]struct{}{
+	"updated_at": {},
+}
+
+// Diff returns the fields that differ between two snapshots as {field: {before, after}}.
+func Diff(before, after map[string]any) map[string]any {
+	out := make(map[string]any)
+	for key, beforeValue := range before {
+		if _, ignored := ignoredDiffKeys[key]; ignored {
+			continue
+		}
+		afterValue, exists := after[key]
+		if exists && reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		out[key] = map[string]any{"before": beforeValue, "after": afterValue}
+	}
+	for key, afterValue := range after {
+		if _, ignored := ignoredDiffKeys[key]; ignored {
+			continue
+		}
+		if _, exists := before[key]; exists {
+			continue
+		}
+		out[key] = map[string]any{"before": nil, "after": afterValue}
+	}
+	return out
+}
+
+// Entry holds the request details of an audited admin action.
+type Entry struct {
+	AdminID       uint64
+	AdminUsername string
+	Method        string
+	Route         string
+	Path          string
+	Action        string
+	Module        string
+	EntityType    string
+	EntityID      string
+	StatusCode    int
+	Before        map[string]any
+	After         map[string]any
+	IP            string
+	UserAgent     string
+}
+
+// Record persists an audit entry, computing the diff between snapshots.
+func Record(ctx context.Context, db *gorm.DB, entry Entry) error {
+	if db == nil {
+		return errors.New("audit: nil db")
+	}
+	row := models.AuditLog{
+		AdminID:       entry.AdminID,
+		AdminUsername: entry.AdminUsername,
+		Method:        entry.Method,
+		Route:         entry.Route,
+		Path:          entry.Path,
+		Action:        entry.Action,
+		Module:        entry.Module,
+		EntityType:    entry.EntityType,
+		EntityID:      entry.EntityID,
+		StatusCode:    entry.StatusCode,
+		Before:        marshalJSON(entry.Before),
+		After:         marshalJSON(entry.After),
+		IP:            entry.IP,
+		UserAgent:     entry.UserAgent,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if entry.Before != nil || entry.After != nil {
+		row.Diff = marshalJSON(Diff(entry.Before, entry.After))
+	}
+	return db.WithContext(ctx).Create(&row).Error
+}
+
+// marshalJSON encodes a snapshot, returning nil for empty input.
+func marshalJSON(value map[string]any) datatypes.JSON {
+	if value == nil {
+		return nil
+	}
+	data, errMarshal := json.Marshal(value)
+	if errMarshal != nil {
+		return nil
+	}
+	return datatypes.JSON(data)
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+func TestMaskHidesNestedSecrets(t *testing.T) {
+	masked, ok := Mask(map[string]any{
+		"username":          "alice",
+		"password":          "hash",
+		"price_input_token": 1.5,
+		"content": map[string]any{
+			"access_token":  "at",
+			"refresh_token": "rt",
+			"email":         "a@example.com",
+		},
+		"api_key_entries": []any{map[string]any{"api-key": "sk-1"}},
+	}).(map[string]any)
+	if !ok {
+		t.Fatalf("expected map result")
+	}
+	if masked["password"] != maskedValue {
+		t.Fatalf("expected password masked, got %v", masked["password"])
+	}
+	if masked["price_input_token"] != 1.5 {
+		t.Fatalf("expected price kept, got %v", masked["price_input_token"])
+	}
+	content := masked["content"].(map[string]any)
+	if content["access_token"] != maskedValue || content["refresh_token"] != maskedValue {
+		t.Fatalf("expected tokens masked, got %v", content)
+	}
+	if content["email"] != "a@example.com" {
+		t.Fatalf("expected email kept, got %v", content["email"])
+	}
+	entry := masked["api_key_entries"].([]any)[0].(map[string]any)
+	if entry["api-key"] != maskedValue {
+		t.Fatalf("expected nested api key masked, got %v", entry["api-key"])
+	}
+}
+
+func TestDiffReportsChangedFieldsOnly(t *testing.T) {
+	diff := Diff(
+		map[string]any{"name": "a", "active": true, "updated_at": "t1"},
+		map[string]any{"name": "b", "active": true, "updated_at": "t2", "note": "x"},
+	)
+	if len(diff) != 2 {
+		t.Fatalf("expected 2 changed fields, got %v", diff)
+	}
+	name := diff["name"].(map[string]any)
+	if name["before"] != "a" || name["after"] != "b" {
+		t.Fatalf("unexpected name diff: %v", name)
+	}
+	if _, ok := diff["note"]; !ok {
+		t.Fatalf("expected added field in diff")
+	}
+}
+
+func TestSnapshotMasksSensitiveSettings(t *testing.T) {
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), "audit.db"))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	rows := []models.Setting{
+		{Key: internalsettings.SMTPPasswordKey, Value: json.RawMessage(`"hunter2"`)},
+		{Key: internalsettings.AnomalyAlertWebhookURLKey, Value: json.RawMessage(`"https://hooks.example.com/T0/secret"`)},
+		{Key: internalsettings.SMTPHostKey, Value: json.RawMessage(`"smtp.example.com"`)},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create settings: %v", errCreate)
+	}
+
+	entity, ok := ResolveEntity("/v0/admin/settings/:key")
+	if !ok {
+		t.Fatalf("expected settings entity")
+	}
+	for _, key := range []string{internalsettings.SMTPPasswordKey, internalsettings.AnomalyAlertWebhookURLKey} {
+		snapshot := Snapshot(context.Background(), conn, entity, key)
+		if snapshot["value"] != maskedValue || snapshot["key"] != key {
+			t.Fatalf("expected %s value masked, got %v", key, snapshot)
+		}
+	}
+	if snapshot := Snapshot(context.Background(), conn, entity, internalsettings.SMTPHostKey); snapshot["value"] != "smtp.example.com" {
+		t.Fatalf("expected non-secret setting kept, got %v", snapshot)
+	}
+}
@@ -4,7 +4,12 @@ import (
 	"context"
 
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tracing"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // StatusCodeHook logs auth results with status-based severity.
@@ -25,6 +30,29 @@ func (h *StatusCodeHook) OnResult(ctx context.Context, result coreauth.Result) {
 		"model":    result.Model,
 		"success":  result.Success,
 	})
+	if requestID := requestlog.FromContext(ctx).RequestID(); requestID != "" {
+		entry = entry.WithField("request_id", requestID)
+	}
+
+	statusCode := 0
+	if result.Error != nil {
+		statusCode = result.Error.HTTPStatus
+	}
+	metrics.ObserveUpstreamResult(result.Provider, result.Model, result.Success, statusCode)
+	requestlog.FromContext(ctx).RecordResult(result)
+	span := tracing.RecordUpstream(ctx,
+		attribute.String("cpab.provider", result.Provider),
+		attribute.String("cpab.model", result.Model),
+		attribute.String("cpab.auth.id", result.AuthID),
+		attribute.Bool("cpab.upstream.success", result.Success),
+	)
+	if statusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+	if !result.Success {
+		span.SetStatus(codes.Error, "upstream request failed")
+	}
+	span.End()
 
 	if result.Success {
 		entry.Debug("request succeeded")
@@ -36,7 +64,6 @@ func (h *StatusCodeHook) OnResult(ctx context.Context, result coreauth.Result) {
 		return
 	}
 
-	statusCode := result.Error.HTTPStatus
 	entry = entry.WithField("status_code", statusCode)
 
 	switch {
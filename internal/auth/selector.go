@@ -18,10 +18,17 @@ import (
 	"github.com/gin-gonic/gin"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelaccess"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/ratelimit"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tracing"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -53,15 +60,43 @@ func NewSelector(db *gorm.DB) *Selector {
 
 // Pick implements coreauth.Selector.
 func (s *Selector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*coreauth.Auth) (*coreauth.Auth, error) {
-	_ = opts
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	spanCtx, span := tracing.Start(ctx, "selector.pick",
+		attribute.String("cpab.provider", provider),
+		attribute.String("cpab.model", model),
+	)
+	defer span.End()
+
+	selected, strategy, errPick := s.pick(spanCtx, provider, model, opts, auths)
+	outcome := selectionOutcome(selected, errPick)
+	metrics.ObserveSelection(provider, model, strategy, outcome)
+	span.SetAttributes(
+		attribute.String("cpab.selector.strategy", strategy),
+		attribute.String("cpab.selector.outcome", outcome),
+	)
+	if errPick != nil {
+		span.RecordError(errPick)
+		span.SetStatus(codes.Error, outcome)
+	}
+	if errPick == nil && selected != nil {
+		span.SetAttributes(attribute.String("cpab.auth.index", authIndexFor(selected)))
+		metrics.SetRelayTarget(ctx, provider, model)
+		tracing.MarkUpstreamStart(ctx)
+	}
+	return selected, errPick
+}
+
+// pick selects an auth and returns the name of the strategy it applied, or "" when it failed
+// before reaching one.
+func (s *Selector) pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*coreauth.Auth) (*coreauth.Auth, string, error) {
+	_ = opts
 
 	now := time.Now()
 	available, errAvailable := getAvailableAuths(auths, provider, model, now)
 	if errAvailable != nil {
-		return nil, errAvailable
+		return nil, "", errAvailable
 	}
 
 	var (
@@ -74,25 +109,38 @@ func (s *Selector) Pick(ctx context.Context, provider, model string, opts clipro
 		if okUser {
 			userGroupIDs, billUserGroupIDs, errLoad := s.loadUserGroups(ctx, userID)
 			if errLoad != nil {
-				return nil, newModelNotFoundError(provider, model)
+				return nil, "", newModelNotFoundError(provider, model)
+			}
+
+			overrides, errOverrides := modelaccess.Load(ctx, s.db, userID, now)
+			if errOverrides != nil {
+				return nil, "", newModelNotFoundError(provider, model)
+			}
+			if overrides.Denies(provider, model) {
+				return nil, "", newModelNotFoundError(provider, model)
 			}
+			// A per-user grant reaches the model even when none of the user's groups may.
+			granted := overrides.Allows(provider, model)
 
 			if mappingUserGroupIDs, okMapping := modelmapping.LookupUserGroupIDs(provider, model); okMapping {
 				mappingUserGroupIDs = mappingUserGroupIDs.Clean()
 				if len(mappingUserGroupIDs) > 0 {
 					selectedUserGroupID = selectFirstAllowedUserGroupID(mappingUserGroupIDs, userGroupIDs, billUserGroupIDs)
-					if selectedUserGroupID == nil {
-						return nil, newModelNotFoundError(provider, model)
+					if selectedUserGroupID == nil && !granted {
+						return nil, "", newModelNotFoundError(provider, model)
 					}
 				}
 			}
 
 			availableFiltered, idByKey, allowedByID, errFilter := s.filterAuthsByAuthGroupUserGroups(ctx, available, userGroupIDs, billUserGroupIDs)
 			if errFilter != nil {
-				return nil, newModelNotFoundError(provider, model)
+				return nil, "", newModelNotFoundError(provider, model)
 			}
 			if len(availableFiltered) == 0 {
-				return nil, newModelNotFoundError(provider, model)
+				if !granted {
+					return nil, "", newModelNotFoundError(provider, model)
+				}
+				availableFiltered = available
 			}
 			available = availableFiltered
 			authGroupIDByAuthKey = idByKey
@@ -100,7 +148,13 @@ func (s *Selector) Pick(ctx context.Context, provider, model string, opts clipro
 		}
 	}
 
+	available, errQuota := applyQuotaScheduling(available, provider, model, internalsettings.LoadQuotaSchedulingPolicy(), now)
+	if errQuota != nil {
+		return nil, "", errQuota
+	}
+
 	mappingID, selector := s.loadModelMappingSelector(ctx, provider, model)
+	strategy := modelMappingSelectorName(selector)
 	var selected *coreauth.Auth
 	var errPick error
 	switch selector {
@@ -112,10 +166,10 @@ func (s *Selector) Pick(ctx context.Context, provider, model string, opts clipro
 		selected = s.pickRoundRobin(available)
 	}
 	if errPick != nil {
-		return nil, errPick
+		return nil, strategy, errPick
 	}
 	if errLimit := s.applyRateLimit(ctx, provider, model, selected); errLimit != nil {
-		return nil, errLimit
+		return nil, strategy, errLimit
 	}
 
 	if selected != nil && authGroupIDByAuthKey != nil {
@@ -138,7 +192,29 @@ func (s *Selector) Pick(ctx context.Context, provider, model string, opts clipro
 		}
 		applyBillingUserGroupIDToContext(ctx, billingUserGroupID)
 	}
-	return selected, nil
+	return selected, strategy, nil
+}
+
+func selectionOutcome(selected *coreauth.Auth, errPick error) string {
+	if errPick == nil {
+		if selected == nil {
+			return "auth_unavailable"
+		}
+		return "selected"
+	}
+	var limitErr *rateLimitError
+	if errors.As(errPick, &limitErr) {
+		return "rate_limited"
+	}
+	var cooldownErr *modelCooldownError
+	if errors.As(errPick, &cooldownErr) {
+		return "cooldown"
+	}
+	var authErr *coreauth.Error
+	if errors.As(errPick, &authErr) && authErr.Code != "" {
+		return authErr.Code
+	}
+	return "error"
 }
 
 func newModelNotFoundError(_ string, _ string) error {
@@ -351,6 +427,10 @@ func (s *Selector) applyRateLimit(ctx context.Context, provider, model string, s
 	if decision.Limit <= 0 {
 		return nil
 	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("cpab.ratelimit.scope", decision.Scope.String()),
+		attribute.Int("cpab.ratelimit.limit", decision.Limit),
+	)
 	key := ratelimit.KeyForDecision(userID, decision)
 	if key == "" {
 		return nil
@@ -362,6 +442,7 @@ func (s *Selector) applyRateLimit(ctx context.Context, provider, model string, s
 	}
 	if !result.Allowed {
 		resetIn := result.Reset.Sub(time.Now())
+		metrics.ObserveRateLimitRejection(decision.Scope.String())
 		return newRateLimitError(resetIn)
 	}
 	return nil
@@ -608,6 +689,17 @@ func (s *Selector) loadModelMappingSelector(_ context.Context, provider, model s
 	return mappingID, normalizeModelMappingSelector(selector)
 }
 
+func modelMappingSelectorName(value int) string {
+	switch value {
+	case modelMappingSelectorFillFirst:
+		return "fill_first"
+	case modelMappingSelectorStick:
+		return "stick"
+	default:
+		return "round_robin"
+	}
+}
+
 func normalizeModelMappingSelector(value int) int {
 	switch value {
 	case modelMappingSelectorRoundRobin, modelMappingSelectorFillFirst, modelMappingSelectorStick:
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+)
+
+// applyQuotaScheduling narrows candidates using the polled quota of each auth.
+// Auths with an exhausted window, and in skip mode auths below the threshold, are cooling
+// until the window resets. In deprioritize mode auths below the threshold are kept only
+// when no auth with enough quota remains.
+func applyQuotaScheduling(available []*coreauth.Auth, provider, model string, policy internalsettings.QuotaSchedulingPolicy, now time.Time) ([]*coreauth.Auth, error) {
+	if policy.Mode == internalsettings.QuotaSchedulingOff || len(available) == 0 {
+		return available, nil
+	}
+
+	healthy := make([]*coreauth.Auth, 0, len(available))
+	var low []*coreauth.Auth
+	var earliest time.Time
+	resetKnown := true
+	for _, candidate := range available {
+		if candidate == nil {
+			continue
+		}
+		standing := quota.Assess(strings.TrimSpace(candidate.ID), model, policy.Threshold, now)
+		if !standing.Low {
+			healthy = append(healthy, candidate)
+			continue
+		}
+		if !standing.Exhausted && policy.Mode == internalsettings.QuotaSchedulingDeprioritize {
+			low = append(low, candidate)
+			continue
+		}
+		log.Debugf("selector: auth %s cooling on quota for %s/%s (remaining=%.3f)", candidate.ID, provider, model, standing.Remaining)
+		if standing.ResetAt == nil {
+			resetKnown = false
+			continue
+		}
+		if earliest.IsZero() || standing.ResetAt.Before(earliest) {
+			earliest = *standing.ResetAt
+		}
+	}
+	if len(healthy) > 0 {
+		return healthy, nil
+	}
+	if len(low) > 0 {
+		return low, nil
+	}
+	if !resetKnown || earliest.IsZero() {
+		return nil, &coreauth.Error{Code: "auth_unavailable", Message: "no auth available"}
+	}
+	resetIn := earliest.Sub(now)
+	if resetIn < 0 {
+		resetIn = 0
+	}
+	return nil, newModelCooldownError(model, provider, resetIn)
+}
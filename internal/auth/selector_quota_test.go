@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+func TestApplyQuotaSchedulingPrefersAuthsWithQuota(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	soon, later, past := now.Add(10*time.Minute), now.Add(2*time.Hour), now.Add(-time.Minute)
+	quota.ReplaceWindows(map[string][]quota.Window{
+		"low":       {{Family: "opus", Window: "7d", RemainingFraction: 0.01, ResetAt: &later}},
+		"exhausted": {{Family: quota.AllModels, Window: "5h", RemainingFraction: 0, ResetAt: &soon}},
+		"healthy":   {{Family: quota.AllModels, Window: "5h", RemainingFraction: 0.6, ResetAt: &soon}},
+		"replenished": {
+			{Family: quota.AllModels, Window: "5h", RemainingFraction: 0, ResetAt: &past},
+		},
+	})
+	t.Cleanup(func() { quota.ReplaceWindows(nil) })
+
+	auths := func(ids ...string) []*coreauth.Auth {
+		out := make([]*coreauth.Auth, 0, len(ids))
+		for _, id := range ids {
+			out = append(out, &coreauth.Auth{ID: id})
+		}
+		return out
+	}
+	deprioritize := internalsettings.QuotaSchedulingPolicy{Mode: internalsettings.QuotaSchedulingDeprioritize, Threshold: 0.05}
+	skip := internalsettings.QuotaSchedulingPolicy{Mode: internalsettings.QuotaSchedulingSkip, Threshold: 0.05}
+
+	got, errApply := applyQuotaScheduling(auths("low", "exhausted", "healthy", "replenished"), "claude", "claude-opus-4-5", deprioritize, now)
+	if errApply != nil || len(got) != 2 || got[0].ID != "healthy" || got[1].ID != "replenished" {
+		t.Fatalf("expected healthy and replenished auths, got %v, %v", got, errApply)
+	}
+
+	got, errApply = applyQuotaScheduling(auths("low", "exhausted"), "claude", "claude-opus-4-5", deprioritize, now)
+	if errApply != nil || len(got) != 1 || got[0].ID != "low" {
+		t.Fatalf("expected low auth as fallback, got %v, %v", got, errApply)
+	}
+
+	// The sonnet family window of "low" does not limit other models.
+	got, errApply = applyQuotaScheduling(auths("low"), "claude", "claude-sonnet-4-5", skip, now)
+	if errApply != nil || len(got) != 1 {
+		t.Fatalf("expected unrelated family to be ignored, got %v, %v", got, errApply)
+	}
+
+	_, errApply = applyQuotaScheduling(auths("low", "exhausted"), "claude", "claude-opus-4-5", skip, now)
+	var cooldownErr *modelCooldownError
+	if !errors.As(errApply, &cooldownErr) {
+		t.Fatalf("expected cooldown error, got %v", errApply)
+	}
+	if cooldownErr.resetIn != 10*time.Minute {
+		t.Fatalf("expected cooldown until the earliest reset, got %s", cooldownErr.resetIn)
+	}
+
+	off := internalsettings.QuotaSchedulingPolicy{Mode: internalsettings.QuotaSchedulingOff}
+	got, errApply = applyQuotaScheduling(auths("exhausted"), "claude", "claude-opus-4-5", off, now)
+	if errApply != nil || len(got) != 1 {
+		t.Fatalf("expected off mode to keep every auth, got %v, %v", got, errApply)
+	}
+}
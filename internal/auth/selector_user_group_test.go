@@ -186,6 +186,73 @@ func TestSelectorFiltersAuthsByAuthGroupUserGroupRestriction(t *testing.T) {
 	}
 }
 
+func TestSelectorHonorsUserModelOverrides(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	now := time.Now().UTC()
+
+	groupTrial := models.UserGroup{Name: "trial", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&groupTrial).Error; errCreate != nil {
+		t.Fatalf("create user group: %v", errCreate)
+	}
+	user := models.User{Username: "user1", Password: "hashed", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	authRecord := models.Auth{Key: "auth-1", Content: datatypes.JSON([]byte(`{"type":"openai"}`)), CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&authRecord).Error; errCreate != nil {
+		t.Fatalf("create auth record: %v", errCreate)
+	}
+
+	restricted := models.ModelMapping{Provider: "openai", ModelName: "gpt-5", NewModelName: "gpt-5", IsEnabled: true, UserGroupID: models.UserGroupIDs{&groupTrial.ID}, CreatedAt: now, UpdatedAt: now}
+	open := models.ModelMapping{Provider: "openai", ModelName: "gpt-4", NewModelName: "gpt-4", IsEnabled: true, CreatedAt: now, UpdatedAt: now}
+	for _, mapping := range []*models.ModelMapping{&restricted, &open} {
+		if errCreate := conn.Create(mapping).Error; errCreate != nil {
+			t.Fatalf("create model mapping: %v", errCreate)
+		}
+	}
+	modelmapping.StoreModelMappings(now, []models.ModelMapping{restricted, open})
+
+	selector := NewSelector(conn)
+	selector.rateLimiter = nil
+	selector.resolveRateLimit = nil
+
+	ctx, _ := buildTestGinContext("/v1/chat/completions", user.ID)
+	auths := []*coreauth.Auth{{ID: authRecord.Key, Status: coreauth.StatusActive}}
+	if _, errPick := selector.Pick(ctx, "openai", "gpt-5", cliproxyexecutor.Options{}, auths); errPick == nil {
+		t.Fatalf("expected restricted model blocked without override")
+	}
+
+	expiresAt := now.Add(time.Hour)
+	grant := models.UserModelOverride{UserID: user.ID, Model: "GPT-5", Effect: models.ModelAccessAllow, ExpiresAt: &expiresAt}
+	deny := models.UserModelOverride{UserID: user.ID, Model: "gpt-4", Effect: models.ModelAccessDeny}
+	for _, override := range []*models.UserModelOverride{&grant, &deny} {
+		if errCreate := conn.Create(override).Error; errCreate != nil {
+			t.Fatalf("create override: %v", errCreate)
+		}
+	}
+	if _, errPick := selector.Pick(ctx, "openai", "gpt-5", cliproxyexecutor.Options{}, auths); errPick != nil {
+		t.Fatalf("expected granted model to be selectable, got %v", errPick)
+	}
+	if _, errPick := selector.Pick(ctx, "openai", "gpt-4", cliproxyexecutor.Options{}, auths); errPick == nil {
+		t.Fatalf("expected denied model blocked")
+	}
+
+	if errUpdate := conn.Model(&grant).Update("expires_at", now.Add(-time.Minute)).Error; errUpdate != nil {
+		t.Fatalf("expire grant: %v", errUpdate)
+	}
+	if _, errPick := selector.Pick(ctx, "openai", "gpt-5", cliproxyexecutor.Options{}, auths); errPick == nil {
+		t.Fatalf("expected expired grant to stop applying")
+	}
+}
+
 func buildTestGinContext(path string, userID uint64) (context.Context, *gin.Context) {
 	w := httptest.NewRecorder()
 	ginCtx, _ := gin.CreateTestContext(w)
@@ -0,0 +1,120 @@
+// Package authfiles parses and stores uploaded CLIProxyAPI auth JSON files.
+package authfiles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNotJSON indicates the file does not carry a .json extension.
+	ErrNotJSON = errors.New("file must be json")
+	// ErrEmpty indicates the file has no content.
+	ErrEmpty = errors.New("empty json file")
+	// ErrInvalidJSON indicates the file content is not a JSON object.
+	ErrInvalidJSON = errors.New("invalid json")
+	// ErrMissingKey indicates no auth key could be derived from the file.
+	ErrMissingKey = errors.New("missing key")
+)
+
+// File is a parsed auth file ready to be stored.
+type File struct {
+	Key      string         // Auth key derived from id, key, or the file name.
+	ProxyURL string         // Proxy URL declared by the file, if any.
+	Payload  map[string]any // Normalized JSON payload.
+}
+
+// Parse validates an auth file and derives its key, proxy URL, and type.
+func Parse(filename string, data []byte) (File, error) {
+	if !strings.EqualFold(filepath.Ext(filename), ".json") {
+		return File{}, ErrNotJSON
+	}
+	if len(data) == 0 {
+		return File{}, ErrEmpty
+	}
+
+	var payload map[string]any
+	if errUnmarshal := json.Unmarshal(data, &payload); errUnmarshal != nil || payload == nil {
+		return File{}, ErrInvalidJSON
+	}
+
+	key := ""
+	if idValue, okID := payload["id"].(string); okID {
+		key = strings.TrimSpace(idValue)
+	}
+	if key == "" {
+		if keyValue, okKey := payload["key"].(string); okKey {
+			key = strings.TrimSpace(keyValue)
+		}
+	}
+	if key == "" {
+		key = strings.TrimSpace(filename)
+	}
+	if key == "" {
+		return File{}, ErrMissingKey
+	}
+
+	if _, okType := payload["type"]; !okType {
+		if provider, okProvider := payload["provider"].(string); okProvider && strings.TrimSpace(provider) != "" {
+			payload["type"] = strings.TrimSpace(provider)
+		} else if metadataValue, okMetadata := payload["metadata"].(map[string]any); okMetadata {
+			if typeValue, okType := metadataValue["type"].(string); okType && strings.TrimSpace(typeValue) != "" {
+				payload["type"] = strings.TrimSpace(typeValue)
+			}
+		}
+	}
+
+	proxyURL := ""
+	if proxyValue, okProxy := payload["proxy_url"].(string); okProxy {
+		proxyURL = strings.TrimSpace(proxyValue)
+	}
+
+	return File{Key: key, ProxyURL: proxyURL, Payload: payload}, nil
+}
+
+// DefaultGroupIDs returns the default auth group as a group list, or nil when none is marked default.
+func DefaultGroupIDs(ctx context.Context, db *gorm.DB) (models.AuthGroupIDs, error) {
+	var defaultGroup models.AuthGroup
+	errFind := db.WithContext(ctx).Where("is_default = ?", true).First(&defaultGroup).Error
+	if errors.Is(errFind, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if errFind != nil {
+		return nil, errFind
+	}
+	defaultGroupID := defaultGroup.ID
+	return models.AuthGroupIDs{&defaultGroupID}, nil
+}
+
+// Upsert stores the file as an available auth, replacing the content of an existing auth with the same key.
+func Upsert(ctx context.Context, db *gorm.DB, file File, authGroupIDs models.AuthGroupIDs, proxyURL string, now time.Time) error {
+	contentBytes, errMarshal := json.Marshal(file.Payload)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	auth := models.Auth{
+		Key:         file.Key,
+		AuthGroupID: authGroupIDs,
+		ProxyURL:    proxyURL,
+		Content:     datatypes.JSON(contentBytes),
+		IsAvailable: true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"content":    auth.Content,
+			"updated_at": now,
+		}),
+	}).Create(&auth).Error
+}
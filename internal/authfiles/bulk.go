@@ -0,0 +1,255 @@
+package authfiles
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Bulk actions applied to every auth matching a Filter.
+const (
+	// ActionSetGroup replaces, extends, or trims the auth groups.
+	ActionSetGroup = "set_group"
+	// ActionSetProxy sets the proxy URL; an empty URL clears it.
+	ActionSetProxy = "set_proxy"
+	// ActionSetPriority sets the selection priority.
+	ActionSetPriority = "set_priority"
+	// ActionEnable marks auths available.
+	ActionEnable = "enable"
+	// ActionDisable marks auths unavailable.
+	ActionDisable = "disable"
+	// ActionDelete removes auths.
+	ActionDelete = "delete"
+)
+
+// Group modes for ActionSetGroup.
+const (
+	// GroupModeSet replaces the auth groups.
+	GroupModeSet = "set"
+	// GroupModeAdd appends groups not yet present.
+	GroupModeAdd = "add"
+	// GroupModeRemove drops the listed groups.
+	GroupModeRemove = "remove"
+)
+
+var (
+	// ErrUnknownAction indicates an unsupported bulk action.
+	ErrUnknownAction = errors.New("unknown bulk action")
+	// ErrUnknownGroupMode indicates an unsupported group mode.
+	ErrUnknownGroupMode = errors.New("unknown group mode")
+)
+
+// Filter selects auths for bulk operations and exports.
+type Filter struct {
+	IDs         []uint64 // Explicit auth IDs.
+	Key         string   // Case-insensitive key substring.
+	AuthGroupID *uint64  // Auths belonging to this group.
+	Type        string   // Auth content type.
+	IsAvailable *bool    // Availability flag.
+}
+
+// Empty reports whether the filter selects nothing in particular, which would match every auth.
+func (f Filter) Empty() bool {
+	return len(f.IDs) == 0 && strings.TrimSpace(f.Key) == "" && f.AuthGroupID == nil &&
+		strings.TrimSpace(f.Type) == "" && f.IsAvailable == nil
+}
+
+// Find returns the auths matching the filter ordered by ID.
+func Find(ctx context.Context, db *gorm.DB, f Filter) ([]models.Auth, error) {
+	q := db.WithContext(ctx).Model(&models.Auth{})
+	if len(f.IDs) > 0 {
+		q = q.Where("id IN ?", f.IDs)
+	}
+	if key := strings.TrimSpace(f.Key); key != "" {
+		q = q.Where(dbutil.CaseInsensitiveLikeExpr(db, "key"), dbutil.NormalizeLikePattern(db, "%"+key+"%"))
+	}
+	if f.AuthGroupID != nil {
+		q = q.Where(dbutil.JSONArrayContainsExpr(db, "auth_group_id"), dbutil.JSONArrayContainsValue(db, *f.AuthGroupID))
+	}
+	if authType := strings.TrimSpace(f.Type); authType != "" {
+		q = q.Where(dbutil.JSONExtractTextExpr(db, "content", "type")+" = ?", authType)
+	}
+	if f.IsAvailable != nil {
+		q = q.Where("is_available = ?", *f.IsAvailable)
+	}
+	var rows []models.Auth
+	if errFind := q.Order("id ASC").Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	return rows, nil
+}
+
+// BulkAction describes the change applied to every selected auth.
+type BulkAction struct {
+	Action       string              // One of the Action constants.
+	GroupMode    string              // Group mode for ActionSetGroup; defaults to GroupModeSet.
+	AuthGroupIDs models.AuthGroupIDs // Groups for ActionSetGroup.
+	ProxyURL     string              // Proxy URL for ActionSetProxy.
+	Priority     int                 // Priority for ActionSetPriority.
+}
+
+// Validate normalizes the action and reports unsupported values.
+func (a *BulkAction) Validate() error {
+	a.Action = strings.ToLower(strings.TrimSpace(a.Action))
+	switch a.Action {
+	case ActionSetGroup:
+		a.GroupMode = strings.ToLower(strings.TrimSpace(a.GroupMode))
+		if a.GroupMode == "" {
+			a.GroupMode = GroupModeSet
+		}
+		if a.GroupMode != GroupModeSet && a.GroupMode != GroupModeAdd && a.GroupMode != GroupModeRemove {
+			return ErrUnknownGroupMode
+		}
+	case ActionSetProxy:
+		a.ProxyURL = strings.TrimSpace(a.ProxyURL)
+	case ActionSetPriority, ActionEnable, ActionDisable, ActionDelete:
+	default:
+		return ErrUnknownAction
+	}
+	return nil
+}
+
+// ApplyBulk applies a validated action to rows in one transaction and returns how many changed.
+func ApplyBulk(ctx context.Context, db *gorm.DB, rows []models.Auth, action BulkAction, now time.Time) (int, error) {
+	changed := 0
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if action.Action == ActionDelete {
+			ids := make([]uint64, 0, len(rows))
+			for _, row := range rows {
+				ids = append(ids, row.ID)
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			res := tx.Where("id IN ?", ids).Delete(&models.Auth{})
+			changed = int(res.RowsAffected)
+			return res.Error
+		}
+		for _, row := range rows {
+			updates := map[string]any{"updated_at": now}
+			switch action.Action {
+			case ActionSetGroup:
+				next := regroup(row.AuthGroupID.Clean(), action.AuthGroupIDs.Clean(), action.GroupMode)
+				if equalGroups(row.AuthGroupID.Clean(), next) {
+					continue
+				}
+				updates["auth_group_id"] = next
+			case ActionSetProxy:
+				if row.ProxyURL == action.ProxyURL {
+					continue
+				}
+				updates["proxy_url"] = action.ProxyURL
+			case ActionSetPriority:
+				if row.Priority == action.Priority {
+					continue
+				}
+				updates["priority"] = action.Priority
+			case ActionEnable, ActionDisable:
+				available := action.Action == ActionEnable
+				if row.IsAvailable == available {
+					continue
+				}
+				updates["is_available"] = available
+			}
+			if errUpdate := tx.Model(&models.Auth{}).Where("id = ?", row.ID).Updates(updates).Error; errUpdate != nil {
+				return errUpdate
+			}
+			changed++
+		}
+		return nil
+	})
+	if errTx != nil {
+		return 0, errTx
+	}
+	return changed, nil
+}
+
+// regroup applies a group mode to an auth's groups, keeping the existing order.
+func regroup(current, target models.AuthGroupIDs, mode string) models.AuthGroupIDs {
+	switch mode {
+	case GroupModeAdd:
+		merged := make(models.AuthGroupIDs, 0, len(current)+len(target))
+		merged = append(merged, current...)
+		merged = append(merged, target...)
+		return merged.Clean()
+	case GroupModeRemove:
+		drop := make(map[uint64]struct{}, len(target))
+		for _, id := range target.Values() {
+			drop[id] = struct{}{}
+		}
+		kept := make(models.AuthGroupIDs, 0, len(current))
+		for _, id := range current {
+			if _, ok := drop[*id]; !ok {
+				kept = append(kept, id)
+			}
+		}
+		return kept.Clean()
+	default:
+		return target.Clean()
+	}
+}
+
+// equalGroups reports whether two cleaned group lists are identical in order.
+func equalGroups(a, b models.AuthGroupIDs) bool {
+	av, bv := a.Values(), b.Values()
+	if len(av) != len(bv) {
+		return false
+	}
+	for i := range av {
+		if av[i] != bv[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteZip writes each auth's content as an indented JSON file named after its key, in a form
+// that Import accepts back.
+func WriteZip(w io.Writer, rows []models.Auth) error {
+	archive := zip.NewWriter(w)
+	used := make(map[string]int, len(rows))
+	for _, row := range rows {
+		name := zipEntryName(row.Key)
+		if n := used[name]; n > 0 {
+			name = fmt.Sprintf("%s-%d.json", strings.TrimSuffix(name, ".json"), n)
+		}
+		used[name]++
+
+		var content bytes.Buffer
+		if errIndent := json.Indent(&content, row.Content, "", "  "); errIndent != nil {
+			content.Reset()
+			content.Write(row.Content)
+		}
+		entry, errCreate := archive.Create(name)
+		if errCreate != nil {
+			return errCreate
+		}
+		if _, errWrite := entry.Write(content.Bytes()); errWrite != nil {
+			return errWrite
+		}
+	}
+	return archive.Close()
+}
+
+// zipEntryName turns an auth key into a flat .json file name.
+func zipEntryName(key string) string {
+	name := path.Base(strings.ReplaceAll(strings.TrimSpace(key), "\\", "/"))
+	if name == "" || name == "." || name == "/" || name == ".." {
+		name = "auth"
+	}
+	if !strings.EqualFold(path.Ext(name), ".json") {
+		name += ".json"
+	}
+	return name
+}
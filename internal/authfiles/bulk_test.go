@@ -0,0 +1,228 @@
+package authfiles
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), name))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func createAuth(t *testing.T, conn *gorm.DB, key, content string, groups ...uint64) models.Auth {
+	t.Helper()
+	ids := make(models.AuthGroupIDs, 0, len(groups))
+	for i := range groups {
+		ids = append(ids, &groups[i])
+	}
+	row := models.Auth{Key: key, AuthGroupID: ids, Content: datatypes.JSON(content), IsAvailable: true}
+	if errCreate := conn.Create(&row).Error; errCreate != nil {
+		t.Fatalf("create auth %s: %v", key, errCreate)
+	}
+	return row
+}
+
+func TestBulkActionsByFilter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	conn := openTestDB(t, "bulk.db")
+
+	createAuth(t, conn, "codex-a.json", `{"type":"codex"}`, 1)
+	createAuth(t, conn, "codex-b.json", `{"type":"codex"}`, 1, 2)
+	createAuth(t, conn, "claude-a.json", `{"type":"claude"}`, 1)
+
+	rows, errFind := Find(ctx, conn, Filter{Type: "codex"})
+	if errFind != nil {
+		t.Fatalf("find: %v", errFind)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 codex auths, got %d", len(rows))
+	}
+
+	action := BulkAction{Action: "SET_GROUP", GroupMode: "add", AuthGroupIDs: models.AuthGroupIDs{ptr(2), ptr(3)}}
+	if errValidate := action.Validate(); errValidate != nil {
+		t.Fatalf("validate: %v", errValidate)
+	}
+	changed, errApply := ApplyBulk(ctx, conn, rows, action, now)
+	if errApply != nil {
+		t.Fatalf("apply: %v", errApply)
+	}
+	if changed != 2 {
+		t.Fatalf("expected 2 changed, got %d", changed)
+	}
+	var updated models.Auth
+	if errLoad := conn.Where("key = ?", "codex-b.json").First(&updated).Error; errLoad != nil {
+		t.Fatalf("load: %v", errLoad)
+	}
+	if got := updated.AuthGroupID.Values(); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Fatalf("unexpected groups %v", got)
+	}
+
+	disable := BulkAction{Action: ActionDisable}
+	if changed, errApply = ApplyBulk(ctx, conn, rows, disable, now); errApply != nil || changed != 2 {
+		t.Fatalf("disable: changed=%d err=%v", changed, errApply)
+	}
+	unavailable := false
+	rows, _ = Find(ctx, conn, Filter{IsAvailable: &unavailable})
+	if changed, errApply = ApplyBulk(ctx, conn, rows, BulkAction{Action: ActionDelete}, now); errApply != nil || changed != 2 {
+		t.Fatalf("delete: changed=%d err=%v", changed, errApply)
+	}
+	var remaining int64
+	conn.Model(&models.Auth{}).Count(&remaining)
+	if remaining != 1 {
+		t.Fatalf("expected 1 auth left, got %d", remaining)
+	}
+
+	if errValidate := (&BulkAction{Action: "explode"}).Validate(); !errors.Is(errValidate, ErrUnknownAction) {
+		t.Fatalf("expected unknown action, got %v", errValidate)
+	}
+}
+
+func TestWriteZipDedupesNames(t *testing.T) {
+	rows := []models.Auth{
+		{Key: "dir/a.json", Content: datatypes.JSON(`{"type":"codex"}`)},
+		{Key: "other/a.json", Content: datatypes.JSON(`{"type":"claude"}`)},
+		{Key: "b", Content: datatypes.JSON(`{"type":"qwen"}`)},
+	}
+	var buf bytes.Buffer
+	if errZip := WriteZip(&buf, rows); errZip != nil {
+		t.Fatalf("write zip: %v", errZip)
+	}
+	reader, errOpen := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if errOpen != nil {
+		t.Fatalf("open zip: %v", errOpen)
+	}
+	want := []string{"a.json", "a-1.json", "b.json"}
+	if len(reader.File) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(reader.File))
+	}
+	for i, file := range reader.File {
+		if file.Name != want[i] {
+			t.Fatalf("entry %d: expected %s, got %s", i, want[i], file.Name)
+		}
+	}
+}
+
+func TestCompleteReauthPreservesTarget(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t, "reauth.db")
+
+	target := createAuth(t, conn, "codex-old.json", `{"type":"codex","refresh_token":"stale"}`, 4)
+	if errUpdate := conn.Model(&models.Auth{}).Where("id = ?", target.ID).Updates(map[string]any{
+		"proxy_url":    "socks5://proxy:1080",
+		"priority":     7,
+		"is_available": false,
+		"updated_at":   time.Now().UTC().Add(-time.Hour),
+	}).Error; errUpdate != nil {
+		t.Fatalf("prepare target: %v", errUpdate)
+	}
+
+	sessions := NewReauthSessions()
+	session, errBegin := sessions.Begin(target.ID, "codex", time.Now().UTC().Add(-time.Second))
+	if errBegin != nil {
+		t.Fatalf("begin: %v", errBegin)
+	}
+	if _, errPending := CompleteReauth(ctx, conn, session, "", time.Now().UTC()); !errors.Is(errPending, ErrReauthPending) {
+		t.Fatalf("expected pending, got %v", errPending)
+	}
+
+	createAuth(t, conn, "codex-new.json", `{"type":"codex","refresh_token":"fresh"}`)
+	updated, errComplete := CompleteReauth(ctx, conn, session, "", time.Now().UTC())
+	if errComplete != nil {
+		t.Fatalf("complete: %v", errComplete)
+	}
+	if updated.ID != target.ID || updated.Key != "codex-old.json" {
+		t.Fatalf("target identity changed: %+v", updated)
+	}
+
+	var stored models.Auth
+	if errLoad := conn.First(&stored, target.ID).Error; errLoad != nil {
+		t.Fatalf("load target: %v", errLoad)
+	}
+	if ContentType(stored.Content) != "codex" || !bytes.Contains(stored.Content, []byte("fresh")) {
+		t.Fatalf("content not replaced: %s", stored.Content)
+	}
+	if !stored.IsAvailable || stored.ProxyURL != "socks5://proxy:1080" || stored.Priority != 7 {
+		t.Fatalf("settings not preserved: %+v", stored)
+	}
+	if got := stored.AuthGroupID.Values(); len(got) != 1 || got[0] != 4 {
+		t.Fatalf("groups not preserved: %v", got)
+	}
+	var count int64
+	conn.Model(&models.Auth{}).Where("key = ?", "codex-new.json").Count(&count)
+	if count != 0 {
+		t.Fatalf("expected issued auth to be removed")
+	}
+}
+
+func TestCompleteReauthRejectsAmbiguousSource(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t, "reauth-ambiguous.db")
+
+	target := createAuth(t, conn, "codex-old.json", `{"type":"codex","refresh_token":"stale"}`)
+	session, errBegin := NewReauthSessions().Begin(target.ID, "codex", time.Now().UTC().Add(-time.Second))
+	if errBegin != nil {
+		t.Fatalf("begin: %v", errBegin)
+	}
+	createAuth(t, conn, "codex-new.json", `{"type":"codex","refresh_token":"fresh"}`)
+	createAuth(t, conn, "codex-other.json", `{"type":"codex","refresh_token":"unrelated"}`)
+
+	if _, errComplete := CompleteReauth(ctx, conn, session, "", time.Now().UTC()); !errors.Is(errComplete, ErrReauthAmbiguous) {
+		t.Fatalf("expected ambiguous source, got %v", errComplete)
+	}
+	var count int64
+	conn.Model(&models.Auth{}).Where("key IN ?", []string{"codex-new.json", "codex-other.json"}).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected candidate auths to be kept, got %d", count)
+	}
+
+	if _, errComplete := CompleteReauth(ctx, conn, session, "codex-new.json", time.Now().UTC()); errComplete != nil {
+		t.Fatalf("complete with source key: %v", errComplete)
+	}
+	conn.Model(&models.Auth{}).Where("key = ?", "codex-other.json").Count(&count)
+	if count != 1 {
+		t.Fatalf("expected unrelated auth to be kept")
+	}
+}
+
+func TestStoredCredentialIssue(t *testing.T) {
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		content string
+		invalid bool
+	}{
+		{`{"type":"codex","refresh_token":"r","expired":"2026-01-01T00:00:00Z"}`, false},
+		{`{"type":"codex","expired":"2026-01-01T00:00:00Z"}`, true},
+		{`{"type":"codex","expired":"2026-01-03T00:00:00Z"}`, false},
+		{`{"type":"gemini","token":{"expiry":"2026-01-01T00:00:00Z"}}`, true},
+		{`{"type":"gemini","token":{"refresh_token":"r","expiry":"2026-01-01T00:00:00Z"}}`, false},
+		{`{"type":"openai-compatibility","expired":"2026-01-01T00:00:00Z"}`, false},
+	}
+	for i, tc := range cases {
+		if got := StoredCredentialIssue(datatypes.JSON(tc.content), now) != ""; got != tc.invalid {
+			t.Fatalf("case %d: expected invalid=%v, got %v", i, tc.invalid, got)
+		}
+	}
+}
+
+func ptr(v uint64) *uint64 {
+	return &v
+}
@@ -0,0 +1,239 @@
+package authfiles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// reauthSessionTTL bounds how long a re-authorization may take.
+const reauthSessionTTL = 30 * time.Minute
+
+var (
+	// ErrReauthUnsupported indicates the auth type has no OAuth requester.
+	ErrReauthUnsupported = errors.New("auth type does not support re-authorization")
+	// ErrReauthNotStarted indicates no live re-authorization session exists for the auth.
+	ErrReauthNotStarted = errors.New("re-authorization not started")
+	// ErrReauthPending indicates no new credentials were issued since the session started.
+	ErrReauthPending = errors.New("re-authorization not completed")
+	// ErrReauthSourceInvalid indicates the named source auth cannot replace the target.
+	ErrReauthSourceInvalid = errors.New("invalid re-authorization source")
+	// ErrReauthAmbiguous indicates several auths were issued since the session started.
+	ErrReauthAmbiguous = errors.New("several credentials were issued since re-authorization started; specify source_key")
+)
+
+// tokenEndpoints maps auth types to the admin OAuth requester that issues their credentials.
+var tokenEndpoints = map[string]string{
+	"claude":      "/v0/admin/tokens/anthropic",
+	"gemini":      "/v0/admin/tokens/gemini",
+	"gemini-cli":  "/v0/admin/tokens/gemini",
+	"codex":       "/v0/admin/tokens/codex",
+	"antigravity": "/v0/admin/tokens/antigravity",
+	"qwen":        "/v0/admin/tokens/qwen",
+	"iflow":       "/v0/admin/tokens/iflow",
+}
+
+// TokenEndpoint returns the admin OAuth requester for an auth type.
+func TokenEndpoint(authType string) (string, bool) {
+	endpoint, ok := tokenEndpoints[strings.ToLower(strings.TrimSpace(authType))]
+	return endpoint, ok
+}
+
+// ContentType returns the type recorded in an auth's content.
+func ContentType(content datatypes.JSON) string {
+	var payload map[string]any
+	if errUnmarshal := json.Unmarshal(content, &payload); errUnmarshal != nil {
+		return ""
+	}
+	authType, _ := payload["type"].(string)
+	return strings.TrimSpace(authType)
+}
+
+// invalidRefreshMarkers are lower-case fragments of upstream errors for revoked or invalid refresh tokens.
+var invalidRefreshMarkers = []string{
+	"invalid_grant",
+	"invalid_refresh_token",
+	"refresh_token_reused",
+	"refresh token is invalid",
+	"refresh token has expired",
+	"refresh token not found",
+	"token has been expired or revoked",
+	"revoked",
+}
+
+// RuntimeCredentialIssue explains why the runtime state of an auth suggests its refresh token is
+// no longer accepted, or returns an empty string.
+func RuntimeCredentialIssue(auth *coreauth.Auth) string {
+	if auth == nil || auth.LastError == nil {
+		return ""
+	}
+	message := strings.ToLower(auth.LastError.Message)
+	for _, marker := range invalidRefreshMarkers {
+		if strings.Contains(message, marker) {
+			return auth.LastError.Message
+		}
+	}
+	if auth.LastError.HTTPStatus == 401 {
+		if auth.LastError.Message != "" {
+			return auth.LastError.Message
+		}
+		return "upstream rejected credentials (401)"
+	}
+	return ""
+}
+
+// StoredCredentialIssue explains why stored OAuth content can no longer be used, or returns an
+// empty string: the access token has expired and there is no refresh token to renew it.
+func StoredCredentialIssue(content datatypes.JSON, now time.Time) string {
+	var payload map[string]any
+	if errUnmarshal := json.Unmarshal(content, &payload); errUnmarshal != nil {
+		return ""
+	}
+	token, _ := payload["token"].(map[string]any)
+	if _, ok := TokenEndpoint(ContentType(content)); !ok {
+		return ""
+	}
+	if stringField(payload, "refresh_token") != "" || stringField(token, "refresh_token") != "" {
+		return ""
+	}
+	expiry := stringField(payload, "expired")
+	if expiry == "" {
+		expiry = stringField(token, "expiry")
+	}
+	expiresAt, errParse := time.Parse(time.RFC3339, expiry)
+	if errParse != nil || expiresAt.After(now) {
+		return ""
+	}
+	return "access token expired and no refresh token is stored"
+}
+
+// stringField reads a trimmed string value from a JSON object.
+func stringField(payload map[string]any, key string) string {
+	if payload == nil {
+		return ""
+	}
+	value, _ := payload[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// ReauthSession tracks one in-progress re-authorization.
+type ReauthSession struct {
+	AuthID    uint64    // Auth whose credentials are replaced.
+	Type      string    // Auth type, selecting the OAuth requester.
+	StartedAt time.Time // Credentials issued after this time are eligible.
+}
+
+// ReauthSessions holds in-progress re-authorizations in memory.
+type ReauthSessions struct {
+	mu       sync.Mutex
+	sessions map[uint64]ReauthSession
+}
+
+// NewReauthSessions constructs an empty session registry.
+func NewReauthSessions() *ReauthSessions {
+	return &ReauthSessions{sessions: make(map[uint64]ReauthSession)}
+}
+
+// Begin starts or restarts the re-authorization of an auth.
+func (s *ReauthSessions) Begin(authID uint64, authType string, now time.Time) (ReauthSession, error) {
+	if _, ok := TokenEndpoint(authType); !ok {
+		return ReauthSession{}, ErrReauthUnsupported
+	}
+	session := ReauthSession{AuthID: authID, Type: strings.ToLower(strings.TrimSpace(authType)), StartedAt: now}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, existing := range s.sessions {
+		if now.Sub(existing.StartedAt) > reauthSessionTTL {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[authID] = session
+	return session, nil
+}
+
+// Lookup returns the live session of an auth.
+func (s *ReauthSessions) Lookup(authID uint64, now time.Time) (ReauthSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[authID]
+	if !ok || now.Sub(session.StartedAt) > reauthSessionTTL {
+		return ReauthSession{}, false
+	}
+	return session, true
+}
+
+// End discards the session of an auth.
+func (s *ReauthSessions) End(authID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, authID)
+}
+
+// CompleteReauth moves credentials issued during the session into the target auth, keeping its
+// ID, key, groups, proxy, priority, and therefore its sticky bindings. The OAuth requester stores
+// new credentials as a separate auth; that auth is found by sourceKey, or as the only auth of the
+// same type created since the session started, and removed once its content is moved. Without a
+// sourceKey, several candidates fail with ErrReauthAmbiguous rather than guessing, since another
+// admin may have added an auth of the same type meanwhile. When the requester overwrote the
+// target itself, the target is returned unchanged.
+func CompleteReauth(ctx context.Context, db *gorm.DB, session ReauthSession, sourceKey string, now time.Time) (models.Auth, error) {
+	var target models.Auth
+	if errFind := db.WithContext(ctx).First(&target, session.AuthID).Error; errFind != nil {
+		return models.Auth{}, errFind
+	}
+
+	sourceKey = strings.TrimSpace(sourceKey)
+	q := db.WithContext(ctx).Where("id <> ?", target.ID)
+	if sourceKey != "" {
+		q = q.Where("key = ?", sourceKey)
+	} else {
+		q = q.Where(dbutil.JSONExtractTextExpr(db, "content", "type")+" = ?", session.Type).
+			Where("created_at >= ?", session.StartedAt)
+	}
+	var candidates []models.Auth
+	if errFind := q.Order("id ASC").Limit(2).Find(&candidates).Error; errFind != nil {
+		return models.Auth{}, errFind
+	}
+	switch {
+	case len(candidates) == 0 && sourceKey != "":
+		return models.Auth{}, ErrReauthSourceInvalid
+	case len(candidates) == 0:
+		if !target.UpdatedAt.Before(session.StartedAt) {
+			return target, nil
+		}
+		return models.Auth{}, ErrReauthPending
+	case len(candidates) > 1:
+		return models.Auth{}, ErrReauthAmbiguous
+	}
+	source := candidates[0]
+	if !strings.EqualFold(ContentType(source.Content), session.Type) {
+		return models.Auth{}, ErrReauthSourceInvalid
+	}
+
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errUpdate := tx.Model(&models.Auth{}).Where("id = ?", target.ID).Updates(map[string]any{
+			"content":      source.Content,
+			"is_available": true,
+			"updated_at":   now,
+		}).Error; errUpdate != nil {
+			return errUpdate
+		}
+		return tx.Delete(&models.Auth{}, source.ID).Error
+	})
+	if errTx != nil {
+		return models.Auth{}, errTx
+	}
+	target.Content = source.Content
+	target.IsAvailable = true
+	target.UpdatedAt = now
+	return target, nil
+}
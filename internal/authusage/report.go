@@ -0,0 +1,280 @@
+// Package authusage attributes usage, revenue, and cost to upstream auths and auth groups.
+package authusage
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authfiles"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// hoursPerMonth is the average month length used to prorate monthly cost bases.
+const hoursPerMonth = 365 * 24 / 12.0
+
+// Report scopes.
+const (
+	// ScopeAuth reports one upstream auth.
+	ScopeAuth = "auth"
+	// ScopeAuthGroup reports the auths whose primary group is one auth group.
+	ScopeAuthGroup = "auth_group"
+)
+
+// Row summarizes what one auth or auth group served, earned, and cost over a period.
+type Row struct {
+	Scope           string   `json:"scope"`                     // ScopeAuth or ScopeAuthGroup.
+	AuthID          uint64   `json:"auth_id,omitempty"`         // Auth ID; 0 for auths not stored in the database.
+	AuthKey         string   `json:"auth_key,omitempty"`        // Auth key, for ScopeAuth.
+	AuthGroupID     uint64   `json:"auth_group_id,omitempty"`   // Primary auth group.
+	AuthGroupName   string   `json:"auth_group_name,omitempty"` // Primary auth group name.
+	Type            string   `json:"type,omitempty"`            // Auth content type, for ScopeAuth.
+	Deleted         bool     `json:"deleted,omitempty"`         // Usage belongs to an auth that no longer exists.
+	Auths           int      `json:"auths"`                     // Auths contributing to the row.
+	Requests        int64    `json:"requests"`                  // Requests served.
+	FailedRequests  int64    `json:"failed_requests"`           // Requests that failed.
+	InputTokens     int64    `json:"input_tokens"`              // Input tokens.
+	OutputTokens    int64    `json:"output_tokens"`             // Output tokens.
+	ReasoningTokens int64    `json:"reasoning_tokens"`          // Reasoning tokens.
+	CachedTokens    int64    `json:"cached_tokens"`             // Cached tokens.
+	TotalTokens     int64    `json:"total_tokens"`              // Total tokens.
+	RevenueMicros   int64    `json:"revenue_micros"`            // Amount billed to users.
+	CostMicros      int64    `json:"cost_micros"`               // Cost basis prorated to the period.
+	MarginMicros    int64    `json:"margin_micros"`             // Revenue minus cost.
+	MarginRate      *float64 `json:"margin_rate,omitempty"`     // Margin as a fraction of revenue; nil without revenue.
+}
+
+// add accumulates another row's counters.
+func (r *Row) add(other Row) {
+	r.Auths += other.Auths
+	r.Requests += other.Requests
+	r.FailedRequests += other.FailedRequests
+	r.InputTokens += other.InputTokens
+	r.OutputTokens += other.OutputTokens
+	r.ReasoningTokens += other.ReasoningTokens
+	r.CachedTokens += other.CachedTokens
+	r.TotalTokens += other.TotalTokens
+	r.RevenueMicros += other.RevenueMicros
+	r.CostMicros += other.CostMicros
+}
+
+// finish derives the margin fields.
+func (r *Row) finish() {
+	r.MarginMicros = r.RevenueMicros - r.CostMicros
+	r.MarginRate = nil
+	if r.RevenueMicros > 0 {
+		rate := float64(r.MarginMicros) / float64(r.RevenueMicros)
+		r.MarginRate = &rate
+	}
+}
+
+// ProratedCost returns the share of a monthly cost basis falling in [from, to), counting only
+// the time after the auth was created.
+func ProratedCost(monthlyMicros int64, createdAt, from, to time.Time) int64 {
+	if monthlyMicros <= 0 {
+		return 0
+	}
+	if createdAt.After(from) {
+		from = createdAt
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return int64(float64(monthlyMicros) * to.Sub(from).Hours() / hoursPerMonth)
+}
+
+// usageTotals is one aggregated usage row per auth key.
+type usageTotals struct {
+	AuthKey         string
+	AuthID          *uint64
+	Requests        int64
+	FailedRequests  int64
+	InputTokens     int64
+	OutputTokens    int64
+	ReasoningTokens int64
+	CachedTokens    int64
+	TotalTokens     int64
+	RevenueMicros   int64
+}
+
+// Build computes per-auth and per-auth-group rows for usage requested in [from, to). Auths
+// without usage are included so idle accounts still show their cost.
+func Build(ctx context.Context, db *gorm.DB, from, to time.Time) ([]Row, []Row, error) {
+	if db == nil {
+		return nil, nil, errors.New("nil db")
+	}
+	var totals []usageTotals
+	if errScan := db.WithContext(ctx).Model(&models.Usage{}).
+		Where("requested_at >= ? AND requested_at < ?", from, to).
+		Select(`
+			auth_key,
+			MAX(auth_id) AS auth_id,
+			COUNT(*) AS requests,
+			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_requests,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(reasoning_tokens), 0) AS reasoning_tokens,
+			COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cost_micros), 0) AS revenue_micros
+		`).
+		Group("auth_key").
+		Scan(&totals).Error; errScan != nil {
+		return nil, nil, errScan
+	}
+
+	var auths []models.Auth
+	if errFind := db.WithContext(ctx).Find(&auths).Error; errFind != nil {
+		return nil, nil, errFind
+	}
+	var groups []models.AuthGroup
+	if errFind := db.WithContext(ctx).Find(&groups).Error; errFind != nil {
+		return nil, nil, errFind
+	}
+	groupNames := make(map[uint64]string, len(groups))
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+
+	byKey := make(map[string]*Row, len(auths)+len(totals))
+	order := make([]string, 0, len(auths)+len(totals))
+	byID := make(map[uint64]string, len(auths))
+	for _, auth := range auths {
+		row := &Row{
+			Scope:      ScopeAuth,
+			AuthID:     auth.ID,
+			AuthKey:    auth.Key,
+			Type:       authfiles.ContentType(auth.Content),
+			Auths:      1,
+			CostMicros: ProratedCost(auth.MonthlyCostMicros, auth.CreatedAt, from, to),
+		}
+		if primary := auth.AuthGroupID.Primary(); primary != nil {
+			row.AuthGroupID = *primary
+			row.AuthGroupName = groupNames[*primary]
+		}
+		byKey[auth.Key] = row
+		byID[auth.ID] = auth.Key
+		order = append(order, auth.Key)
+	}
+	for _, total := range totals {
+		row, ok := byKey[total.AuthKey]
+		if !ok && total.AuthID != nil {
+			// The auth was renamed since the usage was recorded.
+			if key, found := byID[*total.AuthID]; found {
+				row, ok = byKey[key], true
+			}
+		}
+		if !ok {
+			row = &Row{Scope: ScopeAuth, AuthKey: total.AuthKey, Deleted: total.AuthKey != "", Auths: 1}
+			if total.AuthID != nil {
+				row.AuthID = *total.AuthID
+			}
+			byKey[total.AuthKey] = row
+			order = append(order, total.AuthKey)
+		}
+		row.add(Row{
+			Requests:        total.Requests,
+			FailedRequests:  total.FailedRequests,
+			InputTokens:     total.InputTokens,
+			OutputTokens:    total.OutputTokens,
+			ReasoningTokens: total.ReasoningTokens,
+			CachedTokens:    total.CachedTokens,
+			TotalTokens:     total.TotalTokens,
+			RevenueMicros:   total.RevenueMicros,
+		})
+	}
+
+	authRows := make([]Row, 0, len(order))
+	groupRows := make(map[uint64]*Row)
+	for _, key := range order {
+		row := byKey[key]
+		row.finish()
+		authRows = append(authRows, *row)
+		if row.AuthGroupID == 0 {
+			continue
+		}
+		group, ok := groupRows[row.AuthGroupID]
+		if !ok {
+			group = &Row{Scope: ScopeAuthGroup, AuthGroupID: row.AuthGroupID, AuthGroupName: row.AuthGroupName}
+			groupRows[row.AuthGroupID] = group
+		}
+		group.add(*row)
+	}
+	groupOut := make([]Row, 0, len(groupRows))
+	for _, group := range groupRows {
+		group.finish()
+		groupOut = append(groupOut, *group)
+	}
+	sortRows(authRows)
+	sortRows(groupOut)
+	return authRows, groupOut, nil
+}
+
+// sortRows orders rows by revenue, then requests, then identity.
+func sortRows(rows []Row) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.RevenueMicros != b.RevenueMicros {
+			return a.RevenueMicros > b.RevenueMicros
+		}
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		if a.AuthGroupID != b.AuthGroupID {
+			return a.AuthGroupID < b.AuthGroupID
+		}
+		return a.AuthKey < b.AuthKey
+	})
+}
+
+// csvHeader lists the CSV columns written by WriteCSV.
+var csvHeader = []string{
+	"scope", "auth_id", "auth_key", "type", "auth_group_id", "auth_group_name", "deleted", "auths",
+	"requests", "failed_requests", "input_tokens", "output_tokens", "reasoning_tokens", "cached_tokens",
+	"total_tokens", "revenue_micros", "cost_micros", "margin_micros", "margin_rate",
+}
+
+// WriteCSV writes rows as CSV with a header line.
+func WriteCSV(w io.Writer, rows []Row) error {
+	writer := csv.NewWriter(w)
+	if errWrite := writer.Write(csvHeader); errWrite != nil {
+		return errWrite
+	}
+	for _, row := range rows {
+		marginRate := ""
+		if row.MarginRate != nil {
+			marginRate = strconv.FormatFloat(*row.MarginRate, 'f', 4, 64)
+		}
+		record := []string{
+			row.Scope,
+			strconv.FormatUint(row.AuthID, 10),
+			row.AuthKey,
+			row.Type,
+			strconv.FormatUint(row.AuthGroupID, 10),
+			row.AuthGroupName,
+			strconv.FormatBool(row.Deleted),
+			strconv.Itoa(row.Auths),
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatInt(row.FailedRequests, 10),
+			strconv.FormatInt(row.InputTokens, 10),
+			strconv.FormatInt(row.OutputTokens, 10),
+			strconv.FormatInt(row.ReasoningTokens, 10),
+			strconv.FormatInt(row.CachedTokens, 10),
+			strconv.FormatInt(row.TotalTokens, 10),
+			strconv.FormatInt(row.RevenueMicros, 10),
+			strconv.FormatInt(row.CostMicros, 10),
+			strconv.FormatInt(row.MarginMicros, 10),
+			marginRate,
+		}
+		if errWrite := writer.Write(record); errWrite != nil {
+			return errWrite
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,120 @@
+package authusage
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), name))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestProratedCost(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(hoursPerMonth * time.Hour / 2)
+	if got := ProratedCost(20_000_000, from.Add(-time.Hour), from, to); got != 10_000_000 {
+		t.Fatalf("expected half a month, got %d", got)
+	}
+	if got := ProratedCost(20_000_000, to, from, to); got != 0 {
+		t.Fatalf("expected nothing before creation, got %d", got)
+	}
+	if got := ProratedCost(0, from, from, to); got != 0 {
+		t.Fatalf("expected zero without cost basis, got %d", got)
+	}
+}
+
+func TestBuildAttributesUsageToAuthsAndGroups(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t, "report.db")
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(hoursPerMonth * time.Hour)
+
+	group := models.AuthGroup{Name: "team"}
+	if errCreate := conn.Create(&group).Error; errCreate != nil {
+		t.Fatalf("create group: %v", errCreate)
+	}
+	groupID := group.ID
+	paid := models.Auth{
+		Key: "paid.json", AuthGroupID: models.AuthGroupIDs{&groupID}, Content: datatypes.JSON(`{"type":"claude"}`),
+		IsAvailable: true, MonthlyCostMicros: 100_000_000, CreatedAt: from.Add(-24 * time.Hour),
+	}
+	idle := models.Auth{
+		Key: "idle.json", AuthGroupID: models.AuthGroupIDs{&groupID}, Content: datatypes.JSON(`{"type":"codex"}`),
+		IsAvailable: true, MonthlyCostMicros: 20_000_000, CreatedAt: from.Add(-24 * time.Hour),
+	}
+	for _, auth := range []*models.Auth{&paid, &idle} {
+		if errCreate := conn.Create(auth).Error; errCreate != nil {
+			t.Fatalf("create auth: %v", errCreate)
+		}
+	}
+
+	usages := []models.Usage{
+		{Provider: "claude", Model: "m", AuthID: &paid.ID, AuthKey: "paid.json", RequestedAt: from.Add(time.Hour), InputTokens: 10, OutputTokens: 5, TotalTokens: 15, CostMicros: 150_000_000},
+		{Provider: "claude", Model: "m", AuthID: &paid.ID, AuthKey: "paid.json", RequestedAt: from.Add(2 * time.Hour), Failed: true},
+		{Provider: "claude", Model: "m", AuthKey: "gone.json", RequestedAt: from.Add(time.Hour), TotalTokens: 7, CostMicros: 1_000_000},
+		{Provider: "claude", Model: "m", AuthID: &paid.ID, AuthKey: "paid.json", RequestedAt: to.Add(time.Hour), CostMicros: 999},
+	}
+	if errCreate := conn.Create(&usages).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	auths, groups, errBuild := Build(ctx, conn, from, to)
+	if errBuild != nil {
+		t.Fatalf("build: %v", errBuild)
+	}
+	if len(auths) != 3 {
+		t.Fatalf("expected 3 auth rows, got %d", len(auths))
+	}
+	first := auths[0]
+	if first.AuthKey != "paid.json" || first.Requests != 2 || first.FailedRequests != 1 || first.TotalTokens != 15 {
+		t.Fatalf("unexpected paid row: %+v", first)
+	}
+	if first.RevenueMicros != 150_000_000 || first.CostMicros != 100_000_000 || first.MarginMicros != 50_000_000 {
+		t.Fatalf("unexpected paid money: %+v", first)
+	}
+	if first.MarginRate == nil || *first.MarginRate < 0.33 || *first.MarginRate > 0.34 {
+		t.Fatalf("unexpected margin rate: %v", first.MarginRate)
+	}
+	if auths[1].AuthKey != "gone.json" || !auths[1].Deleted || auths[1].RevenueMicros != 1_000_000 {
+		t.Fatalf("unexpected deleted row: %+v", auths[1])
+	}
+	if auths[2].AuthKey != "idle.json" || auths[2].Requests != 0 || auths[2].MarginMicros != -20_000_000 || auths[2].MarginRate != nil {
+		t.Fatalf("unexpected idle row: %+v", auths[2])
+	}
+
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group row, got %d", len(groups))
+	}
+	if g := groups[0]; g.AuthGroupName != "team" || g.Auths != 2 || g.RevenueMicros != 150_000_000 || g.CostMicros != 120_000_000 {
+		t.Fatalf("unexpected group row: %+v", g)
+	}
+
+	var buf bytes.Buffer
+	if errWrite := WriteCSV(&buf, auths); errWrite != nil {
+		t.Fatalf("write csv: %v", errWrite)
+	}
+	records, errRead := csv.NewReader(&buf).ReadAll()
+	if errRead != nil {
+		t.Fatalf("read csv: %v", errRead)
+	}
+	if len(records) != 4 || len(records[0]) != len(csvHeader) || records[1][2] != "paid.json" {
+		t.Fatalf("unexpected csv: %v", records)
+	}
+}
@@ -0,0 +1,458 @@
+// Package backup exports, restores, and copies the full database across SQLite and PostgreSQL.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// ArchiveFormat identifies backup archives in the header line.
+	ArchiveFormat = "cliproxyapi-business-backup"
+	// ArchiveVersion is the archive layout version written by Export.
+	ArchiveVersion = 1
+	// DefaultBatchSize is the number of rows inserted per statement during restore and copy.
+	DefaultBatchSize = 200
+)
+
+var (
+	// ErrInvalidArchive indicates the input is not a readable backup archive.
+	ErrInvalidArchive = errors.New("backup: invalid archive")
+	// ErrUnsupportedVersion indicates the archive was written by a newer layout version.
+	ErrUnsupportedVersion = errors.New("backup: unsupported archive version")
+	// ErrCountMismatch indicates row counts differ between source and target after a copy.
+	ErrCountMismatch = errors.New("backup: row count mismatch")
+)
+
+// Header is the first line of an archive.
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Dialect   string    `json:"dialect"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// record is one line of an archive after the header: a table start, a row, or the end marker.
+type record struct {
+	Table string          `json:"table,omitempty"`
+	Rows  int64           `json:"rows,omitempty"`
+	Row   json.RawMessage `json:"row,omitempty"`
+	EOF   bool            `json:"eof,omitempty"`
+}
+
+// TableCount reports rows processed for a table.
+type TableCount struct {
+	Table  string `json:"table"`
+	Source int64  `json:"source"`
+	Target int64  `json:"target"`
+}
+
+// table couples a table name with its model schema.
+type table struct {
+	name   string
+	model  any
+	schema *schema.Schema
+}
+
+// newRow allocates a pointer to a zero model value.
+func (t table) newRow() reflect.Value {
+	return reflect.New(t.schema.ModelType)
+}
+
+// orderBy returns the primary-key ordering used for deterministic exports.
+func (t table) orderBy() string {
+	if len(t.schema.PrimaryFieldDBNames) == 0 {
+		return ""
+	}
+	return strings.Join(t.schema.PrimaryFieldDBNames, ", ")
+}
+
+// tables returns all models sorted so referenced tables precede referencing tables.
+func tables(conn *gorm.DB) ([]table, error) {
+	models := dbutil.Models()
+	parsed := make([]table, 0, len(models))
+	index := make(map[string]int, len(models))
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: conn}
+		if errParse := stmt.Parse(model); errParse != nil {
+			return nil, fmt.Errorf("backup: parse model %T: %w", model, errParse)
+		}
+		index[stmt.Schema.Table] = len(parsed)
+		parsed = append(parsed, table{name: stmt.Schema.Table, model: model, schema: stmt.Schema})
+	}
+
+	deps := make(map[string]map[string]struct{}, len(parsed))
+	addDep := func(from, to string) {
+		if from == to {
+			return
+		}
+		if _, ok := index[to]; !ok {
+			return
+		}
+		if deps[from] == nil {
+			deps[from] = make(map[string]struct{})
+		}
+		deps[from][to] = struct{}{}
+	}
+	for _, t := range parsed {
+		for _, rel := range t.schema.Relationships.Relations {
+			if rel.FieldSchema == nil {
+				continue
+			}
+			switch rel.Type {
+			case schema.BelongsTo:
+				addDep(t.name, rel.FieldSchema.Table)
+			case schema.HasOne, schema.HasMany:
+				addDep(rel.FieldSchema.Table, t.name)
+			}
+		}
+	}
+
+	ordered := make([]table, 0, len(parsed))
+	state := make(map[string]int, len(parsed))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("backup: dependency cycle at table %s", name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		names := make([]string, 0, len(deps[name]))
+		for dep := range deps[name] {
+			names = append(names, dep)
+		}
+		sort.Slice(names, func(i, j int) bool { return index[names[i]] < index[names[j]] })
+		for _, dep := range names {
+			if errVisit := visit(dep); errVisit != nil {
+				return errVisit
+			}
+		}
+		state[name] = 2
+		ordered = append(ordered, parsed[index[name]])
+		return nil
+	}
+	for _, t := range parsed {
+		if errVisit := visit(t.name); errVisit != nil {
+			return nil, errVisit
+		}
+	}
+	return ordered, nil
+}
+
+// Export writes a gzip-compressed, line-delimited JSON archive of every table.
+func Export(ctx context.Context, conn *gorm.DB, w io.Writer) ([]TableCount, error) {
+	list, errTables := tables(conn)
+	if errTables != nil {
+		return nil, errTables
+	}
+
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	header := Header{
+		Format:    ArchiveFormat,
+		Version:   ArchiveVersion,
+		Dialect:   dbutil.DialectName(conn),
+		CreatedAt: time.Now().UTC(),
+	}
+	if errEncode := enc.Encode(header); errEncode != nil {
+		return nil, fmt.Errorf("backup: write header: %w", errEncode)
+	}
+
+	counts := make([]TableCount, 0, len(list))
+	errTx := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, t := range list {
+			var total int64
+			if errCount := tx.Model(t.model).Count(&total).Error; errCount != nil {
+				return fmt.Errorf("backup: count %s: %w", t.name, errCount)
+			}
+			if errEncode := enc.Encode(record{Table: t.name, Rows: total}); errEncode != nil {
+				return fmt.Errorf("backup: write %s: %w", t.name, errEncode)
+			}
+			written, errStream := streamRows(tx, t, func(row reflect.Value) error {
+				data, errMarshal := json.Marshal(row.Interface())
+				if errMarshal != nil {
+					return errMarshal
+				}
+				return enc.Encode(record{Row: data})
+			})
+			if errStream != nil {
+				return fmt.Errorf("backup: export %s: %w", t.name, errStream)
+			}
+			if written != total {
+				return fmt.Errorf("backup: export %s: table changed during export", t.name)
+			}
+			counts = append(counts, TableCount{Table: t.name, Source: total, Target: written})
+		}
+		return nil
+	}, exportTxOptions(conn))
+	if errTx != nil {
+		return nil, errTx
+	}
+	if errEncode := enc.Encode(record{EOF: true}); errEncode != nil {
+		return nil, fmt.Errorf("backup: write end marker: %w", errEncode)
+	}
+	if errClose := gz.Close(); errClose != nil {
+		return nil, fmt.Errorf("backup: close archive: %w", errClose)
+	}
+	return counts, nil
+}
+
+// Import replaces the contents of every table with the rows in an archive.
+func Import(ctx context.Context, conn *gorm.DB, r io.Reader) (Header, []TableCount, error) {
+	list, errTables := tables(conn)
+	if errTables != nil {
+		return Header{}, nil, errTables
+	}
+	byName := make(map[string]table, len(list))
+	for _, t := range list {
+		byName[t.name] = t
+	}
+
+	gz, errGzip := gzip.NewReader(r)
+	if errGzip != nil {
+		return Header{}, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, errGzip)
+	}
+	defer func() { _ = gz.Close() }()
+	dec := json.NewDecoder(bufio.NewReader(gz))
+
+	var header Header
+	if errDecode := dec.Decode(&header); errDecode != nil || header.Format != ArchiveFormat {
+		return Header{}, nil, ErrInvalidArchive
+	}
+	if header.Version <= 0 || header.Version > ArchiveVersion {
+		return header, nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, header.Version)
+	}
+
+	counts := make([]TableCount, 0, len(list))
+	errTx := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errClear := clearTables(tx, list); errClear != nil {
+			return errClear
+		}
+		for {
+			var rec record
+			if errDecode := dec.Decode(&rec); errDecode != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, errDecode)
+			}
+			if rec.EOF {
+				break
+			}
+			t, ok := byName[rec.Table]
+			if !ok {
+				return fmt.Errorf("%w: unknown table %q", ErrInvalidArchive, rec.Table)
+			}
+			batch := newBatchWriter(tx, t, DefaultBatchSize)
+			for i := int64(0); i < rec.Rows; i++ {
+				var rowRec record
+				if errDecode := dec.Decode(&rowRec); errDecode != nil || len(rowRec.Row) == 0 {
+					return fmt.Errorf("%w: truncated table %s", ErrInvalidArchive, t.name)
+				}
+				row := t.newRow()
+				if errUnmarshal := json.Unmarshal(rowRec.Row, row.Interface()); errUnmarshal != nil {
+					return fmt.Errorf("%w: table %s row %d: %v", ErrInvalidArchive, t.name, i+1, errUnmarshal)
+				}
+				if errAdd := batch.add(row); errAdd != nil {
+					return errAdd
+				}
+			}
+			if errFlush := batch.flush(); errFlush != nil {
+				return errFlush
+			}
+			counts = append(counts, TableCount{Table: t.name, Source: rec.Rows, Target: batch.written})
+		}
+		return resetSequences(tx, list)
+	})
+	if errTx != nil {
+		return header, nil, errTx
+	}
+	return header, counts, nil
+}
+
+// Copy streams every table from src into dst, replacing dst contents, then verifies row counts.
+// Both databases must already be migrated to the same schema.
+func Copy(ctx context.Context, src, dst *gorm.DB, batchSize int) ([]TableCount, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	list, errTables := tables(dst)
+	if errTables != nil {
+		return nil, errTables
+	}
+
+	errTx := dst.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errClear := clearTables(tx, list); errClear != nil {
+			return errClear
+		}
+		return src.WithContext(ctx).Transaction(func(srcTx *gorm.DB) error {
+			for _, t := range list {
+				batch := newBatchWriter(tx, t, batchSize)
+				if _, errStream := streamRows(srcTx, t, batch.add); errStream != nil {
+					return fmt.Errorf("backup: copy %s: %w", t.name, errStream)
+				}
+				if errFlush := batch.flush(); errFlush != nil {
+					return errFlush
+				}
+			}
+			return nil
+		}, exportTxOptions(src))
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	if errReset := resetSequences(dst.WithContext(ctx), list); errReset != nil {
+		return nil, errReset
+	}
+	return Verify(ctx, src, dst)
+}
+
+// Verify compares per-table row counts between two databases.
+func Verify(ctx context.Context, src, dst *gorm.DB) ([]TableCount, error) {
+	list, errTables := tables(dst)
+	if errTables != nil {
+		return nil, errTables
+	}
+	counts := make([]TableCount, 0, len(list))
+	var mismatched []string
+	for _, t := range list {
+		count := TableCount{Table: t.name}
+		if errCount := src.WithContext(ctx).Model(t.model).Count(&count.Source).Error; errCount != nil {
+			return nil, fmt.Errorf("backup: count source %s: %w", t.name, errCount)
+		}
+		if errCount := dst.WithContext(ctx).Model(t.model).Count(&count.Target).Error; errCount != nil {
+			return nil, fmt.Errorf("backup: count target %s: %w", t.name, errCount)
+		}
+		if count.Source != count.Target {
+			mismatched = append(mismatched, fmt.Sprintf("%s (%d != %d)", t.name, count.Source, count.Target))
+		}
+		counts = append(counts, count)
+	}
+	if len(mismatched) > 0 {
+		return counts, fmt.Errorf("%w: %s", ErrCountMismatch, strings.Join(mismatched, ", "))
+	}
+	return counts, nil
+}
+
+// streamRows scans a table row by row in primary-key order.
+func streamRows(tx *gorm.DB, t table, fn func(row reflect.Value) error) (int64, error) {
+	q := tx.Model(t.model)
+	if order := t.orderBy(); order != "" {
+		q = q.Order(order)
+	}
+	rows, errRows := q.Rows()
+	if errRows != nil {
+		return 0, errRows
+	}
+	defer func() { _ = rows.Close() }()
+
+	var n int64
+	for rows.Next() {
+		row := t.newRow()
+		if errScan := tx.ScanRows(rows, row.Interface()); errScan != nil {
+			return n, errScan
+		}
+		if errFn := fn(row); errFn != nil {
+			return n, errFn
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// batchWriter buffers rows as column maps, so zero values on columns with defaults and primary keys are kept verbatim.
+type batchWriter struct {
+	tx      *gorm.DB
+	table   table
+	size    int
+	rows    []map[string]any
+	written int64
+}
+
+// newBatchWriter creates a batch writer for a table.
+func newBatchWriter(tx *gorm.DB, t table, size int) *batchWriter {
+	return &batchWriter{tx: tx, table: t, size: size, rows: make([]map[string]any, 0, size)}
+}
+
+// add buffers a row pointer and flushes when the batch is full.
+func (b *batchWriter) add(row reflect.Value) error {
+	values := make(map[string]any, len(b.table.schema.DBNames))
+	for _, field := range b.table.schema.Fields {
+		if field.DBName == "" || !field.Creatable {
+			continue
+		}
+		value, _ := field.ValueOf(b.tx.Statement.Context, row.Elem())
+		values[field.DBName] = value
+	}
+	b.rows = append(b.rows, values)
+	if len(b.rows) >= b.size {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush inserts buffered rows.
+func (b *batchWriter) flush() error {
+	if len(b.rows) == 0 {
+		return nil
+	}
+	if errCreate := b.tx.Table(b.table.name).Create(&b.rows).Error; errCreate != nil {
+		return fmt.Errorf("backup: insert %s: %w", b.table.name, errCreate)
+	}
+	b.written += int64(len(b.rows))
+	b.rows = make([]map[string]any, 0, b.size)
+	return nil
+}
+
+// clearTables deletes all rows, children first.
+func clearTables(tx *gorm.DB, list []table) error {
+	for i := len(list) - 1; i >= 0; i-- {
+		t := list[i]
+		if errDelete := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Where("1 = 1").Delete(t.model).Error; errDelete != nil {
+			return fmt.Errorf("backup: clear %s: %w", t.name, errDelete)
+		}
+	}
+	return nil
+}
+
+// resetSequences moves PostgreSQL serial sequences past the imported primary keys.
+func resetSequences(tx *gorm.DB, list []table) error {
+	if dbutil.DialectName(tx) != dbutil.DialectPostgres {
+		return nil
+	}
+	for _, t := range list {
+		field := t.schema.PrioritizedPrimaryField
+		if field == nil || !field.AutoIncrement {
+			continue
+		}
+		stmt := fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			t.name, field.DBName, tx.Statement.Quote(field.DBName), tx.Statement.Quote(t.name),
+		)
+		if errExec := tx.Exec(stmt).Error; errExec != nil {
+			return fmt.Errorf("backup: reset sequence %s: %w", t.name, errExec)
+		}
+	}
+	return nil
+}
+
+// exportTxOptions requests a consistent read-only snapshot where the dialect supports it.
+func exportTxOptions(conn *gorm.DB) *sql.TxOptions {
+	if dbutil.DialectName(conn) == dbutil.DialectPostgres {
+		return &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), name))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func seedSource(t *testing.T, conn *gorm.DB) (models.UserGroup, models.User) {
+	t.Helper()
+	group := models.UserGroup{Name: "vip", RequireMFA: true}
+	if errCreate := conn.Create(&group).Error; errCreate != nil {
+		t.Fatalf("create group: %v", errCreate)
+	}
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x", Active: true, UserGroupID: models.UserGroupIDs{&group.ID}}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	admin := models.Admin{Username: "root", Password: "x", Active: true}
+	if errCreate := conn.Create(&admin).Error; errCreate != nil {
+		t.Fatalf("create admin: %v", errCreate)
+	}
+	// Zero values on columns with defaults must survive the round trip.
+	if errUpdate := conn.Model(&admin).Update("active", false).Error; errUpdate != nil {
+		t.Fatalf("deactivate admin: %v", errUpdate)
+	}
+	usage := models.Usage{Provider: "codex", Model: "gpt-5", UserID: &user.ID, RequestedAt: time.Now().UTC(), TotalTokens: 42}
+	if errCreate := conn.Create(&usage).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	return group, user
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := openTestDB(t, "src.db")
+	group, user := seedSource(t, src)
+
+	var archive bytes.Buffer
+	if _, errExport := Export(ctx, src, &archive); errExport != nil {
+		t.Fatalf("Export: %v", errExport)
+	}
+
+	dst := openTestDB(t, "dst.db")
+	header, counts, errImport := Import(ctx, dst, bytes.NewReader(archive.Bytes()))
+	if errImport != nil {
+		t.Fatalf("Import: %v", errImport)
+	}
+	if header.Version != ArchiveVersion || header.Dialect != db.DialectSQLite || len(counts) == 0 {
+		t.Fatalf("unexpected import result: %+v %+v", header, counts)
+	}
+	if _, errVerify := Verify(ctx, src, dst); errVerify != nil {
+		t.Fatalf("Verify: %v", errVerify)
+	}
+
+	var admin models.Admin
+	if errFind := dst.Where("username = ?", "root").First(&admin).Error; errFind != nil {
+		t.Fatalf("find admin: %v", errFind)
+	}
+	if admin.Active {
+		t.Fatalf("expected inactive admin to stay inactive")
+	}
+
+	var found models.User
+	if errFind := dst.Where(db.JSONArrayContainsExpr(dst, "user_group_id"), db.JSONArrayContainsValue(dst, group.ID)).
+		First(&found).Error; errFind != nil {
+		t.Fatalf("find user by group: %v", errFind)
+	}
+	if found.ID != user.ID {
+		t.Fatalf("expected user %d, got %d", user.ID, found.ID)
+	}
+
+	next := models.User{Username: "bob", Email: "bob@example.com", Password: "x"}
+	if errCreate := dst.Create(&next).Error; errCreate != nil || next.ID <= user.ID {
+		t.Fatalf("expected new ids after imported rows, got %d %v", next.ID, errCreate)
+	}
+}
+
+func TestImportRejectsInvalidArchive(t *testing.T) {
+	dst := openTestDB(t, "dst.db")
+	if _, _, errImport := Import(context.Background(), dst, bytes.NewReader([]byte("not an archive"))); !errors.Is(errImport, ErrInvalidArchive) {
+		t.Fatalf("expected ErrInvalidArchive, got %v", errImport)
+	}
+}
+
+func TestCopyVerifiesCounts(t *testing.T) {
+	ctx := context.Background()
+	src := openTestDB(t, "src.db")
+	seedSource(t, src)
+	dst := openTestDB(t, "dst.db")
+
+	counts, errCopy := Copy(ctx, src, dst, 1)
+	if errCopy != nil {
+		t.Fatalf("Copy: %v", errCopy)
+	}
+	for _, count := range counts {
+		if count.Table == "usages" && count.Target != 1 {
+			t.Fatalf("expected one usage row, got %+v", count)
+		}
+	}
+
+	if errCreate := src.Create(&models.Proxy{ProxyURL: "http://proxy:1"}).Error; errCreate != nil {
+		t.Fatalf("create proxy: %v", errCreate)
+	}
+	if _, errVerify := Verify(ctx, src, dst); !errors.Is(errVerify, ErrCountMismatch) {
+		t.Fatalf("expected ErrCountMismatch, got %v", errVerify)
+	}
+}
@@ -0,0 +1,182 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Payer identifies whose bills and prepaid cards cover a user's usage.
+type Payer struct {
+	UserID         uint64                     // User who made the request.
+	OrganizationID *uint64                    // Organization charged instead of the user, if any.
+	Member         *models.OrganizationMember // Membership record when charged to an organization.
+}
+
+// ResolvePayer returns the organization payer for members, or the user itself.
+func ResolvePayer(ctx context.Context, db *gorm.DB, userID uint64) (Payer, error) {
+	payer := Payer{UserID: userID}
+	if db == nil || userID == 0 {
+		return payer, nil
+	}
+	var member models.OrganizationMember
+	if errFind := db.WithContext(ctx).Where("user_id = ?", userID).Take(&member).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return payer, nil
+		}
+		return payer, errFind
+	}
+	orgID := member.OrganizationID
+	payer.OrganizationID = &orgID
+	payer.Member = &member
+	return payer, nil
+}
+
+// OrganizationPayer returns a payer charging the organization directly.
+func OrganizationPayer(userID, organizationID uint64) Payer {
+	orgID := organizationID
+	return Payer{UserID: userID, OrganizationID: &orgID}
+}
+
+// IsOrganization reports whether usage is charged to an organization.
+func (p Payer) IsOrganization() bool {
+	return p.OrganizationID != nil && *p.OrganizationID != 0
+}
+
+// ScopeBills restricts a bill query to the payer's bills.
+func (p Payer) ScopeBills(q *gorm.DB) *gorm.DB {
+	if p.IsOrganization() {
+		return q.Where("organization_id = ?", *p.OrganizationID)
+	}
+	return q.Where("user_id = ? AND organization_id IS NULL", p.UserID)
+}
+
+// ScopePrepaidCards restricts a prepaid card query to the payer's redeemed cards.
+func (p Payer) ScopePrepaidCards(q *gorm.DB) *gorm.DB {
+	if p.IsOrganization() {
+		return q.Where("organization_id = ?", *p.OrganizationID)
+	}
+	return q.Where("redeemed_user_id = ? AND organization_id IS NULL", p.UserID)
+}
+
+// ScopeUsage restricts a usage query to the requests charged to the payer.
+func (p Payer) ScopeUsage(q *gorm.DB) *gorm.DB {
+	if p.IsOrganization() {
+		return q.Where("organization_id = ?", *p.OrganizationID)
+	}
+	return q.Where("user_id = ? AND organization_id IS NULL", p.UserID)
+}
+
+// UserIDs returns the users whose bill-derived groups follow the payer's bills.
+func (p Payer) UserIDs(ctx context.Context, db *gorm.DB) ([]uint64, error) {
+	if !p.IsOrganization() {
+		return []uint64{p.UserID}, nil
+	}
+	var ids []uint64
+	if errFind := db.WithContext(ctx).
+		Model(&models.OrganizationMember{}).
+		Where("organization_id = ?", *p.OrganizationID).
+		Pluck("user_id", &ids).Error; errFind != nil {
+		return nil, errFind
+	}
+	return ids, nil
+}
+
+// RefreshBillUserGroupIDs recomputes bill_user_group_id for every user covered by the payer's bills.
+func RefreshBillUserGroupIDs(ctx context.Context, tx *gorm.DB, payer Payer) error {
+	if tx == nil {
+		return errors.New("nil tx")
+	}
+	if payer.UserID == 0 && !payer.IsOrganization() {
+		return errors.New("empty user id")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	now := time.Now().UTC()
+	var bills []models.Bill
+	if errFind := payer.ScopeBills(tx.WithContext(ctx).Model(&models.Bill{})).
+		Select("user_group_id").
+		Where("is_enabled = ? AND status = ? AND left_quota > 0", true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", now, now).
+		Find(&bills).Error; errFind != nil {
+		return errFind
+	}
+
+	seen := make(map[uint64]struct{})
+	merged := make(models.UserGroupIDs, 0)
+	for _, bill := range bills {
+		for _, gid := range bill.UserGroupID.Clean() {
+			if gid == nil || *gid == 0 {
+				continue
+			}
+			if _, ok := seen[*gid]; ok {
+				continue
+			}
+			seen[*gid] = struct{}{}
+			idCopy := *gid
+			merged = append(merged, &idCopy)
+		}
+	}
+
+	userIDs, errUsers := payer.UserIDs(ctx, tx)
+	if errUsers != nil {
+		return errUsers
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return tx.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id IN ?", userIDs).
+		Update("bill_user_group_id", merged.Clean()).Error
+}
+
+// MemberSpendWithinLimits reports whether an organization member is below their daily and monthly spend caps.
+func MemberSpendWithinLimits(ctx context.Context, db *gorm.DB, member *models.OrganizationMember, now time.Time) (bool, error) {
+	if member == nil || (member.DailySpendLimit <= 0 && member.MonthlySpendLimit <= 0) {
+		return true, nil
+	}
+	if db == nil {
+		return false, errors.New("nil db")
+	}
+	localNow := now.In(time.Local)
+	dayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.Local)
+	monthStart := time.Date(localNow.Year(), localNow.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	spend := func(since time.Time) (float64, error) {
+		var costMicros int64
+		if errSum := db.WithContext(ctx).
+			Model(&models.Usage{}).
+			Where("user_id = ? AND organization_id = ? AND requested_at >= ?", member.UserID, member.OrganizationID, since).
+			Select("COALESCE(SUM(cost_micros), 0)").
+			Scan(&costMicros).Error; errSum != nil {
+			return 0, errSum
+		}
+		return float64(costMicros) / 1_000_000, nil
+	}
+
+	if member.DailySpendLimit > 0 {
+		spent, errSpend := spend(dayStart)
+		if errSpend != nil {
+			return false, errSpend
+		}
+		if spent >= member.DailySpendLimit {
+			return false, nil
+		}
+	}
+	if member.MonthlySpendLimit > 0 {
+		spent, errSpend := spend(monthStart)
+		if errSpend != nil {
+			return false, errSpend
+		}
+		if spent >= member.MonthlySpendLimit {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,112 @@
+package billing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+func TestOrganizationPayerScopesAndSpendLimits(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	owner := models.User{Username: "owner", Email: "owner@example.com", Password: "x", CreatedAt: now, UpdatedAt: now}
+	member := models.User{Username: "member", Email: "member@example.com", Password: "x", CreatedAt: now, UpdatedAt: now}
+	solo := models.User{Username: "solo", Email: "solo@example.com", Password: "x", CreatedAt: now, UpdatedAt: now}
+	for _, user := range []*models.User{&owner, &member, &solo} {
+		if errCreate := conn.Create(user).Error; errCreate != nil {
+			t.Fatalf("create user: %v", errCreate)
+		}
+	}
+
+	org := models.Organization{Name: "acme", OwnerUserID: owner.ID, CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&org).Error; errCreate != nil {
+		t.Fatalf("create organization: %v", errCreate)
+	}
+	memberships := []models.OrganizationMember{
+		{OrganizationID: org.ID, UserID: owner.ID, Role: models.OrganizationRoleOwner, CreatedAt: now, UpdatedAt: now},
+		{OrganizationID: org.ID, UserID: member.ID, Role: models.OrganizationRoleMember, DailySpendLimit: 1, CreatedAt: now, UpdatedAt: now},
+	}
+	if errCreate := conn.Create(&memberships).Error; errCreate != nil {
+		t.Fatalf("create members: %v", errCreate)
+	}
+
+	orgID := org.ID
+	cards := []models.PrepaidCard{
+		{Name: "org", CardSN: "sn-org", Password: "p1", Amount: 10, Balance: 10, RedeemedUserID: &owner.ID, OrganizationID: &orgID, IsEnabled: true, CreatedAt: now},
+		{Name: "own", CardSN: "sn-own", Password: "p2", Amount: 5, Balance: 5, RedeemedUserID: &owner.ID, IsEnabled: true, CreatedAt: now},
+	}
+	if errCreate := conn.Create(&cards).Error; errCreate != nil {
+		t.Fatalf("create cards: %v", errCreate)
+	}
+
+	payer, errPayer := ResolvePayer(ctx, conn, member.ID)
+	if errPayer != nil {
+		t.Fatalf("resolve payer: %v", errPayer)
+	}
+	if !payer.IsOrganization() || *payer.OrganizationID != org.ID || payer.Member == nil {
+		t.Fatalf("expected organization payer, got %+v", payer)
+	}
+	var orgCards []models.PrepaidCard
+	if errFind := payer.ScopePrepaidCards(conn.Model(&models.PrepaidCard{})).Find(&orgCards).Error; errFind != nil {
+		t.Fatalf("scope cards: %v", errFind)
+	}
+	if len(orgCards) != 1 || orgCards[0].CardSN != "sn-org" {
+		t.Fatalf("expected only the organization card, got %+v", orgCards)
+	}
+
+	personal := Payer{UserID: owner.ID}
+	var personalCards []models.PrepaidCard
+	if errFind := personal.ScopePrepaidCards(conn.Model(&models.PrepaidCard{})).Find(&personalCards).Error; errFind != nil {
+		t.Fatalf("scope cards: %v", errFind)
+	}
+	if len(personalCards) != 1 || personalCards[0].CardSN != "sn-own" {
+		t.Fatalf("expected only the personal card, got %+v", personalCards)
+	}
+
+	soloPayer, errSolo := ResolvePayer(ctx, conn, solo.ID)
+	if errSolo != nil {
+		t.Fatalf("resolve payer: %v", errSolo)
+	}
+	if soloPayer.IsOrganization() {
+		t.Fatalf("expected personal payer for non-member")
+	}
+
+	within, errLimit := MemberSpendWithinLimits(ctx, conn, payer.Member, now)
+	if errLimit != nil {
+		t.Fatalf("check limits: %v", errLimit)
+	}
+	if !within {
+		t.Fatalf("expected member within limits before usage")
+	}
+	usage := models.Usage{
+		Provider:       "openai",
+		Model:          "gpt",
+		UserID:         &member.ID,
+		OrganizationID: &orgID,
+		RequestedAt:    now,
+		CostMicros:     1_500_000,
+		CreatedAt:      now,
+	}
+	if errCreate := conn.Create(&usage).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	within, errLimit = MemberSpendWithinLimits(ctx, conn, payer.Member, now)
+	if errLimit != nil {
+		t.Fatalf("check limits: %v", errLimit)
+	}
+	if within {
+		t.Fatalf("expected member over daily limit after usage")
+	}
+}
@@ -0,0 +1,230 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Balance summarizes the quota and prepaid balance available to a payer.
+type Balance struct {
+	BillTotalQuota float64    // Total quota across active paid bills.
+	BillLeftQuota  float64    // Remaining quota across active paid bills.
+	DailyQuota     float64    // Sum of daily quotas for limited bills; zero when unlimited or no bills.
+	DailyUnlimited bool       // True when any active bill has no daily cap.
+	UsedToday      float64    // Cost charged to the payer since local midnight.
+	PrepaidBalance float64    // Remaining balance across valid redeemed prepaid cards.
+	AccessUntil    *time.Time // Latest bill period end or prepaid card expiry, nil when none expire.
+}
+
+// DailyLeft returns the remaining daily quota, or -1 when no daily cap applies.
+func (b Balance) DailyLeft() float64 {
+	if b.DailyUnlimited || b.DailyQuota <= 0 {
+		return -1
+	}
+	left := b.DailyQuota - b.UsedToday
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Remaining returns the spendable amount across bills and prepaid cards.
+func (b Balance) Remaining() float64 {
+	return b.BillLeftQuota + b.PrepaidBalance
+}
+
+// LoadBalance summarizes active bills, today's spend and prepaid cards for the payer.
+func LoadBalance(ctx context.Context, db *gorm.DB, payer Payer, now time.Time) (Balance, error) {
+	var balance Balance
+	if db == nil {
+		return balance, errors.New("nil db")
+	}
+
+	var bills struct {
+		TotalQuota     float64 `gorm:"column:total_quota"`     // Total quota across bills.
+		LeftQuota      float64 `gorm:"column:left_quota"`      // Remaining quota across bills.
+		DailyQuota     float64 `gorm:"column:daily_quota"`     // Sum of daily quotas for limited bills.
+		UnlimitedDaily int64   `gorm:"column:unlimited_daily"` // Count of bills without a daily cap.
+	}
+	if errBills := payer.ScopeBills(db.WithContext(ctx).Model(&models.Bill{})).
+		Select(`
+			COALESCE(SUM(total_quota), 0) AS total_quota,
+			COALESCE(SUM(left_quota), 0) AS left_quota,
+			COALESCE(SUM(CASE WHEN daily_quota > 0 THEN daily_quota ELSE 0 END), 0) AS daily_quota,
+			COALESCE(SUM(CASE WHEN daily_quota <= 0 THEN 1 ELSE 0 END), 0) AS unlimited_daily
+		`).
+		Where("is_enabled = ? AND status = ? AND left_quota > 0", true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", now, now).
+		Scan(&bills).Error; errBills != nil {
+		return balance, errBills
+	}
+	balance.BillTotalQuota = bills.TotalQuota
+	balance.BillLeftQuota = bills.LeftQuota
+	balance.DailyQuota = bills.DailyQuota
+	balance.DailyUnlimited = bills.UnlimitedDaily > 0
+
+	var latestBill models.Bill
+	errLatest := payer.ScopeBills(db.WithContext(ctx).Model(&models.Bill{})).
+		Where("is_enabled = ? AND status = ? AND left_quota > 0", true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", now, now).
+		Order("period_end DESC").
+		Take(&latestBill).Error
+	switch {
+	case errLatest == nil:
+		periodEnd := latestBill.PeriodEnd
+		balance.AccessUntil = &periodEnd
+	case !errors.Is(errLatest, gorm.ErrRecordNotFound):
+		return balance, errLatest
+	}
+
+	localNow := now.In(time.Local)
+	todayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.Local)
+	usedToday, errUsed := SpendBetween(ctx, db, payer, todayStart, todayStart.AddDate(0, 0, 1))
+	if errUsed != nil {
+		return balance, errUsed
+	}
+	balance.UsedToday = usedToday
+
+	var cards []models.PrepaidCard
+	if errCards := payer.ScopePrepaidCards(db.WithContext(ctx).Model(&models.PrepaidCard{})).
+		Select("balance", "expires_at").
+		Where("is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", true).
+		Where("(expires_at IS NULL OR expires_at >= ?)", now).
+		Find(&cards).Error; errCards != nil {
+		return balance, errCards
+	}
+	cardsNeverExpire := false
+	for _, card := range cards {
+		balance.PrepaidBalance += card.Balance
+		if card.ExpiresAt == nil {
+			cardsNeverExpire = true
+			continue
+		}
+		if balance.AccessUntil == nil || card.ExpiresAt.After(*balance.AccessUntil) {
+			expiresAt := *card.ExpiresAt
+			balance.AccessUntil = &expiresAt
+		}
+	}
+	if cardsNeverExpire {
+		balance.AccessUntil = nil
+	}
+	return balance, nil
+}
+
+// SpendBetween returns the cost charged to the payer in [from, to).
+func SpendBetween(ctx context.Context, db *gorm.DB, payer Payer, from, to time.Time) (float64, error) {
+	if db == nil {
+		return 0, errors.New("nil db")
+	}
+	var costMicros int64
+	if errSum := payer.ScopeUsage(db.WithContext(ctx).Model(&models.Usage{})).
+		Where("requested_at >= ? AND requested_at < ?", from, to).
+		Select("COALESCE(SUM(cost_micros), 0)").
+		Scan(&costMicros).Error; errSum != nil {
+		return 0, errSum
+	}
+	return float64(costMicros) / 1_000_000, nil
+}
+
+// ModelUsage aggregates the usage charged to a payer for one model.
+type ModelUsage struct {
+	Model           string `json:"model"`
+	Requests        int64  `json:"requests"`
+	FailedRequests  int64  `json:"failed_requests"`
+	InputTokens     int64  `json:"input_tokens"`
+	OutputTokens    int64  `json:"output_tokens"`
+	CachedTokens    int64  `json:"cached_tokens"`
+	ReasoningTokens int64  `json:"reasoning_tokens"`
+	TotalTokens     int64  `json:"total_tokens"`
+	CostMicros      int64  `json:"cost_micros"`
+}
+
+// LoadModelUsage returns per-model usage charged to the payer in [from, to), ordered by model.
+func LoadModelUsage(ctx context.Context, db *gorm.DB, payer Payer, from, to time.Time) ([]ModelUsage, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	rows := make([]ModelUsage, 0)
+	if errScan := payer.ScopeUsage(db.WithContext(ctx).Model(&models.Usage{})).
+		Where("requested_at >= ? AND requested_at < ?", from, to).
+		Select(`
+			model,
+			COUNT(*) AS requests,
+			COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
+			COALESCE(SUM(reasoning_tokens), 0) AS reasoning_tokens,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cost_micros), 0) AS cost_micros
+		`).
+		Group("model").
+		Order("model").
+		Scan(&rows).Error; errScan != nil {
+		return nil, errScan
+	}
+	return rows, nil
+}
+
+// DailyModelUsage is the cost charged to a payer for one model on one day.
+type DailyModelUsage struct {
+	Day        time.Time // Start of the day.
+	Model      string    // Model name.
+	CostMicros int64     // Cost charged on the day.
+}
+
+// LoadDailyModelUsage returns per-day, per-model cost charged to the payer in [from, to), ordered by
+// day and model. Days start at from and advance by calendar day in from's location. Rows are bucketed
+// against the precomputed day boundaries so days stay exact across DST changes on every dialect.
+func LoadDailyModelUsage(ctx context.Context, db *gorm.DB, payer Payer, from, to time.Time) ([]DailyModelUsage, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	var days []time.Time
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	if len(days) == 0 {
+		return []DailyModelUsage{}, nil
+	}
+
+	var dayExpr strings.Builder
+	args := make([]any, 0, len(days)-1)
+	dayExpr.WriteString("CASE")
+	for i := 1; i < len(days); i++ {
+		dayExpr.WriteString(" WHEN requested_at < ? THEN ")
+		dayExpr.WriteString(strconv.Itoa(i - 1))
+		args = append(args, days[i])
+	}
+	dayExpr.WriteString(" ELSE ")
+	dayExpr.WriteString(strconv.Itoa(len(days) - 1))
+	dayExpr.WriteString(" END")
+
+	var rows []struct {
+		DayIndex   int    `gorm:"column:day_index"`   // Index into days.
+		Model      string `gorm:"column:model"`       // Model name.
+		CostMicros int64  `gorm:"column:cost_micros"` // Cost charged on the day.
+	}
+	if errScan := payer.ScopeUsage(db.WithContext(ctx).Model(&models.Usage{})).
+		Where("requested_at >= ? AND requested_at < ?", from, to).
+		Select(dayExpr.String()+" AS day_index, model, COALESCE(SUM(cost_micros), 0) AS cost_micros", args...).
+		Group("day_index, model").
+		Order("day_index, model").
+		Scan(&rows).Error; errScan != nil {
+		return nil, errScan
+	}
+	out := make([]DailyModelUsage, 0, len(rows))
+	for _, row := range rows {
+		if row.DayIndex < 0 || row.DayIndex >= len(days) {
+			continue
+		}
+		out = append(out, DailyModelUsage{Day: days[row.DayIndex], Model: row.Model, CostMicros: row.CostMicros})
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package billing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+func TestLoadBalanceAndModelUsage(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	plan := models.Plan{Name: "pro", CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&plan).Error; errCreate != nil {
+		t.Fatalf("create plan: %v", errCreate)
+	}
+	bill := models.Bill{
+		PlanID:      plan.ID,
+		UserID:      user.ID,
+		PeriodType:  models.BillPeriodTypeMonthly,
+		PeriodStart: now.AddDate(0, 0, -1),
+		PeriodEnd:   now.AddDate(0, 1, 0),
+		TotalQuota:  100,
+		DailyQuota:  10,
+		LeftQuota:   80,
+		IsEnabled:   true,
+		Status:      models.BillStatusPaid,
+	}
+	if errCreate := conn.Create(&bill).Error; errCreate != nil {
+		t.Fatalf("create bill: %v", errCreate)
+	}
+	expiresAt := now.AddDate(0, 2, 0)
+	card := models.PrepaidCard{Name: "card", CardSN: "sn-1", Password: "p", Amount: 5, Balance: 4, RedeemedUserID: &user.ID, RedeemedAt: &now, ExpiresAt: &expiresAt, IsEnabled: true, CreatedAt: now}
+	if errCreate := conn.Create(&card).Error; errCreate != nil {
+		t.Fatalf("create card: %v", errCreate)
+	}
+	usages := []models.Usage{
+		{Provider: "openai", Model: "gpt-5", UserID: &user.ID, RequestedAt: now, InputTokens: 10, TotalTokens: 15, CostMicros: 2_000_000},
+		{Provider: "openai", Model: "gpt-5", UserID: &user.ID, RequestedAt: now, Failed: true, TotalTokens: 5, CostMicros: 500_000},
+		{Provider: "claude", Model: "claude-sonnet", UserID: &user.ID, RequestedAt: now.AddDate(0, 0, -40), CostMicros: 9_000_000},
+	}
+	if errCreate := conn.Create(&usages).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	payer := Payer{UserID: user.ID}
+	balance, errBalance := LoadBalance(ctx, conn, payer, now)
+	if errBalance != nil {
+		t.Fatalf("LoadBalance: %v", errBalance)
+	}
+	if balance.BillLeftQuota != 80 || balance.PrepaidBalance != 4 || balance.Remaining() != 84 {
+		t.Fatalf("unexpected balance: %+v", balance)
+	}
+	if balance.UsedToday != 2.5 || balance.DailyLeft() != 7.5 {
+		t.Fatalf("unexpected daily usage: used=%v left=%v", balance.UsedToday, balance.DailyLeft())
+	}
+	if balance.AccessUntil == nil || !balance.AccessUntil.Equal(expiresAt) {
+		t.Fatalf("expected access until card expiry, got %v", balance.AccessUntil)
+	}
+
+	rows, errUsage := LoadModelUsage(ctx, conn, payer, now.AddDate(0, 0, -1), now.AddDate(0, 0, 1))
+	if errUsage != nil {
+		t.Fatalf("LoadModelUsage: %v", errUsage)
+	}
+	if len(rows) != 1 || rows[0].Model != "gpt-5" || rows[0].Requests != 2 || rows[0].FailedRequests != 1 || rows[0].CostMicros != 2_500_000 {
+		t.Fatalf("unexpected model usage: %+v", rows)
+	}
+}
+
+func TestLoadDailyModelUsage(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	usages := []models.Usage{
+		{Provider: "openai", Model: "gpt-5", UserID: &user.ID, RequestedAt: from.Add(time.Hour), CostMicros: 1_000_000},
+		{Provider: "openai", Model: "gpt-5", UserID: &user.ID, RequestedAt: from.Add(23 * time.Hour), CostMicros: 500_000},
+		{Provider: "claude", Model: "claude-sonnet", UserID: &user.ID, RequestedAt: from.Add(26 * time.Hour), CostMicros: 2_000_000},
+		{Provider: "openai", Model: "gpt-5", UserID: &user.ID, RequestedAt: from.Add(50 * time.Hour), CostMicros: 3_000_000},
+		{Provider: "openai", Model: "gpt-5", UserID: &user.ID, RequestedAt: from.Add(-time.Hour), CostMicros: 9_000_000},
+	}
+	if errCreate := conn.Create(&usages).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+
+	rows, errUsage := LoadDailyModelUsage(context.Background(), conn, Payer{UserID: user.ID}, from, from.AddDate(0, 0, 3))
+	if errUsage != nil {
+		t.Fatalf("LoadDailyModelUsage: %v", errUsage)
+	}
+	want := []DailyModelUsage{
+		{Day: from, Model: "gpt-5", CostMicros: 1_500_000},
+		{Day: from.AddDate(0, 0, 1), Model: "claude-sonnet", CostMicros: 2_000_000},
+		{Day: from.AddDate(0, 0, 2), Model: "gpt-5", CostMicros: 3_000_000},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %+v", len(want), rows)
+	}
+	for i := range want {
+		if !rows[i].Day.Equal(want[i].Day) || rows[i].Model != want[i].Model || rows[i].CostMicros != want[i].CostMicros {
+			t.Fatalf("row %d: got %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+}
@@ -16,6 +16,8 @@ const (
 	EnvDBConnection = "DB_CONNECTION"
 	EnvJWTSecret    = "JWT_SECRET"
 	EnvJWTExpiry    = "JWT_EXPIRY"
+	EnvMetricsToken = "METRICS_TOKEN"
+	EnvOTLPEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
 )
 
 // AppConfig holds resolved application configuration values.
@@ -116,3 +118,161 @@ func LoadJWTConfig(configPath string) (JWTConfig, error) {
 	}
 	return result, nil
 }
+
+// defaultMetricsPath is the route used when the config omits a metrics path.
+const defaultMetricsPath = "/metrics"
+
+// MetricsConfig holds Prometheus endpoint settings.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	Token   string `yaml:"token"`
+}
+
+// LoadMetricsConfig loads metrics endpoint settings from the YAML config file.
+// Fields omitted from the metrics block keep their defaults. The endpoint is enabled by
+// default only when a token is configured, since it exposes per-auth labels.
+func LoadMetricsConfig(configPath string) MetricsConfig {
+	// fileConfig maps the YAML fields needed for metrics settings.
+	type fileConfig struct {
+		Metrics struct {
+			Enabled *bool  `yaml:"enabled"`
+			Path    string `yaml:"path"`
+			Token   string `yaml:"token"`
+		} `yaml:"metrics"`
+	}
+
+	var cfg fileConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal != nil {
+			cfg = fileConfig{}
+		}
+	}
+
+	result := MetricsConfig{Path: strings.TrimSpace(cfg.Metrics.Path), Token: strings.TrimSpace(cfg.Metrics.Token)}
+	if token := strings.TrimSpace(os.Getenv(EnvMetricsToken)); token != "" {
+		result.Token = token
+	}
+	if cfg.Metrics.Enabled != nil {
+		result.Enabled = *cfg.Metrics.Enabled
+	} else {
+		result.Enabled = result.Token != ""
+	}
+
+	if result.Path == "" {
+		result.Path = defaultMetricsPath
+	}
+	if !strings.HasPrefix(result.Path, "/") {
+		result.Path = "/" + result.Path
+	}
+	return result
+}
+
+// Supported tracing exporters.
+const (
+	TracingExporterOTLP = "otlp"
+	TracingExporterFile = "file"
+)
+
+// defaultTracingServiceName identifies this service in exported traces.
+const defaultTracingServiceName = "cliproxy-business"
+
+// TracingConfig holds OpenTelemetry tracing settings.
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Exporter    string            `yaml:"exporter"`
+	Endpoint    string            `yaml:"endpoint"`
+	Insecure    bool              `yaml:"insecure"`
+	Headers     map[string]string `yaml:"headers"`
+	FilePath    string            `yaml:"file-path"`
+	ServiceName string            `yaml:"service-name"`
+	SampleRatio float64           `yaml:"sample-ratio"`
+}
+
+// LoadTracingConfig loads tracing settings from the YAML config file.
+func LoadTracingConfig(configPath string) TracingConfig {
+	// fileConfig maps the YAML fields needed for tracing settings.
+	type fileConfig struct {
+		Tracing TracingConfig `yaml:"tracing"`
+	}
+
+	var result TracingConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal == nil {
+			result = cfg.Tracing
+		}
+	}
+
+	if endpoint := strings.TrimSpace(os.Getenv(EnvOTLPEndpoint)); endpoint != "" && strings.TrimSpace(result.Endpoint) == "" {
+		result.Endpoint = endpoint
+	}
+
+	result.Exporter = strings.ToLower(strings.TrimSpace(result.Exporter))
+	if result.Exporter == "" {
+		result.Exporter = TracingExporterOTLP
+	}
+	result.Endpoint = strings.TrimSpace(result.Endpoint)
+	result.FilePath = strings.TrimSpace(result.FilePath)
+	result.ServiceName = strings.TrimSpace(result.ServiceName)
+	if result.ServiceName == "" {
+		result.ServiceName = defaultTracingServiceName
+	}
+	if result.SampleRatio <= 0 || result.SampleRatio > 1 {
+		result.SampleRatio = 1
+	}
+	return result
+}
+
+// URLList is a list of URLs that also accepts a single scalar URL in YAML.
+type URLList []string
+
+// UnmarshalYAML decodes either a scalar or a sequence of URLs.
+func (l *URLList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = URLList{value.Value}
+		return nil
+	}
+	var list []string
+	if errDecode := value.Decode(&list); errDecode != nil {
+		return errDecode
+	}
+	*l = list
+	return nil
+}
+
+// QuotaConfig holds quota poller settings.
+type QuotaConfig struct {
+	// Endpoints overrides the upstream quota URLs per provider, keyed by provider name
+	// (antigravity, codex, gemini-cli, claude, openai-compatibility).
+	Endpoints map[string]URLList `yaml:"endpoints"`
+}
+
+// LoadQuotaConfig loads quota poller settings from the YAML config file.
+func LoadQuotaConfig(configPath string) QuotaConfig {
+	// fileConfig maps the YAML fields needed for quota settings.
+	type fileConfig struct {
+		Quota QuotaConfig `yaml:"quota"`
+	}
+
+	var result QuotaConfig
+	data, errRead := os.ReadFile(configPath)
+	if errRead == nil {
+		var cfg fileConfig
+		if errUnmarshal := yaml.Unmarshal(data, &cfg); errUnmarshal == nil {
+			result = cfg.Quota
+		}
+	}
+	return result
+}
+
+// EndpointMap returns the endpoint overrides as plain string slices.
+func (c QuotaConfig) EndpointMap() map[string][]string {
+	out := make(map[string][]string, len(c.Endpoints))
+	for provider, urls := range c.Endpoints {
+		out[provider] = []string(urls)
+	}
+	return out
+}
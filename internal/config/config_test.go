@@ -40,3 +40,74 @@ func TestLoadJWTConfig_EnvOverride(t *testing.T) {
 		t.Fatalf("expected expiry=%s, got %s", (2 * time.Hour).String(), cfg.Expiry.String())
 	}
 }
+
+func TestLoadMetricsConfig_DefaultsAndEnvOverride(t *testing.T) {
+	cfg := LoadMetricsConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if cfg.Enabled || cfg.Path != "/metrics" || cfg.Token != "" {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+
+	t.Setenv("METRICS_TOKEN", "env-token")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("metrics:\n  enabled: true\n  path: internal/metrics\n  token: file-token\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg = LoadMetricsConfig(configPath)
+	if cfg.Path != "/internal/metrics" {
+		t.Fatalf("expected path=%q, got %q", "/internal/metrics", cfg.Path)
+	}
+	if cfg.Token != "env-token" {
+		t.Fatalf("expected token=%q, got %q", "env-token", cfg.Token)
+	}
+}
+
+func TestLoadMetricsConfig_PartialBlockKeepsDefaults(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("metrics:\n  token: file-token\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg := LoadMetricsConfig(configPath)
+	if !cfg.Enabled || cfg.Path != "/metrics" || cfg.Token != "file-token" {
+		t.Fatalf("expected token to enable metrics on the default path, got %+v", cfg)
+	}
+
+	if err := os.WriteFile(configPath, []byte("metrics:\n  path: /prom\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg = LoadMetricsConfig(configPath)
+	if cfg.Enabled || cfg.Path != "/prom" {
+		t.Fatalf("expected metrics off without a token, got %+v", cfg)
+	}
+
+	if err := os.WriteFile(configPath, []byte("metrics:\n  enabled: true\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if cfg = LoadMetricsConfig(configPath); !cfg.Enabled || cfg.Path != "/metrics" {
+		t.Fatalf("expected explicit enable on the default path, got %+v", cfg)
+	}
+
+	t.Setenv("METRICS_TOKEN", "env-token")
+	if err := os.WriteFile(configPath, []byte("metrics:\n  enabled: false\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if cfg = LoadMetricsConfig(configPath); cfg.Enabled {
+		t.Fatalf("expected explicit disable to win over the token, got %+v", cfg)
+	}
+}
+
+func TestLoadQuotaConfig_ScalarAndListEndpoints(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	body := "quota:\n  endpoints:\n    codex: http://127.0.0.1:9000/usage\n    antigravity:\n      - http://127.0.0.1:9000/a\n      - http://127.0.0.1:9000/b\n"
+	if err := os.WriteFile(configPath, []byte(body), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	endpoints := LoadQuotaConfig(configPath).EndpointMap()
+	if got := endpoints["codex"]; len(got) != 1 || got[0] != "http://127.0.0.1:9000/usage" {
+		t.Fatalf("unexpected codex endpoints: %v", got)
+	}
+	if got := endpoints["antigravity"]; len(got) != 2 || got[1] != "http://127.0.0.1:9000/b" {
+		t.Fatalf("unexpected antigravity endpoints: %v", got)
+	}
+}
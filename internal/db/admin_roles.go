@@ -0,0 +1,255 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// defaultAdminRole describes a role seeded by the admin_roles migration.
+type defaultAdminRole struct {
+	Name        string
+	Description string
+	Grants      []string
+}
+
+// defaultAdminRoles are the built-in roles seeded once, skipping names that already exist.
+var defaultAdminRoles = []defaultAdminRole{
+	{
+		Name:        "operator",
+		Description: "Manages upstream accounts, models, proxies and quotas.",
+		Grants: []string{
+			"module:Auth Files",
+			"module:Auth Groups",
+			"module:Auth Tokens",
+			"module:Dashboard",
+			"module:Logs:read",
+			"module:Models",
+			"module:Provider API Keys",
+			"module:Proxies",
+			"module:Quota",
+		},
+	},
+	{
+		Name:        "finance",
+		Description: "Manages plans, billing rules, bills and prepaid cards.",
+		Grants: []string{
+			"module:Billing",
+			"module:Billing Rules",
+			"module:Bills",
+			"module:Dashboard:read",
+			"module:Plans",
+			"module:Prepaid Cards",
+			"module:Usage",
+		},
+	},
+	{
+		Name:        "support",
+		Description: "Manages users and their API keys.",
+		Grants: []string{
+			"module:API Keys",
+			"module:Invite Codes",
+			"module:Logs:read",
+			"module:Usage:read",
+			"module:User Groups:read",
+			"module:Users",
+		},
+	},
+}
+
+// seedAdminRoles converts per-admin permission lists into roles and seeds the default roles.
+// It runs once as a migration, so roles deleted later are not recreated. Legacy permissions
+// are only converted while no roles exist yet.
+func seedAdminRoles(conn *gorm.DB) error {
+	var count int64
+	if errCount := conn.Model(&models.AdminRole{}).Count(&count).Error; errCount != nil {
+		return fmt.Errorf("db: count admin roles: %w", errCount)
+	}
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if count == 0 {
+			if errLegacy := migrateLegacyAdminPermissions(tx); errLegacy != nil {
+				return errLegacy
+			}
+		}
+		now := time.Now().UTC()
+		for _, seed := range defaultAdminRoles {
+			grants, errMarshal := json.Marshal(seed.Grants)
+			if errMarshal != nil {
+				return fmt.Errorf("db: marshal %s role: %w", seed.Name, errMarshal)
+			}
+			var existing int64
+			if errCount := tx.Model(&models.AdminRole{}).Where("name = ?", seed.Name).Count(&existing).Error; errCount != nil {
+				return fmt.Errorf("db: query %s role: %w", seed.Name, errCount)
+			}
+			if existing > 0 {
+				continue
+			}
+			role := models.AdminRole{
+				Name:        seed.Name,
+				Description: seed.Description,
+				Permissions: datatypes.JSON(grants),
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			if errCreate := tx.Create(&role).Error; errCreate != nil {
+				return fmt.Errorf("db: create %s role: %w", seed.Name, errCreate)
+			}
+		}
+		return nil
+	})
+}
+
+// unseedAdminRoles folds each admin's role grants back into its direct permissions, which is all
+// earlier releases read, then removes the default roles and their IDs from role assignments.
+// Roles converted from legacy permissions are kept.
+func unseedAdminRoles(conn *gorm.DB) error {
+	names := make([]string, 0, len(defaultAdminRoles))
+	for _, seed := range defaultAdminRoles {
+		names = append(names, seed.Name)
+	}
+	return conn.Transaction(func(tx *gorm.DB) error {
+		var roles []models.AdminRole
+		if errFind := tx.Select("id", "name", "permissions").Find(&roles).Error; errFind != nil {
+			return fmt.Errorf("db: list admin roles: %w", errFind)
+		}
+		grantsByRole := make(map[uint64][]string, len(roles))
+		removed := make(map[uint64]bool)
+		for _, role := range roles {
+			grantsByRole[role.ID] = legacyPermissionList(role.Permissions)
+			for _, name := range names {
+				if role.Name == name {
+					removed[role.ID] = true
+				}
+			}
+		}
+
+		var admins []models.Admin
+		if errFind := tx.Select("id", "permissions", "role_ids").
+			Where("is_super_admin = ?", false).
+			Order("id ASC").
+			Find(&admins).Error; errFind != nil {
+			return fmt.Errorf("db: list admins: %w", errFind)
+		}
+		for _, admin := range admins {
+			var roleIDs []uint64
+			if len(admin.RoleIDs) > 0 {
+				if errUnmarshal := json.Unmarshal(admin.RoleIDs, &roleIDs); errUnmarshal != nil {
+					return fmt.Errorf("db: decode admin %d roles: %w", admin.ID, errUnmarshal)
+				}
+			}
+			if len(roleIDs) == 0 {
+				continue
+			}
+			perms := legacyPermissionList(admin.Permissions)
+			kept := make([]uint64, 0, len(roleIDs))
+			for _, roleID := range roleIDs {
+				perms = append(perms, grantsByRole[roleID]...)
+				if !removed[roleID] {
+					kept = append(kept, roleID)
+				}
+			}
+			merged, errMarshal := json.Marshal(perms)
+			if errMarshal != nil {
+				return fmt.Errorf("db: marshal admin %d permissions: %w", admin.ID, errMarshal)
+			}
+			encodedPerms, errMarshal := json.Marshal(legacyPermissionList(merged))
+			if errMarshal != nil {
+				return fmt.Errorf("db: marshal admin %d permissions: %w", admin.ID, errMarshal)
+			}
+			encodedRoles, errMarshal := json.Marshal(kept)
+			if errMarshal != nil {
+				return fmt.Errorf("db: marshal admin %d roles: %w", admin.ID, errMarshal)
+			}
+			if errUpdate := tx.Model(&models.Admin{}).Where("id = ?", admin.ID).Updates(map[string]any{
+				"permissions": datatypes.JSON(encodedPerms),
+				"role_ids":    datatypes.JSON(encodedRoles),
+			}).Error; errUpdate != nil {
+				return fmt.Errorf("db: restore admin %d permissions: %w", admin.ID, errUpdate)
+			}
+		}
+
+		if errDelete := tx.Where("name IN ?", names).Delete(&models.AdminRole{}).Error; errDelete != nil {
+			return fmt.Errorf("db: delete default admin roles: %w", errDelete)
+		}
+		return nil
+	})
+}
+
+// migrateLegacyAdminPermissions moves each distinct per-admin permission list into a legacy role.
+func migrateLegacyAdminPermissions(tx *gorm.DB) error {
+	var admins []models.Admin
+	if errFind := tx.Select("id", "username", "permissions", "is_super_admin").
+		Where("is_super_admin = ?", false).
+		Order("id ASC").
+		Find(&admins).Error; errFind != nil {
+		return fmt.Errorf("db: list admins: %w", errFind)
+	}
+
+	roleBySet := make(map[string]uint64)
+	now := time.Now().UTC()
+	for _, admin := range admins {
+		perms := legacyPermissionList(admin.Permissions)
+		if len(perms) == 0 {
+			continue
+		}
+		encoded, errMarshal := json.Marshal(perms)
+		if errMarshal != nil {
+			return fmt.Errorf("db: marshal admin %d permissions: %w", admin.ID, errMarshal)
+		}
+		roleID, ok := roleBySet[string(encoded)]
+		if !ok {
+			role := models.AdminRole{
+				Name:        "legacy-" + admin.Username,
+				Description: "Migrated from per-admin permissions.",
+				Permissions: datatypes.JSON(encoded),
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			if errCreate := tx.Create(&role).Error; errCreate != nil {
+				return fmt.Errorf("db: create legacy role for admin %d: %w", admin.ID, errCreate)
+			}
+			roleID = role.ID
+			roleBySet[string(encoded)] = roleID
+		}
+		roleIDs, _ := json.Marshal([]uint64{roleID})
+		if errUpdate := tx.Model(&models.Admin{}).Where("id = ?", admin.ID).Updates(map[string]any{
+			"role_ids":    datatypes.JSON(roleIDs),
+			"permissions": datatypes.JSON([]byte("[]")),
+		}).Error; errUpdate != nil {
+			return fmt.Errorf("db: assign legacy role to admin %d: %w", admin.ID, errUpdate)
+		}
+	}
+	return nil
+}
+
+// legacyPermissionList decodes, de-duplicates and sorts a stored permission list.
+func legacyPermissionList(raw []byte) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var perms []string
+	if errUnmarshal := json.Unmarshal(raw, &perms); errUnmarshal != nil {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(perms))
+	out := make([]string, 0, len(perms))
+	for _, perm := range perms {
+		trimmed := strings.TrimSpace(perm)
+		if trimmed == "" {
+			continue
+		}
+		if _, ok := seen[trimmed]; ok {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		out = append(out, trimmed)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -13,22 +13,47 @@ import (
 	"gorm.io/gorm"
 )
 
-// Migrate runs database migrations for the current dialect.
-func Migrate(conn *gorm.DB) error {
-	if conn == nil {
-		return fmt.Errorf("db: nil connection")
-	}
-	switch DialectName(conn) {
-	case DialectSQLite:
-		return migrateSQLite(conn)
-	case DialectPostgres, "":
-		return migratePostgres(conn)
-	default:
-		return fmt.Errorf("db: unsupported dialect: %s", DialectName(conn))
+// Models returns every model managed by the migrations, in migration order.
+func Models() []any {
+	all := append(baselineModels(), mfaCredentialModels()...)
+	return append(all, &models.UserModelOverride{}, &models.ModelCatalogEntry{}, &models.QuotaSnapshot{}, &models.ReportSchedule{}, &models.SpendAnomaly{})
+}
+
+// baselineModels returns the models created by the baseline migration.
+func baselineModels() []any {
+	return []any{
+		&models.Admin{},
+		&models.Plan{},
+		&models.UserGroup{},
+		&models.AuthGroup{},
+		&models.User{},
+		&models.Auth{},
+		&models.Quota{},
+		&models.APIKey{},
+		&models.Usage{},
+		&models.Bill{},
+		&models.BillingRule{},
+		&models.ModelMapping{},
+		&models.UserModelAuthBinding{},
+		&models.ModelPayloadRule{},
+		&models.ProviderAPIKey{},
+		&models.Proxy{},
+		&models.PrepaidCard{},
+		&models.Setting{},
+		&models.AuditLog{},
+		&models.AdminRole{},
+		&models.Organization{},
+		&models.OrganizationMember{},
+		&models.OrganizationInvitation{},
+		&models.InviteCode{},
+		&models.EmailVerification{},
+		&models.OIDCProvider{},
+		&models.SSOIdentity{},
+		&models.SSOLoginState{},
 	}
 }
 
-// migratePostgres applies PostgreSQL-specific schema updates and indexes.
+// migratePostgres applies PostgreSQL-specific schema updates and indexes; it is baseline migration 1.
 func migratePostgres(conn *gorm.DB) error {
 	if errRename := conn.Exec(`
 		DO $$
@@ -48,26 +73,7 @@ func migratePostgres(conn *gorm.DB) error {
 		return errPreUserGroup
 	}
 
-	if errAutoMigrate := conn.AutoMigrate(
-		&models.Admin{},
-		&models.Plan{},
-		&models.UserGroup{},
-		&models.AuthGroup{},
-		&models.User{},
-		&models.Auth{},
-		&models.Quota{},
-		&models.APIKey{},
-		&models.Usage{},
-		&models.Bill{},
-		&models.BillingRule{},
-		&models.ModelMapping{},
-		&models.UserModelAuthBinding{},
-		&models.ModelPayloadRule{},
-		&models.ProviderAPIKey{},
-		&models.Proxy{},
-		&models.PrepaidCard{},
-		&models.Setting{},
-	); errAutoMigrate != nil {
+	if errAutoMigrate := conn.AutoMigrate(baselineModels()...); errAutoMigrate != nil {
 		return fmt.Errorf("db: migrate: %w", errAutoMigrate)
 	}
 	if errSeed := ensureDefaultGroups(conn); errSeed != nil {
@@ -85,6 +91,9 @@ func migratePostgres(conn *gorm.DB) error {
 	if errSeed := ensureRateLimitSetting(conn); errSeed != nil {
 		return errSeed
 	}
+	if errSeed := ensureRegistrationSettings(conn); errSeed != nil {
+		return errSeed
+	}
 	if errAuthGroup := migrateAuthGroupIDsPostgres(conn); errAuthGroup != nil {
 		return errAuthGroup
 	}
@@ -706,7 +715,7 @@ func migratePostgres(conn *gorm.DB) error {
 	return nil
 }
 
-// migrateSQLite applies SQLite-specific schema updates and indexes.
+// migrateSQLite applies SQLite-specific schema updates and indexes; it is baseline migration 1.
 func migrateSQLite(conn *gorm.DB) error {
 	if errFix := fixSQLiteTimestampColumns(conn); errFix != nil {
 		return errFix
@@ -716,26 +725,7 @@ func migrateSQLite(conn *gorm.DB) error {
 		return fmt.Errorf("db: rename recharge_cards: %w", errRename)
 	}
 
-	if errAutoMigrate := conn.AutoMigrate(
-		&models.Admin{},
-		&models.Plan{},
-		&models.UserGroup{},
-		&models.AuthGroup{},
-		&models.User{},
-		&models.Auth{},
-		&models.Quota{},
-		&models.APIKey{},
-		&models.Usage{},
-		&models.Bill{},
-		&models.BillingRule{},
-		&models.ModelMapping{},
-		&models.UserModelAuthBinding{},
-		&models.ModelPayloadRule{},
-		&models.ProviderAPIKey{},
-		&models.Proxy{},
-		&models.PrepaidCard{},
-		&models.Setting{},
-	); errAutoMigrate != nil {
+	if errAutoMigrate := conn.AutoMigrate(baselineModels()...); errAutoMigrate != nil {
 		return fmt.Errorf("db: migrate: %w", errAutoMigrate)
 	}
 	if errSeed := ensureDefaultGroups(conn); errSeed != nil {
@@ -753,6 +743,9 @@ func migrateSQLite(conn *gorm.DB) error {
 	if errSeed := ensureRateLimitSetting(conn); errSeed != nil {
 		return errSeed
 	}
+	if errSeed := ensureRegistrationSettings(conn); errSeed != nil {
+		return errSeed
+	}
 	if errAuthGroup := migrateAuthGroupIDsSQLite(conn); errAuthGroup != nil {
 		return errAuthGroup
 	}
@@ -1543,6 +1536,61 @@ func ensureRateLimitSetting(conn *gorm.DB) error {
 	return ensureIntSetting(conn, internalsettings.RateLimitKey, internalsettings.DefaultRateLimit)
 }
 
+// ensureRegistrationSettings ensures the registration policy settings exist with defaults.
+func ensureRegistrationSettings(conn *gorm.DB) error {
+	if errEnsure := ensureStringSetting(
+		conn,
+		internalsettings.RegistrationModeKey,
+		internalsettings.DefaultRegistrationMode,
+	); errEnsure != nil {
+		return errEnsure
+	}
+	if errEnsure := ensureBoolSetting(conn, internalsettings.RegistrationRequireEmailVerificationKey, false); errEnsure != nil {
+		return errEnsure
+	}
+	return ensureIntSetting(
+		conn,
+		internalsettings.RegistrationIPLimitPerHourKey,
+		internalsettings.DefaultRegistrationIPLimitPerHour,
+	)
+}
+
+// ensureStringSetting ensures a string setting exists and defaults when empty.
+func ensureStringSetting(conn *gorm.DB, key string, value string) error {
+	payload, errMarshal := json.Marshal(value)
+	if errMarshal != nil {
+		return fmt.Errorf("db: marshal %s setting: %w", key, errMarshal)
+	}
+	rawValue := json.RawMessage(payload)
+
+	var existing models.Setting
+	if errFind := conn.Where("key = ?", key).First(&existing).Error; errFind == nil {
+		trimmed := strings.TrimSpace(string(existing.Value))
+		if len(existing.Value) == 0 || trimmed == "" || trimmed == "null" {
+			if errUpdate := conn.Model(&existing).Updates(map[string]any{
+				"value":      rawValue,
+				"updated_at": time.Now().UTC(),
+			}).Error; errUpdate != nil {
+				return fmt.Errorf("db: update %s setting: %w", key, errUpdate)
+			}
+		}
+		return nil
+	} else if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("db: query %s setting: %w", key, errFind)
+	}
+
+	now := time.Now().UTC()
+	setting := models.Setting{
+		Key:       key,
+		Value:     rawValue,
+		UpdatedAt: now,
+	}
+	if errCreate := conn.Create(&setting).Error; errCreate != nil {
+		return fmt.Errorf("db: create %s setting: %w", key, errCreate)
+	}
+	return nil
+}
+
 // ensureIntSetting ensures an integer setting exists and defaults when empty.
 func ensureIntSetting(conn *gorm.DB, key string, value int) error {
 	payload, errMarshal := json.Marshal(value)
@@ -1853,3 +1901,137 @@ func quoteSQLiteIdentifier(name string) string {
 	}
 	return "\"" + strings.ReplaceAll(name, "\"", "\"\"") + "\""
 }
+
+// legacyPasskey holds the single passkey formerly stored on users and admins.
+type legacyPasskey struct {
+	ID                    uint64 // Owner ID.
+	PasskeyID             []byte // WebAuthn credential ID.
+	PasskeyPublicKey      []byte // WebAuthn public key bytes.
+	PasskeySignCount      *int64 // WebAuthn signature counter.
+	PasskeyBackupEligible *bool  // WebAuthn backup eligibility flag.
+	PasskeyBackupState    *bool  // WebAuthn backup state flag.
+}
+
+// migrateLegacyPasskeys moves single-passkey columns into passkey_credentials and clears them.
+func migrateLegacyPasskeys(conn *gorm.DB) error {
+	owners := []struct {
+		table string
+		kind  string
+	}{
+		{table: "users", kind: models.MFAOwnerUser},
+		{table: "admins", kind: models.MFAOwnerAdmin},
+	}
+	for _, owner := range owners {
+		if !conn.Migrator().HasColumn(owner.table, "passkey_id") {
+			continue
+		}
+		var rows []legacyPasskey
+		if errFind := conn.Table(owner.table).
+			Select("id", "passkey_id", "passkey_public_key", "passkey_sign_count", "passkey_backup_eligible", "passkey_backup_state").
+			Where("passkey_id IS NOT NULL AND passkey_public_key IS NOT NULL").
+			Find(&rows).Error; errFind != nil {
+			return fmt.Errorf("db: load legacy %s passkeys: %w", owner.kind, errFind)
+		}
+		for _, row := range rows {
+			if len(row.PasskeyID) == 0 || len(row.PasskeyPublicKey) == 0 {
+				continue
+			}
+			errTx := conn.Transaction(func(tx *gorm.DB) error {
+				var existing int64
+				if errCount := tx.Model(&models.PasskeyCredential{}).
+					Where("credential_id = ?", row.PasskeyID).
+					Count(&existing).Error; errCount != nil {
+					return errCount
+				}
+				if existing == 0 {
+					credential := models.PasskeyCredential{
+						OwnerKind:      owner.kind,
+						OwnerID:        row.ID,
+						Name:           "Passkey",
+						CredentialID:   row.PasskeyID,
+						PublicKey:      row.PasskeyPublicKey,
+						BackupEligible: row.PasskeyBackupEligible,
+						BackupState:    row.PasskeyBackupState,
+						CreatedAt:      time.Now().UTC(),
+					}
+					if row.PasskeySignCount != nil && *row.PasskeySignCount > 0 {
+						credential.SignCount = uint32(*row.PasskeySignCount)
+					}
+					if errCreate := tx.Create(&credential).Error; errCreate != nil {
+						return errCreate
+					}
+				}
+				return tx.Table(owner.table).Where("id = ?", row.ID).Updates(map[string]any{
+					"passkey_id":              nil,
+					"passkey_public_key":      nil,
+					"passkey_sign_count":      nil,
+					"passkey_backup_eligible": nil,
+					"passkey_backup_state":    nil,
+				}).Error
+			})
+			if errTx != nil {
+				return fmt.Errorf("db: migrate legacy %s passkey: %w", owner.kind, errTx)
+			}
+		}
+	}
+	return nil
+}
+
+// legacyPasskeyColumns lists the single-passkey columns and their types on users and admins.
+var legacyPasskeyColumns = []struct {
+	name    string
+	sqlType string
+}{
+	{name: "passkey_id", sqlType: "bytea"},
+	{name: "passkey_public_key", sqlType: "bytea"},
+	{name: "passkey_sign_count", sqlType: "bigint"},
+	{name: "passkey_backup_eligible", sqlType: "boolean"},
+	{name: "passkey_backup_state", sqlType: "boolean"},
+}
+
+// restoreLegacyPasskeys copies each owner's oldest passkey back into the single-passkey columns,
+// adding the columns where the schema lacks them. Later credentials have no legacy slot.
+func restoreLegacyPasskeys(conn *gorm.DB) error {
+	owners := []struct {
+		table string
+		kind  string
+	}{
+		{table: "users", kind: models.MFAOwnerUser},
+		{table: "admins", kind: models.MFAOwnerAdmin},
+	}
+	for _, owner := range owners {
+		var credentials []models.PasskeyCredential
+		if errFind := conn.Where("owner_kind = ?", owner.kind).Order("owner_id ASC, id ASC").Find(&credentials).Error; errFind != nil {
+			return fmt.Errorf("db: load %s passkeys: %w", owner.kind, errFind)
+		}
+		if len(credentials) == 0 {
+			continue
+		}
+		for _, column := range legacyPasskeyColumns {
+			if conn.Migrator().HasColumn(owner.table, column.name) {
+				continue
+			}
+			stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", owner.table, column.name, column.sqlType)
+			if errAdd := conn.Exec(stmt).Error; errAdd != nil {
+				return fmt.Errorf("db: add %s.%s: %w", owner.table, column.name, errAdd)
+			}
+		}
+		restored := make(map[uint64]bool, len(credentials))
+		for _, credential := range credentials {
+			if restored[credential.OwnerID] {
+				continue
+			}
+			restored[credential.OwnerID] = true
+			if errUpdate := conn.Table(owner.table).Where("id = ?", credential.OwnerID).Updates(map[string]any{
+				"passkey_id":              credential.CredentialID,
+				"passkey_public_key":      credential.PublicKey,
+				"passkey_sign_count":      int64(credential.SignCount),
+				"passkey_backup_eligible": credential.BackupEligible,
+				"passkey_backup_state":    credential.BackupState,
+			}).Error; errUpdate != nil {
+				return fmt.Errorf("db: restore legacy %s passkey: %w", owner.kind, errUpdate)
+			}
+		}
+	}
+	return nil
+}
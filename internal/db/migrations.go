@@ -0,0 +1,459 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	// ErrSchemaTooNew indicates the database has migrations this binary does not know about.
+	ErrSchemaTooNew = errors.New("db: database schema is newer than this binary")
+	// ErrIrreversible indicates a rollback crosses a migration without a down step.
+	ErrIrreversible = errors.New("db: migration cannot be reverted")
+)
+
+// Migration is a numbered schema change for one dialect.
+type Migration struct {
+	Version int64                     // Monotonic step number.
+	Name    string                    // Short snake_case description.
+	Up      func(conn *gorm.DB) error // Applies the step.
+	Down    func(conn *gorm.DB) error // Reverts the step; nil when irreversible.
+}
+
+// MigrationState describes a registered migration and whether it has been applied.
+type MigrationState struct {
+	Version    int64
+	Name       string
+	Applied    bool
+	AppliedAt  *time.Time
+	Reversible bool
+}
+
+// PendingStep lists the SQL a pending migration would execute.
+type PendingStep struct {
+	Version    int64
+	Name       string
+	Statements []string
+}
+
+// postgresMigrations returns the PostgreSQL migration registry.
+func postgresMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "baseline", Up: migratePostgres},
+		{Version: 2, Name: "mfa_credentials", Up: upMFACredentials, Down: downMFACredentials},
+		{Version: 3, Name: "user_model_overrides", Up: upUserModelOverrides, Down: downUserModelOverrides},
+		{Version: 4, Name: "model_catalog", Up: upModelCatalog, Down: downModelCatalog},
+		{Version: 5, Name: "quota_windows", Up: upQuotaWindows, Down: downQuotaWindows},
+		{Version: 6, Name: "quota_snapshots", Up: upQuotaSnapshots, Down: downQuotaSnapshots},
+		{Version: 7, Name: "auth_cost_basis", Up: upAuthCostBasis, Down: downAuthCostBasis},
+		{Version: 8, Name: "report_schedules", Up: upReportSchedules, Down: downReportSchedules},
+		{Version: 9, Name: "usage_request_details", Up: upUsageRequestDetails, Down: downUsageRequestDetails},
+		{Version: 10, Name: "spend_anomalies", Up: upSpendAnomalies, Down: downSpendAnomalies},
+		{Version: 11, Name: "admin_roles", Up: seedAdminRoles, Down: unseedAdminRoles},
+	}
+}
+
+// sqliteMigrations returns the SQLite migration registry.
+func sqliteMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "baseline", Up: migrateSQLite},
+		{Version: 2, Name: "mfa_credentials", Up: upMFACredentials, Down: downMFACredentials},
+		{Version: 3, Name: "user_model_overrides", Up: upUserModelOverrides, Down: downUserModelOverrides},
+		{Version: 4, Name: "model_catalog", Up: upModelCatalog, Down: downModelCatalog},
+		{Version: 5, Name: "quota_windows", Up: upQuotaWindows, Down: downQuotaWindows},
+		{Version: 6, Name: "quota_snapshots", Up: upQuotaSnapshots, Down: downQuotaSnapshots},
+		{Version: 7, Name: "auth_cost_basis", Up: upAuthCostBasis, Down: downAuthCostBasis},
+		{Version: 8, Name: "report_schedules", Up: upReportSchedules, Down: downReportSchedules},
+		{Version: 9, Name: "usage_request_details", Up: upUsageRequestDetails, Down: downUsageRequestDetails},
+		{Version: 10, Name: "spend_anomalies", Up: upSpendAnomalies, Down: downSpendAnomalies},
+		{Version: 11, Name: "admin_roles", Up: seedAdminRoles, Down: unseedAdminRoles},
+	}
+}
+
+// Migrations returns the migration registry for the connection's dialect.
+func Migrations(conn *gorm.DB) ([]Migration, error) {
+	switch DialectName(conn) {
+	case DialectSQLite:
+		return sqliteMigrations(), nil
+	case DialectPostgres, "":
+		return postgresMigrations(), nil
+	default:
+		return nil, fmt.Errorf("db: unsupported dialect: %s", DialectName(conn))
+	}
+}
+
+// Migrate applies pending migrations and refuses to run against a schema newer than this binary.
+func Migrate(conn *gorm.DB) error {
+	if conn == nil {
+		return fmt.Errorf("db: nil connection")
+	}
+	migrations, applied, errLoad := loadMigrations(conn)
+	if errLoad != nil {
+		return errLoad
+	}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if errUp := migration.Up(conn); errUp != nil {
+			return fmt.Errorf("db: migration %d %s: %w", migration.Version, migration.Name, errUp)
+		}
+		record := models.SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}
+		if errRecord := conn.Create(&record).Error; errRecord != nil {
+			return fmt.Errorf("db: record migration %d: %w", migration.Version, errRecord)
+		}
+	}
+	return nil
+}
+
+// MigrateDown reverts applied migrations above target, newest first.
+func MigrateDown(conn *gorm.DB, target int64) ([]Migration, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("db: nil connection")
+	}
+	migrations, applied, errLoad := loadMigrations(conn)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+
+	var toRevert []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version <= target {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return nil, fmt.Errorf("%w: %d %s", ErrIrreversible, migration.Version, migration.Name)
+		}
+		toRevert = append(toRevert, migration)
+	}
+
+	reverted := make([]Migration, 0, len(toRevert))
+	for _, migration := range toRevert {
+		if errDown := migration.Down(conn); errDown != nil {
+			return reverted, fmt.Errorf("db: revert migration %d %s: %w", migration.Version, migration.Name, errDown)
+		}
+		if errDelete := conn.Where("version = ?", migration.Version).Delete(&models.SchemaMigration{}).Error; errDelete != nil {
+			return reverted, fmt.Errorf("db: unrecord migration %d: %w", migration.Version, errDelete)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// MigrationStatus lists registered migrations with their applied state without writing to the database.
+func MigrationStatus(conn *gorm.DB) ([]MigrationState, error) {
+	migrations, applied, errLoad := loadMigrationsReadOnly(conn)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{
+			Version:    migration.Version,
+			Name:       migration.Name,
+			Reversible: migration.Down != nil,
+		}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			state.Applied = true
+			state.AppliedAt = &appliedAt
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// errDryRunRollback aborts the dry-run transaction after pending migrations ran.
+var errDryRunRollback = errors.New("db: dry run rollback")
+
+// PendingSQL runs pending migrations inside a transaction that is rolled back and returns the statements each executed.
+func PendingSQL(conn *gorm.DB) ([]PendingStep, error) {
+	migrations, applied, errLoad := loadMigrations(conn)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	steps := make([]PendingStep, 0)
+	errTx := conn.Session(&gorm.Session{Logger: recorder}).Transaction(func(tx *gorm.DB) error {
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			recorder.statements = nil
+			if errUp := migration.Up(tx); errUp != nil {
+				return fmt.Errorf("db: migration %d %s: %w", migration.Version, migration.Name, errUp)
+			}
+			steps = append(steps, PendingStep{Version: migration.Version, Name: migration.Name, Statements: recorder.statements})
+		}
+		return errDryRunRollback
+	})
+	if !errors.Is(errTx, errDryRunRollback) {
+		return steps, errTx
+	}
+	return steps, nil
+}
+
+// loadMigrations creates the schema_migrations table when missing and returns the registry and
+// applied records, rejecting unknown applied versions.
+func loadMigrations(conn *gorm.DB) ([]Migration, map[int64]models.SchemaMigration, error) {
+	if errTable := conn.AutoMigrate(&models.SchemaMigration{}); errTable != nil {
+		return nil, nil, fmt.Errorf("db: migrate schema_migrations: %w", errTable)
+	}
+	return loadMigrationsReadOnly(conn)
+}
+
+// loadMigrationsReadOnly is loadMigrations without creating schema_migrations; a missing table
+// means no migration has been applied.
+func loadMigrationsReadOnly(conn *gorm.DB) ([]Migration, map[int64]models.SchemaMigration, error) {
+	migrations, errRegistry := Migrations(conn)
+	if errRegistry != nil {
+		return nil, nil, errRegistry
+	}
+	var rows []models.SchemaMigration
+	if conn.Migrator().HasTable(&models.SchemaMigration{}) {
+		if errFind := conn.Order("version ASC").Find(&rows).Error; errFind != nil {
+			return nil, nil, fmt.Errorf("db: load schema_migrations: %w", errFind)
+		}
+	}
+
+	known := make(map[int64]struct{}, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = struct{}{}
+	}
+	applied := make(map[int64]models.SchemaMigration, len(rows))
+	var unknown []string
+	for _, row := range rows {
+		if _, ok := known[row.Version]; !ok {
+			unknown = append(unknown, fmt.Sprintf("%d %s", row.Version, row.Name))
+		}
+		applied[row.Version] = row
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, nil, fmt.Errorf("%w: unknown applied migrations: %s", ErrSchemaTooNew, strings.Join(unknown, ", "))
+	}
+	return migrations, applied, nil
+}
+
+// sqlRecorder captures statements traced during a dry run, skipping read-only queries.
+type sqlRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+// LogMode keeps the recorder when GORM derives a session logger.
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface { return r }
+
+// Trace records the rendered SQL of a statement.
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	sql = strings.TrimSpace(sql)
+	upper := strings.ToUpper(sql)
+	if sql == "" || strings.HasPrefix(upper, "SELECT") || (strings.HasPrefix(upper, "PRAGMA") && !strings.Contains(sql, "=")) {
+		return
+	}
+	r.statements = append(r.statements, sql)
+}
+
+// mfaCredentialModels returns the models created by the mfa_credentials migration.
+func mfaCredentialModels() []any {
+	return []any{&models.PasskeyCredential{}, &models.RecoveryCode{}}
+}
+
+// upMFACredentials creates passkey and recovery code tables and moves legacy single passkeys into them.
+func upMFACredentials(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(mfaCredentialModels()...); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate mfa credentials: %w", errAutoMigrate)
+	}
+	if errLegacy := migrateLegacyPasskeys(conn); errLegacy != nil {
+		return errLegacy
+	}
+	return ensureBoolSetting(conn, internalsettings.AdminMFARequiredKey, false)
+}
+
+// downMFACredentials moves one passkey per owner back into the legacy columns, then drops passkey
+// and recovery code tables and the admin MFA policy setting.
+func downMFACredentials(conn *gorm.DB) error {
+	if errRestore := restoreLegacyPasskeys(conn); errRestore != nil {
+		return errRestore
+	}
+	if errDrop := conn.Migrator().DropTable(mfaCredentialModels()...); errDrop != nil {
+		return fmt.Errorf("db: drop mfa credentials: %w", errDrop)
+	}
+	if errDelete := conn.Where("key = ?", internalsettings.AdminMFARequiredKey).Delete(&models.Setting{}).Error; errDelete != nil {
+		return fmt.Errorf("db: delete %s setting: %w", internalsettings.AdminMFARequiredKey, errDelete)
+	}
+	return nil
+}
+
+// upUserModelOverrides creates the per-user model access override table.
+func upUserModelOverrides(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(&models.UserModelOverride{}); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate user model overrides: %w", errAutoMigrate)
+	}
+	return nil
+}
+
+// downUserModelOverrides drops the per-user model access override table.
+func downUserModelOverrides(conn *gorm.DB) error {
+	if errDrop := conn.Migrator().DropTable(&models.UserModelOverride{}); errDrop != nil {
+		return fmt.Errorf("db: drop user model overrides: %w", errDrop)
+	}
+	return nil
+}
+
+// upModelCatalog creates the model catalogue metadata table.
+func upModelCatalog(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(&models.ModelCatalogEntry{}); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate model catalog: %w", errAutoMigrate)
+	}
+	return nil
+}
+
+// downModelCatalog drops the model catalogue metadata table.
+func downModelCatalog(conn *gorm.DB) error {
+	if errDrop := conn.Migrator().DropTable(&models.ModelCatalogEntry{}); errDrop != nil {
+		return fmt.Errorf("db: drop model catalog: %w", errDrop)
+	}
+	return nil
+}
+
+// upQuotaWindows adds the runtime auth key and normalized window columns to quota rows.
+func upQuotaWindows(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(&models.Quota{}); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate quota windows: %w", errAutoMigrate)
+	}
+	return nil
+}
+
+// downQuotaWindows drops the runtime auth key and normalized window columns from quota rows.
+func downQuotaWindows(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	for _, column := range []string{"Windows", "AuthKey"} {
+		if !migrator.HasColumn(&models.Quota{}, column) {
+			continue
+		}
+		if errDrop := migrator.DropColumn(&models.Quota{}, column); errDrop != nil {
+			return fmt.Errorf("db: drop quota %s: %w", column, errDrop)
+		}
+	}
+	return nil
+}
+
+// upQuotaSnapshots creates the quota history table.
+func upQuotaSnapshots(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(&models.QuotaSnapshot{}); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate quota snapshots: %w", errAutoMigrate)
+	}
+	return nil
+}
+
+// downQuotaSnapshots drops the quota history table.
+func downQuotaSnapshots(conn *gorm.DB) error {
+	if errDrop := conn.Migrator().DropTable(&models.QuotaSnapshot{}); errDrop != nil {
+		return fmt.Errorf("db: drop quota snapshots: %w", errDrop)
+	}
+	return nil
+}
+
+// upAuthCostBasis adds the monthly cost basis column to auths.
+func upAuthCostBasis(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(&models.Auth{}); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate auth cost basis: %w", errAutoMigrate)
+	}
+	return nil
+}
+
+// downAuthCostBasis drops the monthly cost basis column from auths.
+func downAuthCostBasis(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	if !migrator.HasColumn(&models.Auth{}, "MonthlyCostMicros") {
+		return nil
+	}
+	if errDrop := migrator.DropColumn(&models.Auth{}, "MonthlyCostMicros"); errDrop != nil {
+		return fmt.Errorf("db: drop auth monthly cost: %w", errDrop)
+	}
+	return nil
+}
+
+// upReportSchedules creates the report schedule table and adds usage summary preferences to users.
+func upReportSchedules(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(&models.ReportSchedule{}, &models.User{}); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate report schedules: %w", errAutoMigrate)
+	}
+	return nil
+}
+
+// downReportSchedules drops the report schedule table and the user usage summary preferences.
+func downReportSchedules(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	if errDrop := migrator.DropTable(&models.ReportSchedule{}); errDrop != nil {
+		return fmt.Errorf("db: drop report schedules: %w", errDrop)
+	}
+	for _, column := range []string{"UsageReportSentAt", "UsageReportFrequency"} {
+		if !migrator.HasColumn(&models.User{}, column) {
+			continue
+		}
+		if errDrop := migrator.DropColumn(&models.User{}, column); errDrop != nil {
+			return fmt.Errorf("db: drop user %s: %w", column, errDrop)
+		}
+	}
+	return nil
+}
+
+// usageRequestDetailColumns lists the per-request detail columns added to usages.
+var usageRequestDetailColumns = []string{
+	"RequestID", "UpstreamStatus", "ErrorCode", "ErrorMessage", "TimeToFirstTokenMs",
+	"DurationMs", "Streaming", "ClientIP", "UserAgent",
+}
+
+// upUsageRequestDetails adds per-request status, latency and client columns to usages.
+func upUsageRequestDetails(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(&models.Usage{}); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate usage request details: %w", errAutoMigrate)
+	}
+	return nil
+}
+
+// downUsageRequestDetails drops the per-request detail columns from usages.
+func downUsageRequestDetails(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	for _, column := range usageRequestDetailColumns {
+		if !migrator.HasColumn(&models.Usage{}, column) {
+			continue
+		}
+		if errDrop := migrator.DropColumn(&models.Usage{}, column); errDrop != nil {
+			return fmt.Errorf("db: drop usage %s: %w", column, errDrop)
+		}
+	}
+	return nil
+}
+
+// upSpendAnomalies creates the spend anomaly table.
+func upSpendAnomalies(conn *gorm.DB) error {
+	if errAutoMigrate := conn.AutoMigrate(&models.SpendAnomaly{}); errAutoMigrate != nil {
+		return fmt.Errorf("db: migrate spend anomalies: %w", errAutoMigrate)
+	}
+	return nil
+}
+
+// downSpendAnomalies drops the spend anomaly table.
+func downSpendAnomalies(conn *gorm.DB) error {
+	if errDrop := conn.Migrator().DropTable(&models.SpendAnomaly{}); errDrop != nil {
+		return fmt.Errorf("db: drop spend anomalies: %w", errDrop)
+	}
+	return nil
+}
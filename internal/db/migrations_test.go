@@ -0,0 +1,193 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestMigrationsRegistry(t *testing.T) {
+	conn, errOpen := Open("file:" + filepath.Join(t.TempDir(), "migrations.db"))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+
+	pending, errPending := PendingSQL(conn)
+	if errPending != nil {
+		t.Fatalf("PendingSQL: %v", errPending)
+	}
+	if len(pending) != len(sqliteMigrations()) {
+		t.Fatalf("expected every migration pending, got %d", len(pending))
+	}
+	if !strings.Contains(strings.Join(pending[1].Statements, "\n"), "passkey_credentials") {
+		t.Fatalf("expected dry run to render passkey table DDL, got %v", pending[1].Statements)
+	}
+	if conn.Migrator().HasTable(&models.User{}) {
+		t.Fatalf("dry run must not create tables")
+	}
+
+	for i := 0; i < 2; i++ {
+		if errMigrate := Migrate(conn); errMigrate != nil {
+			t.Fatalf("Migrate: %v", errMigrate)
+		}
+	}
+	states, errStatus := MigrationStatus(conn)
+	if errStatus != nil {
+		t.Fatalf("MigrationStatus: %v", errStatus)
+	}
+	for _, state := range states {
+		if !state.Applied {
+			t.Fatalf("expected migration %d applied", state.Version)
+		}
+	}
+
+	reverted, errDown := MigrateDown(conn, 1)
+	if errDown != nil || len(reverted) != len(states)-1 || reverted[len(reverted)-1].Name != "mfa_credentials" {
+		t.Fatalf("MigrateDown: %v %v", reverted, errDown)
+	}
+	if conn.Migrator().HasTable(&models.PasskeyCredential{}) {
+		t.Fatalf("expected passkey table to be dropped")
+	}
+	if _, errDown = MigrateDown(conn, 0); !errors.Is(errDown, ErrIrreversible) {
+		t.Fatalf("expected baseline to be irreversible, got %v", errDown)
+	}
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("re-apply: %v", errMigrate)
+	}
+	if !conn.Migrator().HasTable(&models.PasskeyCredential{}) {
+		t.Fatalf("expected passkey table to be recreated")
+	}
+
+	future := models.SchemaMigration{Version: 9999, Name: "from_the_future", AppliedAt: time.Now().UTC()}
+	if errCreate := conn.Create(&future).Error; errCreate != nil {
+		t.Fatalf("insert future migration: %v", errCreate)
+	}
+	if errMigrate := Migrate(conn); !errors.Is(errMigrate, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", errMigrate)
+	}
+}
+
+func TestAdminRolesSeededOnce(t *testing.T) {
+	conn, errOpen := Open("file:" + filepath.Join(t.TempDir(), "roles.db"))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("Migrate: %v", errMigrate)
+	}
+	var count int64
+	if errCount := conn.Model(&models.AdminRole{}).Count(&count).Error; errCount != nil || count != int64(len(defaultAdminRoles)) {
+		t.Fatalf("expected %d default roles, got %d %v", len(defaultAdminRoles), count, errCount)
+	}
+
+	if errDelete := conn.Where("1 = 1").Delete(&models.AdminRole{}).Error; errDelete != nil {
+		t.Fatalf("delete roles: %v", errDelete)
+	}
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("Migrate: %v", errMigrate)
+	}
+	if errCount := conn.Model(&models.AdminRole{}).Count(&count).Error; errCount != nil || count != 0 {
+		t.Fatalf("expected deleted roles to stay deleted, got %d %v", count, errCount)
+	}
+}
+
+func TestMFACredentialsDownKeepsPasskeys(t *testing.T) {
+	conn, errOpen := Open("file:" + filepath.Join(t.TempDir(), "passkeys.db"))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("Migrate: %v", errMigrate)
+	}
+	user := models.User{Username: "alice", Password: "x"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	credentials := []models.PasskeyCredential{
+		{OwnerKind: models.MFAOwnerUser, OwnerID: user.ID, Name: "Laptop", CredentialID: []byte("cred-1"), PublicKey: []byte("key-1"), SignCount: 7},
+		{OwnerKind: models.MFAOwnerUser, OwnerID: user.ID, Name: "Phone", CredentialID: []byte("cred-2"), PublicKey: []byte("key-2")},
+	}
+	if errCreate := conn.Create(&credentials).Error; errCreate != nil {
+		t.Fatalf("create passkeys: %v", errCreate)
+	}
+
+	if _, errDown := MigrateDown(conn, 1); errDown != nil {
+		t.Fatalf("MigrateDown: %v", errDown)
+	}
+	var legacy legacyPasskey
+	if errFind := conn.Table("users").
+		Select("id", "passkey_id", "passkey_public_key", "passkey_sign_count").
+		Where("id = ?", user.ID).
+		Take(&legacy).Error; errFind != nil {
+		t.Fatalf("load legacy passkey: %v", errFind)
+	}
+	if string(legacy.PasskeyID) != "cred-1" || string(legacy.PasskeyPublicKey) != "key-1" || legacy.PasskeySignCount == nil || *legacy.PasskeySignCount != 7 {
+		t.Fatalf("expected first passkey in legacy columns, got %+v", legacy)
+	}
+
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("re-apply: %v", errMigrate)
+	}
+	var restored []models.PasskeyCredential
+	if errFind := conn.Where("owner_kind = ? AND owner_id = ?", models.MFAOwnerUser, user.ID).Find(&restored).Error; errFind != nil {
+		t.Fatalf("load passkeys: %v", errFind)
+	}
+	if len(restored) != 1 || string(restored[0].CredentialID) != "cred-1" || restored[0].SignCount != 7 {
+		t.Fatalf("expected passkey to survive the round trip, got %+v", restored)
+	}
+}
+
+func TestAdminRolesDownRestoresPermissions(t *testing.T) {
+	conn, errOpen := Open("file:" + filepath.Join(t.TempDir(), "roles-down.db"))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("Migrate: %v", errMigrate)
+	}
+	if _, errDown := MigrateDown(conn, 10); errDown != nil {
+		t.Fatalf("MigrateDown: %v", errDown)
+	}
+	admin := models.Admin{Username: "bob", Password: "x", Permissions: datatypes.JSON(`["module:Users"]`), RoleIDs: datatypes.JSON(`[]`)}
+	if errCreate := conn.Create(&admin).Error; errCreate != nil {
+		t.Fatalf("create admin: %v", errCreate)
+	}
+
+	if errMigrate := Migrate(conn); errMigrate != nil {
+		t.Fatalf("Migrate: %v", errMigrate)
+	}
+	var legacy, support models.AdminRole
+	if errFind := conn.Where("name = ?", "legacy-bob").Take(&legacy).Error; errFind != nil {
+		t.Fatalf("expected legacy role: %v", errFind)
+	}
+	if errFind := conn.Where("name = ?", "support").Take(&support).Error; errFind != nil {
+		t.Fatalf("expected support role: %v", errFind)
+	}
+	roleIDs, _ := json.Marshal([]uint64{legacy.ID, support.ID})
+	if errUpdate := conn.Model(&models.Admin{}).Where("id = ?", admin.ID).Update("role_ids", datatypes.JSON(roleIDs)).Error; errUpdate != nil {
+		t.Fatalf("assign roles: %v", errUpdate)
+	}
+
+	if _, errDown := MigrateDown(conn, 10); errDown != nil {
+		t.Fatalf("MigrateDown: %v", errDown)
+	}
+	var restored models.Admin
+	if errFind := conn.First(&restored, admin.ID).Error; errFind != nil {
+		t.Fatalf("load admin: %v", errFind)
+	}
+	perms := legacyPermissionList(restored.Permissions)
+	if len(perms) != len(defaultAdminRoles[2].Grants) || !slices.Contains(perms, "module:Users") || !slices.Contains(perms, "module:API Keys") {
+		t.Fatalf("expected legacy and support grants in permissions, got %v", perms)
+	}
+	var kept []uint64
+	if errUnmarshal := json.Unmarshal(restored.RoleIDs, &kept); errUnmarshal != nil || !slices.Equal(kept, []uint64{legacy.ID}) {
+		t.Fatalf("expected only the legacy role to stay assigned, got %s %v", restored.RoleIDs, errUnmarshal)
+	}
+}
@@ -0,0 +1,207 @@
+// Package export streams tabular records as CSV or XLSX without buffering them in memory.
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Export formats.
+const (
+	// FormatCSV writes comma-separated values.
+	FormatCSV = "csv"
+	// FormatXLSX writes a single-sheet Office Open XML workbook.
+	FormatXLSX = "xlsx"
+)
+
+// ErrInvalidFormat indicates an unsupported export format.
+var ErrInvalidFormat = errors.New("invalid export format")
+
+// ParseFormat normalizes a requested format, defaulting to CSV.
+func ParseFormat(format string) (string, error) {
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "":
+		return FormatCSV, nil
+	case FormatCSV, FormatXLSX:
+		return format, nil
+	default:
+		return "", ErrInvalidFormat
+	}
+}
+
+// ContentType returns the MIME type of a format.
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Filename returns an attachment filename for an export generated at now.
+func Filename(name, format string, now time.Time) string {
+	return fmt.Sprintf("%s-%s.%s", name, now.UTC().Format("20060102-150405"), format)
+}
+
+// Writer writes export rows. Values may be strings, integers, floats, booleans, times,
+// *uint64, *time.Time or nil; numbers and booleans stay typed in XLSX.
+type Writer interface {
+	// WriteRow writes one row.
+	WriteRow(values ...any) error
+	// Close flushes buffered output and finishes the document.
+	Close() error
+}
+
+// NewWriter returns a Writer producing format on w. sheet names the XLSX worksheet.
+func NewWriter(w io.Writer, format, sheet string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, sheet)
+	default:
+		return nil, ErrInvalidFormat
+	}
+}
+
+// csvWriter writes rows with encoding/csv, which flushes its buffer as it fills.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// WriteRow writes one CSV record.
+func (c *csvWriter) WriteRow(values ...any) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		text, isString := formatValue(value)
+		if isString {
+			text = escapeFormula(text)
+		}
+		record[i] = text
+	}
+	return c.w.Write(record)
+}
+
+// Close flushes buffered records.
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeFormula prefixes text that spreadsheet applications would evaluate as a formula.
+func escapeFormula(text string) string {
+	if text == "" {
+		return text
+	}
+	switch text[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + text
+	}
+	return text
+}
+
+// formatValue renders a cell value as text and reports whether it is a string rather than a
+// number, boolean or time.
+func formatValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case bool:
+		return strconv.FormatBool(v), false
+	case int:
+		return strconv.Itoa(v), false
+	case int64:
+		return strconv.FormatInt(v, 10), false
+	case uint64:
+		return strconv.FormatUint(v, 10), false
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), false
+	case time.Time:
+		if v.IsZero() {
+			return "", false
+		}
+		return v.UTC().Format(time.RFC3339), false
+	case *uint64:
+		if v == nil {
+			return "", false
+		}
+		return strconv.FormatUint(*v, 10), false
+	case *time.Time:
+		if v == nil {
+			return "", false
+		}
+		return formatValue(*v)
+	case fmt.Stringer:
+		return v.String(), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// Table describes how records of type T are exported.
+type Table[T any] struct {
+	Name   string         // Base filename and XLSX sheet name.
+	Header []string       // Column names.
+	Row    func(*T) []any // Converts a record into a row matching Header.
+}
+
+// Serve runs query and streams every matched record to the response as format. Query errors
+// before the first byte is written are reported as JSON; later failures are logged and end
+// the response early, since the status has already been sent.
+func Serve[T any](c *gin.Context, query *gorm.DB, format string, table Table[T]) {
+	rows, errRows := query.Rows()
+	if errRows != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed"})
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	c.Header("Content-Type", ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", Filename(table.Name, format, time.Now())))
+	c.Status(http.StatusOK)
+
+	errExport := func() error {
+		w, errWriter := NewWriter(c.Writer, format, table.Name)
+		if errWriter != nil {
+			return errWriter
+		}
+		headerRow := make([]any, len(table.Header))
+		for i, name := range table.Header {
+			headerRow[i] = name
+		}
+		if errWrite := w.WriteRow(headerRow...); errWrite != nil {
+			return errWrite
+		}
+		for rows.Next() {
+			var record T
+			if errScan := query.ScanRows(rows, &record); errScan != nil {
+				return errScan
+			}
+			if errWrite := w.WriteRow(table.Row(&record)...); errWrite != nil {
+				return errWrite
+			}
+		}
+		if errNext := rows.Err(); errNext != nil {
+			return errNext
+		}
+		return w.Close()
+	}()
+	if errExport != nil {
+		log.WithError(errExport).WithField("export", table.Name).Warn("export: stream failed")
+	}
+}
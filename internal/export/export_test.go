@@ -0,0 +1,150 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), name))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+var testTable = Table[models.Usage]{
+	Name:   "usage",
+	Header: []string{"id", "model", "user_id", "failed", "cost_micros", "requested_at"},
+	Row: func(row *models.Usage) []any {
+		return []any{row.ID, row.Model, row.UserID, row.Failed, row.CostMicros, row.RequestedAt}
+	},
+}
+
+func serveUsage(t *testing.T, conn *gorm.DB, format string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/export", nil)
+	Serve(c, conn.Model(&models.Usage{}).Order("id ASC"), format, testTable)
+	return rec
+}
+
+func seedUsage(t *testing.T, conn *gorm.DB) {
+	t.Helper()
+	userID := uint64(7)
+	at := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	rows := []models.Usage{
+		{Provider: "claude", Model: "=cmd|' /C calc'!A0", UserID: &userID, RequestedAt: at, CostMicros: 1500},
+		{Provider: "codex", Model: "gpt <x> & y", RequestedAt: at.Add(time.Hour), Failed: true},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, _ := ParseFormat(""); format != FormatCSV {
+		t.Fatalf("expected csv default, got %q", format)
+	}
+	if format, _ := ParseFormat(" XLSX "); format != FormatXLSX {
+		t.Fatalf("expected xlsx, got %q", format)
+	}
+	if _, errFormat := ParseFormat("pdf"); errFormat != ErrInvalidFormat {
+		t.Fatalf("expected invalid format, got %v", errFormat)
+	}
+}
+
+func TestServeCSV(t *testing.T) {
+	conn := openTestDB(t, "csv.db")
+	seedUsage(t, conn)
+
+	rec := serveUsage(t, conn, FormatCSV)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Disposition"), `attachment; filename="usage-`) {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	records, errRead := csv.NewReader(rec.Body).ReadAll()
+	if errRead != nil {
+		t.Fatalf("read csv: %v", errRead)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != "id,model,user_id,failed,cost_micros,requested_at" {
+		t.Fatalf("unexpected records %v", records)
+	}
+	if got := records[1]; got[1] != "'=cmd|' /C calc'!A0" || got[2] != "7" || got[3] != "false" || got[4] != "1500" || got[5] != "2026-03-10T12:00:00Z" {
+		t.Fatalf("unexpected first row %v", got)
+	}
+	if got := records[2]; got[1] != "gpt <x> & y" || got[2] != "" || got[3] != "true" {
+		t.Fatalf("unexpected second row %v", got)
+	}
+}
+
+func TestServeXLSX(t *testing.T) {
+	conn := openTestDB(t, "xlsx.db")
+	seedUsage(t, conn)
+
+	rec := serveUsage(t, conn, FormatXLSX)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ContentType(FormatXLSX) {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	archive, errZip := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if errZip != nil {
+		t.Fatalf("open xlsx: %v", errZip)
+	}
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		r, errOpen := file.Open()
+		if errOpen != nil {
+			t.Fatalf("open %s: %v", file.Name, errOpen)
+		}
+		content, _ := io.ReadAll(r)
+		_ = r.Close()
+		parts[file.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if errUnmarshal := xml.Unmarshal([]byte(parts["xl/worksheets/sheet1.xml"]), &sheet); errUnmarshal != nil {
+		t.Fatalf("parse sheet: %v", errUnmarshal)
+	}
+	if len(sheet.Rows) != 3 || sheet.Rows[0].Cells[1].Inline != "model" {
+		t.Fatalf("unexpected rows %+v", sheet.Rows)
+	}
+	first := sheet.Rows[1].Cells
+	if first[1].Inline != "=cmd|' /C calc'!A0" || first[2].Value != "7" || first[3].Type != "b" || first[4].Type != "" || first[4].Value != "1500" {
+		t.Fatalf("unexpected first row %+v", first)
+	}
+	if second := sheet.Rows[2].Cells; second[1].Inline != "gpt <x> & y" || second[3].Value != "1" {
+		t.Fatalf("unexpected second row %+v", second)
+	}
+}
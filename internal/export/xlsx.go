@@ -0,0 +1,155 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xlsxParts lists the static parts of a single-sheet workbook with their archive paths.
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="1"><fill><patternFill patternType="none"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/></cellXfs>` +
+		`</styleSheet>`},
+}
+
+// xlsxWriter streams rows into the worksheet part of a zip archive. Strings are written
+// inline so no shared string table has to be kept in memory.
+type xlsxWriter struct {
+	zw  *zip.Writer
+	buf *bufio.Writer
+	row int
+}
+
+// newXLSXWriter writes the workbook skeleton and opens the worksheet.
+func newXLSXWriter(w io.Writer, sheet string) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		if errPart := writeZipPart(zw, part.name, part.content); errPart != nil {
+			return nil, errPart
+		}
+	}
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + escapeXML(sheetName(sheet)) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if errPart := writeZipPart(zw, "xl/workbook.xml", workbook); errPart != nil {
+		return nil, errPart
+	}
+	sheetWriter, errCreate := zw.Create("xl/worksheets/sheet1.xml")
+	if errCreate != nil {
+		return nil, errCreate
+	}
+	buf := bufio.NewWriterSize(sheetWriter, 64*1024)
+	if _, errWrite := buf.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); errWrite != nil {
+		return nil, errWrite
+	}
+	return &xlsxWriter{zw: zw, buf: buf}, nil
+}
+
+// writeZipPart writes one complete archive entry.
+func writeZipPart(zw *zip.Writer, name, content string) error {
+	w, errCreate := zw.Create(name)
+	if errCreate != nil {
+		return errCreate
+	}
+	_, errWrite := io.WriteString(w, content)
+	return errWrite
+}
+
+// WriteRow writes one worksheet row.
+func (x *xlsxWriter) WriteRow(values ...any) error {
+	x.row++
+	var b strings.Builder
+	b.WriteString(`<row r="`)
+	b.WriteString(strconv.Itoa(x.row))
+	b.WriteString(`">`)
+	for _, value := range values {
+		switch v := value.(type) {
+		case int, int64, uint64, float64:
+			text, _ := formatValue(v)
+			b.WriteString(`<c><v>` + text + `</v></c>`)
+		case *uint64:
+			if v == nil {
+				b.WriteString(`<c/>`)
+				continue
+			}
+			b.WriteString(`<c><v>` + strconv.FormatUint(*v, 10) + `</v></c>`)
+		case bool:
+			flag := "0"
+			if v {
+				flag = "1"
+			}
+			b.WriteString(`<c t="b"><v>` + flag + `</v></c>`)
+		default:
+			text, _ := formatValue(v)
+			if text == "" {
+				b.WriteString(`<c/>`)
+				continue
+			}
+			b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">` + escapeXML(text) + `</t></is></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+	_, errWrite := x.buf.WriteString(b.String())
+	return errWrite
+}
+
+// Close finishes the worksheet and the archive.
+func (x *xlsxWriter) Close() error {
+	if _, errWrite := x.buf.WriteString(`</sheetData></worksheet>`); errWrite != nil {
+		return errWrite
+	}
+	if errFlush := x.buf.Flush(); errFlush != nil {
+		return errFlush
+	}
+	return x.zw.Close()
+}
+
+// escapeXML escapes text for element content and attributes, replacing characters XML
+// cannot represent.
+func escapeXML(text string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// sheetName returns a worksheet name Excel accepts: at most 31 characters without []:*?/\.
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		return "Sheet1"
+	}
+	return name
+}
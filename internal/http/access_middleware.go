@@ -38,6 +38,8 @@ func AccessAuthMiddleware(manager *sdkaccess.Manager) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 		case errors.Is(err, access.ErrInsufficientBalance):
 			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{"error": "Insufficient balance"})
+		case errors.Is(err, access.ErrSpendLimitExceeded):
+			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{"error": "Spend limit exceeded"})
 		default:
 			log.WithError(err).Error("access auth middleware error")
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Authentication service error"})
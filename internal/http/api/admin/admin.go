@@ -11,6 +11,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
 	handlers "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/handlers"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	"gorm.io/gorm"
@@ -38,9 +39,16 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	adminGroup.POST("/login/totp", authHandler.LoginTOTP)
 	adminGroup.POST("/login/passkey/options", authHandler.LoginPasskeyOptions)
 	adminGroup.POST("/login/passkey/verify", authHandler.LoginPasskeyVerify)
+	adminGroup.POST("/login/recovery", authHandler.LoginRecovery)
+
+	ssoHandler := handlers.NewSSOHandler(db, jwtCfg)
+	adminGroup.GET("/sso/providers", ssoHandler.Providers)
+	adminGroup.GET("/sso/:provider/login", ssoHandler.Login)
+	adminGroup.GET("/sso/:provider/callback", ssoHandler.Callback)
 
 	selfAuthed := adminGroup.Group("")
 	selfAuthed.Use(adminAuthMiddleware(db, jwtCfg))
+	selfAuthed.Use(adminAuditMiddleware(db))
 
 	mfaHandler := handlers.NewMFAHandler(db, webAuthn)
 	selfAuthed.GET("/mfa/status", mfaHandler.Status)
@@ -50,10 +58,18 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	selfAuthed.POST("/mfa/passkey/options", mfaHandler.BeginPasskeyRegistration)
 	selfAuthed.POST("/mfa/passkey/verify", mfaHandler.FinishPasskeyRegistration)
 	selfAuthed.POST("/mfa/passkey/disable", mfaHandler.DisablePasskey)
+	selfAuthed.GET("/mfa/passkeys", mfaHandler.ListPasskeys)
+	selfAuthed.PUT("/mfa/passkeys/:id", mfaHandler.RenamePasskey)
+	selfAuthed.DELETE("/mfa/passkeys/:id", mfaHandler.DeletePasskey)
+	selfAuthed.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	selfAuthed.POST("/sso/:provider/link", ssoHandler.Link)
+	selfAuthed.GET("/sso/identities", ssoHandler.Identities)
+	selfAuthed.DELETE("/sso/identities/:id", ssoHandler.Unlink)
 
 	authed := adminGroup.Group("")
 	authed.Use(adminAuthMiddleware(db, jwtCfg))
 	authed.Use(adminPermissionMiddleware(db))
+	authed.Use(adminAuditMiddleware(db))
 
 	apiKeyHandler := handlers.NewAPIKeyHandler(db)
 	authed.POST("/api-keys", apiKeyHandler.Create)
@@ -75,6 +91,8 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 
 	usageHandler := handlers.NewUsageHandler(db)
 	authed.GET("/usage", usageHandler.List)
+	authed.GET("/usage/auths", usageHandler.AuthReport)
+	authed.GET("/usage/export", usageHandler.Export)
 
 	billingHandler := handlers.NewBillingHandler(db)
 	authed.GET("/billing/summary", billingHandler.Summary)
@@ -82,12 +100,17 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	userHandler := handlers.NewUserHandler(db)
 	authed.POST("/users", userHandler.Create)
 	authed.GET("/users", userHandler.List)
+	authed.GET("/users/export", userHandler.Export)
 	authed.GET("/users/:id", userHandler.Get)
 	authed.PUT("/users/:id", userHandler.Update)
 	authed.DELETE("/users/:id", userHandler.Delete)
 	authed.POST("/users/:id/disable", userHandler.Disable)
 	authed.POST("/users/:id/enable", userHandler.Enable)
 	authed.PUT("/users/:id/password", userHandler.ChangePassword)
+	authed.POST("/users/:id/mfa/reset", userHandler.ResetMFA)
+	authed.GET("/users/:id/model-overrides", userHandler.ListModelOverrides)
+	authed.PUT("/users/:id/model-overrides", userHandler.SetModelOverride)
+	authed.DELETE("/users/:id/model-overrides/:override_id", userHandler.DeleteModelOverride)
 
 	authGroupHandler := handlers.NewAuthGroupHandler(db)
 	authed.POST("/auth-groups", authGroupHandler.Create)
@@ -98,18 +121,30 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.POST("/auth-groups/:id/default", authGroupHandler.SetDefault)
 
 	authFileHandler := handlers.NewAuthFileHandler(db)
+	if baseHandler != nil && baseHandler.AuthManager != nil {
+		authFileHandler.WithAuthManager(baseHandler.AuthManager)
+	}
 	authed.POST("/auth-files", authFileHandler.Create)
 	authed.POST("/auth-files/import", authFileHandler.Import)
+	authed.POST("/auth-files/bulk", authFileHandler.Bulk)
+	authed.GET("/auth-files/export", authFileHandler.Export)
+	authed.POST("/auth-files/export", authFileHandler.Export)
+	authed.GET("/auth-files/invalid", authFileHandler.Invalid)
 	authed.GET("/auth-files", authFileHandler.List)
 	authed.GET("/auth-files/:id", authFileHandler.Get)
 	authed.PUT("/auth-files/:id", authFileHandler.Update)
 	authed.DELETE("/auth-files/:id", authFileHandler.Delete)
 	authed.POST("/auth-files/:id/available", authFileHandler.SetAvailable)
 	authed.POST("/auth-files/:id/unavailable", authFileHandler.SetUnavailable)
+	authed.POST("/auth-files/:id/reauth", authFileHandler.StartReauth)
+	authed.POST("/auth-files/:id/reauth/complete", authFileHandler.CompleteReauth)
 	authed.GET("/auth-files/types", authFileHandler.ListTypes)
 
 	quotaHandler := handlers.NewQuotaHandler(db)
 	authed.GET("/quotas", quotaHandler.List)
+	authed.GET("/quotas/capacity", quotaHandler.Capacity)
+	authed.GET("/quotas/history", quotaHandler.History)
+	authed.GET("/quotas/forecast", quotaHandler.Forecast)
 
 	userGroupHandler := handlers.NewUserGroupHandler(db)
 	authed.POST("/user-groups", userGroupHandler.Create)
@@ -135,6 +170,20 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.PUT("/prepaid-cards/:id", prepaidCardHandler.Update)
 	authed.DELETE("/prepaid-cards/:id", prepaidCardHandler.Delete)
 
+	inviteCodeHandler := handlers.NewInviteCodeHandler(db)
+	authed.POST("/invite-codes", inviteCodeHandler.Create)
+	authed.GET("/invite-codes", inviteCodeHandler.List)
+	authed.GET("/invite-codes/:id", inviteCodeHandler.Get)
+	authed.PUT("/invite-codes/:id", inviteCodeHandler.Update)
+	authed.DELETE("/invite-codes/:id", inviteCodeHandler.Delete)
+
+	oidcProviderHandler := handlers.NewOIDCProviderHandler(db)
+	authed.POST("/oidc-providers", oidcProviderHandler.Create)
+	authed.GET("/oidc-providers", oidcProviderHandler.List)
+	authed.GET("/oidc-providers/:id", oidcProviderHandler.Get)
+	authed.PUT("/oidc-providers/:id", oidcProviderHandler.Update)
+	authed.DELETE("/oidc-providers/:id", oidcProviderHandler.Delete)
+
 	adminHandler := handlers.NewAdminHandler(db)
 	authed.POST("/admins", adminHandler.Create)
 	authed.GET("/admins", adminHandler.List)
@@ -144,13 +193,22 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.POST("/admins/:id/disable", adminHandler.Disable)
 	authed.POST("/admins/:id/enable", adminHandler.Enable)
 	authed.PUT("/admins/:id/password", adminHandler.ChangePassword)
+	authed.POST("/admins/:id/mfa/reset", adminHandler.ResetMFA)
 
 	permissionHandler := handlers.NewPermissionHandler()
 	authed.GET("/permissions", permissionHandler.List)
 
+	adminRoleHandler := handlers.NewAdminRoleHandler(db)
+	authed.POST("/admin-roles", adminRoleHandler.Create)
+	authed.GET("/admin-roles", adminRoleHandler.List)
+	authed.GET("/admin-roles/:id", adminRoleHandler.Get)
+	authed.PUT("/admin-roles/:id", adminRoleHandler.Update)
+	authed.DELETE("/admin-roles/:id", adminRoleHandler.Delete)
+
 	billHandler := handlers.NewBillHandler(db)
 	authed.POST("/bills", billHandler.Create)
 	authed.GET("/bills", billHandler.List)
+	authed.GET("/bills/export", billHandler.Export)
 	authed.GET("/bills/:id", billHandler.Get)
 	authed.PUT("/bills/:id", billHandler.Update)
 	authed.DELETE("/bills/:id", billHandler.Delete)
@@ -161,12 +219,22 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.POST("/model-mappings", modelMappingHandler.Create)
 	authed.GET("/model-mappings", modelMappingHandler.List)
 	authed.GET("/model-mappings/available-models", modelMappingHandler.AvailableModels)
+	authed.GET("/model-mappings/export", modelMappingHandler.Export)
+	authed.POST("/model-mappings/import", modelMappingHandler.Import)
+	authed.POST("/model-mappings/bulk", modelMappingHandler.Bulk)
 	authed.GET("/model-mappings/:id", modelMappingHandler.Get)
 	authed.PUT("/model-mappings/:id", modelMappingHandler.Update)
 	authed.DELETE("/model-mappings/:id", modelMappingHandler.Delete)
 	authed.POST("/model-mappings/:id/enable", modelMappingHandler.Enable)
 	authed.POST("/model-mappings/:id/disable", modelMappingHandler.Disable)
 
+	modelCatalogHandler := handlers.NewModelCatalogHandler(db)
+	authed.GET("/model-catalog", modelCatalogHandler.List)
+	authed.POST("/model-catalog", modelCatalogHandler.Create)
+	authed.GET("/model-catalog/:id", modelCatalogHandler.Get)
+	authed.PUT("/model-catalog/:id", modelCatalogHandler.Update)
+	authed.DELETE("/model-catalog/:id", modelCatalogHandler.Delete)
+
 	payloadRuleHandler := handlers.NewModelPayloadRuleHandler(db)
 	authed.GET("/model-mappings/:id/payload-rules", payloadRuleHandler.List)
 	authed.POST("/model-mappings/:id/payload-rules", payloadRuleHandler.Create)
@@ -175,6 +243,7 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 
 	logsHandler := handlers.NewAdminLogsHandler(db)
 	authed.GET("/logs", logsHandler.List)
+	authed.GET("/logs/export", logsHandler.Export)
 	authed.GET("/logs/detail", logsHandler.Detail)
 	authed.GET("/logs/stats", logsHandler.Stats)
 	authed.GET("/logs/trend", logsHandler.Trend)
@@ -197,6 +266,26 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	authed.PUT("/settings/:key", settingHandler.Update)
 	authed.DELETE("/settings/:key", settingHandler.Delete)
 
+	auditLogHandler := handlers.NewAuditLogHandler(db)
+	authed.GET("/audit-logs", auditLogHandler.List)
+	authed.GET("/audit-logs/export", auditLogHandler.Export)
+
+	reportScheduleHandler := handlers.NewReportScheduleHandler(db)
+	if baseHandler != nil && baseHandler.AuthManager != nil {
+		reportScheduleHandler.WithAuthManager(baseHandler.AuthManager)
+	}
+	authed.POST("/report-schedules", reportScheduleHandler.Create)
+	authed.GET("/report-schedules", reportScheduleHandler.List)
+	authed.GET("/report-schedules/:id", reportScheduleHandler.Get)
+	authed.PUT("/report-schedules/:id", reportScheduleHandler.Update)
+	authed.DELETE("/report-schedules/:id", reportScheduleHandler.Delete)
+	authed.POST("/report-schedules/:id/run", reportScheduleHandler.Run)
+	authed.GET("/report-schedules/:id/preview", reportScheduleHandler.Preview)
+
+	spendAnomalyHandler := handlers.NewSpendAnomalyHandler(db)
+	authed.GET("/spend-anomalies", spendAnomalyHandler.List)
+	authed.POST("/spend-anomalies/:id/resolve", spendAnomalyHandler.Resolve)
+
 	dashboardHandler := handlers.NewDashboardHandler(db)
 	authed.GET("/dashboard/kpi", dashboardHandler.KPI)
 	authed.GET("/dashboard/traffic", dashboardHandler.Traffic)
@@ -218,6 +307,11 @@ func RegisterAdminRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, co
 	}
 }
 
+// adminMFAEnrollmentExempt reports whether a route stays reachable before required MFA is enrolled.
+func adminMFAEnrollmentExempt(route string) bool {
+	return strings.HasPrefix(route, "/v0/admin/mfa/") || strings.HasPrefix(route, "/v0/admin/sso/")
+}
+
 // adminAuthMiddleware validates admin JWTs and loads admin context.
 func adminAuthMiddleware(db *gorm.DB, jwtCfg config.JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -253,12 +347,28 @@ func adminAuthMiddleware(db *gorm.DB, jwtCfg config.JWTConfig) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin disabled"})
 			return
 		}
+		if mfa.AdminRequired(admin) && !adminMFAEnrollmentExempt(c.FullPath()) {
+			status, errStatus := mfa.LoadStatus(c.Request.Context(), db, models.MFAOwnerAdmin, admin.ID, admin.TOTPSecret)
+			if errStatus != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+				return
+			}
+			if !status.Enabled() {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "mfa enrollment required"})
+				return
+			}
+		}
 
-		adminPermissions := permissions.ParsePermissions(admin.Permissions)
+		access, errAccess := permissions.ResolveAccess(c.Request.Context(), db, admin)
+		if errAccess != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "load admin roles failed"})
+			return
+		}
 		c.Set("adminID", admin.ID)
 		c.Set("adminUsername", admin.Username)
-		c.Set("adminPermissions", adminPermissions)
+		c.Set(adminAccessKey, access)
 		c.Set("adminIsSuperAdmin", admin.IsSuperAdmin)
+		c.Set(handlers.AdminUserGroupScopeKey, access.UserGroupScope)
 		c.Next()
 	}
 }
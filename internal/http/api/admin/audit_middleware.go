@@ -0,0 +1,146 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/audit"
+	permissions "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxAuditResponseBytes bounds the response body kept to resolve created entity IDs.
+const maxAuditResponseBytes = 64 << 10
+
+// auditResponseWriter buffers the start of the response body.
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write forwards the response and keeps a bounded copy.
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if remaining := maxAuditResponseBytes - w.body.Len(); remaining > 0 {
+		if len(data) > remaining {
+			w.body.Write(data[:remaining])
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// adminAuditMiddleware records every mutating admin request with entity snapshots.
+func adminAuditMiddleware(db *gorm.DB) gin.HandlerFunc {
+	permissionMap := permissions.DefinitionMap()
+
+	return func(c *gin.Context) {
+		if !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		entity, hasEntity := audit.ResolveEntity(route)
+		entityID := ""
+		if hasEntity && entity.Param != "" {
+			entityID = strings.TrimSpace(c.Param(entity.Param))
+		}
+
+		ctx := c.Request.Context()
+		var before map[string]any
+		if hasEntity && entityID != "" {
+			before = audit.Snapshot(ctx, db, entity, entityID)
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		var after map[string]any
+		if hasEntity {
+			if entityID == "" {
+				entityID = createdEntityID(writer.body.Bytes(), entity.Column)
+			}
+			if entityID != "" {
+				after = audit.Snapshot(ctx, db, entity, entityID)
+			}
+			if after == nil && entityID == "" {
+				after = maskedResponse(writer.body.Bytes(), writer.Status())
+			}
+		}
+
+		entry := audit.Entry{
+			Method:     c.Request.Method,
+			Route:      route,
+			Path:       c.Request.URL.Path,
+			EntityType: entity.Type,
+			EntityID:   entityID,
+			StatusCode: writer.Status(),
+			Before:     before,
+			After:      after,
+			IP:         c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+		}
+		if definition, ok := permissionMap[permissions.Key(c.Request.Method, route)]; ok {
+			entry.Action = definition.Label
+			entry.Module = definition.Module
+		}
+		if adminID, ok := c.Get("adminID"); ok {
+			entry.AdminID, _ = adminID.(uint64)
+		}
+		entry.AdminUsername = c.GetString("adminUsername")
+
+		recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if errRecord := audit.Record(recordCtx, db, entry); errRecord != nil {
+			log.WithError(errRecord).Warnf("audit: record %s %s failed", entry.Method, entry.Route)
+		}
+	}
+}
+
+// isMutatingMethod reports whether the HTTP method changes state.
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// createdEntityID extracts the identifier of a created entity from a JSON response.
+func createdEntityID(body []byte, column string) string {
+	var payload map[string]any
+	if errUnmarshal := json.Unmarshal(body, &payload); errUnmarshal != nil {
+		return ""
+	}
+	switch value := payload[column].(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case float64:
+		return strconv.FormatUint(uint64(value), 10)
+	default:
+		return ""
+	}
+}
+
+// maskedResponse returns a masked JSON response body for successful actions without a single entity.
+func maskedResponse(body []byte, status int) map[string]any {
+	if status >= http.StatusBadRequest {
+		return nil
+	}
+	var payload map[string]any
+	if errUnmarshal := json.Unmarshal(body, &payload); errUnmarshal != nil {
+		return nil
+	}
+	masked, _ := audit.Mask(payload).(map[string]any)
+	return masked
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// AdminRoleHandler manages admin role endpoints.
+type AdminRoleHandler struct {
+	db *gorm.DB
+}
+
+// NewAdminRoleHandler constructs an AdminRoleHandler.
+func NewAdminRoleHandler(db *gorm.DB) *AdminRoleHandler {
+	return &AdminRoleHandler{db: db}
+}
+
+// createAdminRoleRequest defines the request body for role creation.
+type createAdminRoleRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Permissions []string            `json:"permissions"`
+	UserGroupID models.UserGroupIDs `json:"user_group_id"`
+}
+
+// Create creates a new admin role.
+func (h *AdminRoleHandler) Create(c *gin.Context) {
+	var body createAdminRoleRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	normalizedPermissions := permissions.NormalizePermissions(body.Permissions)
+	if errValidate := permissions.ValidatePermissions(normalizedPermissions); errValidate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidate.Error()})
+		return
+	}
+	permissionsJSON, errMarshal := permissions.MarshalPermissions(normalizedPermissions)
+	if errMarshal != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "marshal permissions failed"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var count int64
+	if errCount := h.db.WithContext(ctx).Model(&models.AdminRole{}).Where("name = ?", name).Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "role name already exists"})
+		return
+	}
+
+	now := time.Now().UTC()
+	role := models.AdminRole{
+		Name:        name,
+		Description: strings.TrimSpace(body.Description),
+		Permissions: datatypes.JSON(permissionsJSON),
+		UserGroupID: body.UserGroupID.Clean(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if errCreate := h.db.WithContext(ctx).Create(&role).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create role failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatAdminRole(&role, 0))
+}
+
+// List returns all admin roles with their assignment counts.
+func (h *AdminRoleHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	var rows []models.AdminRole
+	if errFind := h.db.WithContext(ctx).Order("name ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list roles failed"})
+		return
+	}
+
+	var admins []models.Admin
+	if errAdmins := h.db.WithContext(ctx).Select("id", "role_ids").Find(&admins).Error; errAdmins != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list roles failed"})
+		return
+	}
+	assigned := make(map[uint64]int)
+	for _, admin := range admins {
+		for _, roleID := range permissions.ParseRoleIDs(admin.RoleIDs) {
+			assigned[roleID]++
+		}
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatAdminRole(&rows[i], assigned[rows[i].ID]))
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": out})
+}
+
+// Get returns a single admin role by ID.
+func (h *AdminRoleHandler) Get(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var role models.AdminRole
+	if errFind := h.db.WithContext(c.Request.Context()).First(&role, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	out := formatAdminRole(&role, 0)
+	out["expanded_permissions"] = permissions.Expand(permissions.ParsePermissions(role.Permissions))
+	delete(out, "admin_count")
+	c.JSON(http.StatusOK, out)
+}
+
+// updateAdminRoleRequest defines the request body for role updates.
+type updateAdminRoleRequest struct {
+	Name        *string              `json:"name"`
+	Description *string              `json:"description"`
+	Permissions *[]string            `json:"permissions"`
+	UserGroupID *models.UserGroupIDs `json:"user_group_id"`
+}
+
+// Update modifies an admin role.
+func (h *AdminRoleHandler) Update(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body updateAdminRoleRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	updates := map[string]any{"updated_at": time.Now().UTC()}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		var count int64
+		if errCount := h.db.WithContext(ctx).Model(&models.AdminRole{}).
+			Where("name = ? AND id <> ?", name, id).
+			Count(&count).Error; errCount != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "role name already exists"})
+			return
+		}
+		updates["name"] = name
+	}
+	if body.Description != nil {
+		updates["description"] = strings.TrimSpace(*body.Description)
+	}
+	if body.Permissions != nil {
+		normalizedPermissions := permissions.NormalizePermissions(*body.Permissions)
+		if errValidate := permissions.ValidatePermissions(normalizedPermissions); errValidate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errValidate.Error()})
+			return
+		}
+		permissionsJSON, errMarshal := permissions.MarshalPermissions(normalizedPermissions)
+		if errMarshal != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "marshal permissions failed"})
+			return
+		}
+		updates["permissions"] = datatypes.JSON(permissionsJSON)
+	}
+	if body.UserGroupID != nil {
+		updates["user_group_id"] = body.UserGroupID.Clean()
+	}
+
+	res := h.db.WithContext(ctx).Model(&models.AdminRole{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Delete removes an admin role and unassigns it from every admin.
+func (h *AdminRoleHandler) Delete(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var found bool
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.AdminRole{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		found = res.RowsAffected > 0
+		if !found {
+			return nil
+		}
+		var admins []models.Admin
+		if errFind := tx.Select("id", "role_ids").
+			Where(dbutil.JSONArrayContainsExpr(tx, "role_ids"), dbutil.JSONArrayContainsValue(tx, id)).
+			Find(&admins).Error; errFind != nil {
+			return errFind
+		}
+		for _, admin := range admins {
+			remaining := make([]uint64, 0)
+			for _, roleID := range permissions.ParseRoleIDs(admin.RoleIDs) {
+				if roleID != id {
+					remaining = append(remaining, roleID)
+				}
+			}
+			roleIDsJSON, errMarshal := permissions.MarshalRoleIDs(remaining)
+			if errMarshal != nil {
+				return errMarshal
+			}
+			if errUpdate := tx.Model(&models.Admin{}).Where("id = ?", admin.ID).
+				Updates(map[string]any{"role_ids": datatypes.JSON(roleIDsJSON), "updated_at": time.Now().UTC()}).Error; errUpdate != nil {
+				return errUpdate
+			}
+		}
+		return nil
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// formatAdminRole converts a role row into its API representation.
+func formatAdminRole(role *models.AdminRole, adminCount int) gin.H {
+	return gin.H{
+		"id":            role.ID,
+		"name":          role.Name,
+		"description":   role.Description,
+		"permissions":   permissions.ParsePermissions(role.Permissions),
+		"user_group_id": role.UserGroupID.Clean(),
+		"admin_count":   adminCount,
+		"created_at":    role.CreatedAt,
+		"updated_at":    role.UpdatedAt,
+	}
+}
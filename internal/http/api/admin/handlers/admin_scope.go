@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// AdminUserGroupScopeKey is the gin context key holding the user groups an admin may manage.
+const AdminUserGroupScopeKey = "adminUserGroupScope"
+
+// readAdminUserGroupScope returns the admin's user group scope; nil means unrestricted.
+func readAdminUserGroupScope(c *gin.Context) []uint64 {
+	if c.GetBool("adminIsSuperAdmin") {
+		return nil
+	}
+	value, ok := c.Get(AdminUserGroupScopeKey)
+	if !ok {
+		return nil
+	}
+	scope, _ := value.([]uint64)
+	if len(scope) == 0 {
+		return nil
+	}
+	return scope
+}
+
+// applyUserGroupScope restricts a query to rows whose JSON group column overlaps the scope.
+func applyUserGroupScope(conn *gorm.DB, q *gorm.DB, column string, scope []uint64) *gorm.DB {
+	if len(scope) == 0 {
+		return q
+	}
+	expr := dbutil.JSONArrayContainsExpr(conn, column)
+	clauses := make([]string, 0, len(scope))
+	args := make([]any, 0, len(scope))
+	for _, groupID := range scope {
+		clauses = append(clauses, expr)
+		args = append(args, dbutil.JSONArrayContainsValue(conn, groupID))
+	}
+	return q.Where("("+strings.Join(clauses, " OR ")+")", args...)
+}
+
+// userGroupsInScope reports whether every group is inside the scope; empty groups are out of scope.
+func userGroupsInScope(groups []uint64, scope []uint64) bool {
+	if len(scope) == 0 {
+		return true
+	}
+	if len(groups) == 0 {
+		return false
+	}
+	allowed := make(map[uint64]struct{}, len(scope))
+	for _, groupID := range scope {
+		allowed[groupID] = struct{}{}
+	}
+	for _, groupID := range groups {
+		if _, ok := allowed[groupID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// applyUserScope restricts a query to rows whose user column references a user the admin may manage.
+// Rows without a user fall outside any scope.
+func applyUserScope(c *gin.Context, conn *gorm.DB, q *gorm.DB, column string) *gorm.DB {
+	scope := readAdminUserGroupScope(c)
+	if len(scope) == 0 {
+		return q
+	}
+	return q.Where(column+" IN (?)", scopedUserIDs(conn, scope))
+}
+
+// scopedUserIDs returns a subquery selecting the IDs of users inside the scope.
+func scopedUserIDs(conn *gorm.DB, scope []uint64) *gorm.DB {
+	users := conn.Session(&gorm.Session{NewDB: true}).Model(&models.User{}).Select("users.id")
+	return applyUserGroupScope(conn, users, "users.user_group_id", scope)
+}
+
+// userInScope reports whether the admin may manage the user.
+func userInScope(c *gin.Context, conn *gorm.DB, userID uint64) (bool, error) {
+	scope := readAdminUserGroupScope(c)
+	if len(scope) == 0 {
+		return true, nil
+	}
+	var count int64
+	q := conn.WithContext(c.Request.Context()).Model(&models.User{}).Where("id = ?", userID)
+	if errCount := applyUserGroupScope(conn, q, "user_group_id", scope).Count(&count).Error; errCount != nil {
+		return false, errCount
+	}
+	return count > 0, nil
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	"gorm.io/datatypes"
@@ -31,7 +33,9 @@ type createAdminRequest struct {
 	Username     string   `json:"username"`
 	Password     string   `json:"password"`
 	Permissions  []string `json:"permissions"`
+	RoleIDs      []uint64 `json:"role_ids"`
 	IsSuperAdmin bool     `json:"is_super_admin"`
+	SSOOnly      bool     `json:"sso_only"`
 }
 
 // Create creates a new admin account.
@@ -68,6 +72,11 @@ func (h *AdminHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "marshal permissions failed"})
 		return
 	}
+	roleIDsJSON, errRoles := h.marshalRoleIDs(c, body.RoleIDs)
+	if errRoles != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errRoles.Error()})
+		return
+	}
 
 	now := time.Now().UTC()
 	admin := models.Admin{
@@ -75,7 +84,9 @@ func (h *AdminHandler) Create(c *gin.Context) {
 		Password:     hash,
 		Active:       true,
 		IsSuperAdmin: body.IsSuperAdmin,
+		SSOOnly:      body.SSOOnly,
 		Permissions:  datatypes.JSON(permissionsJSON),
+		RoleIDs:      datatypes.JSON(roleIDsJSON),
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -88,7 +99,9 @@ func (h *AdminHandler) Create(c *gin.Context) {
 		"username":       admin.Username,
 		"active":         admin.Active,
 		"is_super_admin": admin.IsSuperAdmin,
+		"sso_only":       admin.SSOOnly,
 		"permissions":    permissions.ParsePermissions(admin.Permissions),
+		"role_ids":       permissions.ParseRoleIDs(admin.RoleIDs),
 	})
 }
 
@@ -122,7 +135,9 @@ func (h *AdminHandler) List(c *gin.Context) {
 			"username":       row.Username,
 			"active":         row.Active,
 			"is_super_admin": row.IsSuperAdmin,
+			"sso_only":       row.SSOOnly,
 			"permissions":    permissions.ParsePermissions(row.Permissions),
+			"role_ids":       permissions.ParseRoleIDs(row.RoleIDs),
 			"created_at":     row.CreatedAt,
 			"updated_at":     row.UpdatedAt,
 		})
@@ -151,7 +166,9 @@ func (h *AdminHandler) Get(c *gin.Context) {
 		"username":       admin.Username,
 		"active":         admin.Active,
 		"is_super_admin": admin.IsSuperAdmin,
+		"sso_only":       admin.SSOOnly,
 		"permissions":    permissions.ParsePermissions(admin.Permissions),
+		"role_ids":       permissions.ParseRoleIDs(admin.RoleIDs),
 		"created_at":     admin.CreatedAt,
 		"updated_at":     admin.UpdatedAt,
 	})
@@ -161,7 +178,9 @@ func (h *AdminHandler) Get(c *gin.Context) {
 type updateAdminRequest struct {
 	Username     *string   `json:"username"`
 	Permissions  *[]string `json:"permissions"`
+	RoleIDs      *[]uint64 `json:"role_ids"`
 	IsSuperAdmin *bool     `json:"is_super_admin"`
+	SSOOnly      *bool     `json:"sso_only"`
 }
 
 // Update modifies admin account fields.
@@ -199,9 +218,20 @@ func (h *AdminHandler) Update(c *gin.Context) {
 		}
 		updates["permissions"] = datatypes.JSON(permissionsJSON)
 	}
+	if body.RoleIDs != nil {
+		roleIDsJSON, errRoles := h.marshalRoleIDs(c, *body.RoleIDs)
+		if errRoles != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errRoles.Error()})
+			return
+		}
+		updates["role_ids"] = datatypes.JSON(roleIDsJSON)
+	}
 	if body.IsSuperAdmin != nil {
 		updates["is_super_admin"] = *body.IsSuperAdmin
 	}
+	if body.SSOOnly != nil {
+		updates["sso_only"] = *body.SSOOnly
+	}
 
 	res := h.db.WithContext(c.Request.Context()).Model(&models.Admin{}).Where("id = ?", id).Updates(updates)
 	if res.Error != nil {
@@ -215,6 +245,23 @@ func (h *AdminHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
+// marshalRoleIDs validates that every role exists and serializes the IDs.
+func (h *AdminHandler) marshalRoleIDs(c *gin.Context, ids []uint64) ([]byte, error) {
+	normalized := permissions.NormalizeRoleIDs(ids)
+	if len(normalized) > 0 {
+		var count int64
+		if errCount := h.db.WithContext(c.Request.Context()).Model(&models.AdminRole{}).
+			Where("id IN ?", normalized).
+			Count(&count).Error; errCount != nil {
+			return nil, errCount
+		}
+		if int(count) != len(normalized) {
+			return nil, fmt.Errorf("invalid role ids")
+		}
+	}
+	return permissions.MarshalRoleIDs(normalized)
+}
+
 // Delete removes an admin account.
 func (h *AdminHandler) Delete(c *gin.Context) {
 	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
@@ -340,3 +387,21 @@ func (h *AdminHandler) ChangePassword(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// ResetMFA removes every MFA factor of an admin so they can enroll again.
+func (h *AdminHandler) ResetMFA(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if errReset := mfa.Reset(c.Request.Context(), h.db, models.MFAOwnerAdmin, id); errReset != nil {
+		if errors.Is(errReset, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset mfa failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
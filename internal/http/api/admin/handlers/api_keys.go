@@ -22,6 +22,12 @@ func NewAPIKeyHandler(db *gorm.DB) *APIKeyHandler {
 	return &APIKeyHandler{db: db}
 }
 
+// scopedKeys returns an API key query limited to keys of users the admin may manage.
+func (h *APIKeyHandler) scopedKeys(c *gin.Context) *gorm.DB {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.APIKey{})
+	return applyUserScope(c, h.db, q, "user_id")
+}
+
 // Create issues a new API key.
 func (h *APIKeyHandler) Create(c *gin.Context) {
 	// body holds the create request payload.
@@ -38,6 +44,11 @@ func (h *APIKeyHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
 		return
 	}
+	// Keys issued here are not bound to a user, so they fall outside any user group scope.
+	if len(readAdminUserGroupScope(c)) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user group out of scope"})
+		return
+	}
 	token, errGenerate := security.GenerateAPIKey()
 	if errGenerate != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "generate api key failed"})
@@ -64,10 +75,10 @@ func (h *APIKeyHandler) Create(c *gin.Context) {
 	})
 }
 
-// List returns all API keys.
+// List returns the API keys the admin may manage.
 func (h *APIKeyHandler) List(c *gin.Context) {
 	var rows []models.APIKey
-	if errFind := h.db.WithContext(c.Request.Context()).Order("created_at DESC").Find(&rows).Error; errFind != nil {
+	if errFind := h.scopedKeys(c).Order("created_at DESC").Find(&rows).Error; errFind != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "list api keys failed"})
 		return
 	}
@@ -95,7 +106,7 @@ func (h *APIKeyHandler) Revoke(c *gin.Context) {
 		return
 	}
 	now := time.Now().UTC()
-	res := h.db.WithContext(c.Request.Context()).Model(&models.APIKey{}).
+	res := h.scopedKeys(c).
 		Where("id = ? AND revoked_at IS NULL", id).
 		Updates(map[string]any{
 			"active":     false,
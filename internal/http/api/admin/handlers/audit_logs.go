@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxAuditExportRows bounds a single audit log export.
+const maxAuditExportRows = 50000
+
+// AuditLogHandler serves admin audit log endpoints.
+type AuditLogHandler struct {
+	db *gorm.DB // Database handle for audit queries.
+}
+
+// NewAuditLogHandler constructs an audit log handler.
+func NewAuditLogHandler(db *gorm.DB) *AuditLogHandler {
+	return &AuditLogHandler{db: db}
+}
+
+// auditLogQuery defines filters shared by list and export.
+type auditLogQuery struct {
+	Page       int    `form:"page,default=1"`   // Page number.
+	Limit      int    `form:"limit,default=20"` // Page size.
+	AdminID    uint64 `form:"admin_id"`         // Acting admin filter.
+	EntityType string `form:"entity_type"`      // Entity type filter.
+	EntityID   string `form:"entity_id"`        // Entity ID filter.
+	Module     string `form:"module"`           // Permission module filter.
+	Method     string `form:"method"`           // HTTP method filter.
+	Status     string `form:"status"`           // "success" or "failed".
+	StartDate  string `form:"start_date"`       // Inclusive start date.
+	EndDate    string `form:"end_date"`         // Inclusive end date.
+	Search     string `form:"search"`           // Free-text search across route, path and admin.
+	Format     string `form:"format"`           // Export format: csv or json.
+}
+
+// List returns audit log entries with paging and filters.
+func (h *AuditLogHandler) List(c *gin.Context) {
+	var q auditLogQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	var total int64
+	if errCount := h.applyFilters(c, q).Count(&total).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "count audit logs failed"})
+		return
+	}
+
+	var rows []models.AuditLog
+	if errFind := h.applyFilters(c, q).
+		Order("created_at DESC, id DESC").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list audit logs failed"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatAuditLog(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": out,
+		"total":      total,
+		"page":       q.Page,
+		"limit":      q.Limit,
+	})
+}
+
+// Export streams filtered audit log entries as CSV or JSON.
+func (h *AuditLogHandler) Export(c *gin.Context) {
+	var q auditLogQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(q.Format))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+
+	var rows []models.AuditLog
+	if errFind := h.applyFilters(c, q).
+		Order("created_at DESC, id DESC").
+		Limit(maxAuditExportRows).
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export audit logs failed"})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		out := make([]gin.H, 0, len(rows))
+		for i := range rows {
+			out = append(out, formatAuditLog(&rows[i]))
+		}
+		c.JSON(http.StatusOK, out)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write([]string{
+		"id", "created_at", "admin_id", "admin_username", "method", "route", "path",
+		"action", "module", "entity_type", "entity_id", "status_code", "ip", "user_agent", "diff",
+	})
+	for _, row := range rows {
+		_ = writer.Write([]string{
+			strconv.FormatUint(row.ID, 10),
+			row.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatUint(row.AdminID, 10),
+			row.AdminUsername,
+			row.Method,
+			row.Route,
+			row.Path,
+			row.Action,
+			row.Module,
+			row.EntityType,
+			row.EntityID,
+			strconv.Itoa(row.StatusCode),
+			row.IP,
+			row.UserAgent,
+			string(row.Diff),
+		})
+	}
+	writer.Flush()
+}
+
+// applyFilters builds the filtered audit log query.
+func (h *AuditLogHandler) applyFilters(c *gin.Context, q auditLogQuery) *gorm.DB {
+	query := h.db.WithContext(c.Request.Context()).Model(&models.AuditLog{})
+	if q.AdminID != 0 {
+		query = query.Where("admin_id = ?", q.AdminID)
+	}
+	if entityType := strings.TrimSpace(q.EntityType); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := strings.TrimSpace(q.EntityID); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if module := strings.TrimSpace(q.Module); module != "" {
+		query = query.Where("module = ?", module)
+	}
+	if method := strings.ToUpper(strings.TrimSpace(q.Method)); method != "" {
+		query = query.Where("method = ?", method)
+	}
+	switch strings.ToLower(strings.TrimSpace(q.Status)) {
+	case "success":
+		query = query.Where("status_code < ?", http.StatusBadRequest)
+	case "failed":
+		query = query.Where("status_code >= ?", http.StatusBadRequest)
+	}
+	if q.StartDate != "" {
+		if startTime, errParse := time.ParseInLocation("2006-01-02", q.StartDate, time.Local); errParse == nil {
+			query = query.Where("created_at >= ?", startTime)
+		}
+	}
+	if q.EndDate != "" {
+		if endTime, errParse := time.ParseInLocation("2006-01-02", q.EndDate, time.Local); errParse == nil {
+			query = query.Where("created_at < ?", endTime.AddDate(0, 0, 1))
+		}
+	}
+	if search := strings.TrimSpace(q.Search); search != "" {
+		pattern := dbutil.NormalizeLikePattern(h.db, "%"+search+"%")
+		query = query.Where(
+			dbutil.CaseInsensitiveLikeExpr(h.db, "route")+" OR "+
+				dbutil.CaseInsensitiveLikeExpr(h.db, "path")+" OR "+
+				dbutil.CaseInsensitiveLikeExpr(h.db, "admin_username"),
+			pattern,
+			pattern,
+			pattern,
+		)
+	}
+	return query
+}
+
+// formatAuditLog converts an audit row into its API representation.
+func formatAuditLog(row *models.AuditLog) gin.H {
+	return gin.H{
+		"id":             row.ID,
+		"admin_id":       row.AdminID,
+		"admin_username": row.AdminUsername,
+		"method":         row.Method,
+		"route":          row.Route,
+		"path":           row.Path,
+		"action":         row.Action,
+		"module":         row.Module,
+		"entity_type":    row.EntityType,
+		"entity_id":      row.EntityID,
+		"status_code":    row.StatusCode,
+		"before":         rawJSONOrNil(row.Before),
+		"after":          rawJSONOrNil(row.After),
+		"diff":           rawJSONOrNil(row.Diff),
+		"ip":             row.IP,
+		"user_agent":     row.UserAgent,
+		"created_at":     row.CreatedAt,
+	}
+}
+
+// rawJSONOrNil returns stored JSON as a raw message, or nil when empty.
+func rawJSONOrNil(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.RawMessage(data)
+}
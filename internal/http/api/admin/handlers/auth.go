@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	"gorm.io/gorm"
@@ -55,8 +56,17 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "admin account is disabled"})
 		return
 	}
+	if admin.SSOOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "sso login required"})
+		return
+	}
 
-	if strings.TrimSpace(admin.TOTPSecret) != "" || len(admin.PasskeyID) > 0 || len(admin.PasskeyPublicKey) > 0 {
+	status, errStatus := mfa.LoadStatus(c.Request.Context(), h.db, models.MFAOwnerAdmin, admin.ID, admin.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if status.Enabled() {
 		c.JSON(http.StatusForbidden, gin.H{"error": "mfa required"})
 		return
 	}
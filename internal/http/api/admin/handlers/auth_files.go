@@ -13,21 +13,24 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authfiles"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 // AuthFileHandler manages auth file endpoints.
 type AuthFileHandler struct {
-	db *gorm.DB
+	db          *gorm.DB
+	authManager *coreauth.Manager
+	reauth      *authfiles.ReauthSessions
 }
 
 // NewAuthFileHandler constructs an AuthFileHandler.
 func NewAuthFileHandler(db *gorm.DB) *AuthFileHandler {
-	return &AuthFileHandler{db: db}
+	return &AuthFileHandler{db: db, reauth: authfiles.NewReauthSessions()}
 }
 
 // createAuthFileRequest defines the request body for auth file creation.
@@ -39,6 +42,7 @@ type createAuthFileRequest struct {
 	IsAvailable *bool               `json:"is_available"`
 	RateLimit   int                 `json:"rate_limit"`
 	Priority    int                 `json:"priority"`
+	MonthlyCost int64               `json:"monthly_cost_micros"`
 }
 
 type importAuthFilesFailure struct {
@@ -63,6 +67,10 @@ func (h *AuthFileHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing key"})
 		return
 	}
+	if body.MonthlyCost < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid monthly_cost_micros"})
+		return
+	}
 
 	isAvailable := true
 	if body.IsAvailable != nil {
@@ -107,15 +115,16 @@ func (h *AuthFileHandler) Create(c *gin.Context) {
 		}
 	}
 	auth := models.Auth{
-		Key:         key,
-		AuthGroupID: authGroupIDs,
-		ProxyURL:    proxyURL,
-		Content:     contentJSON,
-		IsAvailable: isAvailable,
-		RateLimit:   body.RateLimit,
-		Priority:    body.Priority,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		Key:               key,
+		AuthGroupID:       authGroupIDs,
+		ProxyURL:          proxyURL,
+		Content:           contentJSON,
+		IsAvailable:       isAvailable,
+		RateLimit:         body.RateLimit,
+		Priority:          body.Priority,
+		MonthlyCostMicros: body.MonthlyCost,
+		CreatedAt:         now,
+		UpdatedAt:         now,
 	}
 
 	if errCreate := h.db.WithContext(c.Request.Context()).Create(&auth).Error; errCreate != nil {
@@ -128,16 +137,17 @@ func (h *AuthFileHandler) Create(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":            auth.ID,
-		"key":           auth.Key,
-		"auth_group_id": auth.AuthGroupID.Clean(),
-		"proxy_url":     auth.ProxyURL,
-		"content":       auth.Content,
-		"is_available":  auth.IsAvailable,
-		"rate_limit":    auth.RateLimit,
-		"priority":      auth.Priority,
-		"created_at":    auth.CreatedAt,
-		"updated_at":    auth.UpdatedAt,
+		"id":                  auth.ID,
+		"key":                 auth.Key,
+		"auth_group_id":       auth.AuthGroupID.Clean(),
+		"proxy_url":           auth.ProxyURL,
+		"content":             auth.Content,
+		"is_available":        auth.IsAvailable,
+		"rate_limit":          auth.RateLimit,
+		"priority":            auth.Priority,
+		"monthly_cost_micros": auth.MonthlyCostMicros,
+		"created_at":          auth.CreatedAt,
+		"updated_at":          auth.UpdatedAt,
 	})
 }
 
@@ -168,16 +178,12 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 	}
 
 	if !groupProvided {
-		var defaultGroup models.AuthGroup
-		if errFind := h.db.WithContext(c.Request.Context()).
-			Where("is_default = ?", true).
-			First(&defaultGroup).Error; errFind == nil {
-			defaultGroupID := defaultGroup.ID
-			authGroupIDs = models.AuthGroupIDs{&defaultGroupID}
-		} else if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+		defaultGroupIDs, errDefault := authfiles.DefaultGroupIDs(c.Request.Context(), h.db)
+		if errDefault != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "query default auth group failed"})
 			return
 		}
+		authGroupIDs = defaultGroupIDs
 	}
 
 	now := time.Now().UTC()
@@ -191,7 +197,7 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 		if !strings.EqualFold(filepath.Ext(file.Filename), ".json") {
 			failures = append(failures, importAuthFilesFailure{
 				File:  file.Filename,
-				Error: "file must be json",
+				Error: authfiles.ErrNotJSON.Error(),
 			})
 			continue
 		}
@@ -212,57 +218,17 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 			})
 			continue
 		}
-		if len(data) == 0 {
-			failures = append(failures, importAuthFilesFailure{
-				File:  file.Filename,
-				Error: "empty json file",
-			})
-			continue
-		}
-
-		var payload map[string]any
-		if errUnmarshal := json.Unmarshal(data, &payload); errUnmarshal != nil {
-			failures = append(failures, importAuthFilesFailure{
-				File:  file.Filename,
-				Error: "invalid json",
-			})
-			continue
-		}
 
-		key := ""
-		if idValue, okID := payload["id"].(string); okID {
-			key = strings.TrimSpace(idValue)
-		}
-		if key == "" {
-			if keyValue, okKey := payload["key"].(string); okKey {
-				key = strings.TrimSpace(keyValue)
-			}
-		}
-		if key == "" {
-			key = strings.TrimSpace(file.Filename)
-		}
-		if key == "" {
+		parsed, errParse := authfiles.Parse(file.Filename, data)
+		if errParse != nil {
 			failures = append(failures, importAuthFilesFailure{
 				File:  file.Filename,
-				Error: "missing key",
+				Error: errParse.Error(),
 			})
 			continue
 		}
 
-		if _, okType := payload["type"]; !okType {
-			if provider, okProvider := payload["provider"].(string); okProvider && strings.TrimSpace(provider) != "" {
-				payload["type"] = strings.TrimSpace(provider)
-			} else if metadataValue, okMetadata := payload["metadata"].(map[string]any); okMetadata {
-				if typeValue, okType := metadataValue["type"].(string); okType && strings.TrimSpace(typeValue) != "" {
-					payload["type"] = strings.TrimSpace(typeValue)
-				}
-			}
-		}
-
-		proxyURL := ""
-		if proxyValue, okProxy := payload["proxy_url"].(string); okProxy {
-			proxyURL = strings.TrimSpace(proxyValue)
-		}
+		proxyURL := parsed.ProxyURL
 		if proxyURL == "" && autoAssignProxyEnabled() {
 			assignedProxyURL, errAssignProxy := pickRandomProxyURL(c.Request.Context(), h.db)
 			if errAssignProxy != nil {
@@ -277,33 +243,7 @@ func (h *AuthFileHandler) Import(c *gin.Context) {
 			}
 		}
 
-		contentBytes, errMarshal := json.Marshal(payload)
-		if errMarshal != nil {
-			failures = append(failures, importAuthFilesFailure{
-				File:  file.Filename,
-				Error: "marshal json failed",
-			})
-			continue
-		}
-
-		auth := models.Auth{
-			Key:         key,
-			AuthGroupID: authGroupIDs,
-			ProxyURL:    proxyURL,
-			Content:     datatypes.JSON(contentBytes),
-			IsAvailable: true,
-			CreatedAt:   now,
-			UpdatedAt:   now,
-		}
-
-		errCreate := h.db.WithContext(c.Request.Context()).Clauses(clause.OnConflict{
-			Columns: []clause.Column{{Name: "key"}},
-			DoUpdates: clause.Assignments(map[string]any{
-				"content":    auth.Content,
-				"updated_at": now,
-			}),
-		}).Create(&auth).Error
-		if errCreate != nil {
+		if errUpsert := authfiles.Upsert(c.Request.Context(), h.db, parsed, authGroupIDs, proxyURL, now); errUpsert != nil {
 			failures = append(failures, importAuthFilesFailure{
 				File:  file.Filename,
 				Error: "import auth file failed",
@@ -358,16 +298,17 @@ func (h *AuthFileHandler) List(c *gin.Context) {
 	for _, row := range rows {
 		authGroupIDs := row.AuthGroupID.Clean()
 		item := gin.H{
-			"id":            row.ID,
-			"key":           row.Key,
-			"auth_group_id": authGroupIDs,
-			"proxy_url":     row.ProxyURL,
-			"content":       row.Content,
-			"is_available":  row.IsAvailable,
-			"rate_limit":    row.RateLimit,
-			"priority":      row.Priority,
-			"created_at":    row.CreatedAt,
-			"updated_at":    row.UpdatedAt,
+			"id":                  row.ID,
+			"key":                 row.Key,
+			"auth_group_id":       authGroupIDs,
+			"proxy_url":           row.ProxyURL,
+			"content":             row.Content,
+			"is_available":        row.IsAvailable,
+			"rate_limit":          row.RateLimit,
+			"priority":            row.Priority,
+			"monthly_cost_micros": row.MonthlyCostMicros,
+			"created_at":          row.CreatedAt,
+			"updated_at":          row.UpdatedAt,
 		}
 		item["auth_group"] = buildAuthGroupSummaries(authGroupIDs, groupMap)
 		out = append(out, item)
@@ -400,16 +341,17 @@ func (h *AuthFileHandler) Get(c *gin.Context) {
 		return
 	}
 	item := gin.H{
-		"id":            auth.ID,
-		"key":           auth.Key,
-		"auth_group_id": authGroupIDs,
-		"proxy_url":     auth.ProxyURL,
-		"content":       auth.Content,
-		"is_available":  auth.IsAvailable,
-		"rate_limit":    auth.RateLimit,
-		"priority":      auth.Priority,
-		"created_at":    auth.CreatedAt,
-		"updated_at":    auth.UpdatedAt,
+		"id":                  auth.ID,
+		"key":                 auth.Key,
+		"auth_group_id":       authGroupIDs,
+		"proxy_url":           auth.ProxyURL,
+		"content":             auth.Content,
+		"is_available":        auth.IsAvailable,
+		"rate_limit":          auth.RateLimit,
+		"priority":            auth.Priority,
+		"monthly_cost_micros": auth.MonthlyCostMicros,
+		"created_at":          auth.CreatedAt,
+		"updated_at":          auth.UpdatedAt,
 	}
 	item["auth_group"] = buildAuthGroupSummaries(authGroupIDs, groupMap)
 	c.JSON(http.StatusOK, item)
@@ -424,6 +366,7 @@ type updateAuthFileRequest struct {
 	IsAvailable *bool                `json:"is_available"`
 	RateLimit   *int                 `json:"rate_limit"`
 	Priority    *int                 `json:"priority"`
+	MonthlyCost *int64               `json:"monthly_cost_micros"`
 }
 
 // Update modifies an auth file entry.
@@ -467,6 +410,13 @@ func (h *AuthFileHandler) Update(c *gin.Context) {
 	if body.Priority != nil {
 		updates["priority"] = *body.Priority
 	}
+	if body.MonthlyCost != nil {
+		if *body.MonthlyCost < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid monthly_cost_micros"})
+			return
+		}
+		updates["monthly_cost_micros"] = *body.MonthlyCost
+	}
 
 	res := h.db.WithContext(c.Request.Context()).Model(&models.Auth{}).Where("id = ?", id).Updates(updates)
 	if res.Error != nil {
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authfiles"
+	"gorm.io/gorm"
+)
+
+// WithAuthManager lets the handler consult runtime auth state, used to detect credentials the
+// upstream rejects. It returns the handler for chaining.
+func (h *AuthFileHandler) WithAuthManager(manager *coreauth.Manager) *AuthFileHandler {
+	h.authManager = manager
+	return h
+}
+
+// authFileFilterRequest selects auths for bulk actions and exports.
+type authFileFilterRequest struct {
+	IDs         []uint64 `json:"ids"`
+	Key         string   `json:"key"`
+	AuthGroupID *uint64  `json:"auth_group_id"`
+	Type        string   `json:"type"`
+	IsAvailable *bool    `json:"is_available"`
+}
+
+// toFilter converts the request into an authfiles.Filter.
+func (r authFileFilterRequest) toFilter() authfiles.Filter {
+	return authfiles.Filter{
+		IDs:         r.IDs,
+		Key:         r.Key,
+		AuthGroupID: r.AuthGroupID,
+		Type:        r.Type,
+		IsAvailable: r.IsAvailable,
+	}
+}
+
+// bulkAuthFilesRequest defines the request body for bulk auth file actions.
+type bulkAuthFilesRequest struct {
+	Filter       authFileFilterRequest `json:"filter"`
+	Action       string                `json:"action"`
+	GroupMode    string                `json:"group_mode"`
+	AuthGroupIDs []uint64              `json:"auth_group_ids"`
+	ProxyURL     string                `json:"proxy_url"`
+	Priority     *int                  `json:"priority"`
+}
+
+// Bulk applies one action to every auth file matching a filter.
+func (h *AuthFileHandler) Bulk(c *gin.Context) {
+	var body bulkAuthFilesRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	filter := body.Filter.toFilter()
+	if filter.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "filter is required"})
+		return
+	}
+	action := authfiles.BulkAction{
+		Action:       body.Action,
+		GroupMode:    body.GroupMode,
+		AuthGroupIDs: authGroupIDsFromValues(body.AuthGroupIDs),
+		ProxyURL:     body.ProxyURL,
+	}
+	if errValidate := action.Validate(); errValidate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidate.Error()})
+		return
+	}
+	if action.Action == authfiles.ActionSetPriority {
+		if body.Priority == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing priority"})
+			return
+		}
+		action.Priority = *body.Priority
+	}
+	if action.Action == authfiles.ActionSetGroup && action.GroupMode != authfiles.GroupModeRemove && len(action.AuthGroupIDs.Clean()) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing auth_group_ids"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	rows, errFind := authfiles.Find(ctx, h.db, filter)
+	if errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	updated, errApply := authfiles.ApplyBulk(ctx, h.db, rows, action, time.Now().UTC())
+	if errApply != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "bulk update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"matched": len(rows), "updated": updated})
+}
+
+// Export downloads the selected auth files as a zip of JSON files. The filter is read from the
+// JSON body on POST and from query parameters on GET, where ids is comma separated.
+func (h *AuthFileHandler) Export(c *gin.Context) {
+	var req authFileFilterRequest
+	if c.Request.Method == http.MethodPost {
+		if errBind := c.ShouldBindJSON(&req); errBind != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+	} else {
+		parsed, errParse := parseAuthFileFilterQuery(c)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errParse.Error()})
+			return
+		}
+		req = parsed
+	}
+
+	rows, errFind := authfiles.Find(c.Request.Context(), h.db, req.toFilter())
+	if errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no auth files matched"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if errZip := authfiles.WriteZip(&buf, rows); errZip != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed"})
+		return
+	}
+	filename := fmt.Sprintf("auth-files-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// parseAuthFileFilterQuery reads an auth file filter from query parameters.
+func parseAuthFileFilterQuery(c *gin.Context) (authFileFilterRequest, error) {
+	req := authFileFilterRequest{
+		Key:  strings.TrimSpace(c.Query("key")),
+		Type: strings.TrimSpace(c.Query("type")),
+	}
+	if idsQ := strings.TrimSpace(c.Query("ids")); idsQ != "" {
+		for _, part := range strings.Split(idsQ, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, errParse := strconv.ParseUint(part, 10, 64)
+			if errParse != nil || id == 0 {
+				return authFileFilterRequest{}, errors.New("invalid ids")
+			}
+			req.IDs = append(req.IDs, id)
+		}
+	}
+	if groupQ := strings.TrimSpace(c.Query("auth_group_id")); groupQ != "" {
+		groupID, errParse := strconv.ParseUint(groupQ, 10, 64)
+		if errParse != nil || groupID == 0 {
+			return authFileFilterRequest{}, errors.New("invalid auth_group_id")
+		}
+		req.AuthGroupID = &groupID
+	}
+	if availableQ := strings.TrimSpace(c.Query("is_available")); availableQ != "" {
+		available, errParse := strconv.ParseBool(availableQ)
+		if errParse != nil {
+			return authFileFilterRequest{}, errors.New("invalid is_available")
+		}
+		req.IsAvailable = &available
+	}
+	return req, nil
+}
+
+// Invalid lists auth files whose refresh token appears to be invalid, either because the
+// upstream rejected it at runtime or because the stored credentials cannot be renewed.
+func (h *AuthFileHandler) Invalid(c *gin.Context) {
+	rows, errFind := authfiles.Find(c.Request.Context(), h.db, authfiles.Filter{})
+	if errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	now := time.Now().UTC()
+	out := make([]gin.H, 0)
+	for _, row := range rows {
+		reason, source := "", ""
+		if h.authManager != nil {
+			if runtimeAuth, ok := h.authManager.GetByID(row.Key); ok {
+				if reason = authfiles.RuntimeCredentialIssue(runtimeAuth); reason != "" {
+					source = "runtime"
+				}
+			}
+		}
+		if reason == "" {
+			if reason = authfiles.StoredCredentialIssue(row.Content, now); reason != "" {
+				source = "stored"
+			}
+		}
+		if reason == "" {
+			continue
+		}
+		authType := authfiles.ContentType(row.Content)
+		endpoint, _ := authfiles.TokenEndpoint(authType)
+		out = append(out, gin.H{
+			"id":             row.ID,
+			"key":            row.Key,
+			"type":           authType,
+			"auth_group_id":  row.AuthGroupID,
+			"is_available":   row.IsAvailable,
+			"reason":         reason,
+			"source":         source,
+			"token_endpoint": endpoint,
+			"updated_at":     row.UpdatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"auth_files": out})
+}
+
+// StartReauth begins a guided re-authorization of an auth file. The caller then runs the
+// returned OAuth requester and calls CompleteReauth once it reports success.
+func (h *AuthFileHandler) StartReauth(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	rows, errFind := authfiles.Find(c.Request.Context(), h.db, authfiles.Filter{IDs: []uint64{id}})
+	if errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	authType := authfiles.ContentType(rows[0].Content)
+	session, errBegin := h.reauth.Begin(id, authType, time.Now().UTC())
+	if errBegin != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errBegin.Error()})
+		return
+	}
+	endpoint, _ := authfiles.TokenEndpoint(authType)
+	c.JSON(http.StatusOK, gin.H{
+		"id":                      id,
+		"type":                    session.Type,
+		"token_endpoint":          endpoint,
+		"status_endpoint":         "/v0/admin/tokens/get-auth-status",
+		"oauth_callback_endpoint": "/v0/admin/tokens/oauth-callback",
+		"started_at":              session.StartedAt,
+	})
+}
+
+// completeReauthRequest defines the request body for completing a re-authorization.
+type completeReauthRequest struct {
+	SourceKey string `json:"source_key"`
+}
+
+// CompleteReauth moves the credentials issued during the re-authorization into the auth file,
+// preserving its ID, groups, and bindings.
+func (h *AuthFileHandler) CompleteReauth(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body completeReauthRequest
+	if c.Request.ContentLength > 0 {
+		if errBind := c.ShouldBindJSON(&body); errBind != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	session, ok := h.reauth.Lookup(id, now)
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": authfiles.ErrReauthNotStarted.Error()})
+		return
+	}
+	row, errComplete := authfiles.CompleteReauth(c.Request.Context(), h.db, session, body.SourceKey, now)
+	switch {
+	case errors.Is(errComplete, authfiles.ErrReauthPending), errors.Is(errComplete, authfiles.ErrReauthAmbiguous):
+		c.JSON(http.StatusConflict, gin.H{"error": errComplete.Error()})
+		return
+	case errors.Is(errComplete, authfiles.ErrReauthSourceInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": errComplete.Error()})
+		return
+	case errors.Is(errComplete, gorm.ErrRecordNotFound):
+		h.reauth.End(id)
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errComplete != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "complete re-authorization failed"})
+		return
+	}
+	h.reauth.End(id)
+	c.JSON(http.StatusOK, gin.H{
+		"id":            row.ID,
+		"key":           row.Key,
+		"auth_group_id": row.AuthGroupID,
+		"is_available":  row.IsAvailable,
+		"updated_at":    row.UpdatedAt,
+	})
+}
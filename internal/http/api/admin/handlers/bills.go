@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/export"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 )
@@ -40,6 +41,26 @@ type createBillRequest struct {
 	Status      int     `json:"status"`       // Bill status.
 }
 
+// scopedBills returns a bill query limited to bills of users the admin may manage.
+func (h *BillHandler) scopedBills(c *gin.Context) *gorm.DB {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.Bill{})
+	return applyUserScope(c, h.db, q, "user_id")
+}
+
+// checkUserInScope writes an error response and returns false when the admin may not manage the user.
+func (h *BillHandler) checkUserInScope(c *gin.Context, userID uint64) bool {
+	ok, errScope := userInScope(c, h.db, userID)
+	if errScope != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query user failed"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user out of scope"})
+		return false
+	}
+	return true
+}
+
 // Create validates input and inserts a bill record.
 func (h *BillHandler) Create(c *gin.Context) {
 	var body createBillRequest
@@ -56,6 +77,9 @@ func (h *BillHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
 		return
 	}
+	if !h.checkUserInScope(c, body.UserID) {
+		return
+	}
 
 	periodType := models.BillPeriodType(body.PeriodType)
 	if periodType != models.BillPeriodTypeMonthly && periodType != models.BillPeriodTypeYearly {
@@ -129,6 +153,47 @@ func (h *BillHandler) Create(c *gin.Context) {
 
 // List returns bills filtered by query parameters.
 func (h *BillHandler) List(c *gin.Context) {
+	var rows []models.Bill
+	if errFind := h.applyFilters(c).Order("created_at DESC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list bills failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, h.formatBill(&row))
+	}
+	c.JSON(http.StatusOK, gin.H{"bills": out})
+}
+
+// Export streams every bill matching the list filters as CSV or XLSX.
+func (h *BillHandler) Export(c *gin.Context) {
+	format, errFormat := export.ParseFormat(c.Query("format"))
+	if errFormat != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+	export.Serve(c, h.applyFilters(c).Order("created_at DESC, id DESC"), format, billExportTable)
+}
+
+// billExportTable describes the columns of a bill export.
+var billExportTable = export.Table[models.Bill]{
+	Name: "bills",
+	Header: []string{
+		"id", "plan_id", "user_id", "organization_id", "user_group_ids", "period_type", "amount",
+		"period_start", "period_end", "total_quota", "daily_quota", "used_quota", "left_quota",
+		"used_count", "rate_limit", "is_enabled", "status", "created_at", "updated_at",
+	},
+	Row: func(bill *models.Bill) []any {
+		return []any{
+			bill.ID, bill.PlanID, bill.UserID, bill.OrganizationID, joinIDs(bill.UserGroupID.Values()), int(bill.PeriodType), bill.Amount,
+			bill.PeriodStart, bill.PeriodEnd, bill.TotalQuota, bill.DailyQuota, bill.UsedQuota, bill.LeftQuota,
+			bill.UsedCount, bill.RateLimit, bill.IsEnabled, int(bill.Status), bill.CreatedAt, bill.UpdatedAt,
+		}
+	},
+}
+
+// applyFilters builds the bill query filtered by plan_id, user_id, status and is_enabled.
+func (h *BillHandler) applyFilters(c *gin.Context) *gorm.DB {
 	var (
 		planIDQ  = strings.TrimSpace(c.Query("plan_id"))
 		userIDQ  = strings.TrimSpace(c.Query("user_id"))
@@ -136,7 +201,7 @@ func (h *BillHandler) List(c *gin.Context) {
 		enabledQ = strings.TrimSpace(c.Query("is_enabled"))
 	)
 
-	q := h.db.WithContext(c.Request.Context()).Model(&models.Bill{})
+	q := h.scopedBills(c)
 	if planIDQ != "" {
 		if id, errParse := strconv.ParseUint(planIDQ, 10, 64); errParse == nil {
 			q = q.Where("plan_id = ?", id)
@@ -159,17 +224,16 @@ func (h *BillHandler) List(c *gin.Context) {
 			q = q.Where("is_enabled = ?", false)
 		}
 	}
+	return q
+}
 
-	var rows []models.Bill
-	if errFind := q.Order("created_at DESC").Find(&rows).Error; errFind != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "list bills failed"})
-		return
+// joinIDs renders IDs as a comma-separated list.
+func joinIDs(ids []uint64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatUint(id, 10))
 	}
-	out := make([]gin.H, 0, len(rows))
-	for _, row := range rows {
-		out = append(out, h.formatBill(&row))
-	}
-	c.JSON(http.StatusOK, gin.H{"bills": out})
+	return strings.Join(parts, ",")
 }
 
 // Get returns a bill by ID.
@@ -180,7 +244,7 @@ func (h *BillHandler) Get(c *gin.Context) {
 		return
 	}
 	var bill models.Bill
-	if errFind := h.db.WithContext(c.Request.Context()).First(&bill, id).Error; errFind != nil {
+	if errFind := h.scopedBills(c).First(&bill, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -223,7 +287,7 @@ func (h *BillHandler) Update(c *gin.Context) {
 	}
 
 	var existing models.Bill
-	if errFind := h.db.WithContext(c.Request.Context()).First(&existing, id).Error; errFind != nil {
+	if errFind := h.scopedBills(c).First(&existing, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -248,6 +312,9 @@ func (h *BillHandler) Update(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id cannot be 0"})
 			return
 		}
+		if !h.checkUserInScope(c, *body.UserID) {
+			return
+		}
 		updates["user_id"] = *body.UserID
 	}
 	if body.PeriodType != nil {
@@ -322,7 +389,7 @@ func (h *BillHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Delete(&models.Bill{}, id)
+	res := h.scopedBills(c).Delete(&models.Bill{}, id)
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
@@ -353,7 +420,7 @@ func (h *BillHandler) setEnabled(c *gin.Context, enabled bool) {
 	}
 
 	now := time.Now().UTC()
-	res := h.db.WithContext(c.Request.Context()).Model(&models.Bill{}).Where("id = ?", id).
+	res := h.scopedBills(c).Where("id = ?", id).
 		Updates(map[string]any{"is_enabled": enabled, "updated_at": now})
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
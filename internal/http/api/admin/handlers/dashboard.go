@@ -3,10 +3,12 @@ package handlers
 import (
 	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/reports"
 	"gorm.io/gorm"
 )
 
@@ -68,19 +70,13 @@ func (h *DashboardHandler) KPI(c *gin.Context) {
 		`).
 		Scan(&yesterdayStats)
 
-	var mtdCost int64
-	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-		Where("requested_at >= ?", monthStart).
-		Select("COALESCE(SUM(cost_micros), 0)").
-		Scan(&mtdCost)
+	mtdTotals, _ := reports.LoadTotals(c.Request.Context(), h.db, reports.Range{From: monthStart})
+	mtdCost := mtdTotals.CostMicros
 
 	lastMonthStart := monthStart.AddDate(0, -1, 0)
 	lastMonthSameDay := lastMonthStart.AddDate(0, 0, now.Day()-1)
-	var lastMtdCost int64
-	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-		Where("requested_at >= ? AND requested_at < ?", lastMonthStart, lastMonthSameDay).
-		Select("COALESCE(SUM(cost_micros), 0)").
-		Scan(&lastMtdCost)
+	lastMtdTotals, _ := reports.LoadTotals(c.Request.Context(), h.db, reports.Range{From: lastMonthStart, To: lastMonthSameDay})
+	lastMtdCost := lastMtdTotals.CostMicros
 
 	requestsTrend := calcTrend(float64(yesterdayStats.Total), float64(todayStats.Total))
 	successRate := 100.0
@@ -159,55 +155,114 @@ func (h *DashboardHandler) CostDistribution(c *gin.Context) {
 	now := time.Now().In(loc)
 	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
 
-	// modelCost captures aggregated costs per model.
-	type modelCost struct {
-		Model      string // Model identifier.
-		CostMicros int64  // Aggregated cost in micros.
-	}
-	var results []modelCost
-	h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
-		Where("requested_at >= ?", monthStart).
-		Select("model, COALESCE(SUM(cost_micros), 0) AS cost_micros").
-		Group("model").
-		Order("cost_micros DESC").
-		Scan(&results)
-
-	var totalCost int64
-	for _, r := range results {
-		totalCost += r.CostMicros
-	}
+	results, _ := reports.TopModels(c.Request.Context(), h.db, reports.Range{From: monthStart}, 0)
 
 	items := make([]costItem, 0, len(results))
 	for _, r := range results {
-		pct := 0.0
-		if totalCost > 0 {
-			pct = float64(r.CostMicros) / float64(totalCost) * 100
-		}
 		items = append(items, costItem{
 			Model:      r.Model,
 			CostMicros: r.CostMicros,
-			Percentage: pct,
+			Percentage: r.Percentage,
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
+// modelHealthWindow is the lookback of the provider health view.
+const modelHealthWindow = time.Hour
+
 // healthItem represents a provider health status entry.
 type healthItem struct {
-	Provider string `json:"provider"` // Provider display name.
-	Status   string `json:"status"`   // Health status label.
-	Latency  string `json:"latency"`  // Observed latency label.
+	Provider      string        `json:"provider"`        // Provider name.
+	Status        string        `json:"status"`          // Health status label: healthy, degraded or down.
+	Latency       string        `json:"latency"`         // Observed latency label.
+	Requests      int64         `json:"requests"`        // Requests in the window.
+	FailureRate   float64       `json:"failure_rate"`    // Failed request percentage.
+	AvgDurationMs int64         `json:"avg_duration_ms"` // Average total duration in milliseconds.
+	AvgTTFTMs     int64         `json:"avg_ttft_ms"`     // Average time to first token in milliseconds.
+	StatusCodes   map[int]int64 `json:"status_codes"`    // Upstream error status counts.
 }
 
-// ModelHealth returns model health status
+// ModelHealth returns per-provider request outcomes and latency over the last hour.
 func (h *DashboardHandler) ModelHealth(c *gin.Context) {
-	items := []healthItem{
-		{Provider: "OpenAI API", Status: "healthy", Latency: "12ms"},
-		{Provider: "Anthropic API", Status: "healthy", Latency: "45ms"},
-		{Provider: "Local LLM Cluster", Status: "degraded", Latency: "Degraded"},
+	ctx := c.Request.Context()
+	since := time.Now().Add(-modelHealthWindow)
+
+	var rows []struct {
+		Provider      string
+		Requests      int64
+		Failed        int64
+		AvgDurationMs float64
+		AvgTTFTMs     float64
 	}
-	c.JSON(http.StatusOK, gin.H{"items": items})
+	if errScan := h.db.WithContext(ctx).Model(&models.Usage{}).
+		Where("requested_at >= ?", since).
+		Select(`
+			provider,
+			COUNT(*) AS requests,
+			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed,
+			COALESCE(AVG(CASE WHEN duration_ms > 0 THEN duration_ms END), 0) AS avg_duration_ms,
+			COALESCE(AVG(CASE WHEN time_to_first_token_ms > 0 THEN time_to_first_token_ms END), 0) AS avg_ttft_ms
+		`).
+		Group("provider").
+		Order("requests DESC, provider").
+		Scan(&rows).Error; errScan != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query model health failed"})
+		return
+	}
+
+	var codes []struct {
+		Provider       string
+		UpstreamStatus int
+		Requests       int64
+	}
+	if errScan := h.db.WithContext(ctx).Model(&models.Usage{}).
+		Where("requested_at >= ? AND upstream_status >= ?", since, http.StatusBadRequest).
+		Select("provider, upstream_status, COUNT(*) AS requests").
+		Group("provider, upstream_status").
+		Scan(&codes).Error; errScan != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query model health failed"})
+		return
+	}
+	statusCodes := make(map[string]map[int]int64)
+	for _, code := range codes {
+		if statusCodes[code.Provider] == nil {
+			statusCodes[code.Provider] = make(map[int]int64)
+		}
+		statusCodes[code.Provider][code.UpstreamStatus] = code.Requests
+	}
+
+	items := make([]healthItem, 0, len(rows))
+	for _, row := range rows {
+		item := healthItem{
+			Provider:      row.Provider,
+			Requests:      row.Requests,
+			AvgDurationMs: int64(math.Round(row.AvgDurationMs)),
+			AvgTTFTMs:     int64(math.Round(row.AvgTTFTMs)),
+			StatusCodes:   statusCodes[row.Provider],
+		}
+		if item.StatusCodes == nil {
+			item.StatusCodes = map[int]int64{}
+		}
+		if row.Requests > 0 {
+			item.FailureRate = float64(row.Failed) / float64(row.Requests) * 100
+		}
+		switch {
+		case item.FailureRate >= 50:
+			item.Status = "down"
+		case item.FailureRate >= 5:
+			item.Status = "degraded"
+		default:
+			item.Status = "healthy"
+		}
+		item.Latency = "n/a"
+		if item.AvgDurationMs > 0 {
+			item.Latency = strconv.FormatInt(item.AvgDurationMs, 10) + "ms"
+		}
+		items = append(items, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "window_minutes": int(modelHealthWindow / time.Minute)})
 }
 
 // transactionItem represents a recent usage record for the dashboard.
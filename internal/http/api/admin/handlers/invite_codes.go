@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// defaultInviteCodeLength is the length of generated invite codes.
+const defaultInviteCodeLength = 12
+
+// InviteCodeHandler handles admin operations for registration invite codes.
+type InviteCodeHandler struct {
+	db *gorm.DB // Database handle for invite code queries.
+}
+
+// NewInviteCodeHandler wires an invite code handler with its database dependency.
+func NewInviteCodeHandler(db *gorm.DB) *InviteCodeHandler {
+	return &InviteCodeHandler{db: db}
+}
+
+// createInviteCodeRequest captures the payload for creating invite codes.
+type createInviteCodeRequest struct {
+	Code            string     `json:"code"`              // Optional explicit code; generated when empty.
+	Count           int        `json:"count"`             // Number of generated codes; ignored when code is set.
+	Description     string     `json:"description"`       // Admin note.
+	MaxUses         *int       `json:"max_uses"`          // Allowed redemptions; 0 means unlimited.
+	UserGroupID     *uint64    `json:"user_group_id"`     // Optional user group for redeemers.
+	CreditAmount    float64    `json:"credit_amount"`     // Optional starter credit.
+	CreditValidDays int        `json:"credit_valid_days"` // Starter credit validity in days.
+	ExpiresAt       *time.Time `json:"expires_at"`        // Optional expiry.
+	IsEnabled       *bool      `json:"is_enabled"`        // Optional active flag.
+}
+
+// Create generates one or more invite codes.
+func (h *InviteCodeHandler) Create(c *gin.Context) {
+	var body createInviteCodeRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	code := strings.TrimSpace(body.Code)
+	count := body.Count
+	if code != "" || count <= 0 {
+		count = 1
+	}
+	if count > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 1000"})
+		return
+	}
+	maxUses := 1
+	if body.MaxUses != nil {
+		if *body.MaxUses < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_uses cannot be negative"})
+			return
+		}
+		maxUses = *body.MaxUses
+	}
+	if body.CreditAmount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "credit_amount cannot be negative"})
+		return
+	}
+	if body.CreditValidDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "credit_valid_days cannot be negative"})
+		return
+	}
+	var userGroupID *uint64
+	if body.UserGroupID != nil && *body.UserGroupID != 0 {
+		if !userGroupsInScope([]uint64{*body.UserGroupID}, readAdminUserGroupScope(c)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "user group out of scope"})
+			return
+		}
+		idCopy := *body.UserGroupID
+		userGroupID = &idCopy
+	}
+	isEnabled := true
+	if body.IsEnabled != nil {
+		isEnabled = *body.IsEnabled
+	}
+	var createdBy *uint64
+	if adminID, ok := readAdminIDFromContext(c); ok {
+		createdBy = &adminID
+	}
+
+	now := time.Now().UTC()
+	created := make([]gin.H, 0, count)
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for i := 0; i < count; i++ {
+			value := code
+			if value == "" {
+				generated, errGenerate := generateCode(defaultInviteCodeLength)
+				if errGenerate != nil {
+					return errGenerate
+				}
+				value = generated
+			}
+			invite := models.InviteCode{
+				Code:             value,
+				Description:      strings.TrimSpace(body.Description),
+				MaxUses:          maxUses,
+				UserGroupID:      userGroupID,
+				CreditAmount:     body.CreditAmount,
+				CreditValidDays:  body.CreditValidDays,
+				ExpiresAt:        body.ExpiresAt,
+				IsEnabled:        isEnabled,
+				CreatedByAdminID: createdBy,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			}
+			if errCreate := tx.Create(&invite).Error; errCreate != nil {
+				return errCreate
+			}
+			created = append(created, h.formatInviteCode(&invite))
+		}
+		return nil
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create invite code failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invite_codes": created})
+}
+
+// List returns invite codes filtered by query parameters.
+func (h *InviteCodeHandler) List(c *gin.Context) {
+	var (
+		codeQ    = strings.TrimSpace(c.Query("code"))
+		enabledQ = strings.TrimSpace(c.Query("is_enabled"))
+	)
+	q := h.db.WithContext(c.Request.Context()).Model(&models.InviteCode{})
+	if codeQ != "" {
+		pattern := dbutil.NormalizeLikePattern(h.db, "%"+codeQ+"%")
+		q = q.Where(dbutil.CaseInsensitiveLikeExpr(h.db, "code"), pattern)
+	}
+	if enabledQ == "true" || enabledQ == "1" {
+		q = q.Where("is_enabled = ?", true)
+	} else if enabledQ == "false" || enabledQ == "0" {
+		q = q.Where("is_enabled = ?", false)
+	}
+
+	var rows []models.InviteCode
+	if errFind := q.Order("created_at DESC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list invite codes failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, h.formatInviteCode(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"invite_codes": out})
+}
+
+// Get fetches a single invite code by ID.
+func (h *InviteCodeHandler) Get(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var invite models.InviteCode
+	if errFind := h.db.WithContext(c.Request.Context()).First(&invite, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, h.formatInviteCode(&invite))
+}
+
+// updateInviteCodeRequest captures optional fields for invite code updates.
+type updateInviteCodeRequest struct {
+	Description     *string    `json:"description"`       // Optional admin note.
+	MaxUses         *int       `json:"max_uses"`          // Optional redemption cap.
+	UserGroupID     *uint64    `json:"user_group_id"`     // Optional user group; 0 clears.
+	CreditAmount    *float64   `json:"credit_amount"`     // Optional starter credit.
+	CreditValidDays *int       `json:"credit_valid_days"` // Optional credit validity.
+	ExpiresAt       *time.Time `json:"expires_at"`        // Optional expiry.
+	ClearExpiresAt  bool       `json:"clear_expires_at"`  // Removes the expiry when true.
+	IsEnabled       *bool      `json:"is_enabled"`        // Optional active flag.
+}
+
+// Update applies validated field changes to an invite code.
+func (h *InviteCodeHandler) Update(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body updateInviteCodeRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	updates := map[string]any{}
+	if body.Description != nil {
+		updates["description"] = strings.TrimSpace(*body.Description)
+	}
+	if body.MaxUses != nil {
+		if *body.MaxUses < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_uses cannot be negative"})
+			return
+		}
+		updates["max_uses"] = *body.MaxUses
+	}
+	if body.UserGroupID != nil {
+		if *body.UserGroupID == 0 {
+			updates["user_group_id"] = nil
+		} else {
+			if !userGroupsInScope([]uint64{*body.UserGroupID}, readAdminUserGroupScope(c)) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "user group out of scope"})
+				return
+			}
+			updates["user_group_id"] = *body.UserGroupID
+		}
+	}
+	if body.CreditAmount != nil {
+		if *body.CreditAmount < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "credit_amount cannot be negative"})
+			return
+		}
+		updates["credit_amount"] = *body.CreditAmount
+	}
+	if body.CreditValidDays != nil {
+		if *body.CreditValidDays < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "credit_valid_days cannot be negative"})
+			return
+		}
+		updates["credit_valid_days"] = *body.CreditValidDays
+	}
+	if body.ClearExpiresAt {
+		updates["expires_at"] = nil
+	} else if body.ExpiresAt != nil {
+		updates["expires_at"] = body.ExpiresAt.UTC()
+	}
+	if body.IsEnabled != nil {
+		updates["is_enabled"] = *body.IsEnabled
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+	updates["updated_at"] = time.Now().UTC()
+
+	res := h.db.WithContext(c.Request.Context()).Model(&models.InviteCode{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Delete removes an invite code by ID.
+func (h *InviteCodeHandler) Delete(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).Delete(&models.InviteCode{}, id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// formatInviteCode maps an invite code model into a response payload.
+func (h *InviteCodeHandler) formatInviteCode(invite *models.InviteCode) gin.H {
+	return gin.H{
+		"id":                  invite.ID,
+		"code":                invite.Code,
+		"description":         invite.Description,
+		"max_uses":            invite.MaxUses,
+		"used_count":          invite.UsedCount,
+		"user_group_id":       invite.UserGroupID,
+		"credit_amount":       invite.CreditAmount,
+		"credit_valid_days":   invite.CreditValidDays,
+		"expires_at":          invite.ExpiresAt,
+		"is_enabled":          invite.IsEnabled,
+		"created_by_admin_id": invite.CreatedByAdminID,
+		"created_at":          invite.CreatedAt,
+		"updated_at":          invite.UpdatedAt,
+	}
+}
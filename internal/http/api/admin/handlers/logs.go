@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/export"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
 	"gorm.io/gorm"
 )
 
@@ -30,6 +32,8 @@ type adminLogsListQuery struct {
 	EndDate   string `form:"end_date"`         // Inclusive end date.
 	Project   string `form:"project"`          // Source/project filter.
 	Model     string `form:"model"`            // Model filter.
+
+	requestlog.Filter // Per-request detail filters.
 }
 
 // adminLogEntry represents a row in the aggregated logs list.
@@ -52,10 +56,13 @@ type adminLogEntry struct {
 
 // adminLogDetailQuery defines filters for log detail entries.
 type adminLogDetailQuery struct {
-	Date     string `form:"date" binding:"required"` // Target date.
-	Model    string `form:"model"`                   // Model filter.
-	Provider string `form:"provider"`                // Provider filter.
-	Project  string `form:"project"`                 // Project/source filter.
+	Date     string `form:"date"`     // Target date; optional when looking up a request ID.
+	Model    string `form:"model"`    // Model filter.
+	Provider string `form:"provider"` // Provider filter.
+	Project  string `form:"project"`  // Project/source filter.
+	TraceID  string `form:"trace_id"` // Trace ID filter.
+
+	requestlog.Filter // Per-request detail filters.
 }
 
 // adminLogDetailEntry represents a single usage record in detail view.
@@ -68,6 +75,23 @@ type adminLogDetailEntry struct {
 	CostMicros   int64     `json:"cost_micros"`   // Cost in micros.
 	Failed       bool      `json:"failed"`        // Failure flag.
 	Username     string    `json:"username"`      // Username.
+	TraceID      string    `json:"trace_id"`      // OpenTelemetry trace ID.
+
+	RequestID          string `json:"request_id"`             // Client-facing request ID.
+	UpstreamStatus     int    `json:"upstream_status"`        // Upstream HTTP status.
+	ErrorCode          string `json:"error_code"`             // Upstream error code.
+	ErrorMessage       string `json:"error_message"`          // Upstream error message.
+	TimeToFirstTokenMs int64  `json:"time_to_first_token_ms"` // Milliseconds until the first byte.
+	DurationMs         int64  `json:"duration_ms"`            // Total duration in milliseconds.
+	Streaming          bool   `json:"streaming"`              // Whether the response was streamed.
+	ClientIP           string `json:"client_ip"`              // Client IP address.
+	UserAgent          string `json:"user_agent"`             // Client user agent.
+}
+
+// scopedUsage returns a usage query limited to requests of users the admin may manage.
+func (h *AdminLogsHandler) scopedUsage(c *gin.Context) *gorm.DB {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.Usage{})
+	return applyUserScope(c, h.db, q, "usages.user_id")
 }
 
 // List returns aggregated usage logs with paging and filters.
@@ -84,78 +108,14 @@ func (h *AdminLogsHandler) List(c *gin.Context) {
 		q.Limit = 20
 	}
 
-	ctx := c.Request.Context()
-
-	query := h.db.WithContext(ctx).
-		Model(&models.Usage{})
-
-	if q.StartDate != "" {
-		if startTime, errParse := time.ParseInLocation("2006-01-02", q.StartDate, time.Local); errParse == nil {
-			query = query.Where("requested_at >= ?", startTime)
-		}
-	}
-	if q.EndDate != "" {
-		if endTime, errParse := time.ParseInLocation("2006-01-02", q.EndDate, time.Local); errParse == nil {
-			query = query.Where("requested_at < ?", endTime.AddDate(0, 0, 1))
-		}
-	}
-	if q.Project != "" {
-		query = query.Where("source = ?", q.Project)
-	}
-	if q.Model != "" {
-		query = query.Where("model = ?", q.Model)
-	}
-
-	// dailyAgg captures aggregated log metrics for a date and model.
-	type dailyAgg struct {
-		Date         string // Date key.
-		Model        string // Model name.
-		Providers    string // Provider list.
-		Requests     int64  // Request count.
-		InputTokens  int64  // Input tokens.
-		OutputTokens int64  // Output tokens.
-		TotalTokens  int64  // Total tokens.
-		CostMicros   int64  // Cost in micros.
-		FailedCount  int64  // Failed request count.
-	}
-
 	var total int64
-	countQuery := h.db.WithContext(ctx).
-		Model(&models.Usage{})
-	if q.StartDate != "" {
-		if startTime, errParse := time.ParseInLocation("2006-01-02", q.StartDate, time.Local); errParse == nil {
-			countQuery = countQuery.Where("requested_at >= ?", startTime)
-		}
-	}
-	if q.EndDate != "" {
-		if endTime, errParse := time.ParseInLocation("2006-01-02", q.EndDate, time.Local); errParse == nil {
-			countQuery = countQuery.Where("requested_at < ?", endTime.AddDate(0, 0, 1))
-		}
-	}
-	if q.Project != "" {
-		countQuery = countQuery.Where("source = ?", q.Project)
-	}
-	if q.Model != "" {
-		countQuery = countQuery.Where("model = ?", q.Model)
-	}
-	countQuery.Select("COUNT(DISTINCT TO_CHAR(requested_at, 'YYYY-MM-DD') || COALESCE(model, ''))").Scan(&total)
+	h.applyListFilters(c, q).
+		Select("COUNT(DISTINCT TO_CHAR(requested_at, 'YYYY-MM-DD') || COALESCE(model, ''))").
+		Scan(&total)
 
 	offset := (q.Page - 1) * q.Limit
-	var aggs []dailyAgg
-	if errAgg := query.
-		Select(`
-			TO_CHAR(requested_at, 'YYYY-MM-DD') AS date,
-			model,
-			COALESCE(STRING_AGG(DISTINCT NULLIF(provider, ''), ','), '') AS providers,
-			COUNT(*) AS requests,
-			COALESCE(SUM(input_tokens), 0) AS input_tokens,
-			COALESCE(SUM(output_tokens), 0) AS output_tokens,
-			COALESCE(SUM(total_tokens), 0) AS total_tokens,
-			COALESCE(SUM(cost_micros), 0) AS cost_micros,
-			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count
-		`).
-		Group("TO_CHAR(requested_at, 'YYYY-MM-DD'), model").
-		Order("TO_CHAR(requested_at, 'YYYY-MM-DD') DESC, model").
+	var aggs []adminLogAgg
+	if errAgg := h.dailyAggQuery(c, q).
 		Offset(offset).Limit(q.Limit).
 		Scan(&aggs).Error; errAgg != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query logs failed"})
@@ -208,6 +168,89 @@ func (h *AdminLogsHandler) List(c *gin.Context) {
 	})
 }
 
+// adminLogAgg captures aggregated log metrics for a date and model.
+type adminLogAgg struct {
+	Date         string // Date key.
+	Model        string // Model name.
+	Providers    string // Provider list.
+	Requests     int64  // Request count.
+	InputTokens  int64  // Input tokens.
+	OutputTokens int64  // Output tokens.
+	TotalTokens  int64  // Total tokens.
+	CostMicros   int64  // Cost in micros.
+	FailedCount  int64  // Failed request count.
+}
+
+// Export streams every aggregated log row matching the list filters as CSV or XLSX.
+func (h *AdminLogsHandler) Export(c *gin.Context) {
+	var q adminLogsListQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	format, errFormat := export.ParseFormat(c.Query("format"))
+	if errFormat != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+	export.Serve(c, h.dailyAggQuery(c, q), format, logExportTable)
+}
+
+// logExportTable describes the columns of an aggregated log export.
+var logExportTable = export.Table[adminLogAgg]{
+	Name: "logs",
+	Header: []string{
+		"date", "model", "providers", "requests", "input_tokens", "output_tokens", "total_tokens",
+		"cost_micros", "failed_count", "error_rate",
+	},
+	Row: func(a *adminLogAgg) []any {
+		return []any{
+			a.Date, a.Model, a.Providers, a.Requests, a.InputTokens, a.OutputTokens, a.TotalTokens,
+			a.CostMicros, a.FailedCount, calcErrorRate(a.FailedCount, a.Requests),
+		}
+	},
+}
+
+// applyListFilters builds the usage query filtered by the list date range, project and model.
+func (h *AdminLogsHandler) applyListFilters(c *gin.Context, q adminLogsListQuery) *gorm.DB {
+	query := h.scopedUsage(c)
+	if q.StartDate != "" {
+		if startTime, errParse := time.ParseInLocation("2006-01-02", q.StartDate, time.Local); errParse == nil {
+			query = query.Where("requested_at >= ?", startTime)
+		}
+	}
+	if q.EndDate != "" {
+		if endTime, errParse := time.ParseInLocation("2006-01-02", q.EndDate, time.Local); errParse == nil {
+			query = query.Where("requested_at < ?", endTime.AddDate(0, 0, 1))
+		}
+	}
+	if q.Project != "" {
+		query = query.Where("source = ?", q.Project)
+	}
+	if q.Model != "" {
+		query = query.Where("model = ?", q.Model)
+	}
+	return q.Filter.Apply(query)
+}
+
+// dailyAggQuery aggregates the filtered usage per date and model, newest first.
+func (h *AdminLogsHandler) dailyAggQuery(c *gin.Context, q adminLogsListQuery) *gorm.DB {
+	return h.applyListFilters(c, q).
+		Select(`
+			TO_CHAR(requested_at, 'YYYY-MM-DD') AS date,
+			model,
+			COALESCE(STRING_AGG(DISTINCT NULLIF(provider, ''), ','), '') AS providers,
+			COUNT(*) AS requests,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cost_micros), 0) AS cost_micros,
+			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count
+		`).
+		Group("TO_CHAR(requested_at, 'YYYY-MM-DD'), model").
+		Order("TO_CHAR(requested_at, 'YYYY-MM-DD') DESC, model")
+}
+
 // Detail returns per-request usage entries for a date and filters.
 func (h *AdminLogsHandler) Detail(c *gin.Context) {
 	var q adminLogDetailQuery
@@ -217,23 +260,12 @@ func (h *AdminLogsHandler) Detail(c *gin.Context) {
 	}
 
 	dateKey := strings.TrimSpace(q.Date)
-	if dateKey == "" {
+	if dateKey == "" && strings.TrimSpace(q.RequestID) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing date"})
 		return
 	}
 
-	day, errParse := time.ParseInLocation("2006-01-02", dateKey, time.Local)
-	if errParse != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
-		return
-	}
-
-	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
-	end := start.AddDate(0, 0, 1)
-
-	ctx := c.Request.Context()
-	query := h.db.WithContext(ctx).
-		Model(&models.Usage{}).
+	query := h.scopedUsage(c).
 		Select(`
 			requested_at,
 			input_tokens,
@@ -242,10 +274,28 @@ func (h *AdminLogsHandler) Detail(c *gin.Context) {
 			total_tokens,
 			cost_micros,
 			failed,
-			COALESCE(users.username, '') AS username
+			COALESCE(usages.trace_id, '') AS trace_id,
+			COALESCE(users.username, '') AS username,
+			COALESCE(usages.request_id, '') AS request_id,
+			usages.upstream_status,
+			COALESCE(usages.error_code, '') AS error_code,
+			COALESCE(usages.error_message, '') AS error_message,
+			usages.time_to_first_token_ms,
+			usages.duration_ms,
+			usages.streaming,
+			COALESCE(usages.client_ip, '') AS client_ip,
+			COALESCE(usages.user_agent, '') AS user_agent
 		`).
-		Joins("LEFT JOIN users ON users.id = usages.user_id").
-		Where("requested_at >= ? AND requested_at < ?", start, end)
+		Joins("LEFT JOIN users ON users.id = usages.user_id")
+	if dateKey != "" {
+		day, errParse := time.ParseInLocation("2006-01-02", dateKey, time.Local)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			return
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+		query = query.Where("requested_at >= ? AND requested_at < ?", start, start.AddDate(0, 0, 1))
+	}
 
 	if strings.TrimSpace(q.Model) != "" {
 		query = query.Where("model = ?", strings.TrimSpace(q.Model))
@@ -256,6 +306,10 @@ func (h *AdminLogsHandler) Detail(c *gin.Context) {
 	if strings.TrimSpace(q.Project) != "" {
 		query = query.Where("source = ?", strings.TrimSpace(q.Project))
 	}
+	if strings.TrimSpace(q.TraceID) != "" {
+		query = query.Where("usages.trace_id = ?", strings.ToLower(strings.TrimSpace(q.TraceID)))
+	}
+	query = q.Filter.Apply(query)
 
 	var rows []adminLogDetailEntry
 	if errFind := query.
@@ -276,6 +330,17 @@ func (h *AdminLogsHandler) Detail(c *gin.Context) {
 			"total_tokens":  row.TotalTokens,
 			"cost":          fmt.Sprintf("$%.4f", float64(row.CostMicros)/1_000_000),
 			"success":       !row.Failed,
+			"trace_id":      row.TraceID,
+
+			"request_id":             row.RequestID,
+			"upstream_status":        row.UpstreamStatus,
+			"error_code":             row.ErrorCode,
+			"error_message":          row.ErrorMessage,
+			"time_to_first_token_ms": row.TimeToFirstTokenMs,
+			"duration_ms":            row.DurationMs,
+			"streaming":              row.Streaming,
+			"client_ip":              row.ClientIP,
+			"user_agent":             row.UserAgent,
 		})
 	}
 
@@ -284,7 +349,6 @@ func (h *AdminLogsHandler) Detail(c *gin.Context) {
 
 // Stats returns aggregated KPIs for today vs yesterday.
 func (h *AdminLogsHandler) Stats(c *gin.Context) {
-	ctx := c.Request.Context()
 	loc := time.Local
 	now := time.Now().In(loc)
 	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
@@ -301,7 +365,7 @@ func (h *AdminLogsHandler) Stats(c *gin.Context) {
 
 	var todayStats, yesterdayStats statResult
 
-	h.db.WithContext(ctx).Model(&models.Usage{}).
+	h.scopedUsage(c).
 		Where("requested_at >= ?", todayStart).
 		Select(`
 			COUNT(*) AS requests,
@@ -311,7 +375,7 @@ func (h *AdminLogsHandler) Stats(c *gin.Context) {
 			COALESCE(AVG(GREATEST(EXTRACT(EPOCH FROM (created_at - requested_at)) * 1000, 0)), 0) AS avg_request_time_ms
 		`).Scan(&todayStats)
 
-	h.db.WithContext(ctx).Model(&models.Usage{}).
+	h.scopedUsage(c).
 		Where("requested_at >= ? AND requested_at < ?", yesterdayStart, todayStart).
 		Select(`
 			COUNT(*) AS requests,
@@ -348,7 +412,6 @@ func (h *AdminLogsHandler) Stats(c *gin.Context) {
 
 // Trend returns a seven-day trend of requests and tokens.
 func (h *AdminLogsHandler) Trend(c *gin.Context) {
-	ctx := c.Request.Context()
 	loc := time.Local
 	now := time.Now().In(loc)
 	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
@@ -374,8 +437,7 @@ func (h *AdminLogsHandler) Trend(c *gin.Context) {
 	}
 
 	var rows []dailyTrend
-	if errFind := h.db.WithContext(ctx).
-		Model(&models.Usage{}).
+	if errFind := h.scopedUsage(c).
 		Select(`TO_CHAR(requested_at, 'YYYY-MM-DD') AS date, COUNT(*) AS requests, COALESCE(SUM(total_tokens), 0) AS total_tokens`).
 		Where("requested_at >= ?", sevenDaysAgo).
 		Group("TO_CHAR(requested_at, 'YYYY-MM-DD')").
@@ -404,7 +466,7 @@ func (h *AdminLogsHandler) Trend(c *gin.Context) {
 // Models returns the distinct model names from usage logs.
 func (h *AdminLogsHandler) Models(c *gin.Context) {
 	var modelList []string
-	if errModels := h.db.WithContext(c.Request.Context()).Table("usages").
+	if errModels := h.scopedUsage(c).
 		Distinct("model").
 		Pluck("model", &modelList).Error; errModels != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query models failed"})
@@ -417,7 +479,7 @@ func (h *AdminLogsHandler) Models(c *gin.Context) {
 // Projects returns the distinct project/source names from usage logs.
 func (h *AdminLogsHandler) Projects(c *gin.Context) {
 	var projects []string
-	if errProjects := h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).
+	if errProjects := h.scopedUsage(c).
 		Where("source != ''").
 		Distinct("source").
 		Pluck("source", &projects).Error; errProjects != nil {
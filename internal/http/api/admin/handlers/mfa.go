@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"image/png"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +19,7 @@ import (
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/pquerna/otp/totp"
 	permissions "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	log "github.com/sirupsen/logrus"
@@ -177,36 +180,13 @@ func (u adminWebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
 	return u.credentials
 }
 
-// newAdminWebAuthnUser builds a WebAuthn adapter from an admin model.
-func newAdminWebAuthnUser(admin models.Admin) adminWebAuthnUser {
-	user := adminWebAuthnUser{
-		id:       admin.ID,
-		username: admin.Username,
+// newAdminWebAuthnUser builds a WebAuthn adapter from an admin model and its passkeys.
+func newAdminWebAuthnUser(admin models.Admin, passkeys []models.PasskeyCredential) adminWebAuthnUser {
+	return adminWebAuthnUser{
+		id:          admin.ID,
+		username:    admin.Username,
+		credentials: mfa.WebAuthnCredentials(passkeys),
 	}
-	if len(admin.PasskeyID) > 0 && len(admin.PasskeyPublicKey) > 0 {
-		signCount := uint32(0)
-		if admin.PasskeySignCount != nil {
-			signCount = *admin.PasskeySignCount
-		}
-		flags := webauthn.CredentialFlags{}
-		if admin.PasskeyBackupEligible != nil {
-			flags.BackupEligible = *admin.PasskeyBackupEligible
-		}
-		if admin.PasskeyBackupState != nil {
-			flags.BackupState = *admin.PasskeyBackupState
-		}
-		user.credentials = []webauthn.Credential{
-			{
-				ID:        admin.PasskeyID,
-				PublicKey: admin.PasskeyPublicKey,
-				Flags:     flags,
-				Authenticator: webauthn.Authenticator{
-					SignCount: signCount,
-				},
-			},
-		}
-	}
-	return user
 }
 
 // readAdminIDFromContext returns the admin ID from request context.
@@ -219,30 +199,67 @@ func readAdminIDFromContext(c *gin.Context) (uint64, bool) {
 	return id, ok
 }
 
-// Status returns MFA enablement status for the admin.
-func (h *MFAHandler) Status(c *gin.Context) {
+// loadAdmin loads the current admin for an MFA endpoint, writing an error response on failure.
+func (h *MFAHandler) loadAdmin(c *gin.Context) (*models.Admin, bool) {
 	adminID, ok := readAdminIDFromContext(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
-		return
+		return nil, false
 	}
-
 	var admin models.Admin
-	if errFind := h.db.WithContext(c.Request.Context()).Select("id", "totp_secret", "passkey_id", "passkey_public_key").First(&admin, adminID).Error; errFind != nil {
-		if errFind == gorm.ErrRecordNotFound {
+	if errFind := h.db.WithContext(c.Request.Context()).First(&admin, adminID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-			return
+			return nil, false
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return nil, false
+	}
+	return &admin, true
+}
+
+// allowFactorRemoval rejects removing the last MFA factor when administrators must use MFA.
+func (h *MFAHandler) allowFactorRemoval(c *gin.Context, admin *models.Admin, removeTOTP bool, removePasskeys int) bool {
+	if !mfa.AdminRequired(*admin) {
+		return true
+	}
+	status, errStatus := mfa.LoadStatus(c.Request.Context(), h.db, models.MFAOwnerAdmin, admin.ID, admin.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return false
+	}
+	if removeTOTP {
+		status.TOTPEnabled = false
+	}
+	status.Passkeys -= removePasskeys
+	if !status.Enabled() {
+		c.JSON(http.StatusConflict, gin.H{"error": "mfa is required for your account"})
+		return false
+	}
+	return true
+}
+
+// Status returns MFA enablement status for the admin.
+func (h *MFAHandler) Status(c *gin.Context) {
+	admin, ok := h.loadAdmin(c)
+	if !ok {
 		return
 	}
 
-	totpEnabled := strings.TrimSpace(admin.TOTPSecret) != ""
-	passkeyEnabled := len(admin.PasskeyID) > 0 && len(admin.PasskeyPublicKey) > 0
+	ctx := c.Request.Context()
+	status, errStatus := mfa.LoadStatus(ctx, h.db, models.MFAOwnerAdmin, admin.ID, admin.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	required := mfa.AdminRequired(*admin)
 
 	c.JSON(http.StatusOK, gin.H{
-		"totp_enabled":    totpEnabled,
-		"passkey_enabled": passkeyEnabled,
+		"totp_enabled":             status.TOTPEnabled,
+		"passkey_enabled":          status.Passkeys > 0,
+		"passkey_count":            status.Passkeys,
+		"recovery_codes_remaining": status.RecoveryCodesRemaining,
+		"mfa_required":             required,
 	})
 }
 
@@ -288,6 +305,8 @@ func (h *MFAHandler) PrepareTOTP(c *gin.Context) {
 	})
 }
 
+// totpConfirmRequest defines the request body for confirming TOTP.
+
 // totpConfirmRequest defines the request body for confirming TOTP.
 type totpConfirmRequest struct {
 	Code string `json:"code"`
@@ -322,7 +341,8 @@ func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
 		return
 	}
 
-	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.Admin{}).
+	ctx := c.Request.Context()
+	if errUpdate := h.db.WithContext(ctx).Model(&models.Admin{}).
 		Where("id = ?", adminID).
 		Updates(map[string]any{"totp_secret": secret, "updated_at": time.Now().UTC()}).Error; errUpdate != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
@@ -330,19 +350,25 @@ func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
 	}
 
 	totpPendingSecrets.Delete(fmt.Sprintf("%d", adminID))
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	codes, errCodes := mfa.EnsureRecoveryCodes(ctx, h.db, models.MFAOwnerAdmin, adminID)
+	if errCodes != nil {
+		log.WithError(errCodes).Warn("mfa: issue recovery codes failed")
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "recovery_codes": codes})
 }
 
 // DisableTOTP removes the admin's TOTP secret.
 func (h *MFAHandler) DisableTOTP(c *gin.Context) {
-	adminID, ok := readAdminIDFromContext(c)
+	admin, ok := h.loadAdmin(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
+		return
+	}
+	if !h.allowFactorRemoval(c, admin, true, 0) {
 		return
 	}
 
 	res := h.db.WithContext(c.Request.Context()).Model(&models.Admin{}).
-		Where("id = ?", adminID).
+		Where("id = ?", admin.ID).
 		Updates(map[string]any{
 			"totp_secret": "",
 			"updated_at":  time.Now().UTC(),
@@ -356,39 +382,134 @@ func (h *MFAHandler) DisableTOTP(c *gin.Context) {
 		return
 	}
 
-	totpPendingSecrets.Delete(fmt.Sprintf("%d", adminID))
+	totpPendingSecrets.Delete(fmt.Sprintf("%d", admin.ID))
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
-// DisablePasskey removes the admin's passkey credentials.
+// DisablePasskey removes all of the admin's passkeys.
 func (h *MFAHandler) DisablePasskey(c *gin.Context) {
+	admin, ok := h.loadAdmin(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerAdmin, admin.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if !h.allowFactorRemoval(c, admin, false, len(passkeys)) {
+		return
+	}
+
+	if errDelete := mfa.DeleteAllPasskeys(ctx, h.db, models.MFAOwnerAdmin, admin.ID); errDelete != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	passkeyRegistrationSessions.Delete(fmt.Sprintf("%d", admin.ID))
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ListPasskeys returns the admin's registered passkeys.
+func (h *MFAHandler) ListPasskeys(c *gin.Context) {
 	adminID, ok := readAdminIDFromContext(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
 		return
 	}
+	passkeys, errList := mfa.ListPasskeys(c.Request.Context(), h.db, models.MFAOwnerAdmin, adminID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		out = append(out, formatPasskey(passkey))
+	}
+	c.JSON(http.StatusOK, gin.H{"passkeys": out})
+}
 
-	res := h.db.WithContext(c.Request.Context()).Model(&models.Admin{}).
-		Where("id = ?", adminID).
-		Updates(map[string]any{
-			"passkey_id":              nil,
-			"passkey_public_key":      nil,
-			"passkey_sign_count":      nil,
-			"passkey_backup_eligible": nil,
-			"passkey_backup_state":    nil,
-			"updated_at":              time.Now().UTC(),
-		})
-	if res.Error != nil {
+// renamePasskeyRequest defines the request body for renaming a passkey.
+type renamePasskeyRequest struct {
+	Name string `json:"name"`
+}
+
+// RenamePasskey changes the label of one of the admin's passkeys.
+func (h *MFAHandler) RenamePasskey(c *gin.Context) {
+	adminID, ok := readAdminIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body renamePasskeyRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if errRename := mfa.RenamePasskey(c.Request.Context(), h.db, models.MFAOwnerAdmin, adminID, id, body.Name); errRename != nil {
+		if errors.Is(errRename, mfa.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// DeletePasskey removes one of the admin's passkeys.
+func (h *MFAHandler) DeletePasskey(c *gin.Context) {
+	admin, ok := h.loadAdmin(c)
+	if !ok {
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if !h.allowFactorRemoval(c, admin, false, 1) {
+		return
+	}
+	if errDelete := mfa.DeletePasskey(c.Request.Context(), h.db, models.MFAOwnerAdmin, admin.ID, id); errDelete != nil {
+		if errors.Is(errDelete, mfa.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
+	c.Status(http.StatusNoContent)
+}
 
-	passkeyRegistrationSessions.Delete(fmt.Sprintf("%d", adminID))
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+// RegenerateRecoveryCodes replaces the admin's recovery codes.
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	admin, ok := h.loadAdmin(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	status, errStatus := mfa.LoadStatus(ctx, h.db, models.MFAOwnerAdmin, admin.ID, admin.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if !status.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mfa not enabled"})
+		return
+	}
+	codes, errCodes := mfa.GenerateRecoveryCodes(ctx, h.db, models.MFAOwnerAdmin, admin.ID)
+	if errCodes != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "generate recovery codes failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
 }
 
 // BeginPasskeyRegistration starts a passkey registration ceremony.
@@ -405,10 +526,9 @@ func (h *MFAHandler) BeginPasskeyRegistration(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var admin models.Admin
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "passkey_id", "passkey_public_key", "passkey_sign_count", "passkey_backup_eligible", "passkey_backup_state").
-		First(&admin, adminID).Error; errFind != nil {
+	if errFind := h.db.WithContext(ctx).Select("id", "username").First(&admin, adminID).Error; errFind != nil {
 		if errFind == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -416,19 +536,24 @@ func (h *MFAHandler) BeginPasskeyRegistration(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerAdmin, admin.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 
-	user := newAdminWebAuthnUser(admin)
+	webauthnUser := newAdminWebAuthnUser(admin, passkeys)
 	options := []webauthn.RegistrationOption{
 		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
 		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
 			UserVerification: protocol.VerificationPreferred,
 		}),
 	}
-	if len(user.WebAuthnCredentials()) > 0 {
-		options = append(options, webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()))
+	if len(webauthnUser.WebAuthnCredentials()) > 0 {
+		options = append(options, webauthn.WithExclusions(webauthn.Credentials(webauthnUser.WebAuthnCredentials()).CredentialDescriptors()))
 	}
 
-	creation, session, err := webAuthn.BeginRegistration(user, options...)
+	creation, session, err := webAuthn.BeginRegistration(webauthnUser, options...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "begin passkey registration failed"})
 		return
@@ -438,7 +563,7 @@ func (h *MFAHandler) BeginPasskeyRegistration(c *gin.Context) {
 	c.JSON(http.StatusOK, creation)
 }
 
-// FinishPasskeyRegistration completes a passkey registration ceremony.
+// FinishPasskeyRegistration completes a passkey registration ceremony; the name query labels the passkey.
 func (h *MFAHandler) FinishPasskeyRegistration(c *gin.Context) {
 	webAuthn, errWebAuthn := loadWebAuthn()
 	if errWebAuthn != nil {
@@ -452,10 +577,9 @@ func (h *MFAHandler) FinishPasskeyRegistration(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var admin models.Admin
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "passkey_id", "passkey_public_key", "passkey_sign_count").
-		First(&admin, adminID).Error; errFind != nil {
+	if errFind := h.db.WithContext(ctx).Select("id", "username").First(&admin, adminID).Error; errFind != nil {
 		if errFind == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -463,6 +587,11 @@ func (h *MFAHandler) FinishPasskeyRegistration(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerAdmin, admin.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 
 	session, ok := passkeyRegistrationSessions.Get(fmt.Sprintf("%d", admin.ID))
 	if !ok {
@@ -470,33 +599,38 @@ func (h *MFAHandler) FinishPasskeyRegistration(c *gin.Context) {
 		return
 	}
 
-	user := newAdminWebAuthnUser(admin)
-	credential, err := webAuthn.FinishRegistration(user, session, c.Request)
+	webauthnUser := newAdminWebAuthnUser(admin, passkeys)
+	credential, err := webAuthn.FinishRegistration(webauthnUser, session, c.Request)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "registration failed"})
 		return
 	}
 
-	signCount := uint32(credential.Authenticator.SignCount)
-	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.Admin{}).
-		Where("id = ?", adminID).
-		Updates(map[string]any{
-			"passkey_id":              credential.ID,
-			"passkey_public_key":      credential.PublicKey,
-			"passkey_sign_count":      signCount,
-			"passkey_backup_eligible": credential.Flags.BackupEligible,
-			"passkey_backup_state":    credential.Flags.BackupState,
-			"updated_at":              time.Now().UTC(),
-		}).Error; errUpdate != nil {
+	passkey, errAdd := mfa.AddPasskey(ctx, h.db, models.MFAOwnerAdmin, admin.ID, c.Query("name"), credential)
+	if errAdd != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
 
 	passkeyRegistrationSessions.Delete(fmt.Sprintf("%d", admin.ID))
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	codes, errCodes := mfa.EnsureRecoveryCodes(ctx, h.db, models.MFAOwnerAdmin, admin.ID)
+	if errCodes != nil {
+		log.WithError(errCodes).Warn("mfa: issue recovery codes failed")
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "passkey": formatPasskey(*passkey), "recovery_codes": codes})
+}
+
+// formatPasskey renders a passkey without its key material.
+func formatPasskey(passkey models.PasskeyCredential) gin.H {
+	return gin.H{
+		"id":           passkey.ID,
+		"name":         passkey.Name,
+		"last_used_at": passkey.LastUsedAt,
+		"created_at":   passkey.CreatedAt,
+	}
 }
 
-// LoginPrepare returns MFA status prior to admin login.
+// LoginPrepare returns MFA status prior to login.
 func (h *AuthHandler) LoginPrepare(c *gin.Context) {
 	var body loginRequest
 	if errBind := c.ShouldBindJSON(&body); errBind != nil {
@@ -510,9 +644,10 @@ func (h *AuthHandler) LoginPrepare(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var admin models.Admin
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "active", "totp_secret", "passkey_id", "passkey_public_key").
+	if errFind := h.db.WithContext(ctx).
+		Select("id", "username", "active", "sso_only", "totp_secret").
 		Where("username = ?", username).
 		First(&admin).Error; errFind != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
@@ -522,15 +657,22 @@ func (h *AuthHandler) LoginPrepare(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "admin account is disabled"})
 		return
 	}
+	if admin.SSOOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "sso login required"})
+		return
+	}
 
-	totpEnabled := strings.TrimSpace(admin.TOTPSecret) != ""
-	passkeyEnabled := len(admin.PasskeyID) > 0 && len(admin.PasskeyPublicKey) > 0
-	mfaEnabled := totpEnabled || passkeyEnabled
+	status, errStatus := mfa.LoadStatus(ctx, h.db, models.MFAOwnerAdmin, admin.ID, admin.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"mfa_enabled":     mfaEnabled,
-		"totp_enabled":    totpEnabled,
-		"passkey_enabled": passkeyEnabled,
+		"mfa_enabled":     status.Enabled(),
+		"totp_enabled":    status.TOTPEnabled,
+		"passkey_enabled": status.Passkeys > 0,
+		"recovery_codes":  status.RecoveryCodesRemaining > 0,
 	})
 }
 
@@ -565,6 +707,10 @@ func (h *AuthHandler) LoginTOTP(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "admin account is disabled"})
 		return
 	}
+	if admin.SSOOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "sso login required"})
+		return
+	}
 	if strings.TrimSpace(admin.TOTPSecret) == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "totp not enabled"})
 		return
@@ -577,6 +723,59 @@ func (h *AuthHandler) LoginTOTP(c *gin.Context) {
 	h.respondWithAdminToken(c, admin)
 }
 
+// loginRecoveryRequest defines the request body for recovery code login.
+type loginRecoveryRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// LoginRecovery authenticates an admin with their password and a one-time recovery code.
+func (h *AuthHandler) LoginRecovery(c *gin.Context) {
+	var body loginRecoveryRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	username := strings.TrimSpace(body.Username)
+	password := strings.TrimSpace(body.Password)
+	code := strings.TrimSpace(body.Code)
+	if username == "" || password == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username, password and code are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var admin models.Admin
+	if errFind := h.db.WithContext(ctx).Where("username = ?", username).First(&admin).Error; errFind != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	if !admin.Active {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin account is disabled"})
+		return
+	}
+	if admin.SSOOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "sso login required"})
+		return
+	}
+	if !security.CheckPassword(admin.Password, password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	consumed, errConsume := mfa.ConsumeRecoveryCode(ctx, h.db, models.MFAOwnerAdmin, admin.ID, code)
+	if errConsume != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if !consumed {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	h.respondWithAdminToken(c, admin)
+}
+
 // loginPasskeyRequest defines the request body for passkey login options.
 type loginPasskeyRequest struct {
 	Username string `json:"username"`
@@ -601,9 +800,10 @@ func (h *AuthHandler) LoginPasskeyOptions(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var admin models.Admin
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "password", "active", "passkey_id", "passkey_public_key", "passkey_sign_count", "passkey_backup_eligible", "passkey_backup_state", "permissions", "is_super_admin").
+	if errFind := h.db.WithContext(ctx).
+		Select("id", "username", "active", "sso_only", "permissions", "is_super_admin").
 		Where("username = ?", username).First(&admin).Error; errFind != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
@@ -612,13 +812,22 @@ func (h *AuthHandler) LoginPasskeyOptions(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "admin account is disabled"})
 		return
 	}
-	if len(admin.PasskeyID) == 0 || len(admin.PasskeyPublicKey) == 0 {
+	if admin.SSOOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "sso login required"})
+		return
+	}
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerAdmin, admin.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if len(passkeys) == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey not enabled"})
 		return
 	}
 
-	user := newAdminWebAuthnUser(admin)
-	assertion, session, err := webAuthn.BeginLogin(user, webauthn.WithUserVerification(protocol.VerificationPreferred))
+	webauthnUser := newAdminWebAuthnUser(admin, passkeys)
+	assertion, session, err := webAuthn.BeginLogin(webauthnUser, webauthn.WithUserVerification(protocol.VerificationPreferred))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "begin passkey login failed"})
 		return
@@ -642,9 +851,10 @@ func (h *AuthHandler) LoginPasskeyVerify(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var admin models.Admin
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "active", "passkey_id", "passkey_public_key", "passkey_sign_count", "passkey_backup_eligible", "passkey_backup_state", "permissions", "is_super_admin").
+	if errFind := h.db.WithContext(ctx).
+		Select("id", "username", "active", "sso_only", "permissions", "is_super_admin").
 		Where("username = ?", username).First(&admin).Error; errFind != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
@@ -653,7 +863,16 @@ func (h *AuthHandler) LoginPasskeyVerify(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "admin account is disabled"})
 		return
 	}
-	if len(admin.PasskeyID) == 0 || len(admin.PasskeyPublicKey) == 0 {
+	if admin.SSOOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "sso login required"})
+		return
+	}
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerAdmin, admin.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if len(passkeys) == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey not enabled"})
 		return
 	}
@@ -671,41 +890,18 @@ func (h *AuthHandler) LoginPasskeyVerify(c *gin.Context) {
 	}
 	c.Request.Body = io.NopCloser(bytes.NewReader(rawBody))
 
-	var derivedBackupEligible *bool
-	var derivedBackupState *bool
-	if admin.PasskeyBackupEligible == nil || admin.PasskeyBackupState == nil {
-		parsed, errParse := protocol.ParseCredentialRequestResponseBytes(rawBody)
-		if errParse != nil {
-			log.WithError(errParse).WithField("username", username).Warn("passkey login parse failed")
-		} else {
-			backupEligible := parsed.Response.AuthenticatorData.Flags.HasBackupEligible()
-			backupState := parsed.Response.AuthenticatorData.Flags.HasBackupState()
-			derivedBackupEligible = &backupEligible
-			derivedBackupState = &backupState
-		}
-	}
-
-	user := newAdminWebAuthnUser(admin)
-	if derivedBackupEligible != nil && len(user.credentials) > 0 {
-		user.credentials[0].Flags.BackupEligible = *derivedBackupEligible
-		user.credentials[0].Flags.BackupState = *derivedBackupState
-	}
-	credential, err := webAuthn.FinishLogin(user, session, c.Request)
+	webauthnUser := newAdminWebAuthnUser(admin, passkeys)
+	mfa.FillMissingBackupFlags(passkeys, webauthnUser.credentials, rawBody)
+	credential, err := webAuthn.FinishLogin(webauthnUser, session, c.Request)
 	if err != nil {
 		log.WithError(err).WithField("username", username).Warn("passkey login failed")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "login failed"})
 		return
 	}
 
-	signCount := uint32(credential.Authenticator.SignCount)
-	_ = h.db.WithContext(c.Request.Context()).Model(&models.Admin{}).
-		Where("id = ?", admin.ID).
-		Updates(map[string]any{
-			"passkey_sign_count":      signCount,
-			"passkey_backup_eligible": credential.Flags.BackupEligible,
-			"passkey_backup_state":    credential.Flags.BackupState,
-			"updated_at":              time.Now().UTC(),
-		}).Error
+	if errRecord := mfa.RecordPasskeyLogin(ctx, h.db, models.MFAOwnerAdmin, admin.ID, credential); errRecord != nil {
+		log.WithError(errRecord).WithField("username", username).Warn("passkey login record failed")
+	}
 
 	passkeyLoginSessions.Delete(username)
 	h.respondWithAdminToken(c, admin)
@@ -720,6 +916,9 @@ func (h *AuthHandler) respondWithAdminToken(c *gin.Context, admin models.Admin)
 	}
 
 	adminPermissions := permissions.ParsePermissions(admin.Permissions)
+	if access, errAccess := permissions.ResolveAccess(c.Request.Context(), h.db, admin); errAccess == nil {
+		adminPermissions = access.Permissions
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
 		"admin": gin.H{
@@ -728,11 +927,21 @@ func (h *AuthHandler) respondWithAdminToken(c *gin.Context, admin models.Admin)
 			"permissions":    adminPermissions,
 			"is_super_admin": admin.IsSuperAdmin,
 		},
-		"user_id":        admin.ID,
-		"username":       admin.Username,
-		"name":           "",
-		"email":          "",
-		"permissions":    adminPermissions,
-		"is_super_admin": admin.IsSuperAdmin,
+		"user_id":            admin.ID,
+		"username":           admin.Username,
+		"name":               "",
+		"email":              "",
+		"permissions":        adminPermissions,
+		"is_super_admin":     admin.IsSuperAdmin,
+		"mfa_setup_required": h.mfaSetupRequired(c, admin),
 	})
 }
+
+// mfaSetupRequired reports whether the admin must enroll in MFA before using the console.
+func (h *AuthHandler) mfaSetupRequired(c *gin.Context, admin models.Admin) bool {
+	if !mfa.AdminRequired(admin) {
+		return false
+	}
+	status, errStatus := mfa.LoadStatus(c.Request.Context(), h.db, models.MFAOwnerAdmin, admin.ID, admin.TOTPSecret)
+	return errStatus == nil && !status.Enabled()
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// ModelCatalogHandler manages admin CRUD endpoints for model catalogue entries.
+type ModelCatalogHandler struct {
+	db *gorm.DB // Database handle for catalogue records.
+}
+
+// NewModelCatalogHandler constructs a model catalogue handler.
+func NewModelCatalogHandler(db *gorm.DB) *ModelCatalogHandler {
+	return &ModelCatalogHandler{db: db}
+}
+
+// modelCatalogRequest captures the payload for creating or updating a catalogue entry.
+type modelCatalogRequest struct {
+	Model             *string    `json:"model"`              // Exposed model name.
+	DisplayName       *string    `json:"display_name"`       // Optional display name.
+	Description       *string    `json:"description"`        // Optional description.
+	ContextLength     *int       `json:"context_length"`     // Optional context window in tokens.
+	MaxOutputTokens   *int       `json:"max_output_tokens"`  // Optional output token limit.
+	SupportsVision    *bool      `json:"supports_vision"`    // Optional vision flag.
+	SupportsTools     *bool      `json:"supports_tools"`     // Optional tool calling flag.
+	SupportsReasoning *bool      `json:"supports_reasoning"` // Optional reasoning flag.
+	ReleasedAt        *time.Time `json:"released_at"`        // Optional release date.
+	DeprecatedAt      *time.Time `json:"deprecated_at"`      // Optional deprecation date.
+	SunsetAt          *time.Time `json:"sunset_at"`          // Optional sunset date.
+	SunsetTarget      *string    `json:"sunset_target"`      // Optional replacement model.
+}
+
+// Create validates input and inserts a new catalogue entry.
+func (h *ModelCatalogHandler) Create(c *gin.Context) {
+	var body modelCatalogRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if body.Model == nil || strings.TrimSpace(*body.Model) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model is required"})
+		return
+	}
+
+	now := time.Now().UTC()
+	entry := models.ModelCatalogEntry{CreatedAt: now, UpdatedAt: now}
+	body.apply(&entry)
+	if errValidate := validateCatalogEntry(&entry); errValidate != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidate})
+		return
+	}
+
+	var count int64
+	if errCount := h.db.WithContext(c.Request.Context()).Model(&models.ModelCatalogEntry{}).
+		Where("LOWER(model) = ?", strings.ToLower(entry.Model)).
+		Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "model already catalogued"})
+		return
+	}
+
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&entry).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create catalogue entry failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatCatalogEntry(&entry))
+}
+
+// List returns catalogue entries, optionally filtered by model name.
+func (h *ModelCatalogHandler) List(c *gin.Context) {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.ModelCatalogEntry{})
+	if modelQ := strings.TrimSpace(c.Query("model")); modelQ != "" {
+		q = q.Where("LOWER(model) LIKE ?", "%"+strings.ToLower(modelQ)+"%")
+	}
+	var rows []models.ModelCatalogEntry
+	if errFind := q.Order("model ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list catalogue failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatCatalogEntry(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": out})
+}
+
+// Get fetches a catalogue entry by ID.
+func (h *ModelCatalogHandler) Get(c *gin.Context) {
+	entry, ok := h.loadEntry(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, formatCatalogEntry(&entry))
+}
+
+// Update applies catalogue field updates; omitted fields are left unchanged.
+func (h *ModelCatalogHandler) Update(c *gin.Context) {
+	entry, ok := h.loadEntry(c)
+	if !ok {
+		return
+	}
+	var body modelCatalogRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if body.Model != nil && strings.TrimSpace(*body.Model) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model cannot be empty"})
+		return
+	}
+	body.apply(&entry)
+	if errValidate := validateCatalogEntry(&entry); errValidate != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidate})
+		return
+	}
+
+	var count int64
+	if errCount := h.db.WithContext(c.Request.Context()).Model(&models.ModelCatalogEntry{}).
+		Where("LOWER(model) = ? AND id <> ?", strings.ToLower(entry.Model), entry.ID).
+		Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "model already catalogued"})
+		return
+	}
+
+	entry.UpdatedAt = time.Now().UTC()
+	if errSave := h.db.WithContext(c.Request.Context()).Save(&entry).Error; errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatCatalogEntry(&entry))
+}
+
+// Delete removes a catalogue entry by ID.
+func (h *ModelCatalogHandler) Delete(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).Delete(&models.ModelCatalogEntry{}, id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// loadEntry parses the ID route parameter and loads the catalogue entry.
+func (h *ModelCatalogHandler) loadEntry(c *gin.Context) (models.ModelCatalogEntry, bool) {
+	var entry models.ModelCatalogEntry
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return entry, false
+	}
+	if errFind := h.db.WithContext(c.Request.Context()).First(&entry, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return entry, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return entry, false
+	}
+	return entry, true
+}
+
+// apply copies the provided request fields onto entry.
+func (r *modelCatalogRequest) apply(entry *models.ModelCatalogEntry) {
+	if r.Model != nil {
+		entry.Model = strings.TrimSpace(*r.Model)
+	}
+	if r.DisplayName != nil {
+		entry.DisplayName = strings.TrimSpace(*r.DisplayName)
+	}
+	if r.Description != nil {
+		entry.Description = strings.TrimSpace(*r.Description)
+	}
+	if r.ContextLength != nil {
+		entry.ContextLength = *r.ContextLength
+	}
+	if r.MaxOutputTokens != nil {
+		entry.MaxOutputTokens = *r.MaxOutputTokens
+	}
+	if r.SupportsVision != nil {
+		entry.SupportsVision = *r.SupportsVision
+	}
+	if r.SupportsTools != nil {
+		entry.SupportsTools = *r.SupportsTools
+	}
+	if r.SupportsReasoning != nil {
+		entry.SupportsReasoning = *r.SupportsReasoning
+	}
+	if r.ReleasedAt != nil {
+		entry.ReleasedAt = normalizeCatalogTime(r.ReleasedAt)
+	}
+	if r.DeprecatedAt != nil {
+		entry.DeprecatedAt = normalizeCatalogTime(r.DeprecatedAt)
+	}
+	if r.SunsetAt != nil {
+		entry.SunsetAt = normalizeCatalogTime(r.SunsetAt)
+	}
+	if r.SunsetTarget != nil {
+		entry.SunsetTarget = strings.TrimSpace(*r.SunsetTarget)
+	}
+}
+
+// normalizeCatalogTime converts a date to UTC and maps the zero time to nil so clients can clear it.
+func normalizeCatalogTime(t *time.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// validateCatalogEntry returns an error message when entry is inconsistent.
+func validateCatalogEntry(entry *models.ModelCatalogEntry) string {
+	if entry.ContextLength < 0 {
+		return "context_length must be non-negative"
+	}
+	if entry.MaxOutputTokens < 0 {
+		return "max_output_tokens must be non-negative"
+	}
+	if entry.SunsetTarget != "" && strings.EqualFold(entry.SunsetTarget, entry.Model) {
+		return "sunset_target must differ from model"
+	}
+	if entry.DeprecatedAt != nil && entry.SunsetAt != nil && entry.SunsetAt.Before(*entry.DeprecatedAt) {
+		return "sunset_at must not be before deprecated_at"
+	}
+	return ""
+}
+
+// formatCatalogEntry converts a catalogue entry into a response payload.
+func formatCatalogEntry(entry *models.ModelCatalogEntry) gin.H {
+	return gin.H{
+		"id":                 entry.ID,
+		"model":              entry.Model,
+		"display_name":       entry.DisplayName,
+		"description":        entry.Description,
+		"context_length":     entry.ContextLength,
+		"max_output_tokens":  entry.MaxOutputTokens,
+		"supports_vision":    entry.SupportsVision,
+		"supports_tools":     entry.SupportsTools,
+		"supports_reasoning": entry.SupportsReasoning,
+		"released_at":        entry.ReleasedAt,
+		"deprecated_at":      entry.DeprecatedAt,
+		"sunset_at":          entry.SunsetAt,
+		"sunset_target":      entry.SunsetTarget,
+		"created_at":         entry.CreatedAt,
+		"updated_at":         entry.UpdatedAt,
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelbundle"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxBundleImportBytes caps the size of an imported bundle.
+const maxBundleImportBytes = 16 << 20
+
+// Export downloads mappings, payload rules, and billing rules as a JSON or YAML bundle.
+func (h *ModelMappingHandler) Export(c *gin.Context) {
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+	if format == "" {
+		format = modelbundle.FormatJSON
+	}
+	if format != modelbundle.FormatJSON && format != modelbundle.FormatYAML {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+	now := time.Now().UTC()
+	bundle, errExport := modelbundle.Export(c.Request.Context(), h.db, modelbundle.Filter{
+		Provider: c.Query("provider"),
+		Model:    c.Query("model"),
+	}, now)
+	if errExport != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export model mappings failed"})
+		return
+	}
+	data, errEncode := modelbundle.Encode(bundle, format)
+	if errEncode != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export model mappings failed"})
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if format == modelbundle.FormatYAML {
+		contentType = "application/yaml; charset=utf-8"
+	}
+	filename := fmt.Sprintf("model-mappings-%s.%s", now.Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// Import applies a bundle with the requested conflict strategy, or previews it with dry_run.
+func (h *ModelMappingHandler) Import(c *gin.Context) {
+	strategy, errStrategy := modelbundle.ParseStrategy(c.Query("strategy"))
+	if errStrategy != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "strategy must be skip, overwrite, or merge"})
+		return
+	}
+	dryRunQ := strings.TrimSpace(c.Query("dry_run"))
+	dryRun := dryRunQ == "1" || strings.EqualFold(dryRunQ, "true")
+
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+	if format == "" && strings.Contains(strings.ToLower(c.ContentType()), "yaml") {
+		format = modelbundle.FormatYAML
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleImportBytes)
+	data, errRead := c.GetRawData()
+	if errRead != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "read bundle failed"})
+		return
+	}
+	bundle, errDecode := modelbundle.Decode(data, format)
+	if errDecode != nil {
+		if errors.Is(errDecode, modelbundle.ErrUnsupportedVersion) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported bundle version"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bundle"})
+		return
+	}
+
+	report, errImport := modelbundle.Import(c.Request.Context(), h.db, bundle, strategy, dryRun, time.Now().UTC())
+	if errImport != nil {
+		if errors.Is(errImport, modelbundle.ErrInvalidBundle) && report != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "bundle validation failed", "report": report})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "import model mappings failed"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// bulkModelMappingRequest selects mappings by filter and the action applied to them.
+type bulkModelMappingRequest struct {
+	IDs          []uint64            `json:"ids"`            // Optional explicit mapping IDs.
+	Provider     string              `json:"provider"`       // Optional provider filter.
+	ModelName    string              `json:"model_name"`     // Optional source model filter.
+	NewModelName string              `json:"new_model_name"` // Optional exposed model filter.
+	UserGroupID  *uint64             `json:"user_group_id"`  // Optional filter on mappings containing a user group.
+	IsEnabled    *bool               `json:"is_enabled"`     // Optional enabled-state filter.
+	Action       string              `json:"action"`         // enable, disable, or regroup.
+	Mode         string              `json:"mode"`           // Regroup mode: set, add, or remove.
+	UserGroups   models.UserGroupIDs `json:"user_groups"`    // Regroup target group IDs.
+}
+
+// Bulk enables, disables, or regroups every mapping matching a filter.
+func (h *ModelMappingHandler) Bulk(c *gin.Context) {
+	var body bulkModelMappingRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	action := strings.ToLower(strings.TrimSpace(body.Action))
+	if action != "enable" && action != "disable" && action != "regroup" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be enable, disable, or regroup"})
+		return
+	}
+	mode := strings.ToLower(strings.TrimSpace(body.Mode))
+	if action == "regroup" {
+		if mode == "" {
+			mode = "set"
+		}
+		if mode != "set" && mode != "add" && mode != "remove" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be set, add, or remove"})
+			return
+		}
+	}
+	provider := strings.TrimSpace(body.Provider)
+	modelName := strings.TrimSpace(body.ModelName)
+	newModelName := strings.TrimSpace(body.NewModelName)
+	if len(body.IDs) == 0 && provider == "" && modelName == "" && newModelName == "" && body.UserGroupID == nil && body.IsEnabled == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one filter is required"})
+		return
+	}
+
+	q := h.db.WithContext(c.Request.Context()).Model(&models.ModelMapping{})
+	if len(body.IDs) > 0 {
+		q = q.Where("id IN ?", body.IDs)
+	}
+	if provider != "" {
+		q = q.Where("LOWER(provider) = ?", strings.ToLower(provider))
+	}
+	if modelName != "" {
+		q = q.Where("model_name = ?", modelName)
+	}
+	if newModelName != "" {
+		q = q.Where("new_model_name = ?", newModelName)
+	}
+	if body.IsEnabled != nil {
+		q = q.Where("is_enabled = ?", *body.IsEnabled)
+	}
+	var rows []models.ModelMapping
+	if errFind := q.Order("id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if body.UserGroupID != nil {
+		filtered := rows[:0]
+		for _, row := range rows {
+			for _, id := range row.UserGroupID.Values() {
+				if id == *body.UserGroupID {
+					filtered = append(filtered, row)
+					break
+				}
+			}
+		}
+		rows = filtered
+	}
+
+	now := time.Now().UTC()
+	targetGroups := body.UserGroups.Clean()
+	updated := 0
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			updates := map[string]any{"updated_at": now}
+			switch action {
+			case "enable", "disable":
+				enabled := action == "enable"
+				if row.IsEnabled == enabled {
+					continue
+				}
+				updates["is_enabled"] = enabled
+			case "regroup":
+				next := regroupUserGroups(row.UserGroupID.Clean(), targetGroups, mode)
+				if sameUserGroups(row.UserGroupID.Clean(), next) {
+					continue
+				}
+				updates["user_group_id"] = next
+			}
+			if errUpdate := tx.Model(&models.ModelMapping{}).Where("id = ?", row.ID).Updates(updates).Error; errUpdate != nil {
+				return errUpdate
+			}
+			updated++
+		}
+		return nil
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"matched": len(rows), "updated": updated})
+}
+
+// regroupUserGroups applies a regroup mode to a mapping's user groups.
+func regroupUserGroups(current, target models.UserGroupIDs, mode string) models.UserGroupIDs {
+	switch mode {
+	case "add":
+		merged := make(models.UserGroupIDs, 0, len(current)+len(target))
+		merged = append(merged, current...)
+		merged = append(merged, target...)
+		return merged.Clean()
+	case "remove":
+		drop := make(map[uint64]struct{}, len(target))
+		for _, id := range target.Values() {
+			drop[id] = struct{}{}
+		}
+		kept := make(models.UserGroupIDs, 0, len(current))
+		for _, id := range current {
+			if _, ok := drop[*id]; !ok {
+				kept = append(kept, id)
+			}
+		}
+		return kept.Clean()
+	default:
+		return target.Clean()
+	}
+}
+
+// sameUserGroups reports whether two cleaned group lists are identical in order.
+func sameUserGroups(a, b models.UserGroupIDs) bool {
+	av, bv := a.Values(), b.Values()
+	if len(av) != len(bv) {
+		return false
+	}
+	for i := range av {
+		if av[i] != bv[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/sso"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// providerSlugPattern restricts provider slugs to URL-safe identifiers.
+var providerSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// maskedClientSecret replaces client secrets in responses.
+const maskedClientSecret = "******"
+
+// OIDCProviderHandler manages OpenID Connect provider registrations.
+type OIDCProviderHandler struct {
+	db *gorm.DB // Database handle for provider queries.
+}
+
+// NewOIDCProviderHandler constructs an OIDCProviderHandler.
+func NewOIDCProviderHandler(db *gorm.DB) *OIDCProviderHandler {
+	return &OIDCProviderHandler{db: db}
+}
+
+// oidcProviderRequest captures create and update payloads; nil fields are left unchanged on update.
+type oidcProviderRequest struct {
+	Slug          *string         `json:"slug"`           // URL-safe identifier.
+	DisplayName   *string         `json:"display_name"`   // Label on login pages.
+	IssuerURL     *string         `json:"issuer_url"`     // Issuer URL.
+	ClientID      *string         `json:"client_id"`      // OAuth2 client ID.
+	ClientSecret  *string         `json:"client_secret"`  // OAuth2 client secret.
+	Scopes        *string         `json:"scopes"`         // Space separated scopes.
+	ForUsers      *bool           `json:"for_users"`      // Offer to end users.
+	ForAdmins     *bool           `json:"for_admins"`     // Offer to administrators.
+	AutoProvision *bool           `json:"auto_provision"` // Create users on first login.
+	LinkByEmail   *bool           `json:"link_by_email"`  // Link users by verified email.
+	GroupsClaim   *string         `json:"groups_claim"`   // Claim holding group names.
+	GroupMapping  json.RawMessage `json:"group_mapping"`  // Claim value to user group IDs.
+	IsEnabled     *bool           `json:"is_enabled"`     // Accept logins.
+}
+
+// apply validates the request and copies set fields into updates.
+func (req *oidcProviderRequest) apply(updates map[string]any) error {
+	if req.Slug != nil {
+		slug := strings.ToLower(strings.TrimSpace(*req.Slug))
+		if !providerSlugPattern.MatchString(slug) {
+			return errors.New("slug must be lowercase letters, digits or dashes")
+		}
+		updates["slug"] = slug
+	}
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if name == "" {
+			return errors.New("display_name cannot be empty")
+		}
+		updates["display_name"] = name
+	}
+	if req.IssuerURL != nil {
+		issuer := strings.TrimRight(strings.TrimSpace(*req.IssuerURL), "/")
+		parsed, errParse := url.Parse(issuer)
+		if errParse != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return errors.New("issuer_url must be an absolute http(s) URL")
+		}
+		updates["issuer_url"] = issuer
+	}
+	if req.ClientID != nil {
+		clientID := strings.TrimSpace(*req.ClientID)
+		if clientID == "" {
+			return errors.New("client_id cannot be empty")
+		}
+		updates["client_id"] = clientID
+	}
+	if req.ClientSecret != nil && *req.ClientSecret != maskedClientSecret {
+		updates["client_secret"] = strings.TrimSpace(*req.ClientSecret)
+	}
+	if req.Scopes != nil {
+		updates["scopes"] = strings.Join(strings.Fields(*req.Scopes), " ")
+	}
+	if req.ForUsers != nil {
+		updates["for_users"] = *req.ForUsers
+	}
+	if req.ForAdmins != nil {
+		updates["for_admins"] = *req.ForAdmins
+	}
+	if req.AutoProvision != nil {
+		updates["auto_provision"] = *req.AutoProvision
+	}
+	if req.LinkByEmail != nil {
+		updates["link_by_email"] = *req.LinkByEmail
+	}
+	if req.GroupsClaim != nil {
+		updates["groups_claim"] = strings.TrimSpace(*req.GroupsClaim)
+	}
+	if len(req.GroupMapping) > 0 && string(req.GroupMapping) != "null" {
+		if _, errMapping := sso.ParseGroupMapping(req.GroupMapping); errMapping != nil {
+			return errors.New("group_mapping must map claim values to user group IDs")
+		}
+		updates["group_mapping"] = datatypes.JSON(req.GroupMapping)
+	}
+	if req.IsEnabled != nil {
+		updates["is_enabled"] = *req.IsEnabled
+	}
+	return nil
+}
+
+// Create registers a new OIDC provider.
+func (h *OIDCProviderHandler) Create(c *gin.Context) {
+	var body oidcProviderRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if body.Slug == nil || body.IssuerURL == nil || body.ClientID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug, issuer_url and client_id are required"})
+		return
+	}
+	fields := map[string]any{}
+	if errApply := body.apply(fields); errApply != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errApply.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	provider := models.OIDCProvider{
+		GroupMapping: datatypes.JSON("{}"),
+		IsEnabled:    true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if errDecode := decodeProviderFields(&provider, fields); errDecode != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errDecode.Error()})
+		return
+	}
+	if provider.DisplayName == "" {
+		provider.DisplayName = provider.Slug
+	}
+
+	var existing int64
+	if errCount := h.db.WithContext(c.Request.Context()).Model(&models.OIDCProvider{}).
+		Where("slug = ?", provider.Slug).Count(&existing).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "slug already exists"})
+		return
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&provider).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create provider failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatOIDCProvider(&provider))
+}
+
+// List returns all OIDC providers.
+func (h *OIDCProviderHandler) List(c *gin.Context) {
+	var rows []models.OIDCProvider
+	if errFind := h.db.WithContext(c.Request.Context()).Order("id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list providers failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatOIDCProvider(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": out})
+}
+
+// Get returns a single OIDC provider.
+func (h *OIDCProviderHandler) Get(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var provider models.OIDCProvider
+	if errFind := h.db.WithContext(c.Request.Context()).First(&provider, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatOIDCProvider(&provider))
+}
+
+// Update modifies an OIDC provider.
+func (h *OIDCProviderHandler) Update(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body oidcProviderRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	updates := map[string]any{}
+	if errApply := body.apply(updates); errApply != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errApply.Error()})
+		return
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+	if slug, ok := updates["slug"]; ok {
+		var existing int64
+		if errCount := h.db.WithContext(c.Request.Context()).Model(&models.OIDCProvider{}).
+			Where("slug = ? AND id <> ?", slug, id).Count(&existing).Error; errCount != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		if existing > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "slug already exists"})
+			return
+		}
+	}
+	updates["updated_at"] = time.Now().UTC()
+
+	res := h.db.WithContext(c.Request.Context()).Model(&models.OIDCProvider{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Delete removes an OIDC provider along with its identity links.
+func (h *OIDCProviderHandler) Delete(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var deleted int64
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if errIdentities := tx.Where("provider_id = ?", id).Delete(&models.SSOIdentity{}).Error; errIdentities != nil {
+			return errIdentities
+		}
+		if errStates := tx.Where("provider_id = ?", id).Delete(&models.SSOLoginState{}).Error; errStates != nil {
+			return errStates
+		}
+		res := tx.Delete(&models.OIDCProvider{}, id)
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// decodeProviderFields copies validated update fields onto a new provider.
+func decodeProviderFields(provider *models.OIDCProvider, fields map[string]any) error {
+	for key, value := range fields {
+		switch key {
+		case "slug":
+			provider.Slug = value.(string)
+		case "display_name":
+			provider.DisplayName = value.(string)
+		case "issuer_url":
+			provider.IssuerURL = value.(string)
+		case "client_id":
+			provider.ClientID = value.(string)
+		case "client_secret":
+			provider.ClientSecret = value.(string)
+		case "scopes":
+			provider.Scopes = value.(string)
+		case "for_users":
+			provider.ForUsers = value.(bool)
+		case "for_admins":
+			provider.ForAdmins = value.(bool)
+		case "auto_provision":
+			provider.AutoProvision = value.(bool)
+		case "link_by_email":
+			provider.LinkByEmail = value.(bool)
+		case "groups_claim":
+			provider.GroupsClaim = value.(string)
+		case "group_mapping":
+			provider.GroupMapping = value.(datatypes.JSON)
+		case "is_enabled":
+			provider.IsEnabled = value.(bool)
+		default:
+			return errors.New("unknown field " + key)
+		}
+	}
+	return nil
+}
+
+// formatOIDCProvider renders a provider with its client secret masked.
+func formatOIDCProvider(provider *models.OIDCProvider) gin.H {
+	secret := ""
+	if provider.ClientSecret != "" {
+		secret = maskedClientSecret
+	}
+	mapping, _ := sso.ParseGroupMapping(provider.GroupMapping)
+	return gin.H{
+		"id":             provider.ID,
+		"slug":           provider.Slug,
+		"display_name":   provider.DisplayName,
+		"issuer_url":     provider.IssuerURL,
+		"client_id":      provider.ClientID,
+		"client_secret":  secret,
+		"scopes":         provider.Scopes,
+		"for_users":      provider.ForUsers,
+		"for_admins":     provider.ForAdmins,
+		"auto_provision": provider.AutoProvision,
+		"link_by_email":  provider.LinkByEmail,
+		"groups_claim":   provider.GroupsClaim,
+		"group_mapping":  mapping,
+		"is_enabled":     provider.IsEnabled,
+		"created_at":     provider.CreatedAt,
+		"updated_at":     provider.UpdatedAt,
+	}
+}
@@ -15,7 +15,7 @@ func NewPermissionHandler() *PermissionHandler {
 	return &PermissionHandler{}
 }
 
-// List returns all permission definitions.
+// List returns all permission definitions and the grantable modules.
 func (h *PermissionHandler) List(c *gin.Context) {
 	defs := permissions.Definitions()
 	out := make([]gin.H, 0, len(defs))
@@ -28,5 +28,5 @@ func (h *PermissionHandler) List(c *gin.Context) {
 			"module": def.Module,
 		})
 	}
-	c.JSON(http.StatusOK, gin.H{"permissions": out})
+	c.JSON(http.StatusOK, gin.H{"permissions": out, "modules": permissions.Modules()})
 }
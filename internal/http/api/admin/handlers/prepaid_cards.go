@@ -35,6 +35,27 @@ type createPrepaidCardRequest struct {
 	IsEnabled   *bool   `json:"is_enabled"`    // Optional active flag.
 }
 
+// scopedCards returns a card query limited to cards redeemed by users the admin may manage
+// and unredeemed cards restricted to the admin's user groups.
+func (h *PrepaidCardHandler) scopedCards(c *gin.Context) *gorm.DB {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.PrepaidCard{})
+	scope := readAdminUserGroupScope(c)
+	if len(scope) == 0 {
+		return q
+	}
+	return q.Where("prepaid_cards.redeemed_user_id IN (?) OR (prepaid_cards.redeemed_user_id IS NULL AND prepaid_cards.user_group_id IN ?)",
+		scopedUserIDs(h.db, scope), scope)
+}
+
+// cardGroupInScope reports whether the admin may manage cards restricted to the user group.
+func cardGroupInScope(c *gin.Context, userGroupID *uint64) bool {
+	var groups []uint64
+	if userGroupID != nil && *userGroupID != 0 {
+		groups = []uint64{*userGroupID}
+	}
+	return userGroupsInScope(groups, readAdminUserGroupScope(c))
+}
+
 // Create validates input and persists a new prepaid card with initial balance.
 func (h *PrepaidCardHandler) Create(c *gin.Context) {
 	var body createPrepaidCardRequest
@@ -69,6 +90,10 @@ func (h *PrepaidCardHandler) Create(c *gin.Context) {
 		}
 		validDays = *body.ValidDays
 	}
+	if !cardGroupInScope(c, body.UserGroupID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user group out of scope"})
+		return
+	}
 
 	now := time.Now().UTC()
 	isEnabled := true
@@ -149,6 +174,11 @@ func (h *PrepaidCardHandler) BatchCreate(c *gin.Context) {
 		validDays = *body.ValidDays
 	}
 
+	if !cardGroupInScope(c, body.UserGroupID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user group out of scope"})
+		return
+	}
+
 	prefix := strings.TrimSpace(body.CardSNPrefix)
 	isEnabled := true
 	if body.IsEnabled != nil {
@@ -205,9 +235,7 @@ func (h *PrepaidCardHandler) List(c *gin.Context) {
 		redeemedUserQ = strings.TrimSpace(c.Query("redeemed_user"))
 	)
 
-	q := h.db.WithContext(c.Request.Context()).
-		Model(&models.PrepaidCard{}).
-		Preload("RedeemedUser")
+	q := h.scopedCards(c).Preload("RedeemedUser")
 	if nameQ != "" {
 		pattern := dbutil.NormalizeLikePattern(h.db, "%"+nameQ+"%")
 		q = q.Where(dbutil.CaseInsensitiveLikeExpr(h.db, "name"), pattern)
@@ -247,7 +275,7 @@ func (h *PrepaidCardHandler) Get(c *gin.Context) {
 		return
 	}
 	var card models.PrepaidCard
-	if errFind := h.db.WithContext(c.Request.Context()).
+	if errFind := h.scopedCards(c).
 		Preload("RedeemedUser").
 		First(&card, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
@@ -279,7 +307,7 @@ func (h *PrepaidCardHandler) Update(c *gin.Context) {
 		return
 	}
 	var card models.PrepaidCard
-	if errFind := h.db.WithContext(c.Request.Context()).First(&card, id).Error; errFind != nil {
+	if errFind := h.scopedCards(c).First(&card, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -326,6 +354,10 @@ func (h *PrepaidCardHandler) Update(c *gin.Context) {
 		updates["amount"] = *body.Amount
 	}
 	if body.UserGroupID != nil {
+		if !cardGroupInScope(c, body.UserGroupID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "user group out of scope"})
+			return
+		}
 		if *body.UserGroupID == 0 {
 			updates["user_group_id"] = nil
 		} else {
@@ -370,7 +402,7 @@ func (h *PrepaidCardHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Delete(&models.PrepaidCard{}, id)
+	res := h.scopedCards(c).Delete(&models.PrepaidCard{}, id)
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+// History returns quota snapshots of one auth, newest first, optionally narrowed to one window.
+func (h *QuotaHandler) History(c *gin.Context) {
+	var (
+		authKey   = strings.TrimSpace(c.Query("auth_key"))
+		authIDStr = strings.TrimSpace(c.Query("auth_id"))
+		windowKey = strings.TrimSpace(c.Query("window"))
+		fromStr   = strings.TrimSpace(c.Query("from"))
+		toStr     = strings.TrimSpace(c.Query("to"))
+		limitStr  = strings.TrimSpace(c.Query("limit"))
+	)
+	if authKey == "" && authIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auth_key or auth_id is required"})
+		return
+	}
+
+	limit := 500
+	if limitStr != "" {
+		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
+			if v > 5000 {
+				v = 5000
+			}
+			limit = v
+		}
+	}
+
+	q := h.db.WithContext(c.Request.Context()).Model(&models.QuotaSnapshot{})
+	if authKey != "" {
+		q = q.Where("auth_key = ?", authKey)
+	}
+	if authIDStr != "" {
+		authID, errParse := strconv.ParseUint(authIDStr, 10, 64)
+		if errParse != nil || authID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid auth_id"})
+			return
+		}
+		q = q.Where("auth_id = ?", authID)
+	}
+	if fromStr != "" {
+		if t, err := time.Parse(time.RFC3339, fromStr); err == nil {
+			q = q.Where("created_at >= ?", t.UTC())
+		}
+	}
+	if toStr != "" {
+		if t, err := time.Parse(time.RFC3339, toStr); err == nil {
+			q = q.Where("created_at <= ?", t.UTC())
+		}
+	}
+
+	var rows []models.QuotaSnapshot
+	if errFind := q.Order("created_at DESC, id DESC").Limit(limit).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		var windows []quota.Window
+		if errUnmarshal := json.Unmarshal(row.Windows, &windows); errUnmarshal != nil {
+			continue
+		}
+		if windowKey != "" {
+			filtered := windows[:0]
+			for _, window := range windows {
+				if window.Key() == windowKey {
+					filtered = append(filtered, window)
+				}
+			}
+			if len(filtered) == 0 {
+				continue
+			}
+			windows = filtered
+		}
+		out = append(out, gin.H{
+			"auth_id":    row.AuthID,
+			"auth_key":   row.AuthKey,
+			"type":       row.Type,
+			"windows":    windows,
+			"created_at": row.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"history": out})
+}
+
+// Forecast returns burn rates and forecast exhaustion per auth and per auth group.
+func (h *QuotaHandler) Forecast(c *gin.Context) {
+	now := time.Now().UTC()
+	forecasts, errLoad := quota.LoadForecasts(c.Request.Context(), h.db, now)
+	if errLoad != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load forecasts failed"})
+		return
+	}
+
+	var groupID uint64
+	if groupQ := strings.TrimSpace(c.Query("auth_group_id")); groupQ != "" {
+		parsed, errParse := strconv.ParseUint(groupQ, 10, 64)
+		if errParse != nil || parsed == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid auth_group_id"})
+			return
+		}
+		groupID = parsed
+	}
+	atRisk := strings.EqualFold(strings.TrimSpace(c.Query("at_risk")), "true") || c.Query("at_risk") == "1"
+	policy := internalsettings.LoadQuotaHistoryPolicy()
+
+	auths := make([]quota.Forecast, 0)
+	groups := make([]quota.Forecast, 0)
+	for _, forecast := range forecasts {
+		if groupID != 0 && forecast.AuthGroupID != groupID {
+			continue
+		}
+		if atRisk && (forecast.ExhaustsAt == nil || !forecast.ExhaustsBeforeReset || forecast.ExhaustsAt.Sub(now) > policy.AlertHorizon) {
+			continue
+		}
+		if forecast.Scope == quota.ScopeAuthGroup {
+			groups = append(groups, forecast)
+			continue
+		}
+		auths = append(auths, forecast)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"generated_at":    now,
+		"horizon_minutes": int(policy.AlertHorizon / time.Minute),
+		"auths":           auths,
+		"auth_groups":     groups,
+	})
+}
@@ -10,6 +10,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
@@ -24,6 +26,9 @@ func NewQuotaHandler(db *gorm.DB) *QuotaHandler {
 	return &QuotaHandler{db: db}
 }
 
+// quotaAuthKeyExpr resolves the auth key of a quota row, including auths defined only in config.
+const quotaAuthKeyExpr = "COALESCE(auths.key, quota.auth_key)"
+
 // quotaListQuery defines filters for the quota list view.
 type quotaListQuery struct {
 	Page  int    `form:"page,default=1"`   // Page number.
@@ -39,6 +44,7 @@ type quotaListRow struct {
 	AuthID    uint64         `gorm:"column:auth_id"`
 	Type      string         `gorm:"column:type"`
 	Data      datatypes.JSON `gorm:"column:data"`
+	Windows   datatypes.JSON `gorm:"column:windows"`
 	UpdatedAt time.Time      `gorm:"column:updated_at"`
 	AuthKey   string         `gorm:"column:auth_key"`
 }
@@ -74,10 +80,11 @@ func (h *QuotaHandler) List(c *gin.Context) {
 
 	base := h.db.WithContext(ctx).
 		Table("quota").
-		Joins("JOIN auths ON auths.id = quota.auth_id")
+		Joins("LEFT JOIN auths ON auths.id = quota.auth_id").
+		Where("auths.id IS NOT NULL OR quota.auth_id = 0")
 	if keyQ != "" {
 		pattern := dbutil.NormalizeLikePattern(h.db, "%"+keyQ+"%")
-		base = base.Where(dbutil.CaseInsensitiveLikeExpr(h.db, "auths.key"), pattern)
+		base = base.Where(dbutil.CaseInsensitiveLikeExpr(h.db, quotaAuthKeyExpr), pattern)
 	}
 	if typeQ != "" {
 		base = base.Where("quota.type = ?", typeQ)
@@ -94,10 +101,11 @@ func (h *QuotaHandler) List(c *gin.Context) {
 
 	typeQuery := h.db.WithContext(ctx).
 		Table("quota").
-		Joins("JOIN auths ON auths.id = quota.auth_id")
+		Joins("LEFT JOIN auths ON auths.id = quota.auth_id").
+		Where("auths.id IS NOT NULL OR quota.auth_id = 0")
 	if keyQ != "" {
 		pattern := dbutil.NormalizeLikePattern(h.db, "%"+keyQ+"%")
-		typeQuery = typeQuery.Where(dbutil.CaseInsensitiveLikeExpr(h.db, "auths.key"), pattern)
+		typeQuery = typeQuery.Where(dbutil.CaseInsensitiveLikeExpr(h.db, quotaAuthKeyExpr), pattern)
 	}
 	if groupID > 0 {
 		typeQuery = typeQuery.Where(dbutil.JSONArrayContainsExpr(h.db, "auths.auth_group_id"), dbutil.JSONArrayContainsValue(h.db, groupID))
@@ -111,8 +119,8 @@ func (h *QuotaHandler) List(c *gin.Context) {
 	offset := (q.Page - 1) * q.Limit
 	var rows []quotaListRow
 	if errFind := base.
-		Select("quota.id, quota.auth_id, quota.type, quota.data, quota.updated_at, auths.key AS auth_key").
-		Order("auths.id ASC, quota.updated_at DESC").
+		Select("quota.id, quota.auth_id, quota.type, quota.data, quota.windows, quota.updated_at, " + quotaAuthKeyExpr + " AS auth_key").
+		Order("quota.auth_id ASC, auth_key ASC, quota.updated_at DESC").
 		Offset(offset).
 		Limit(q.Limit).
 		Scan(&rows).Error; errFind != nil {
@@ -132,6 +140,7 @@ func (h *QuotaHandler) List(c *gin.Context) {
 			"auth_key":   row.AuthKey,
 			"type":       row.Type,
 			"data":       payload,
+			"windows":    row.Windows,
 			"updated_at": row.UpdatedAt,
 		})
 	}
@@ -145,6 +154,38 @@ func (h *QuotaHandler) List(c *gin.Context) {
 	})
 }
 
+// Capacity sums the remaining quota of every auth per window, and per auth type for one model
+// when the model query parameter is set, to forecast when capacity runs out.
+func (h *QuotaHandler) Capacity(c *gin.Context) {
+	rows, errLoad := quota.LoadAuthWindows(c.Request.Context(), h.db)
+	if errLoad != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load quotas failed"})
+		return
+	}
+	if typeQ := strings.TrimSpace(c.Query("type")); typeQ != "" {
+		filtered := rows[:0]
+		for _, row := range rows {
+			if row.Type == typeQ {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	policy := internalsettings.LoadQuotaSchedulingPolicy()
+	now := time.Now().UTC()
+	out := gin.H{
+		"mode":      policy.Mode,
+		"threshold": policy.Threshold,
+		"windows":   quota.SummarizeWindows(rows, policy.Threshold, now),
+	}
+	if model := strings.TrimSpace(c.Query("model")); model != "" {
+		out["model"] = model
+		out["capacity"] = quota.SummarizeModel(rows, model, policy.Threshold, now)
+	}
+	c.JSON(http.StatusOK, out)
+}
+
 func isAntigravityType(value string) bool {
 	return strings.Contains(strings.ToLower(strings.TrimSpace(value)), "antigravity")
 }
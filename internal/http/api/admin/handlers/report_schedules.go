@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/reports"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ReportScheduleHandler manages scheduled report endpoints.
+type ReportScheduleHandler struct {
+	db          *gorm.DB
+	authManager *coreauth.Manager
+}
+
+// NewReportScheduleHandler constructs a ReportScheduleHandler.
+func NewReportScheduleHandler(db *gorm.DB) *ReportScheduleHandler {
+	return &ReportScheduleHandler{db: db}
+}
+
+// WithAuthManager lets reports include runtime credential errors in the auth health section.
+// It returns the handler for chaining.
+func (h *ReportScheduleHandler) WithAuthManager(manager *coreauth.Manager) *ReportScheduleHandler {
+	h.authManager = manager
+	return h
+}
+
+// reportScheduleRequest defines the request body for creating and updating report schedules.
+type reportScheduleRequest struct {
+	Name      *string   `json:"name"`
+	Frequency *string   `json:"frequency"`
+	Sections  *[]string `json:"sections"`
+	Format    *string   `json:"format"`
+	Channel   *string   `json:"channel"`
+	Emails    *[]string `json:"emails"`
+	Webhook   *string   `json:"webhook"`
+	Enabled   *bool     `json:"enabled"`
+}
+
+// apply copies the provided fields onto the schedule and validates the result.
+func (r reportScheduleRequest) apply(schedule *models.ReportSchedule) error {
+	if r.Name != nil {
+		schedule.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.Frequency != nil {
+		schedule.Frequency = strings.ToLower(strings.TrimSpace(*r.Frequency))
+	}
+	if r.Format != nil {
+		schedule.Format = strings.ToLower(strings.TrimSpace(*r.Format))
+	}
+	if r.Channel != nil {
+		schedule.Channel = strings.ToLower(strings.TrimSpace(*r.Channel))
+	}
+	if r.Webhook != nil {
+		schedule.Webhook = strings.TrimSpace(*r.Webhook)
+	}
+	if r.Enabled != nil {
+		schedule.Enabled = *r.Enabled
+	}
+	if r.Sections != nil {
+		sections, errSections := reports.NormalizeSections(*r.Sections)
+		if errSections != nil {
+			return errSections
+		}
+		if len(sections) == len(reports.AllSections) {
+			sections = []string{}
+		}
+		raw, _ := json.Marshal(sections)
+		schedule.Sections = datatypes.JSON(raw)
+	}
+	if r.Emails != nil {
+		emails := make([]string, 0, len(*r.Emails))
+		for _, email := range *r.Emails {
+			if email = strings.TrimSpace(email); email != "" {
+				emails = append(emails, email)
+			}
+		}
+		raw, _ := json.Marshal(emails)
+		schedule.Emails = datatypes.JSON(raw)
+	}
+
+	if schedule.Name == "" {
+		return errors.New("missing name")
+	}
+	if !reports.ValidFrequency(schedule.Frequency) {
+		return errors.New("invalid frequency")
+	}
+	if schedule.Format != reports.FormatHTML && schedule.Format != reports.FormatCSV {
+		return errors.New("invalid format")
+	}
+	switch schedule.Channel {
+	case reports.ChannelEmail:
+		var emails []string
+		_ = json.Unmarshal(schedule.Emails, &emails)
+		if len(emails) == 0 {
+			return errors.New("missing emails")
+		}
+	case reports.ChannelWebhook:
+		parsed, errParse := url.Parse(schedule.Webhook)
+		if errParse != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New("invalid webhook")
+		}
+	default:
+		return errors.New("invalid channel")
+	}
+	return nil
+}
+
+// Create creates a report schedule, first due at the end of the current period.
+func (h *ReportScheduleHandler) Create(c *gin.Context) {
+	var body reportScheduleRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	schedule := models.ReportSchedule{
+		Format:   reports.FormatHTML,
+		Channel:  reports.ChannelEmail,
+		Sections: datatypes.JSON("[]"),
+		Emails:   datatypes.JSON("[]"),
+		Enabled:  true,
+	}
+	if errApply := body.apply(&schedule); errApply != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errApply.Error()})
+		return
+	}
+	now := time.Now()
+	schedule.NextRunAt = reports.NextRun(schedule.Frequency, now).UTC()
+	schedule.CreatedAt = now.UTC()
+	schedule.UpdatedAt = now.UTC()
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&schedule).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create report schedule failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, formatReportSchedule(&schedule))
+}
+
+// List returns every report schedule.
+func (h *ReportScheduleHandler) List(c *gin.Context) {
+	var rows []models.ReportSchedule
+	if errFind := h.db.WithContext(c.Request.Context()).Order("id ASC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list report schedules failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatReportSchedule(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"report_schedules": out})
+}
+
+// Get returns one report schedule.
+func (h *ReportScheduleHandler) Get(c *gin.Context) {
+	schedule, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, formatReportSchedule(schedule))
+}
+
+// Update modifies a report schedule. Changing the frequency or re-enabling the schedule
+// reschedules the next run to the end of the current period.
+func (h *ReportScheduleHandler) Update(c *gin.Context) {
+	schedule, ok := h.load(c)
+	if !ok {
+		return
+	}
+	var body reportScheduleRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	previousFrequency, wasEnabled := schedule.Frequency, schedule.Enabled
+	if errApply := body.apply(schedule); errApply != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errApply.Error()})
+		return
+	}
+	now := time.Now()
+	if schedule.Frequency != previousFrequency || (schedule.Enabled && !wasEnabled) {
+		schedule.NextRunAt = reports.NextRun(schedule.Frequency, now).UTC()
+	}
+	schedule.UpdatedAt = now.UTC()
+	if errSave := h.db.WithContext(c.Request.Context()).Save(schedule).Error; errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatReportSchedule(schedule))
+}
+
+// Delete removes a report schedule.
+func (h *ReportScheduleHandler) Delete(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).Delete(&models.ReportSchedule{}, id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Run delivers a schedule's report for the last complete period now, without changing when
+// it is next due.
+func (h *ReportScheduleHandler) Run(c *gin.Context) {
+	schedule, ok := h.load(c)
+	if !ok {
+		return
+	}
+	scheduler := reports.NewScheduler(h.db, h.authManager)
+	if errRun := scheduler.Run(c.Request.Context(), schedule, time.Now()); errRun != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": errRun.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, formatReportSchedule(schedule))
+}
+
+// Preview renders a schedule's report for the last complete period without delivering it.
+// format=json returns the structured report; otherwise the schedule's format is used.
+func (h *ReportScheduleHandler) Preview(c *gin.Context) {
+	schedule, ok := h.load(c)
+	if !ok {
+		return
+	}
+	scheduler := reports.NewScheduler(h.db, h.authManager)
+	report, errBuild := scheduler.Preview(c.Request.Context(), schedule, time.Now())
+	if errBuild != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "build report failed"})
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+	if format == "" {
+		format = schedule.Format
+	}
+	switch format {
+	case "json":
+		c.JSON(http.StatusOK, report)
+	case reports.FormatHTML, reports.FormatCSV:
+		content, errRender := reports.Render(report, format)
+		if errRender != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "render report failed"})
+			return
+		}
+		contentType := "text/html; charset=utf-8"
+		if format == reports.FormatCSV {
+			contentType = "text/csv; charset=utf-8"
+			filename := fmt.Sprintf("report-%s-%s.csv", report.Frequency, report.From.Format("20060102"))
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		}
+		c.Data(http.StatusOK, contentType, []byte(content))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+	}
+}
+
+// load reads the schedule named by the id parameter, writing an error response on failure.
+func (h *ReportScheduleHandler) load(c *gin.Context) (*models.ReportSchedule, bool) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var schedule models.ReportSchedule
+	if errFind := h.db.WithContext(c.Request.Context()).First(&schedule, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return nil, false
+	}
+	return &schedule, true
+}
+
+// formatReportSchedule renders a schedule for API responses.
+func formatReportSchedule(schedule *models.ReportSchedule) gin.H {
+	return gin.H{
+		"id":          schedule.ID,
+		"name":        schedule.Name,
+		"frequency":   schedule.Frequency,
+		"sections":    schedule.Sections,
+		"format":      schedule.Format,
+		"channel":     schedule.Channel,
+		"emails":      schedule.Emails,
+		"webhook":     schedule.Webhook,
+		"enabled":     schedule.Enabled,
+		"next_run_at": schedule.NextRunAt,
+		"last_run_at": schedule.LastRunAt,
+		"last_error":  schedule.LastError,
+		"created_at":  schedule.CreatedAt,
+		"updated_at":  schedule.UpdatedAt,
+	}
+}
@@ -36,15 +36,32 @@ type createSettingRequest struct {
 var positiveIntSettingKeys = map[string]struct{}{
 	internalsettings.QuotaPollIntervalSecondsKey: {},
 	internalsettings.QuotaPollMaxConcurrencyKey:  {},
+	internalsettings.SMTPPortKey:                 {},
+	internalsettings.AnomalyBaselineDaysKey:      {},
 }
 
 var nonNegativeIntSettingKeys = map[string]struct{}{
-	internalsettings.RateLimitKey:        {},
-	internalsettings.RateLimitRedisDBKey: {},
+	internalsettings.RateLimitKey:                  {},
+	internalsettings.RateLimitRedisDBKey:           {},
+	internalsettings.RegistrationIPLimitPerHourKey: {},
+	internalsettings.RegistrationBonusValidDaysKey: {},
+	internalsettings.QuotaHistoryRetentionDaysKey:  {},
+	internalsettings.QuotaAlertHorizonMinutesKey:   {},
+	internalsettings.AnomalyMinHourlyRequestsKey:   {},
+}
+
+var nonNegativeNumberSettingKeys = map[string]struct{}{
+	internalsettings.AnomalySpendMultiplierKey:   {},
+	internalsettings.AnomalyRequestMultiplierKey: {},
+	internalsettings.AnomalyMinHourlySpendKey:    {},
 }
 
 var errPositiveIntegerValue = errors.New("value must be a positive integer")
+var errRegistrationModeValue = errors.New("value must be one of open, invite_only, closed")
 var errNonNegativeIntegerValue = errors.New("value must be a non-negative integer")
+var errQuotaSchedulingModeValue = errors.New("value must be one of off, deprioritize, skip")
+var errFractionValue = errors.New("value must be a number between 0 and 1")
+var errNonNegativeNumberValue = errors.New("value must be a non-negative number")
 
 // Create validates and inserts a setting, then refreshes the snapshot.
 func (h *SettingHandler) Create(c *gin.Context) {
@@ -220,6 +237,36 @@ func (h *SettingHandler) refreshDBConfigSnapshot(ctx context.Context) error {
 }
 
 func validateSettingValue(key string, value json.RawMessage) error {
+	if key == internalsettings.RegistrationModeKey {
+		var mode string
+		if errUnmarshal := json.Unmarshal(bytes.TrimSpace(value), &mode); errUnmarshal != nil ||
+			!internalsettings.ValidRegistrationMode(strings.ToLower(strings.TrimSpace(mode))) {
+			return errRegistrationModeValue
+		}
+		return nil
+	}
+	if key == internalsettings.QuotaSchedulingModeKey {
+		var mode string
+		if errUnmarshal := json.Unmarshal(bytes.TrimSpace(value), &mode); errUnmarshal != nil ||
+			!internalsettings.ValidQuotaSchedulingMode(strings.ToLower(strings.TrimSpace(mode))) {
+			return errQuotaSchedulingModeValue
+		}
+		return nil
+	}
+	if key == internalsettings.QuotaSchedulingThresholdKey {
+		var fraction float64
+		if errUnmarshal := json.Unmarshal(bytes.TrimSpace(value), &fraction); errUnmarshal != nil || fraction < 0 || fraction > 1 {
+			return errFractionValue
+		}
+		return nil
+	}
+	if _, ok := nonNegativeNumberSettingKeys[key]; ok {
+		var number float64
+		if errUnmarshal := json.Unmarshal(bytes.TrimSpace(value), &number); errUnmarshal != nil || number < 0 {
+			return errNonNegativeNumberValue
+		}
+		return nil
+	}
 	if _, ok := positiveIntSettingKeys[key]; !ok {
 		if _, okNonNegative := nonNegativeIntSettingKeys[key]; !okNonNegative {
 			return nil
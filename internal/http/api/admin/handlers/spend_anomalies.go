@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// SpendAnomalyHandler serves admin endpoints for detected spend anomalies.
+type SpendAnomalyHandler struct {
+	db *gorm.DB // Database handle for anomaly queries.
+}
+
+// NewSpendAnomalyHandler constructs a spend anomaly handler.
+func NewSpendAnomalyHandler(db *gorm.DB) *SpendAnomalyHandler {
+	return &SpendAnomalyHandler{db: db}
+}
+
+// spendAnomalyQuery defines list filters.
+type spendAnomalyQuery struct {
+	Page     int    `form:"page,default=1"`   // Page number.
+	Limit    int    `form:"limit,default=20"` // Page size.
+	Scope    string `form:"scope"`            // api_key or user.
+	Kind     string `form:"kind"`             // Anomaly kind.
+	Status   string `form:"status"`           // "open" or "resolved".
+	APIKeyID uint64 `form:"api_key_id"`       // Flagged API key filter.
+	UserID   uint64 `form:"user_id"`          // Owning or flagged user filter.
+}
+
+// resolveSpendAnomalyRequest is the body of a resolve request.
+type resolveSpendAnomalyRequest struct {
+	ReactivateKey bool `json:"reactivate_key"` // Reactivate the suspended API key.
+}
+
+// List returns spend anomalies with paging and filters, newest first.
+func (h *SpendAnomalyHandler) List(c *gin.Context) {
+	var q spendAnomalyQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	var total int64
+	if errCount := h.applyFilters(c, q).Count(&total).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "count spend anomalies failed"})
+		return
+	}
+
+	var rows []models.SpendAnomaly
+	if errFind := h.applyFilters(c, q).
+		Order("detected_at DESC, id DESC").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list spend anomalies failed"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatSpendAnomaly(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"anomalies": out,
+		"total":     total,
+		"page":      q.Page,
+		"limit":     q.Limit,
+	})
+}
+
+// scopedAnomalies returns an anomaly query limited to users the admin may manage.
+func (h *SpendAnomalyHandler) scopedAnomalies(c *gin.Context) *gorm.DB {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.SpendAnomaly{})
+	return applyUserScope(c, h.db, q, "spend_anomalies.user_id")
+}
+
+// applyFilters builds the filtered anomaly query.
+func (h *SpendAnomalyHandler) applyFilters(c *gin.Context, q spendAnomalyQuery) *gorm.DB {
+	query := h.scopedAnomalies(c)
+	if scope := strings.TrimSpace(q.Scope); scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	if kind := strings.TrimSpace(q.Kind); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	switch strings.ToLower(strings.TrimSpace(q.Status)) {
+	case "open":
+		query = query.Where("resolved_at IS NULL")
+	case "resolved":
+		query = query.Where("resolved_at IS NOT NULL")
+	}
+	if q.APIKeyID > 0 {
+		query = query.Where("api_key_id = ?", q.APIKeyID)
+	}
+	if q.UserID > 0 {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	return query
+}
+
+// Resolve closes an anomaly and optionally reactivates the API key it suspended.
+func (h *SpendAnomalyHandler) Resolve(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body resolveSpendAnomalyRequest
+	if c.Request.ContentLength != 0 {
+		if errBind := c.ShouldBindJSON(&body); errBind != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	var row models.SpendAnomaly
+	if errFind := h.scopedAnomalies(c).First(&row, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "load spend anomaly failed"})
+		return
+	}
+	if body.ReactivateKey && (row.APIKeyID == nil || !row.Suspended) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "anomaly did not suspend an api key"})
+		return
+	}
+
+	now := time.Now().UTC()
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if row.ResolvedAt == nil {
+			if errUpdate := tx.Model(&models.SpendAnomaly{}).Where("id = ?", row.ID).Update("resolved_at", now).Error; errUpdate != nil {
+				return errUpdate
+			}
+			row.ResolvedAt = &now
+		}
+		if body.ReactivateKey {
+			return tx.Model(&models.APIKey{}).
+				Where("id = ? AND revoked_at IS NULL", *row.APIKeyID).
+				Updates(map[string]any{"active": true, "updated_at": now}).Error
+		}
+		return nil
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve spend anomaly failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatSpendAnomaly(&row))
+}
+
+// formatSpendAnomaly formats an anomaly into response JSON.
+func formatSpendAnomaly(row *models.SpendAnomaly) gin.H {
+	return gin.H{
+		"id":          row.ID,
+		"scope":       row.Scope,
+		"subject_id":  row.SubjectID,
+		"kind":        row.Kind,
+		"api_key_id":  row.APIKeyID,
+		"user_id":     row.UserID,
+		"observed":    row.Observed,
+		"baseline":    row.Baseline,
+		"details":     row.Details,
+		"message":     row.Message,
+		"suspended":   row.Suspended,
+		"detected_at": row.DetectedAt,
+		"resolved_at": row.ResolvedAt,
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/sso"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// adminSSOPathPrefix is the route prefix of admin SSO endpoints.
+	adminSSOPathPrefix = "/v0/admin/sso/"
+	// adminSSODefaultReturn is where the browser lands after admin SSO when no path was requested.
+	adminSSODefaultReturn = "/admin"
+)
+
+// SSOHandler handles OpenID Connect login for administrators.
+type SSOHandler struct {
+	db      *gorm.DB
+	jwtCfg  config.JWTConfig
+	service *sso.Service
+}
+
+// NewSSOHandler constructs an SSOHandler.
+func NewSSOHandler(db *gorm.DB, jwtCfg config.JWTConfig) *SSOHandler {
+	return &SSOHandler{db: db, jwtCfg: jwtCfg, service: sso.NewService(db, nil)}
+}
+
+// callbackURL returns the redirect URI registered for a provider.
+func (h *SSOHandler) callbackURL(c *gin.Context, slug string) string {
+	return sso.CallbackURL(c.Request, adminSSOPathPrefix+slug+"/callback")
+}
+
+// Providers lists the providers administrators can sign in with.
+func (h *SSOHandler) Providers(c *gin.Context) {
+	rows, errList := h.service.Providers(c.Request.Context(), models.SSOKindAdmin)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list providers failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{"slug": row.Slug, "display_name": row.DisplayName})
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": out})
+}
+
+// Login redirects the browser to the provider's authorization endpoint.
+func (h *SSOHandler) Login(c *gin.Context) {
+	ctx := c.Request.Context()
+	provider, errProvider := h.service.LoadProvider(ctx, c.Param("provider"), models.SSOKindAdmin)
+	if errProvider != nil {
+		writeSSOError(c, errProvider)
+		return
+	}
+	returnTo := sso.SanitizeReturnTo(c.Query("return_to"), adminSSODefaultReturn)
+	authURL, errStart := h.service.Start(ctx, provider, models.SSOKindAdmin, h.callbackURL(c, provider.Slug), returnTo, nil)
+	if errStart != nil {
+		log.WithError(errStart).Warn("admin sso: start login failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// ssoLinkRequest defines the request body for starting an account link.
+type ssoLinkRequest struct {
+	ReturnTo string `json:"return_to"`
+}
+
+// Link starts a flow that links a provider identity to the current administrator.
+func (h *SSOHandler) Link(c *gin.Context) {
+	adminID, ok := readAdminIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
+		return
+	}
+	var body ssoLinkRequest
+	_ = c.ShouldBindJSON(&body)
+
+	ctx := c.Request.Context()
+	provider, errProvider := h.service.LoadProvider(ctx, c.Param("provider"), models.SSOKindAdmin)
+	if errProvider != nil {
+		writeSSOError(c, errProvider)
+		return
+	}
+	returnTo := sso.SanitizeReturnTo(body.ReturnTo, adminSSODefaultReturn)
+	authURL, errStart := h.service.Start(ctx, provider, models.SSOKindAdmin, h.callbackURL(c, provider.Slug), returnTo, &adminID)
+	if errStart != nil {
+		log.WithError(errStart).Warn("admin sso: start link failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// Callback completes the authorization code flow and hands a token to the web UI.
+func (h *SSOHandler) Callback(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := c.Param("provider")
+	state := c.Query("state")
+
+	returnTo := adminSSODefaultReturn
+	if stored, ok := h.service.PeekReturnTo(ctx, state); ok {
+		returnTo = sso.SanitizeReturnTo(stored, adminSSODefaultReturn)
+	}
+	if idpError := strings.TrimSpace(c.Query("error")); idpError != "" {
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_error": {idpError}}))
+		return
+	}
+
+	result, errComplete := h.service.Complete(ctx, models.SSOKindAdmin, slug, state, c.Query("code"), h.callbackURL(c, slug))
+	if errComplete != nil {
+		log.WithError(errComplete).Warn("admin sso: callback failed")
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_error": {sso.ErrorCode(errComplete)}}))
+		return
+	}
+	admin, errResolve := h.service.ResolveAdmin(ctx, result.Provider, result.Claims, result.LinkPrincipalID)
+	if errResolve != nil {
+		log.WithError(errResolve).Warnf("admin sso: resolve admin for provider %s failed", slug)
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_error": {sso.ErrorCode(errResolve)}}))
+		return
+	}
+	if result.LinkPrincipalID != nil {
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_linked": {slug}}))
+		return
+	}
+
+	token, errToken := security.GenerateAdminToken(h.jwtCfg.Secret, admin.ID, admin.Username, h.jwtCfg.Expiry)
+	if errToken != nil {
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_error": {"login_failed"}}))
+		return
+	}
+	c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_token": {token}}))
+}
+
+// Identities lists the SSO identities linked to the current administrator.
+func (h *SSOHandler) Identities(c *gin.Context) {
+	adminID, ok := readAdminIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
+		return
+	}
+	var rows []models.SSOIdentity
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("kind = ? AND admin_id = ?", models.SSOKindAdmin, adminID).
+		Order("id ASC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list identities failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": formatSSOIdentities(h.db, c, rows)})
+}
+
+// Unlink removes an SSO identity from the current administrator.
+func (h *SSOHandler) Unlink(c *gin.Context) {
+	adminID, ok := readAdminIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var admin models.Admin
+	if errFind := h.db.WithContext(ctx).Select("id", "sso_only").First(&admin, adminID).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if admin.SSOOnly {
+		var count int64
+		if errCount := h.db.WithContext(ctx).Model(&models.SSOIdentity{}).
+			Where("kind = ? AND admin_id = ?", models.SSOKindAdmin, adminID).
+			Count(&count).Error; errCount != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		if count <= 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot unlink the last identity of an sso-only admin"})
+			return
+		}
+	}
+
+	res := h.db.WithContext(ctx).
+		Where("id = ? AND kind = ? AND admin_id = ?", id, models.SSOKindAdmin, adminID).
+		Delete(&models.SSOIdentity{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "unlink failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeSSOError maps flow start errors to JSON responses.
+func writeSSOError(c *gin.Context, err error) {
+	if errors.Is(err, sso.ErrProviderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "query provider failed"})
+}
+
+// formatSSOIdentities renders identities with their provider labels.
+func formatSSOIdentities(db *gorm.DB, c *gin.Context, rows []models.SSOIdentity) []gin.H {
+	providerIDs := make([]uint64, 0, len(rows))
+	for _, row := range rows {
+		providerIDs = append(providerIDs, row.ProviderID)
+	}
+	names := make(map[uint64]models.OIDCProvider)
+	if len(providerIDs) > 0 {
+		var providers []models.OIDCProvider
+		if errFind := db.WithContext(c.Request.Context()).
+			Select("id", "slug", "display_name").
+			Where("id IN ?", providerIDs).
+			Find(&providers).Error; errFind == nil {
+			for _, provider := range providers {
+				names[provider.ID] = provider
+			}
+		}
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		provider := names[row.ProviderID]
+		out = append(out, gin.H{
+			"id":                    row.ID,
+			"provider_id":           row.ProviderID,
+			"provider_slug":         provider.Slug,
+			"provider_display_name": provider.DisplayName,
+			"email":                 row.Email,
+			"last_login_at":         row.LastLoginAt,
+			"created_at":            row.CreatedAt,
+		})
+	}
+	return out
+}
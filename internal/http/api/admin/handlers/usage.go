@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/export"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 )
@@ -23,15 +24,8 @@ func NewUsageHandler(db *gorm.DB) *UsageHandler {
 
 // List returns usage records with optional filters.
 func (h *UsageHandler) List(c *gin.Context) {
-	var (
-		apiKeyIDStr = strings.TrimSpace(c.Query("api_key_id"))
-		fromStr     = strings.TrimSpace(c.Query("from"))
-		toStr       = strings.TrimSpace(c.Query("to"))
-		limitStr    = strings.TrimSpace(c.Query("limit"))
-	)
-
 	limit := 100
-	if limitStr != "" {
+	if limitStr := strings.TrimSpace(c.Query("limit")); limitStr != "" {
 		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
 			if v > 1000 {
 				v = 1000
@@ -40,7 +34,55 @@ func (h *UsageHandler) List(c *gin.Context) {
 		}
 	}
 
+	var rows []models.Usage
+	if errFind := h.applyFilters(c).Order("requested_at DESC").Limit(limit).Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": rows})
+}
+
+// Export streams every usage record matching the list filters as CSV or XLSX.
+func (h *UsageHandler) Export(c *gin.Context) {
+	format, errFormat := export.ParseFormat(c.Query("format"))
+	if errFormat != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+	export.Serve(c, h.applyFilters(c).Order("requested_at DESC, id DESC"), format, usageExportTable)
+}
+
+// usageExportTable describes the columns of a usage export.
+var usageExportTable = export.Table[models.Usage]{
+	Name: "usage",
+	Header: []string{
+		"id", "requested_at", "provider", "model", "user_id", "user_group_id", "api_key_id", "auth_id",
+		"organization_id", "auth_key", "source", "failed", "input_tokens", "output_tokens",
+		"reasoning_tokens", "cached_tokens", "total_tokens", "cost_micros", "trace_id", "request_id",
+		"upstream_status", "error_code", "error_message", "time_to_first_token_ms", "duration_ms", "streaming",
+		"client_ip", "user_agent",
+	},
+	Row: func(row *models.Usage) []any {
+		return []any{
+			row.ID, row.RequestedAt, row.Provider, row.Model, row.UserID, row.UserGroupID, row.APIKeyID, row.AuthID,
+			row.OrganizationID, row.AuthKey, row.Source, row.Failed, row.InputTokens, row.OutputTokens,
+			row.ReasoningTokens, row.CachedTokens, row.TotalTokens, row.CostMicros, row.TraceID, row.RequestID,
+			row.UpstreamStatus, row.ErrorCode, row.ErrorMessage, row.TimeToFirstTokenMs, row.DurationMs, row.Streaming,
+			row.ClientIP, row.UserAgent,
+		}
+	},
+}
+
+// applyFilters builds the usage query filtered by api_key_id, from and to, limited to the admin's user scope.
+func (h *UsageHandler) applyFilters(c *gin.Context) *gorm.DB {
+	var (
+		apiKeyIDStr = strings.TrimSpace(c.Query("api_key_id"))
+		fromStr     = strings.TrimSpace(c.Query("from"))
+		toStr       = strings.TrimSpace(c.Query("to"))
+	)
+
 	q := h.db.WithContext(c.Request.Context()).Model(&models.Usage{})
+	q = applyUserScope(c, h.db, q, "usages.user_id")
 	if apiKeyIDStr != "" {
 		if id, errParseUint := strconv.ParseUint(apiKeyIDStr, 10, 64); errParseUint == nil {
 			q = q.Where("api_key_id = ?", id)
@@ -56,11 +98,5 @@ func (h *UsageHandler) List(c *gin.Context) {
 			q = q.Where("requested_at <= ?", t.UTC())
 		}
 	}
-
-	var rows []models.Usage
-	if errFind := q.Order("requested_at DESC").Limit(limit).Find(&rows).Error; errFind != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"usage": rows})
+	return q
 }
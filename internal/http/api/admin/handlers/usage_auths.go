@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authusage"
+)
+
+// AuthReport returns requests, tokens, revenue, cost, and margin per upstream auth and per auth
+// group for [from, to), defaulting to the current month. format=csv downloads the rows of the
+// selected scope (auth by default, or auth_group) as CSV.
+func (h *UsageHandler) AuthReport(c *gin.Context) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now
+	if fromStr := strings.TrimSpace(c.Query("from")); fromStr != "" {
+		t, errParse := time.Parse(time.RFC3339, fromStr)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		from = t.UTC()
+	}
+	if toStr := strings.TrimSpace(c.Query("to")); toStr != "" {
+		t, errParse := time.Parse(time.RFC3339, toStr)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		to = t.UTC()
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+	scope := strings.ToLower(strings.TrimSpace(c.Query("scope")))
+	if scope == "" {
+		scope = authusage.ScopeAuth
+	}
+	if scope != authusage.ScopeAuth && scope != authusage.ScopeAuthGroup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope"})
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+	if format != "" && format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+
+	auths, groups, errBuild := authusage.Build(c.Request.Context(), h.db, from, to)
+	if errBuild != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "build auth usage report failed"})
+		return
+	}
+
+	if format == "csv" {
+		rows := auths
+		if scope == authusage.ScopeAuthGroup {
+			rows = groups
+		}
+		filename := fmt.Sprintf("auth-usage-%s-%s-%s.csv", scope, from.Format("20060102"), to.Format("20060102"))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		_ = authusage.WriteCSV(c.Writer, rows)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from,
+		"to":          to,
+		"auths":       auths,
+		"auth_groups": groups,
+	})
+}
@@ -25,9 +25,10 @@ func NewUserGroupHandler(db *gorm.DB) *UserGroupHandler {
 
 // createUserGroupRequest defines the request body for user group creation.
 type createUserGroupRequest struct {
-	Name      string `json:"name"`
-	IsDefault bool   `json:"is_default"`
-	RateLimit int    `json:"rate_limit"`
+	Name       string `json:"name"`
+	IsDefault  bool   `json:"is_default"`
+	RateLimit  int    `json:"rate_limit"`
+	RequireMFA bool   `json:"require_mfa"`
 }
 
 // Create creates a new user group.
@@ -45,11 +46,12 @@ func (h *UserGroupHandler) Create(c *gin.Context) {
 
 	now := time.Now().UTC()
 	group := models.UserGroup{
-		Name:      name,
-		IsDefault: body.IsDefault,
-		RateLimit: body.RateLimit,
-		CreatedAt: now,
-		UpdatedAt: now,
+		Name:       name,
+		IsDefault:  body.IsDefault,
+		RateLimit:  body.RateLimit,
+		RequireMFA: body.RequireMFA,
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	}
 
 	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
@@ -66,11 +68,12 @@ func (h *UserGroupHandler) Create(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{
-		"id":         group.ID,
-		"name":       group.Name,
-		"is_default": group.IsDefault,
-		"created_at": group.CreatedAt,
-		"updated_at": group.UpdatedAt,
+		"id":          group.ID,
+		"name":        group.Name,
+		"is_default":  group.IsDefault,
+		"require_mfa": group.RequireMFA,
+		"created_at":  group.CreatedAt,
+		"updated_at":  group.UpdatedAt,
 	})
 }
 
@@ -100,12 +103,13 @@ func (h *UserGroupHandler) List(c *gin.Context) {
 	out := make([]gin.H, 0, len(rows))
 	for _, row := range rows {
 		out = append(out, gin.H{
-			"id":         row.ID,
-			"name":       row.Name,
-			"is_default": row.IsDefault,
-			"rate_limit": row.RateLimit,
-			"created_at": row.CreatedAt,
-			"updated_at": row.UpdatedAt,
+			"id":          row.ID,
+			"name":        row.Name,
+			"is_default":  row.IsDefault,
+			"rate_limit":  row.RateLimit,
+			"require_mfa": row.RequireMFA,
+			"created_at":  row.CreatedAt,
+			"updated_at":  row.UpdatedAt,
 		})
 	}
 	c.JSON(http.StatusOK, gin.H{"user_groups": out})
@@ -128,20 +132,22 @@ func (h *UserGroupHandler) Get(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"id":         group.ID,
-		"name":       group.Name,
-		"is_default": group.IsDefault,
-		"rate_limit": group.RateLimit,
-		"created_at": group.CreatedAt,
-		"updated_at": group.UpdatedAt,
+		"id":          group.ID,
+		"name":        group.Name,
+		"is_default":  group.IsDefault,
+		"rate_limit":  group.RateLimit,
+		"require_mfa": group.RequireMFA,
+		"created_at":  group.CreatedAt,
+		"updated_at":  group.UpdatedAt,
 	})
 }
 
 // updateUserGroupRequest defines the request body for user group updates.
 type updateUserGroupRequest struct {
-	Name      *string `json:"name"`
-	IsDefault *bool   `json:"is_default"`
-	RateLimit *int    `json:"rate_limit"`
+	Name       *string `json:"name"`
+	IsDefault  *bool   `json:"is_default"`
+	RateLimit  *int    `json:"rate_limit"`
+	RequireMFA *bool   `json:"require_mfa"`
 }
 
 // Update modifies a user group.
@@ -176,6 +182,9 @@ func (h *UserGroupHandler) Update(c *gin.Context) {
 		if body.RateLimit != nil {
 			updates["rate_limit"] = *body.RateLimit
 		}
+		if body.RequireMFA != nil {
+			updates["require_mfa"] = *body.RequireMFA
+		}
 
 		res := tx.Model(&models.UserGroup{}).Where("id = ?", id).Updates(updates)
 		if res.Error != nil {
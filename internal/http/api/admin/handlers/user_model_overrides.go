@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// setModelOverrideRequest defines the request body for granting or denying a model to a user.
+type setModelOverrideRequest struct {
+	Model     string                   `json:"model"`
+	Effect    models.ModelAccessEffect `json:"effect"`
+	Note      string                   `json:"note"`
+	ExpiresAt *time.Time               `json:"expires_at"`
+}
+
+// ListModelOverrides returns the per-user model grants and denials of a user.
+func (h *UserHandler) ListModelOverrides(c *gin.Context) {
+	userID, ok := h.loadScopedUserID(c)
+	if !ok {
+		return
+	}
+	var overrides []models.UserModelOverride
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("user_id = ?", userID).
+		Order("model ASC").
+		Find(&overrides).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	now := time.Now().UTC()
+	out := make([]gin.H, 0, len(overrides))
+	for i := range overrides {
+		out = append(out, formatModelOverride(&overrides[i], now))
+	}
+	c.JSON(http.StatusOK, gin.H{"overrides": out})
+}
+
+// SetModelOverride creates or replaces the override of one model for a user.
+func (h *UserHandler) SetModelOverride(c *gin.Context) {
+	userID, ok := h.loadScopedUserID(c)
+	if !ok {
+		return
+	}
+	var body setModelOverrideRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	modelName := strings.TrimSpace(body.Model)
+	if modelName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing model"})
+		return
+	}
+	effect := models.ModelAccessEffect(strings.ToLower(strings.TrimSpace(string(body.Effect))))
+	if effect != models.ModelAccessAllow && effect != models.ModelAccessDeny {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "effect must be allow or deny"})
+		return
+	}
+	now := time.Now().UTC()
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+	var createdBy *uint64
+	if adminID, okAdmin := readAdminIDFromContext(c); okAdmin {
+		createdBy = &adminID
+	}
+
+	var override models.UserModelOverride
+	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		errFind := tx.Where("user_id = ? AND LOWER(model) = ?", userID, strings.ToLower(modelName)).First(&override).Error
+		switch {
+		case errFind == nil:
+			override.Model = modelName
+			override.Effect = effect
+			override.Note = strings.TrimSpace(body.Note)
+			override.ExpiresAt = body.ExpiresAt
+			override.CreatedByAdminID = createdBy
+			override.UpdatedAt = now
+			return tx.Save(&override).Error
+		case errors.Is(errFind, gorm.ErrRecordNotFound):
+			override = models.UserModelOverride{
+				UserID:           userID,
+				Model:            modelName,
+				Effect:           effect,
+				Note:             strings.TrimSpace(body.Note),
+				ExpiresAt:        body.ExpiresAt,
+				CreatedByAdminID: createdBy,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			}
+			return tx.Create(&override).Error
+		default:
+			return errFind
+		}
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save override failed"})
+		return
+	}
+	c.JSON(http.StatusOK, formatModelOverride(&override, now))
+}
+
+// DeleteModelOverride removes one model override of a user.
+func (h *UserHandler) DeleteModelOverride(c *gin.Context) {
+	userID, ok := h.loadScopedUserID(c)
+	if !ok {
+		return
+	}
+	overrideID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("override_id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid override id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).
+		Where("id = ? AND user_id = ?", overrideID, userID).
+		Delete(&models.UserModelOverride{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// loadScopedUserID parses the user ID route parameter and checks the admin may manage the user.
+func (h *UserHandler) loadScopedUserID(c *gin.Context) (uint64, bool) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	var count int64
+	if errCount := h.scopedUsers(c).Where("id = ?", id).Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return 0, false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return 0, false
+	}
+	return id, true
+}
+
+// formatModelOverride converts an override into a response payload.
+func formatModelOverride(override *models.UserModelOverride, now time.Time) gin.H {
+	return gin.H{
+		"id":                  override.ID,
+		"user_id":             override.UserID,
+		"model":               override.Model,
+		"effect":              override.Effect,
+		"note":                override.Note,
+		"expires_at":          override.ExpiresAt,
+		"expired":             override.ExpiresAt != nil && !override.ExpiresAt.After(now),
+		"created_by_admin_id": override.CreatedByAdminID,
+		"created_at":          override.CreatedAt,
+		"updated_at":          override.UpdatedAt,
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/export"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	"gorm.io/gorm"
@@ -24,12 +26,20 @@ func NewUserHandler(db *gorm.DB) *UserHandler {
 	return &UserHandler{db: db}
 }
 
+// scopedUsers returns a user query limited to the groups the admin may manage.
+func (h *UserHandler) scopedUsers(c *gin.Context) *gorm.DB {
+	q := h.db.WithContext(c.Request.Context()).Model(&models.User{})
+	return applyUserGroupScope(h.db, q, "user_group_id", readAdminUserGroupScope(c))
+}
+
 // createUserRequest defines the request body for user creation.
 type createUserRequest struct {
 	Username  string `json:"username"`
 	Email     string `json:"email"`
 	Password  string `json:"password"`
 	RateLimit int    `json:"rate_limit"`
+
+	UserGroupID models.UserGroupIDs `json:"user_group_id"`
 }
 
 // Create creates a new user account.
@@ -50,6 +60,12 @@ func (h *UserHandler) Create(c *gin.Context) {
 		return
 	}
 
+	userGroupID := body.UserGroupID.Clean()
+	if !userGroupsInScope(userGroupID.Values(), readAdminUserGroupScope(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user group out of scope"})
+		return
+	}
+
 	hash, errHash := security.HashPassword(password)
 	if errHash != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash password failed"})
@@ -58,14 +74,15 @@ func (h *UserHandler) Create(c *gin.Context) {
 
 	now := time.Now().UTC()
 	user := models.User{
-		Username:  username,
-		Email:     strings.TrimSpace(body.Email),
-		Password:  hash,
-		RateLimit: body.RateLimit,
-		Active:    true,
-		Disabled:  false,
-		CreatedAt: now,
-		UpdatedAt: now,
+		Username:    username,
+		Email:       strings.TrimSpace(body.Email),
+		Password:    hash,
+		UserGroupID: userGroupID,
+		RateLimit:   body.RateLimit,
+		Active:      true,
+		Disabled:    false,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 	if errCreate := h.db.WithContext(c.Request.Context()).Create(&user).Error; errCreate != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create user failed"})
@@ -81,6 +98,60 @@ func (h *UserHandler) Create(c *gin.Context) {
 
 // List returns users with optional filters.
 func (h *UserHandler) List(c *gin.Context) {
+	q := h.applyFilters(c)
+	var rows []models.User
+	if errFind := q.Order("created_at DESC").Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list users failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{
+			"id":                 row.ID,
+			"username":           row.Username,
+			"email":              row.Email,
+			"user_group_id":      row.UserGroupID.Clean(),
+			"bill_user_group_id": row.BillUserGroupID.Clean(),
+			"daily_max_usage":    row.DailyMaxUsage,
+			"rate_limit":         row.RateLimit,
+			"active":             row.Active,
+			"disabled":           row.Disabled,
+			"email_verified":     !row.EmailVerificationPending,
+			"created_at":         row.CreatedAt,
+			"updated_at":         row.UpdatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"users": out})
+}
+
+// Export streams every user matching the list filters as CSV or XLSX.
+func (h *UserHandler) Export(c *gin.Context) {
+	format, errFormat := export.ParseFormat(c.Query("format"))
+	if errFormat != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+	export.Serve(c, h.applyFilters(c).Order("created_at DESC, id DESC"), format, userExportTable)
+}
+
+// userExportTable describes the columns of a user export.
+var userExportTable = export.Table[models.User]{
+	Name: "users",
+	Header: []string{
+		"id", "username", "email", "user_group_ids", "bill_user_group_ids", "daily_max_usage", "rate_limit",
+		"active", "disabled", "email_verified", "created_at", "updated_at",
+	},
+	Row: func(user *models.User) []any {
+		return []any{
+			user.ID, user.Username, user.Email, joinIDs(user.UserGroupID.Values()), joinIDs(user.BillUserGroupID.Values()),
+			user.DailyMaxUsage, user.RateLimit, user.Active, user.Disabled, !user.EmailVerificationPending,
+			user.CreatedAt, user.UpdatedAt,
+		}
+	},
+}
+
+// applyFilters builds the scoped user query filtered by username, id, email and search.
+func (h *UserHandler) applyFilters(c *gin.Context) *gorm.DB {
 	var (
 		usernameQ = strings.TrimSpace(c.Query("username"))
 		idQ       = strings.TrimSpace(c.Query("id"))
@@ -88,7 +159,7 @@ func (h *UserHandler) List(c *gin.Context) {
 		searchQ   = strings.TrimSpace(c.Query("search"))
 	)
 
-	q := h.db.WithContext(c.Request.Context()).Model(&models.User{})
+	q := h.scopedUsers(c)
 	if usernameQ != "" {
 		pattern := dbutil.NormalizeLikePattern(h.db, "%"+usernameQ+"%")
 		q = q.Where(dbutil.CaseInsensitiveLikeExpr(h.db, "username"), pattern)
@@ -113,29 +184,7 @@ func (h *UserHandler) List(c *gin.Context) {
 			searchPattern,
 		)
 	}
-
-	var rows []models.User
-	if errFind := q.Order("created_at DESC").Find(&rows).Error; errFind != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "list users failed"})
-		return
-	}
-	out := make([]gin.H, 0, len(rows))
-	for _, row := range rows {
-		out = append(out, gin.H{
-			"id":                 row.ID,
-			"username":           row.Username,
-			"email":              row.Email,
-			"user_group_id":      row.UserGroupID.Clean(),
-			"bill_user_group_id": row.BillUserGroupID.Clean(),
-			"daily_max_usage":    row.DailyMaxUsage,
-			"rate_limit":         row.RateLimit,
-			"active":             row.Active,
-			"disabled":           row.Disabled,
-			"created_at":         row.CreatedAt,
-			"updated_at":         row.UpdatedAt,
-		})
-	}
-	c.JSON(http.StatusOK, gin.H{"users": out})
+	return q
 }
 
 // Get returns a user by ID.
@@ -146,7 +195,7 @@ func (h *UserHandler) Get(c *gin.Context) {
 		return
 	}
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).First(&user, id).Error; errFind != nil {
+	if errFind := h.scopedUsers(c).First(&user, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -164,6 +213,9 @@ func (h *UserHandler) Get(c *gin.Context) {
 		"rate_limit":         user.RateLimit,
 		"active":             user.Active,
 		"disabled":           user.Disabled,
+		"email_verified":     !user.EmailVerificationPending,
+		"invite_code_id":     user.InviteCodeID,
+		"registered_ip":      user.RegisteredIP,
 		"created_at":         user.CreatedAt,
 		"updated_at":         user.UpdatedAt,
 	})
@@ -177,6 +229,7 @@ type updateUserRequest struct {
 	DailyMaxUsage *float64             `json:"daily_max_usage"`
 	RateLimit     *int                 `json:"rate_limit"`
 	Disabled      *bool                `json:"disabled"`
+	EmailVerified *bool                `json:"email_verified"`
 }
 
 // Update modifies a user account.
@@ -203,7 +256,12 @@ func (h *UserHandler) Update(c *gin.Context) {
 		updates["email"] = strings.TrimSpace(*body.Email)
 	}
 	if body.UserGroupID != nil {
-		updates["user_group_id"] = body.UserGroupID.Clean()
+		userGroupID := body.UserGroupID.Clean()
+		if !userGroupsInScope(userGroupID.Values(), readAdminUserGroupScope(c)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "user group out of scope"})
+			return
+		}
+		updates["user_group_id"] = userGroupID
 	}
 	if body.DailyMaxUsage != nil {
 		updates["daily_max_usage"] = *body.DailyMaxUsage
@@ -214,8 +272,16 @@ func (h *UserHandler) Update(c *gin.Context) {
 	if body.Disabled != nil {
 		updates["disabled"] = *body.Disabled
 	}
+	if body.EmailVerified != nil {
+		updates["email_verification_pending"] = !*body.EmailVerified
+		if *body.EmailVerified {
+			updates["email_verified_at"] = time.Now().UTC()
+		} else {
+			updates["email_verified_at"] = nil
+		}
+	}
 
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).Where("id = ?", id).Updates(updates)
+	res := h.scopedUsers(c).Where("id = ?", id).Updates(updates)
 	if res.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
@@ -238,7 +304,7 @@ func (h *UserHandler) Delete(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	var user models.User
-	if errFind := h.db.WithContext(ctx).First(&user, id).Error; errFind != nil {
+	if errFind := h.scopedUsers(c).First(&user, id).Error; errFind != nil {
 		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -251,6 +317,9 @@ func (h *UserHandler) Delete(c *gin.Context) {
 		if errDelKeys := tx.Where("user_id = ?", id).Delete(&models.APIKey{}).Error; errDelKeys != nil {
 			return errDelKeys
 		}
+		if errDelOverrides := tx.Where("user_id = ?", id).Delete(&models.UserModelOverride{}).Error; errDelOverrides != nil {
+			return errDelOverrides
+		}
 		if errDelUser := tx.Delete(&models.User{}, id).Error; errDelUser != nil {
 			return errDelUser
 		}
@@ -271,7 +340,7 @@ func (h *UserHandler) Disable(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+	res := h.scopedUsers(c).
 		Where("id = ?", id).
 		Updates(map[string]any{"disabled": true, "updated_at": time.Now().UTC()})
 	if res.Error != nil {
@@ -292,7 +361,7 @@ func (h *UserHandler) Enable(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+	res := h.scopedUsers(c).
 		Where("id = ?", id).
 		Updates(map[string]any{"disabled": false, "updated_at": time.Now().UTC()})
 	if res.Error != nil {
@@ -333,7 +402,7 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash password failed"})
 		return
 	}
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+	res := h.scopedUsers(c).
 		Where("id = ?", id).
 		Updates(map[string]any{"password": hash, "updated_at": time.Now().UTC()})
 	if res.Error != nil {
@@ -346,3 +415,30 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// ResetMFA removes every MFA factor of a user so they can enroll again.
+func (h *UserHandler) ResetMFA(c *gin.Context) {
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var count int64
+	if errCount := h.scopedUsers(c).Where("id = ?", id).Count(&count).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if errReset := mfa.Reset(c.Request.Context(), h.db, models.MFAOwnerUser, id); errReset != nil {
+		if errors.Is(errReset, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset mfa failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,102 @@
+package permissions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GrantAll grants every permission definition.
+const GrantAll = "*"
+
+// moduleGrantPrefix marks grants that cover a whole permission module.
+const moduleGrantPrefix = "module:"
+
+// readOnlySuffix limits a module grant to GET routes.
+const readOnlySuffix = ":read"
+
+// ModuleGrant builds a grant covering every permission in a module.
+func ModuleGrant(module string) string {
+	return moduleGrantPrefix + module
+}
+
+// ModuleReadGrant builds a grant covering the read-only permissions in a module.
+func ModuleReadGrant(module string) string {
+	return moduleGrantPrefix + module + readOnlySuffix
+}
+
+// Modules returns the distinct permission modules in definition order.
+func Modules() []string {
+	seen := make(map[string]struct{})
+	out := make([]string, 0)
+	for _, def := range definitions {
+		if _, ok := seen[def.Module]; ok {
+			continue
+		}
+		seen[def.Module] = struct{}{}
+		out = append(out, def.Module)
+	}
+	return out
+}
+
+// MatchGrant reports whether a grant covers the permission definition.
+//
+// Supported grants are "*", "module:<Module>", "module:<Module>:read",
+// exact keys such as "GET /v0/admin/users", and keys whose method is "*"
+// or whose path ends with "*" as a prefix wildcard.
+func MatchGrant(grant string, def Definition) bool {
+	grant = strings.TrimSpace(grant)
+	if grant == "" {
+		return false
+	}
+	if grant == GrantAll {
+		return true
+	}
+	if strings.HasPrefix(grant, moduleGrantPrefix) {
+		module := strings.TrimPrefix(grant, moduleGrantPrefix)
+		if base, readOnly := strings.CutSuffix(module, readOnlySuffix); readOnly {
+			return base == def.Module && def.Method == http.MethodGet
+		}
+		return module == def.Module
+	}
+	if grant == def.Key {
+		return true
+	}
+	method, path, ok := strings.Cut(grant, " ")
+	if !ok {
+		return false
+	}
+	method = strings.ToUpper(strings.TrimSpace(method))
+	path = strings.TrimSpace(path)
+	if method != "*" && method != def.Method {
+		return false
+	}
+	if prefix, isWildcard := strings.CutSuffix(path, "*"); isWildcard {
+		return strings.HasPrefix(def.Path, prefix)
+	}
+	return path == def.Path
+}
+
+// validateGrant checks that a grant is well formed and covers at least one definition.
+func validateGrant(grant string) error {
+	if _, ok := definitionMap[grant]; ok || grant == GrantAll {
+		return nil
+	}
+	for _, def := range definitions {
+		if MatchGrant(grant, def) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid permission: %s", grant)
+}
+
+// Expand returns the permission keys covered by the grants.
+func Expand(grants []string) []string {
+	out := make([]string, 0)
+	for _, def := range definitions {
+		if HasPermission(grants, def.Key) {
+			out = append(out, def.Key)
+		}
+	}
+	return out
+}
@@ -2,7 +2,6 @@ package permissions
 
 import (
 	"encoding/json"
-	"fmt"
 	"sort"
 	"strings"
 )
@@ -43,19 +42,18 @@ func NormalizePermissions(perms []string) []string {
 	return normalized
 }
 
-// ValidatePermissions validates that all permissions exist in the definition set.
+// ValidatePermissions validates that all grants match at least one permission definition.
 func ValidatePermissions(perms []string) error {
 	if len(perms) == 0 {
 		return nil
 	}
-	allowed := definitionMap
 	for _, perm := range perms {
 		trimmed := strings.TrimSpace(perm)
 		if trimmed == "" {
 			continue
 		}
-		if _, ok := allowed[trimmed]; !ok {
-			return fmt.Errorf("invalid permission: %s", trimmed)
+		if errGrant := validateGrant(trimmed); errGrant != nil {
+			return errGrant
 		}
 	}
 	return nil
@@ -79,15 +77,19 @@ func MarshalPermissions(perms []string) ([]byte, error) {
 	return json.Marshal(normalized)
 }
 
-// HasPermission checks whether the key exists in the permission list.
+// HasPermission checks whether any grant in the permission list covers the key.
 func HasPermission(perms []string, key string) bool {
 	if key == "" {
 		return false
 	}
+	def, known := definitionMap[key]
 	for _, perm := range perms {
 		if perm == key {
 			return true
 		}
+		if known && MatchGrant(perm, def) {
+			return true
+		}
 	}
 	return false
 }
@@ -127,15 +129,29 @@ var definitions = []Definition{
 	newDefinition("GET", "/v0/admin/dashboard/cost-distribution", "View Cost Distribution", "Dashboard"),
 	newDefinition("GET", "/v0/admin/dashboard/model-health", "View Model Health", "Dashboard"),
 	newDefinition("GET", "/v0/admin/dashboard/transactions", "View Recent Transactions", "Dashboard"),
+	newDefinition("POST", "/v0/admin/report-schedules", "Create Report Schedule", "Reports"),
+	newDefinition("GET", "/v0/admin/report-schedules", "List Report Schedules", "Reports"),
+	newDefinition("GET", "/v0/admin/report-schedules/:id", "Get Report Schedule", "Reports"),
+	newDefinition("PUT", "/v0/admin/report-schedules/:id", "Update Report Schedule", "Reports"),
+	newDefinition("DELETE", "/v0/admin/report-schedules/:id", "Delete Report Schedule", "Reports"),
+	newDefinition("POST", "/v0/admin/report-schedules/:id/run", "Run Report Schedule", "Reports"),
+	newDefinition("GET", "/v0/admin/report-schedules/:id/preview", "Preview Report Schedule", "Reports"),
+	newDefinition("GET", "/v0/admin/spend-anomalies", "List Spend Anomalies", "Spend Anomalies"),
+	newDefinition("POST", "/v0/admin/spend-anomalies/:id/resolve", "Resolve Spend Anomaly", "Spend Anomalies"),
 
 	newDefinition("POST", "/v0/admin/users", "Create User", "Users"),
 	newDefinition("GET", "/v0/admin/users", "List Users", "Users"),
+	newDefinition("GET", "/v0/admin/users/export", "Export Users", "Users"),
 	newDefinition("GET", "/v0/admin/users/:id", "Get User", "Users"),
 	newDefinition("PUT", "/v0/admin/users/:id", "Update User", "Users"),
 	newDefinition("DELETE", "/v0/admin/users/:id", "Delete User", "Users"),
 	newDefinition("POST", "/v0/admin/users/:id/disable", "Disable User", "Users"),
 	newDefinition("POST", "/v0/admin/users/:id/enable", "Enable User", "Users"),
 	newDefinition("PUT", "/v0/admin/users/:id/password", "Change User Password", "Users"),
+	newDefinition("POST", "/v0/admin/users/:id/mfa/reset", "Reset User MFA", "Users"),
+	newDefinition("GET", "/v0/admin/users/:id/model-overrides", "List User Model Overrides", "Users"),
+	newDefinition("PUT", "/v0/admin/users/:id/model-overrides", "Set User Model Override", "Users"),
+	newDefinition("DELETE", "/v0/admin/users/:id/model-overrides/:override_id", "Delete User Model Override", "Users"),
 
 	newDefinition("POST", "/v0/admin/user-groups", "Create User Group", "User Groups"),
 	newDefinition("GET", "/v0/admin/user-groups", "List User Groups", "User Groups"),
@@ -153,19 +169,31 @@ var definitions = []Definition{
 
 	newDefinition("POST", "/v0/admin/auth-files", "Create Auth File", "Auth Files"),
 	newDefinition("POST", "/v0/admin/auth-files/import", "Import Auth Files", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/bulk", "Bulk Update Auth Files", "Auth Files"),
+	newDefinition("GET", "/v0/admin/auth-files/export", "Export Auth Files", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/export", "Export Selected Auth Files", "Auth Files"),
+	newDefinition("GET", "/v0/admin/auth-files/invalid", "List Invalid Auth Files", "Auth Files"),
 	newDefinition("GET", "/v0/admin/auth-files", "List Auth Files", "Auth Files"),
 	newDefinition("GET", "/v0/admin/auth-files/:id", "Get Auth File", "Auth Files"),
 	newDefinition("PUT", "/v0/admin/auth-files/:id", "Update Auth File", "Auth Files"),
 	newDefinition("DELETE", "/v0/admin/auth-files/:id", "Delete Auth File", "Auth Files"),
 	newDefinition("POST", "/v0/admin/auth-files/:id/available", "Set Auth File Available", "Auth Files"),
 	newDefinition("POST", "/v0/admin/auth-files/:id/unavailable", "Set Auth File Unavailable", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/:id/reauth", "Start Auth File Re-authorization", "Auth Files"),
+	newDefinition("POST", "/v0/admin/auth-files/:id/reauth/complete", "Complete Auth File Re-authorization", "Auth Files"),
 	newDefinition("GET", "/v0/admin/auth-files/types", "List Auth File Types", "Auth Files"),
 
 	newDefinition("GET", "/v0/admin/quotas", "List Quotas", "Quota"),
+	newDefinition("GET", "/v0/admin/quotas/capacity", "View Quota Capacity", "Quota"),
+	newDefinition("GET", "/v0/admin/quotas/history", "View Quota History", "Quota"),
+	newDefinition("GET", "/v0/admin/quotas/forecast", "View Quota Forecast", "Quota"),
 
 	newDefinition("POST", "/v0/admin/model-mappings", "Create Model Mapping", "Models"),
 	newDefinition("GET", "/v0/admin/model-mappings", "List Model Mappings", "Models"),
 	newDefinition("GET", "/v0/admin/model-mappings/available-models", "List Available Models", "Models"),
+	newDefinition("GET", "/v0/admin/model-mappings/export", "Export Model Mappings", "Models"),
+	newDefinition("POST", "/v0/admin/model-mappings/import", "Import Model Mappings", "Models"),
+	newDefinition("POST", "/v0/admin/model-mappings/bulk", "Bulk Update Model Mappings", "Models"),
 	newDefinition("GET", "/v0/admin/model-mappings/:id", "Get Model Mapping", "Models"),
 	newDefinition("PUT", "/v0/admin/model-mappings/:id", "Update Model Mapping", "Models"),
 	newDefinition("DELETE", "/v0/admin/model-mappings/:id", "Delete Model Mapping", "Models"),
@@ -175,6 +203,11 @@ var definitions = []Definition{
 	newDefinition("POST", "/v0/admin/model-mappings/:id/payload-rules", "Create Model Payload Rule", "Models"),
 	newDefinition("PUT", "/v0/admin/model-mappings/:id/payload-rules/:rule_id", "Update Model Payload Rule", "Models"),
 	newDefinition("DELETE", "/v0/admin/model-mappings/:id/payload-rules/:rule_id", "Delete Model Payload Rule", "Models"),
+	newDefinition("GET", "/v0/admin/model-catalog", "List Model Catalogue", "Models"),
+	newDefinition("POST", "/v0/admin/model-catalog", "Create Model Catalogue Entry", "Models"),
+	newDefinition("GET", "/v0/admin/model-catalog/:id", "Get Model Catalogue Entry", "Models"),
+	newDefinition("PUT", "/v0/admin/model-catalog/:id", "Update Model Catalogue Entry", "Models"),
+	newDefinition("DELETE", "/v0/admin/model-catalog/:id", "Delete Model Catalogue Entry", "Models"),
 
 	newDefinition("POST", "/v0/admin/api-keys", "Create API Key", "API Keys"),
 	newDefinition("GET", "/v0/admin/api-keys", "List API Keys", "API Keys"),
@@ -198,8 +231,21 @@ var definitions = []Definition{
 	newDefinition("PUT", "/v0/admin/prepaid-cards/:id", "Update Prepaid Card", "Prepaid Cards"),
 	newDefinition("DELETE", "/v0/admin/prepaid-cards/:id", "Delete Prepaid Card", "Prepaid Cards"),
 
+	newDefinition("POST", "/v0/admin/invite-codes", "Create Invite Codes", "Invite Codes"),
+	newDefinition("GET", "/v0/admin/invite-codes", "List Invite Codes", "Invite Codes"),
+	newDefinition("GET", "/v0/admin/invite-codes/:id", "Get Invite Code", "Invite Codes"),
+	newDefinition("PUT", "/v0/admin/invite-codes/:id", "Update Invite Code", "Invite Codes"),
+	newDefinition("DELETE", "/v0/admin/invite-codes/:id", "Delete Invite Code", "Invite Codes"),
+
+	newDefinition("POST", "/v0/admin/oidc-providers", "Create OIDC Provider", "Single Sign-On"),
+	newDefinition("GET", "/v0/admin/oidc-providers", "List OIDC Providers", "Single Sign-On"),
+	newDefinition("GET", "/v0/admin/oidc-providers/:id", "Get OIDC Provider", "Single Sign-On"),
+	newDefinition("PUT", "/v0/admin/oidc-providers/:id", "Update OIDC Provider", "Single Sign-On"),
+	newDefinition("DELETE", "/v0/admin/oidc-providers/:id", "Delete OIDC Provider", "Single Sign-On"),
+
 	newDefinition("POST", "/v0/admin/bills", "Create Bill", "Bills"),
 	newDefinition("GET", "/v0/admin/bills", "List Bills", "Bills"),
+	newDefinition("GET", "/v0/admin/bills/export", "Export Bills", "Bills"),
 	newDefinition("GET", "/v0/admin/bills/:id", "Get Bill", "Bills"),
 	newDefinition("PUT", "/v0/admin/bills/:id", "Update Bill", "Bills"),
 	newDefinition("DELETE", "/v0/admin/bills/:id", "Delete Bill", "Bills"),
@@ -214,6 +260,7 @@ var definitions = []Definition{
 	newDefinition("POST", "/v0/admin/billing-rules/:id/enabled", "Set Billing Rule Enabled", "Billing Rules"),
 
 	newDefinition("GET", "/v0/admin/logs", "List Logs", "Logs"),
+	newDefinition("GET", "/v0/admin/logs/export", "Export Logs", "Logs"),
 	newDefinition("GET", "/v0/admin/logs/detail", "View Log Details", "Logs"),
 	newDefinition("GET", "/v0/admin/logs/stats", "View Log Stats", "Logs"),
 	newDefinition("GET", "/v0/admin/logs/trend", "View Log Trend", "Logs"),
@@ -226,7 +273,12 @@ var definitions = []Definition{
 	newDefinition("PUT", "/v0/admin/settings/:key", "Update Setting", "Settings"),
 	newDefinition("DELETE", "/v0/admin/settings/:key", "Delete Setting", "Settings"),
 
+	newDefinition("GET", "/v0/admin/audit-logs", "List Audit Logs", "Audit Logs"),
+	newDefinition("GET", "/v0/admin/audit-logs/export", "Export Audit Logs", "Audit Logs"),
+
 	newDefinition("GET", "/v0/admin/usage", "View Usage", "Usage"),
+	newDefinition("GET", "/v0/admin/usage/auths", "View Auth Usage Report", "Usage"),
+	newDefinition("GET", "/v0/admin/usage/export", "Export Usage", "Usage"),
 	newDefinition("GET", "/v0/admin/billing/summary", "View Billing Summary", "Billing"),
 
 	newDefinition("POST", "/v0/admin/admins", "Create Administrator", "Administrators"),
@@ -237,7 +289,13 @@ var definitions = []Definition{
 	newDefinition("POST", "/v0/admin/admins/:id/disable", "Disable Administrator", "Administrators"),
 	newDefinition("POST", "/v0/admin/admins/:id/enable", "Enable Administrator", "Administrators"),
 	newDefinition("PUT", "/v0/admin/admins/:id/password", "Change Administrator Password", "Administrators"),
+	newDefinition("POST", "/v0/admin/admins/:id/mfa/reset", "Reset Administrator MFA", "Administrators"),
 	newDefinition("GET", "/v0/admin/permissions", "List Permission Definitions", "Administrators"),
+	newDefinition("POST", "/v0/admin/admin-roles", "Create Administrator Role", "Administrators"),
+	newDefinition("GET", "/v0/admin/admin-roles", "List Administrator Roles", "Administrators"),
+	newDefinition("GET", "/v0/admin/admin-roles/:id", "Get Administrator Role", "Administrators"),
+	newDefinition("PUT", "/v0/admin/admin-roles/:id", "Update Administrator Role", "Administrators"),
+	newDefinition("DELETE", "/v0/admin/admin-roles/:id", "Delete Administrator Role", "Administrators"),
 
 	newDefinition("POST", "/v0/admin/plans", "Create Plan", "Plans"),
 	newDefinition("GET", "/v0/admin/plans", "List Plans", "Plans"),
@@ -0,0 +1,102 @@
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Access is the effective authorization of an admin after merging roles.
+type Access struct {
+	Permissions    []string // Direct grants merged with role grants.
+	UserGroupScope []uint64 // User groups the admin may manage; nil means unrestricted.
+}
+
+// ParseRoleIDs parses and normalizes role IDs from JSON.
+func ParseRoleIDs(raw []byte) []uint64 {
+	if len(raw) == 0 {
+		return []uint64{}
+	}
+	var ids []uint64
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return []uint64{}
+	}
+	return NormalizeRoleIDs(ids)
+}
+
+// NormalizeRoleIDs removes zero and duplicate IDs and sorts the rest.
+func NormalizeRoleIDs(ids []uint64) []uint64 {
+	seen := make(map[uint64]struct{}, len(ids))
+	out := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// MarshalRoleIDs serializes normalized role IDs to JSON.
+func MarshalRoleIDs(ids []uint64) ([]byte, error) {
+	return json.Marshal(NormalizeRoleIDs(ids))
+}
+
+// ResolveAccess merges the admin's direct grants with the grants of its roles.
+//
+// The user group scope is the union of role scopes and only applies when every
+// role granting access is scoped and the admin holds no direct grants.
+func ResolveAccess(ctx context.Context, db *gorm.DB, admin models.Admin) (Access, error) {
+	direct := ParsePermissions(admin.Permissions)
+	access := Access{Permissions: direct}
+	if admin.IsSuperAdmin {
+		return access, nil
+	}
+
+	roleIDs := ParseRoleIDs(admin.RoleIDs)
+	if len(roleIDs) == 0 || db == nil {
+		return access, nil
+	}
+	var roles []models.AdminRole
+	if errFind := db.WithContext(ctx).Where("id IN ?", roleIDs).Find(&roles).Error; errFind != nil {
+		return access, errFind
+	}
+
+	merged := append([]string{}, direct...)
+	scoped := len(direct) == 0
+	scopeSeen := make(map[uint64]struct{})
+	scope := make([]uint64, 0)
+	for _, role := range roles {
+		grants := ParsePermissions(role.Permissions)
+		if len(grants) == 0 {
+			continue
+		}
+		merged = append(merged, grants...)
+		groups := role.UserGroupID.Values()
+		if len(groups) == 0 {
+			scoped = false
+			continue
+		}
+		for _, groupID := range groups {
+			if _, ok := scopeSeen[groupID]; ok {
+				continue
+			}
+			scopeSeen[groupID] = struct{}{}
+			scope = append(scope, groupID)
+		}
+	}
+	access.Permissions = NormalizePermissions(merged)
+	if scoped && len(scope) > 0 {
+		sort.Slice(scope, func(i, j int) bool { return scope[i] < scope[j] })
+		access.UserGroupScope = scope
+	}
+	return access, nil
+}
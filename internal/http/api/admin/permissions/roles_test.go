@@ -0,0 +1,111 @@
+package permissions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestHasPermissionMatchesGrants(t *testing.T) {
+	listUsers := Key("GET", "/v0/admin/users")
+	deleteUser := Key("DELETE", "/v0/admin/users/:id")
+
+	cases := []struct {
+		grant string
+		key   string
+		want  bool
+	}{
+		{GrantAll, deleteUser, true},
+		{ModuleGrant("Users"), deleteUser, true},
+		{ModuleReadGrant("Users"), listUsers, true},
+		{ModuleReadGrant("Users"), deleteUser, false},
+		{ModuleGrant("Bills"), listUsers, false},
+		{"* /v0/admin/users*", deleteUser, true},
+		{"GET /v0/admin/users/*", deleteUser, false},
+		{listUsers, listUsers, true},
+	}
+	for _, tc := range cases {
+		if got := HasPermission([]string{tc.grant}, tc.key); got != tc.want {
+			t.Errorf("HasPermission(%q, %q) = %v, want %v", tc.grant, tc.key, got, tc.want)
+		}
+	}
+
+	if errValidate := ValidatePermissions([]string{ModuleGrant("Nope")}); errValidate == nil {
+		t.Fatalf("expected unknown module to be rejected")
+	}
+	if errValidate := ValidatePermissions([]string{ModuleReadGrant("Users"), "GET /v0/admin/bills*"}); errValidate != nil {
+		t.Fatalf("validate grants: %v", errValidate)
+	}
+}
+
+func TestMigrateLegacyPermissionsAndResolveScope(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	now := time.Now().UTC()
+	if errMigrate := conn.AutoMigrate(&models.Admin{}); errMigrate != nil {
+		t.Fatalf("migrate admins: %v", errMigrate)
+	}
+	root := models.Admin{Username: "root", Password: "hashed", Active: true, IsSuperAdmin: true, CreatedAt: now, UpdatedAt: now}
+	if errCreate := conn.Create(&root).Error; errCreate != nil {
+		t.Fatalf("create super admin: %v", errCreate)
+	}
+	legacy := models.Admin{
+		Username:    "alice",
+		Password:    "hashed",
+		Active:      true,
+		Permissions: datatypes.JSON([]byte(`["GET /v0/admin/users"]`)),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if errCreate := conn.Create(&legacy).Error; errCreate != nil {
+		t.Fatalf("create admin: %v", errCreate)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	var migrated models.Admin
+	if errFind := conn.First(&migrated, legacy.ID).Error; errFind != nil {
+		t.Fatalf("reload admin: %v", errFind)
+	}
+	if direct := ParsePermissions(migrated.Permissions); len(direct) != 0 {
+		t.Fatalf("expected direct permissions to be cleared, got %v", direct)
+	}
+	access, errAccess := ResolveAccess(context.Background(), conn, migrated)
+	if errAccess != nil {
+		t.Fatalf("resolve access: %v", errAccess)
+	}
+	if !HasPermission(access.Permissions, Key("GET", "/v0/admin/users")) {
+		t.Fatalf("expected migrated role to keep the legacy permission, got %v", access.Permissions)
+	}
+	if access.UserGroupScope != nil {
+		t.Fatalf("expected unscoped access, got %v", access.UserGroupScope)
+	}
+
+	groupID := uint64(7)
+	role := models.AdminRole{
+		Name:        "scoped-support",
+		Permissions: datatypes.JSON([]byte(`["module:Users"]`)),
+		UserGroupID: models.UserGroupIDs{&groupID},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if errCreate := conn.Create(&role).Error; errCreate != nil {
+		t.Fatalf("create role: %v", errCreate)
+	}
+	roleIDs, _ := MarshalRoleIDs([]uint64{role.ID})
+	scopedAdmin := models.Admin{Username: "bob", RoleIDs: datatypes.JSON(roleIDs)}
+	access, errAccess = ResolveAccess(context.Background(), conn, scopedAdmin)
+	if errAccess != nil {
+		t.Fatalf("resolve scoped access: %v", errAccess)
+	}
+	if len(access.UserGroupScope) != 1 || access.UserGroupScope[0] != groupID {
+		t.Fatalf("expected scope [%d], got %v", groupID, access.UserGroupScope)
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	handlers "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/handlers"
 	permissions "github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/admin/permissions"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 )
 
+// adminAccessKey is the gin context key holding the admin's resolved permissions.Access,
+// so roles are loaded once per request.
+const adminAccessKey = "adminAccess"
+
 // adminPermissionMiddleware enforces permission checks for admin routes.
 func adminPermissionMiddleware(db *gorm.DB) gin.HandlerFunc {
 	permissionMap := permissions.DefinitionMap()
@@ -31,9 +36,9 @@ func adminPermissionMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		adminPermissions, okPermissions := readAdminPermissionsFromContext(c)
+		access, okAccess := readAdminAccessFromContext(c)
 		adminIsSuperAdmin, okSuper := readAdminIsSuperAdminFromContext(c)
-		if !okPermissions || !okSuper {
+		if !okAccess || !okSuper {
 			adminIDValue, exists := c.Get("adminID")
 			if !exists {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
@@ -46,14 +51,20 @@ func adminPermissionMiddleware(db *gorm.DB) gin.HandlerFunc {
 			}
 
 			var admin models.Admin
-			if errFind := db.WithContext(c.Request.Context()).Select("id", "permissions", "is_super_admin").First(&admin, adminID).Error; errFind != nil {
+			if errFind := db.WithContext(c.Request.Context()).Select("id", "permissions", "role_ids", "is_super_admin").First(&admin, adminID).Error; errFind != nil {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin not found"})
 				return
 			}
-			adminPermissions = permissions.ParsePermissions(admin.Permissions)
+			resolved, errAccess := permissions.ResolveAccess(c.Request.Context(), db, admin)
+			if errAccess != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "load admin roles failed"})
+				return
+			}
+			access = resolved
 			adminIsSuperAdmin = admin.IsSuperAdmin
-			c.Set("adminPermissions", adminPermissions)
+			c.Set(adminAccessKey, access)
 			c.Set("adminIsSuperAdmin", adminIsSuperAdmin)
+			c.Set(handlers.AdminUserGroupScopeKey, access.UserGroupScope)
 		}
 
 		if adminIsSuperAdmin {
@@ -61,7 +72,7 @@ func adminPermissionMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		if !permissions.HasPermission(adminPermissions, key) {
+		if !permissions.HasPermission(access.Permissions, key) {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
 			return
 		}
@@ -70,14 +81,14 @@ func adminPermissionMiddleware(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// readAdminPermissionsFromContext extracts permissions from the gin context.
-func readAdminPermissionsFromContext(c *gin.Context) ([]string, bool) {
-	value, ok := c.Get("adminPermissions")
+// readAdminAccessFromContext extracts the access resolved by the auth middleware from the gin context.
+func readAdminAccessFromContext(c *gin.Context) (permissions.Access, bool) {
+	value, ok := c.Get(adminAccessKey)
 	if !ok {
-		return nil, false
+		return permissions.Access{}, false
 	}
-	permissionsList, ok := value.([]string)
-	return permissionsList, ok
+	access, ok := value.(permissions.Access)
+	return access, ok
 }
 
 // readAdminIsSuperAdminFromContext extracts the super admin flag from context.
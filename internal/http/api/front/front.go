@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/http/api/front/handlers"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelregistry"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
@@ -28,15 +29,27 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 	front.POST("/login/totp", authHandler.LoginTOTP)
 	front.POST("/login/passkey/options", authHandler.LoginPasskeyOptions)
 	front.POST("/login/passkey/verify", authHandler.LoginPasskeyVerify)
+	front.POST("/login/recovery", authHandler.LoginRecovery)
 	front.POST("/reset-password", authHandler.ResetPassword)
+	front.POST("/verify-email", authHandler.VerifyEmail)
 	front.GET("/config", handlers.GetPublicConfig)
 
+	ssoHandler := handlers.NewSSOHandler(db, jwtCfg)
+	front.GET("/sso/providers", ssoHandler.Providers)
+	front.GET("/sso/:provider/login", ssoHandler.Login)
+	front.GET("/sso/:provider/callback", ssoHandler.Callback)
+
 	authed := front.Group("")
 	authed.Use(userAuthMiddleware(db, jwtCfg))
 
 	profileHandler := handlers.NewProfileHandler(db)
 	authed.GET("/profile", profileHandler.Get)
 	authed.PUT("/profile/password", profileHandler.ChangePassword)
+	authed.PUT("/profile/usage-report", profileHandler.SetUsageReport)
+	authed.POST("/profile/verify-email/resend", authHandler.ResendVerification)
+	authed.POST("/sso/:provider/link", ssoHandler.Link)
+	authed.GET("/sso/identities", ssoHandler.Identities)
+	authed.DELETE("/sso/identities/:id", ssoHandler.Unlink)
 
 	webAuthn, errWebAuthn := security.NewWebAuthn()
 	if errWebAuthn != nil {
@@ -50,6 +63,10 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 	authed.POST("/mfa/passkey/options", mfaHandler.BeginPasskeyRegistration)
 	authed.POST("/mfa/passkey/verify", mfaHandler.FinishPasskeyRegistration)
 	authed.POST("/mfa/passkey/disable", mfaHandler.DisablePasskey)
+	authed.GET("/mfa/passkeys", mfaHandler.ListPasskeys)
+	authed.PUT("/mfa/passkeys/:id", mfaHandler.RenamePasskey)
+	authed.DELETE("/mfa/passkeys/:id", mfaHandler.DeletePasskey)
+	authed.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
 
 	prepaidHandler := handlers.NewPrepaidCardFrontHandler(db)
 	authed.GET("/prepaid-card", prepaidHandler.GetCurrent)
@@ -63,6 +80,26 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 	authed.POST("/bills", billHandler.Create)
 	authed.GET("/bills", billHandler.List)
 
+	organizationHandler := handlers.NewOrganizationFrontHandler(db)
+	authed.POST("/organizations", organizationHandler.Create)
+	authed.GET("/organization", organizationHandler.Get)
+	authed.PUT("/organization", organizationHandler.Update)
+	authed.DELETE("/organization", organizationHandler.Delete)
+	authed.POST("/organization/leave", organizationHandler.Leave)
+	authed.GET("/organization/members", organizationHandler.ListMembers)
+	authed.PUT("/organization/members/:user_id", organizationHandler.UpdateMember)
+	authed.DELETE("/organization/members/:user_id", organizationHandler.RemoveMember)
+	authed.GET("/organization/invitations", organizationHandler.ListInvitations)
+	authed.POST("/organization/invitations", organizationHandler.CreateInvitation)
+	authed.DELETE("/organization/invitations/:id", organizationHandler.RevokeInvitation)
+	authed.GET("/organization/api-keys", organizationHandler.ListAPIKeys)
+	authed.GET("/organization/bills", organizationHandler.ListBills)
+	authed.GET("/organization/prepaid-cards", organizationHandler.ListPrepaidCards)
+	authed.GET("/organization/usage", organizationHandler.Usage)
+	authed.GET("/organization/logs", organizationHandler.Logs)
+	authed.GET("/organization-invitations", organizationHandler.MyInvitations)
+	authed.POST("/organization-invitations/accept", organizationHandler.AcceptInvitation)
+
 	apiKeyHandler := handlers.NewAPIKeyHandler(db)
 	authed.GET("/api-keys", apiKeyHandler.List)
 	authed.GET("/api-keys/stats", apiKeyHandler.Stats)
@@ -88,6 +125,7 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 
 	logsHandler := handlers.NewLogsHandler(db)
 	authed.GET("/logs", logsHandler.List)
+	authed.GET("/logs/export", logsHandler.Export)
 	authed.GET("/logs/stats", logsHandler.Stats)
 	authed.GET("/logs/trend", logsHandler.Trend)
 	authed.GET("/logs/models", logsHandler.Models)
@@ -95,6 +133,13 @@ func RegisterFrontRoutes(r *gin.Engine, db *gorm.DB, jwtCfg config.JWTConfig, mo
 	authed.GET("/logs/detail", logsHandler.Detail)
 }
 
+// mfaEnrollmentExempt reports whether a route stays reachable before required MFA is enrolled.
+func mfaEnrollmentExempt(route string) bool {
+	return route == "/v0/front/profile" ||
+		strings.HasPrefix(route, "/v0/front/mfa/") ||
+		strings.HasPrefix(route, "/v0/front/sso/")
+}
+
 // userAuthMiddleware validates user JWTs and loads the user into context.
 func userAuthMiddleware(db *gorm.DB, jwtCfg config.JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -130,6 +175,24 @@ func userAuthMiddleware(db *gorm.DB, jwtCfg config.JWTConfig) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user disabled"})
 			return
 		}
+		if !mfaEnrollmentExempt(c.FullPath()) {
+			required, errRequired := mfa.UserRequired(c.Request.Context(), db, user)
+			if errRequired != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+				return
+			}
+			if required {
+				status, errStatus := mfa.LoadStatus(c.Request.Context(), db, models.MFAOwnerUser, user.ID, user.TOTPSecret)
+				if errStatus != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+					return
+				}
+				if !status.Enabled() {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "mfa enrollment required"})
+					return
+				}
+			}
+		}
 
 		c.Set("userID", user.ID)
 		c.Next()
@@ -216,6 +216,9 @@ func (h *APIKeyHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
 		return
 	}
+	if !requireVerifiedEmail(c, h.db, userID) {
+		return
+	}
 
 	token, errGenerate := security.GenerateAPIKey()
 	if errGenerate != nil {
@@ -8,8 +8,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	"gorm.io/gorm"
 )
 
@@ -26,13 +28,20 @@ func NewAuthHandler(db *gorm.DB, jwtCfg config.JWTConfig) *AuthHandler {
 
 // registerRequest defines the request body for user registration.
 type registerRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
 }
 
-// Register creates a new user account.
+// Register creates a new user account according to the registration policy.
 func (h *AuthHandler) Register(c *gin.Context) {
+	policy := internalsettings.LoadRegistrationPolicy()
+	if policy.Mode == internalsettings.RegistrationModeClosed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "registration is closed"})
+		return
+	}
+
 	var body registerRequest
 	if errBind := c.ShouldBindJSON(&body); errBind != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
@@ -48,31 +57,48 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing password"})
 		return
 	}
-
-	var exists models.User
-	if errCheck := h.db.WithContext(c.Request.Context()).Where("username = ?", username).First(&exists).Error; errCheck == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "username already exists"})
+	email := strings.TrimSpace(body.Email)
+	if email == "" && policy.RequiresEmail() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing email"})
+		return
+	}
+	if email != "" && !strings.Contains(email, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+	if email != "" && !policy.EmailDomainAllowed(email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "email domain not allowed"})
+		return
+	}
+	inviteCode := strings.TrimSpace(body.InviteCode)
+	if inviteCode == "" && policy.Mode == internalsettings.RegistrationModeInviteOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invite code required"})
 		return
 	}
 
+	ctx := c.Request.Context()
+	now := time.Now().UTC()
+	clientIP := c.ClientIP()
+
 	hash, errHash := security.HashPassword(password)
 	if errHash != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash password failed"})
 		return
 	}
 
-	now := time.Now().UTC()
 	user := models.User{
-		Username:  username,
-		Email:     strings.TrimSpace(body.Email),
-		Password:  hash,
-		Active:    true,
-		Disabled:  false,
-		CreatedAt: now,
-		UpdatedAt: now,
+		Username:                 username,
+		Email:                    email,
+		Password:                 hash,
+		EmailVerificationPending: policy.RequireEmailVerification && email != "",
+		RegisteredIP:             clientIP,
+		Active:                   true,
+		Disabled:                 false,
+		CreatedAt:                now,
+		UpdatedAt:                now,
 	}
 	var defaultGroup models.UserGroup
-	if errFind := h.db.WithContext(c.Request.Context()).
+	if errFind := h.db.WithContext(ctx).
 		Where("is_default = ?", true).
 		First(&defaultGroup).Error; errFind == nil {
 		user.UserGroupID = models.UserGroupIDs{&defaultGroup.ID}
@@ -80,14 +106,77 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query default user group failed"})
 		return
 	}
-	if errCreate := h.db.WithContext(c.Request.Context()).Create(&user).Error; errCreate != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "create user failed"})
+
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if policy.IPLimitPerHour > 0 && clientIP != "" {
+			if errLock := lockRegistrationIP(tx, clientIP); errLock != nil {
+				return errLock
+			}
+			recent, errCount := countRecentRegistrations(ctx, tx, clientIP, now.Add(-time.Hour))
+			if errCount != nil {
+				return errCount
+			}
+			if recent >= int64(policy.IPLimitPerHour) {
+				return errRegistrationLimited
+			}
+		}
+		if errTaken := checkUserTaken(tx, username, email); errTaken != nil {
+			return errTaken
+		}
+		var invite *models.InviteCode
+		if inviteCode != "" {
+			redeemed, errRedeem := redeemInviteCode(tx, inviteCode, now)
+			if errRedeem != nil {
+				return errRedeem
+			}
+			invite = redeemed
+			user.InviteCodeID = &invite.ID
+			if invite.UserGroupID != nil && *invite.UserGroupID != 0 {
+				groupID := *invite.UserGroupID
+				user.UserGroupID = models.UserGroupIDs{&groupID}
+			}
+		}
+		if errCreate := tx.Create(&user).Error; errCreate != nil {
+			return userUniqueViolation(errCreate)
+		}
+		if !user.EmailVerificationPending {
+			if errBonus := grantSignupBonus(tx, user.ID, policy, now); errBonus != nil {
+				return errBonus
+			}
+		}
+		if invite != nil {
+			if errCredit := grantStarterCredit(tx, user.ID, "Invite credit", invite.CreditAmount, invite.CreditValidDays, now); errCredit != nil {
+				return errCredit
+			}
+		}
+		return nil
+	})
+	if errTx != nil {
+		switch {
+		case errors.Is(errTx, errInviteCodeInvalid):
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid invite code"})
+		case errors.Is(errTx, errRegistrationLimited):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many registrations, try again later"})
+		case errors.Is(errTx, errUsernameTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "username already exists"})
+		case errors.Is(errTx, errEmailTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "create user failed"})
+		}
 		return
 	}
+
+	if user.EmailVerificationPending {
+		if errSend := sendEmailVerification(ctx, h.db, &user); errSend != nil {
+			logVerificationSendError(user.ID, errSend)
+		}
+	}
 	c.JSON(http.StatusCreated, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
+		"id":                          user.ID,
+		"username":                    user.Username,
+		"email":                       user.Email,
+		"email_verification_required": user.EmailVerificationPending,
 	})
 }
 
@@ -131,7 +220,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	if strings.TrimSpace(user.TOTPSecret) != "" || len(user.PasskeyID) > 0 || len(user.PasskeyPublicKey) > 0 {
+	status, errStatus := mfa.LoadStatus(c.Request.Context(), h.db, models.MFAOwnerUser, user.ID, user.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if status.Enabled() {
 		c.JSON(http.StatusForbidden, gin.H{"error": "mfa required"})
 		return
 	}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"errors"
 	"net/http"
 	"strconv"
@@ -9,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -26,7 +26,8 @@ func NewBillFrontHandler(db *gorm.DB) *BillFrontHandler {
 
 // createBillFrontRequest defines the request body for creating bills.
 type createBillFrontRequest struct {
-	PlanID uint64 `json:"plan_id"`
+	PlanID         uint64  `json:"plan_id"`
+	OrganizationID *uint64 `json:"organization_id"` // Purchase for this organization from its shared balance.
 }
 
 // Create purchases a plan using prepaid balance and creates a bill.
@@ -49,6 +50,15 @@ func (h *BillFrontHandler) Create(c *gin.Context) {
 		return
 	}
 
+	payer := billing.Payer{UserID: userID}
+	if body.OrganizationID != nil {
+		if _, errManager := loadOrganizationManager(c.Request.Context(), h.db, userID, *body.OrganizationID); errManager != nil {
+			writeOrganizationError(c, errManager)
+			return
+		}
+		payer = billing.OrganizationPayer(userID, *body.OrganizationID)
+	}
+
 	var plan models.Plan
 	if errFindPlan := h.db.WithContext(c.Request.Context()).
 		Where("id = ? AND is_enabled = ?", body.PlanID, true).
@@ -67,9 +77,9 @@ func (h *BillFrontHandler) Create(c *gin.Context) {
 	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
 		now := time.Now().UTC()
 		var cards []models.PrepaidCard
-		if errCards := tx.WithContext(c.Request.Context()).
+		if errCards := payer.ScopePrepaidCards(tx.WithContext(c.Request.Context())).
 			Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
+			Where("is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", true).
 			Where("(expires_at IS NULL OR expires_at >= ?)", now).
 			Order("expires_at ASC NULLS LAST, redeemed_at ASC NULLS LAST, id ASC").
 			Find(&cards).Error; errCards != nil {
@@ -108,28 +118,29 @@ func (h *BillFrontHandler) Create(c *gin.Context) {
 
 		periodEnd := now.AddDate(0, 1, 0)
 		bill := models.Bill{
-			PlanID:      plan.ID,
-			UserID:      userID,
-			UserGroupID: plan.UserGroupID.Clean(),
-			PeriodType:  models.BillPeriodTypeMonthly,
-			Amount:      requiredAmount,
-			PeriodStart: now,
-			PeriodEnd:   periodEnd,
-			TotalQuota:  plan.TotalQuota,
-			DailyQuota:  plan.DailyQuota,
-			UsedQuota:   0,
-			LeftQuota:   plan.TotalQuota,
-			UsedCount:   0,
-			RateLimit:   plan.RateLimit,
-			IsEnabled:   true,
-			Status:      models.BillStatusPaid,
-			CreatedAt:   now,
-			UpdatedAt:   now,
+			PlanID:         plan.ID,
+			UserID:         userID,
+			OrganizationID: payer.OrganizationID,
+			UserGroupID:    plan.UserGroupID.Clean(),
+			PeriodType:     models.BillPeriodTypeMonthly,
+			Amount:         requiredAmount,
+			PeriodStart:    now,
+			PeriodEnd:      periodEnd,
+			TotalQuota:     plan.TotalQuota,
+			DailyQuota:     plan.DailyQuota,
+			UsedQuota:      0,
+			LeftQuota:      plan.TotalQuota,
+			UsedCount:      0,
+			RateLimit:      plan.RateLimit,
+			IsEnabled:      true,
+			Status:         models.BillStatusPaid,
+			CreatedAt:      now,
+			UpdatedAt:      now,
 		}
 		if errCreateBill := tx.WithContext(c.Request.Context()).Create(&bill).Error; errCreateBill != nil {
 			return errCreateBill
 		}
-		if errRefresh := refreshBillUserGroupIDs(c.Request.Context(), tx, userID); errRefresh != nil {
+		if errRefresh := billing.RefreshBillUserGroupIDs(c.Request.Context(), tx, payer); errRefresh != nil {
 			return errRefresh
 		}
 		created = bill
@@ -148,50 +159,6 @@ func (h *BillFrontHandler) Create(c *gin.Context) {
 	c.JSON(http.StatusCreated, h.formatBill(&created))
 }
 
-func refreshBillUserGroupIDs(ctx context.Context, tx *gorm.DB, userID uint64) error {
-	if tx == nil {
-		return errors.New("nil tx")
-	}
-	if userID == 0 {
-		return errors.New("empty user id")
-	}
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	now := time.Now().UTC()
-	var bills []models.Bill
-	if errFind := tx.WithContext(ctx).
-		Model(&models.Bill{}).
-		Select("user_group_id").
-		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
-		Where("period_start <= ? AND period_end >= ?", now, now).
-		Find(&bills).Error; errFind != nil {
-		return errFind
-	}
-
-	seen := make(map[uint64]struct{})
-	merged := make(models.UserGroupIDs, 0)
-	for _, bill := range bills {
-		for _, gid := range bill.UserGroupID.Clean() {
-			if gid == nil || *gid == 0 {
-				continue
-			}
-			if _, ok := seen[*gid]; ok {
-				continue
-			}
-			seen[*gid] = struct{}{}
-			idCopy := *gid
-			merged = append(merged, &idCopy)
-		}
-	}
-
-	return tx.WithContext(ctx).
-		Model(&models.User{}).
-		Where("id = ?", userID).
-		Update("bill_user_group_id", merged.Clean()).Error
-}
-
 // List returns bills for the authenticated user with filters.
 func (h *BillFrontHandler) List(c *gin.Context) {
 	userID := getUserID(c)
@@ -207,7 +174,7 @@ func (h *BillFrontHandler) List(c *gin.Context) {
 
 	q := h.db.WithContext(c.Request.Context()).
 		Model(&models.Bill{}).
-		Where("user_id = ?", userID)
+		Where("user_id = ? AND organization_id IS NULL", userID)
 
 	if planIDQ != "" {
 		if id, errParse := strconv.ParseUint(planIDQ, 10, 64); errParse == nil {
@@ -250,22 +217,23 @@ func (h *BillFrontHandler) List(c *gin.Context) {
 // formatBill converts a bill model to a response payload.
 func (h *BillFrontHandler) formatBill(bill *models.Bill) gin.H {
 	return gin.H{
-		"id":           bill.ID,
-		"plan_id":      bill.PlanID,
-		"user_id":      bill.UserID,
-		"period_type":  bill.PeriodType,
-		"amount":       bill.Amount,
-		"period_start": bill.PeriodStart,
-		"period_end":   bill.PeriodEnd,
-		"total_quota":  bill.TotalQuota,
-		"daily_quota":  bill.DailyQuota,
-		"used_quota":   bill.UsedQuota,
-		"left_quota":   bill.LeftQuota,
-		"used_count":   bill.UsedCount,
-		"rate_limit":   bill.RateLimit,
-		"is_enabled":   bill.IsEnabled,
-		"status":       bill.Status,
-		"created_at":   bill.CreatedAt,
-		"updated_at":   bill.UpdatedAt,
+		"id":              bill.ID,
+		"plan_id":         bill.PlanID,
+		"user_id":         bill.UserID,
+		"organization_id": bill.OrganizationID,
+		"period_type":     bill.PeriodType,
+		"amount":          bill.Amount,
+		"period_start":    bill.PeriodStart,
+		"period_end":      bill.PeriodEnd,
+		"total_quota":     bill.TotalQuota,
+		"daily_quota":     bill.DailyQuota,
+		"used_quota":      bill.UsedQuota,
+		"left_quota":      bill.LeftQuota,
+		"used_count":      bill.UsedCount,
+		"rate_limit":      bill.RateLimit,
+		"is_enabled":      bill.IsEnabled,
+		"status":          bill.Status,
+		"created_at":      bill.CreatedAt,
+		"updated_at":      bill.UpdatedAt,
 	}
 }
@@ -12,7 +12,9 @@ import (
 
 // publicConfigResponse is the response payload for public config.
 type publicConfigResponse struct {
-	SiteName string `json:"site_name"`
+	SiteName                  string `json:"site_name"`
+	RegistrationMode          string `json:"registration_mode"`
+	EmailVerificationRequired bool   `json:"email_verification_required"`
 }
 
 // GetPublicConfig returns public configuration for the front UI.
@@ -21,7 +23,12 @@ func GetPublicConfig(c *gin.Context) {
 	if siteName == "" {
 		siteName = internalsettings.DefaultSiteName
 	}
-	c.JSON(http.StatusOK, publicConfigResponse{SiteName: siteName})
+	policy := internalsettings.LoadRegistrationPolicy()
+	c.JSON(http.StatusOK, publicConfigResponse{
+		SiteName:                  siteName,
+		RegistrationMode:          policy.Mode,
+		EmailVerificationRequired: policy.RequireEmailVerification,
+	})
 }
 
 // dbConfigString reads a string value from the DB config snapshot.
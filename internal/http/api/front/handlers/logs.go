@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/export"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
 	"gorm.io/gorm"
 )
 
@@ -30,6 +32,8 @@ type logsListQuery struct {
 	EndDate   string `form:"end_date"`
 	Project   string `form:"project"`
 	Model     string `form:"model"`
+
+	requestlog.Filter
 }
 
 // logEntry defines an aggregated log entry response.
@@ -70,76 +74,14 @@ func (h *LogsHandler) List(c *gin.Context) {
 		q.Limit = 20
 	}
 
-	ctx := c.Request.Context()
-
-	query := h.db.WithContext(ctx).Model(&models.Usage{}).Where("user_id = ?", userID)
-
-	if q.StartDate != "" {
-		if startTime, errParse := time.ParseInLocation("2006-01-02", q.StartDate, time.Local); errParse == nil {
-			query = query.Where("requested_at >= ?", startTime)
-		}
-	}
-	if q.EndDate != "" {
-		if endTime, errParse := time.ParseInLocation("2006-01-02", q.EndDate, time.Local); errParse == nil {
-			query = query.Where("requested_at < ?", endTime.AddDate(0, 0, 1))
-		}
-	}
-	if q.Project != "" {
-		query = query.Where("source = ?", q.Project)
-	}
-	if q.Model != "" {
-		query = query.Where("model = ?", q.Model)
-	}
-
-	// dailyAgg holds aggregated usage per day/model.
-	type dailyAgg struct {
-		Date         string
-		Model        string
-		Providers    string
-		Requests     int64
-		InputTokens  int64
-		OutputTokens int64
-		TotalTokens  int64
-		CostMicros   int64
-		FailedCount  int64
-	}
-
 	var total int64
-	countQuery := h.db.WithContext(ctx).Model(&models.Usage{}).Where("user_id = ?", userID)
-	if q.StartDate != "" {
-		if startTime, errParse := time.ParseInLocation("2006-01-02", q.StartDate, time.Local); errParse == nil {
-			countQuery = countQuery.Where("requested_at >= ?", startTime)
-		}
-	}
-	if q.EndDate != "" {
-		if endTime, errParse := time.ParseInLocation("2006-01-02", q.EndDate, time.Local); errParse == nil {
-			countQuery = countQuery.Where("requested_at < ?", endTime.AddDate(0, 0, 1))
-		}
-	}
-	if q.Project != "" {
-		countQuery = countQuery.Where("source = ?", q.Project)
-	}
-	if q.Model != "" {
-		countQuery = countQuery.Where("model = ?", q.Model)
-	}
-	countQuery.Select("COUNT(DISTINCT TO_CHAR(requested_at, 'YYYY-MM-DD') || model)").Scan(&total)
+	h.applyListFilters(c, userID, q).
+		Select("COUNT(DISTINCT TO_CHAR(requested_at, 'YYYY-MM-DD') || model)").
+		Scan(&total)
 
 	offset := (q.Page - 1) * q.Limit
-	var aggs []dailyAgg
-	if errAgg := query.
-		Select(`
-			TO_CHAR(requested_at, 'YYYY-MM-DD') AS date,
-			model,
-			COALESCE(STRING_AGG(DISTINCT NULLIF(provider, ''), ','), '') AS providers,
-			COUNT(*) AS requests,
-			COALESCE(SUM(input_tokens), 0) AS input_tokens,
-			COALESCE(SUM(output_tokens), 0) AS output_tokens,
-			COALESCE(SUM(total_tokens), 0) AS total_tokens,
-			COALESCE(SUM(cost_micros), 0) AS cost_micros,
-			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count
-		`).
-		Group("TO_CHAR(requested_at, 'YYYY-MM-DD'), model").
-		Order("TO_CHAR(requested_at, 'YYYY-MM-DD') DESC, model").
+	var aggs []logAgg
+	if errAgg := h.dailyAggQuery(c, userID, q).
 		Offset(offset).Limit(q.Limit).
 		Scan(&aggs).Error; errAgg != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query logs failed"})
@@ -192,6 +134,95 @@ func (h *LogsHandler) List(c *gin.Context) {
 	})
 }
 
+// logAgg holds aggregated usage per day/model.
+type logAgg struct {
+	Date         string
+	Model        string
+	Providers    string
+	Requests     int64
+	InputTokens  int64
+	OutputTokens int64
+	TotalTokens  int64
+	CostMicros   int64
+	FailedCount  int64
+}
+
+// Export streams every aggregated log row of the user matching the list filters as CSV or XLSX.
+func (h *LogsHandler) Export(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var q logsListQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	format, errFormat := export.ParseFormat(c.Query("format"))
+	if errFormat != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+	export.Serve(c, h.dailyAggQuery(c, userID, q), format, logExportTable)
+}
+
+// logExportTable describes the columns of a log export.
+var logExportTable = export.Table[logAgg]{
+	Name: "logs",
+	Header: []string{
+		"date", "model", "providers", "requests", "input_tokens", "output_tokens", "total_tokens",
+		"cost_micros", "failed_count",
+	},
+	Row: func(a *logAgg) []any {
+		return []any{
+			a.Date, a.Model, a.Providers, a.Requests, a.InputTokens, a.OutputTokens, a.TotalTokens,
+			a.CostMicros, a.FailedCount,
+		}
+	},
+}
+
+// applyListFilters builds the user's usage query filtered by date range, project and model.
+func (h *LogsHandler) applyListFilters(c *gin.Context, userID uint64, q logsListQuery) *gorm.DB {
+	query := h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).Where("user_id = ?", userID)
+	if q.StartDate != "" {
+		if startTime, errParse := time.ParseInLocation("2006-01-02", q.StartDate, time.Local); errParse == nil {
+			query = query.Where("requested_at >= ?", startTime)
+		}
+	}
+	if q.EndDate != "" {
+		if endTime, errParse := time.ParseInLocation("2006-01-02", q.EndDate, time.Local); errParse == nil {
+			query = query.Where("requested_at < ?", endTime.AddDate(0, 0, 1))
+		}
+	}
+	if q.Project != "" {
+		query = query.Where("source = ?", q.Project)
+	}
+	if q.Model != "" {
+		query = query.Where("model = ?", q.Model)
+	}
+	return q.Filter.Apply(query)
+}
+
+// dailyAggQuery aggregates the user's filtered usage per date and model, newest first.
+func (h *LogsHandler) dailyAggQuery(c *gin.Context, userID uint64, q logsListQuery) *gorm.DB {
+	return h.applyListFilters(c, userID, q).
+		Select(`
+			TO_CHAR(requested_at, 'YYYY-MM-DD') AS date,
+			model,
+			COALESCE(STRING_AGG(DISTINCT NULLIF(provider, ''), ','), '') AS providers,
+			COUNT(*) AS requests,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cost_micros), 0) AS cost_micros,
+			SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count
+		`).
+		Group("TO_CHAR(requested_at, 'YYYY-MM-DD'), model").
+		Order("TO_CHAR(requested_at, 'YYYY-MM-DD') DESC, model")
+}
+
 // Stats returns high-level usage statistics for today vs yesterday.
 func (h *LogsHandler) Stats(c *gin.Context) {
 	userID := getUserID(c)
@@ -406,10 +437,12 @@ func calcChange(current, previous int64) float64 {
 
 // logDetailQuery defines query parameters for log detail retrieval.
 type logDetailQuery struct {
-	Date     string `form:"date" binding:"required"`
+	Date     string `form:"date"` // Optional when looking up a request ID.
 	Model    string `form:"model"`
 	Provider string `form:"provider"`
 	Project  string `form:"project"`
+
+	requestlog.Filter
 }
 
 // logDetailEntry defines a detailed usage record.
@@ -421,6 +454,16 @@ type logDetailEntry struct {
 	TotalTokens  int64     `json:"total_tokens"`
 	CostMicros   int64     `json:"cost_micros"`
 	Failed       bool      `json:"failed"`
+
+	RequestID          string `json:"request_id"`
+	UpstreamStatus     int    `json:"upstream_status"`
+	ErrorCode          string `json:"error_code"`
+	ErrorMessage       string `json:"error_message"`
+	TimeToFirstTokenMs int64  `json:"time_to_first_token_ms"`
+	DurationMs         int64  `json:"duration_ms"`
+	Streaming          bool   `json:"streaming"`
+	ClientIP           string `json:"client_ip"`
+	UserAgent          string `json:"user_agent"`
 }
 
 // Detail returns raw usage details for a given day and filters.
@@ -438,23 +481,24 @@ func (h *LogsHandler) Detail(c *gin.Context) {
 	}
 
 	dateKey := strings.TrimSpace(q.Date)
-	if dateKey == "" {
+	if dateKey == "" && strings.TrimSpace(q.RequestID) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing date"})
 		return
 	}
-	day, errParse := time.ParseInLocation("2006-01-02", dateKey, time.Local)
-	if errParse != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
-		return
-	}
-	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
-	end := start.AddDate(0, 0, 1)
 
 	ctx := c.Request.Context()
 	query := h.db.WithContext(ctx).
 		Model(&models.Usage{}).
-		Where("user_id = ?", userID).
-		Where("requested_at >= ? AND requested_at < ?", start, end)
+		Where("user_id = ?", userID)
+	if dateKey != "" {
+		day, errParse := time.ParseInLocation("2006-01-02", dateKey, time.Local)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			return
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+		query = query.Where("requested_at >= ? AND requested_at < ?", start, start.AddDate(0, 0, 1))
+	}
 
 	if strings.TrimSpace(q.Model) != "" {
 		query = query.Where("model = ?", strings.TrimSpace(q.Model))
@@ -465,10 +509,13 @@ func (h *LogsHandler) Detail(c *gin.Context) {
 	if strings.TrimSpace(q.Project) != "" {
 		query = query.Where("source = ?", strings.TrimSpace(q.Project))
 	}
+	query = q.Filter.Apply(query)
 
 	var rows []logDetailEntry
 	if errFind := query.
-		Select("requested_at, input_tokens, output_tokens, cached_tokens, total_tokens, cost_micros, failed").
+		Select("requested_at, input_tokens, output_tokens, cached_tokens, total_tokens, cost_micros, failed, " +
+			"request_id, upstream_status, error_code, error_message, time_to_first_token_ms, duration_ms, " +
+			"streaming, client_ip, user_agent").
 		Order("requested_at DESC").
 		Find(&rows).Error; errFind != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query details failed"})
@@ -485,6 +532,16 @@ func (h *LogsHandler) Detail(c *gin.Context) {
 			"total_tokens":  row.TotalTokens,
 			"cost":          fmt.Sprintf("$%.4f", float64(row.CostMicros)/1_000_000),
 			"success":       !row.Failed,
+
+			"request_id":             row.RequestID,
+			"upstream_status":        row.UpstreamStatus,
+			"error_code":             row.ErrorCode,
+			"error_message":          row.ErrorMessage,
+			"time_to_first_token_ms": row.TimeToFirstTokenMs,
+			"duration_ms":            row.DurationMs,
+			"streaming":              row.Streaming,
+			"client_ip":              row.ClientIP,
+			"user_agent":             row.UserAgent,
 		})
 	}
 
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"image/png"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +18,7 @@ import (
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/pquerna/otp/totp"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mfa"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
 	log "github.com/sirupsen/logrus"
@@ -176,36 +179,13 @@ func (u userWebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
 	return u.credentials
 }
 
-// newUserWebAuthnUser builds a WebAuthn adapter from a user model.
-func newUserWebAuthnUser(user models.User) userWebAuthnUser {
-	out := userWebAuthnUser{
-		id:       user.ID,
-		username: user.Username,
+// newUserWebAuthnUser builds a WebAuthn adapter from a user model and its passkeys.
+func newUserWebAuthnUser(user models.User, passkeys []models.PasskeyCredential) userWebAuthnUser {
+	return userWebAuthnUser{
+		id:          user.ID,
+		username:    user.Username,
+		credentials: mfa.WebAuthnCredentials(passkeys),
 	}
-	if len(user.PasskeyID) > 0 && len(user.PasskeyPublicKey) > 0 {
-		signCount := uint32(0)
-		if user.PasskeySignCount != nil {
-			signCount = *user.PasskeySignCount
-		}
-		flags := webauthn.CredentialFlags{}
-		if user.PasskeyBackupEligible != nil {
-			flags.BackupEligible = *user.PasskeyBackupEligible
-		}
-		if user.PasskeyBackupState != nil {
-			flags.BackupState = *user.PasskeyBackupState
-		}
-		out.credentials = []webauthn.Credential{
-			{
-				ID:        user.PasskeyID,
-				PublicKey: user.PasskeyPublicKey,
-				Flags:     flags,
-				Authenticator: webauthn.Authenticator{
-					SignCount: signCount,
-				},
-			},
-		}
-	}
-	return out
 }
 
 // readUserIDFromContext returns the user ID from request context.
@@ -217,30 +197,77 @@ func readUserIDFromContext(c *gin.Context) (uint64, bool) {
 	return userID, true
 }
 
-// Status returns MFA enablement status for the user.
-func (h *MFAHandler) Status(c *gin.Context) {
+// loadUser loads the current user for an MFA endpoint, writing an error response on failure.
+func (h *MFAHandler) loadUser(c *gin.Context) (*models.User, bool) {
 	userID, ok := readUserIDFromContext(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
-		return
+		return nil, false
 	}
-
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).Select("id", "totp_secret", "passkey_id", "passkey_public_key").First(&user, userID).Error; errFind != nil {
-		if errFind == gorm.ErrRecordNotFound {
+	if errFind := h.db.WithContext(c.Request.Context()).First(&user, userID).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-			return
+			return nil, false
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// allowFactorRemoval rejects removing the last MFA factor when a user group requires MFA.
+func (h *MFAHandler) allowFactorRemoval(c *gin.Context, user *models.User, removeTOTP bool, removePasskeys int) bool {
+	ctx := c.Request.Context()
+	required, errRequired := mfa.UserRequired(ctx, h.db, *user)
+	if errRequired != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return false
+	}
+	if !required {
+		return true
+	}
+	status, errStatus := mfa.LoadStatus(ctx, h.db, models.MFAOwnerUser, user.ID, user.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return false
+	}
+	if removeTOTP {
+		status.TOTPEnabled = false
+	}
+	status.Passkeys -= removePasskeys
+	if !status.Enabled() {
+		c.JSON(http.StatusConflict, gin.H{"error": "mfa is required for your account"})
+		return false
+	}
+	return true
+}
+
+// Status returns MFA enablement status for the user.
+func (h *MFAHandler) Status(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
 		return
 	}
 
-	totpEnabled := strings.TrimSpace(user.TOTPSecret) != ""
-	passkeyEnabled := len(user.PasskeyID) > 0 && len(user.PasskeyPublicKey) > 0
+	ctx := c.Request.Context()
+	status, errStatus := mfa.LoadStatus(ctx, h.db, models.MFAOwnerUser, user.ID, user.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	required, errRequired := mfa.UserRequired(ctx, h.db, *user)
+	if errRequired != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"totp_enabled":    totpEnabled,
-		"passkey_enabled": passkeyEnabled,
+		"totp_enabled":             status.TOTPEnabled,
+		"passkey_enabled":          status.Passkeys > 0,
+		"passkey_count":            status.Passkeys,
+		"recovery_codes_remaining": status.RecoveryCodesRemaining,
+		"mfa_required":             required,
 	})
 }
 
@@ -286,6 +313,8 @@ func (h *MFAHandler) PrepareTOTP(c *gin.Context) {
 	})
 }
 
+// totpConfirmRequest defines the request body for confirming TOTP.
+
 // totpConfirmRequest defines the request body for confirming TOTP.
 type totpConfirmRequest struct {
 	Code string `json:"code"`
@@ -320,7 +349,8 @@ func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
 		return
 	}
 
-	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+	ctx := c.Request.Context()
+	if errUpdate := h.db.WithContext(ctx).Model(&models.User{}).
 		Where("id = ?", userID).
 		Updates(map[string]any{"totp_secret": secret, "updated_at": time.Now().UTC()}).Error; errUpdate != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
@@ -328,19 +358,25 @@ func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
 	}
 
 	totpPendingSecrets.Delete(fmt.Sprintf("%d", userID))
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	codes, errCodes := mfa.EnsureRecoveryCodes(ctx, h.db, models.MFAOwnerUser, userID)
+	if errCodes != nil {
+		log.WithError(errCodes).Warn("mfa: issue recovery codes failed")
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "recovery_codes": codes})
 }
 
 // DisableTOTP removes the user's TOTP secret.
 func (h *MFAHandler) DisableTOTP(c *gin.Context) {
-	userID, ok := readUserIDFromContext(c)
+	user, ok := h.loadUser(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	if !h.allowFactorRemoval(c, user, true, 0) {
 		return
 	}
 
 	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
-		Where("id = ?", userID).
+		Where("id = ?", user.ID).
 		Updates(map[string]any{
 			"totp_secret": "",
 			"updated_at":  time.Now().UTC(),
@@ -354,39 +390,134 @@ func (h *MFAHandler) DisableTOTP(c *gin.Context) {
 		return
 	}
 
-	totpPendingSecrets.Delete(fmt.Sprintf("%d", userID))
+	totpPendingSecrets.Delete(fmt.Sprintf("%d", user.ID))
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
-// DisablePasskey removes the user's passkey credentials.
+// DisablePasskey removes all of the user's passkeys.
 func (h *MFAHandler) DisablePasskey(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerUser, user.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if !h.allowFactorRemoval(c, user, false, len(passkeys)) {
+		return
+	}
+
+	if errDelete := mfa.DeleteAllPasskeys(ctx, h.db, models.MFAOwnerUser, user.ID); errDelete != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	passkeyRegistrationSessions.Delete(fmt.Sprintf("%d", user.ID))
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ListPasskeys returns the user's registered passkeys.
+func (h *MFAHandler) ListPasskeys(c *gin.Context) {
 	userID, ok := readUserIDFromContext(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
+	passkeys, errList := mfa.ListPasskeys(c.Request.Context(), h.db, models.MFAOwnerUser, userID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		out = append(out, formatPasskey(passkey))
+	}
+	c.JSON(http.StatusOK, gin.H{"passkeys": out})
+}
 
-	res := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
-		Where("id = ?", userID).
-		Updates(map[string]any{
-			"passkey_id":              nil,
-			"passkey_public_key":      nil,
-			"passkey_sign_count":      nil,
-			"passkey_backup_eligible": nil,
-			"passkey_backup_state":    nil,
-			"updated_at":              time.Now().UTC(),
-		})
-	if res.Error != nil {
+// renamePasskeyRequest defines the request body for renaming a passkey.
+type renamePasskeyRequest struct {
+	Name string `json:"name"`
+}
+
+// RenamePasskey changes the label of one of the user's passkeys.
+func (h *MFAHandler) RenamePasskey(c *gin.Context) {
+	userID, ok := readUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body renamePasskeyRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	if errRename := mfa.RenamePasskey(c.Request.Context(), h.db, models.MFAOwnerUser, userID, id, body.Name); errRename != nil {
+		if errors.Is(errRename, mfa.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// DeletePasskey removes one of the user's passkeys.
+func (h *MFAHandler) DeletePasskey(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
 		return
 	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if !h.allowFactorRemoval(c, user, false, 1) {
+		return
+	}
+	if errDelete := mfa.DeletePasskey(c.Request.Context(), h.db, models.MFAOwnerUser, user.ID, id); errDelete != nil {
+		if errors.Is(errDelete, mfa.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
 
-	passkeyRegistrationSessions.Delete(fmt.Sprintf("%d", userID))
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+// RegenerateRecoveryCodes replaces the user's recovery codes.
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	status, errStatus := mfa.LoadStatus(ctx, h.db, models.MFAOwnerUser, user.ID, user.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if !status.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mfa not enabled"})
+		return
+	}
+	codes, errCodes := mfa.GenerateRecoveryCodes(ctx, h.db, models.MFAOwnerUser, user.ID)
+	if errCodes != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "generate recovery codes failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
 }
 
 // BeginPasskeyRegistration starts a passkey registration ceremony.
@@ -403,10 +534,9 @@ func (h *MFAHandler) BeginPasskeyRegistration(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "passkey_id", "passkey_public_key", "passkey_sign_count", "passkey_backup_eligible", "passkey_backup_state").
-		First(&user, userID).Error; errFind != nil {
+	if errFind := h.db.WithContext(ctx).Select("id", "username").First(&user, userID).Error; errFind != nil {
 		if errFind == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -414,8 +544,13 @@ func (h *MFAHandler) BeginPasskeyRegistration(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerUser, user.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 
-	webauthnUser := newUserWebAuthnUser(user)
+	webauthnUser := newUserWebAuthnUser(user, passkeys)
 	options := []webauthn.RegistrationOption{
 		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
 		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
@@ -436,7 +571,7 @@ func (h *MFAHandler) BeginPasskeyRegistration(c *gin.Context) {
 	c.JSON(http.StatusOK, creation)
 }
 
-// FinishPasskeyRegistration completes a passkey registration ceremony.
+// FinishPasskeyRegistration completes a passkey registration ceremony; the name query labels the passkey.
 func (h *MFAHandler) FinishPasskeyRegistration(c *gin.Context) {
 	webAuthn, errWebAuthn := loadWebAuthn()
 	if errWebAuthn != nil {
@@ -450,10 +585,9 @@ func (h *MFAHandler) FinishPasskeyRegistration(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "passkey_id", "passkey_public_key", "passkey_sign_count").
-		First(&user, userID).Error; errFind != nil {
+	if errFind := h.db.WithContext(ctx).Select("id", "username").First(&user, userID).Error; errFind != nil {
 		if errFind == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
@@ -461,6 +595,11 @@ func (h *MFAHandler) FinishPasskeyRegistration(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
 	}
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerUser, user.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 
 	session, ok := passkeyRegistrationSessions.Get(fmt.Sprintf("%d", user.ID))
 	if !ok {
@@ -468,30 +607,35 @@ func (h *MFAHandler) FinishPasskeyRegistration(c *gin.Context) {
 		return
 	}
 
-	webauthnUser := newUserWebAuthnUser(user)
+	webauthnUser := newUserWebAuthnUser(user, passkeys)
 	credential, err := webAuthn.FinishRegistration(webauthnUser, session, c.Request)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "registration failed"})
 		return
 	}
 
-	signCount := uint32(credential.Authenticator.SignCount)
-	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
-		Where("id = ?", userID).
-		Updates(map[string]any{
-			"passkey_id":              credential.ID,
-			"passkey_public_key":      credential.PublicKey,
-			"passkey_sign_count":      signCount,
-			"passkey_backup_eligible": credential.Flags.BackupEligible,
-			"passkey_backup_state":    credential.Flags.BackupState,
-			"updated_at":              time.Now().UTC(),
-		}).Error; errUpdate != nil {
+	passkey, errAdd := mfa.AddPasskey(ctx, h.db, models.MFAOwnerUser, user.ID, c.Query("name"), credential)
+	if errAdd != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
 
 	passkeyRegistrationSessions.Delete(fmt.Sprintf("%d", user.ID))
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	codes, errCodes := mfa.EnsureRecoveryCodes(ctx, h.db, models.MFAOwnerUser, user.ID)
+	if errCodes != nil {
+		log.WithError(errCodes).Warn("mfa: issue recovery codes failed")
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "passkey": formatPasskey(*passkey), "recovery_codes": codes})
+}
+
+// formatPasskey renders a passkey without its key material.
+func formatPasskey(passkey models.PasskeyCredential) gin.H {
+	return gin.H{
+		"id":           passkey.ID,
+		"name":         passkey.Name,
+		"last_used_at": passkey.LastUsedAt,
+		"created_at":   passkey.CreatedAt,
+	}
 }
 
 // LoginPrepare returns MFA status prior to login.
@@ -508,9 +652,10 @@ func (h *AuthHandler) LoginPrepare(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "disabled", "totp_secret", "passkey_id", "passkey_public_key").
+	if errFind := h.db.WithContext(ctx).
+		Select("id", "username", "disabled", "totp_secret").
 		Where("username = ?", username).
 		First(&user).Error; errFind != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
@@ -521,14 +666,17 @@ func (h *AuthHandler) LoginPrepare(c *gin.Context) {
 		return
 	}
 
-	totpEnabled := strings.TrimSpace(user.TOTPSecret) != ""
-	passkeyEnabled := len(user.PasskeyID) > 0 && len(user.PasskeyPublicKey) > 0
-	mfaEnabled := totpEnabled || passkeyEnabled
+	status, errStatus := mfa.LoadStatus(ctx, h.db, models.MFAOwnerUser, user.ID, user.TOTPSecret)
+	if errStatus != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"mfa_enabled":     mfaEnabled,
-		"totp_enabled":    totpEnabled,
-		"passkey_enabled": passkeyEnabled,
+		"mfa_enabled":     status.Enabled(),
+		"totp_enabled":    status.TOTPEnabled,
+		"passkey_enabled": status.Passkeys > 0,
+		"recovery_codes":  status.RecoveryCodesRemaining > 0,
 	})
 }
 
@@ -575,6 +723,55 @@ func (h *AuthHandler) LoginTOTP(c *gin.Context) {
 	h.respondWithUserToken(c, user)
 }
 
+// loginRecoveryRequest defines the request body for recovery code login.
+type loginRecoveryRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// LoginRecovery authenticates a user with their password and a one-time recovery code.
+func (h *AuthHandler) LoginRecovery(c *gin.Context) {
+	var body loginRecoveryRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	username := strings.TrimSpace(body.Username)
+	password := strings.TrimSpace(body.Password)
+	code := strings.TrimSpace(body.Code)
+	if username == "" || password == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username, password and code are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var user models.User
+	if errFind := h.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; errFind != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user disabled"})
+		return
+	}
+	if !security.CheckPassword(user.Password, password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	consumed, errConsume := mfa.ConsumeRecoveryCode(ctx, h.db, models.MFAOwnerUser, user.ID, code)
+	if errConsume != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if !consumed {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	h.respondWithUserToken(c, user)
+}
+
 // loginPasskeyRequest defines the request body for passkey login options.
 type loginPasskeyRequest struct {
 	Username string `json:"username"`
@@ -599,9 +796,10 @@ func (h *AuthHandler) LoginPasskeyOptions(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "disabled", "passkey_id", "passkey_public_key", "passkey_sign_count", "passkey_backup_eligible", "passkey_backup_state", "name", "email").
+	if errFind := h.db.WithContext(ctx).
+		Select("id", "username", "disabled", "name", "email").
 		Where("username = ?", username).First(&user).Error; errFind != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
@@ -610,12 +808,17 @@ func (h *AuthHandler) LoginPasskeyOptions(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "user disabled"})
 		return
 	}
-	if len(user.PasskeyID) == 0 || len(user.PasskeyPublicKey) == 0 {
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerUser, user.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if len(passkeys) == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey not enabled"})
 		return
 	}
 
-	webauthnUser := newUserWebAuthnUser(user)
+	webauthnUser := newUserWebAuthnUser(user, passkeys)
 	assertion, session, err := webAuthn.BeginLogin(webauthnUser, webauthn.WithUserVerification(protocol.VerificationPreferred))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "begin passkey login failed"})
@@ -640,9 +843,10 @@ func (h *AuthHandler) LoginPasskeyVerify(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
 	var user models.User
-	if errFind := h.db.WithContext(c.Request.Context()).
-		Select("id", "username", "disabled", "passkey_id", "passkey_public_key", "passkey_sign_count", "passkey_backup_eligible", "passkey_backup_state", "name", "email").
+	if errFind := h.db.WithContext(ctx).
+		Select("id", "username", "disabled", "name", "email").
 		Where("username = ?", username).First(&user).Error; errFind != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
@@ -651,7 +855,12 @@ func (h *AuthHandler) LoginPasskeyVerify(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "user disabled"})
 		return
 	}
-	if len(user.PasskeyID) == 0 || len(user.PasskeyPublicKey) == 0 {
+	passkeys, errList := mfa.ListPasskeys(ctx, h.db, models.MFAOwnerUser, user.ID)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if len(passkeys) == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey not enabled"})
 		return
 	}
@@ -669,25 +878,8 @@ func (h *AuthHandler) LoginPasskeyVerify(c *gin.Context) {
 	}
 	c.Request.Body = io.NopCloser(bytes.NewReader(rawBody))
 
-	var derivedBackupEligible *bool
-	var derivedBackupState *bool
-	if user.PasskeyBackupEligible == nil || user.PasskeyBackupState == nil {
-		parsed, errParse := protocol.ParseCredentialRequestResponseBytes(rawBody)
-		if errParse != nil {
-			log.WithError(errParse).WithField("username", username).Warn("passkey login parse failed")
-		} else {
-			backupEligible := parsed.Response.AuthenticatorData.Flags.HasBackupEligible()
-			backupState := parsed.Response.AuthenticatorData.Flags.HasBackupState()
-			derivedBackupEligible = &backupEligible
-			derivedBackupState = &backupState
-		}
-	}
-
-	webauthnUser := newUserWebAuthnUser(user)
-	if derivedBackupEligible != nil && len(webauthnUser.credentials) > 0 {
-		webauthnUser.credentials[0].Flags.BackupEligible = *derivedBackupEligible
-		webauthnUser.credentials[0].Flags.BackupState = *derivedBackupState
-	}
+	webauthnUser := newUserWebAuthnUser(user, passkeys)
+	mfa.FillMissingBackupFlags(passkeys, webauthnUser.credentials, rawBody)
 	credential, err := webAuthn.FinishLogin(webauthnUser, session, c.Request)
 	if err != nil {
 		log.WithError(err).WithField("username", username).Warn("passkey login failed")
@@ -695,15 +887,9 @@ func (h *AuthHandler) LoginPasskeyVerify(c *gin.Context) {
 		return
 	}
 
-	signCount := uint32(credential.Authenticator.SignCount)
-	_ = h.db.WithContext(c.Request.Context()).Model(&models.User{}).
-		Where("id = ?", user.ID).
-		Updates(map[string]any{
-			"passkey_sign_count":      signCount,
-			"passkey_backup_eligible": credential.Flags.BackupEligible,
-			"passkey_backup_state":    credential.Flags.BackupState,
-			"updated_at":              time.Now().UTC(),
-		}).Error
+	if errRecord := mfa.RecordPasskeyLogin(ctx, h.db, models.MFAOwnerUser, user.ID, credential); errRecord != nil {
+		log.WithError(errRecord).WithField("username", username).Warn("passkey login record failed")
+	}
 
 	passkeyLoginSessions.Delete(username)
 	h.respondWithUserToken(c, user)
@@ -718,10 +904,22 @@ func (h *AuthHandler) respondWithUserToken(c *gin.Context, user models.User) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"name":     user.Name,
-		"email":    user.Email,
-		"token":    token,
+		"user_id":            user.ID,
+		"username":           user.Username,
+		"name":               user.Name,
+		"email":              user.Email,
+		"token":              token,
+		"mfa_setup_required": h.mfaSetupRequired(c, user),
 	})
 }
+
+// mfaSetupRequired reports whether the user must enroll in MFA before using the console.
+func (h *AuthHandler) mfaSetupRequired(c *gin.Context, user models.User) bool {
+	ctx := c.Request.Context()
+	required, errRequired := mfa.UserRequired(ctx, h.db, user)
+	if errRequired != nil || !required {
+		return false
+	}
+	status, errStatus := mfa.LoadStatus(ctx, h.db, models.MFAOwnerUser, user.ID, user.TOTPSecret)
+	return errStatus == nil && !status.Enabled()
+}
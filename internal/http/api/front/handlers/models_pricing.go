@@ -7,10 +7,13 @@ import (
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	sdkcliproxy "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelaccess"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelcatalog"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelregistry"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
@@ -42,6 +45,23 @@ type modelPricingItem struct {
 	PriceOutputToken      *float64           `json:"price_output_token,omitempty"`
 	PriceCacheCreateToken *float64           `json:"price_cache_create_token,omitempty"`
 	PriceCacheReadToken   *float64           `json:"price_cache_read_token,omitempty"`
+
+	Description     string             `json:"description,omitempty"`
+	ContextLength   int                `json:"context_length,omitempty"`
+	MaxOutputTokens int                `json:"max_output_tokens,omitempty"`
+	Capabilities    *modelCapabilities `json:"capabilities,omitempty"`
+	ReleasedAt      *time.Time         `json:"released_at,omitempty"`
+	Deprecated      bool               `json:"deprecated"`
+	DeprecatedAt    *time.Time         `json:"deprecated_at,omitempty"`
+	SunsetAt        *time.Time         `json:"sunset_at,omitempty"`
+	SunsetTarget    string             `json:"sunset_target,omitempty"`
+}
+
+// modelCapabilities lists the catalogued capabilities of a model.
+type modelCapabilities struct {
+	Vision    bool `json:"vision"`
+	Tools     bool `json:"tools"`
+	Reasoning bool `json:"reasoning"`
 }
 
 // modelAvailability captures availability metadata for a model.
@@ -142,6 +162,13 @@ func (h *ModelPricingHandler) List(c *gin.Context) {
 		return
 	}
 
+	now := time.Now().UTC()
+	overrides, errOverrides := modelaccess.Load(ctx, h.db, userID, now)
+	if errOverrides != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query model overrides failed"})
+		return
+	}
+
 	onlyMapped := loadOnlyMapped()
 	available, errModels := h.loadAvailableModels(ctx, onlyMapped)
 	if errModels != nil {
@@ -160,19 +187,25 @@ func (h *ModelPricingHandler) List(c *gin.Context) {
 			continue
 		}
 
+		if overrides.Denies(provider, modelID) {
+			continue
+		}
+
 		var billingUserGroupID *uint64
-		if allowed, okAllowed := modelmapping.LookupUserGroupIDs(provider, modelID); okAllowed && len(allowed.Clean()) > 0 {
-			hasGroup := false
+		allowed, okAllowed := modelmapping.LookupUserGroupIDs(provider, modelID)
+		hasGroup := false
+		if okAllowed && len(allowed.Clean()) > 0 {
 			for _, allowedID := range allowed.Values() {
 				if _, ok := userAccessGroups[allowedID]; ok {
 					hasGroup = true
 					break
 				}
 			}
-			if !hasGroup {
+			if !hasGroup && !overrides.Allows(provider, modelID) {
 				continue
 			}
-
+		}
+		if hasGroup {
 			allowedSet := make(map[uint64]struct{}, len(allowed.Values()))
 			for _, allowedID := range allowed.Values() {
 				if allowedID == 0 {
@@ -231,6 +264,7 @@ func (h *ModelPricingHandler) List(c *gin.Context) {
 			result.PriceCacheCreateToken = rule.PriceCacheCreateToken
 			result.PriceCacheReadToken = rule.PriceCacheReadToken
 		}
+		applyCatalogMetadata(&result, now)
 
 		switch result.BillingType {
 		case models.BillingTypePerRequest:
@@ -254,6 +288,30 @@ func (h *ModelPricingHandler) List(c *gin.Context) {
 	})
 }
 
+// applyCatalogMetadata fills descriptive fields from the model catalogue.
+func applyCatalogMetadata(item *modelPricingItem, now time.Time) {
+	entry, ok := modelcatalog.Lookup(item.Model)
+	if !ok {
+		return
+	}
+	if entry.DisplayName != "" {
+		item.DisplayName = entry.DisplayName
+	}
+	item.Description = entry.Description
+	item.ContextLength = entry.ContextLength
+	item.MaxOutputTokens = entry.MaxOutputTokens
+	item.Capabilities = &modelCapabilities{
+		Vision:    entry.SupportsVision,
+		Tools:     entry.SupportsTools,
+		Reasoning: entry.SupportsReasoning,
+	}
+	item.ReleasedAt = entry.ReleasedAt
+	item.Deprecated = modelcatalog.StatusAt(entry, now) != modelcatalog.StatusActive
+	item.DeprecatedAt = entry.DeprecatedAt
+	item.SunsetAt = entry.SunsetAt
+	item.SunsetTarget = entry.SunsetTarget
+}
+
 // loadBillingRules loads enabled billing rules for the given groups.
 func (h *ModelPricingHandler) loadBillingRules(ctx context.Context, authGroupID uint64, userGroupIDs []uint64, defaultAuthGroupID, defaultUserGroupID uint64) ([]models.BillingRule, error) {
 	if h.db == nil {
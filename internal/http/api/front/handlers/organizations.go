@@ -0,0 +1,1036 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"gorm.io/gorm"
+)
+
+// defaultInvitationDays is the invitation lifetime when none is requested.
+const defaultInvitationDays = 7
+
+var (
+	// errNotOrganizationMember indicates the user does not belong to the organization.
+	errNotOrganizationMember = errors.New("not an organization member")
+	// errOrganizationForbidden indicates the member's role does not allow the action.
+	errOrganizationForbidden = errors.New("organization permission denied")
+)
+
+// OrganizationFrontHandler handles organization endpoints for users.
+type OrganizationFrontHandler struct {
+	db *gorm.DB
+}
+
+// NewOrganizationFrontHandler constructs an OrganizationFrontHandler.
+func NewOrganizationFrontHandler(db *gorm.DB) *OrganizationFrontHandler {
+	return &OrganizationFrontHandler{db: db}
+}
+
+// loadMembership returns the organization membership of a user.
+func loadMembership(ctx context.Context, db *gorm.DB, userID uint64) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	if errFind := db.WithContext(ctx).Where("user_id = ?", userID).Take(&member).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, errNotOrganizationMember
+		}
+		return nil, errFind
+	}
+	return &member, nil
+}
+
+// loadOrganizationManager returns the membership when the user is an owner or admin of the organization.
+func loadOrganizationManager(ctx context.Context, db *gorm.DB, userID, organizationID uint64) (*models.OrganizationMember, error) {
+	member, errMember := loadMembership(ctx, db, userID)
+	if errMember != nil {
+		return nil, errMember
+	}
+	if member.OrganizationID != organizationID {
+		return nil, errNotOrganizationMember
+	}
+	if !member.Role.CanManage() {
+		return nil, errOrganizationForbidden
+	}
+	return member, nil
+}
+
+// writeOrganizationError maps organization lookup errors to responses.
+func writeOrganizationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errNotOrganizationMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+	case errors.Is(err, errOrganizationForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "organization permission denied"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query organization failed"})
+	}
+}
+
+// currentMember loads the caller's membership, writing an error response when absent.
+func (h *OrganizationFrontHandler) currentMember(c *gin.Context, requireManager bool) (*models.OrganizationMember, bool) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+	member, errMember := loadMembership(c.Request.Context(), h.db, userID)
+	if errMember != nil {
+		writeOrganizationError(c, errMember)
+		return nil, false
+	}
+	if requireManager && !member.Role.CanManage() {
+		writeOrganizationError(c, errOrganizationForbidden)
+		return nil, false
+	}
+	return member, true
+}
+
+// createOrganizationRequest defines the request body for organization creation.
+type createOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+// Create creates an organization owned by the current user.
+func (h *OrganizationFrontHandler) Create(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var body createOrganizationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, errMember := loadMembership(ctx, h.db, userID); errMember == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "already in an organization"})
+		return
+	} else if !errors.Is(errMember, errNotOrganizationMember) {
+		writeOrganizationError(c, errMember)
+		return
+	}
+
+	now := time.Now().UTC()
+	org := models.Organization{Name: name, OwnerUserID: userID, CreatedAt: now, UpdatedAt: now}
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errCreate := tx.Create(&org).Error; errCreate != nil {
+			return errCreate
+		}
+		member := models.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         userID,
+			Role:           models.OrganizationRoleOwner,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		return tx.Create(&member).Error
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create organization failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":            org.ID,
+		"name":          org.Name,
+		"owner_user_id": org.OwnerUserID,
+		"role":          models.OrganizationRoleOwner,
+	})
+}
+
+// Get returns the current user's organization with its shared balance.
+func (h *OrganizationFrontHandler) Get(c *gin.Context) {
+	member, ok := h.currentMember(c, false)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var org models.Organization
+	if errFind := h.db.WithContext(ctx).First(&org, member.OrganizationID).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query organization failed"})
+		return
+	}
+	var memberCount int64
+	if errCount := h.db.WithContext(ctx).Model(&models.OrganizationMember{}).
+		Where("organization_id = ?", org.ID).
+		Count(&memberCount).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query organization failed"})
+		return
+	}
+
+	payer := billing.OrganizationPayer(member.UserID, org.ID)
+	now := time.Now().UTC()
+	var prepaidBalance float64
+	if errSum := payer.ScopePrepaidCards(h.db.WithContext(ctx).Model(&models.PrepaidCard{})).
+		Where("is_enabled = ? AND redeemed_at IS NOT NULL", true).
+		Where("(expires_at IS NULL OR expires_at >= ?)", now).
+		Select("COALESCE(SUM(balance), 0)").
+		Scan(&prepaidBalance).Error; errSum != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query balance failed"})
+		return
+	}
+	var billLeftQuota float64
+	if errSum := payer.ScopeBills(h.db.WithContext(ctx).Model(&models.Bill{})).
+		Where("is_enabled = ? AND status = ?", true, models.BillStatusPaid).
+		Where("period_start <= ? AND period_end >= ?", now, now).
+		Select("COALESCE(SUM(left_quota), 0)").
+		Scan(&billLeftQuota).Error; errSum != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query balance failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization": gin.H{
+			"id":              org.ID,
+			"name":            org.Name,
+			"owner_user_id":   org.OwnerUserID,
+			"member_count":    memberCount,
+			"prepaid_balance": prepaidBalance,
+			"bill_left_quota": billLeftQuota,
+			"created_at":      org.CreatedAt,
+			"updated_at":      org.UpdatedAt,
+		},
+		"membership": formatOrganizationMember(member, nil),
+	})
+}
+
+// updateOrganizationRequest defines the request body for organization updates.
+type updateOrganizationRequest struct {
+	Name *string `json:"name"`
+}
+
+// Update renames the current organization.
+func (h *OrganizationFrontHandler) Update(c *gin.Context) {
+	member, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	var body updateOrganizationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	updates := map[string]any{"updated_at": time.Now().UTC()}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		updates["name"] = name
+	}
+	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.Organization{}).
+		Where("id = ?", member.OrganizationID).
+		Updates(updates).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Delete dissolves an organization that has no other members, returning its balance to the owner.
+func (h *OrganizationFrontHandler) Delete(c *gin.Context) {
+	member, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	if member.Role != models.OrganizationRoleOwner {
+		writeOrganizationError(c, errOrganizationForbidden)
+		return
+	}
+	ctx := c.Request.Context()
+	var memberCount int64
+	if errCount := h.db.WithContext(ctx).Model(&models.OrganizationMember{}).
+		Where("organization_id = ?", member.OrganizationID).
+		Count(&memberCount).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query organization failed"})
+		return
+	}
+	if memberCount > 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "remove all other members first"})
+		return
+	}
+
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errBills := tx.Model(&models.Bill{}).
+			Where("organization_id = ?", member.OrganizationID).
+			Updates(map[string]any{"organization_id": nil, "user_id": member.UserID}).Error; errBills != nil {
+			return errBills
+		}
+		if errCards := tx.Model(&models.PrepaidCard{}).
+			Where("organization_id = ?", member.OrganizationID).
+			Updates(map[string]any{"organization_id": nil, "redeemed_user_id": member.UserID}).Error; errCards != nil {
+			return errCards
+		}
+		if errInvites := tx.Where("organization_id = ?", member.OrganizationID).Delete(&models.OrganizationInvitation{}).Error; errInvites != nil {
+			return errInvites
+		}
+		if errMembers := tx.Where("organization_id = ?", member.OrganizationID).Delete(&models.OrganizationMember{}).Error; errMembers != nil {
+			return errMembers
+		}
+		if errOrg := tx.Delete(&models.Organization{}, member.OrganizationID).Error; errOrg != nil {
+			return errOrg
+		}
+		return billing.RefreshBillUserGroupIDs(ctx, tx, billing.Payer{UserID: member.UserID})
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete organization failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListMembers returns the members of the current organization.
+func (h *OrganizationFrontHandler) ListMembers(c *gin.Context) {
+	member, ok := h.currentMember(c, false)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var members []models.OrganizationMember
+	if errFind := h.db.WithContext(ctx).
+		Where("organization_id = ?", member.OrganizationID).
+		Order("id ASC").
+		Find(&members).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list members failed"})
+		return
+	}
+	userIDs := make([]uint64, 0, len(members))
+	for _, row := range members {
+		userIDs = append(userIDs, row.UserID)
+	}
+	var users []models.User
+	if len(userIDs) > 0 {
+		if errUsers := h.db.WithContext(ctx).Select("id", "username", "email").Where("id IN ?", userIDs).Find(&users).Error; errUsers != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "list members failed"})
+			return
+		}
+	}
+	usersByID := make(map[uint64]*models.User, len(users))
+	for i := range users {
+		usersByID[users[i].ID] = &users[i]
+	}
+
+	out := make([]gin.H, 0, len(members))
+	for i := range members {
+		out = append(out, formatOrganizationMember(&members[i], usersByID[members[i].UserID]))
+	}
+	c.JSON(http.StatusOK, gin.H{"members": out})
+}
+
+// updateOrganizationMemberRequest defines the request body for member updates.
+type updateOrganizationMemberRequest struct {
+	Role              *models.OrganizationRole `json:"role"`
+	DailySpendLimit   *float64                 `json:"daily_spend_limit"`
+	MonthlySpendLimit *float64                 `json:"monthly_spend_limit"`
+}
+
+// UpdateMember changes a member's role or spend limits; assigning owner transfers ownership.
+func (h *OrganizationFrontHandler) UpdateMember(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	targetUserID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("user_id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+	var body updateOrganizationMemberRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	target, errTarget := loadMembership(ctx, h.db, targetUserID)
+	if errTarget != nil || target.OrganizationID != actor.OrganizationID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+	if actor.Role != models.OrganizationRoleOwner && target.Role != models.OrganizationRoleMember {
+		writeOrganizationError(c, errOrganizationForbidden)
+		return
+	}
+
+	now := time.Now().UTC()
+	updates := map[string]any{"updated_at": now}
+	if body.DailySpendLimit != nil {
+		if *body.DailySpendLimit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid daily_spend_limit"})
+			return
+		}
+		updates["daily_spend_limit"] = *body.DailySpendLimit
+	}
+	if body.MonthlySpendLimit != nil {
+		if *body.MonthlySpendLimit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid monthly_spend_limit"})
+			return
+		}
+		updates["monthly_spend_limit"] = *body.MonthlySpendLimit
+	}
+
+	transferOwnership := false
+	if body.Role != nil && *body.Role != target.Role {
+		role := *body.Role
+		if !role.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+		if actor.Role != models.OrganizationRoleOwner || target.Role == models.OrganizationRoleOwner {
+			writeOrganizationError(c, errOrganizationForbidden)
+			return
+		}
+		updates["role"] = role
+		transferOwnership = role == models.OrganizationRoleOwner
+	}
+
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errUpdate := tx.Model(&models.OrganizationMember{}).Where("id = ?", target.ID).Updates(updates).Error; errUpdate != nil {
+			return errUpdate
+		}
+		if !transferOwnership {
+			return nil
+		}
+		if errDemote := tx.Model(&models.OrganizationMember{}).Where("id = ?", actor.ID).
+			Updates(map[string]any{"role": models.OrganizationRoleAdmin, "updated_at": now}).Error; errDemote != nil {
+			return errDemote
+		}
+		return tx.Model(&models.Organization{}).Where("id = ?", actor.OrganizationID).
+			Updates(map[string]any{"owner_user_id": target.UserID, "updated_at": now}).Error
+	})
+	if errTx != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update member failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// RemoveMember removes a member from the current organization.
+func (h *OrganizationFrontHandler) RemoveMember(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	targetUserID, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("user_id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+	ctx := c.Request.Context()
+	target, errTarget := loadMembership(ctx, h.db, targetUserID)
+	if errTarget != nil || target.OrganizationID != actor.OrganizationID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+	if target.Role == models.OrganizationRoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot remove the owner"})
+		return
+	}
+	if actor.Role != models.OrganizationRoleOwner && target.Role != models.OrganizationRoleMember {
+		writeOrganizationError(c, errOrganizationForbidden)
+		return
+	}
+	if errRemove := h.removeMember(ctx, target); errRemove != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "remove member failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Leave removes the current user from their organization.
+func (h *OrganizationFrontHandler) Leave(c *gin.Context) {
+	member, ok := h.currentMember(c, false)
+	if !ok {
+		return
+	}
+	if member.Role == models.OrganizationRoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "transfer ownership before leaving"})
+		return
+	}
+	if errRemove := h.removeMember(c.Request.Context(), member); errRemove != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "leave organization failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// removeMember deletes a membership and restores the user's own bill groups.
+func (h *OrganizationFrontHandler) removeMember(ctx context.Context, member *models.OrganizationMember) error {
+	return h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errDelete := tx.Delete(&models.OrganizationMember{}, member.ID).Error; errDelete != nil {
+			return errDelete
+		}
+		return billing.RefreshBillUserGroupIDs(ctx, tx, billing.Payer{UserID: member.UserID})
+	})
+}
+
+// createInvitationRequest defines the request body for invitations.
+type createInvitationRequest struct {
+	Email         string                  `json:"email"`
+	Role          models.OrganizationRole `json:"role"`
+	ExpiresInDays int                     `json:"expires_in_days"`
+}
+
+// CreateInvitation invites an email address to the current organization.
+func (h *OrganizationFrontHandler) CreateInvitation(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	var body createInvitationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(body.Email))
+	if email == "" || !strings.Contains(email, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+	role := body.Role
+	if role == "" {
+		role = models.OrganizationRoleMember
+	}
+	if role != models.OrganizationRoleMember && role != models.OrganizationRoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+	if role == models.OrganizationRoleAdmin && actor.Role != models.OrganizationRoleOwner {
+		writeOrganizationError(c, errOrganizationForbidden)
+		return
+	}
+	days := body.ExpiresInDays
+	if days <= 0 {
+		days = defaultInvitationDays
+	}
+
+	token, errToken := security.GenerateRandomString(48)
+	if errToken != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "generate invitation failed"})
+		return
+	}
+	now := time.Now().UTC()
+	invitation := models.OrganizationInvitation{
+		OrganizationID:  actor.OrganizationID,
+		Email:           email,
+		Role:            role,
+		Token:           token,
+		InvitedByUserID: actor.UserID,
+		ExpiresAt:       now.AddDate(0, 0, days),
+		CreatedAt:       now,
+	}
+	if errCreate := h.db.WithContext(c.Request.Context()).Create(&invitation).Error; errCreate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create invitation failed"})
+		return
+	}
+	out := formatOrganizationInvitation(&invitation)
+	out["token"] = invitation.Token
+	c.JSON(http.StatusCreated, out)
+}
+
+// ListInvitations returns pending invitations of the current organization.
+func (h *OrganizationFrontHandler) ListInvitations(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	var rows []models.OrganizationInvitation
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("organization_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", actor.OrganizationID).
+		Order("created_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list invitations failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatOrganizationInvitation(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": out})
+}
+
+// RevokeInvitation cancels a pending invitation.
+func (h *OrganizationFrontHandler) RevokeInvitation(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).Model(&models.OrganizationInvitation{}).
+		Where("id = ? AND organization_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id, actor.OrganizationID).
+		Update("revoked_at", time.Now().UTC())
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "revoke invitation failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// MyInvitations returns pending invitations addressed to the current user's verified email.
+// Tokens are never listed; unverified users must accept with the token shared by the inviter.
+func (h *OrganizationFrontHandler) MyInvitations(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	ctx := c.Request.Context()
+	var user models.User
+	if errFind := h.db.WithContext(ctx).Select("id", "email", "email_verification_pending", "email_verified_at").First(&user, userID).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query user failed"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	out := make([]gin.H, 0)
+	if email == "" || !emailVerified(&user) {
+		c.JSON(http.StatusOK, gin.H{"invitations": out})
+		return
+	}
+
+	var rows []models.OrganizationInvitation
+	if errFind := h.db.WithContext(ctx).
+		Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", email, time.Now().UTC()).
+		Order("created_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list invitations failed"})
+		return
+	}
+	orgNames := make(map[uint64]string)
+	for _, row := range rows {
+		orgNames[row.OrganizationID] = ""
+	}
+	if len(orgNames) > 0 {
+		orgIDs := make([]uint64, 0, len(orgNames))
+		for id := range orgNames {
+			orgIDs = append(orgIDs, id)
+		}
+		var orgs []models.Organization
+		if errOrgs := h.db.WithContext(ctx).Select("id", "name").Where("id IN ?", orgIDs).Find(&orgs).Error; errOrgs == nil {
+			for _, org := range orgs {
+				orgNames[org.ID] = org.Name
+			}
+		}
+	}
+	for i := range rows {
+		item := formatOrganizationInvitation(&rows[i])
+		item["organization_name"] = orgNames[rows[i].OrganizationID]
+		out = append(out, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": out})
+}
+
+// acceptInvitationRequest defines the request body for accepting invitations.
+type acceptInvitationRequest struct {
+	Token        string `json:"token"`         // Invitation token shared by the inviter.
+	InvitationID uint64 `json:"invitation_id"` // Invitation to accept by ID; requires a verified email.
+}
+
+// emailVerified reports whether the user's email address has been verified.
+func emailVerified(user *models.User) bool {
+	return user.EmailVerifiedAt != nil && !user.EmailVerificationPending
+}
+
+// AcceptInvitation joins the organization named by an invitation token, or by invitation ID
+// when the invitation was addressed to the user's verified email.
+func (h *OrganizationFrontHandler) AcceptInvitation(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var body acceptInvitationRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	token := strings.TrimSpace(body.Token)
+	if token == "" && body.InvitationID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing token"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var user models.User
+	if errFind := h.db.WithContext(ctx).Select("id", "email", "email_verification_pending", "email_verified_at").First(&user, userID).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query user failed"})
+		return
+	}
+	query := h.db.WithContext(ctx).Where("token = ?", token)
+	if token == "" {
+		// Without the token, the verified email is the only proof the invitation is the user's.
+		if !emailVerified(&user) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "verify your email or use the invitation token"})
+			return
+		}
+		query = h.db.WithContext(ctx).Where("id = ?", body.InvitationID)
+	}
+	var invitation models.OrganizationInvitation
+	if errFind := query.Take(&invitation).Error; errFind != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invitation not found"})
+		return
+	}
+	now := time.Now().UTC()
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || !invitation.ExpiresAt.After(now) {
+		c.JSON(http.StatusGone, gin.H{"error": "invitation is no longer valid"})
+		return
+	}
+	if !strings.EqualFold(strings.TrimSpace(user.Email), invitation.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invitation was sent to a different email"})
+		return
+	}
+	if _, errMember := loadMembership(ctx, h.db, userID); errMember == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "already in an organization"})
+		return
+	} else if !errors.Is(errMember, errNotOrganizationMember) {
+		writeOrganizationError(c, errMember)
+		return
+	}
+
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.OrganizationInvitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitation.ID).
+			Updates(map[string]any{"accepted_at": now, "accepted_user_id": userID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errNotOrganizationMember
+		}
+		member := models.OrganizationMember{
+			OrganizationID: invitation.OrganizationID,
+			UserID:         userID,
+			Role:           invitation.Role,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if errCreate := tx.Create(&member).Error; errCreate != nil {
+			return errCreate
+		}
+		return billing.RefreshBillUserGroupIDs(ctx, tx, billing.OrganizationPayer(userID, invitation.OrganizationID))
+	})
+	if errTx != nil {
+		if errors.Is(errTx, errNotOrganizationMember) {
+			c.JSON(http.StatusGone, gin.H{"error": "invitation is no longer valid"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "accept invitation failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organization_id": invitation.OrganizationID, "role": invitation.Role})
+}
+
+// ListAPIKeys returns the API keys of every organization member.
+func (h *OrganizationFrontHandler) ListAPIKeys(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	var rows []models.APIKey
+	if errFind := h.db.WithContext(ctx).
+		Where("user_id IN (?)", h.db.Model(&models.OrganizationMember{}).Select("user_id").Where("organization_id = ?", actor.OrganizationID)).
+		Order("created_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list api keys failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{
+			"id":           row.ID,
+			"user_id":      row.UserID,
+			"name":         row.Name,
+			"token":        maskOrganizationAPIKey(row.APIKey),
+			"status":       row.Status(),
+			"expires_at":   row.ExpiresAt,
+			"last_used_at": row.LastUsedAt,
+			"created_at":   row.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": out})
+}
+
+// ListBills returns the organization's shared bills.
+func (h *OrganizationFrontHandler) ListBills(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	var rows []models.Bill
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("organization_id = ?", actor.OrganizationID).
+		Order("created_at DESC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list bills failed"})
+		return
+	}
+	formatter := &BillFrontHandler{db: h.db}
+	out := make([]gin.H, 0, len(rows))
+	for i := range rows {
+		out = append(out, formatter.formatBill(&rows[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"bills": out})
+}
+
+// ListPrepaidCards returns prepaid cards redeemed into the organization.
+func (h *OrganizationFrontHandler) ListPrepaidCards(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	var cards []models.PrepaidCard
+	if errFind := h.db.WithContext(c.Request.Context()).
+		Where("organization_id = ?", actor.OrganizationID).
+		Order("redeemed_at DESC NULLS LAST, created_at DESC").
+		Find(&cards).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query cards failed"})
+		return
+	}
+	resp := make([]prepaidCardDTO, 0, len(cards))
+	for _, card := range cards {
+		resp = append(resp, prepaidCardDTO{
+			ID:          card.ID,
+			Name:        card.Name,
+			CardSN:      card.CardSN,
+			Amount:      card.Amount,
+			Balance:     card.Balance,
+			ValidDays:   card.ValidDays,
+			ExpiresAt:   card.ExpiresAt,
+			RedeemedAt:  card.RedeemedAt,
+			RedeemedUid: card.RedeemedUserID,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"cards": resp})
+}
+
+// organizationUsageQuery defines filters for organization usage endpoints.
+type organizationUsageQuery struct {
+	Page      int    `form:"page,default=1"`
+	Limit     int    `form:"limit,default=20"`
+	StartDate string `form:"start_date"`
+	EndDate   string `form:"end_date"`
+	UserID    uint64 `form:"user_id"`
+	Model     string `form:"model"`
+}
+
+// usageQuery builds the filtered usage query charged to the organization.
+func (h *OrganizationFrontHandler) usageQuery(c *gin.Context, organizationID uint64, q organizationUsageQuery) *gorm.DB {
+	query := h.db.WithContext(c.Request.Context()).Model(&models.Usage{}).Where("organization_id = ?", organizationID)
+	if q.StartDate != "" {
+		if startTime, errParse := time.ParseInLocation("2006-01-02", q.StartDate, time.Local); errParse == nil {
+			query = query.Where("requested_at >= ?", startTime)
+		}
+	}
+	if q.EndDate != "" {
+		if endTime, errParse := time.ParseInLocation("2006-01-02", q.EndDate, time.Local); errParse == nil {
+			query = query.Where("requested_at < ?", endTime.AddDate(0, 0, 1))
+		}
+	}
+	if q.UserID != 0 {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	if model := strings.TrimSpace(q.Model); model != "" {
+		query = query.Where("model = ?", model)
+	}
+	return query
+}
+
+// Usage aggregates organization usage in total, per member and per model.
+func (h *OrganizationFrontHandler) Usage(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	var q organizationUsageQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+
+	// usageAgg holds aggregated usage for one grouping key.
+	type usageAgg struct {
+		UserID       *uint64
+		Model        string
+		Requests     int64
+		InputTokens  int64
+		OutputTokens int64
+		TotalTokens  int64
+		CostMicros   int64
+		FailedCount  int64
+	}
+	const aggSelect = `
+		COUNT(*) AS requests,
+		COALESCE(SUM(input_tokens), 0) AS input_tokens,
+		COALESCE(SUM(output_tokens), 0) AS output_tokens,
+		COALESCE(SUM(total_tokens), 0) AS total_tokens,
+		COALESCE(SUM(cost_micros), 0) AS cost_micros,
+		COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_count`
+
+	var totals usageAgg
+	if errTotals := h.usageQuery(c, actor.OrganizationID, q).Select(aggSelect).Scan(&totals).Error; errTotals != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usage failed"})
+		return
+	}
+	var byMember []usageAgg
+	if errMembers := h.usageQuery(c, actor.OrganizationID, q).
+		Select("user_id," + aggSelect).
+		Group("user_id").
+		Order("cost_micros DESC").
+		Scan(&byMember).Error; errMembers != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usage failed"})
+		return
+	}
+	var byModel []usageAgg
+	if errModels := h.usageQuery(c, actor.OrganizationID, q).
+		Select("model," + aggSelect).
+		Group("model").
+		Order("cost_micros DESC").
+		Scan(&byModel).Error; errModels != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usage failed"})
+		return
+	}
+
+	format := func(agg usageAgg) gin.H {
+		return gin.H{
+			"requests":      agg.Requests,
+			"input_tokens":  agg.InputTokens,
+			"output_tokens": agg.OutputTokens,
+			"total_tokens":  agg.TotalTokens,
+			"cost_micros":   agg.CostMicros,
+			"cost":          float64(agg.CostMicros) / 1_000_000,
+			"failed_count":  agg.FailedCount,
+		}
+	}
+	members := make([]gin.H, 0, len(byMember))
+	for _, agg := range byMember {
+		item := format(agg)
+		item["user_id"] = agg.UserID
+		members = append(members, item)
+	}
+	modelsOut := make([]gin.H, 0, len(byModel))
+	for _, agg := range byModel {
+		item := format(agg)
+		item["model"] = agg.Model
+		modelsOut = append(modelsOut, item)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"totals":  format(totals),
+		"members": members,
+		"models":  modelsOut,
+	})
+}
+
+// Logs returns paged usage records charged to the organization.
+func (h *OrganizationFrontHandler) Logs(c *gin.Context) {
+	actor, ok := h.currentMember(c, true)
+	if !ok {
+		return
+	}
+	var q organizationUsageQuery
+	if errBind := c.ShouldBindQuery(&q); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	var total int64
+	if errCount := h.usageQuery(c, actor.OrganizationID, q).Count(&total).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "count logs failed"})
+		return
+	}
+	var rows []models.Usage
+	if errFind := h.usageQuery(c, actor.OrganizationID, q).
+		Order("requested_at DESC, id DESC").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list logs failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{
+			"id":            row.ID,
+			"user_id":       row.UserID,
+			"api_key_id":    row.APIKeyID,
+			"provider":      row.Provider,
+			"model":         row.Model,
+			"requested_at":  row.RequestedAt,
+			"failed":        row.Failed,
+			"input_tokens":  row.InputTokens,
+			"output_tokens": row.OutputTokens,
+			"total_tokens":  row.TotalTokens,
+			"cost_micros":   row.CostMicros,
+			"cost":          float64(row.CostMicros) / 1_000_000,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"logs": out, "total": total, "page": q.Page, "limit": q.Limit})
+}
+
+// formatOrganizationMember converts a membership into its API representation.
+func formatOrganizationMember(member *models.OrganizationMember, user *models.User) gin.H {
+	out := gin.H{
+		"user_id":             member.UserID,
+		"organization_id":     member.OrganizationID,
+		"role":                member.Role,
+		"daily_spend_limit":   member.DailySpendLimit,
+		"monthly_spend_limit": member.MonthlySpendLimit,
+		"joined_at":           member.CreatedAt,
+	}
+	if user != nil {
+		out["username"] = user.Username
+		out["email"] = user.Email
+	}
+	return out
+}
+
+// formatOrganizationInvitation converts an invitation into its API representation without the token.
+func formatOrganizationInvitation(invitation *models.OrganizationInvitation) gin.H {
+	return gin.H{
+		"id":                 invitation.ID,
+		"organization_id":    invitation.OrganizationID,
+		"email":              invitation.Email,
+		"role":               invitation.Role,
+		"invited_by_user_id": invitation.InvitedByUserID,
+		"expires_at":         invitation.ExpiresAt,
+		"created_at":         invitation.CreatedAt,
+	}
+}
+
+// maskOrganizationAPIKey hides all but the edges of a member's API key.
+func maskOrganizationAPIKey(key string) string {
+	if len(key) <= 12 {
+		return "****"
+	}
+	return key[:8] + "****" + key[len(key)-4:]
+}
@@ -37,8 +37,9 @@ type prepaidCardDTO struct {
 
 // redeemCardRequest defines the request body for card redemption.
 type redeemCardRequest struct {
-	CardSN   string `json:"card_sn"`
-	Password string `json:"password"`
+	CardSN         string  `json:"card_sn"`
+	Password       string  `json:"password"`
+	OrganizationID *uint64 `json:"organization_id"` // Redeem into this organization's shared balance.
 }
 
 // Redeem redeems a prepaid card for the current user.
@@ -62,6 +63,13 @@ func (h *PrepaidCardFrontHandler) Redeem(c *gin.Context) {
 		return
 	}
 
+	if body.OrganizationID != nil {
+		if _, errManager := loadOrganizationManager(c.Request.Context(), h.db, userID, *body.OrganizationID); errManager != nil {
+			writeOrganizationError(c, errManager)
+			return
+		}
+	}
+
 	var result gin.H
 	errTx := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
 		var card models.PrepaidCard
@@ -97,6 +105,7 @@ func (h *PrepaidCardFrontHandler) Redeem(c *gin.Context) {
 		}
 		if errUpdate := tx.Model(&card).Updates(map[string]any{
 			"redeemed_user_id": userID,
+			"organization_id":  body.OrganizationID,
 			"redeemed_at":      now,
 			"expires_at":       expiresAt,
 		}).Error; errUpdate != nil {
@@ -108,14 +117,15 @@ func (h *PrepaidCardFrontHandler) Redeem(c *gin.Context) {
 		card.RedeemedAt = &now
 		card.ExpiresAt = expiresAt
 		result = gin.H{
-			"id":          card.ID,
-			"name":        card.Name,
-			"card_sn":     card.CardSN,
-			"amount":      card.Amount,
-			"balance":     card.Balance,
-			"valid_days":  card.ValidDays,
-			"expires_at":  card.ExpiresAt,
-			"redeemed_at": card.RedeemedAt,
+			"id":              card.ID,
+			"name":            card.Name,
+			"card_sn":         card.CardSN,
+			"amount":          card.Amount,
+			"balance":         card.Balance,
+			"valid_days":      card.ValidDays,
+			"expires_at":      card.ExpiresAt,
+			"redeemed_at":     card.RedeemedAt,
+			"organization_id": body.OrganizationID,
 		}
 		return nil
 	})
@@ -8,7 +8,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/reports"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	"gorm.io/gorm"
 )
 
@@ -41,13 +43,17 @@ func (h *ProfileHandler) Get(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"active":     user.Active,
-		"disabled":   user.Disabled,
-		"created_at": user.CreatedAt,
-		"updated_at": user.UpdatedAt,
+		"id":                     user.ID,
+		"username":               user.Username,
+		"email":                  user.Email,
+		"email_verified":         !user.EmailVerificationPending,
+		"email_verified_at":      user.EmailVerifiedAt,
+		"active":                 user.Active,
+		"disabled":               user.Disabled,
+		"usage_report_frequency": user.UsageReportFrequency,
+		"usage_report_available": internalsettings.UserUsageSummariesEnabled(),
+		"created_at":             user.CreatedAt,
+		"updated_at":             user.UpdatedAt,
 	})
 }
 
@@ -108,3 +114,43 @@ func (h *ProfileHandler) ChangePassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// usageReportRequest defines the request body for usage summary email preferences.
+type usageReportRequest struct {
+	Frequency string `json:"frequency"`
+}
+
+// SetUsageReport sets how often the user receives usage summary emails; an empty frequency
+// opts out.
+func (h *ProfileHandler) SetUsageReport(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var body usageReportRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	frequency := strings.ToLower(strings.TrimSpace(body.Frequency))
+	if frequency != "" {
+		if !reports.ValidFrequency(frequency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid frequency"})
+			return
+		}
+		if !internalsettings.UserUsageSummariesEnabled() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "usage summaries are disabled"})
+			return
+		}
+	}
+
+	if errUpdate := h.db.WithContext(c.Request.Context()).Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]any{"usage_report_frequency": frequency, "updated_at": time.Now().UTC()}).Error; errUpdate != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"usage_report_frequency": frequency})
+}
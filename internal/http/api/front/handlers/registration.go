@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// emailVerificationTTL is how long a verification token stays valid.
+	emailVerificationTTL = 24 * time.Hour
+	// emailVerificationResendInterval is the minimum gap between verification emails.
+	emailVerificationResendInterval = time.Minute
+	// mailSendTimeout bounds a single verification email delivery.
+	mailSendTimeout = 20 * time.Second
+	// signupBonusName names the prepaid card carrying the registration bonus.
+	signupBonusName = "Sign-up bonus"
+)
+
+var (
+	// errInviteCodeInvalid indicates the invite code is unknown, disabled, expired or used up.
+	errInviteCodeInvalid = errors.New("invalid invite code")
+	// errRegistrationLimited indicates the client IP reached the hourly sign-up limit.
+	errRegistrationLimited = errors.New("too many registrations")
+	// errUsernameTaken indicates another user already has the username.
+	errUsernameTaken = errors.New("username already exists")
+	// errEmailTaken indicates another user already has the email.
+	errEmailTaken = errors.New("email already exists")
+)
+
+// redeemInviteCode atomically consumes one use of an invite code.
+func redeemInviteCode(tx *gorm.DB, code string, now time.Time) (*models.InviteCode, error) {
+	var invite models.InviteCode
+	if errFind := tx.Where("code = ?", code).Take(&invite).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, errInviteCodeInvalid
+		}
+		return nil, errFind
+	}
+	if !invite.IsEnabled || (invite.ExpiresAt != nil && !invite.ExpiresAt.After(now)) {
+		return nil, errInviteCodeInvalid
+	}
+	res := tx.Model(&models.InviteCode{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", invite.ID).
+		Updates(map[string]any{"used_count": gorm.Expr("used_count + 1"), "updated_at": now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errInviteCodeInvalid
+	}
+	invite.UsedCount++
+	return &invite, nil
+}
+
+// grantStarterCredit creates an already redeemed prepaid card carrying sign-up credit.
+func grantStarterCredit(tx *gorm.DB, userID uint64, name string, amount float64, validDays int, now time.Time) error {
+	if amount <= 0 {
+		return nil
+	}
+	serial, errSerial := security.GenerateRandomString(12)
+	if errSerial != nil {
+		return errSerial
+	}
+	password, errPassword := security.GenerateRandomString(16)
+	if errPassword != nil {
+		return errPassword
+	}
+	var expiresAt *time.Time
+	if validDays > 0 {
+		exp := now.AddDate(0, 0, validDays)
+		expiresAt = &exp
+	}
+	redeemedUserID := userID
+	card := models.PrepaidCard{
+		Name:           name,
+		CardSN:         "SIGNUP-" + strings.ToUpper(serial),
+		Password:       password,
+		Amount:         amount,
+		Balance:        amount,
+		ValidDays:      validDays,
+		ExpiresAt:      expiresAt,
+		IsEnabled:      true,
+		RedeemedUserID: &redeemedUserID,
+		CreatedAt:      now,
+		RedeemedAt:     &now,
+	}
+	return tx.Create(&card).Error
+}
+
+// grantSignupBonus grants the registration bonus unless the user already received it.
+func grantSignupBonus(tx *gorm.DB, userID uint64, policy internalsettings.RegistrationPolicy, now time.Time) error {
+	if policy.BonusAmount <= 0 {
+		return nil
+	}
+	var granted int64
+	if errCount := tx.Model(&models.PrepaidCard{}).
+		Where("redeemed_user_id = ? AND name = ?", userID, signupBonusName).
+		Count(&granted).Error; errCount != nil {
+		return errCount
+	}
+	if granted > 0 {
+		return nil
+	}
+	return grantStarterCredit(tx, userID, signupBonusName, policy.BonusAmount, policy.BonusValidDays, now)
+}
+
+// lockRegistrationIP serializes sign ups from one IP until the transaction ends so the
+// hourly limit cannot be raced. SQLite already serializes writers.
+func lockRegistrationIP(tx *gorm.DB, ip string) error {
+	if dbutil.DialectName(tx) != dbutil.DialectPostgres {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "registration:"+ip).Error
+}
+
+// checkUserTaken reports whether the username or email already belongs to a user.
+func checkUserTaken(tx *gorm.DB, username, email string) error {
+	var count int64
+	if errCount := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; errCount != nil {
+		return errCount
+	}
+	if count > 0 {
+		return errUsernameTaken
+	}
+	if email == "" {
+		return nil
+	}
+	if errCount := tx.Model(&models.User{}).Where("email = ?", email).Count(&count).Error; errCount != nil {
+		return errCount
+	}
+	if count > 0 {
+		return errEmailTaken
+	}
+	return nil
+}
+
+// userUniqueViolation maps a unique index violation on users to errUsernameTaken or errEmailTaken,
+// covering sign ups that raced past checkUserTaken.
+func userUniqueViolation(err error) error {
+	msg := strings.ToLower(err.Error())
+	if !strings.Contains(msg, "duplicate") && !strings.Contains(msg, "unique") {
+		return err
+	}
+	switch {
+	case strings.Contains(msg, "email"):
+		return errEmailTaken
+	case strings.Contains(msg, "username"):
+		return errUsernameTaken
+	default:
+		return err
+	}
+}
+
+// countRecentRegistrations returns how many users signed up from ip since the given time.
+func countRecentRegistrations(ctx context.Context, db *gorm.DB, ip string, since time.Time) (int64, error) {
+	var count int64
+	errCount := db.WithContext(ctx).Model(&models.User{}).
+		Where("registered_ip = ? AND created_at >= ?", ip, since).
+		Count(&count).Error
+	return count, errCount
+}
+
+// sendEmailVerification issues a new verification token and emails it to the user.
+func sendEmailVerification(ctx context.Context, db *gorm.DB, user *models.User) error {
+	email := strings.TrimSpace(user.Email)
+	if email == "" {
+		return errors.New("missing email")
+	}
+	token, errToken := security.GenerateRandomString(48)
+	if errToken != nil {
+		return errToken
+	}
+	now := time.Now().UTC()
+	verification := models.EmailVerification{
+		UserID:    user.ID,
+		Email:     email,
+		Token:     token,
+		ExpiresAt: now.Add(emailVerificationTTL),
+		CreatedAt: now,
+	}
+	if errCreate := db.WithContext(ctx).Create(&verification).Error; errCreate != nil {
+		return errCreate
+	}
+
+	siteName := dbConfigString(internalsettings.SiteNameKey)
+	if siteName == "" {
+		siteName = internalsettings.DefaultSiteName
+	}
+	body := fmt.Sprintf(
+		"Hello %s,\n\nUse the verification code below to confirm your email address for %s:\n\n%s\n\nThe code expires in %d hours.\n",
+		user.Username, siteName, token, int(emailVerificationTTL/time.Hour),
+	)
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailSendTimeout)
+	defer cancel()
+	return mail.Send(sendCtx, email, siteName+" email verification", body)
+}
+
+// logVerificationSendError records a failed verification email without failing the request.
+func logVerificationSendError(userID uint64, err error) {
+	if errors.Is(err, mail.ErrNotConfigured) {
+		log.Warnf("registration: smtp not configured, verification email for user %d not sent", userID)
+		return
+	}
+	log.WithError(err).Warnf("registration: send verification email for user %d failed", userID)
+}
+
+// requireVerifiedEmail rejects the request when the policy demands a verified email the user lacks.
+func requireVerifiedEmail(c *gin.Context, db *gorm.DB, userID uint64) bool {
+	if !internalsettings.LoadRegistrationPolicy().RequireEmailVerification {
+		return true
+	}
+	var user models.User
+	if errFind := db.WithContext(c.Request.Context()).
+		Select("id", "email_verification_pending").
+		First(&user, userID).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query user failed"})
+		return false
+	}
+	if user.EmailVerificationPending {
+		c.JSON(http.StatusForbidden, gin.H{"error": "email verification required"})
+		return false
+	}
+	return true
+}
+
+// verifyEmailRequest defines the request body for email verification.
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail confirms a user's email address using a verification token.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var body verifyEmailRequest
+	if errBind := c.ShouldBindJSON(&body); errBind != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+	token := strings.TrimSpace(body.Token)
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing token"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	now := time.Now().UTC()
+	var verification models.EmailVerification
+	if errFind := h.db.WithContext(ctx).Where("token = ?", token).Take(&verification).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if verification.UsedAt != nil || !verification.ExpiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+		return
+	}
+
+	errTx := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errUse := tx.Model(&models.EmailVerification{}).
+			Where("id = ?", verification.ID).
+			Update("used_at", now).Error; errUse != nil {
+			return errUse
+		}
+		var user models.User
+		if errFind := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "email_verification_pending", "registered_ip").
+			Where("id = ? AND email = ?", verification.UserID, verification.Email).
+			Take(&user).Error; errFind != nil {
+			return errFind
+		}
+		if errUpdate := tx.Model(&models.User{}).
+			Where("id = ?", user.ID).
+			Updates(map[string]any{
+				"email_verification_pending": false,
+				"email_verified_at":          now,
+				"updated_at":                 now,
+			}).Error; errUpdate != nil {
+			return errUpdate
+		}
+		// Self-registered users awaiting verification were not granted the bonus at sign up.
+		if user.EmailVerificationPending && user.RegisteredIP != "" {
+			return grantSignupBonus(tx, user.ID, internalsettings.LoadRegistrationPolicy(), now)
+		}
+		return nil
+	})
+	if errTx != nil {
+		if errors.Is(errTx, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "verify email failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ResendVerification sends a fresh verification email to the current user.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	ctx := c.Request.Context()
+	var user models.User
+	if errFind := h.db.WithContext(ctx).First(&user, userID).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query user failed"})
+		return
+	}
+	if !user.EmailVerificationPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email already verified"})
+		return
+	}
+	if strings.TrimSpace(user.Email) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing email"})
+		return
+	}
+
+	var recent int64
+	if errCount := h.db.WithContext(ctx).Model(&models.EmailVerification{}).
+		Where("user_id = ? AND created_at >= ?", userID, time.Now().UTC().Add(-emailVerificationResendInterval)).
+		Count(&recent).Error; errCount != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	if recent > 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "verification email sent recently"})
+		return
+	}
+
+	if errSend := sendEmailVerification(ctx, h.db, &user); errSend != nil {
+		logVerificationSendError(user.ID, errSend)
+		if errors.Is(errSend, mail.ErrNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "email delivery is not configured"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "send verification email failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
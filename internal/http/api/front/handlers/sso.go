@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/sso"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// frontSSOPathPrefix is the route prefix of user SSO endpoints.
+	frontSSOPathPrefix = "/v0/front/sso/"
+	// frontSSODefaultReturn is where the browser lands after user SSO when no path was requested.
+	frontSSODefaultReturn = "/"
+)
+
+// SSOHandler handles OpenID Connect login for front-end users.
+type SSOHandler struct {
+	db      *gorm.DB
+	jwtCfg  config.JWTConfig
+	service *sso.Service
+}
+
+// NewSSOHandler constructs an SSOHandler.
+func NewSSOHandler(db *gorm.DB, jwtCfg config.JWTConfig) *SSOHandler {
+	return &SSOHandler{db: db, jwtCfg: jwtCfg, service: sso.NewService(db, nil)}
+}
+
+// callbackURL returns the redirect URI registered for a provider.
+func (h *SSOHandler) callbackURL(c *gin.Context, slug string) string {
+	return sso.CallbackURL(c.Request, frontSSOPathPrefix+slug+"/callback")
+}
+
+// Providers lists the providers users can sign in with.
+func (h *SSOHandler) Providers(c *gin.Context) {
+	rows, errList := h.service.Providers(c.Request.Context(), models.SSOKindUser)
+	if errList != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list providers failed"})
+		return
+	}
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, gin.H{"slug": row.Slug, "display_name": row.DisplayName})
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": out})
+}
+
+// Login redirects the browser to the provider's authorization endpoint.
+func (h *SSOHandler) Login(c *gin.Context) {
+	ctx := c.Request.Context()
+	provider, errProvider := h.service.LoadProvider(ctx, c.Param("provider"), models.SSOKindUser)
+	if errProvider != nil {
+		writeSSOError(c, errProvider)
+		return
+	}
+	returnTo := sso.SanitizeReturnTo(c.Query("return_to"), frontSSODefaultReturn)
+	authURL, errStart := h.service.Start(ctx, provider, models.SSOKindUser, h.callbackURL(c, provider.Slug), returnTo, nil)
+	if errStart != nil {
+		log.WithError(errStart).Warn("front sso: start login failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// ssoLinkRequest defines the request body for starting an account link.
+type ssoLinkRequest struct {
+	ReturnTo string `json:"return_to"`
+}
+
+// Link starts a flow that links a provider identity to the current user.
+func (h *SSOHandler) Link(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var body ssoLinkRequest
+	_ = c.ShouldBindJSON(&body)
+
+	ctx := c.Request.Context()
+	provider, errProvider := h.service.LoadProvider(ctx, c.Param("provider"), models.SSOKindUser)
+	if errProvider != nil {
+		writeSSOError(c, errProvider)
+		return
+	}
+	returnTo := sso.SanitizeReturnTo(body.ReturnTo, frontSSODefaultReturn)
+	authURL, errStart := h.service.Start(ctx, provider, models.SSOKindUser, h.callbackURL(c, provider.Slug), returnTo, &userID)
+	if errStart != nil {
+		log.WithError(errStart).Warn("front sso: start link failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// Callback completes the authorization code flow and hands a token to the web UI.
+func (h *SSOHandler) Callback(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := c.Param("provider")
+	state := c.Query("state")
+
+	returnTo := frontSSODefaultReturn
+	if stored, ok := h.service.PeekReturnTo(ctx, state); ok {
+		returnTo = sso.SanitizeReturnTo(stored, frontSSODefaultReturn)
+	}
+	if idpError := strings.TrimSpace(c.Query("error")); idpError != "" {
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_error": {idpError}}))
+		return
+	}
+
+	result, errComplete := h.service.Complete(ctx, models.SSOKindUser, slug, state, c.Query("code"), h.callbackURL(c, slug))
+	if errComplete != nil {
+		log.WithError(errComplete).Warn("front sso: callback failed")
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_error": {sso.ErrorCode(errComplete)}}))
+		return
+	}
+	user, errResolve := h.service.ResolveUser(ctx, result.Provider, result.Claims, result.LinkPrincipalID)
+	if errResolve != nil {
+		log.WithError(errResolve).Warnf("front sso: resolve user for provider %s failed", slug)
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_error": {sso.ErrorCode(errResolve)}}))
+		return
+	}
+	if result.LinkPrincipalID != nil {
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_linked": {slug}}))
+		return
+	}
+
+	token, errToken := security.GenerateToken(h.jwtCfg.Secret, user.ID, user.Username, user.Name, user.Email, h.jwtCfg.Expiry)
+	if errToken != nil {
+		c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_error": {"login_failed"}}))
+		return
+	}
+	c.Redirect(http.StatusFound, sso.FragmentURL(returnTo, url.Values{"sso_token": {token}}))
+}
+
+// Identities lists the SSO identities linked to the current user.
+func (h *SSOHandler) Identities(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	ctx := c.Request.Context()
+	var rows []models.SSOIdentity
+	if errFind := h.db.WithContext(ctx).
+		Where("kind = ? AND user_id = ?", models.SSOKindUser, userID).
+		Order("id ASC").
+		Find(&rows).Error; errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list identities failed"})
+		return
+	}
+
+	providers := make(map[uint64]models.OIDCProvider)
+	if len(rows) > 0 {
+		providerIDs := make([]uint64, 0, len(rows))
+		for _, row := range rows {
+			providerIDs = append(providerIDs, row.ProviderID)
+		}
+		var list []models.OIDCProvider
+		if errList := h.db.WithContext(ctx).
+			Select("id", "slug", "display_name").
+			Where("id IN ?", providerIDs).
+			Find(&list).Error; errList == nil {
+			for _, provider := range list {
+				providers[provider.ID] = provider
+			}
+		}
+	}
+
+	out := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		provider := providers[row.ProviderID]
+		out = append(out, gin.H{
+			"id":                    row.ID,
+			"provider_slug":         provider.Slug,
+			"provider_display_name": provider.DisplayName,
+			"email":                 row.Email,
+			"last_login_at":         row.LastLoginAt,
+			"created_at":            row.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": out})
+}
+
+// Unlink removes an SSO identity from the current user.
+func (h *SSOHandler) Unlink(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, errParse := strconv.ParseUint(strings.TrimSpace(c.Param("id")), 10, 64)
+	if errParse != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res := h.db.WithContext(c.Request.Context()).
+		Where("id = ? AND kind = ? AND user_id = ?", id, models.SSOKindUser, userID).
+		Delete(&models.SSOIdentity{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "unlink failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeSSOError maps flow start errors to JSON responses.
+func writeSSOError(c *gin.Context, err error) {
+	if errors.Is(err, sso.ErrProviderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "query provider failed"})
+}
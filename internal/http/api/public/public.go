@@ -0,0 +1,254 @@
+package public
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
+	"gorm.io/gorm"
+)
+
+// maxUsageRangeDays caps date ranges accepted by the usage endpoints.
+const maxUsageRangeDays = 100
+
+// RegisterPublicRoutes registers billing endpoints authenticated by API key under /v1.
+func RegisterPublicRoutes(r *gin.Engine, db *gorm.DB) {
+	if r == nil || db == nil {
+		return
+	}
+
+	handler := NewBillingHandler(db)
+	r.GET("/v1/dashboard/billing/subscription", handler.Subscription)
+	r.GET("/v1/dashboard/billing/usage", handler.DashboardUsage)
+	r.GET("/v1/billing/balance", handler.Balance)
+	r.GET("/v1/billing/usage", handler.Usage)
+}
+
+// BillingHandler serves balance and usage queries for API key holders.
+type BillingHandler struct {
+	db *gorm.DB
+}
+
+// NewBillingHandler constructs a BillingHandler.
+func NewBillingHandler(db *gorm.DB) *BillingHandler {
+	return &BillingHandler{db: db}
+}
+
+// Subscription returns an OpenAI-style billing subscription.
+// hard_limit_usd is the remaining balance plus this month's spend, so clients
+// subtracting the month's dashboard usage arrive at the remaining balance.
+func (h *BillingHandler) Subscription(c *gin.Context) {
+	payer, ok := h.resolvePayer(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	now := time.Now().UTC()
+	balance, errBalance := billing.LoadBalance(ctx, h.db, payer, now)
+	if errBalance != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query balance failed"})
+		return
+	}
+	localNow := now.In(time.Local)
+	monthStart := time.Date(localNow.Year(), localNow.Month(), 1, 0, 0, 0, 0, time.Local)
+	monthSpend, errSpend := billing.SpendBetween(ctx, h.db, payer, monthStart, monthStart.AddDate(0, 1, 0))
+	if errSpend != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usage failed"})
+		return
+	}
+
+	limit := balance.Remaining() + monthSpend
+	var accessUntil int64
+	if balance.AccessUntil != nil {
+		accessUntil = balance.AccessUntil.Unix()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":                "billing_subscription",
+		"has_payment_method":    balance.Remaining() > 0,
+		"soft_limit_usd":        limit,
+		"hard_limit_usd":        limit,
+		"system_hard_limit_usd": limit,
+		"access_until":          accessUntil,
+	})
+}
+
+// DashboardUsage returns OpenAI-style daily costs in cents for [start_date, end_date).
+func (h *BillingHandler) DashboardUsage(c *gin.Context) {
+	payer, ok := h.resolvePayer(c)
+	if !ok {
+		return
+	}
+	from, to, okRange := parseDateRange(c)
+	if !okRange {
+		return
+	}
+
+	rows, errUsage := billing.LoadDailyModelUsage(c.Request.Context(), h.db, payer, from, to)
+	if errUsage != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usage failed"})
+		return
+	}
+	dailyCosts := make([]gin.H, 0)
+	var totalMicros int64
+	next := 0
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		lineItems := make([]gin.H, 0)
+		for ; next < len(rows) && rows[next].Day.Equal(day); next++ {
+			totalMicros += rows[next].CostMicros
+			lineItems = append(lineItems, gin.H{"name": rows[next].Model, "cost": microsToCents(rows[next].CostMicros)})
+		}
+		dailyCosts = append(dailyCosts, gin.H{"timestamp": day.Unix(), "line_items": lineItems})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object":      "list",
+		"daily_costs": dailyCosts,
+		"total_usage": microsToCents(totalMicros),
+	})
+}
+
+// Balance returns remaining bill quota, daily quota and prepaid balance.
+func (h *BillingHandler) Balance(c *gin.Context) {
+	payer, ok := h.resolvePayer(c)
+	if !ok {
+		return
+	}
+	balance, errBalance := billing.LoadBalance(c.Request.Context(), h.db, payer, time.Now().UTC())
+	if errBalance != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query balance failed"})
+		return
+	}
+
+	var dailyLeft *float64
+	if left := balance.DailyLeft(); left >= 0 {
+		dailyLeft = &left
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":           "billing_balance",
+		"organization_id":  payer.OrganizationID,
+		"bill_total_quota": balance.BillTotalQuota,
+		"bill_left_quota":  balance.BillLeftQuota,
+		"daily_quota":      balance.DailyQuota,
+		"daily_unlimited":  balance.DailyUnlimited,
+		"used_today":       balance.UsedToday,
+		"daily_left":       dailyLeft,
+		"prepaid_balance":  balance.PrepaidBalance,
+		"remaining":        balance.Remaining(),
+		"access_until":     balance.AccessUntil,
+	})
+}
+
+// Usage returns per-model usage for [start_date, end_date).
+func (h *BillingHandler) Usage(c *gin.Context) {
+	payer, ok := h.resolvePayer(c)
+	if !ok {
+		return
+	}
+	from, to, okRange := parseDateRange(c)
+	if !okRange {
+		return
+	}
+	rows, errUsage := billing.LoadModelUsage(c.Request.Context(), h.db, payer, from, to)
+	if errUsage != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query usage failed"})
+		return
+	}
+
+	data := make([]gin.H, 0, len(rows))
+	var totalMicros int64
+	for _, row := range rows {
+		totalMicros += row.CostMicros
+		data = append(data, gin.H{
+			"model":            row.Model,
+			"requests":         row.Requests,
+			"failed_requests":  row.FailedRequests,
+			"input_tokens":     row.InputTokens,
+			"output_tokens":    row.OutputTokens,
+			"cached_tokens":    row.CachedTokens,
+			"reasoning_tokens": row.ReasoningTokens,
+			"total_tokens":     row.TotalTokens,
+			"cost":             float64(row.CostMicros) / 1_000_000,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":     "list",
+		"start_date": from.Format(time.DateOnly),
+		"end_date":   to.Format(time.DateOnly),
+		"data":       data,
+		"total_cost": float64(totalMicros) / 1_000_000,
+	})
+}
+
+// resolvePayer loads the payer for the authenticated API key, writing an error response on failure.
+func (h *BillingHandler) resolvePayer(c *gin.Context) (billing.Payer, bool) {
+	userID := accessUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "api key is not bound to a user"})
+		return billing.Payer{}, false
+	}
+	payer, errPayer := billing.ResolvePayer(c.Request.Context(), h.db, userID)
+	if errPayer != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve payer failed"})
+		return billing.Payer{}, false
+	}
+	return payer, true
+}
+
+// accessUserID extracts the user ID from API key access metadata.
+func accessUserID(c *gin.Context) uint64 {
+	v, exists := c.Get("accessMetadata")
+	if !exists {
+		return 0
+	}
+	meta, ok := v.(map[string]string)
+	if !ok {
+		return 0
+	}
+	parsed, errParse := strconv.ParseUint(strings.TrimSpace(meta["user_id"]), 10, 64)
+	if errParse != nil {
+		return 0
+	}
+	return parsed
+}
+
+// parseDateRange parses start_date and exclusive end_date in local time, defaulting to the last 30 days.
+func parseDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now().In(time.Local)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from := today.AddDate(0, 0, -29)
+	to := today.AddDate(0, 0, 1)
+
+	if raw := strings.TrimSpace(c.Query("start_date")); raw != "" {
+		parsed, errParse := time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("end_date")); raw != "" {
+		parsed, errParse := time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must be before end_date"})
+		return time.Time{}, time.Time{}, false
+	}
+	if from.AddDate(0, 0, maxUsageRangeDays).Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date range must not exceed 100 days"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// microsToCents converts cost micros to cents.
+func microsToCents(micros int64) float64 {
+	return float64(micros) / 10_000
+}
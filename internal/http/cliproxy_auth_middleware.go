@@ -8,7 +8,10 @@ import (
 	"github.com/gin-gonic/gin"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tracing"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // CLIProxyAuthMiddleware enforces CLIProxyAPI authentication on selected routes.
@@ -27,7 +30,19 @@ func CLIProxyAuthMiddleware(manager *sdkaccess.Manager, websocketAuth bool) gin.
 			return
 		}
 
-		result, err := manager.Authenticate(c.Request.Context(), c.Request)
+		spanCtx, span := tracing.Start(c.Request.Context(), "access.authenticate")
+		result, err := manager.Authenticate(spanCtx, c.Request)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "authentication failed")
+		} else if result != nil {
+			span.SetAttributes(
+				attribute.String("cpab.access.provider", result.Provider),
+				attribute.String("cpab.user_id", result.Metadata["user_id"]),
+				attribute.String("cpab.api_key_id", result.Metadata["api_key_id"]),
+			)
+		}
+		span.End()
 		if err == nil {
 			if result != nil {
 				c.Set("apiKey", result.Principal)
@@ -47,6 +62,8 @@ func CLIProxyAuthMiddleware(manager *sdkaccess.Manager, websocketAuth bool) gin.
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 		case errors.Is(err, access.ErrInsufficientBalance):
 			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{"error": "Insufficient balance"})
+		case errors.Is(err, access.ErrSpendLimitExceeded):
+			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{"error": "Spend limit exceeded"})
 		default:
 			log.Errorf("authentication middleware error: %v", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Authentication service error"})
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+)
+
+// RelayMetricsMiddleware records request counts and latency for relay routes.
+func RelayMetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil || c.Request == nil || c.Request.URL == nil || !isRelayPath(c.Request.URL.Path) {
+			if c != nil {
+				c.Next()
+			}
+			return
+		}
+
+		started := time.Now()
+		c.Next()
+
+		provider, model := metrics.RelayTarget(c)
+		metrics.ObserveRelayRequest(provider, model, c.Writer.Status(), time.Since(started))
+	}
+}
+
+// isRelayPath reports whether a path is served by the upstream relay.
+func isRelayPath(path string) bool {
+	return hasPathPrefix(path, "/v1") || hasPathPrefix(path, "/v1beta") || hasPathPrefix(path, "/api")
+}
@@ -0,0 +1,216 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelcatalog"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+// ModelCatalogMiddleware marks requests for deprecated models with warning headers and
+// redirects requests for sunset models to their replacement.
+func ModelCatalogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil || c.Request == nil || c.Request.URL == nil || c.Request.Method != http.MethodPost || !modelcatalog.HasEntries() {
+			if c != nil {
+				c.Next()
+			}
+			return
+		}
+
+		path := c.Request.URL.Path
+		switch {
+		case hasPathPrefix(path, "/v1beta/models"):
+			handleGeminiModelCatalog(c)
+		case hasPathPrefix(path, "/v1") && !access.IsBillingQueryPath(path):
+			handleBodyModelCatalog(c)
+		}
+		if !c.IsAborted() {
+			c.Next()
+		}
+	}
+}
+
+// handleBodyModelCatalog applies catalogue rules to the "model" field of a JSON request body.
+func handleBodyModelCatalog(c *gin.Context) {
+	if c.Request.Body == nil {
+		return
+	}
+	rawBody, errRead := io.ReadAll(c.Request.Body)
+	if errRead != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "read request body failed"})
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(rawBody))
+
+	var fields map[string]json.RawMessage
+	if errUnmarshal := json.Unmarshal(rawBody, &fields); errUnmarshal != nil {
+		return
+	}
+	var model string
+	if errModel := json.Unmarshal(fields["model"], &model); errModel != nil || strings.TrimSpace(model) == "" {
+		return
+	}
+
+	target, ok := applyModelCatalog(c, model)
+	if !ok || target == model {
+		return
+	}
+	encodedTarget, errTarget := json.Marshal(target)
+	if errTarget != nil {
+		return
+	}
+	fields["model"] = encodedTarget
+	rewritten, errMarshal := json.Marshal(fields)
+	if errMarshal != nil {
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(rewritten))
+	c.Request.ContentLength = int64(len(rewritten))
+	c.Request.Header.Set("Content-Length", strconv.Itoa(len(rewritten)))
+}
+
+// handleGeminiModelCatalog applies catalogue rules to the model named in a Gemini action path.
+func handleGeminiModelCatalog(c *gin.Context) {
+	action := c.Param("action")
+	name, method, _ := strings.Cut(strings.TrimPrefix(action, "/"), ":")
+	if strings.TrimSpace(name) == "" {
+		return
+	}
+	target, ok := applyModelCatalog(c, name)
+	if !ok || target == name {
+		return
+	}
+	rewritten := "/" + target
+	if method != "" {
+		rewritten += ":" + method
+	}
+	for i := range c.Params {
+		if c.Params[i].Key == "action" {
+			c.Params[i].Value = rewritten
+		}
+	}
+	c.Request.URL.Path = strings.TrimSuffix(c.Request.URL.Path, action) + rewritten
+}
+
+// applyModelCatalog sets lifecycle headers for model and returns the model that should serve the request.
+// It aborts with 410 Gone and returns false when the model is sunset without a replacement.
+func applyModelCatalog(c *gin.Context, model string) (string, bool) {
+	entry, found := modelcatalog.Lookup(model)
+	if !found {
+		return model, true
+	}
+	now := time.Now().UTC()
+	status := modelcatalog.StatusAt(entry, now)
+	if status == modelcatalog.StatusActive {
+		return model, true
+	}
+
+	setDeprecationHeaders(c, entry)
+	if status == modelcatalog.StatusDeprecated {
+		message := fmt.Sprintf("model %s is deprecated", model)
+		if entry.SunsetAt != nil {
+			message += " and will be retired on " + entry.SunsetAt.UTC().Format(time.DateOnly)
+		}
+		if target := strings.TrimSpace(entry.SunsetTarget); target != "" {
+			message += "; use " + target
+		}
+		c.Header("Warning", warningHeader(message))
+		return model, true
+	}
+
+	target, redirected, ok := modelcatalog.Resolve(model, now)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusGone, gin.H{"error": fmt.Sprintf("model %s has been retired", model)})
+		return model, false
+	}
+	if redirected {
+		c.Header("Warning", warningHeader(fmt.Sprintf("model %s has been retired; request served by %s", model, target)))
+	}
+	return target, true
+}
+
+// setDeprecationHeaders writes the Deprecation and Sunset headers for a catalogue entry.
+func setDeprecationHeaders(c *gin.Context, entry models.ModelCatalogEntry) {
+	if entry.DeprecatedAt != nil {
+		c.Header("Deprecation", "@"+strconv.FormatInt(entry.DeprecatedAt.Unix(), 10))
+	} else {
+		c.Header("Deprecation", "true")
+	}
+	if entry.SunsetAt != nil {
+		c.Header("Sunset", entry.SunsetAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// warningHeader formats a miscellaneous persistent warning.
+func warningHeader(message string) string {
+	return `299 - ` + strconv.Quote(message)
+}
+
+// applyCatalogEntry overlays catalogue metadata on a model listing entry.
+func applyCatalogEntry(result map[string]any, modelID string, handlerType string) {
+	if result == nil {
+		return
+	}
+	entry, found := modelcatalog.Lookup(modelID)
+	if !found {
+		return
+	}
+
+	if handlerType == "gemini" {
+		if entry.DisplayName != "" {
+			result["displayName"] = entry.DisplayName
+		}
+		if entry.Description != "" {
+			result["description"] = entry.Description
+		}
+		if entry.ContextLength > 0 {
+			result["inputTokenLimit"] = entry.ContextLength
+		}
+		if entry.MaxOutputTokens > 0 {
+			result["outputTokenLimit"] = entry.MaxOutputTokens
+		}
+		return
+	}
+
+	if entry.DisplayName != "" {
+		result["display_name"] = entry.DisplayName
+	}
+	if entry.Description != "" {
+		result["description"] = entry.Description
+	}
+	if entry.ContextLength > 0 {
+		result["context_length"] = entry.ContextLength
+	}
+	if entry.MaxOutputTokens > 0 {
+		result["max_completion_tokens"] = entry.MaxOutputTokens
+	}
+	result["capabilities"] = map[string]bool{
+		"vision":    entry.SupportsVision,
+		"tools":     entry.SupportsTools,
+		"reasoning": entry.SupportsReasoning,
+	}
+	if entry.ReleasedAt != nil {
+		result["released_at"] = entry.ReleasedAt.Unix()
+	}
+	status := modelcatalog.StatusAt(entry, time.Now().UTC())
+	result["deprecated"] = status != modelcatalog.StatusActive
+	if entry.DeprecatedAt != nil {
+		result["deprecated_at"] = entry.DeprecatedAt.Unix()
+	}
+	if entry.SunsetAt != nil {
+		result["sunset_at"] = entry.SunsetAt.Unix()
+	}
+	if target := strings.TrimSpace(entry.SunsetTarget); target != "" {
+		result["sunset_target"] = target
+	}
+}
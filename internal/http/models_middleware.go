@@ -12,6 +12,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	sdkcliproxy "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelaccess"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelcatalog"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelregistry"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
@@ -38,13 +40,25 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 		switch path {
 		case "/v1/models":
 			onlyMapped := dbConfigBool("ONLY_MAPPED_MODELS")
-			userGroups, billUserGroups, okUser := loadUserGroupMembership(c, db)
+			visibility, okUser := loadUserGroupMembership(c, db)
 			userAgent := c.GetHeader("User-Agent")
 			if strings.HasPrefix(userAgent, "claude-cli") {
 				if !onlyMapped {
 					data := sdkcliproxy.GlobalModelRegistry().GetAvailableModels("claude")
 					if okUser {
-						data = filterOpenAIRegistryModelsByUserGroups(data, "claude", userGroups, billUserGroups)
+						data = filterOpenAIRegistryModelsByUserGroups(data, "claude", visibility)
+					}
+					for i, model := range data {
+						if id, ok := model["id"].(string); ok {
+							if _, found := modelcatalog.Lookup(id); found {
+								overlaid := make(map[string]any, len(model))
+								for k, v := range model {
+									overlaid[k] = v
+								}
+								applyCatalogEntry(overlaid, id, "claude")
+								data[i] = overlaid
+							}
+						}
 					}
 					c.AbortWithStatusJSON(http.StatusOK, gin.H{"data": data})
 					return
@@ -57,7 +71,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 				}
 
 				if okUser {
-					modelInfos = filterModelInfosByUserGroups(modelInfos, userGroups, billUserGroups)
+					modelInfos = filterModelInfosByUserGroups(modelInfos, visibility)
 				}
 
 				data := make([]map[string]any, 0, len(modelInfos))
@@ -77,10 +91,8 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 				for _, model := range allModels {
 					if okUser {
 						if id, ok := model["id"].(string); ok && strings.TrimSpace(id) != "" {
-							if allowed, okAllowed := modelmapping.LookupUserGroupIDs("openai", strings.TrimSpace(id)); okAllowed && len(allowed.Clean()) > 0 {
-								if !hasAnyAllowedUserGroup(allowed, userGroups, billUserGroups) {
-									continue
-								}
+							if !visibility.allows("openai", strings.TrimSpace(id)) {
+								continue
 							}
 						}
 					}
@@ -94,6 +106,9 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 					if ownedBy, exists := model["owned_by"]; exists {
 						filteredModel["owned_by"] = ownedBy
 					}
+					if id, ok := model["id"].(string); ok {
+						applyCatalogEntry(filteredModel, id, "openai")
+					}
 					filtered = append(filtered, filteredModel)
 				}
 				c.AbortWithStatusJSON(http.StatusOK, gin.H{"object": "list", "data": filtered})
@@ -107,7 +122,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 			}
 
 			if okUser {
-				modelInfos = filterModelInfosByUserGroups(modelInfos, userGroups, billUserGroups)
+				modelInfos = filterModelInfosByUserGroups(modelInfos, visibility)
 			}
 
 			data := make([]map[string]any, 0, len(modelInfos))
@@ -131,12 +146,12 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 
 		case "/v1beta/models":
 			onlyMapped := dbConfigBool("ONLY_MAPPED_MODELS")
-			userGroups, billUserGroups, okUser := loadUserGroupMembership(c, db)
+			visibility, okUser := loadUserGroupMembership(c, db)
 			rawModels := make([]map[string]any, 0)
 			if !onlyMapped {
 				rawModels = sdkcliproxy.GlobalModelRegistry().GetAvailableModels("gemini")
 				if okUser {
-					rawModels = filterGeminiRegistryModelsByUserGroups(rawModels, userGroups, billUserGroups)
+					rawModels = filterGeminiRegistryModelsByUserGroups(rawModels, visibility)
 				}
 			} else {
 				modelInfos, errList := listMappedModelInfos(c.Request.Context(), db, store)
@@ -145,7 +160,7 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 					return
 				}
 				if okUser {
-					modelInfos = filterModelInfosByUserGroups(modelInfos, userGroups, billUserGroups)
+					modelInfos = filterModelInfosByUserGroups(modelInfos, visibility)
 				}
 
 				rawModels = make([]map[string]any, 0, len(modelInfos))
@@ -167,6 +182,9 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 				if name, ok := normalizedModel["name"].(string); ok && name != "" && !strings.HasPrefix(name, "models/") {
 					normalizedModel["name"] = "models/" + name
 				}
+				if name, ok := model["name"].(string); ok && !onlyMapped {
+					applyCatalogEntry(normalizedModel, strings.TrimPrefix(strings.TrimSpace(name), "models/"), "gemini")
+				}
 				if _, ok := normalizedModel["supportedGenerationMethods"]; !ok {
 					normalizedModel["supportedGenerationMethods"] = defaultMethods
 				}
@@ -182,33 +200,62 @@ func CLIProxyModelsMiddleware(db *gorm.DB, store *modelregistry.Store) gin.Handl
 	}
 }
 
-func loadUserGroupMembership(c *gin.Context, db *gorm.DB) (models.UserGroupIDs, models.UserGroupIDs, bool) {
+// modelVisibility holds the group memberships and per-user overrides that decide which models a user sees.
+type modelVisibility struct {
+	userGroups     models.UserGroupIDs
+	billUserGroups models.UserGroupIDs
+	overrides      modelaccess.Overrides
+}
+
+// allows reports whether the user may see a model: overrides win, then mapping user groups apply.
+func (v modelVisibility) allows(provider, model string) bool {
+	if v.overrides.Denies(provider, model) {
+		return false
+	}
+	if v.overrides.Allows(provider, model) {
+		return true
+	}
+	if allowed, okAllowed := modelmapping.LookupUserGroupIDs(provider, model); okAllowed && len(allowed.Clean()) > 0 {
+		return hasAnyAllowedUserGroup(allowed, v.userGroups, v.billUserGroups)
+	}
+	return true
+}
+
+func loadUserGroupMembership(c *gin.Context, db *gorm.DB) (modelVisibility, bool) {
 	if c == nil || db == nil {
-		return nil, nil, false
+		return modelVisibility{}, false
 	}
 	v, exists := c.Get("accessMetadata")
 	if !exists {
-		return nil, nil, false
+		return modelVisibility{}, false
 	}
 	meta, ok := v.(map[string]string)
 	if !ok {
-		return nil, nil, false
+		return modelVisibility{}, false
 	}
 	rawUserID := strings.TrimSpace(meta["user_id"])
 	if rawUserID == "" {
-		return nil, nil, false
+		return modelVisibility{}, false
 	}
 	parsed, errParse := strconv.ParseUint(rawUserID, 10, 64)
 	if errParse != nil || parsed == 0 {
-		return nil, nil, false
+		return modelVisibility{}, false
 	}
 	var user models.User
 	if errFind := db.WithContext(c.Request.Context()).
 		Select("user_group_id", "bill_user_group_id").
 		First(&user, parsed).Error; errFind != nil {
-		return nil, nil, false
+		return modelVisibility{}, false
 	}
-	return user.UserGroupID.Clean(), user.BillUserGroupID.Clean(), true
+	overrides, errOverrides := modelaccess.Load(c.Request.Context(), db, parsed, time.Now().UTC())
+	if errOverrides != nil {
+		return modelVisibility{}, false
+	}
+	return modelVisibility{
+		userGroups:     user.UserGroupID.Clean(),
+		billUserGroups: user.BillUserGroupID.Clean(),
+		overrides:      overrides,
+	}, true
 }
 
 func hasAnyAllowedUserGroup(allowed, userGroups, billUserGroups models.UserGroupIDs) bool {
@@ -240,7 +287,7 @@ func hasAnyAllowedUserGroup(allowed, userGroups, billUserGroups models.UserGroup
 	return false
 }
 
-func filterOpenAIRegistryModelsByUserGroups(raw []map[string]any, provider string, userGroups, billUserGroups models.UserGroupIDs) []map[string]any {
+func filterOpenAIRegistryModelsByUserGroups(raw []map[string]any, provider string, visibility modelVisibility) []map[string]any {
 	provider = strings.TrimSpace(provider)
 	if provider == "" || len(raw) == 0 {
 		return raw
@@ -249,19 +296,15 @@ func filterOpenAIRegistryModelsByUserGroups(raw []map[string]any, provider strin
 	for _, model := range raw {
 		id, _ := model["id"].(string)
 		id = strings.TrimSpace(id)
-		if id != "" {
-			if allowed, okAllowed := modelmapping.LookupUserGroupIDs(provider, id); okAllowed && len(allowed.Clean()) > 0 {
-				if !hasAnyAllowedUserGroup(allowed, userGroups, billUserGroups) {
-					continue
-				}
-			}
+		if id != "" && !visibility.allows(provider, id) {
+			continue
 		}
 		filtered = append(filtered, model)
 	}
 	return filtered
 }
 
-func filterGeminiRegistryModelsByUserGroups(raw []map[string]any, userGroups, billUserGroups models.UserGroupIDs) []map[string]any {
+func filterGeminiRegistryModelsByUserGroups(raw []map[string]any, visibility modelVisibility) []map[string]any {
 	if len(raw) == 0 {
 		return raw
 	}
@@ -273,19 +316,15 @@ func filterGeminiRegistryModelsByUserGroups(raw []map[string]any, userGroups, bi
 		if lookup == "" {
 			lookup = name
 		}
-		if lookup != "" {
-			if allowed, okAllowed := modelmapping.LookupUserGroupIDs("gemini", lookup); okAllowed && len(allowed.Clean()) > 0 {
-				if !hasAnyAllowedUserGroup(allowed, userGroups, billUserGroups) {
-					continue
-				}
-			}
+		if lookup != "" && !visibility.allows("gemini", lookup) {
+			continue
 		}
 		filtered = append(filtered, model)
 	}
 	return filtered
 }
 
-func filterModelInfosByUserGroups(raw []*sdkcliproxy.ModelInfo, userGroups, billUserGroups models.UserGroupIDs) []*sdkcliproxy.ModelInfo {
+func filterModelInfosByUserGroups(raw []*sdkcliproxy.ModelInfo, visibility modelVisibility) []*sdkcliproxy.ModelInfo {
 	if len(raw) == 0 {
 		return raw
 	}
@@ -299,12 +338,8 @@ func filterModelInfosByUserGroups(raw []*sdkcliproxy.ModelInfo, userGroups, bill
 			continue
 		}
 		provider := strings.ToLower(strings.TrimSpace(info.OwnedBy))
-		if provider != "" {
-			if allowed, okAllowed := modelmapping.LookupUserGroupIDs(provider, modelID); okAllowed && len(allowed.Clean()) > 0 {
-				if !hasAnyAllowedUserGroup(allowed, userGroups, billUserGroups) {
-					continue
-				}
-			}
+		if !visibility.allows(provider, modelID) {
+			continue
 		}
 		filtered = append(filtered, info)
 	}
@@ -365,8 +400,17 @@ func parseDBConfigBool(raw json.RawMessage) bool {
 	return false
 }
 
-// convertModelToMap converts a ModelInfo into a response map for the handler type.
+// convertModelToMap converts a ModelInfo into a response map for the handler type, overlaid with catalogue metadata.
 func convertModelToMap(model *sdkcliproxy.ModelInfo, handlerType string) map[string]any {
+	result := registryModelToMap(model, handlerType)
+	if result != nil {
+		applyCatalogEntry(result, model.ID, handlerType)
+	}
+	return result
+}
+
+// registryModelToMap converts a ModelInfo into a response map for the handler type.
+func registryModelToMap(model *sdkcliproxy.ModelInfo, handlerType string) map[string]any {
 	if model == nil {
 		return nil
 	}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
+	"gorm.io/gorm"
+)
+
+// requestIDHeader carries the request ID from and to clients.
+const requestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds accepted request IDs.
+const maxRequestIDLength = 64
+
+// sdkRequestIDKey is the gin key under which the upstream SDK logger stores the ID it
+// generated for the request; reusing it keeps server logs and usage rows correlated.
+const sdkRequestIDKey = "__request_id__"
+
+// RelayRequestLogMiddleware assigns every relay request an ID, honoring a valid incoming
+// X-Request-Id, returns it in the response headers and SSE error events, and tracks client
+// details, time to first byte and duration so the usage records it produces can be enriched.
+func RelayRequestLogMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c == nil || c.Request == nil || c.Request.URL == nil || !isRelayPath(c.Request.URL.Path) {
+			if c != nil {
+				c.Next()
+			}
+			return
+		}
+
+		requestID := resolveRequestID(c)
+		c.Header(requestIDHeader, requestID)
+		tracker := requestlog.Begin(c, db, requestID, time.Now())
+		c.Writer = &relayResponseWriter{ResponseWriter: c.Writer, tracker: tracker, requestID: requestID}
+		c.Next()
+		tracker.Finish(time.Now(), isEventStream(c.Writer))
+	}
+}
+
+// resolveRequestID returns the client-supplied request ID when valid, otherwise the ID the
+// SDK logger generated, otherwise a new random ID.
+func resolveRequestID(c *gin.Context) string {
+	if id := strings.TrimSpace(c.GetHeader(requestIDHeader)); validRequestID(id) {
+		return id
+	}
+	if v, exists := c.Get(sdkRequestIDKey); exists {
+		if id, ok := v.(string); ok && validRequestID(id) {
+			return id
+		}
+	}
+	return rand.Text()
+}
+
+// validRequestID reports whether id is non-empty, bounded and limited to characters that are
+// safe in headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isEventStream reports whether the response is a server-sent event stream.
+func isEventStream(w gin.ResponseWriter) bool {
+	return strings.HasPrefix(strings.ToLower(w.Header().Get("Content-Type")), "text/event-stream")
+}
+
+// relayResponseWriter reports the first body write of a response to the request tracker and
+// tags SSE error events with the request ID.
+type relayResponseWriter struct {
+	gin.ResponseWriter
+	tracker   *requestlog.Tracker
+	requestID string
+	written   bool
+}
+
+// Write marks the first byte and writes data, tagging it when it is an SSE error event.
+func (w *relayResponseWriter) Write(data []byte) (int, error) {
+	w.markFirstByte(len(data))
+	if tagged, ok := tagSSEError(data, w.requestID); ok && isEventStream(w.ResponseWriter) {
+		if _, errWrite := w.ResponseWriter.Write(tagged); errWrite != nil {
+			return 0, errWrite
+		}
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes s through Write.
+func (w *relayResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// markFirstByte notifies the tracker on the first non-empty write.
+func (w *relayResponseWriter) markFirstByte(n int) {
+	if w.written || n == 0 {
+		return
+	}
+	w.written = true
+	w.tracker.MarkFirstByte(time.Now(), isEventStream(w.ResponseWriter))
+}
+
+// tagSSEError adds a request_id field to the JSON payload of an SSE error event, written by
+// the relay handlers either as "event: error" or as an OpenAI-style "data: {"error": ...}".
+func tagSSEError(data []byte, requestID string) ([]byte, bool) {
+	trimmed := bytes.TrimLeft(data, "\n")
+	if requestID == "" || !(bytes.HasPrefix(trimmed, []byte("event: error\n")) || bytes.HasPrefix(trimmed, []byte(`data: {"error"`))) {
+		return nil, false
+	}
+	text := string(data)
+	body := strings.TrimLeft(text, "\n")
+	lead := text[:len(text)-len(body)]
+
+	var prefix string
+	switch {
+	case strings.HasPrefix(body, "event: error\ndata: "):
+		prefix = "event: error\ndata: "
+	case strings.HasPrefix(body, `data: {"error"`):
+		prefix = "data: "
+	default:
+		return nil, false
+	}
+	payload := body[len(prefix):]
+	end := strings.IndexByte(payload, '\n')
+	if end < 0 {
+		return nil, false
+	}
+	object := strings.TrimSpace(payload[:end])
+	if !strings.HasPrefix(object, "{") || !json.Valid([]byte(object)) {
+		return nil, false
+	}
+	idJSON, _ := json.Marshal(requestID)
+	rest := strings.TrimSpace(object[1:])
+	tagged := `{"request_id":` + string(idJSON)
+	if rest != "}" {
+		tagged += ","
+	}
+	tagged += rest
+	return []byte(lead + prefix + tagged + payload[end:]), true
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTagSSEError(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"event: error\ndata: {\"type\":\"error\"}\n\n", "event: error\ndata: {\"request_id\":\"req-1\",\"type\":\"error\"}\n\n"},
+		{"\nevent: error\ndata: {}\n\n", "\nevent: error\ndata: {\"request_id\":\"req-1\"}\n\n"},
+		{"data: {\"error\":{\"message\":\"boom\"}}\n\n", "data: {\"request_id\":\"req-1\",\"error\":{\"message\":\"boom\"}}\n\n"},
+	}
+	for _, tc := range cases {
+		got, ok := tagSSEError([]byte(tc.in), "req-1")
+		if !ok || string(got) != tc.want {
+			t.Fatalf("tagSSEError(%q) = %q, %v; want %q", tc.in, got, ok, tc.want)
+		}
+	}
+	for _, in := range []string{"data: {\"choices\":[]}\n\n", "event: error\ndata: not json\n\n", "event: error\ndata: {\"a\":1}"} {
+		if _, ok := tagSSEError([]byte(in), "req-1"); ok {
+			t.Fatalf("expected %q to be left untouched", in)
+		}
+	}
+}
+
+func TestRelayRequestLogMiddlewareRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(RelayRequestLogMiddleware(nil))
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString("data: {\"error\":{\"message\":\"boom\"}}\n\n")
+	})
+
+	serve := func(incoming string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		if incoming != "" {
+			req.Header.Set(requestIDHeader, incoming)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("client-42")
+	if got := rec.Header().Get(requestIDHeader); got != "client-42" {
+		t.Fatalf("expected incoming request ID to be echoed, got %q", got)
+	}
+	if body := rec.Body.String(); body != "data: {\"request_id\":\"client-42\",\"error\":{\"message\":\"boom\"}}\n\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	rec = serve("bad id\r\n")
+	if got := rec.Header().Get(requestIDHeader); got == "" || !validRequestID(got) || got == "bad id" {
+		t.Fatalf("expected a generated request ID, got %q", got)
+	}
+}
@@ -0,0 +1,207 @@
+// Package mail sends transactional email through the SMTP server configured in settings.
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+)
+
+// implicitTLSPort is the SMTP port that expects TLS from the first byte.
+const implicitTLSPort = 465
+
+// dialTimeout bounds the SMTP connection attempt.
+const dialTimeout = 15 * time.Second
+
+// ErrNotConfigured indicates SMTP settings are missing.
+var ErrNotConfigured = errors.New("mail: smtp not configured")
+
+// Attachment is a file attached to a Message.
+type Attachment struct {
+	Filename    string // File name shown to the recipient.
+	ContentType string // MIME type; defaults to application/octet-stream.
+	Data        []byte // File content.
+}
+
+// Message is an email with a plain-text body and optional HTML alternative and attachments.
+type Message struct {
+	To          string       // Recipient address.
+	Subject     string       // Subject line.
+	Text        string       // Plain-text body.
+	HTML        string       // Optional HTML alternative of the body.
+	Attachments []Attachment // Optional attachments.
+}
+
+// Send delivers a plain-text message using the current SMTP settings.
+func Send(ctx context.Context, to, subject, body string) error {
+	return SendMessage(ctx, Message{To: to, Subject: subject, Text: body})
+}
+
+// SendMessage delivers a message using the current SMTP settings.
+func SendMessage(ctx context.Context, msg Message) error {
+	cfg := internalsettings.LoadSMTPConfig()
+	if !cfg.Configured() {
+		return ErrNotConfigured
+	}
+	to := strings.TrimSpace(msg.To)
+	if to == "" || strings.ContainsAny(to, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("mail: invalid recipient or subject")
+	}
+	for _, attachment := range msg.Attachments {
+		if strings.ContainsAny(attachment.Filename, "\r\n\"") || strings.ContainsAny(attachment.ContentType, "\r\n") {
+			return errors.New("mail: invalid attachment")
+		}
+	}
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	var errDial error
+	if cfg.Port == implicitTLSPort {
+		conn, errDial = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: cfg.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, errDial = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if errDial != nil {
+		return fmt.Errorf("mail: dial: %w", errDial)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, errClient := smtp.NewClient(conn, cfg.Host)
+	if errClient != nil {
+		_ = conn.Close()
+		return fmt.Errorf("mail: handshake: %w", errClient)
+	}
+	defer func() { _ = client.Close() }()
+
+	if cfg.Port != implicitTLSPort {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if errTLS := client.StartTLS(&tls.Config{ServerName: cfg.Host}); errTLS != nil {
+				return fmt.Errorf("mail: starttls: %w", errTLS)
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if errAuth := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); errAuth != nil {
+			return fmt.Errorf("mail: auth: %w", errAuth)
+		}
+	}
+	if errFrom := client.Mail(cfg.From); errFrom != nil {
+		return fmt.Errorf("mail: from: %w", errFrom)
+	}
+	if errRcpt := client.Rcpt(to); errRcpt != nil {
+		return fmt.Errorf("mail: rcpt: %w", errRcpt)
+	}
+	writer, errData := client.Data()
+	if errData != nil {
+		return fmt.Errorf("mail: data: %w", errData)
+	}
+	msg.To = to
+	message := buildMessage(cfg.From, msg)
+	if _, errWrite := writer.Write([]byte(message)); errWrite != nil {
+		_ = writer.Close()
+		return fmt.Errorf("mail: write: %w", errWrite)
+	}
+	if errClose := writer.Close(); errClose != nil {
+		return fmt.Errorf("mail: close: %w", errClose)
+	}
+	return client.Quit()
+}
+
+// buildMessage renders RFC 5322 headers and the body. A plain-text message is sent as is;
+// an HTML alternative or attachments turn it into a MIME multipart message.
+func buildMessage(from string, msg Message) string {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if msg.HTML == "" && len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(crlf(msg.Text))
+		return b.String()
+	}
+
+	body := textPart(msg.Text)
+	if msg.HTML != "" {
+		body = multipartBody("alternative", []string{body, htmlPart(msg.HTML)})
+	}
+	if len(msg.Attachments) > 0 {
+		parts := []string{body}
+		for _, attachment := range msg.Attachments {
+			parts = append(parts, attachmentPart(attachment))
+		}
+		body = multipartBody("mixed", parts)
+	}
+	b.WriteString(body)
+	return b.String()
+}
+
+// crlf converts bare line feeds to CRLF line endings.
+func crlf(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// textPart renders a plain-text MIME part including its headers.
+func textPart(text string) string {
+	return "Content-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: base64\r\n\r\n" + base64Lines([]byte(crlf(text)))
+}
+
+// htmlPart renders an HTML MIME part including its headers.
+func htmlPart(html string) string {
+	return "Content-Type: text/html; charset=UTF-8\r\nContent-Transfer-Encoding: base64\r\n\r\n" + base64Lines([]byte(html))
+}
+
+// attachmentPart renders an attachment MIME part including its headers.
+func attachmentPart(attachment Attachment) string {
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return "Content-Type: " + contentType + "\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"Content-Disposition: attachment; filename=\"" + attachment.Filename + "\"\r\n\r\n" +
+		base64Lines(attachment.Data)
+}
+
+// multipartBody wraps rendered parts into a multipart entity including its Content-Type header.
+func multipartBody(subtype string, parts []string) string {
+	writer := multipart.NewWriter(io.Discard)
+	boundary := writer.Boundary()
+	var b strings.Builder
+	b.WriteString("Content-Type: multipart/" + subtype + "; boundary=" + boundary + "\r\n\r\n")
+	for _, part := range parts {
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString(part)
+		b.WriteString("\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return b.String()
+}
+
+// base64Lines encodes data as base64 wrapped at 76 characters per line.
+func base64Lines(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.String()
+}
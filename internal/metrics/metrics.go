@@ -0,0 +1,389 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// namespace prefixes every exported metric name.
+const namespace = "cpab"
+
+// Gin context keys used to carry the selected relay target to the HTTP middleware.
+const (
+	// relayProviderKey stores the provider chosen by the selector.
+	relayProviderKey = "relayProvider"
+	// relayModelKey stores the model chosen by the selector.
+	relayModelKey = "relayModel"
+)
+
+// unknownLabel fills labels whose value could not be resolved.
+const unknownLabel = "unknown"
+
+// registry holds every collector exposed by Handler.
+var registry = prometheus.NewRegistry()
+
+// modelSource reports whether a provider model is configured; unset accepts every model.
+var modelSource atomic.Pointer[func(provider, model string) bool]
+
+// Relay and business layer collectors.
+var (
+	relayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "relay",
+		Name:      "requests_total",
+		Help:      "Relay requests handled, by provider, model and HTTP status.",
+	}, []string{"provider", "model", "status"})
+
+	relayDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "relay",
+		Name:      "request_duration_seconds",
+		Help:      "Relay request latency, by provider, model and HTTP status.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"provider", "model", "status"})
+
+	upstreamResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "results_total",
+		Help:      "Upstream call results reported by the auth manager, by provider, model and HTTP status.",
+	}, []string{"provider", "model", "status"})
+
+	selectorPicks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "selector",
+		Name:      "picks_total",
+		Help:      "Auth selector decisions, by provider, model, strategy and outcome.",
+	}, []string{"provider", "model", "strategy", "outcome"})
+
+	rateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "selector",
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter, by decision scope.",
+	}, []string{"scope"})
+
+	usageRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "billing",
+		Name:      "usage_records_total",
+		Help:      "Usage records persisted, by provider, model and failure flag.",
+	}, []string{"provider", "model", "failed"})
+
+	billedCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "billing",
+		Name:      "cost_micros_total",
+		Help:      "Billed cost in micros, by provider and model.",
+	}, []string{"provider", "model"})
+
+	billedTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "billing",
+		Name:      "tokens_total",
+		Help:      "Metered tokens, by provider, model and token type.",
+	}, []string{"provider", "model", "type"})
+
+	quotaPolls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "quota",
+		Name:      "polls_total",
+		Help:      "Quota poll attempts, by provider and result.",
+	}, []string{"provider", "result"})
+
+	quotaRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "quota",
+		Name:      "remaining_ratio",
+		Help:      "Upstream remaining quota fraction (0-1), by auth, provider and quota bucket.",
+	}, []string{"auth_id", "provider", "bucket"})
+
+	watcherPollDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "watcher",
+		Name:      "poll_duration_seconds",
+		Help:      "Database watcher poll duration, by source.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"source"})
+)
+
+// init registers the default and application collectors.
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		relayRequests,
+		relayDuration,
+		upstreamResults,
+		selectorPicks,
+		rateLimitRejections,
+		usageRecords,
+		billedCost,
+		billedTokens,
+		quotaPolls,
+		quotaRemaining,
+		watcherPollDuration,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format, requiring a bearer token when set.
+// Without a token only loopback clients are served.
+func Handler(token string) gin.HandlerFunc {
+	token = strings.TrimSpace(token)
+	inner := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		if token == "" {
+			if !isLoopback(c.Request.RemoteAddr) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "metrics token not configured"})
+				return
+			}
+		} else {
+			provided := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid metrics token"})
+				return
+			}
+		}
+		inner.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// isLoopback reports whether the connection's remote address is a loopback address. It ignores
+// forwarding headers, which clients control.
+func isLoopback(remoteAddr string) bool {
+	host, _, errSplit := net.SplitHostPort(remoteAddr)
+	if errSplit != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// RegisterDBStats exposes connection pool statistics for the given database.
+func RegisterDBStats(sqlDB *sql.DB, dbName string) error {
+	if sqlDB == nil {
+		return nil
+	}
+	return registry.Register(collectors.NewDBStatsCollector(sqlDB, dbName))
+}
+
+// RegisterAuthSource exposes auth availability counts computed from the supplied lister.
+func RegisterAuthSource(list func() []*coreauth.Auth) error {
+	if list == nil {
+		return nil
+	}
+	return registry.Register(&authCollector{list: list})
+}
+
+// RegisterModelSource bounds model labels to models the supplied check reports as configured;
+// other models, which clients can name freely, are recorded as unknown.
+func RegisterModelSource(known func(provider, model string) bool) {
+	if known == nil {
+		return
+	}
+	modelSource.Store(&known)
+}
+
+// SetRelayTarget records the selected provider and model on the request's gin context.
+func SetRelayTarget(ctx context.Context, provider, model string) {
+	if ctx == nil {
+		return
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return
+	}
+	ginCtx.Set(relayProviderKey, provider)
+	ginCtx.Set(relayModelKey, model)
+}
+
+// RelayTarget returns the provider and model recorded by SetRelayTarget.
+func RelayTarget(c *gin.Context) (string, string) {
+	if c == nil {
+		return "", ""
+	}
+	return c.GetString(relayProviderKey), c.GetString(relayModelKey)
+}
+
+// ObserveRelayRequest records a completed relay request.
+func ObserveRelayRequest(provider, model string, status int, elapsed time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	provider, model = labelOrUnknown(provider), modelLabel(provider, model)
+	relayRequests.WithLabelValues(provider, model, statusLabel).Inc()
+	relayDuration.WithLabelValues(provider, model, statusLabel).Observe(elapsed.Seconds())
+}
+
+// ObserveUpstreamResult records an upstream call outcome reported by the auth manager.
+func ObserveUpstreamResult(provider, model string, success bool, status int) {
+	statusLabel := "ok"
+	switch {
+	case status > 0:
+		statusLabel = strconv.Itoa(status)
+	case !success:
+		statusLabel = "error"
+	}
+	upstreamResults.WithLabelValues(labelOrUnknown(provider), modelLabel(provider, model), statusLabel).Inc()
+}
+
+// ObserveSelection records a selector decision.
+func ObserveSelection(provider, model, strategy, outcome string) {
+	selectorPicks.WithLabelValues(labelOrUnknown(provider), modelLabel(provider, model), labelOrUnknown(strategy), labelOrUnknown(outcome)).Inc()
+}
+
+// ObserveRateLimitRejection records a request rejected by the rate limiter.
+func ObserveRateLimitRejection(scope string) {
+	rateLimitRejections.WithLabelValues(labelOrUnknown(scope)).Inc()
+}
+
+// UsageSample carries the billed values of a persisted usage record.
+type UsageSample struct {
+	Provider        string
+	Model           string
+	Failed          bool
+	CostMicros      int64
+	InputTokens     int64
+	OutputTokens    int64
+	ReasoningTokens int64
+	CachedTokens    int64
+}
+
+// ObserveUsage records billed cost and token counters for a usage record.
+func ObserveUsage(sample UsageSample) {
+	provider, model := labelOrUnknown(sample.Provider), modelLabel(sample.Provider, sample.Model)
+	usageRecords.WithLabelValues(provider, model, strconv.FormatBool(sample.Failed)).Inc()
+	if sample.CostMicros > 0 {
+		billedCost.WithLabelValues(provider, model).Add(float64(sample.CostMicros))
+	}
+	addTokens(provider, model, "input", sample.InputTokens)
+	addTokens(provider, model, "output", sample.OutputTokens)
+	addTokens(provider, model, "reasoning", sample.ReasoningTokens)
+	addTokens(provider, model, "cached", sample.CachedTokens)
+}
+
+// addTokens increments the token counter when the count is positive.
+func addTokens(provider, model, tokenType string, count int64) {
+	if count <= 0 {
+		return
+	}
+	billedTokens.WithLabelValues(provider, model, tokenType).Add(float64(count))
+}
+
+// ObserveQuotaPoll records the outcome of a quota poll.
+func ObserveQuotaPoll(provider string, ok bool) {
+	result := "success"
+	if !ok {
+		result = "failure"
+	}
+	quotaPolls.WithLabelValues(labelOrUnknown(provider), result).Inc()
+}
+
+// SetQuotaRemaining replaces the remaining quota fractions reported for an auth.
+func SetQuotaRemaining(authID uint64, provider string, remaining map[string]float64) {
+	authLabel := strconv.FormatUint(authID, 10)
+	quotaRemaining.DeletePartialMatch(prometheus.Labels{"auth_id": authLabel})
+	for bucket, fraction := range remaining {
+		quotaRemaining.WithLabelValues(authLabel, labelOrUnknown(provider), labelOrUnknown(bucket)).Set(fraction)
+	}
+}
+
+// ForgetQuotaRemaining drops remaining quota series for auths that are no longer polled.
+func ForgetQuotaRemaining(authID uint64) {
+	quotaRemaining.DeletePartialMatch(prometheus.Labels{"auth_id": strconv.FormatUint(authID, 10)})
+}
+
+// ObserveWatcherPoll records how long a watcher poll took.
+func ObserveWatcherPoll(source string, elapsed time.Duration) {
+	watcherPollDuration.WithLabelValues(labelOrUnknown(source)).Observe(elapsed.Seconds())
+}
+
+// labelOrUnknown trims a label value and substitutes a placeholder when empty.
+func labelOrUnknown(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return unknownLabel
+	}
+	return value
+}
+
+// modelLabel returns the model label, substituting the placeholder for unconfigured models.
+func modelLabel(provider, model string) string {
+	label := labelOrUnknown(model)
+	if label == unknownLabel {
+		return label
+	}
+	if known := modelSource.Load(); known != nil && !(*known)(strings.TrimSpace(provider), label) {
+		return unknownLabel
+	}
+	return label
+}
+
+// authCollector reports auth availability gauges on each scrape.
+type authCollector struct {
+	list func() []*coreauth.Auth
+	mu   sync.Mutex
+}
+
+// authStatesDesc describes the auth availability gauge.
+var authStatesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "auths", "count"),
+	"Auths known to the auth manager, by provider and state.",
+	[]string{"provider", "state"},
+	nil,
+)
+
+// Describe implements prometheus.Collector.
+func (c *authCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- authStatesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *authCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// stateKey groups auth counts by provider and state.
+	type stateKey struct {
+		provider string
+		state    string
+	}
+	counts := make(map[stateKey]int)
+	now := time.Now()
+	for _, auth := range c.list() {
+		if auth == nil {
+			continue
+		}
+		counts[stateKey{provider: labelOrUnknown(auth.Provider), state: authState(auth, now)}]++
+	}
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(authStatesDesc, prometheus.GaugeValue, float64(count), key.provider, key.state)
+	}
+}
+
+// authState classifies an auth for the availability gauge.
+func authState(auth *coreauth.Auth, now time.Time) string {
+	switch {
+	case auth.Disabled || auth.Status == coreauth.StatusDisabled:
+		return "disabled"
+	case auth.Unavailable && auth.NextRetryAfter.After(now):
+		if auth.Quota.Exceeded {
+			return "cooldown"
+		}
+		return "unavailable"
+	default:
+		return "available"
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func serveMetrics(t *testing.T, token, remoteAddr, authorization string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/metrics", Handler(token))
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", "127.0.0.1")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestHandlerRequiresToken(t *testing.T) {
+	cases := []struct {
+		authorization string
+		want          int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+	for _, tc := range cases {
+		if got := serveMetrics(t, "secret", "192.0.2.1:4000", tc.authorization); got != tc.want {
+			t.Fatalf("authorization %q: got status %d, want %d", tc.authorization, got, tc.want)
+		}
+	}
+}
+
+func TestHandlerWithoutTokenServesLoopbackOnly(t *testing.T) {
+	cases := map[string]int{
+		"127.0.0.1:4000":   http.StatusOK,
+		"[::1]:4000":       http.StatusOK,
+		"192.0.2.1:4000":   http.StatusForbidden,
+		"[2001:db8::1]:80": http.StatusForbidden,
+	}
+	for remoteAddr, want := range cases {
+		if got := serveMetrics(t, "", remoteAddr, ""); got != want {
+			t.Fatalf("remote %s: got status %d, want %d", remoteAddr, got, want)
+		}
+	}
+}
+
+func TestSelectionLabelsUnconfiguredModelsAsUnknown(t *testing.T) {
+	RegisterModelSource(func(provider, model string) bool { return provider == "claude" && model == "claude-x" })
+	defer modelSource.Store(nil)
+
+	ObserveSelection("claude", "claude-x", "round_robin", "selected")
+	ObserveSelection("claude", "made-up-model", "round_robin", "selected")
+	ObserveSelection("claude", "another-made-up-model", "round_robin", "selected")
+
+	if got := testutil.ToFloat64(selectorPicks.WithLabelValues("claude", "claude-x", "round_robin", "selected")); got != 1 {
+		t.Fatalf("expected configured model to keep its label, got %v", got)
+	}
+	if got := testutil.ToFloat64(selectorPicks.WithLabelValues("claude", unknownLabel, "round_robin", "selected")); got != 2 {
+		t.Fatalf("expected unconfigured models under %q, got %v", unknownLabel, got)
+	}
+}
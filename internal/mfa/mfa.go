@@ -0,0 +1,341 @@
+// Package mfa stores passkeys and recovery codes shared by user and admin accounts.
+package mfa
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeCount is the number of recovery codes issued per generation.
+const RecoveryCodeCount = 10
+
+// recoveryAlphabet avoids characters that are easy to confuse when typed.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// DefaultPasskeyName labels passkeys registered without a name.
+const DefaultPasskeyName = "Passkey"
+
+// maxPasskeyNameLength bounds passkey labels.
+const maxPasskeyNameLength = 64
+
+// ErrNotFound indicates the credential does not exist for the owner.
+var ErrNotFound = errors.New("mfa: credential not found")
+
+// Status summarises the MFA factors enrolled for an account.
+type Status struct {
+	TOTPEnabled            bool // Whether a TOTP secret is configured.
+	Passkeys               int  // Number of registered passkeys.
+	RecoveryCodesRemaining int  // Unused recovery codes.
+}
+
+// Enabled reports whether any primary MFA factor is enrolled.
+func (s Status) Enabled() bool {
+	return s.TOTPEnabled || s.Passkeys > 0
+}
+
+// LoadStatus counts the factors enrolled for an owner.
+func LoadStatus(ctx context.Context, db *gorm.DB, kind string, ownerID uint64, totpSecret string) (Status, error) {
+	status := Status{TOTPEnabled: strings.TrimSpace(totpSecret) != ""}
+	var passkeys int64
+	if errCount := db.WithContext(ctx).Model(&models.PasskeyCredential{}).
+		Where("owner_kind = ? AND owner_id = ?", kind, ownerID).
+		Count(&passkeys).Error; errCount != nil {
+		return status, errCount
+	}
+	var codes int64
+	if errCount := db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("owner_kind = ? AND owner_id = ? AND used_at IS NULL", kind, ownerID).
+		Count(&codes).Error; errCount != nil {
+		return status, errCount
+	}
+	status.Passkeys = int(passkeys)
+	status.RecoveryCodesRemaining = int(codes)
+	return status, nil
+}
+
+// ListPasskeys returns the owner's passkeys ordered by creation.
+func ListPasskeys(ctx context.Context, db *gorm.DB, kind string, ownerID uint64) ([]models.PasskeyCredential, error) {
+	var rows []models.PasskeyCredential
+	errFind := db.WithContext(ctx).
+		Where("owner_kind = ? AND owner_id = ?", kind, ownerID).
+		Order("id ASC").
+		Find(&rows).Error
+	return rows, errFind
+}
+
+// WebAuthnCredentials converts stored passkeys into WebAuthn credentials.
+func WebAuthnCredentials(rows []models.PasskeyCredential) []webauthn.Credential {
+	out := make([]webauthn.Credential, 0, len(rows))
+	for _, row := range rows {
+		flags := webauthn.CredentialFlags{}
+		if row.BackupEligible != nil {
+			flags.BackupEligible = *row.BackupEligible
+		}
+		if row.BackupState != nil {
+			flags.BackupState = *row.BackupState
+		}
+		out = append(out, webauthn.Credential{
+			ID:            row.CredentialID,
+			PublicKey:     row.PublicKey,
+			Flags:         flags,
+			Authenticator: webauthn.Authenticator{SignCount: row.SignCount},
+		})
+	}
+	return out
+}
+
+// FillMissingBackupFlags copies backup flags from an assertion onto credentials stored without them.
+func FillMissingBackupFlags(rows []models.PasskeyCredential, credentials []webauthn.Credential, assertion []byte) {
+	parsed, errParse := protocol.ParseCredentialRequestResponseBytes(assertion)
+	if errParse != nil {
+		return
+	}
+	for i, row := range rows {
+		if i >= len(credentials) || !bytes.Equal(row.CredentialID, parsed.RawID) {
+			continue
+		}
+		if row.BackupEligible == nil || row.BackupState == nil {
+			credentials[i].Flags.BackupEligible = parsed.Response.AuthenticatorData.Flags.HasBackupEligible()
+			credentials[i].Flags.BackupState = parsed.Response.AuthenticatorData.Flags.HasBackupState()
+		}
+	}
+}
+
+// NormalizePasskeyName trims a passkey label and applies the default.
+func NormalizePasskeyName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return DefaultPasskeyName
+	}
+	if runes := []rune(name); len(runes) > maxPasskeyNameLength {
+		name = string(runes[:maxPasskeyNameLength])
+	}
+	return name
+}
+
+// AddPasskey stores a newly registered credential.
+func AddPasskey(ctx context.Context, db *gorm.DB, kind string, ownerID uint64, name string, credential *webauthn.Credential) (*models.PasskeyCredential, error) {
+	backupEligible := credential.Flags.BackupEligible
+	backupState := credential.Flags.BackupState
+	row := models.PasskeyCredential{
+		OwnerKind:      kind,
+		OwnerID:        ownerID,
+		Name:           NormalizePasskeyName(name),
+		CredentialID:   credential.ID,
+		PublicKey:      credential.PublicKey,
+		SignCount:      credential.Authenticator.SignCount,
+		BackupEligible: &backupEligible,
+		BackupState:    &backupState,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if errCreate := db.WithContext(ctx).Create(&row).Error; errCreate != nil {
+		return nil, errCreate
+	}
+	return &row, nil
+}
+
+// RecordPasskeyLogin stores the counter and flags reported by a successful assertion.
+func RecordPasskeyLogin(ctx context.Context, db *gorm.DB, kind string, ownerID uint64, credential *webauthn.Credential) error {
+	return db.WithContext(ctx).Model(&models.PasskeyCredential{}).
+		Where("owner_kind = ? AND owner_id = ? AND credential_id = ?", kind, ownerID, credential.ID).
+		Updates(map[string]any{
+			"sign_count":      credential.Authenticator.SignCount,
+			"backup_eligible": credential.Flags.BackupEligible,
+			"backup_state":    credential.Flags.BackupState,
+			"last_used_at":    time.Now().UTC(),
+		}).Error
+}
+
+// RenamePasskey changes the label of one of the owner's passkeys.
+func RenamePasskey(ctx context.Context, db *gorm.DB, kind string, ownerID, id uint64, name string) error {
+	res := db.WithContext(ctx).Model(&models.PasskeyCredential{}).
+		Where("id = ? AND owner_kind = ? AND owner_id = ?", id, kind, ownerID).
+		Update("name", NormalizePasskeyName(name))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePasskey removes one of the owner's passkeys.
+func DeletePasskey(ctx context.Context, db *gorm.DB, kind string, ownerID, id uint64) error {
+	res := db.WithContext(ctx).
+		Where("id = ? AND owner_kind = ? AND owner_id = ?", id, kind, ownerID).
+		Delete(&models.PasskeyCredential{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAllPasskeys removes every passkey registered to the owner.
+func DeleteAllPasskeys(ctx context.Context, db *gorm.DB, kind string, ownerID uint64) error {
+	return db.WithContext(ctx).
+		Where("owner_kind = ? AND owner_id = ?", kind, ownerID).
+		Delete(&models.PasskeyCredential{}).Error
+}
+
+// GenerateRecoveryCodes replaces the owner's recovery codes and returns the new plaintext codes.
+func GenerateRecoveryCodes(ctx context.Context, db *gorm.DB, kind string, ownerID uint64) ([]string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, RecoveryCodeCount)
+	now := time.Now().UTC()
+	for len(codes) < RecoveryCodeCount {
+		code, errCode := newRecoveryCode()
+		if errCode != nil {
+			return nil, errCode
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{
+			OwnerKind: kind,
+			OwnerID:   ownerID,
+			CodeHash:  hashRecoveryCode(code),
+			CreatedAt: now,
+		})
+	}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if errDelete := tx.Where("owner_kind = ? AND owner_id = ?", kind, ownerID).
+			Delete(&models.RecoveryCode{}).Error; errDelete != nil {
+			return errDelete
+		}
+		return tx.Create(&rows).Error
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return codes, nil
+}
+
+// EnsureRecoveryCodes issues recovery codes when the owner has none left; it returns nil otherwise.
+func EnsureRecoveryCodes(ctx context.Context, db *gorm.DB, kind string, ownerID uint64) ([]string, error) {
+	var remaining int64
+	if errCount := db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("owner_kind = ? AND owner_id = ? AND used_at IS NULL", kind, ownerID).
+		Count(&remaining).Error; errCount != nil {
+		return nil, errCount
+	}
+	if remaining > 0 {
+		return nil, nil
+	}
+	return GenerateRecoveryCodes(ctx, db, kind, ownerID)
+}
+
+// ConsumeRecoveryCode marks a matching unused code as used and reports whether one was found.
+func ConsumeRecoveryCode(ctx context.Context, db *gorm.DB, kind string, ownerID uint64, code string) (bool, error) {
+	if normalizeRecoveryCode(code) == "" {
+		return false, nil
+	}
+	res := db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("owner_kind = ? AND owner_id = ? AND code_hash = ? AND used_at IS NULL", kind, ownerID, hashRecoveryCode(code)).
+		Update("used_at", time.Now().UTC())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// Reset removes every MFA factor of an owner, including the TOTP secret.
+func Reset(ctx context.Context, db *gorm.DB, kind string, ownerID uint64) error {
+	var owner any
+	switch kind {
+	case models.MFAOwnerUser:
+		owner = &models.User{}
+	case models.MFAOwnerAdmin:
+		owner = &models.Admin{}
+	default:
+		return fmt.Errorf("mfa: unknown owner kind %q", kind)
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(owner).Where("id = ?", ownerID).
+			Updates(map[string]any{"totp_secret": "", "updated_at": time.Now().UTC()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if errDelete := tx.Where("owner_kind = ? AND owner_id = ?", kind, ownerID).
+			Delete(&models.PasskeyCredential{}).Error; errDelete != nil {
+			return errDelete
+		}
+		return tx.Where("owner_kind = ? AND owner_id = ?", kind, ownerID).
+			Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// AdminRequired reports whether the admin must enroll in MFA; SSO-only admins rely on their provider.
+func AdminRequired(admin models.Admin) bool {
+	return !admin.SSOOnly && internalsettings.AdminMFARequired()
+}
+
+// UserRequired reports whether any of the user's groups requires MFA.
+func UserRequired(ctx context.Context, db *gorm.DB, user models.User) (bool, error) {
+	ids := make([]uint64, 0, len(user.UserGroupID)+len(user.BillUserGroupID))
+	for _, group := range [][]*uint64{user.UserGroupID, user.BillUserGroupID} {
+		for _, id := range group {
+			if id != nil {
+				ids = append(ids, *id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+	var count int64
+	if errCount := db.WithContext(ctx).Model(&models.UserGroup{}).
+		Where("id IN ? AND require_mfa = ?", ids, true).
+		Count(&count).Error; errCount != nil {
+		return false, errCount
+	}
+	return count > 0, nil
+}
+
+// newRecoveryCode returns a random code formatted as two groups of five characters. Each character
+// is drawn uniformly from recoveryAlphabet.
+func newRecoveryCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(recoveryAlphabet)))
+	out := make([]byte, 0, 11)
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			out = append(out, '-')
+		}
+		n, errRand := rand.Int(rand.Reader, alphabetSize)
+		if errRand != nil {
+			return "", fmt.Errorf("mfa: generate recovery code: %w", errRand)
+		}
+		out = append(out, recoveryAlphabet[n.Int64()])
+	}
+	return string(out), nil
+}
+
+// normalizeRecoveryCode lowercases a code and strips separators.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// hashRecoveryCode returns the stored hash for a code.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,137 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func TestRecoveryCodesAndReset(t *testing.T) {
+	conn := openTestDB(t)
+	ctx := context.Background()
+
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x", TOTPSecret: "SECRET"}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+
+	codes, errCodes := EnsureRecoveryCodes(ctx, conn, models.MFAOwnerUser, user.ID)
+	if errCodes != nil || len(codes) != RecoveryCodeCount {
+		t.Fatalf("expected %d codes, got %v %v", RecoveryCodeCount, codes, errCodes)
+	}
+	if again, _ := EnsureRecoveryCodes(ctx, conn, models.MFAOwnerUser, user.ID); again != nil {
+		t.Fatalf("expected existing codes to be kept")
+	}
+
+	if ok, _ := ConsumeRecoveryCode(ctx, conn, models.MFAOwnerAdmin, user.ID, codes[0]); ok {
+		t.Fatalf("code must not be accepted for another owner kind")
+	}
+	if ok, errConsume := ConsumeRecoveryCode(ctx, conn, models.MFAOwnerUser, user.ID, " "+strings.ToUpper(codes[0])+" "); errConsume != nil || !ok {
+		t.Fatalf("expected code to be accepted, got %v %v", ok, errConsume)
+	}
+	if ok, _ := ConsumeRecoveryCode(ctx, conn, models.MFAOwnerUser, user.ID, codes[0]); ok {
+		t.Fatalf("code must be single use")
+	}
+
+	status, errStatus := LoadStatus(ctx, conn, models.MFAOwnerUser, user.ID, user.TOTPSecret)
+	if errStatus != nil || !status.Enabled() || status.RecoveryCodesRemaining != RecoveryCodeCount-1 {
+		t.Fatalf("unexpected status: %+v %v", status, errStatus)
+	}
+
+	passkey := models.PasskeyCredential{OwnerKind: models.MFAOwnerUser, OwnerID: user.ID, Name: "Laptop", CredentialID: []byte("cred"), PublicKey: []byte("key")}
+	if errCreate := conn.Create(&passkey).Error; errCreate != nil {
+		t.Fatalf("create passkey: %v", errCreate)
+	}
+	if errRename := RenamePasskey(ctx, conn, models.MFAOwnerUser, user.ID+1, passkey.ID, "Other"); !errors.Is(errRename, ErrNotFound) {
+		t.Fatalf("expected rename of another owner's passkey to fail, got %v", errRename)
+	}
+
+	if errReset := Reset(ctx, conn, models.MFAOwnerUser, user.ID); errReset != nil {
+		t.Fatalf("reset: %v", errReset)
+	}
+	var reloaded models.User
+	if errFind := conn.First(&reloaded, user.ID).Error; errFind != nil {
+		t.Fatalf("reload user: %v", errFind)
+	}
+	status, _ = LoadStatus(ctx, conn, models.MFAOwnerUser, user.ID, reloaded.TOTPSecret)
+	if status.Enabled() || status.RecoveryCodesRemaining != 0 {
+		t.Fatalf("expected all factors removed, got %+v", status)
+	}
+}
+
+func TestUserRequired(t *testing.T) {
+	conn := openTestDB(t)
+	ctx := context.Background()
+
+	open := models.UserGroup{Name: "open"}
+	strict := models.UserGroup{Name: "strict", RequireMFA: true}
+	for _, group := range []*models.UserGroup{&open, &strict} {
+		if errCreate := conn.Create(group).Error; errCreate != nil {
+			t.Fatalf("create group: %v", errCreate)
+		}
+	}
+
+	user := models.User{UserGroupID: models.UserGroupIDs{&open.ID}}
+	if required, _ := UserRequired(ctx, conn, user); required {
+		t.Fatalf("group without policy must not require mfa")
+	}
+	user.BillUserGroupID = models.UserGroupIDs{&strict.ID}
+	if required, errRequired := UserRequired(ctx, conn, user); errRequired != nil || !required {
+		t.Fatalf("expected bill group policy to require mfa, got %v %v", required, errRequired)
+	}
+}
+
+func TestMigrateLegacyPasskeys(t *testing.T) {
+	conn := openTestDB(t)
+	ctx := context.Background()
+
+	admin := models.Admin{Username: "root", Password: "x", Active: true}
+	if errCreate := conn.Create(&admin).Error; errCreate != nil {
+		t.Fatalf("create admin: %v", errCreate)
+	}
+	for _, column := range []string{"passkey_id blob", "passkey_public_key blob", "passkey_sign_count integer", "passkey_backup_eligible boolean", "passkey_backup_state boolean"} {
+		if errAlter := conn.Exec("ALTER TABLE admins ADD COLUMN " + column).Error; errAlter != nil {
+			t.Fatalf("add legacy column: %v", errAlter)
+		}
+	}
+	if errUpdate := conn.Exec(
+		"UPDATE admins SET passkey_id = ?, passkey_public_key = ?, passkey_sign_count = 7 WHERE id = ?",
+		[]byte("legacy-cred"), []byte("legacy-key"), admin.ID,
+	).Error; errUpdate != nil {
+		t.Fatalf("seed legacy passkey: %v", errUpdate)
+	}
+
+	// Databases created before versioned migrations have no schema_migrations rows.
+	if errClear := conn.Where("1 = 1").Delete(&models.SchemaMigration{}).Error; errClear != nil {
+		t.Fatalf("clear schema migrations: %v", errClear)
+	}
+	for i := 0; i < 2; i++ {
+		if errMigrate := db.Migrate(conn); errMigrate != nil {
+			t.Fatalf("migrate db: %v", errMigrate)
+		}
+	}
+	passkeys, errList := ListPasskeys(ctx, conn, models.MFAOwnerAdmin, admin.ID)
+	if errList != nil || len(passkeys) != 1 {
+		t.Fatalf("expected one migrated passkey, got %v %v", passkeys, errList)
+	}
+	if string(passkeys[0].CredentialID) != "legacy-cred" || passkeys[0].SignCount != 7 {
+		t.Fatalf("unexpected migrated passkey: %+v", passkeys[0])
+	}
+}
@@ -0,0 +1,79 @@
+package modelaccess
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// Overrides maps normalized model names to a user's active override effects.
+type Overrides map[string]models.ModelAccessEffect
+
+// Load returns the user's overrides that have not expired at now.
+func Load(ctx context.Context, db *gorm.DB, userID uint64, now time.Time) (Overrides, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	if userID == 0 {
+		return Overrides{}, nil
+	}
+	var rows []models.UserModelOverride
+	if errFind := db.WithContext(ctx).
+		Select("model", "effect").
+		Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	overrides := make(Overrides, len(rows))
+	for _, row := range rows {
+		key := NormalizeModel(row.Model)
+		if key == "" {
+			continue
+		}
+		// Deny wins when both effects exist under names that normalize alike.
+		if overrides[key] == models.ModelAccessDeny {
+			continue
+		}
+		overrides[key] = row.Effect
+	}
+	return overrides, nil
+}
+
+// Lookup returns the override for a model by its requested name or its mapped alias.
+func (o Overrides) Lookup(provider, model string) (models.ModelAccessEffect, bool) {
+	if len(o) == 0 {
+		return "", false
+	}
+	if effect, ok := o[NormalizeModel(model)]; ok {
+		return effect, true
+	}
+	name := strings.TrimPrefix(strings.TrimSpace(model), "models/")
+	if alias, ok := modelmapping.LookupMappedModelName(provider, name); ok {
+		if effect, okAlias := o[NormalizeModel(alias)]; okAlias {
+			return effect, true
+		}
+	}
+	return "", false
+}
+
+// Allows reports whether the user was granted the model outside their groups.
+func (o Overrides) Allows(provider, model string) bool {
+	effect, ok := o.Lookup(provider, model)
+	return ok && effect == models.ModelAccessAllow
+}
+
+// Denies reports whether the model is denied for the user.
+func (o Overrides) Denies(provider, model string) bool {
+	effect, ok := o.Lookup(provider, model)
+	return ok && effect == models.ModelAccessDeny
+}
+
+// NormalizeModel lower-cases a model name and strips the Gemini "models/" prefix.
+func NormalizeModel(model string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(model), "models/"))
+}
@@ -0,0 +1,913 @@
+// Package modelbundle exports and imports model mappings, payload rules, and billing rules as one portable bundle.
+//
+// Groups are referenced by name rather than ID so a bundle exported from one environment can be
+// imported into another whose group IDs differ.
+package modelbundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gopkg.in/yaml.v3"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	// BundleFormat identifies model bundles.
+	BundleFormat = "cliproxyapi-business-models"
+	// BundleVersion is the bundle layout version written by Export.
+	BundleVersion = 1
+
+	// FormatJSON encodes bundles as JSON.
+	FormatJSON = "json"
+	// FormatYAML encodes bundles as YAML.
+	FormatYAML = "yaml"
+)
+
+var (
+	// ErrInvalidBundle indicates the bundle cannot be decoded or fails validation.
+	ErrInvalidBundle = errors.New("modelbundle: invalid bundle")
+	// ErrUnsupportedVersion indicates the bundle was written by a newer layout version.
+	ErrUnsupportedVersion = errors.New("modelbundle: unsupported bundle version")
+	// ErrUnknownStrategy indicates an unsupported conflict strategy.
+	ErrUnknownStrategy = errors.New("modelbundle: unknown strategy")
+)
+
+// Strategy controls how imported entries that already exist are handled.
+type Strategy string
+
+// Strategy constants define conflict handling for existing entries.
+const (
+	// StrategySkip leaves existing entries untouched.
+	StrategySkip Strategy = "skip"
+	// StrategyOverwrite replaces existing entries with the bundle contents, including removing
+	// payload rules the bundle does not carry.
+	StrategyOverwrite Strategy = "overwrite"
+	// StrategyMerge applies bundle values over existing entries, unions user groups, and keeps
+	// existing payload rules, prices and enabled flags the bundle leaves unset.
+	StrategyMerge Strategy = "merge"
+)
+
+// ParseStrategy validates a strategy name; an empty name selects StrategySkip.
+func ParseStrategy(raw string) (Strategy, error) {
+	switch s := Strategy(strings.ToLower(strings.TrimSpace(raw))); s {
+	case "":
+		return StrategySkip, nil
+	case StrategySkip, StrategyOverwrite, StrategyMerge:
+		return s, nil
+	default:
+		return "", ErrUnknownStrategy
+	}
+}
+
+// Bundle is the portable representation of model routing and pricing configuration.
+type Bundle struct {
+	Format       string        `json:"format" yaml:"format"`
+	Version      int           `json:"version" yaml:"version"`
+	ExportedAt   time.Time     `json:"exported_at" yaml:"exported_at"`
+	Mappings     []Mapping     `json:"mappings" yaml:"mappings"`
+	BillingRules []BillingRule `json:"billing_rules" yaml:"billing_rules"`
+}
+
+// Mapping is a model mapping with its optional payload rule.
+type Mapping struct {
+	Provider     string       `json:"provider" yaml:"provider"`
+	ModelName    string       `json:"model_name" yaml:"model_name"`
+	NewModelName string       `json:"new_model_name" yaml:"new_model_name"`
+	Fork         bool         `json:"fork" yaml:"fork"`
+	Selector     int          `json:"selector" yaml:"selector"`
+	RateLimit    int          `json:"rate_limit" yaml:"rate_limit"`
+	UserGroups   []string     `json:"user_groups" yaml:"user_groups"`
+	IsEnabled    *bool        `json:"is_enabled,omitempty" yaml:"is_enabled,omitempty"`
+	PayloadRule  *PayloadRule `json:"payload_rule,omitempty" yaml:"payload_rule,omitempty"`
+}
+
+// PayloadRule is the payload injection rule attached to a mapping.
+type PayloadRule struct {
+	Protocol    string `json:"protocol" yaml:"protocol"`
+	Params      any    `json:"params" yaml:"params"`
+	IsEnabled   *bool  `json:"is_enabled,omitempty" yaml:"is_enabled,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// BillingRule is a billing rule scoped by auth and user group names.
+type BillingRule struct {
+	AuthGroup             string   `json:"auth_group" yaml:"auth_group"`
+	UserGroup             string   `json:"user_group" yaml:"user_group"`
+	Provider              string   `json:"provider" yaml:"provider"`
+	Model                 string   `json:"model" yaml:"model"`
+	BillingType           int      `json:"billing_type" yaml:"billing_type"`
+	PricePerRequest       *float64 `json:"price_per_request,omitempty" yaml:"price_per_request,omitempty"`
+	PriceInputToken       *float64 `json:"price_input_token,omitempty" yaml:"price_input_token,omitempty"`
+	PriceOutputToken      *float64 `json:"price_output_token,omitempty" yaml:"price_output_token,omitempty"`
+	PriceCacheCreateToken *float64 `json:"price_cache_create_token,omitempty" yaml:"price_cache_create_token,omitempty"`
+	PriceCacheReadToken   *float64 `json:"price_cache_read_token,omitempty" yaml:"price_cache_read_token,omitempty"`
+	IsEnabled             *bool    `json:"is_enabled,omitempty" yaml:"is_enabled,omitempty"`
+}
+
+// Filter narrows an export.
+type Filter struct {
+	Provider string // Provider name; empty exports every provider.
+	Model    string // Source or exposed model name; empty exports every model.
+}
+
+// Encode serialises a bundle in the given format.
+func Encode(bundle *Bundle, format string) ([]byte, error) {
+	if bundle == nil {
+		return nil, ErrInvalidBundle
+	}
+	if strings.EqualFold(strings.TrimSpace(format), FormatYAML) {
+		return yaml.Marshal(bundle)
+	}
+	return json.MarshalIndent(bundle, "", "  ")
+}
+
+// Decode parses a bundle in the given format and checks its header.
+func Decode(data []byte, format string) (*Bundle, error) {
+	var bundle Bundle
+	var errUnmarshal error
+	if strings.EqualFold(strings.TrimSpace(format), FormatYAML) {
+		errUnmarshal = yaml.Unmarshal(data, &bundle)
+	} else {
+		errUnmarshal = json.Unmarshal(data, &bundle)
+	}
+	if errUnmarshal != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, errUnmarshal)
+	}
+	if bundle.Format != "" && bundle.Format != BundleFormat {
+		return nil, fmt.Errorf("%w: format %q", ErrInvalidBundle, bundle.Format)
+	}
+	if bundle.Version > BundleVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	return &bundle, nil
+}
+
+// Export reads mappings, their payload rules, and billing rules matching filter.
+func Export(ctx context.Context, db *gorm.DB, filter Filter, now time.Time) (*Bundle, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	conn := db.WithContext(ctx)
+	groups, errGroups := loadGroups(conn)
+	if errGroups != nil {
+		return nil, errGroups
+	}
+
+	mappingQ := conn.Model(&models.ModelMapping{})
+	ruleQ := conn.Model(&models.BillingRule{})
+	if provider := strings.TrimSpace(filter.Provider); provider != "" {
+		mappingQ = mappingQ.Where("LOWER(provider) = ?", strings.ToLower(provider))
+		ruleQ = ruleQ.Where("LOWER(provider) = ?", strings.ToLower(provider))
+	}
+	if model := strings.TrimSpace(filter.Model); model != "" {
+		mappingQ = mappingQ.Where("LOWER(model_name) = ? OR LOWER(new_model_name) = ?", strings.ToLower(model), strings.ToLower(model))
+		ruleQ = ruleQ.Where("LOWER(model) = ?", strings.ToLower(model))
+	}
+
+	var mappings []models.ModelMapping
+	if errFind := mappingQ.Order("provider ASC, new_model_name ASC, model_name ASC, id ASC").Find(&mappings).Error; errFind != nil {
+		return nil, errFind
+	}
+	payloads, errPayloads := loadPayloadRules(conn)
+	if errPayloads != nil {
+		return nil, errPayloads
+	}
+	var rules []models.BillingRule
+	if errFind := ruleQ.Order("provider ASC, model ASC, id ASC").Find(&rules).Error; errFind != nil {
+		return nil, errFind
+	}
+
+	bundle := &Bundle{
+		Format:       BundleFormat,
+		Version:      BundleVersion,
+		ExportedAt:   now.UTC(),
+		Mappings:     make([]Mapping, 0, len(mappings)),
+		BillingRules: make([]BillingRule, 0, len(rules)),
+	}
+	for i := range mappings {
+		bundle.Mappings = append(bundle.Mappings, mappingFromModel(&mappings[i], payloads[mappings[i].ID], groups))
+	}
+	for i := range rules {
+		bundle.BillingRules = append(bundle.BillingRules, billingRuleFromModel(&rules[i], groups))
+	}
+	return bundle, nil
+}
+
+// Action describes what an import does with one bundle entry.
+type Action string
+
+// Action constants describe import outcomes.
+const (
+	// ActionCreate inserts a new entry.
+	ActionCreate Action = "create"
+	// ActionUpdate changes an existing entry.
+	ActionUpdate Action = "update"
+	// ActionSkip leaves a differing existing entry untouched.
+	ActionSkip Action = "skip"
+	// ActionUnchanged means the existing entry already matches.
+	ActionUnchanged Action = "unchanged"
+)
+
+// FieldDiff records one changed field.
+type FieldDiff struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// Change describes the import outcome of one bundle entry.
+type Change struct {
+	Kind   string      `json:"kind"`
+	Key    string      `json:"key"`
+	Action Action      `json:"action"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// Report summarises an import or its dry-run preview.
+type Report struct {
+	Strategy  Strategy `json:"strategy"`
+	DryRun    bool     `json:"dry_run"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Skipped   int      `json:"skipped"`
+	Unchanged int      `json:"unchanged"`
+	Changes   []Change `json:"changes"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// add records a change and updates the counters.
+func (r *Report) add(change Change) {
+	switch change.Action {
+	case ActionCreate:
+		r.Created++
+	case ActionUpdate:
+		r.Updated++
+	case ActionSkip:
+		r.Skipped++
+	case ActionUnchanged:
+		r.Unchanged++
+	}
+	r.Changes = append(r.Changes, change)
+}
+
+// Import applies bundle using strategy. With dryRun the returned report previews the changes
+// and nothing is written. Validation problems are listed in Report.Errors, in which case nothing
+// is written and ErrInvalidBundle is returned.
+func Import(ctx context.Context, db *gorm.DB, bundle *Bundle, strategy Strategy, dryRun bool, now time.Time) (*Report, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	if bundle == nil {
+		return nil, ErrInvalidBundle
+	}
+	if _, errStrategy := ParseStrategy(string(strategy)); errStrategy != nil {
+		return nil, errStrategy
+	}
+	report := &Report{Strategy: strategy, DryRun: dryRun, Changes: []Change{}}
+	errTx := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		imp, errLoad := newImporter(tx, strategy, now.UTC())
+		if errLoad != nil {
+			return errLoad
+		}
+		plans := imp.planMappings(bundle.Mappings, report)
+		rulePlans := imp.planBillingRules(bundle.BillingRules, report)
+		if len(report.Errors) > 0 {
+			return ErrInvalidBundle
+		}
+		if dryRun {
+			return nil
+		}
+		for _, plan := range plans {
+			if errApply := imp.applyMapping(plan); errApply != nil {
+				return errApply
+			}
+		}
+		for _, plan := range rulePlans {
+			if errApply := imp.applyBillingRule(plan); errApply != nil {
+				return errApply
+			}
+		}
+		return nil
+	})
+	if errTx != nil {
+		if errors.Is(errTx, ErrInvalidBundle) {
+			return report, errTx
+		}
+		return nil, errTx
+	}
+	return report, nil
+}
+
+// groupIndex resolves group names and IDs in both directions.
+type groupIndex struct {
+	userNames map[uint64]string // User group ID to name.
+	authNames map[uint64]string // Auth group ID to name.
+	userIDs   map[string]uint64 // Lower-cased user group name to ID.
+	authIDs   map[string]uint64 // Lower-cased auth group name to ID.
+}
+
+// loadGroups reads every user and auth group.
+func loadGroups(db *gorm.DB) (*groupIndex, error) {
+	var userGroups []models.UserGroup
+	if errFind := db.Select("id", "name").Find(&userGroups).Error; errFind != nil {
+		return nil, errFind
+	}
+	var authGroups []models.AuthGroup
+	if errFind := db.Select("id", "name").Find(&authGroups).Error; errFind != nil {
+		return nil, errFind
+	}
+	idx := &groupIndex{
+		userNames: make(map[uint64]string, len(userGroups)),
+		authNames: make(map[uint64]string, len(authGroups)),
+		userIDs:   make(map[string]uint64, len(userGroups)),
+		authIDs:   make(map[string]uint64, len(authGroups)),
+	}
+	for _, g := range userGroups {
+		idx.userNames[g.ID] = g.Name
+		idx.userIDs[strings.ToLower(strings.TrimSpace(g.Name))] = g.ID
+	}
+	for _, g := range authGroups {
+		idx.authNames[g.ID] = g.Name
+		idx.authIDs[strings.ToLower(strings.TrimSpace(g.Name))] = g.ID
+	}
+	return idx, nil
+}
+
+// loadPayloadRules indexes payload rules by mapping ID.
+func loadPayloadRules(db *gorm.DB) (map[uint64]*models.ModelPayloadRule, error) {
+	var rows []models.ModelPayloadRule
+	if errFind := db.Order("id ASC").Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	out := make(map[uint64]*models.ModelPayloadRule, len(rows))
+	for i := range rows {
+		if _, exists := out[rows[i].ModelMappingID]; !exists {
+			out[rows[i].ModelMappingID] = &rows[i]
+		}
+	}
+	return out, nil
+}
+
+// mappingFromModel converts a stored mapping and payload rule into bundle form.
+func mappingFromModel(m *models.ModelMapping, payload *models.ModelPayloadRule, groups *groupIndex) Mapping {
+	names := make([]string, 0, len(m.UserGroupID))
+	for _, id := range m.UserGroupID.Values() {
+		if name, ok := groups.userNames[id]; ok {
+			names = append(names, name)
+		}
+	}
+	enabled := m.IsEnabled
+	out := Mapping{
+		Provider:     m.Provider,
+		ModelName:    m.ModelName,
+		NewModelName: m.NewModelName,
+		Fork:         m.Fork,
+		Selector:     m.Selector,
+		RateLimit:    m.RateLimit,
+		UserGroups:   names,
+		IsEnabled:    &enabled,
+	}
+	if payload != nil {
+		var params any
+		if len(payload.Params) > 0 {
+			_ = json.Unmarshal(payload.Params, &params)
+		}
+		payloadEnabled := payload.IsEnabled
+		out.PayloadRule = &PayloadRule{
+			Protocol:    payload.Protocol,
+			Params:      params,
+			IsEnabled:   &payloadEnabled,
+			Description: payload.Description,
+		}
+	}
+	normalizeMapping(&out)
+	return out
+}
+
+// billingRuleFromModel converts a stored billing rule into bundle form.
+func billingRuleFromModel(r *models.BillingRule, groups *groupIndex) BillingRule {
+	enabled := r.IsEnabled
+	return BillingRule{
+		AuthGroup:             groups.authNames[r.AuthGroupID],
+		UserGroup:             groups.userNames[r.UserGroupID],
+		Provider:              r.Provider,
+		Model:                 r.Model,
+		BillingType:           int(r.BillingType),
+		PricePerRequest:       r.PricePerRequest,
+		PriceInputToken:       r.PriceInputToken,
+		PriceOutputToken:      r.PriceOutputToken,
+		PriceCacheCreateToken: r.PriceCacheCreateToken,
+		PriceCacheReadToken:   r.PriceCacheReadToken,
+		IsEnabled:             &enabled,
+	}
+}
+
+// normalizeMapping trims names, de-duplicates groups, defaults flags to enabled, and canonicalises params.
+func normalizeMapping(m *Mapping) {
+	m.Provider = strings.TrimSpace(m.Provider)
+	m.ModelName = strings.TrimSpace(m.ModelName)
+	m.NewModelName = strings.TrimSpace(m.NewModelName)
+	m.UserGroups = normalizeNames(m.UserGroups)
+	m.IsEnabled = boolOrTrue(m.IsEnabled)
+	if m.PayloadRule != nil {
+		m.PayloadRule.Protocol = strings.TrimSpace(m.PayloadRule.Protocol)
+		m.PayloadRule.Description = strings.TrimSpace(m.PayloadRule.Description)
+		m.PayloadRule.IsEnabled = boolOrTrue(m.PayloadRule.IsEnabled)
+		m.PayloadRule.Params = canonicalParams(m.PayloadRule.Params)
+	}
+}
+
+// normalizeBillingRule trims names and defaults the enabled flag.
+func normalizeBillingRule(r *BillingRule) {
+	r.AuthGroup = strings.TrimSpace(r.AuthGroup)
+	r.UserGroup = strings.TrimSpace(r.UserGroup)
+	r.Provider = strings.TrimSpace(r.Provider)
+	r.Model = strings.TrimSpace(r.Model)
+	r.IsEnabled = boolOrTrue(r.IsEnabled)
+}
+
+// normalizeNames trims and de-duplicates group names case-insensitively. Order is kept because
+// the first group is the primary group used for billing.
+func normalizeNames(names []string) []string {
+	out := make([]string, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, name)
+	}
+	return out
+}
+
+// canonicalParams round-trips params through JSON so YAML and JSON sources compare equal.
+func canonicalParams(params any) any {
+	if params == nil {
+		return map[string]any{}
+	}
+	raw, errMarshal := json.Marshal(params)
+	if errMarshal != nil {
+		return params
+	}
+	var out any
+	if errUnmarshal := json.Unmarshal(raw, &out); errUnmarshal != nil {
+		return params
+	}
+	return out
+}
+
+// boolOrTrue returns a copy of v, defaulting to true when unset.
+func boolOrTrue(v *bool) *bool {
+	out := true
+	if v != nil {
+		out = *v
+	}
+	return &out
+}
+
+// mappingKey identifies a mapping by provider, source, and exposed name.
+func mappingKey(provider, modelName, newModelName string) string {
+	return strings.ToLower(provider) + "/" + strings.ToLower(modelName) + " -> " + strings.ToLower(newModelName)
+}
+
+// billingRuleKey identifies a billing rule by its scope.
+func billingRuleKey(authGroup, userGroup, provider, model string) string {
+	return strings.ToLower(authGroup) + "|" + strings.ToLower(userGroup) + "|" + strings.ToLower(provider) + "|" + strings.ToLower(model)
+}
+
+// importer holds the state loaded for one import transaction.
+type importer struct {
+	tx       *gorm.DB
+	strategy Strategy
+	now      time.Time
+	groups   *groupIndex
+	mappings map[string]*models.ModelMapping     // Existing mappings by key.
+	payloads map[uint64]*models.ModelPayloadRule // Existing payload rules by mapping ID.
+	rules    map[string]*models.BillingRule      // Existing billing rules by key.
+}
+
+// mappingPlan is a pending mapping write.
+type mappingPlan struct {
+	action   Action
+	existing *models.ModelMapping
+	target   Mapping
+	groupIDs models.UserGroupIDs
+}
+
+// billingRulePlan is a pending billing rule write.
+type billingRulePlan struct {
+	action      Action
+	existing    *models.BillingRule
+	target      BillingRule
+	authGroupID uint64
+	userGroupID uint64
+}
+
+// newImporter loads groups and existing rows inside tx.
+func newImporter(tx *gorm.DB, strategy Strategy, now time.Time) (*importer, error) {
+	groups, errGroups := loadGroups(tx)
+	if errGroups != nil {
+		return nil, errGroups
+	}
+	var mappings []models.ModelMapping
+	if errFind := tx.Order("id ASC").Find(&mappings).Error; errFind != nil {
+		return nil, errFind
+	}
+	payloads, errPayloads := loadPayloadRules(tx)
+	if errPayloads != nil {
+		return nil, errPayloads
+	}
+	var rules []models.BillingRule
+	if errFind := tx.Order("id ASC").Find(&rules).Error; errFind != nil {
+		return nil, errFind
+	}
+	imp := &importer{
+		tx:       tx,
+		strategy: strategy,
+		now:      now,
+		groups:   groups,
+		mappings: make(map[string]*models.ModelMapping, len(mappings)),
+		payloads: payloads,
+		rules:    make(map[string]*models.BillingRule, len(rules)),
+	}
+	for i := range mappings {
+		key := mappingKey(mappings[i].Provider, mappings[i].ModelName, mappings[i].NewModelName)
+		if _, exists := imp.mappings[key]; !exists {
+			imp.mappings[key] = &mappings[i]
+		}
+	}
+	for i := range rules {
+		key := billingRuleKey(groups.authNames[rules[i].AuthGroupID], groups.userNames[rules[i].UserGroupID], rules[i].Provider, rules[i].Model)
+		if _, exists := imp.rules[key]; !exists {
+			imp.rules[key] = &rules[i]
+		}
+	}
+	return imp, nil
+}
+
+// planMappings validates bundle mappings and decides the action for each.
+func (imp *importer) planMappings(entries []Mapping, report *Report) []mappingPlan {
+	plans := make([]mappingPlan, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for i := range entries {
+		entry := entries[i]
+		// Record which flags the bundle sets before normalising defaults them to enabled.
+		enabledSet := entry.IsEnabled != nil
+		payloadEnabledSet := entry.PayloadRule != nil && entry.PayloadRule.IsEnabled != nil
+		if entry.PayloadRule != nil {
+			payload := *entry.PayloadRule
+			entry.PayloadRule = &payload
+		}
+		normalizeMapping(&entry)
+		key := mappingKey(entry.Provider, entry.ModelName, entry.NewModelName)
+		if entry.Provider == "" || entry.ModelName == "" || entry.NewModelName == "" {
+			report.Errors = append(report.Errors, fmt.Sprintf("mappings[%d]: provider, model_name and new_model_name are required", i))
+			continue
+		}
+		if entry.Selector < 0 || entry.Selector > 2 {
+			report.Errors = append(report.Errors, fmt.Sprintf("mapping %s: selector must be 0, 1, or 2", key))
+			continue
+		}
+		if entry.RateLimit < 0 {
+			report.Errors = append(report.Errors, fmt.Sprintf("mapping %s: rate_limit must be non-negative", key))
+			continue
+		}
+		if entry.PayloadRule != nil {
+			if _, isObject := entry.PayloadRule.Params.(map[string]any); !isObject {
+				report.Errors = append(report.Errors, fmt.Sprintf("mapping %s: payload_rule.params must be an object", key))
+				continue
+			}
+		}
+		if _, dup := seen[key]; dup {
+			report.Errors = append(report.Errors, fmt.Sprintf("mapping %s: duplicate entry", key))
+			continue
+		}
+		seen[key] = struct{}{}
+
+		existing := imp.mappings[key]
+		if existing == nil {
+			groupIDs, ok := imp.resolveUserGroups(entry.UserGroups, key, report)
+			if !ok {
+				continue
+			}
+			plans = append(plans, mappingPlan{action: ActionCreate, target: entry, groupIDs: groupIDs})
+			report.add(Change{Kind: "mapping", Key: key, Action: ActionCreate})
+			continue
+		}
+
+		current := mappingFromModel(existing, imp.payloads[existing.ID], imp.groups)
+		target := entry
+		if imp.strategy == StrategyMerge {
+			target.UserGroups = normalizeNames(append(append([]string{}, current.UserGroups...), entry.UserGroups...))
+			if !enabledSet {
+				target.IsEnabled = current.IsEnabled
+			}
+			if target.PayloadRule == nil {
+				target.PayloadRule = current.PayloadRule
+			} else if !payloadEnabledSet && current.PayloadRule != nil {
+				target.PayloadRule.IsEnabled = current.PayloadRule.IsEnabled
+			}
+		}
+		groupIDs, ok := imp.resolveUserGroups(target.UserGroups, key, report)
+		if !ok {
+			continue
+		}
+		if imp.strategy == StrategyMerge {
+			// Keep IDs of groups that no longer resolve to a name so merging never drops access.
+			groupIDs = mergeGroupIDs(existing.UserGroupID, groupIDs)
+		}
+		fields := diffMappings(current, target)
+		switch {
+		case len(fields) == 0:
+			report.add(Change{Kind: "mapping", Key: key, Action: ActionUnchanged})
+		case imp.strategy == StrategySkip:
+			report.add(Change{Kind: "mapping", Key: key, Action: ActionSkip, Fields: fields})
+		default:
+			plans = append(plans, mappingPlan{action: ActionUpdate, existing: existing, target: target, groupIDs: groupIDs})
+			report.add(Change{Kind: "mapping", Key: key, Action: ActionUpdate, Fields: fields})
+		}
+	}
+	return plans
+}
+
+// planBillingRules validates bundle billing rules and decides the action for each.
+func (imp *importer) planBillingRules(entries []BillingRule, report *Report) []billingRulePlan {
+	plans := make([]billingRulePlan, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for i := range entries {
+		entry := entries[i]
+		enabledSet := entry.IsEnabled != nil
+		normalizeBillingRule(&entry)
+		key := billingRuleKey(entry.AuthGroup, entry.UserGroup, entry.Provider, entry.Model)
+		authGroupID, okAuth := imp.groups.authIDs[strings.ToLower(entry.AuthGroup)]
+		if !okAuth {
+			report.Errors = append(report.Errors, fmt.Sprintf("billing rule %s: unknown auth group %q", key, entry.AuthGroup))
+			continue
+		}
+		userGroupID, okUser := imp.groups.userIDs[strings.ToLower(entry.UserGroup)]
+		if !okUser {
+			report.Errors = append(report.Errors, fmt.Sprintf("billing rule %s: unknown user group %q", key, entry.UserGroup))
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			report.Errors = append(report.Errors, fmt.Sprintf("billing rule %s: duplicate entry", key))
+			continue
+		}
+		seen[key] = struct{}{}
+
+		existing := imp.rules[key]
+		target := entry
+		if existing != nil && imp.strategy == StrategyMerge {
+			current := billingRuleFromModel(existing, imp.groups)
+			target.PricePerRequest = firstPrice(entry.PricePerRequest, current.PricePerRequest)
+			target.PriceInputToken = firstPrice(entry.PriceInputToken, current.PriceInputToken)
+			target.PriceOutputToken = firstPrice(entry.PriceOutputToken, current.PriceOutputToken)
+			target.PriceCacheCreateToken = firstPrice(entry.PriceCacheCreateToken, current.PriceCacheCreateToken)
+			target.PriceCacheReadToken = firstPrice(entry.PriceCacheReadToken, current.PriceCacheReadToken)
+			if !enabledSet {
+				target.IsEnabled = current.IsEnabled
+			}
+		}
+		if errValidate := validateBillingRule(&target); errValidate != "" {
+			report.Errors = append(report.Errors, fmt.Sprintf("billing rule %s: %s", key, errValidate))
+			continue
+		}
+
+		if existing == nil {
+			plans = append(plans, billingRulePlan{action: ActionCreate, target: target, authGroupID: authGroupID, userGroupID: userGroupID})
+			report.add(Change{Kind: "billing_rule", Key: key, Action: ActionCreate})
+			continue
+		}
+		fields := diffBillingRules(billingRuleFromModel(existing, imp.groups), target)
+		switch {
+		case len(fields) == 0:
+			report.add(Change{Kind: "billing_rule", Key: key, Action: ActionUnchanged})
+		case imp.strategy == StrategySkip:
+			report.add(Change{Kind: "billing_rule", Key: key, Action: ActionSkip, Fields: fields})
+		default:
+			plans = append(plans, billingRulePlan{action: ActionUpdate, existing: existing, target: target, authGroupID: authGroupID, userGroupID: userGroupID})
+			report.add(Change{Kind: "billing_rule", Key: key, Action: ActionUpdate, Fields: fields})
+		}
+	}
+	return plans
+}
+
+// resolveUserGroups maps group names to IDs, recording unknown names as errors.
+func (imp *importer) resolveUserGroups(names []string, key string, report *Report) (models.UserGroupIDs, bool) {
+	ids := make(models.UserGroupIDs, 0, len(names))
+	for _, name := range names {
+		id, ok := imp.groups.userIDs[strings.ToLower(name)]
+		if !ok {
+			report.Errors = append(report.Errors, fmt.Sprintf("mapping %s: unknown user group %q", key, name))
+			return nil, false
+		}
+		idCopy := id
+		ids = append(ids, &idCopy)
+	}
+	return ids.Clean(), true
+}
+
+// applyMapping writes a planned mapping and its payload rule.
+func (imp *importer) applyMapping(plan mappingPlan) error {
+	target := plan.target
+	enabled := *target.IsEnabled
+	var mappingID uint64
+	if plan.action == ActionCreate {
+		row := models.ModelMapping{
+			Provider:     target.Provider,
+			ModelName:    target.ModelName,
+			NewModelName: target.NewModelName,
+			Fork:         target.Fork,
+			Selector:     target.Selector,
+			RateLimit:    target.RateLimit,
+			UserGroupID:  plan.groupIDs,
+			IsEnabled:    enabled,
+			CreatedAt:    imp.now,
+			UpdatedAt:    imp.now,
+		}
+		if errCreate := imp.tx.Create(&row).Error; errCreate != nil {
+			return errCreate
+		}
+		if !enabled {
+			// GORM skips zero values of columns with defaults on insert.
+			if errUpdate := imp.tx.Model(&models.ModelMapping{}).Where("id = ?", row.ID).Update("is_enabled", false).Error; errUpdate != nil {
+				return errUpdate
+			}
+		}
+		mappingID = row.ID
+	} else {
+		mappingID = plan.existing.ID
+		if errUpdate := imp.tx.Model(&models.ModelMapping{}).Where("id = ?", mappingID).Updates(map[string]any{
+			"fork":          target.Fork,
+			"selector":      target.Selector,
+			"rate_limit":    target.RateLimit,
+			"user_group_id": plan.groupIDs,
+			"is_enabled":    enabled,
+			"updated_at":    imp.now,
+		}).Error; errUpdate != nil {
+			return errUpdate
+		}
+	}
+	return imp.applyPayloadRule(mappingID, target.PayloadRule)
+}
+
+// applyPayloadRule creates, updates, or removes the payload rule of a mapping.
+func (imp *importer) applyPayloadRule(mappingID uint64, rule *PayloadRule) error {
+	existing := imp.payloads[mappingID]
+	if rule == nil {
+		if existing == nil {
+			return nil
+		}
+		return imp.tx.Delete(&models.ModelPayloadRule{}, existing.ID).Error
+	}
+	params, errMarshal := json.Marshal(rule.Params)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	enabled := *rule.IsEnabled
+	if existing != nil {
+		return imp.tx.Model(&models.ModelPayloadRule{}).Where("id = ?", existing.ID).Updates(map[string]any{
+			"protocol":    rule.Protocol,
+			"params":      datatypes.JSON(params),
+			"is_enabled":  enabled,
+			"description": rule.Description,
+			"updated_at":  imp.now,
+		}).Error
+	}
+	row := models.ModelPayloadRule{
+		ModelMappingID: mappingID,
+		Protocol:       rule.Protocol,
+		Params:         datatypes.JSON(params),
+		IsEnabled:      enabled,
+		Description:    rule.Description,
+		CreatedAt:      imp.now,
+		UpdatedAt:      imp.now,
+	}
+	if errCreate := imp.tx.Create(&row).Error; errCreate != nil {
+		return errCreate
+	}
+	if !enabled {
+		return imp.tx.Model(&models.ModelPayloadRule{}).Where("id = ?", row.ID).Update("is_enabled", false).Error
+	}
+	return nil
+}
+
+// applyBillingRule writes a planned billing rule.
+func (imp *importer) applyBillingRule(plan billingRulePlan) error {
+	target := plan.target
+	enabled := *target.IsEnabled
+	if plan.action == ActionCreate {
+		row := models.BillingRule{
+			AuthGroupID:           plan.authGroupID,
+			UserGroupID:           plan.userGroupID,
+			Provider:              target.Provider,
+			Model:                 target.Model,
+			BillingType:           models.BillingType(target.BillingType),
+			PricePerRequest:       target.PricePerRequest,
+			PriceInputToken:       target.PriceInputToken,
+			PriceOutputToken:      target.PriceOutputToken,
+			PriceCacheCreateToken: target.PriceCacheCreateToken,
+			PriceCacheReadToken:   target.PriceCacheReadToken,
+			IsEnabled:             enabled,
+			CreatedAt:             imp.now,
+			UpdatedAt:             imp.now,
+		}
+		if errCreate := imp.tx.Omit("AuthGroup", "UserGroup").Create(&row).Error; errCreate != nil {
+			return errCreate
+		}
+		if !enabled {
+			return imp.tx.Model(&models.BillingRule{}).Where("id = ?", row.ID).Update("is_enabled", false).Error
+		}
+		return nil
+	}
+	return imp.tx.Model(&models.BillingRule{}).Where("id = ?", plan.existing.ID).Updates(map[string]any{
+		"billing_type":             models.BillingType(target.BillingType),
+		"price_per_request":        target.PricePerRequest,
+		"price_input_token":        target.PriceInputToken,
+		"price_output_token":       target.PriceOutputToken,
+		"price_cache_create_token": target.PriceCacheCreateToken,
+		"price_cache_read_token":   target.PriceCacheReadToken,
+		"is_enabled":               enabled,
+		"updated_at":               imp.now,
+	}).Error
+}
+
+// validateBillingRule mirrors the checks of the billing rule admin endpoints.
+func validateBillingRule(r *BillingRule) string {
+	switch models.BillingType(r.BillingType) {
+	case models.BillingTypePerRequest:
+		if r.PricePerRequest == nil {
+			return "price_per_request is required for per_request billing"
+		}
+	case models.BillingTypePerToken:
+		if r.PriceInputToken == nil || r.PriceOutputToken == nil {
+			return "price_input_token and price_output_token are required for per_token billing"
+		}
+	default:
+		return "billing_type must be 1 (per_request) or 2 (per_token)"
+	}
+	return ""
+}
+
+// diffMappings lists the fields that differ between two normalised mappings.
+func diffMappings(from, to Mapping) []FieldDiff {
+	var out []FieldDiff
+	out = appendDiff(out, "fork", from.Fork, to.Fork)
+	out = appendDiff(out, "selector", from.Selector, to.Selector)
+	out = appendDiff(out, "rate_limit", from.RateLimit, to.RateLimit)
+	out = appendDiff(out, "user_groups", from.UserGroups, to.UserGroups)
+	out = appendDiff(out, "is_enabled", *from.IsEnabled, *to.IsEnabled)
+	out = appendDiff(out, "payload_rule", from.PayloadRule, to.PayloadRule)
+	return out
+}
+
+// diffBillingRules lists the fields that differ between two normalised billing rules.
+func diffBillingRules(from, to BillingRule) []FieldDiff {
+	var out []FieldDiff
+	out = appendDiff(out, "billing_type", from.BillingType, to.BillingType)
+	out = appendDiff(out, "price_per_request", from.PricePerRequest, to.PricePerRequest)
+	out = appendDiff(out, "price_input_token", from.PriceInputToken, to.PriceInputToken)
+	out = appendDiff(out, "price_output_token", from.PriceOutputToken, to.PriceOutputToken)
+	out = appendDiff(out, "price_cache_create_token", from.PriceCacheCreateToken, to.PriceCacheCreateToken)
+	out = appendDiff(out, "price_cache_read_token", from.PriceCacheReadToken, to.PriceCacheReadToken)
+	out = appendDiff(out, "is_enabled", *from.IsEnabled, *to.IsEnabled)
+	return out
+}
+
+// appendDiff appends a FieldDiff when from and to differ.
+func appendDiff(out []FieldDiff, field string, from, to any) []FieldDiff {
+	if reflect.DeepEqual(from, to) {
+		return out
+	}
+	return append(out, FieldDiff{Field: field, From: from, To: to})
+}
+
+// mergeGroupIDs unions two group ID lists.
+func mergeGroupIDs(a, b models.UserGroupIDs) models.UserGroupIDs {
+	out := make(models.UserGroupIDs, 0, len(a)+len(b))
+	out = append(out, a...)
+	out = append(out, b...)
+	return out.Clean()
+}
+
+// firstPrice returns a when set, otherwise b.
+func firstPrice(a, b *float64) *float64 {
+	if a != nil {
+		return a
+	}
+	return b
+}
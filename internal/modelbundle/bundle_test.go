@@ -0,0 +1,199 @@
+package modelbundle
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), name))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func createGroups(t *testing.T, conn *gorm.DB, userNames ...string) map[string]uint64 {
+	t.Helper()
+	ids := make(map[string]uint64, len(userNames)+1)
+	for _, name := range userNames {
+		group := models.UserGroup{Name: name}
+		if errCreate := conn.Create(&group).Error; errCreate != nil {
+			t.Fatalf("create user group: %v", errCreate)
+		}
+		ids[name] = group.ID
+	}
+	authGroup := models.AuthGroup{Name: "pool"}
+	if errCreate := conn.Create(&authGroup).Error; errCreate != nil {
+		t.Fatalf("create auth group: %v", errCreate)
+	}
+	ids["pool"] = authGroup.ID
+	return ids
+}
+
+func loadMapping(t *testing.T, conn *gorm.DB, newModelName string) models.ModelMapping {
+	t.Helper()
+	var mapping models.ModelMapping
+	if errFind := conn.Where("new_model_name = ?", newModelName).First(&mapping).Error; errFind != nil {
+		t.Fatalf("load mapping %s: %v", newModelName, errFind)
+	}
+	return mapping
+}
+
+func TestExportImportAcrossGroupIDs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	src := openTestDB(t, "src.db")
+	srcGroups := createGroups(t, src, "vip", "std")
+	vipID := srcGroups["vip"]
+	mapping := models.ModelMapping{Provider: "claude", ModelName: "claude-real", NewModelName: "claude-alias", Selector: 1, RateLimit: 5, UserGroupID: models.UserGroupIDs{&vipID}, IsEnabled: true}
+	if errCreate := src.Create(&mapping).Error; errCreate != nil {
+		t.Fatalf("create mapping: %v", errCreate)
+	}
+	rule := models.ModelPayloadRule{ModelMappingID: mapping.ID, Protocol: "claude", Params: datatypes.JSON(`{"default":{"max_tokens":1024}}`), IsEnabled: true}
+	if errCreate := src.Create(&rule).Error; errCreate != nil {
+		t.Fatalf("create payload rule: %v", errCreate)
+	}
+	inputPrice, outputPrice := 1.5, 3.0
+	billingRule := models.BillingRule{AuthGroupID: srcGroups["pool"], UserGroupID: vipID, Provider: "claude", Model: "claude-alias", BillingType: models.BillingTypePerToken, PriceInputToken: &inputPrice, PriceOutputToken: &outputPrice, IsEnabled: true}
+	if errCreate := src.Omit("AuthGroup", "UserGroup").Create(&billingRule).Error; errCreate != nil {
+		t.Fatalf("create billing rule: %v", errCreate)
+	}
+
+	exported, errExport := Export(ctx, src, Filter{Provider: "claude"}, now)
+	if errExport != nil {
+		t.Fatalf("Export: %v", errExport)
+	}
+	data, errEncode := Encode(exported, FormatYAML)
+	if errEncode != nil {
+		t.Fatalf("Encode: %v", errEncode)
+	}
+	bundle, errDecode := Decode(data, FormatYAML)
+	if errDecode != nil {
+		t.Fatalf("Decode: %v", errDecode)
+	}
+
+	// Groups are created in a different order so IDs differ from the source.
+	dst := openTestDB(t, "dst.db")
+	dstGroups := createGroups(t, dst, "std", "vip")
+
+	preview, errPreview := Import(ctx, dst, bundle, StrategySkip, true, now)
+	if errPreview != nil {
+		t.Fatalf("Import dry run: %v", errPreview)
+	}
+	if preview.Created != 2 {
+		t.Fatalf("expected 2 creations in preview, got %+v", preview)
+	}
+	var count int64
+	dst.Model(&models.ModelMapping{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("dry run wrote %d mappings", count)
+	}
+
+	if _, errImport := Import(ctx, dst, bundle, StrategySkip, false, now); errImport != nil {
+		t.Fatalf("Import: %v", errImport)
+	}
+	imported := loadMapping(t, dst, "claude-alias")
+	if groups := imported.UserGroupID.Values(); len(groups) != 1 || groups[0] != dstGroups["vip"] {
+		t.Fatalf("expected mapping group remapped to %d, got %v", dstGroups["vip"], groups)
+	}
+	var importedRule models.ModelPayloadRule
+	if errFind := dst.Where("model_mapping_id = ?", imported.ID).First(&importedRule).Error; errFind != nil {
+		t.Fatalf("load payload rule: %v", errFind)
+	}
+	var importedBilling models.BillingRule
+	if errFind := dst.First(&importedBilling).Error; errFind != nil {
+		t.Fatalf("load billing rule: %v", errFind)
+	}
+	if importedBilling.UserGroupID != dstGroups["vip"] || importedBilling.PriceOutputToken == nil || *importedBilling.PriceOutputToken != 3.0 {
+		t.Fatalf("unexpected billing rule: %+v", importedBilling)
+	}
+
+	again, errAgain := Import(ctx, dst, bundle, StrategyOverwrite, false, now)
+	if errAgain != nil {
+		t.Fatalf("re-import: %v", errAgain)
+	}
+	if again.Unchanged != 2 || again.Updated != 0 {
+		t.Fatalf("expected re-import to be unchanged, got %+v", again)
+	}
+}
+
+func TestImportStrategies(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	conn := openTestDB(t, "strategies.db")
+	groups := createGroups(t, conn, "vip", "std")
+	vipID := groups["vip"]
+	existing := models.ModelMapping{Provider: "codex", ModelName: "gpt-real", NewModelName: "gpt-alias", RateLimit: 1, UserGroupID: models.UserGroupIDs{&vipID}, IsEnabled: true}
+	if errCreate := conn.Create(&existing).Error; errCreate != nil {
+		t.Fatalf("create mapping: %v", errCreate)
+	}
+
+	disabled := false
+	incoming := func() *Bundle {
+		return &Bundle{Mappings: []Mapping{{
+			Provider:     "codex",
+			ModelName:    "gpt-real",
+			NewModelName: "gpt-alias",
+			RateLimit:    9,
+			UserGroups:   []string{"std"},
+			IsEnabled:    &disabled,
+		}}}
+	}
+
+	skipped, errSkip := Import(ctx, conn, incoming(), StrategySkip, false, now)
+	if errSkip != nil {
+		t.Fatalf("skip import: %v", errSkip)
+	}
+	if skipped.Skipped != 1 || len(skipped.Changes[0].Fields) == 0 {
+		t.Fatalf("expected a skipped change with a diff, got %+v", skipped)
+	}
+	if got := loadMapping(t, conn, "gpt-alias"); got.RateLimit != 1 {
+		t.Fatalf("skip strategy modified mapping: %+v", got)
+	}
+
+	if _, errMerge := Import(ctx, conn, incoming(), StrategyMerge, false, now); errMerge != nil {
+		t.Fatalf("merge import: %v", errMerge)
+	}
+	merged := loadMapping(t, conn, "gpt-alias")
+	if values := merged.UserGroupID.Values(); merged.RateLimit != 9 || merged.IsEnabled || len(values) != 2 || values[0] != vipID {
+		t.Fatalf("unexpected merged mapping: %+v", merged)
+	}
+
+	unset := incoming()
+	unset.Mappings[0].IsEnabled = nil
+	if _, errMerge := Import(ctx, conn, unset, StrategyMerge, false, now); errMerge != nil {
+		t.Fatalf("merge import without is_enabled: %v", errMerge)
+	}
+	if got := loadMapping(t, conn, "gpt-alias"); got.IsEnabled {
+		t.Fatalf("merge without is_enabled re-enabled mapping: %+v", got)
+	}
+
+	if _, errOverwrite := Import(ctx, conn, incoming(), StrategyOverwrite, false, now); errOverwrite != nil {
+		t.Fatalf("overwrite import: %v", errOverwrite)
+	}
+	overwritten := loadMapping(t, conn, "gpt-alias")
+	if values := overwritten.UserGroupID.Values(); len(values) != 1 || values[0] != groups["std"] {
+		t.Fatalf("unexpected overwritten groups: %v", values)
+	}
+
+	invalid := incoming()
+	invalid.Mappings[0].UserGroups = []string{"missing"}
+	report, errInvalid := Import(ctx, conn, invalid, StrategyOverwrite, false, now)
+	if !errors.Is(errInvalid, ErrInvalidBundle) || report == nil || len(report.Errors) != 1 {
+		t.Fatalf("expected validation error report, got %+v, %v", report, errInvalid)
+	}
+}
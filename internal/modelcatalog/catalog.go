@@ -0,0 +1,123 @@
+package modelcatalog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxRedirectHops bounds sunset redirect chains so misconfigured cycles terminate.
+const maxRedirectHops = 8
+
+// Status describes the lifecycle state of a catalogued model at a point in time.
+type Status int
+
+// Status constants define model lifecycle states.
+const (
+	// StatusActive means the model is neither deprecated nor sunset.
+	StatusActive Status = iota
+	// StatusDeprecated means the model still serves requests but carries warnings.
+	StatusDeprecated
+	// StatusSunset means the model no longer serves requests under its own name.
+	StatusSunset
+)
+
+var globalEntries atomic.Value
+
+func init() {
+	globalEntries.Store(map[string]models.ModelCatalogEntry{})
+}
+
+// Store replaces the in-memory catalogue snapshot.
+func Store(rows []models.ModelCatalogEntry) {
+	next := make(map[string]models.ModelCatalogEntry, len(rows))
+	for _, row := range rows {
+		key := normalize(row.Model)
+		if key == "" {
+			continue
+		}
+		next[key] = row
+	}
+	globalEntries.Store(next)
+}
+
+// Reload reads every catalogue entry from the database into the snapshot.
+func Reload(ctx context.Context, db *gorm.DB) error {
+	if db == nil {
+		return errors.New("nil db")
+	}
+	var rows []models.ModelCatalogEntry
+	if errFind := db.WithContext(ctx).Find(&rows).Error; errFind != nil {
+		return errFind
+	}
+	Store(rows)
+	return nil
+}
+
+// Lookup returns the catalogue entry for an exposed model name.
+func Lookup(model string) (models.ModelCatalogEntry, bool) {
+	key := normalize(model)
+	if key == "" {
+		return models.ModelCatalogEntry{}, false
+	}
+	entries, _ := globalEntries.Load().(map[string]models.ModelCatalogEntry)
+	entry, ok := entries[key]
+	return entry, ok
+}
+
+// HasEntries reports whether any catalogue entry is loaded.
+func HasEntries() bool {
+	entries, _ := globalEntries.Load().(map[string]models.ModelCatalogEntry)
+	return len(entries) > 0
+}
+
+// StatusAt returns the lifecycle state of an entry at now.
+func StatusAt(entry models.ModelCatalogEntry, now time.Time) Status {
+	if entry.SunsetAt != nil && !now.Before(*entry.SunsetAt) {
+		return StatusSunset
+	}
+	if entry.DeprecatedAt != nil && !now.Before(*entry.DeprecatedAt) {
+		return StatusDeprecated
+	}
+	if entry.SunsetAt != nil {
+		// A scheduled sunset implies deprecation even without an explicit date.
+		return StatusDeprecated
+	}
+	return StatusActive
+}
+
+// Resolve follows sunset redirects from model and returns the model that should serve the request.
+// redirected is false when the model is served under its own name; ok is false when the model is
+// sunset without a replacement.
+func Resolve(model string, now time.Time) (target string, redirected bool, ok bool) {
+	target = strings.TrimSpace(model)
+	seen := make(map[string]struct{}, 1)
+	for hop := 0; hop < maxRedirectHops; hop++ {
+		entry, found := Lookup(target)
+		if !found || StatusAt(entry, now) != StatusSunset {
+			return target, redirected, true
+		}
+		key := normalize(target)
+		if _, loop := seen[key]; loop {
+			return target, redirected, false
+		}
+		seen[key] = struct{}{}
+		next := strings.TrimSpace(entry.SunsetTarget)
+		if next == "" {
+			return target, redirected, false
+		}
+		target = next
+		redirected = true
+	}
+	return target, redirected, false
+}
+
+// normalize lower-cases a model name and strips the Gemini "models/" prefix.
+func normalize(model string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(model), "models/"))
+}
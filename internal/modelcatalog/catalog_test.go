@@ -0,0 +1,57 @@
+package modelcatalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+)
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestStatusAtAndResolve(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+	t.Cleanup(func() { Store(nil) })
+
+	Store([]models.ModelCatalogEntry{
+		{Model: "active"},
+		{Model: "old", DeprecatedAt: timePtr(past), SunsetAt: timePtr(future), SunsetTarget: "active"},
+		{Model: "Retired", SunsetAt: timePtr(past), SunsetTarget: "middle"},
+		{Model: "middle", SunsetAt: timePtr(past), SunsetTarget: "active"},
+		{Model: "gone", SunsetAt: timePtr(past)},
+		{Model: "loop-a", SunsetAt: timePtr(past), SunsetTarget: "loop-b"},
+		{Model: "loop-b", SunsetAt: timePtr(past), SunsetTarget: "loop-a"},
+	})
+
+	active, _ := Lookup("active")
+	if got := StatusAt(active, now); got != StatusActive {
+		t.Fatalf("active status = %v", got)
+	}
+	old, _ := Lookup("models/OLD")
+	if got := StatusAt(old, now); got != StatusDeprecated {
+		t.Fatalf("old status = %v", got)
+	}
+	if got := StatusAt(old, future); got != StatusSunset {
+		t.Fatalf("old status after sunset = %v", got)
+	}
+
+	if target, redirected, ok := Resolve("old", now); !ok || redirected || target != "old" {
+		t.Fatalf("Resolve(old) = %q %v %v", target, redirected, ok)
+	}
+	if target, redirected, ok := Resolve("retired", now); !ok || !redirected || target != "active" {
+		t.Fatalf("Resolve(retired) = %q %v %v", target, redirected, ok)
+	}
+	if _, _, ok := Resolve("gone", now); ok {
+		t.Fatalf("Resolve(gone) should fail without a target")
+	}
+	if _, _, ok := Resolve("loop-a", now); ok {
+		t.Fatalf("Resolve(loop-a) should fail on a cycle")
+	}
+	if target, redirected, ok := Resolve("unknown", now); !ok || redirected || target != "unknown" {
+		t.Fatalf("Resolve(unknown) = %q %v %v", target, redirected, ok)
+	}
+}
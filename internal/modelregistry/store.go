@@ -117,6 +117,22 @@ func (s *Store) GetByProviderModelID(provider, modelID string) *sdkcliproxy.Mode
 	return cloneModelInfo(info)
 }
 
+// Has reports whether a model is registered for the provider.
+func (s *Store) Has(provider, modelID string) bool {
+	if s == nil {
+		return false
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	key := strings.ToLower(strings.TrimSpace(modelID))
+	if provider == "" || key == "" {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.providerModels[provider][key] != nil
+}
+
 // SnapshotAll returns a de-duplicated list of models across providers.
 func (s *Store) SnapshotAll() []*sdkcliproxy.ModelInfo {
 	if s == nil {
@@ -15,16 +15,14 @@ type Admin struct {
 
 	Active bool `gorm:"not null;default:true"` // Whether the admin can sign in.
 
+	SSOOnly bool `gorm:"not null;default:false"` // Restricts sign in to SSO providers.
+
 	IsSuperAdmin bool `gorm:"not null;default:false"` // Grants all permissions when true.
 
-	Permissions datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Permission keys in JSON.
+	Permissions datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Direct permission grants in JSON.
+	RoleIDs     datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Assigned admin role IDs in JSON.
 
-	TOTPSecret            string  `gorm:"type:text"`    // TOTP secret for MFA.
-	PasskeyID             []byte  `gorm:"type:bytea"`   // WebAuthn credential ID.
-	PasskeyPublicKey      []byte  `gorm:"type:bytea"`   // WebAuthn public key bytes.
-	PasskeySignCount      *uint32 `gorm:"type:bigint"`  // WebAuthn signature counter.
-	PasskeyBackupEligible *bool   `gorm:"type:boolean"` // WebAuthn backup eligibility flag.
-	PasskeyBackupState    *bool   `gorm:"type:boolean"` // WebAuthn backup state flag.
+	TOTPSecret string `gorm:"type:text"` // TOTP secret for MFA; passkeys live in PasskeyCredential.
 
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
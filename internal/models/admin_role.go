@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AdminRole groups permission grants that can be assigned to administrators.
+type AdminRole struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name        string `gorm:"type:varchar(100);not null;uniqueIndex"` // Unique role name.
+	Description string `gorm:"type:text"`                              // Role description.
+
+	Permissions datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Permission grants in JSON.
+
+	UserGroupID UserGroupIDs `gorm:"type:jsonb"` // User groups the role may manage; empty means all.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
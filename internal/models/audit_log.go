@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AuditLog records a mutating admin request and the entity state around it.
+type AuditLog struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	AdminID       uint64 `gorm:"not null;index"` // Acting admin ID.
+	AdminUsername string `gorm:"type:text"`      // Acting admin username.
+
+	Method     string `gorm:"type:varchar(16);not null"` // HTTP method.
+	Route      string `gorm:"type:text;not null;index"`  // Matched route template.
+	Path       string `gorm:"type:text;not null"`        // Requested path.
+	Action     string `gorm:"type:text"`                 // Permission label of the route.
+	Module     string `gorm:"type:text;index"`           // Permission module of the route.
+	EntityType string `gorm:"type:varchar(64);index"`    // Target entity type.
+	EntityID   string `gorm:"type:varchar(255);index"`   // Target entity identifier.
+	StatusCode int    `gorm:"not null;default:0"`        // Response status code.
+
+	Before datatypes.JSON `gorm:"type:jsonb"` // Masked entity snapshot before the change.
+	After  datatypes.JSON `gorm:"type:jsonb"` // Masked entity snapshot after the change.
+	Diff   datatypes.JSON `gorm:"type:jsonb"` // Changed fields with before/after values.
+
+	IP        string `gorm:"type:varchar(64)"` // Client IP address.
+	UserAgent string `gorm:"type:text"`        // Client user agent.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime;index"` // Creation timestamp.
+}
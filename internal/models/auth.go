@@ -171,6 +171,8 @@ type Auth struct {
 	RateLimit   int  `gorm:"not null;default:0"`                 // Rate limit per second.
 	Priority    int  `gorm:"not null;default:0;index"`           // Selection priority (higher wins).
 
+	MonthlyCostMicros int64 `gorm:"not null;default:0"` // Admin-entered monthly cost basis in micros.
+
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
 }
@@ -38,6 +38,8 @@ type Bill struct {
 	UserID uint64 `gorm:"not null;index"`    // Related user ID.
 	User   User   `gorm:"foreignKey:UserID"` // Related user record.
 
+	OrganizationID *uint64 `gorm:"index"` // Owning organization ID for shared bills.
+
 	UserGroupID UserGroupIDs `gorm:"type:jsonb;not null;default:'[]'"` // User group IDs granted by this bill.
 
 	PeriodType BillPeriodType `gorm:"not null"` // Billing period type.
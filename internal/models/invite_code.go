@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// InviteCode grants sign up access and optional starter benefits to new users.
+type InviteCode struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Code        string `gorm:"type:text;not null;uniqueIndex"` // Code entered at sign up.
+	Description string `gorm:"type:text"`                      // Admin note.
+
+	MaxUses   int `gorm:"not null;default:1"` // Allowed redemptions; 0 means unlimited.
+	UsedCount int `gorm:"not null;default:0"` // Redemptions so far.
+
+	UserGroupID *uint64 `gorm:"index"` // User group assigned to redeemers, if any.
+
+	CreditAmount    float64 `gorm:"type:decimal(20,10);not null;default:0"` // Starter prepaid credit granted to redeemers.
+	CreditValidDays int     `gorm:"not null;default:0"`                     // Validity of the starter credit in days.
+
+	ExpiresAt *time.Time // Expiration time, if any.
+	IsEnabled bool       `gorm:"not null;default:true"` // Whether the code can be redeemed.
+
+	CreatedByAdminID *uint64 `gorm:"index"` // Admin who created the code.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// EmailVerification stores a pending email verification token for a user.
+type EmailVerification struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID uint64 `gorm:"not null;index"`                 // User being verified.
+	Email  string `gorm:"type:text;not null"`             // Address the token was sent to.
+	Token  string `gorm:"type:text;not null;uniqueIndex"` // Verification token.
+
+	ExpiresAt time.Time  `gorm:"not null"` // Token expiry.
+	UsedAt    *time.Time // Time the token was consumed.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// MFA credential owner kinds.
+const (
+	MFAOwnerUser  = "user"  // Credential belongs to an end user.
+	MFAOwnerAdmin = "admin" // Credential belongs to an administrator.
+)
+
+// PasskeyCredential stores one WebAuthn credential registered to a user or admin.
+type PasskeyCredential struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	OwnerKind string `gorm:"type:text;not null;index:idx_passkey_owner,priority:1"` // Owner kind: user or admin.
+	OwnerID   uint64 `gorm:"not null;index:idx_passkey_owner,priority:2"`           // Owning user or admin ID.
+	Name      string `gorm:"type:text;not null"`                                    // Label chosen by the owner.
+
+	CredentialID   []byte `gorm:"type:bytea;not null;uniqueIndex"` // WebAuthn credential ID.
+	PublicKey      []byte `gorm:"type:bytea;not null"`             // WebAuthn public key bytes.
+	SignCount      uint32 `gorm:"type:bigint;not null;default:0"`  // WebAuthn signature counter.
+	BackupEligible *bool  `gorm:"type:boolean"`                    // WebAuthn backup eligibility flag.
+	BackupState    *bool  `gorm:"type:boolean"`                    // WebAuthn backup state flag.
+
+	LastUsedAt *time.Time // Last successful login with this credential.
+	CreatedAt  time.Time  `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
+
+// RecoveryCode stores a hashed one-time MFA recovery code.
+type RecoveryCode struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	OwnerKind string `gorm:"type:text;not null;index:idx_recovery_owner,priority:1"` // Owner kind: user or admin.
+	OwnerID   uint64 `gorm:"not null;index:idx_recovery_owner,priority:2"`           // Owning user or admin ID.
+	CodeHash  string `gorm:"type:text;not null"`                                     // SHA-256 hash of the code.
+
+	UsedAt    *time.Time // Time the code was consumed.
+	CreatedAt time.Time  `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ModelCatalogEntry stores admin-maintained descriptive metadata for an exposed model name.
+type ModelCatalogEntry struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Model       string `gorm:"type:varchar(255);not null;uniqueIndex"` // Exposed model name, matching ModelMapping.NewModelName.
+	DisplayName string `gorm:"type:varchar(255)"`                      // Human-friendly name.
+	Description string `gorm:"type:text"`                              // Free-form description.
+
+	ContextLength   int `gorm:"not null;default:0"` // Context window in tokens; 0 means unknown.
+	MaxOutputTokens int `gorm:"not null;default:0"` // Maximum output tokens; 0 means unknown.
+
+	SupportsVision    bool `gorm:"not null;default:false"` // Accepts image input.
+	SupportsTools     bool `gorm:"not null;default:false"` // Supports tool or function calling.
+	SupportsReasoning bool `gorm:"not null;default:false"` // Exposes reasoning or thinking output.
+
+	ReleasedAt   *time.Time // Release date, if known.
+	DeprecatedAt *time.Time // Date the model is announced deprecated; requests then carry warning headers.
+	SunsetAt     *time.Time // Date after which requests are redirected to SunsetTarget.
+	SunsetTarget string     `gorm:"type:varchar(255)"` // Replacement model used after sunset.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// OrganizationRole is a member's role within an organization.
+type OrganizationRole string
+
+// OrganizationRole constants define member roles.
+const (
+	// OrganizationRoleOwner can manage everything, including admins.
+	OrganizationRoleOwner OrganizationRole = "owner"
+	// OrganizationRoleAdmin can manage members, invitations and balance.
+	OrganizationRoleAdmin OrganizationRole = "admin"
+	// OrganizationRoleMember can use the shared balance through their API keys.
+	OrganizationRoleMember OrganizationRole = "member"
+)
+
+// Valid reports whether the role is a known organization role.
+func (r OrganizationRole) Valid() bool {
+	switch r {
+	case OrganizationRoleOwner, OrganizationRoleAdmin, OrganizationRoleMember:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanManage reports whether the role may manage members and balance.
+func (r OrganizationRole) CanManage() bool {
+	return r == OrganizationRoleOwner || r == OrganizationRoleAdmin
+}
+
+// Organization groups users who share prepaid balance and bills.
+type Organization struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name string `gorm:"type:text;not null"` // Display name.
+
+	OwnerUserID uint64 `gorm:"not null;index"` // Owning user ID.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// OrganizationMember links a user to an organization.
+type OrganizationMember struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	OrganizationID uint64 `gorm:"not null;index"`       // Organization ID.
+	UserID         uint64 `gorm:"not null;uniqueIndex"` // Member user ID; a user joins at most one organization.
+
+	Role OrganizationRole `gorm:"type:varchar(16);not null;default:'member'"` // Member role.
+
+	DailySpendLimit   float64 `gorm:"type:decimal(20,10);not null;default:0"` // Daily spend cap; 0 means unlimited.
+	MonthlySpendLimit float64 `gorm:"type:decimal(20,10);not null;default:0"` // Monthly spend cap; 0 means unlimited.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// OrganizationInvitation is a pending invitation to join an organization.
+type OrganizationInvitation struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	OrganizationID uint64 `gorm:"not null;index"` // Organization ID.
+
+	Email string           `gorm:"type:text;not null;index"`                   // Invited email address.
+	Role  OrganizationRole `gorm:"type:varchar(16);not null;default:'member'"` // Role granted on acceptance.
+	Token string           `gorm:"type:text;not null;uniqueIndex"`             // Acceptance token.
+
+	InvitedByUserID uint64 `gorm:"not null"` // Inviting user ID.
+
+	ExpiresAt      time.Time  `gorm:"not null"` // Expiration time.
+	AcceptedAt     *time.Time // Acceptance time, if accepted.
+	AcceptedUserID *uint64    // Accepting user ID, if accepted.
+	RevokedAt      *time.Time // Revocation time, if revoked.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
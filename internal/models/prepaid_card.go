@@ -19,6 +19,8 @@ type PrepaidCard struct {
 	RedeemedUserID *uint64 `gorm:"index"`                     // User who redeemed the card.
 	RedeemedUser   *User   `gorm:"foreignKey:RedeemedUserID"` // Redeeming user record.
 
+	OrganizationID *uint64 `gorm:"index"` // Organization the card was redeemed into, if any.
+
 	UserGroupID *uint64 `gorm:"index"` // User group scope for deductions, if any.
 
 	CreatedAt  time.Time  `gorm:"not null;autoCreateTime"` // Creation timestamp.
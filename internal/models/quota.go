@@ -10,10 +10,12 @@ import (
 type Quota struct {
 	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
 
-	AuthID uint64 `gorm:"not null;index"`                       // Related auth ID.
-	Type   string `gorm:"column:type;type:text;not null;index"` // Auth content type.
+	AuthID  uint64 `gorm:"not null;index"`                       // Related auth ID; 0 for auths defined only in config.
+	AuthKey string `gorm:"type:text;index"`                      // Runtime auth identifier.
+	Type    string `gorm:"column:type;type:text;not null;index"` // Auth content type.
 
-	Data datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'"` // Quota payload.
+	Data    datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'"` // Raw upstream quota payload.
+	Windows datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Normalized quota windows.
 
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
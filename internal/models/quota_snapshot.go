@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// QuotaSnapshot stores the normalized quota windows of an auth at one poll, forming its history.
+type QuotaSnapshot struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	AuthID  uint64 `gorm:"not null;index"`                       // Related auth ID; 0 for auths defined only in config.
+	AuthKey string `gorm:"type:text;not null;index"`             // Runtime auth identifier.
+	Type    string `gorm:"column:type;type:text;not null;index"` // Auth content type.
+
+	Windows datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // Normalized quota windows at poll time.
+
+	CreatedAt time.Time `gorm:"not null;index"` // Poll timestamp.
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ReportSchedule configures a periodic business report and where it is delivered.
+type ReportSchedule struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Name      string         `gorm:"type:text;not null"`                 // Display name, used in the subject line.
+	Frequency string         `gorm:"type:text;not null"`                 // Period covered: daily, weekly, or monthly.
+	Sections  datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"`   // Included sections; empty means all.
+	Format    string         `gorm:"type:text;not null;default:'html'"`  // Rendering: html or csv.
+	Channel   string         `gorm:"type:text;not null;default:'email'"` // Delivery channel: email or webhook.
+	Emails    datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"`   // Email recipients for the email channel.
+	Webhook   string         `gorm:"type:text"`                          // Target URL for the webhook channel.
+	Enabled   bool           `gorm:"type:boolean;not null;default:true"` // Whether the schedule runs.
+	NextRunAt time.Time      `gorm:"not null;index"`                     // Next time the schedule is due.
+
+	LastRunAt *time.Time // Last delivery attempt.
+	LastError string     `gorm:"type:text"` // Error of the last delivery attempt; empty on success.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaMigration records a versioned schema migration applied to the database.
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"` // Migration step number.
+	Name      string    `gorm:"type:text;not null"`             // Migration step name.
+	AppliedAt time.Time `gorm:"not null"`                       // Time the step was applied.
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// SpendAnomaly records unusual spend or usage detected for an API key or user.
+type SpendAnomaly struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Scope     string `gorm:"type:varchar(16);not null;index:idx_spend_anomalies_subject,priority:1"` // Flagged subject type: api_key or user.
+	SubjectID uint64 `gorm:"not null;index:idx_spend_anomalies_subject,priority:2"`                  // Flagged API key or user ID.
+	Kind      string `gorm:"type:varchar(32);not null;index"`                                        // spend_spike, request_spike, new_ip_range, or new_model.
+
+	APIKeyID *uint64 `gorm:"index"` // Flagged API key, for api_key anomalies.
+	UserID   *uint64 `gorm:"index"` // Owning or flagged user, when known.
+
+	Observed float64        `gorm:"not null;default:0"`               // Last hour spend or requests, or the number of new IP ranges or models.
+	Baseline float64        `gorm:"not null;default:0"`               // Baseline hourly spend or requests, or the number of known IP ranges or models.
+	Details  datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"` // New IP ranges or models, for those kinds.
+	Message  string         `gorm:"type:text;not null"`               // Human readable summary.
+
+	Suspended  bool       `gorm:"not null;default:false"` // Whether the API key was suspended automatically.
+	DetectedAt time.Time  `gorm:"not null;index"`         // Detection time.
+	ResolvedAt *time.Time `gorm:"index"`                  // Time an admin resolved the anomaly; nil while open.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// SSO principal kinds stored on identities and login states.
+const (
+	SSOKindUser  = "user"  // End-user login through /v0/front.
+	SSOKindAdmin = "admin" // Administrator login through /v0/admin.
+)
+
+// OIDCProvider stores an OpenID Connect identity provider registration.
+type OIDCProvider struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	Slug        string `gorm:"type:text;not null;uniqueIndex"` // URL-safe identifier used in login routes.
+	DisplayName string `gorm:"type:text;not null"`             // Label shown on login pages.
+
+	IssuerURL    string `gorm:"type:text;not null"` // Issuer URL for discovery.
+	ClientID     string `gorm:"type:text;not null"` // OAuth2 client ID.
+	ClientSecret string `gorm:"type:text"`          // OAuth2 client secret.
+	Scopes       string `gorm:"type:text"`          // Space separated scopes; empty uses the defaults.
+
+	ForUsers  bool `gorm:"not null;default:false"` // Whether end users may sign in with this provider.
+	ForAdmins bool `gorm:"not null;default:false"` // Whether administrators may sign in with this provider.
+
+	AutoProvision bool `gorm:"not null;default:false"` // Creates users on first login.
+	LinkByEmail   bool `gorm:"not null;default:false"` // Links existing users by verified email.
+
+	GroupsClaim  string         `gorm:"type:text"`                        // ID token claim holding group names.
+	GroupMapping datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'"` // Claim value to user group ID mapping.
+
+	IsEnabled bool `gorm:"not null;default:true"` // Whether the provider accepts logins.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
+
+// SSOIdentity links a provider subject to a user or administrator.
+type SSOIdentity struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	ProviderID uint64 `gorm:"not null;uniqueIndex:idx_sso_identity_subject"`           // Identity provider ID.
+	Kind       string `gorm:"type:text;not null;uniqueIndex:idx_sso_identity_subject"` // Principal kind (user or admin).
+	Subject    string `gorm:"type:text;not null;uniqueIndex:idx_sso_identity_subject"` // Provider subject identifier.
+
+	UserID  *uint64 `gorm:"index"` // Linked user, for user identities.
+	AdminID *uint64 `gorm:"index"` // Linked administrator, for admin identities.
+
+	Email string `gorm:"type:text"` // Email claim at last login.
+
+	LastLoginAt *time.Time // Last successful SSO login.
+	CreatedAt   time.Time  `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
+
+// SSOLoginState stores a pending authorization request between redirect and callback.
+type SSOLoginState struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	State        string `gorm:"type:text;not null;uniqueIndex"` // Opaque state parameter.
+	ProviderID   uint64 `gorm:"not null"`                       // Identity provider ID.
+	Kind         string `gorm:"type:text;not null"`             // Principal kind (user or admin).
+	Nonce        string `gorm:"type:text;not null"`             // ID token nonce.
+	CodeVerifier string `gorm:"type:text;not null"`             // PKCE code verifier.
+	ReturnTo     string `gorm:"type:text"`                      // Relative path to return to after login.
+
+	LinkPrincipalID *uint64 // User or admin to link instead of logging in.
+
+	ExpiresAt time.Time `gorm:"not null;index"`          // Expiry of the pending request.
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+}
@@ -11,10 +11,11 @@ type Usage struct {
 	Provider string `gorm:"type:text;not null;index"` // Provider name.
 	Model    string `gorm:"type:text;not null;index"` // Model name.
 
-	UserID      *uint64 `gorm:"index"` // Related user ID.
-	UserGroupID *uint64 `gorm:"index"` // Billing user group ID, when available.
-	APIKeyID    *uint64 `gorm:"index"` // Related API key ID.
-	AuthID      *uint64 `gorm:"index"` // Related auth ID.
+	UserID         *uint64 `gorm:"index"` // Related user ID.
+	UserGroupID    *uint64 `gorm:"index"` // Billing user group ID, when available.
+	APIKeyID       *uint64 `gorm:"index"` // Related API key ID.
+	AuthID         *uint64 `gorm:"index"` // Related auth ID.
+	OrganizationID *uint64 `gorm:"index"` // Organization charged for the request, if any.
 
 	AuthKey   string `gorm:"type:text;index"` // Auth key value.
 	AuthIndex string `gorm:"type:text"`       // Auth index identifier.
@@ -31,5 +32,17 @@ type Usage struct {
 
 	CostMicros int64 `gorm:"not null;default:0"` // Cost in micros.
 
+	TraceID string `gorm:"type:varchar(32);index"` // OpenTelemetry trace ID of the request.
+
+	RequestID          string `gorm:"type:varchar(64);index"`   // Client-facing request ID.
+	UpstreamStatus     int    `gorm:"not null;default:0;index"` // Upstream HTTP status; 0 when unknown.
+	ErrorCode          string `gorm:"type:text"`                // Upstream error code, when failed.
+	ErrorMessage       string `gorm:"type:text"`                // Upstream error message, when failed.
+	TimeToFirstTokenMs int64  `gorm:"not null;default:0"`       // Milliseconds until the first response byte.
+	DurationMs         int64  `gorm:"not null;default:0"`       // Milliseconds until the response completed.
+	Streaming          bool   `gorm:"not null;default:false"`   // Whether the response was streamed.
+	ClientIP           string `gorm:"type:varchar(64)"`         // Client IP address.
+	UserAgent          string `gorm:"type:text"`                // Client user agent.
+
 	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
 }
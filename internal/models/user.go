@@ -11,6 +11,12 @@ type User struct {
 	Email    string `gorm:"type:text;uniqueIndex"`          // Email address.
 	Password string `gorm:"type:text;not null"`             // Hashed password.
 
+	EmailVerificationPending bool       `gorm:"not null;default:false"` // Whether the email still awaits verification.
+	EmailVerifiedAt          *time.Time // Time the email was verified.
+
+	InviteCodeID *uint64 `gorm:"index"`           // Invite code used at sign up.
+	RegisteredIP string  `gorm:"type:text;index"` // Client IP at sign up.
+
 	UserGroupID UserGroupIDs `gorm:"type:jsonb;not null;default:'[]'"` // Assigned user group IDs.
 	UserGroup   []*UserGroup `gorm:"-"`                                // Assigned user groups.
 
@@ -25,12 +31,10 @@ type User struct {
 	Active   bool `gorm:"not null;default:true"`  // Whether the user can sign in.
 	Disabled bool `gorm:"not null;default:false"` // Explicit disable flag.
 
-	TOTPSecret            string  `gorm:"type:text"`    // TOTP secret for MFA.
-	PasskeyID             []byte  `gorm:"type:bytea"`   // WebAuthn credential ID.
-	PasskeyPublicKey      []byte  `gorm:"type:bytea"`   // WebAuthn public key bytes.
-	PasskeySignCount      *uint32 `gorm:"type:bigint"`  // WebAuthn signature counter.
-	PasskeyBackupEligible *bool   `gorm:"type:boolean"` // WebAuthn backup eligibility flag.
-	PasskeyBackupState    *bool   `gorm:"type:boolean"` // WebAuthn backup state flag.
+	TOTPSecret string `gorm:"type:text"` // TOTP secret for MFA; passkeys live in PasskeyCredential.
+
+	UsageReportFrequency string     `gorm:"type:text;not null;default:''"` // Usage summary email period (daily, weekly, monthly); empty opts out.
+	UsageReportSentAt    *time.Time // Last usage summary email delivery.
 
 	APIKeys []APIKey `gorm:"foreignKey:UserID"` // Related API keys.
 
@@ -6,9 +6,10 @@ import "time"
 type UserGroup struct {
 	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
 
-	Name      string `gorm:"type:text;not null;uniqueIndex"` // Display name.
-	IsDefault bool   `gorm:"not null;default:false"`         // Marks the default group.
-	RateLimit int    `gorm:"not null;default:0"`             // Rate limit per second.
+	Name       string `gorm:"type:text;not null;uniqueIndex"` // Display name.
+	IsDefault  bool   `gorm:"not null;default:false"`         // Marks the default group.
+	RateLimit  int    `gorm:"not null;default:0"`             // Rate limit per second.
+	RequireMFA bool   `gorm:"not null;default:false"`         // Requires members to enroll in MFA.
 
 	Users []User `gorm:"-"` // Related users (not persisted).
 
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ModelAccessEffect describes whether a per-user override grants or denies a model.
+type ModelAccessEffect string
+
+// ModelAccessEffect constants define override effects.
+const (
+	// ModelAccessAllow grants a model outside the user's groups.
+	ModelAccessAllow ModelAccessEffect = "allow"
+	// ModelAccessDeny hides a model from the user regardless of groups.
+	ModelAccessDeny ModelAccessEffect = "deny"
+)
+
+// UserModelOverride grants or denies a single model for one user, optionally until an expiry.
+type UserModelOverride struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement"` // Primary key.
+
+	UserID uint64            `gorm:"not null;uniqueIndex:idx_user_model_overrides_user_model,priority:1"`                   // Affected user ID.
+	Model  string            `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_model_overrides_user_model,priority:2"` // Client-visible model name.
+	Effect ModelAccessEffect `gorm:"type:varchar(16);not null"`                                                             // Allow or deny.
+
+	Note      string     `gorm:"type:text"` // Admin note, e.g. the trial reason.
+	ExpiresAt *time.Time `gorm:"index"`     // Expiration time, if any.
+
+	CreatedByAdminID *uint64 `gorm:"index"` // Admin who created the override.
+
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"` // Creation timestamp.
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"` // Last update timestamp.
+}
@@ -0,0 +1,163 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// alertWebhookTimeout bounds one webhook delivery.
+const alertWebhookTimeout = 10 * time.Second
+
+// alertEvent names the webhook event carrying exhaustion forecasts.
+const alertEvent = "quota.exhaustion_forecast"
+
+// alertPayload is the JSON body posted to the alert webhook.
+type alertPayload struct {
+	Event       string     `json:"event"`        // Always alertEvent.
+	GeneratedAt time.Time  `json:"generated_at"` // Evaluation time.
+	Horizon     string     `json:"horizon"`      // Configured alert horizon.
+	Alerts      []Forecast `json:"alerts"`       // Forecasts exhausting within the horizon.
+}
+
+// Alerter sends exhaustion alerts at most once per window cycle. Sent alerts are tracked in
+// memory, so a restart may repeat an alert for the current cycle.
+type Alerter struct {
+	mu       sync.Mutex
+	sent     map[string]time.Time
+	client   *http.Client
+	sendMail func(ctx context.Context, to, subject, body string) error
+}
+
+// NewAlerter constructs an Alerter delivering through HTTP webhooks and SMTP.
+func NewAlerter() *Alerter {
+	return &Alerter{
+		sent:     make(map[string]time.Time),
+		client:   &http.Client{Timeout: alertWebhookTimeout},
+		sendMail: mail.Send,
+	}
+}
+
+// Check builds forecasts at now and delivers the alerts that became due.
+func (a *Alerter) Check(ctx context.Context, db *gorm.DB, policy internalsettings.QuotaHistoryPolicy, now time.Time) error {
+	if a == nil || !policy.AlertsEnabled() {
+		return nil
+	}
+	forecasts, errLoad := LoadForecasts(ctx, db, now)
+	if errLoad != nil {
+		return errLoad
+	}
+	alerts := a.due(forecasts, policy.AlertHorizon, now)
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	// Alerts count as sent once any channel delivered them, so a failing channel is retried on
+	// the next check without repeating the alert on channels that work.
+	var errs []error
+	delivered := false
+	if policy.WebhookURL != "" {
+		if errPost := a.postWebhook(ctx, policy.WebhookURL, alertPayload{
+			Event:       alertEvent,
+			GeneratedAt: now,
+			Horizon:     policy.AlertHorizon.String(),
+			Alerts:      alerts,
+		}); errPost != nil {
+			errs = append(errs, errPost)
+		} else {
+			delivered = true
+		}
+	}
+	subject, body := alertMessage(alerts, now)
+	for _, to := range policy.AlertEmails {
+		if errSend := a.sendMail(ctx, to, subject, body); errSend != nil {
+			errs = append(errs, fmt.Errorf("quota alert: mail %s: %w", to, errSend))
+		} else {
+			delivered = true
+		}
+	}
+	if delivered {
+		a.markSent(alerts, now)
+	}
+	return errors.Join(errs...)
+}
+
+// alertKey identifies the quota window a forecast belongs to.
+func alertKey(forecast Forecast) string {
+	return strings.Join([]string{forecast.Scope, forecast.AuthKey, fmt.Sprint(forecast.AuthGroupID), forecast.Type, forecast.Window}, "|")
+}
+
+// markSent records alerts as delivered for their current cycle.
+func (a *Alerter) markSent(alerts []Forecast, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, alert := range alerts {
+		if alert.ResetAt != nil {
+			a.sent[alertKey(alert)] = *alert.ResetAt
+		} else {
+			a.sent[alertKey(alert)] = now
+		}
+	}
+}
+
+// due returns forecasts exhausting before their reset within horizon that were not yet alerted
+// for the current cycle.
+func (a *Alerter) due(forecasts []Forecast, horizon time.Duration, now time.Time) []Forecast {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]Forecast, 0)
+	for _, forecast := range forecasts {
+		if forecast.ExhaustsAt == nil || !forecast.ExhaustsBeforeReset || forecast.ExhaustsAt.Sub(now) > horizon {
+			continue
+		}
+		if sentFor, ok := a.sent[alertKey(forecast)]; ok {
+			// A known reset identifies the cycle; without one, repeat after a full horizon.
+			if forecast.ResetAt != nil && sentFor.Equal(*forecast.ResetAt) {
+				continue
+			}
+			if forecast.ResetAt == nil && now.Sub(sentFor) < horizon {
+				continue
+			}
+		}
+		out = append(out, forecast)
+	}
+	return out
+}
+
+// postWebhook delivers the payload as JSON.
+func (a *Alerter) postWebhook(ctx context.Context, targetURL string, payload alertPayload) error {
+	if errPost := webhook.Post(ctx, a.client, targetURL, payload); errPost != nil {
+		return fmt.Errorf("quota alert: %w", errPost)
+	}
+	return nil
+}
+
+// alertMessage renders the plain-text alert email.
+func alertMessage(alerts []Forecast, now time.Time) (string, string) {
+	subject := fmt.Sprintf("Quota exhaustion forecast: %d window(s) at risk", len(alerts))
+	var b strings.Builder
+	b.WriteString("The following quota windows are forecast to run out before they reset:\n\n")
+	for _, alert := range alerts {
+		target := alert.AuthKey
+		if alert.Scope == ScopeAuthGroup {
+			target = fmt.Sprintf("auth group %d (%d auths)", alert.AuthGroupID, alert.Auths)
+		}
+		fmt.Fprintf(&b, "- %s [%s %s]: %.1f%% left, burning %.1f%%/h, exhausted in %s",
+			target, alert.Type, alert.Window, alert.Remaining*100, alert.BurnRate*100,
+			alert.ExhaustsAt.Sub(now).Round(time.Minute))
+		if alert.ResetAt != nil {
+			fmt.Fprintf(&b, ", resets %s", alert.ResetAt.UTC().Format(time.RFC3339))
+		}
+		b.WriteString("\n")
+	}
+	return subject, b.String()
+}
@@ -0,0 +1,26 @@
+package quota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlerterRepeatsUndeliveredAlerts(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	exhausts := now.Add(30 * time.Minute)
+	reset := now.Add(2 * time.Hour)
+	forecasts := []Forecast{{Scope: ScopeAuth, AuthKey: "auth-a", Type: "claude", Window: "5h",
+		ExhaustsAt: &exhausts, ExhaustsBeforeReset: true, ResetAt: &reset}}
+
+	a := NewAlerter()
+	if due := a.due(forecasts, time.Hour, now); len(due) != 1 {
+		t.Fatalf("expected alert to be due, got %d", len(due))
+	}
+	if due := a.due(forecasts, time.Hour, now.Add(time.Minute)); len(due) != 1 {
+		t.Fatalf("expected undelivered alert to stay due, got %d", len(due))
+	}
+	a.markSent(forecasts, now)
+	if due := a.due(forecasts, time.Hour, now.Add(2*time.Minute)); len(due) != 0 {
+		t.Fatalf("expected delivered alert not to repeat in the same cycle, got %d", len(due))
+	}
+}
@@ -0,0 +1,151 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// AllModels is the Window family used when a quota applies to every model of an auth.
+const AllModels = "*"
+
+// Window units describe what Used and Limit count.
+const (
+	// UnitFraction means Used and Limit are unknown and only RemainingFraction is reported.
+	UnitFraction = "fraction"
+	// UnitPercent means Used is a percentage of a Limit of 100.
+	UnitPercent = "percent"
+	// UnitRequests means Used and Limit count requests.
+	UnitRequests = "requests"
+	// UnitTokens means Used and Limit count tokens.
+	UnitTokens = "tokens"
+	// UnitUSD means Used and Limit are amounts in US dollars.
+	UnitUSD = "usd"
+)
+
+// Window is one normalized quota window for a model family.
+type Window struct {
+	Family            string     `json:"family"`             // Model family, or AllModels.
+	Window            string     `json:"window,omitempty"`   // Window length such as "5h" or "7d"; empty when unknown.
+	Unit              string     `json:"unit"`               // Unit of Used and Limit.
+	Used              *float64   `json:"used,omitempty"`     // Consumed amount, when reported.
+	Limit             *float64   `json:"limit,omitempty"`    // Window limit, when reported.
+	RemainingFraction float64    `json:"remaining_fraction"` // Remaining share of the window in [0, 1].
+	ResetAt           *time.Time `json:"reset_at,omitempty"` // When the window resets, when reported.
+}
+
+// Key identifies the window among those of one auth.
+func (w Window) Key() string {
+	key := w.Family
+	if w.Window != "" {
+		key += "/" + w.Window
+	}
+	if w.Unit != "" && w.Unit != UnitFraction && w.Unit != UnitPercent {
+		key += ":" + w.Unit
+	}
+	return key
+}
+
+// Result is the outcome of one fetch: the raw upstream payload and its normalized windows.
+type Result struct {
+	Payload []byte   // Raw upstream response stored as-is.
+	Windows []Window // Normalized windows parsed from Payload.
+}
+
+// Client issues HTTP requests on behalf of one auth, applying its credentials and proxy.
+type Client interface {
+	Do(ctx context.Context, method, targetURL string, body []byte, headers http.Header) (int, []byte, error)
+}
+
+// Request carries everything a Fetcher needs for one auth.
+type Request struct {
+	Auth   *coreauth.Auth // Auth being polled.
+	URLs   []string       // Endpoints to try: the configured override, or the registered defaults.
+	Client Client         // Credentialed HTTP client for Auth.
+	Now    time.Time      // Poll time used to resolve relative reset times.
+}
+
+// Fetcher retrieves and normalizes quota data for one provider.
+type Fetcher interface {
+	// Supports reports whether the auth carries credentials this fetcher can use.
+	Supports(auth *coreauth.Auth) bool
+	// Fetch retrieves the quota of req.Auth.
+	Fetch(ctx context.Context, req Request) (Result, error)
+}
+
+// StatusError reports a non-2xx upstream response.
+type StatusError struct {
+	Code int    // HTTP status code.
+	Body string // Truncated response body.
+}
+
+// Error implements error.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status=%d body=%s", e.Code, e.Body)
+}
+
+// registration couples a fetcher with its default endpoints.
+type registration struct {
+	fetcher     Fetcher
+	defaultURLs []string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]registration)
+)
+
+// RegisterFetcher installs the fetcher for a provider, replacing any previous registration.
+// defaultURLs are used unless the config overrides the provider's endpoints; a provider with
+// neither is not polled.
+func RegisterFetcher(provider string, fetcher Fetcher, defaultURLs ...string) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" || fetcher == nil {
+		return
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[provider] = registration{fetcher: fetcher, defaultURLs: append([]string(nil), defaultURLs...)}
+}
+
+// lookupFetcher returns the fetcher registered for a provider and its default endpoints.
+func lookupFetcher(provider string) (Fetcher, []string, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	reg, ok := registry[provider]
+	return reg.fetcher, reg.defaultURLs, ok
+}
+
+// RegisteredProviders lists providers with a registered fetcher.
+func RegisteredProviders() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]string, 0, len(registry))
+	for provider := range registry {
+		out = append(out, provider)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// fetcherProvider returns the registry key for an auth: OpenAI-compatible auths share one
+// fetcher regardless of their configured provider name.
+func fetcherProvider(auth *coreauth.Auth, rowType string) string {
+	if auth != nil && auth.Attributes != nil && strings.TrimSpace(auth.Attributes["compat_name"]) != "" {
+		return providerOpenAICompat
+	}
+	provider := ""
+	if auth != nil {
+		provider = strings.ToLower(strings.TrimSpace(auth.Provider))
+	}
+	if provider == "" {
+		provider = strings.ToLower(strings.TrimSpace(rowType))
+	}
+	return provider
+}
@@ -0,0 +1,406 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// Providers with built-in fetchers.
+const (
+	providerAntigravity  = "antigravity"
+	providerCodex        = "codex"
+	providerGeminiCLI    = "gemini-cli"
+	providerClaude       = "claude"
+	providerOpenAICompat = "openai-compatibility"
+)
+
+const (
+	antigravityUserAgent = "antigravity/1.11.5 windows/amd64"
+	codexUserAgent       = "codex_cli_rs/0.76.0 (Debian 13.0.0; x86_64) WindowsTerminal"
+	claudeOAuthBeta      = "oauth-2025-04-20"
+)
+
+var (
+	antigravityQuotaURLs = []string{
+		"https://daily-cloudcode-pa.googleapis.com/v1internal:fetchAvailableModels",
+		"https://daily-cloudcode-pa.sandbox.googleapis.com/v1internal:fetchAvailableModels",
+		"https://cloudcode-pa.googleapis.com/v1internal:fetchAvailableModels",
+	}
+	geminiCLIQuotaURL = "https://cloudcode-pa.googleapis.com/v1internal:retrieveUserQuota"
+	codexUsageURL     = "https://chatgpt.com/backend-api/wham/usage"
+	claudeUsageURL    = "https://api.anthropic.com/api/oauth/usage"
+)
+
+// errNoEndpoint indicates a fetch was attempted without any endpoint.
+var errNoEndpoint = errors.New("quota: no endpoint configured")
+
+func init() {
+	RegisterFetcher(providerAntigravity, antigravityFetcher{}, antigravityQuotaURLs...)
+	RegisterFetcher(providerCodex, codexFetcher{}, codexUsageURL)
+	RegisterFetcher(providerGeminiCLI, geminiCLIFetcher{}, geminiCLIQuotaURL)
+	RegisterFetcher(providerClaude, claudeFetcher{}, claudeUsageURL)
+	// OpenAI-compatible upstreams rarely expose usage, so they are polled only when configured.
+	RegisterFetcher(providerOpenAICompat, openAICompatFetcher{})
+}
+
+// antigravityFetcher reads per-model remaining fractions from fetchAvailableModels.
+type antigravityFetcher struct{}
+
+// Supports implements Fetcher.
+func (antigravityFetcher) Supports(auth *coreauth.Auth) bool { return auth != nil }
+
+// Fetch tries each endpoint in order and returns the first successful response.
+func (antigravityFetcher) Fetch(ctx context.Context, req Request) (Result, error) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("User-Agent", antigravityUserAgent)
+	payload, errFetch := firstSuccess(ctx, req, http.MethodPost, []byte("{}"), headers)
+	if errFetch != nil {
+		return Result{}, errFetch
+	}
+	return Result{Payload: payload, Windows: parseAntigravityWindows(payload)}, nil
+}
+
+// parseAntigravityWindows extracts one window per model.
+func parseAntigravityWindows(payload []byte) []Window {
+	root := decodeObject(payload)
+	out := make([]Window, 0)
+	for name, raw := range mapFromAny(root["models"]) {
+		info := mapFromAny(mapFromAny(raw)["quotaInfo"])
+		fraction, ok := floatFromAny(info["remainingFraction"])
+		if !ok {
+			continue
+		}
+		out = append(out, Window{
+			Family:            strings.TrimSpace(name),
+			Unit:              UnitFraction,
+			RemainingFraction: clampFraction(fraction),
+			ResetAt:           timeFromAny(info["resetTime"]),
+		})
+	}
+	return sortWindows(out)
+}
+
+// codexFetcher reads ChatGPT plan rate-limit windows.
+type codexFetcher struct{}
+
+// Supports reports whether the auth is a ChatGPT OAuth login rather than an API key.
+func (codexFetcher) Supports(auth *coreauth.Auth) bool { return auth != nil && !hasAPIKey(auth) }
+
+// Fetch requests the usage endpoint with the ChatGPT account header.
+func (codexFetcher) Fetch(ctx context.Context, req Request) (Result, error) {
+	accountID := resolveCodexAccountID(req.Auth.Metadata)
+	if accountID == "" {
+		return Result{}, errors.New("missing account id")
+	}
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("User-Agent", codexUserAgent)
+	headers.Set("Chatgpt-Account-Id", accountID)
+	payload, errFetch := firstSuccess(ctx, req, http.MethodGet, nil, headers)
+	if errFetch != nil {
+		return Result{}, errFetch
+	}
+	return Result{Payload: payload, Windows: parseCodexWindows(payload, req.Now)}, nil
+}
+
+// parseCodexWindows extracts the primary and secondary rate-limit windows.
+func parseCodexWindows(payload []byte, now time.Time) []Window {
+	rateLimit := mapFromAny(decodeObject(payload)["rate_limit"])
+	out := make([]Window, 0, 2)
+	for _, name := range []string{"primary_window", "secondary_window"} {
+		window := mapFromAny(rateLimit[name])
+		used, ok := floatFromAny(window["used_percent"])
+		if !ok {
+			continue
+		}
+		label := strings.TrimSuffix(name, "_window")
+		if seconds, okSeconds := floatFromAny(window["limit_window_seconds"]); okSeconds && seconds > 0 {
+			label = formatWindow(time.Duration(seconds) * time.Second)
+		}
+		resetAt := timeFromAny(window["reset_at"])
+		if resetAt == nil {
+			if after, okAfter := floatFromAny(window["reset_after_seconds"]); okAfter && after >= 0 {
+				at := now.Add(time.Duration(after) * time.Second).UTC()
+				resetAt = &at
+			}
+		}
+		out = append(out, percentWindow(AllModels, label, used, resetAt))
+	}
+	return out
+}
+
+// geminiCLIFetcher reads Code Assist per-model quota buckets.
+type geminiCLIFetcher struct{}
+
+// Supports implements Fetcher.
+func (geminiCLIFetcher) Supports(auth *coreauth.Auth) bool { return auth != nil && !hasAPIKey(auth) }
+
+// Fetch requests the quota buckets of the auth's project.
+func (geminiCLIFetcher) Fetch(ctx context.Context, req Request) (Result, error) {
+	projectID := resolveGeminiProjectID(req.Auth.Metadata)
+	if projectID == "" {
+		return Result{}, errors.New("missing project id")
+	}
+	body, errMarshal := json.Marshal(map[string]string{"project": projectID})
+	if errMarshal != nil {
+		return Result{}, errMarshal
+	}
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	payload, errFetch := firstSuccess(ctx, req, http.MethodPost, body, headers)
+	if errFetch != nil {
+		return Result{}, errFetch
+	}
+	return Result{Payload: payload, Windows: parseGeminiCLIWindows(payload)}, nil
+}
+
+// parseGeminiCLIWindows extracts one window per model and token type.
+func parseGeminiCLIWindows(payload []byte) []Window {
+	buckets, _ := decodeObject(payload)["buckets"].([]any)
+	out := make([]Window, 0, len(buckets))
+	for _, raw := range buckets {
+		bucket := mapFromAny(raw)
+		name := normalizeString(bucket["modelId"])
+		fraction, ok := floatFromAny(bucket["remainingFraction"])
+		if name == "" || !ok {
+			continue
+		}
+		unit := UnitFraction
+		switch strings.ToLower(normalizeString(bucket["tokenType"])) {
+		case "requests":
+			unit = UnitRequests
+		case "tokens":
+			unit = UnitTokens
+		}
+		out = append(out, Window{
+			Family:            name,
+			Window:            "1d",
+			Unit:              unit,
+			RemainingFraction: clampFraction(fraction),
+			ResetAt:           timeFromAny(bucket["resetTime"]),
+		})
+	}
+	return sortWindows(out)
+}
+
+// claudeFetcher reads Claude subscription utilization for OAuth logins.
+type claudeFetcher struct{}
+
+// Supports reports whether the auth is a Claude OAuth login rather than an API key.
+func (claudeFetcher) Supports(auth *coreauth.Auth) bool {
+	if auth == nil || hasAPIKey(auth) {
+		return false
+	}
+	return normalizeString(auth.Metadata["access_token"]) != ""
+}
+
+// Fetch requests the OAuth usage endpoint.
+func (claudeFetcher) Fetch(ctx context.Context, req Request) (Result, error) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Anthropic-Beta", claudeOAuthBeta)
+	payload, errFetch := firstSuccess(ctx, req, http.MethodGet, nil, headers)
+	if errFetch != nil {
+		return Result{}, errFetch
+	}
+	return Result{Payload: payload, Windows: parseClaudeWindows(payload)}, nil
+}
+
+// claudeWindowFamilies maps usage response keys to window length and model family.
+var claudeWindowFamilies = map[string][2]string{
+	"five_hour":        {"5h", AllModels},
+	"seven_day":        {"7d", AllModels},
+	"seven_day_opus":   {"7d", "opus"},
+	"seven_day_sonnet": {"7d", "sonnet"},
+}
+
+// parseClaudeWindows extracts utilization windows; entries reported as null are skipped.
+func parseClaudeWindows(payload []byte) []Window {
+	root := decodeObject(payload)
+	out := make([]Window, 0, len(claudeWindowFamilies))
+	for key, spec := range claudeWindowFamilies {
+		entry := mapFromAny(root[key])
+		used, ok := floatFromAny(entry["utilization"])
+		if !ok {
+			continue
+		}
+		out = append(out, percentWindow(spec[1], spec[0], used, timeFromAny(entry["resets_at"])))
+	}
+	return sortWindows(out)
+}
+
+// openAICompatFetcher reads OpenAI-style billing endpoints of API-key upstreams.
+//
+// The configured endpoint is a base such as "{base_url}/dashboard/billing"; "{base_url}" expands to
+// the auth's base URL and "/subscription" and "/usage" are appended.
+type openAICompatFetcher struct{}
+
+// Supports reports whether the auth has a base URL and key.
+func (openAICompatFetcher) Supports(auth *coreauth.Auth) bool {
+	return auth != nil && hasAPIKey(auth) && strings.TrimSpace(auth.Attributes["base_url"]) != ""
+}
+
+// Fetch combines the subscription limit with month-to-date usage.
+func (openAICompatFetcher) Fetch(ctx context.Context, req Request) (Result, error) {
+	if len(req.URLs) == 0 {
+		return Result{}, errNoEndpoint
+	}
+	base := strings.TrimRight(strings.ReplaceAll(req.URLs[0], "{base_url}", strings.TrimRight(req.Auth.Attributes["base_url"], "/")), "/")
+	headers := http.Header{}
+	headers.Set("Accept", "application/json")
+
+	subscription, errSub := doChecked(ctx, req.Client, http.MethodGet, base+"/subscription", nil, headers)
+	if errSub != nil {
+		return Result{}, errSub
+	}
+	monthStart := time.Date(req.Now.Year(), req.Now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	query := url.Values{}
+	query.Set("start_date", monthStart.Format(time.DateOnly))
+	query.Set("end_date", req.Now.UTC().AddDate(0, 0, 1).Format(time.DateOnly))
+	usage, errUsage := doChecked(ctx, req.Client, http.MethodGet, base+"/usage?"+query.Encode(), nil, headers)
+	if errUsage != nil {
+		return Result{}, errUsage
+	}
+
+	payload, errMarshal := json.Marshal(map[string]json.RawMessage{
+		"subscription": normalizePayload(subscription),
+		"usage":        normalizePayload(usage),
+	})
+	if errMarshal != nil {
+		return Result{}, errMarshal
+	}
+	return Result{Payload: payload, Windows: parseOpenAICompatWindows(subscription, usage, monthStart)}, nil
+}
+
+// parseOpenAICompatWindows builds a monthly dollar window from subscription and usage payloads.
+func parseOpenAICompatWindows(subscription, usage []byte, monthStart time.Time) []Window {
+	limit, okLimit := floatFromAny(decodeObject(subscription)["hard_limit_usd"])
+	cents, okUsage := floatFromAny(decodeObject(usage)["total_usage"])
+	if !okLimit || !okUsage {
+		return []Window{}
+	}
+	used := cents / 100
+	remaining := 0.0
+	if limit > 0 {
+		remaining = clampFraction(1 - used/limit)
+	}
+	resetAt := monthStart.AddDate(0, 1, 0)
+	return []Window{{
+		Family:            AllModels,
+		Window:            "1mo",
+		Unit:              UnitUSD,
+		Used:              &used,
+		Limit:             &limit,
+		RemainingFraction: remaining,
+		ResetAt:           &resetAt,
+	}}
+}
+
+// firstSuccess tries each endpoint of req in order and returns the first 2xx payload.
+func firstSuccess(ctx context.Context, req Request, method string, body []byte, headers http.Header) ([]byte, error) {
+	if len(req.URLs) == 0 {
+		return nil, errNoEndpoint
+	}
+	var lastErr error
+	for _, target := range req.URLs {
+		payload, errDo := doChecked(ctx, req.Client, method, target, body, headers)
+		if errDo == nil {
+			return payload, nil
+		}
+		lastErr = errDo
+	}
+	return nil, lastErr
+}
+
+// doChecked issues a request and converts non-2xx responses into a StatusError.
+func doChecked(ctx context.Context, client Client, method, target string, body []byte, headers http.Header) ([]byte, error) {
+	status, payload, errDo := client.Do(ctx, method, target, body, headers.Clone())
+	if errDo != nil {
+		return nil, errDo
+	}
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return nil, &StatusError{Code: status, Body: summarizePayload(payload)}
+	}
+	return payload, nil
+}
+
+// percentWindow builds a window from a used percentage.
+func percentWindow(family, window string, usedPercent float64, resetAt *time.Time) Window {
+	limit := 100.0
+	return Window{
+		Family:            family,
+		Window:            window,
+		Unit:              UnitPercent,
+		Used:              &usedPercent,
+		Limit:             &limit,
+		RemainingFraction: clampFraction(1 - usedPercent/100),
+		ResetAt:           resetAt,
+	}
+}
+
+// formatWindow renders a window length in the largest whole unit: "7d", "5h", or "30m".
+func formatWindow(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	case d >= time.Hour && d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	default:
+		return strconv.Itoa(int(d/time.Minute)) + "m"
+	}
+}
+
+// hasAPIKey reports whether the auth authenticates with a static API key.
+func hasAPIKey(auth *coreauth.Auth) bool {
+	return auth != nil && auth.Attributes != nil && strings.TrimSpace(auth.Attributes["api_key"]) != ""
+}
+
+// decodeObject parses a JSON object, returning nil on failure.
+func decodeObject(payload []byte) map[string]any {
+	var root map[string]any
+	if errUnmarshal := json.Unmarshal(payload, &root); errUnmarshal != nil {
+		return nil
+	}
+	return root
+}
+
+// timeFromAny parses an RFC 3339 string or Unix seconds.
+func timeFromAny(value any) *time.Time {
+	switch typed := value.(type) {
+	case string:
+		raw := strings.TrimSpace(typed)
+		if raw == "" {
+			return nil
+		}
+		if parsed, errParse := time.Parse(time.RFC3339Nano, raw); errParse == nil {
+			utc := parsed.UTC()
+			return &utc
+		}
+		if seconds, errParse := strconv.ParseInt(raw, 10, 64); errParse == nil && seconds > 0 {
+			at := time.Unix(seconds, 0).UTC()
+			return &at
+		}
+	case float64:
+		if typed > 0 {
+			at := time.Unix(int64(typed), 0).UTC()
+			return &at
+		}
+	}
+	return nil
+}
+
+// sortWindows orders windows by key for stable storage.
+func sortWindows(windows []Window) []Window {
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Key() < windows[j].Key() })
+	return windows
+}
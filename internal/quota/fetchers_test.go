@@ -0,0 +1,130 @@
+package quota
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// plainClient issues requests without auth-specific credentials.
+type plainClient struct{}
+
+func (plainClient) Do(ctx context.Context, method, targetURL string, body []byte, headers http.Header) (int, []byte, error) {
+	req, errReq := http.NewRequestWithContext(ctx, method, targetURL, bytes.NewReader(body))
+	if errReq != nil {
+		return 0, nil, errReq
+	}
+	req.Header = headers
+	resp, errDo := http.DefaultClient.Do(req)
+	if errDo != nil {
+		return 0, nil, errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	payload, errRead := io.ReadAll(resp.Body)
+	return resp.StatusCode, payload, errRead
+}
+
+func TestClaudeFetcherNormalizesUtilization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Anthropic-Beta") != claudeOAuthBeta {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"five_hour":{"utilization":25,"resets_at":"2026-01-02T03:00:00Z"},"seven_day":{"utilization":80,"resets_at":null},"seven_day_opus":null}`))
+	}))
+	defer server.Close()
+
+	auth := &coreauth.Auth{ID: "claude-1", Provider: "claude", Metadata: map[string]any{"access_token": "token"}}
+	fetcher := claudeFetcher{}
+	if !fetcher.Supports(auth) {
+		t.Fatalf("expected OAuth auth to be supported")
+	}
+	if fetcher.Supports(&coreauth.Auth{Provider: "claude", Attributes: map[string]string{"api_key": "sk"}}) {
+		t.Fatalf("expected API key auth to be unsupported")
+	}
+
+	result, errFetch := fetcher.Fetch(context.Background(), Request{Auth: auth, URLs: []string{server.URL}, Client: plainClient{}, Now: time.Now().UTC()})
+	if errFetch != nil {
+		t.Fatalf("Fetch: %v", errFetch)
+	}
+	if len(result.Windows) != 2 {
+		t.Fatalf("expected 2 windows, got %+v", result.Windows)
+	}
+	fiveHour := result.Windows[0]
+	if fiveHour.Key() != "*/5h" || fiveHour.RemainingFraction != 0.75 || fiveHour.ResetAt == nil || fiveHour.ResetAt.Hour() != 3 {
+		t.Fatalf("unexpected five hour window: %+v", fiveHour)
+	}
+	if result.Windows[1].Key() != "*/7d" || result.Windows[1].ResetAt != nil {
+		t.Fatalf("unexpected seven day window: %+v", result.Windows[1])
+	}
+}
+
+func TestOpenAICompatFetcherUsesConfiguredEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/dashboard/billing/subscription":
+			_, _ = w.Write([]byte(`{"hard_limit_usd":20}`))
+		case "/v1/dashboard/billing/usage":
+			if r.URL.Query().Get("start_date") != "2026-03-01" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"total_usage":500}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	auth := &coreauth.Auth{ID: "compat-1", Provider: "relay", Attributes: map[string]string{
+		"api_key":     "sk",
+		"base_url":    server.URL + "/v1",
+		"compat_name": "relay",
+	}}
+	if provider := fetcherProvider(auth, ""); provider != providerOpenAICompat {
+		t.Fatalf("expected openai-compatibility provider, got %q", provider)
+	}
+	fetcher, defaults, ok := lookupFetcher(providerOpenAICompat)
+	if !ok || len(defaults) != 0 {
+		t.Fatalf("expected an openai-compatibility fetcher without defaults")
+	}
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	result, errFetch := fetcher.Fetch(context.Background(), Request{
+		Auth:   auth,
+		URLs:   []string{"{base_url}/dashboard/billing"},
+		Client: plainClient{},
+		Now:    now,
+	})
+	if errFetch != nil {
+		t.Fatalf("Fetch: %v", errFetch)
+	}
+	if len(result.Windows) != 1 {
+		t.Fatalf("expected 1 window, got %+v", result.Windows)
+	}
+	window := result.Windows[0]
+	if *window.Used != 5 || *window.Limit != 20 || window.RemainingFraction != 0.75 || !window.ResetAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected window: %+v", window)
+	}
+}
+
+func TestCodexWindowsFromRateLimit(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	payload := []byte(`{"rate_limit":{"primary_window":{"used_percent":40,"limit_window_seconds":18000,"reset_after_seconds":600},"secondary_window":{"used_percent":10,"limit_window_seconds":604800,"reset_at":1767571200}}}`)
+	windows := parseCodexWindows(payload, now)
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 windows, got %+v", windows)
+	}
+	if windows[0].Window != "5h" || windows[0].RemainingFraction != 0.6 || !windows[0].ResetAt.Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("unexpected primary window: %+v", windows[0])
+	}
+	if windows[1].Window != "7d" || windows[1].ResetAt.Unix() != 1767571200 {
+		t.Fatalf("unexpected secondary window: %+v", windows[1])
+	}
+}
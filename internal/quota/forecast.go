@@ -0,0 +1,211 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+// burnRateLookback bounds the history used to estimate burn rates.
+const burnRateLookback = 6 * time.Hour
+
+// Forecast scopes.
+const (
+	// ScopeAuth forecasts one auth.
+	ScopeAuth = "auth"
+	// ScopeAuthGroup forecasts the combined quota of an auth group.
+	ScopeAuthGroup = "auth_group"
+)
+
+// Point is one observation of a quota window.
+type Point struct {
+	At        time.Time  // Poll time.
+	Remaining float64    // Remaining fraction at poll time.
+	ResetAt   *time.Time // Reported reset time at poll time.
+}
+
+// Forecast estimates when an auth or auth group runs out of one quota window.
+type Forecast struct {
+	Scope               string     `json:"scope"`                   // ScopeAuth or ScopeAuthGroup.
+	AuthID              uint64     `json:"auth_id,omitempty"`       // Auth ID; 0 for auths defined only in config.
+	AuthKey             string     `json:"auth_key,omitempty"`      // Runtime auth identifier, for ScopeAuth.
+	AuthGroupID         uint64     `json:"auth_group_id,omitempty"` // Primary auth group.
+	Type                string     `json:"type"`                    // Auth content type.
+	Window              string     `json:"window"`                  // Window key, see Window.Key.
+	Auths               int        `json:"auths"`                   // Auths contributing to the forecast.
+	Remaining           float64    `json:"remaining"`               // Remaining fraction; summed across auths for groups.
+	BurnRate            float64    `json:"burn_rate_per_hour"`      // Remaining fraction consumed per hour.
+	ResetAt             *time.Time `json:"reset_at,omitempty"`      // Next reset; the earliest member reset for groups.
+	ExhaustsAt          *time.Time `json:"exhausts_at,omitempty"`   // Forecast exhaustion; nil when quota is not being consumed.
+	ExhaustsBeforeReset bool       `json:"exhausts_before_reset"`   // Whether exhaustion is forecast before the reset.
+}
+
+// burnRate returns the fraction consumed per hour over the current window cycle. Points must be
+// ordered by time; the cycle starts after the last replenishment, seen as remaining going up.
+func burnRate(points []Point) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	start := len(points) - 1
+	for start > 0 && points[start-1].Remaining >= points[start].Remaining {
+		start--
+	}
+	first, last := points[start], points[len(points)-1]
+	elapsed := last.At.Sub(first.At).Hours()
+	if elapsed <= 0 {
+		return 0
+	}
+	rate := (first.Remaining - last.Remaining) / elapsed
+	if rate <= 0 {
+		return 0
+	}
+	return rate
+}
+
+// finish fills the exhaustion fields from the remaining quota and burn rate as of at.
+func (f *Forecast) finish(at time.Time) {
+	if f.BurnRate <= 0 {
+		return
+	}
+	exhaustsAt := at.Add(time.Duration(f.Remaining / f.BurnRate * float64(time.Hour)))
+	f.ExhaustsAt = &exhaustsAt
+	f.ExhaustsBeforeReset = f.ResetAt == nil || exhaustsAt.Before(*f.ResetAt)
+}
+
+// BuildForecasts computes per-auth and per-auth-group forecasts from snapshots ordered by time.
+// groupByKey maps auth keys to their primary auth group. Windows past their reset are skipped.
+func BuildForecasts(snapshots []models.QuotaSnapshot, groupByKey map[string]uint64, now time.Time) []Forecast {
+	// series collects the points of one auth window.
+	type series struct {
+		authID  uint64
+		authKey string
+		authTyp string
+		window  string
+		points  []Point
+	}
+	byKey := make(map[string]*series)
+	for _, snapshot := range snapshots {
+		var windows []Window
+		if errUnmarshal := json.Unmarshal(snapshot.Windows, &windows); errUnmarshal != nil {
+			continue
+		}
+		for _, window := range windows {
+			key := snapshot.AuthKey + "|" + window.Key()
+			s, ok := byKey[key]
+			if !ok {
+				s = &series{authKey: snapshot.AuthKey, window: window.Key()}
+				byKey[key] = s
+			}
+			s.authID, s.authTyp = snapshot.AuthID, snapshot.Type
+			s.points = append(s.points, Point{At: snapshot.CreatedAt, Remaining: window.RemainingFraction, ResetAt: window.ResetAt})
+		}
+	}
+
+	auths := make([]Forecast, 0, len(byKey))
+	groups := make(map[string]*Forecast)
+	for _, s := range byKey {
+		last := s.points[len(s.points)-1]
+		if last.ResetAt != nil && !last.ResetAt.After(now) {
+			continue
+		}
+		forecast := Forecast{
+			Scope:       ScopeAuth,
+			AuthID:      s.authID,
+			AuthKey:     s.authKey,
+			AuthGroupID: groupByKey[s.authKey],
+			Type:        s.authTyp,
+			Window:      s.window,
+			Auths:       1,
+			Remaining:   last.Remaining,
+			BurnRate:    burnRate(s.points),
+			ResetAt:     last.ResetAt,
+		}
+		forecast.finish(last.At)
+		auths = append(auths, forecast)
+
+		if forecast.AuthGroupID == 0 {
+			continue
+		}
+		groupKey := strings.Join([]string{strconv.FormatUint(forecast.AuthGroupID, 10), s.authTyp, s.window}, "|")
+		group, ok := groups[groupKey]
+		if !ok {
+			group = &Forecast{Scope: ScopeAuthGroup, AuthGroupID: forecast.AuthGroupID, Type: s.authTyp, Window: s.window}
+			groups[groupKey] = group
+		}
+		group.Auths++
+		group.Remaining += forecast.Remaining
+		group.BurnRate += forecast.BurnRate
+		group.ResetAt = earlierTime(group.ResetAt, forecast.ResetAt)
+	}
+	for _, group := range groups {
+		group.finish(now)
+		auths = append(auths, *group)
+	}
+	sort.Slice(auths, func(i, j int) bool {
+		a, b := auths[i], auths[j]
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		if a.AuthGroupID != b.AuthGroupID {
+			return a.AuthGroupID < b.AuthGroupID
+		}
+		if a.AuthKey != b.AuthKey {
+			return a.AuthKey < b.AuthKey
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Window < b.Window
+	})
+	return auths
+}
+
+// LoadForecasts reads recent snapshots and auth groups and builds forecasts at now.
+func LoadForecasts(ctx context.Context, db *gorm.DB, now time.Time) ([]Forecast, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	var snapshots []models.QuotaSnapshot
+	if errFind := db.WithContext(ctx).
+		Where("created_at >= ?", now.Add(-burnRateLookback)).
+		Order("created_at ASC, id ASC").
+		Find(&snapshots).Error; errFind != nil {
+		return nil, errFind
+	}
+	if len(snapshots) == 0 {
+		return []Forecast{}, nil
+	}
+
+	// authRow captures the group membership of an auth.
+	type authRow struct {
+		Key        string              `gorm:"column:key"`           // Runtime auth identifier.
+		AuthGroups models.AuthGroupIDs `gorm:"column:auth_group_id"` // Auth groups; the first is primary.
+	}
+	var rows []authRow
+	if errFind := db.WithContext(ctx).Model(&models.Auth{}).Select("key", "auth_group_id").Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	groupByKey := make(map[string]uint64, len(rows))
+	existing := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		existing[row.Key] = struct{}{}
+		if primary := row.AuthGroups.Primary(); primary != nil {
+			groupByKey[row.Key] = *primary
+		}
+	}
+	// History of deleted auths is kept until retention but no longer forecast.
+	kept := snapshots[:0]
+	for _, snapshot := range snapshots {
+		if _, ok := existing[snapshot.AuthKey]; ok || snapshot.AuthID == 0 {
+			kept = append(kept, snapshot)
+		}
+	}
+	return BuildForecasts(kept, groupByKey, now), nil
+}
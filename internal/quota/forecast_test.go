@@ -0,0 +1,115 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/datatypes"
+)
+
+// snapshotAt builds a snapshot with one all-models window.
+func snapshotAt(authID uint64, authKey string, at time.Time, remaining float64, resetAt time.Time) models.QuotaSnapshot {
+	windows, _ := json.Marshal([]Window{{Family: AllModels, Window: "5h", Unit: UnitPercent, RemainingFraction: remaining, ResetAt: &resetAt}})
+	return models.QuotaSnapshot{AuthID: authID, AuthKey: authKey, Type: "codex", Windows: datatypes.JSON(windows), CreatedAt: at}
+}
+
+func TestBuildForecastsUsesCurrentCycle(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	reset := now.Add(4 * time.Hour)
+	snapshots := []models.QuotaSnapshot{
+		// Replenished at 10:00; only the following points form the current cycle.
+		snapshotAt(1, "a", now.Add(-3*time.Hour), 0.1, now.Add(-2*time.Hour)),
+		snapshotAt(1, "a", now.Add(-2*time.Hour), 1.0, reset),
+		snapshotAt(1, "a", now.Add(-time.Hour), 0.8, reset),
+		snapshotAt(1, "a", now, 0.6, reset),
+		snapshotAt(2, "b", now.Add(-time.Hour), 0.9, reset),
+		snapshotAt(2, "b", now, 0.9, reset),
+	}
+	forecasts := BuildForecasts(snapshots, map[string]uint64{"a": 7, "b": 7}, now)
+	if len(forecasts) != 3 {
+		t.Fatalf("expected 2 auth and 1 group forecasts, got %+v", forecasts)
+	}
+	a, b, group := forecasts[0], forecasts[1], forecasts[2]
+	if a.AuthKey != "a" || a.BurnRate < 0.199 || a.BurnRate > 0.201 || a.ExhaustsAt == nil || !a.ExhaustsBeforeReset {
+		t.Fatalf("unexpected auth forecast: %+v", a)
+	}
+	if got := a.ExhaustsAt.Sub(now); got < 179*time.Minute || got > 181*time.Minute {
+		t.Fatalf("expected exhaustion in 3h, got %s", got)
+	}
+	if b.BurnRate != 0 || b.ExhaustsAt != nil {
+		t.Fatalf("expected idle auth without forecast: %+v", b)
+	}
+	if group.Scope != ScopeAuthGroup || group.AuthGroupID != 7 || group.Auths != 2 || group.Remaining != 1.5 {
+		t.Fatalf("unexpected group forecast: %+v", group)
+	}
+	// 1.5 remaining at 0.2 per hour lasts past the reset.
+	if group.ExhaustsAt == nil || group.ExhaustsBeforeReset {
+		t.Fatalf("expected group to outlast its reset: %+v", group)
+	}
+}
+
+func TestAlerterDeliversOncePerCycle(t *testing.T) {
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), "alerts.db"))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	reset := now.Add(4 * time.Hour)
+	auth := models.Auth{Key: "a", Content: datatypes.JSON(`{"type":"codex"}`)}
+	if errCreate := conn.Create(&auth).Error; errCreate != nil {
+		t.Fatalf("create auth: %v", errCreate)
+	}
+	for _, snapshot := range []models.QuotaSnapshot{
+		snapshotAt(auth.ID, "a", now.Add(-time.Hour), 0.5, reset),
+		snapshotAt(auth.ID, "a", now, 0.1, reset),
+		snapshotAt(auth.ID, "deleted", now.Add(-time.Hour), 0.5, reset),
+		snapshotAt(auth.ID+1, "deleted", now, 0.1, reset),
+	} {
+		if errCreate := conn.Create(&snapshot).Error; errCreate != nil {
+			t.Fatalf("create snapshot: %v", errCreate)
+		}
+	}
+
+	var received []alertPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload alertPayload
+		if errDecode := json.NewDecoder(r.Body).Decode(&payload); errDecode != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	var mailed []string
+	alerter := NewAlerter()
+	alerter.sendMail = func(_ context.Context, to, _, _ string) error {
+		mailed = append(mailed, to)
+		return nil
+	}
+	policy := internalsettings.QuotaHistoryPolicy{AlertHorizon: time.Hour, WebhookURL: server.URL, AlertEmails: []string{"ops@example.com"}}
+
+	if errCheck := alerter.Check(context.Background(), conn, policy, now); errCheck != nil {
+		t.Fatalf("Check: %v", errCheck)
+	}
+	if len(received) != 1 || len(received[0].Alerts) != 1 || received[0].Alerts[0].AuthKey != "a" || len(mailed) != 1 {
+		t.Fatalf("expected one alert for auth a, got %+v, mailed %v", received, mailed)
+	}
+	if errCheck := alerter.Check(context.Background(), conn, policy, now.Add(time.Minute)); errCheck != nil {
+		t.Fatalf("second Check: %v", errCheck)
+	}
+	if len(received) != 1 || len(mailed) != 1 {
+		t.Fatalf("expected no repeat alert within the cycle, got %d webhooks and %d mails", len(received), len(mailed))
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 
@@ -28,21 +29,7 @@ const (
 	maxConcurrentRequests = 5
 	noAuthRetryInterval   = 10 * time.Second
 	maxErrorBodyBytes     = 512
-)
-
-const (
-	antigravityUserAgent = "antigravity/1.11.5 windows/amd64"
-	codexUserAgent       = "codex_cli_rs/0.76.0 (Debian 13.0.0; x86_64) WindowsTerminal"
-)
-
-var (
-	antigravityQuotaURLs = []string{
-		"https://daily-cloudcode-pa.googleapis.com/v1internal:fetchAvailableModels",
-		"https://daily-cloudcode-pa.sandbox.googleapis.com/v1internal:fetchAvailableModels",
-		"https://cloudcode-pa.googleapis.com/v1internal:fetchAvailableModels",
-	}
-	geminiCLIQuotaURL = "https://cloudcode-pa.googleapis.com/v1internal:retrieveUserQuota"
-	codexUsageURL     = "https://chatgpt.com/backend-api/wham/usage"
+	historyPruneInterval  = time.Hour
 )
 
 type authRowInfo struct {
@@ -58,6 +45,10 @@ type Poller struct {
 	interval       time.Duration
 	requestTimeout time.Duration
 	hadAuths       bool
+	polledAuthIDs  map[uint64]struct{}
+	endpoints      map[string][]string
+	alerter        *Alerter
+	lastPrune      time.Time
 }
 
 // NewPoller constructs a quota poller.
@@ -70,7 +61,37 @@ func NewPoller(db *gorm.DB, manager *coreauth.Manager) *Poller {
 		manager:        manager,
 		interval:       defaultPollInterval,
 		requestTimeout: defaultRequestTimeout,
+		alerter:        NewAlerter(),
+	}
+}
+
+// SetEndpoints overrides the upstream URLs per provider, e.g. to point at a local stand-in.
+// Providers without an override keep the defaults registered with their fetcher.
+func (p *Poller) SetEndpoints(endpoints map[string][]string) {
+	if p == nil {
+		return
+	}
+	p.endpoints = make(map[string][]string, len(endpoints))
+	for provider, urls := range endpoints {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		cleaned := make([]string, 0, len(urls))
+		for _, u := range urls {
+			if u = strings.TrimSpace(u); u != "" {
+				cleaned = append(cleaned, u)
+			}
+		}
+		if provider != "" && len(cleaned) > 0 {
+			p.endpoints[provider] = cleaned
+		}
+	}
+}
+
+// endpointsFor returns the configured URLs for a provider, falling back to defaults.
+func (p *Poller) endpointsFor(provider string, defaults []string) []string {
+	if urls, ok := p.endpoints[provider]; ok {
+		return urls
 	}
+	return defaults
 }
 
 // Start launches the polling loop in a background goroutine.
@@ -140,6 +161,7 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 	sem := make(chan struct{}, maxConcurrency)
 	var wg sync.WaitGroup
 	shouldStop := false
+	polled := make(map[uint64]struct{}, len(auths))
 
 	for _, auth := range auths {
 		if shouldStop {
@@ -148,18 +170,24 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 		if auth == nil || strings.TrimSpace(auth.ID) == "" {
 			continue
 		}
-		row, ok := rowMap[auth.ID]
-		if !ok || row.RuntimeOnly {
+		row, hasRow := rowMap[auth.ID]
+		if hasRow && row.RuntimeOnly {
 			continue
 		}
 
-		provider := strings.ToLower(strings.TrimSpace(auth.Provider))
-		if provider == "" {
-			provider = strings.ToLower(strings.TrimSpace(row.Type))
+		provider := fetcherProvider(auth, row.Type)
+		fetcher, defaults, ok := lookupFetcher(provider)
+		if !ok || !fetcher.Supports(auth) {
+			continue
 		}
-		if provider != "antigravity" && provider != "codex" && provider != "gemini-cli" {
+		urls := p.endpointsFor(provider, defaults)
+		if len(urls) == 0 {
 			continue
 		}
+		if !hasRow {
+			// Auths defined only in config have no auths row; quota rows are keyed by runtime ID.
+			row = authRowInfo{Type: provider}
+		}
 
 		select {
 		case sem <- struct{}{}:
@@ -171,6 +199,9 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 			break
 		}
 
+		if row.ID != 0 {
+			polled[row.ID] = struct{}{}
+		}
 		wg.Add(1)
 		authCopy := auth
 		rowCopy := row
@@ -178,23 +209,41 @@ func (p *Poller) poll(ctx context.Context) time.Duration {
 		go func() {
 			defer wg.Done()
 			defer func() { <-sem }()
-			switch providerCopy {
-			case "antigravity":
-				p.pollAntigravity(ctx, authCopy, rowCopy)
-			case "codex":
-				p.pollCodex(ctx, authCopy, rowCopy)
-			case "gemini-cli":
-				p.pollGeminiCLI(ctx, authCopy, rowCopy)
-			default:
-				return
-			}
+			p.pollAuth(ctx, fetcher, urls, providerCopy, authCopy, rowCopy)
 		}()
 	}
 
 	wg.Wait()
+	if !shouldStop {
+		for authID := range p.polledAuthIDs {
+			if _, ok := polled[authID]; !ok {
+				metrics.ForgetQuotaRemaining(authID)
+			}
+		}
+		p.polledAuthIDs = polled
+		p.afterPoll(ctx)
+	}
 	return interval
 }
 
+// afterPoll prunes expired history and checks exhaustion forecasts for alerts.
+func (p *Poller) afterPoll(ctx context.Context) {
+	policy := internalsettings.LoadQuotaHistoryPolicy()
+	now := time.Now().UTC()
+	if policy.Retention > 0 && now.Sub(p.lastPrune) >= historyPruneInterval {
+		if errPrune := p.db.WithContext(ctx).
+			Where("created_at < ?", now.Add(-policy.Retention)).
+			Delete(&models.QuotaSnapshot{}).Error; errPrune != nil {
+			log.WithError(errPrune).Warn("quota poller: prune history failed")
+		} else {
+			p.lastPrune = now
+		}
+	}
+	if errAlert := p.alerter.Check(ctx, p.db, policy, now); errAlert != nil {
+		log.WithError(errAlert).Warn("quota poller: exhaustion alert failed")
+	}
+}
+
 func (p *Poller) loadAuthRows(ctx context.Context) (map[string]authRowInfo, error) {
 	if p == nil || p.db == nil {
 		return nil, errors.New("quota poller: db not initialized")
@@ -243,84 +292,36 @@ func (p *Poller) resolvePollConfig() (time.Duration, int) {
 	return time.Duration(intervalSeconds) * time.Second, maxConcurrency
 }
 
-func (p *Poller) pollAntigravity(ctx context.Context, auth *coreauth.Auth, row authRowInfo) {
-	headers := http.Header{}
-	headers.Set("Content-Type", "application/json")
-	headers.Set("User-Agent", antigravityUserAgent)
-	body := []byte("{}")
-
-	for _, url := range antigravityQuotaURLs {
-		status, payload, errReq := p.doRequest(ctx, auth, http.MethodPost, url, body, headers)
-		if errReq != nil {
-			log.WithError(errReq).Warnf("quota poller: antigravity request failed (auth=%s)", auth.ID)
-			continue
-		}
-		if status < http.StatusOK || status >= http.StatusMultipleChoices {
-			log.Warnf("quota poller: antigravity status=%d (auth=%s body=%s)", status, auth.ID, summarizePayload(payload))
-			continue
-		}
-		if errSave := p.saveQuota(ctx, row.ID, row.Type, payload); errSave != nil {
-			log.WithError(errSave).Warnf("quota poller: antigravity save failed (auth=%s)", auth.ID)
-		}
-		return
-	}
-}
-
-func (p *Poller) pollCodex(ctx context.Context, auth *coreauth.Auth, row authRowInfo) {
-	metadata := auth.Metadata
-	accountID := resolveCodexAccountID(metadata)
-	if accountID == "" {
-		log.Warnf("quota poller: codex missing account id (auth=%s)", auth.ID)
-		return
-	}
-
-	headers := http.Header{}
-	headers.Set("Content-Type", "application/json")
-	headers.Set("User-Agent", codexUserAgent)
-	headers.Set("Chatgpt-Account-Id", accountID)
-
-	status, payload, errReq := p.doRequest(ctx, auth, http.MethodGet, codexUsageURL, nil, headers)
-	if errReq != nil {
-		log.WithError(errReq).Warnf("quota poller: codex request failed (auth=%s)", auth.ID)
-		return
-	}
-	if status < http.StatusOK || status >= http.StatusMultipleChoices {
-		log.Warnf("quota poller: codex status=%d (auth=%s body=%s)", status, auth.ID, summarizePayload(payload))
+// pollAuth fetches and stores the quota of one auth.
+func (p *Poller) pollAuth(ctx context.Context, fetcher Fetcher, urls []string, provider string, auth *coreauth.Auth, row authRowInfo) {
+	result, errFetch := fetcher.Fetch(ctx, Request{
+		Auth:   auth,
+		URLs:   urls,
+		Client: authClient{poller: p, auth: auth},
+		Now:    time.Now().UTC(),
+	})
+	if errFetch != nil {
+		log.WithError(errFetch).Warnf("quota poller: %s fetch failed (auth=%s)", provider, auth.ID)
+		metrics.ObserveQuotaPoll(provider, false)
 		return
 	}
-	if errSave := p.saveQuota(ctx, row.ID, row.Type, payload); errSave != nil {
-		log.WithError(errSave).Warnf("quota poller: codex save failed (auth=%s)", auth.ID)
+	if errSave := p.saveQuota(ctx, row.ID, auth.ID, row.Type, result); errSave != nil {
+		log.WithError(errSave).Warnf("quota poller: %s save failed (auth=%s)", provider, auth.ID)
 	}
+	// Publish immediately so the selector reacts without waiting for the watcher reload.
+	StoreWindows(auth.ID, result.Windows)
+	observeQuota(row.ID, provider, result.Windows)
 }
 
-func (p *Poller) pollGeminiCLI(ctx context.Context, auth *coreauth.Auth, row authRowInfo) {
-	metadata := auth.Metadata
-	projectID := resolveGeminiProjectID(metadata)
-	if projectID == "" {
-		log.Warnf("quota poller: gemini-cli missing project id (auth=%s)", auth.ID)
-		return
-	}
-
-	headers := http.Header{}
-	headers.Set("Content-Type", "application/json")
-	body, errMarshal := json.Marshal(map[string]string{"project": projectID})
-	if errMarshal != nil {
-		log.WithError(errMarshal).Warnf("quota poller: gemini-cli request body failed (auth=%s)", auth.ID)
-		return
-	}
+// authClient adapts the poller's credentialed requests to the Client interface.
+type authClient struct {
+	poller *Poller
+	auth   *coreauth.Auth
+}
 
-	status, payload, errReq := p.doRequest(ctx, auth, http.MethodPost, geminiCLIQuotaURL, body, headers)
-	if errReq != nil {
-		log.WithError(errReq).Warnf("quota poller: gemini-cli request failed (auth=%s)", auth.ID)
-		return
-	}
-	if status < http.StatusOK || status >= http.StatusMultipleChoices {
-		log.Warnf("quota poller: gemini-cli status=%d (auth=%s body=%s)", status, auth.ID, summarizePayload(payload))
-		return
-	}
-	if errSave := p.saveQuota(ctx, row.ID, row.Type, payload); errSave != nil {
-		log.WithError(errSave).Warnf("quota poller: gemini-cli save failed (auth=%s)", auth.ID)
-	}
+// Do implements Client.
+func (c authClient) Do(ctx context.Context, method, targetURL string, body []byte, headers http.Header) (int, []byte, error) {
+	return c.poller.doRequest(ctx, c.auth, method, targetURL, body, headers)
 }
 
 func (p *Poller) doRequest(ctx context.Context, auth *coreauth.Auth, method, targetURL string, body []byte, headers http.Header) (int, []byte, error) {
@@ -356,7 +357,7 @@ func (p *Poller) doRequest(ctx context.Context, auth *coreauth.Auth, method, tar
 	return resp.StatusCode, payload, nil
 }
 
-func (p *Poller) saveQuota(ctx context.Context, authID uint64, authType string, payload []byte) error {
+func (p *Poller) saveQuota(ctx context.Context, authID uint64, authKey string, authType string, result Result) error {
 	if p == nil || p.db == nil {
 		return errors.New("quota poller: db not initialized")
 	}
@@ -369,30 +370,59 @@ func (p *Poller) saveQuota(ctx context.Context, authID uint64, authType string,
 		authType = "unknown"
 	}
 
-	payload = normalizePayload(payload)
+	payload := normalizePayload(result.Payload)
 	if len(payload) == 0 {
 		return errors.New("quota poller: empty payload")
 	}
+	windows := result.Windows
+	if windows == nil {
+		windows = []Window{}
+	}
+	windowsJSON, errMarshal := json.Marshal(windows)
+	if errMarshal != nil {
+		return errMarshal
+	}
 
 	now := time.Now().UTC()
+	if internalsettings.LoadQuotaHistoryPolicy().Retention > 0 {
+		snapshot := models.QuotaSnapshot{
+			AuthID:    authID,
+			AuthKey:   authKey,
+			Type:      authType,
+			Windows:   datatypes.JSON(windowsJSON),
+			CreatedAt: now,
+		}
+		if errSnapshot := p.db.WithContext(ctx).Create(&snapshot).Error; errSnapshot != nil {
+			log.WithError(errSnapshot).Warnf("quota poller: record history failed (auth=%s)", authKey)
+		}
+	}
+
 	var existing models.Quota
-	errFind := p.db.WithContext(ctx).
-		Where("auth_id = ? AND type = ?", authID, authType).
-		First(&existing).Error
+	lookup := p.db.WithContext(ctx).Where("type = ?", authType)
+	if authID != 0 {
+		lookup = lookup.Where("auth_id = ?", authID)
+	} else {
+		lookup = lookup.Where("auth_id = ? AND auth_key = ?", 0, authKey)
+	}
+	errFind := lookup.First(&existing).Error
 	if errFind == nil {
 		return p.db.WithContext(ctx).
 			Model(&models.Quota{}).
 			Where("id = ?", existing.ID).
 			Updates(map[string]any{
+				"auth_key":   authKey,
 				"data":       datatypes.JSON(payload),
+				"windows":    datatypes.JSON(windowsJSON),
 				"updated_at": now,
 			}).Error
 	}
 	if errors.Is(errFind, gorm.ErrRecordNotFound) {
 		row := models.Quota{
 			AuthID:    authID,
+			AuthKey:   authKey,
 			Type:      authType,
 			Data:      datatypes.JSON(payload),
+			Windows:   datatypes.JSON(windowsJSON),
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
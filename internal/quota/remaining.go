@@ -0,0 +1,43 @@
+package quota
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+)
+
+// observeQuota records a successful poll and exports the remaining fraction of each window.
+// Auths defined only in config have no stable numeric ID and are not exported.
+func observeQuota(authID uint64, provider string, windows []Window) {
+	metrics.ObserveQuotaPoll(provider, true)
+	if authID == 0 {
+		return
+	}
+	remaining := make(map[string]float64, len(windows))
+	for _, window := range windows {
+		remaining[window.Key()] = window.RemainingFraction
+	}
+	metrics.SetQuotaRemaining(authID, provider, remaining)
+}
+
+// floatFromAny converts a JSON number into a float64.
+func floatFromAny(value any) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		if math.IsNaN(typed) || math.IsInf(typed, 0) {
+			return 0, false
+		}
+		return typed, true
+	case json.Number:
+		parsed, errParse := typed.Float64()
+		return parsed, errParse == nil
+	default:
+		return 0, false
+	}
+}
+
+// clampFraction bounds a fraction to the [0, 1] range.
+func clampFraction(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
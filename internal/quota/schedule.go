@@ -0,0 +1,300 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	windowsMu     sync.Mutex
+	windowsByAuth atomic.Value
+)
+
+func init() {
+	windowsByAuth.Store(map[string][]Window{})
+}
+
+// StoreWindows replaces the polled windows of one auth in the in-memory snapshot.
+func StoreWindows(authKey string, windows []Window) {
+	authKey = strings.TrimSpace(authKey)
+	if authKey == "" {
+		return
+	}
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	current, _ := windowsByAuth.Load().(map[string][]Window)
+	next := make(map[string][]Window, len(current)+1)
+	for key, value := range current {
+		next[key] = value
+	}
+	next[authKey] = append([]Window(nil), windows...)
+	windowsByAuth.Store(next)
+}
+
+// ReplaceWindows replaces the whole in-memory snapshot.
+func ReplaceWindows(byAuth map[string][]Window) {
+	next := make(map[string][]Window, len(byAuth))
+	for key, windows := range byAuth {
+		if key = strings.TrimSpace(key); key != "" {
+			next[key] = append([]Window(nil), windows...)
+		}
+	}
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	windowsByAuth.Store(next)
+}
+
+// WindowsFor returns the polled windows of one auth.
+func WindowsFor(authKey string) []Window {
+	byAuth, _ := windowsByAuth.Load().(map[string][]Window)
+	return byAuth[strings.TrimSpace(authKey)]
+}
+
+// AuthWindows is the stored quota of one auth.
+type AuthWindows struct {
+	AuthKey string   `gorm:"column:auth_key"` // Runtime auth identifier.
+	Type    string   `gorm:"column:type"`     // Auth content type.
+	Windows []Window `gorm:"-"`               // Normalized windows.
+}
+
+// LoadAuthWindows reads the stored windows of every auth that still exists.
+func LoadAuthWindows(ctx context.Context, db *gorm.DB) ([]AuthWindows, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	// windowsRow captures one quota row with its resolved auth key.
+	type windowsRow struct {
+		AuthKey string         `gorm:"column:auth_key"` // Runtime auth identifier.
+		Type    string         `gorm:"column:type"`     // Auth content type.
+		Windows datatypes.JSON `gorm:"column:windows"`  // Normalized windows.
+	}
+	var rows []windowsRow
+	if errFind := db.WithContext(ctx).
+		Table("quota").
+		Joins("LEFT JOIN auths ON auths.id = quota.auth_id").
+		Where("auths.id IS NOT NULL OR quota.auth_id = 0").
+		Select("COALESCE(auths.key, quota.auth_key) AS auth_key, quota.type, quota.windows").
+		Scan(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	out := make([]AuthWindows, 0, len(rows))
+	for _, row := range rows {
+		key := strings.TrimSpace(row.AuthKey)
+		if key == "" {
+			continue
+		}
+		var windows []Window
+		if len(row.Windows) > 0 {
+			if errUnmarshal := json.Unmarshal(row.Windows, &windows); errUnmarshal != nil {
+				continue
+			}
+		}
+		out = append(out, AuthWindows{AuthKey: key, Type: row.Type, Windows: windows})
+	}
+	return out, nil
+}
+
+// ReloadWindows reads every stored quota row into the in-memory snapshot.
+func ReloadWindows(ctx context.Context, db *gorm.DB) error {
+	rows, errLoad := LoadAuthWindows(ctx, db)
+	if errLoad != nil {
+		return errLoad
+	}
+	byAuth := make(map[string][]Window, len(rows))
+	for _, row := range rows {
+		byAuth[row.AuthKey] = row.Windows
+	}
+	ReplaceWindows(byAuth)
+	return nil
+}
+
+// applicableWindows returns the windows that limit requests for model. A window applies when
+// it covers every model or when its family names the model; exact family matches take
+// precedence over partial ones such as "opus" for "claude-opus-4-5".
+func applicableWindows(windows []Window, model string) []Window {
+	model = strings.ToLower(strings.TrimSpace(model))
+	var shared, exact, partial []Window
+	for _, window := range windows {
+		family := strings.ToLower(strings.TrimSpace(window.Family))
+		switch {
+		case family == "" || family == AllModels:
+			shared = append(shared, window)
+		case model == "":
+		case family == model:
+			exact = append(exact, window)
+		case strings.Contains(model, family):
+			partial = append(partial, window)
+		}
+	}
+	if len(exact) > 0 {
+		return append(shared, exact...)
+	}
+	return append(shared, partial...)
+}
+
+// Standing summarizes the quota of one auth for one model.
+type Standing struct {
+	Known     bool       // Whether a current window applies to the model.
+	Remaining float64    // Lowest remaining fraction among applicable windows.
+	Low       bool       // Whether an applicable window is below the threshold.
+	Exhausted bool       // Whether an applicable window has no quota left.
+	ResetAt   *time.Time // When every low window has reset; nil when unknown.
+}
+
+// Assess evaluates the snapshot for authKey and model at now.
+func Assess(authKey, model string, threshold float64, now time.Time) Standing {
+	return assessWindows(WindowsFor(authKey), model, threshold, now)
+}
+
+// assessWindows evaluates windows for model. Windows whose reset time has passed are ignored
+// because the upstream has replenished them since they were polled.
+func assessWindows(windows []Window, model string, threshold float64, now time.Time) Standing {
+	var standing Standing
+	resetKnown := true
+	for _, window := range applicableWindows(windows, model) {
+		if window.ResetAt != nil && !window.ResetAt.After(now) {
+			continue
+		}
+		if !standing.Known || window.RemainingFraction < standing.Remaining {
+			standing.Remaining = window.RemainingFraction
+		}
+		standing.Known = true
+		if window.RemainingFraction > 0 && window.RemainingFraction >= threshold {
+			continue
+		}
+		standing.Low = true
+		if window.RemainingFraction <= 0 {
+			standing.Exhausted = true
+		}
+		if window.ResetAt == nil {
+			resetKnown = false
+			continue
+		}
+		if standing.ResetAt == nil || window.ResetAt.After(*standing.ResetAt) {
+			resetAt := *window.ResetAt
+			standing.ResetAt = &resetAt
+		}
+	}
+	if !resetKnown {
+		standing.ResetAt = nil
+	}
+	return standing
+}
+
+// Capacity aggregates the quota of many auths for one model family or model.
+type Capacity struct {
+	Type         string     `json:"type"`                    // Auth content type.
+	Family       string     `json:"family"`                  // Model family, AllModels, or the requested model.
+	Window       string     `json:"window,omitempty"`        // Window length, when aggregating one window.
+	Unit         string     `json:"unit,omitempty"`          // Unit of the amounts.
+	Auths        int        `json:"auths"`                   // Auths reporting the window.
+	Cooling      int        `json:"cooling"`                 // Auths below the threshold until reset.
+	Remaining    float64    `json:"remaining"`               // Sum of remaining fractions, in whole-auth equivalents.
+	RemainingSum *float64   `json:"remaining_sum,omitempty"` // Sum of Limit minus Used when every auth reports both.
+	LimitSum     *float64   `json:"limit_sum,omitempty"`     // Sum of Limit when every auth reports it.
+	NextResetAt  *time.Time `json:"next_reset_at,omitempty"` // Earliest reset among cooling auths.
+}
+
+// SummarizeWindows aggregates current windows per auth type and window key.
+func SummarizeWindows(rows []AuthWindows, threshold float64, now time.Time) []Capacity {
+	// amounts tracks whether every auth reported absolute amounts.
+	type amounts struct {
+		complete  bool
+		remaining float64
+		limit     float64
+	}
+	byKey := make(map[string]*Capacity)
+	sums := make(map[string]*amounts)
+	order := make([]string, 0)
+	for _, row := range rows {
+		for _, window := range row.Windows {
+			if window.ResetAt != nil && !window.ResetAt.After(now) {
+				continue
+			}
+			key := row.Type + "|" + window.Key()
+			capacity, ok := byKey[key]
+			if !ok {
+				capacity = &Capacity{Type: row.Type, Family: window.Family, Window: window.Window, Unit: window.Unit}
+				byKey[key] = capacity
+				sums[key] = &amounts{complete: true}
+				order = append(order, key)
+			}
+			capacity.Auths++
+			capacity.Remaining += window.RemainingFraction
+			if window.RemainingFraction <= 0 || window.RemainingFraction < threshold {
+				capacity.Cooling++
+				capacity.NextResetAt = earlierTime(capacity.NextResetAt, window.ResetAt)
+			}
+			sum := sums[key]
+			if window.Used == nil || window.Limit == nil {
+				sum.complete = false
+				continue
+			}
+			sum.remaining += *window.Limit - *window.Used
+			sum.limit += *window.Limit
+		}
+	}
+	sort.Strings(order)
+	out := make([]Capacity, 0, len(order))
+	for _, key := range order {
+		capacity := byKey[key]
+		if sum := sums[key]; sum.complete {
+			remaining, limit := sum.remaining, sum.limit
+			capacity.RemainingSum = &remaining
+			capacity.LimitSum = &limit
+		}
+		out = append(out, *capacity)
+	}
+	return out
+}
+
+// SummarizeModel aggregates, per auth type, the quota available to model across auths.
+func SummarizeModel(rows []AuthWindows, model string, threshold float64, now time.Time) []Capacity {
+	byType := make(map[string]*Capacity)
+	types := make([]string, 0)
+	for _, row := range rows {
+		standing := assessWindows(row.Windows, model, threshold, now)
+		if !standing.Known {
+			continue
+		}
+		capacity, ok := byType[row.Type]
+		if !ok {
+			capacity = &Capacity{Type: row.Type, Family: model, Unit: UnitFraction}
+			byType[row.Type] = capacity
+			types = append(types, row.Type)
+		}
+		capacity.Auths++
+		capacity.Remaining += standing.Remaining
+		if standing.Low {
+			capacity.Cooling++
+			capacity.NextResetAt = earlierTime(capacity.NextResetAt, standing.ResetAt)
+		}
+	}
+	sort.Strings(types)
+	out := make([]Capacity, 0, len(types))
+	for _, authType := range types {
+		out = append(out, *byType[authType])
+	}
+	return out
+}
+
+// earlierTime returns the earlier of two optional times.
+func earlierTime(current, candidate *time.Time) *time.Time {
+	if candidate == nil {
+		return current
+	}
+	if current == nil || candidate.Before(*current) {
+		value := *candidate
+		return &value
+	}
+	return current
+}
@@ -0,0 +1,58 @@
+package quota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssessAndSummarizeCapacity(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	reset := now.Add(time.Hour)
+	used, limit := 15.0, 20.0
+	rows := []AuthWindows{
+		{AuthKey: "a", Type: "antigravity", Windows: []Window{
+			{Family: "gemini-2.5-flash", Unit: UnitFraction, RemainingFraction: 0.02, ResetAt: &reset},
+			{Family: "gemini-2.5-flash-lite", Unit: UnitFraction, RemainingFraction: 0.9, ResetAt: &reset},
+		}},
+		{AuthKey: "b", Type: "antigravity", Windows: []Window{
+			{Family: "gemini-2.5-flash", Unit: UnitFraction, RemainingFraction: 0.5},
+		}},
+		{AuthKey: "c", Type: "relay", Windows: []Window{
+			{Family: AllModels, Window: "1mo", Unit: UnitUSD, Used: &used, Limit: &limit, RemainingFraction: 0.25, ResetAt: &reset},
+		}},
+	}
+
+	// The exact family wins over the shorter family it contains.
+	lite := assessWindows(rows[0].Windows, "gemini-2.5-flash-lite", 0.05, now)
+	if !lite.Known || lite.Low || lite.Remaining != 0.9 {
+		t.Fatalf("unexpected flash-lite standing: %+v", lite)
+	}
+	flash := assessWindows(rows[0].Windows, "gemini-2.5-flash", 0.05, now)
+	if !flash.Low || flash.Exhausted || flash.ResetAt == nil || !flash.ResetAt.Equal(reset) {
+		t.Fatalf("unexpected flash standing: %+v", flash)
+	}
+	if stale := assessWindows(rows[0].Windows, "gemini-2.5-flash", 0.05, reset); stale.Known {
+		t.Fatalf("expected windows past their reset to be ignored: %+v", stale)
+	}
+
+	windows := SummarizeWindows(rows, 0.05, now)
+	if len(windows) != 3 {
+		t.Fatalf("expected 3 window aggregates, got %+v", windows)
+	}
+	flashWindow := windows[0]
+	if flashWindow.Family != "gemini-2.5-flash" || flashWindow.Auths != 2 || flashWindow.Cooling != 1 || flashWindow.Remaining != 0.52 {
+		t.Fatalf("unexpected flash aggregate: %+v", flashWindow)
+	}
+	if flashWindow.RemainingSum != nil || flashWindow.NextResetAt == nil {
+		t.Fatalf("unexpected flash amounts: %+v", flashWindow)
+	}
+	if usd := windows[2]; usd.Type != "relay" || usd.RemainingSum == nil || *usd.RemainingSum != 5 || *usd.LimitSum != 20 {
+		t.Fatalf("unexpected usd aggregate: %+v", usd)
+	}
+
+	model := SummarizeModel(rows, "gemini-2.5-flash", 0.05, now)
+	// The relay window covers every model, so it contributes capacity too.
+	if len(model) != 2 || model[0].Auths != 2 || model[0].Cooling != 1 || model[1].Type != "relay" || model[1].Remaining != 0.25 {
+		t.Fatalf("unexpected model capacity: %+v", model)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
@@ -78,11 +79,14 @@ func ResolveLimit(ctx context.Context, db *gorm.DB, userID uint64, provider, mod
 }
 
 func resolveBillRateLimit(ctx context.Context, db *gorm.DB, userID uint64, now time.Time) (int, error) {
+	payer, errPayer := billing.ResolvePayer(ctx, db, userID)
+	if errPayer != nil {
+		return 0, errPayer
+	}
 	var rows []models.Bill
-	if errFind := db.WithContext(ctx).
-		Model(&models.Bill{}).
+	if errFind := payer.ScopeBills(db.WithContext(ctx).Model(&models.Bill{})).
 		Select("rate_limit").
-		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
+		Where("is_enabled = ? AND status = ? AND left_quota > 0", true, models.BillStatusPaid).
 		Where("period_start <= ? AND period_end >= ?", now, now).
 		Find(&rows).Error; errFind != nil {
 		return 0, errFind
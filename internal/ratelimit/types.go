@@ -32,3 +32,15 @@ type Decision struct {
 	Scope     Scope
 	MappingID uint64
 }
+
+// String returns the metrics label for the scope.
+func (s Scope) String() string {
+	switch s {
+	case ScopeUser:
+		return "user"
+	case ScopeModelMapping:
+		return "model_mapping"
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,268 @@
+package reports
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/authfiles"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	"gorm.io/gorm"
+)
+
+// Range selects usage requested in [From, To), optionally for one user. A zero To leaves the
+// range open.
+type Range struct {
+	From   time.Time // Inclusive start.
+	To     time.Time // Exclusive end; zero for no end.
+	UserID *uint64   // Restricts usage to one user when set.
+}
+
+// usage returns a usage query limited to the range.
+func (r Range) usage(ctx context.Context, db *gorm.DB) *gorm.DB {
+	q := db.WithContext(ctx).Model(&models.Usage{}).Where("requested_at >= ?", r.From)
+	if !r.To.IsZero() {
+		q = q.Where("requested_at < ?", r.To)
+	}
+	if r.UserID != nil {
+		q = q.Where("user_id = ?", *r.UserID)
+	}
+	return q
+}
+
+// Totals aggregates usage over a range.
+type Totals struct {
+	Requests       int64 `json:"requests"`        // Requests served.
+	FailedRequests int64 `json:"failed_requests"` // Requests that failed.
+	InputTokens    int64 `json:"input_tokens"`    // Input tokens.
+	OutputTokens   int64 `json:"output_tokens"`   // Output tokens.
+	TotalTokens    int64 `json:"total_tokens"`    // Total tokens.
+	CostMicros     int64 `json:"cost_micros"`     // Amount billed to users.
+}
+
+// FailureRate returns the failed share of requests as a percentage.
+func (t Totals) FailureRate() float64 {
+	if t.Requests == 0 {
+		return 0
+	}
+	return float64(t.FailedRequests) / float64(t.Requests) * 100
+}
+
+// LoadTotals aggregates usage over a range.
+func LoadTotals(ctx context.Context, db *gorm.DB, r Range) (Totals, error) {
+	var totals Totals
+	errScan := r.usage(ctx, db).
+		Select(`
+			COUNT(*) AS requests,
+			COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cost_micros), 0) AS cost_micros
+		`).
+		Scan(&totals).Error
+	return totals, errScan
+}
+
+// ModelRow aggregates usage of one model.
+type ModelRow struct {
+	Model          string  `json:"model"`           // Model identifier.
+	Requests       int64   `json:"requests"`        // Requests served.
+	FailedRequests int64   `json:"failed_requests"` // Requests that failed.
+	TotalTokens    int64   `json:"total_tokens"`    // Total tokens.
+	CostMicros     int64   `json:"cost_micros"`     // Amount billed to users.
+	Percentage     float64 `json:"percentage"`      // Share of the range's total cost.
+}
+
+// TopModels returns models ordered by cost; limit <= 0 returns every model.
+func TopModels(ctx context.Context, db *gorm.DB, r Range, limit int) ([]ModelRow, error) {
+	var rows []ModelRow
+	q := r.usage(ctx, db).
+		Select(`
+			model,
+			COUNT(*) AS requests,
+			COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cost_micros), 0) AS cost_micros
+		`).
+		Group("model").
+		Order("cost_micros DESC, requests DESC, model ASC")
+	if errScan := q.Scan(&rows).Error; errScan != nil {
+		return nil, errScan
+	}
+	var totalCost int64
+	for _, row := range rows {
+		totalCost += row.CostMicros
+	}
+	for i := range rows {
+		if totalCost > 0 {
+			rows[i].Percentage = float64(rows[i].CostMicros) / float64(totalCost) * 100
+		}
+	}
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// UserRow aggregates usage of one user.
+type UserRow struct {
+	UserID      uint64 `json:"user_id"`      // User ID.
+	Username    string `json:"username"`     // Login name.
+	Email       string `json:"email"`        // Email address.
+	Requests    int64  `json:"requests"`     // Requests made.
+	TotalTokens int64  `json:"total_tokens"` // Total tokens.
+	CostMicros  int64  `json:"cost_micros"`  // Amount billed.
+}
+
+// TopUsers returns the users with the highest cost over a range.
+func TopUsers(ctx context.Context, db *gorm.DB, r Range, limit int) ([]UserRow, error) {
+	var rows []UserRow
+	q := r.usage(ctx, db).
+		Where("user_id IS NOT NULL").
+		Select(`
+			user_id,
+			COUNT(*) AS requests,
+			COALESCE(SUM(total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(cost_micros), 0) AS cost_micros
+		`).
+		Group("user_id").
+		Order("cost_micros DESC, requests DESC, user_id ASC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if errScan := q.Scan(&rows).Error; errScan != nil {
+		return nil, errScan
+	}
+	if len(rows) == 0 {
+		return rows, nil
+	}
+	ids := make([]uint64, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.UserID)
+	}
+	var users []models.User
+	if errFind := db.WithContext(ctx).Select("id", "username", "email").Where("id IN ?", ids).Find(&users).Error; errFind != nil {
+		return nil, errFind
+	}
+	byID := make(map[uint64]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	for i := range rows {
+		rows[i].Username = byID[rows[i].UserID].Username
+		rows[i].Email = byID[rows[i].UserID].Email
+	}
+	return rows, nil
+}
+
+// FailureRow aggregates failures of one provider and model.
+type FailureRow struct {
+	Provider       string  `json:"provider"`        // Provider name.
+	Model          string  `json:"model"`           // Model identifier.
+	Requests       int64   `json:"requests"`        // Requests served.
+	FailedRequests int64   `json:"failed_requests"` // Requests that failed.
+	FailureRate    float64 `json:"failure_rate"`    // Failed share of requests as a percentage.
+}
+
+// FailureRates returns providers and models with failures, highest failure count first.
+func FailureRates(ctx context.Context, db *gorm.DB, r Range, limit int) ([]FailureRow, error) {
+	var rows []FailureRow
+	q := r.usage(ctx, db).
+		Select(`
+			provider,
+			model,
+			COUNT(*) AS requests,
+			COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0) AS failed_requests
+		`).
+		Group("provider, model").
+		Having("SUM(CASE WHEN failed THEN 1 ELSE 0 END) > 0").
+		Order("failed_requests DESC, requests DESC, provider ASC, model ASC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if errScan := q.Scan(&rows).Error; errScan != nil {
+		return nil, errScan
+	}
+	for i := range rows {
+		rows[i].FailureRate = Totals{Requests: rows[i].Requests, FailedRequests: rows[i].FailedRequests}.FailureRate()
+	}
+	return rows, nil
+}
+
+// AuthIssue describes an auth needing attention.
+type AuthIssue struct {
+	AuthID  uint64 `json:"auth_id"`  // Auth ID.
+	AuthKey string `json:"auth_key"` // Auth key.
+	Type    string `json:"type"`     // Auth content type.
+	Reason  string `json:"reason"`   // Why the auth needs attention.
+}
+
+// AuthHealth summarizes the state of the stored upstream auths.
+type AuthHealth struct {
+	Total       int         `json:"total"`       // Stored auths.
+	Available   int         `json:"available"`   // Auths enabled for selection.
+	Unavailable int         `json:"unavailable"` // Auths disabled by admins.
+	Invalid     int         `json:"invalid"`     // Enabled auths whose credentials appear invalid.
+	Issues      []AuthIssue `json:"issues"`      // Enabled auths with invalid credentials.
+}
+
+// LoadAuthHealth counts auths by state and lists enabled auths with invalid credentials. The
+// manager, when set, contributes runtime credential errors.
+func LoadAuthHealth(ctx context.Context, db *gorm.DB, manager *coreauth.Manager, now time.Time) (AuthHealth, error) {
+	var rows []models.Auth
+	if errFind := db.WithContext(ctx).Order("id ASC").Find(&rows).Error; errFind != nil {
+		return AuthHealth{}, errFind
+	}
+	health := AuthHealth{Total: len(rows), Issues: make([]AuthIssue, 0)}
+	for _, row := range rows {
+		if !row.IsAvailable {
+			health.Unavailable++
+			continue
+		}
+		health.Available++
+		reason := ""
+		if manager != nil {
+			if runtimeAuth, ok := manager.GetByID(row.Key); ok {
+				reason = authfiles.RuntimeCredentialIssue(runtimeAuth)
+			}
+		}
+		if reason == "" {
+			reason = authfiles.StoredCredentialIssue(row.Content, now)
+		}
+		if reason == "" {
+			continue
+		}
+		health.Invalid++
+		health.Issues = append(health.Issues, AuthIssue{
+			AuthID:  row.ID,
+			AuthKey: row.Key,
+			Type:    authfiles.ContentType(row.Content),
+			Reason:  reason,
+		})
+	}
+	return health, nil
+}
+
+// QuotaOutlook returns auth and auth group forecasts that exhaust before their reset within
+// horizon, soonest first.
+func QuotaOutlook(ctx context.Context, db *gorm.DB, now time.Time, horizon time.Duration, limit int) ([]quota.Forecast, error) {
+	forecasts, errLoad := quota.LoadForecasts(ctx, db, now)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	out := make([]quota.Forecast, 0)
+	for _, forecast := range forecasts {
+		if forecast.ExhaustsAt == nil || !forecast.ExhaustsBeforeReset || forecast.ExhaustsAt.Sub(now) > horizon {
+			continue
+		}
+		out = append(out, forecast)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ExhaustsAt.Before(*out[j].ExhaustsAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
@@ -0,0 +1,208 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatMicros renders an amount in micros with four decimals.
+func formatMicros(micros int64) string {
+	return strconv.FormatFloat(float64(micros)/1_000_000, 'f', 4, 64)
+}
+
+// formatPercent renders a percentage with one decimal.
+func formatPercent(value float64) string {
+	return strconv.FormatFloat(value, 'f', 1, 64) + "%"
+}
+
+// formatTime renders a timestamp in its own location.
+func formatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04 MST")
+}
+
+// sectionTitles names sections in rendered output.
+var sectionTitles = map[string]string{
+	SectionRevenue:      "Revenue",
+	SectionTopUsers:     "Top users",
+	SectionTopModels:    "Top models",
+	SectionFailureRates: "Failure rates",
+	SectionAuthHealth:   "Auth health",
+	SectionQuotaOutlook: "Quota outlook",
+}
+
+// table is a titled grid shared by the HTML and CSV renderers.
+type table struct {
+	Section string
+	Title   string
+	Header  []string
+	Rows    [][]string
+}
+
+// tables converts the report sections into tables in rendering order.
+func (r *Report) tables() []table {
+	out := make([]table, 0, len(r.Sections))
+	for _, section := range r.Sections {
+		t := table{Section: section, Title: sectionTitles[section]}
+		switch section {
+		case SectionRevenue:
+			if r.Revenue == nil {
+				continue
+			}
+			cur, prev := r.Revenue.Current, r.Revenue.Previous
+			t.Header = []string{"metric", "period", "previous period", "change"}
+			t.Rows = [][]string{
+				{"billed", formatMicros(cur.CostMicros), formatMicros(prev.CostMicros), formatPercent(r.Revenue.CostChange)},
+				{"requests", strconv.FormatInt(cur.Requests, 10), strconv.FormatInt(prev.Requests, 10), formatPercent(r.Revenue.RequestTrend)},
+				{"failed requests", strconv.FormatInt(cur.FailedRequests, 10), strconv.FormatInt(prev.FailedRequests, 10), ""},
+				{"total tokens", strconv.FormatInt(cur.TotalTokens, 10), strconv.FormatInt(prev.TotalTokens, 10), ""},
+			}
+		case SectionTopUsers:
+			t.Header = []string{"user id", "username", "email", "requests", "total tokens", "billed"}
+			for _, row := range r.TopUsers {
+				t.Rows = append(t.Rows, []string{
+					strconv.FormatUint(row.UserID, 10), row.Username, row.Email,
+					strconv.FormatInt(row.Requests, 10), strconv.FormatInt(row.TotalTokens, 10), formatMicros(row.CostMicros),
+				})
+			}
+		case SectionTopModels:
+			t.Header = []string{"model", "requests", "failed", "total tokens", "billed", "share"}
+			for _, row := range r.TopModels {
+				t.Rows = append(t.Rows, []string{
+					row.Model, strconv.FormatInt(row.Requests, 10), strconv.FormatInt(row.FailedRequests, 10),
+					strconv.FormatInt(row.TotalTokens, 10), formatMicros(row.CostMicros), formatPercent(row.Percentage),
+				})
+			}
+		case SectionFailureRates:
+			t.Header = []string{"provider", "model", "requests", "failed", "failure rate"}
+			for _, row := range r.FailureRates {
+				t.Rows = append(t.Rows, []string{
+					row.Provider, row.Model, strconv.FormatInt(row.Requests, 10),
+					strconv.FormatInt(row.FailedRequests, 10), formatPercent(row.FailureRate),
+				})
+			}
+		case SectionAuthHealth:
+			if r.AuthHealth == nil {
+				continue
+			}
+			h := r.AuthHealth
+			t.Header = []string{"auth", "type", "issue"}
+			t.Rows = append(t.Rows, []string{
+				fmt.Sprintf("%d total, %d available, %d disabled, %d invalid", h.Total, h.Available, h.Unavailable, h.Invalid), "", "",
+			})
+			for _, issue := range h.Issues {
+				t.Rows = append(t.Rows, []string{issue.AuthKey, issue.Type, issue.Reason})
+			}
+		case SectionQuotaOutlook:
+			t.Header = []string{"scope", "auth", "auth group", "type", "window", "remaining", "burn per hour", "exhausts at", "resets at"}
+			for _, forecast := range r.QuotaOutlook {
+				resetAt := ""
+				if forecast.ResetAt != nil {
+					resetAt = formatTime(forecast.ResetAt.In(r.To.Location()))
+				}
+				t.Rows = append(t.Rows, []string{
+					forecast.Scope, forecast.AuthKey, strconv.FormatUint(forecast.AuthGroupID, 10), forecast.Type, forecast.Window,
+					formatPercent(forecast.Remaining * 100), formatPercent(forecast.BurnRate * 100),
+					formatTime(forecast.ExhaustsAt.In(r.To.Location())), resetAt,
+				})
+			}
+		default:
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// htmlTemplate renders a report as a self-contained HTML document suitable for email.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family:Arial,Helvetica,sans-serif;color:#222;">
+<h1 style="font-size:20px;">{{.Title}}</h1>
+<p style="color:#666;">{{.Period}} &middot; generated {{.GeneratedAt}}</p>
+{{range .Tables}}<h2 style="font-size:16px;margin-top:24px;">{{.Title}}</h2>
+{{if .Rows}}<table cellpadding="6" cellspacing="0" style="border-collapse:collapse;font-size:13px;">
+<tr>{{range .Header}}<th align="left" style="border-bottom:1px solid #ccc;">{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td style="border-bottom:1px solid #eee;">{{.}}</td>{{end}}</tr>
+{{end}}</table>{{else}}<p style="color:#666;">Nothing to report.</p>{{end}}
+{{end}}</body>
+</html>
+`))
+
+// RenderHTML renders the report as an HTML document.
+func RenderHTML(r *Report) (string, error) {
+	var buf bytes.Buffer
+	errExecute := htmlTemplate.Execute(&buf, struct {
+		Title       string
+		Period      string
+		GeneratedAt string
+		Tables      []table
+	}{
+		Title:       r.Title,
+		Period:      periodLabel(r),
+		GeneratedAt: formatTime(r.GeneratedAt),
+		Tables:      r.tables(),
+	})
+	if errExecute != nil {
+		return "", errExecute
+	}
+	return buf.String(), nil
+}
+
+// RenderCSV renders the report as CSV: each section starts with a row naming it, followed by
+// its header and rows, and sections are separated by an empty line.
+func RenderCSV(r *Report) (string, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	for i, t := range r.tables() {
+		if i > 0 {
+			if errWrite := writer.Write([]string{}); errWrite != nil {
+				return "", errWrite
+			}
+		}
+		if errWrite := writer.Write([]string{"section", t.Section}); errWrite != nil {
+			return "", errWrite
+		}
+		if errWrite := writer.Write(t.Header); errWrite != nil {
+			return "", errWrite
+		}
+		if errWrite := writer.WriteAll(t.Rows); errWrite != nil {
+			return "", errWrite
+		}
+	}
+	writer.Flush()
+	if errFlush := writer.Error(); errFlush != nil {
+		return "", errFlush
+	}
+	return buf.String(), nil
+}
+
+// RenderText renders a short plain-text summary used as the email text body.
+func RenderText(r *Report) string {
+	var b strings.Builder
+	b.WriteString(r.Title + "\n")
+	b.WriteString(periodLabel(r) + "\n\n")
+	if r.Revenue != nil {
+		cur := r.Revenue.Current
+		fmt.Fprintf(&b, "Billed: %s (%s vs previous period)\n", formatMicros(cur.CostMicros), formatPercent(r.Revenue.CostChange))
+		fmt.Fprintf(&b, "Requests: %d, failed: %d (%s)\n", cur.Requests, cur.FailedRequests, formatPercent(cur.FailureRate()))
+	}
+	if r.AuthHealth != nil {
+		fmt.Fprintf(&b, "Auths: %d available, %d disabled, %d with invalid credentials\n",
+			r.AuthHealth.Available, r.AuthHealth.Unavailable, r.AuthHealth.Invalid)
+	}
+	if len(r.QuotaOutlook) > 0 {
+		fmt.Fprintf(&b, "Quota windows at risk within %s: %d\n", quotaOutlookHorizon, len(r.QuotaOutlook))
+	}
+	return b.String()
+}
+
+// periodLabel describes the covered period.
+func periodLabel(r *Report) string {
+	return fmt.Sprintf("%s report for %s to %s", r.Frequency, r.From.Format("2006-01-02"), r.To.Format("2006-01-02"))
+}
@@ -0,0 +1,256 @@
+// Package reports builds periodic business reports from the dashboard queries and delivers them
+// by email or webhook.
+package reports
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
+	"gorm.io/gorm"
+)
+
+// Report frequencies.
+const (
+	// FrequencyDaily covers the previous day.
+	FrequencyDaily = "daily"
+	// FrequencyWeekly covers the previous Monday-to-Monday week.
+	FrequencyWeekly = "weekly"
+	// FrequencyMonthly covers the previous calendar month.
+	FrequencyMonthly = "monthly"
+)
+
+// Report sections.
+const (
+	// SectionRevenue reports totals billed, compared with the period before.
+	SectionRevenue = "revenue"
+	// SectionTopUsers lists the users with the highest cost.
+	SectionTopUsers = "top_users"
+	// SectionTopModels lists the models with the highest cost.
+	SectionTopModels = "top_models"
+	// SectionFailureRates lists providers and models with failures.
+	SectionFailureRates = "failure_rates"
+	// SectionAuthHealth summarizes upstream auth state.
+	SectionAuthHealth = "auth_health"
+	// SectionQuotaOutlook lists quota windows forecast to run out.
+	SectionQuotaOutlook = "quota_outlook"
+)
+
+// Report formats and delivery channels.
+const (
+	// FormatHTML renders the report as an HTML document.
+	FormatHTML = "html"
+	// FormatCSV renders the report as CSV blocks, one per section.
+	FormatCSV = "csv"
+	// ChannelEmail delivers the report by SMTP.
+	ChannelEmail = "email"
+	// ChannelWebhook posts the report as JSON.
+	ChannelWebhook = "webhook"
+)
+
+// topLimit bounds the rows of ranked sections.
+const topLimit = 10
+
+// quotaOutlookHorizon bounds how far ahead the quota outlook looks.
+const quotaOutlookHorizon = 24 * time.Hour
+
+// AllSections lists every section in rendering order.
+var AllSections = []string{
+	SectionRevenue, SectionTopUsers, SectionTopModels, SectionFailureRates, SectionAuthHealth, SectionQuotaOutlook,
+}
+
+// ErrUnknownSection indicates an unsupported report section.
+var ErrUnknownSection = errors.New("unknown report section")
+
+// ValidFrequency reports whether the frequency is supported.
+func ValidFrequency(frequency string) bool {
+	switch frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+		return true
+	default:
+		return false
+	}
+}
+
+// NormalizeSections lowercases, dedupes, and validates sections, returning them in rendering
+// order. An empty list selects every section.
+func NormalizeSections(sections []string) ([]string, error) {
+	wanted := make(map[string]struct{}, len(sections))
+	for _, section := range sections {
+		section = strings.ToLower(strings.TrimSpace(section))
+		if section == "" {
+			continue
+		}
+		known := false
+		for _, candidate := range AllSections {
+			if candidate == section {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, ErrUnknownSection
+		}
+		wanted[section] = struct{}{}
+	}
+	if len(wanted) == 0 {
+		return append([]string(nil), AllSections...), nil
+	}
+	out := make([]string, 0, len(wanted))
+	for _, section := range AllSections {
+		if _, ok := wanted[section]; ok {
+			out = append(out, section)
+		}
+	}
+	return out, nil
+}
+
+// periodStart returns the start of the period containing t.
+func periodStart(frequency string, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch frequency {
+	case FrequencyWeekly:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case FrequencyMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day
+	}
+}
+
+// advance returns the start of the period after the one starting at start.
+func advance(frequency string, start time.Time) time.Time {
+	switch frequency {
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7)
+	case FrequencyMonthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Period returns the last complete period before now, in now's location.
+func Period(frequency string, now time.Time) (time.Time, time.Time) {
+	to := periodStart(frequency, now)
+	switch frequency {
+	case FrequencyWeekly:
+		return to.AddDate(0, 0, -7), to
+	case FrequencyMonthly:
+		return to.AddDate(0, -1, 0), to
+	default:
+		return to.AddDate(0, 0, -1), to
+	}
+}
+
+// NextRun returns when a schedule with the frequency is next due after now: the end of the
+// current period.
+func NextRun(frequency string, now time.Time) time.Time {
+	return advance(frequency, periodStart(frequency, now))
+}
+
+// RevenueSection compares the period totals with the period before.
+type RevenueSection struct {
+	Current      Totals  `json:"current"`       // Totals of the period.
+	Previous     Totals  `json:"previous"`      // Totals of the period before.
+	CostChange   float64 `json:"cost_change"`   // Percentage change of the billed amount.
+	RequestTrend float64 `json:"request_trend"` // Percentage change of requests.
+}
+
+// Report is the rendered-independent content of one report.
+type Report struct {
+	Title        string           `json:"title"`                   // Report title.
+	Frequency    string           `json:"frequency"`               // Covered period length.
+	From         time.Time        `json:"from"`                    // Inclusive period start.
+	To           time.Time        `json:"to"`                      // Exclusive period end.
+	GeneratedAt  time.Time        `json:"generated_at"`            // Build time.
+	Sections     []string         `json:"sections"`                // Included sections in rendering order.
+	Revenue      *RevenueSection  `json:"revenue,omitempty"`       // SectionRevenue.
+	TopUsers     []UserRow        `json:"top_users,omitempty"`     // SectionTopUsers.
+	TopModels    []ModelRow       `json:"top_models,omitempty"`    // SectionTopModels.
+	FailureRates []FailureRow     `json:"failure_rates,omitempty"` // SectionFailureRates.
+	AuthHealth   *AuthHealth      `json:"auth_health,omitempty"`   // SectionAuthHealth.
+	QuotaOutlook []quota.Forecast `json:"quota_outlook,omitempty"` // SectionQuotaOutlook.
+}
+
+// Options selects what a report covers.
+type Options struct {
+	Title     string            // Report title.
+	Frequency string            // Covered period length.
+	Sections  []string          // Normalized sections.
+	Manager   *coreauth.Manager // Optional runtime auth state for SectionAuthHealth.
+}
+
+// Build assembles the report for the last complete period before now.
+func Build(ctx context.Context, db *gorm.DB, opts Options, now time.Time) (*Report, error) {
+	if db == nil {
+		return nil, errors.New("nil db")
+	}
+	from, to := Period(opts.Frequency, now)
+	report := &Report{
+		Title:       opts.Title,
+		Frequency:   opts.Frequency,
+		From:        from,
+		To:          to,
+		GeneratedAt: now,
+		Sections:    opts.Sections,
+	}
+	r := Range{From: from, To: to}
+	for _, section := range opts.Sections {
+		var errSection error
+		switch section {
+		case SectionRevenue:
+			report.Revenue, errSection = loadRevenue(ctx, db, opts.Frequency, r)
+		case SectionTopUsers:
+			report.TopUsers, errSection = TopUsers(ctx, db, r, topLimit)
+		case SectionTopModels:
+			report.TopModels, errSection = TopModels(ctx, db, r, topLimit)
+		case SectionFailureRates:
+			report.FailureRates, errSection = FailureRates(ctx, db, r, topLimit)
+		case SectionAuthHealth:
+			var health AuthHealth
+			health, errSection = LoadAuthHealth(ctx, db, opts.Manager, now)
+			report.AuthHealth = &health
+		case SectionQuotaOutlook:
+			report.QuotaOutlook, errSection = QuotaOutlook(ctx, db, now, quotaOutlookHorizon, topLimit)
+		}
+		if errSection != nil {
+			return nil, errSection
+		}
+	}
+	return report, nil
+}
+
+// loadRevenue loads the totals of a period and the period before it.
+func loadRevenue(ctx context.Context, db *gorm.DB, frequency string, r Range) (*RevenueSection, error) {
+	current, errCurrent := LoadTotals(ctx, db, r)
+	if errCurrent != nil {
+		return nil, errCurrent
+	}
+	prevFrom, prevTo := Period(frequency, r.From)
+	previous, errPrevious := LoadTotals(ctx, db, Range{From: prevFrom, To: prevTo})
+	if errPrevious != nil {
+		return nil, errPrevious
+	}
+	return &RevenueSection{
+		Current:      current,
+		Previous:     previous,
+		CostChange:   percentChange(previous.CostMicros, current.CostMicros),
+		RequestTrend: percentChange(previous.Requests, current.Requests),
+	}, nil
+}
+
+// percentChange returns the percentage change from prev to current.
+func percentChange(prev, current int64) float64 {
+	if prev == 0 {
+		if current > 0 {
+			return 100
+		}
+		return 0
+	}
+	return float64(current-prev) / float64(prev) * 100
+}
@@ -0,0 +1,237 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), name))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+// mailbox records messages instead of sending them.
+type mailbox struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *mailbox) send(_ context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newTestScheduler(conn *gorm.DB, box *mailbox) *Scheduler {
+	s := NewScheduler(conn, nil)
+	s.location = time.UTC
+	s.sendMail = box.send
+	return s
+}
+
+func TestPeriods(t *testing.T) {
+	now := time.Date(2026, 3, 11, 15, 30, 0, 0, time.UTC) // Wednesday.
+	cases := []struct {
+		frequency string
+		from, to  time.Time
+		next      time.Time
+	}{
+		{FrequencyDaily, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{FrequencyWeekly, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{FrequencyMonthly, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		from, to := Period(tc.frequency, now)
+		if !from.Equal(tc.from) || !to.Equal(tc.to) {
+			t.Fatalf("%s: expected [%s, %s), got [%s, %s)", tc.frequency, tc.from, tc.to, from, to)
+		}
+		if next := NextRun(tc.frequency, now); !next.Equal(tc.next) {
+			t.Fatalf("%s: expected next run %s, got %s", tc.frequency, tc.next, next)
+		}
+	}
+}
+
+func TestNormalizeSections(t *testing.T) {
+	sections, errNormalize := NormalizeSections([]string{"Top_Models", "revenue", "revenue"})
+	if errNormalize != nil {
+		t.Fatalf("normalize: %v", errNormalize)
+	}
+	if strings.Join(sections, ",") != "revenue,top_models" {
+		t.Fatalf("unexpected sections %v", sections)
+	}
+	if all, _ := NormalizeSections(nil); len(all) != len(AllSections) {
+		t.Fatalf("expected every section, got %v", all)
+	}
+	if _, errUnknown := NormalizeSections([]string{"weather"}); errUnknown != ErrUnknownSection {
+		t.Fatalf("expected unknown section, got %v", errUnknown)
+	}
+}
+
+func seedUsage(t *testing.T, conn *gorm.DB, day time.Time) models.User {
+	t.Helper()
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x", UsageReportFrequency: FrequencyDaily}
+	if errCreate := conn.Create(&user).Error; errCreate != nil {
+		t.Fatalf("create user: %v", errCreate)
+	}
+	usages := []models.Usage{
+		{Provider: "claude", Model: "claude-x", UserID: &user.ID, RequestedAt: day.Add(time.Hour), TotalTokens: 100, CostMicros: 3_000_000},
+		{Provider: "claude", Model: "claude-x", UserID: &user.ID, RequestedAt: day.Add(2 * time.Hour), Failed: true},
+		{Provider: "codex", Model: "gpt-y", UserID: &user.ID, RequestedAt: day.Add(3 * time.Hour), TotalTokens: 50, CostMicros: 1_000_000},
+		{Provider: "codex", Model: "gpt-y", UserID: &user.ID, RequestedAt: day.Add(-time.Hour), CostMicros: 2_000_000},
+	}
+	if errCreate := conn.Create(&usages).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	return user
+}
+
+func TestBuildAndRender(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t, "build.db")
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	seedUsage(t, conn, day)
+
+	report, errBuild := Build(ctx, conn, Options{Title: "Ops", Frequency: FrequencyDaily, Sections: AllSections}, day.Add(30*time.Hour))
+	if errBuild != nil {
+		t.Fatalf("build: %v", errBuild)
+	}
+	if report.Revenue == nil || report.Revenue.Current.CostMicros != 4_000_000 || report.Revenue.Previous.CostMicros != 2_000_000 {
+		t.Fatalf("unexpected revenue: %+v", report.Revenue)
+	}
+	if report.Revenue.Current.FailedRequests != 1 || report.Revenue.CostChange != 100 {
+		t.Fatalf("unexpected revenue detail: %+v", report.Revenue)
+	}
+	if len(report.TopModels) != 2 || report.TopModels[0].Model != "claude-x" || report.TopModels[0].Percentage != 75 {
+		t.Fatalf("unexpected top models: %+v", report.TopModels)
+	}
+	if len(report.TopUsers) != 1 || report.TopUsers[0].Username != "alice" || report.TopUsers[0].Requests != 3 {
+		t.Fatalf("unexpected top users: %+v", report.TopUsers)
+	}
+	if len(report.FailureRates) != 1 || report.FailureRates[0].Model != "claude-x" || report.FailureRates[0].FailureRate != 50 {
+		t.Fatalf("unexpected failure rates: %+v", report.FailureRates)
+	}
+
+	html, errHTML := RenderHTML(report)
+	if errHTML != nil || !strings.Contains(html, "<h2 style=\"font-size:16px;margin-top:24px;\">Top models</h2>") || !strings.Contains(html, "claude-x") {
+		t.Fatalf("unexpected html (%v): %s", errHTML, html)
+	}
+	csvContent, errCSV := RenderCSV(report)
+	if errCSV != nil || !strings.Contains(csvContent, "section,top_users\n") || !strings.Contains(csvContent, "alice@example.com") {
+		t.Fatalf("unexpected csv (%v): %s", errCSV, csvContent)
+	}
+}
+
+func TestSchedulerDeliversDueSchedulesOnce(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t, "schedule.db")
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	seedUsage(t, conn, day)
+
+	var (
+		hooksMu sync.Mutex
+		hooks   []webhookPayload
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		hooksMu.Lock()
+		hooks = append(hooks, payload)
+		hooksMu.Unlock()
+	}))
+	defer server.Close()
+
+	due := day.Add(24 * time.Hour)
+	schedules := []models.ReportSchedule{
+		{Name: "Daily mail", Frequency: FrequencyDaily, Sections: datatypes.JSON(`["revenue"]`), Format: FormatCSV, Channel: ChannelEmail,
+			Emails: datatypes.JSON(`["ops@example.com"]`), Enabled: true, NextRunAt: due},
+		{Name: "Daily hook", Frequency: FrequencyDaily, Sections: datatypes.JSON(`[]`), Format: FormatHTML, Channel: ChannelWebhook,
+			Emails: datatypes.JSON(`[]`), Webhook: server.URL, Enabled: true, NextRunAt: due},
+		{Name: "Later", Frequency: FrequencyWeekly, Sections: datatypes.JSON(`[]`), Format: FormatHTML, Channel: ChannelEmail,
+			Emails: datatypes.JSON(`["ops@example.com"]`), Enabled: true, NextRunAt: due.Add(72 * time.Hour)},
+	}
+	if errCreate := conn.Create(&schedules).Error; errCreate != nil {
+		t.Fatalf("create schedules: %v", errCreate)
+	}
+
+	box := &mailbox{}
+	scheduler := newTestScheduler(conn, box)
+	now := due.Add(time.Minute)
+	scheduler.Tick(ctx, now)
+	scheduler.Tick(ctx, now.Add(time.Minute))
+
+	if len(box.sent) != 1 {
+		t.Fatalf("expected 1 mail, got %d", len(box.sent))
+	}
+	msg := box.sent[0]
+	if msg.To != "ops@example.com" || len(msg.Attachments) != 1 || !strings.Contains(string(msg.Attachments[0].Data), "section,revenue") {
+		t.Fatalf("unexpected mail: %+v", msg)
+	}
+	if len(hooks) != 1 || hooks[0].Event != webhookEvent || hooks[0].Report == nil || !strings.Contains(hooks[0].Content, "<html>") {
+		t.Fatalf("unexpected webhooks: %+v", hooks)
+	}
+
+	var stored models.ReportSchedule
+	if errLoad := conn.First(&stored, schedules[0].ID).Error; errLoad != nil {
+		t.Fatalf("load schedule: %v", errLoad)
+	}
+	if !stored.NextRunAt.Equal(due.Add(24*time.Hour)) || stored.LastRunAt == nil || stored.LastError != "" {
+		t.Fatalf("unexpected schedule state: %+v", stored)
+	}
+}
+
+func TestUserSummariesRequireSettingAndSendOncePerPeriod(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t, "summary.db")
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	seedUsage(t, conn, day)
+
+	box := &mailbox{}
+	scheduler := newTestScheduler(conn, box)
+	now := day.Add(25 * time.Hour)
+
+	internalsettings.StoreDBConfig(time.Now(), nil)
+	scheduler.Tick(ctx, now)
+	if len(box.sent) != 0 {
+		t.Fatalf("expected no summaries while disabled, got %d", len(box.sent))
+	}
+
+	internalsettings.StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		internalsettings.ReportUserSummariesEnabledKey: json.RawMessage("true"),
+	})
+	defer internalsettings.StoreDBConfig(time.Now(), nil)
+	scheduler.Tick(ctx, now)
+	scheduler.Tick(ctx, now.Add(time.Hour))
+	if len(box.sent) != 1 {
+		t.Fatalf("expected 1 summary, got %d", len(box.sent))
+	}
+	if msg := box.sent[0]; msg.To != "alice@example.com" || !strings.Contains(msg.Text, "Requests: 3 (failed: 1)") {
+		t.Fatalf("unexpected summary: %+v", msg)
+	}
+
+	scheduler.Tick(ctx, now.Add(24*time.Hour))
+	if len(box.sent) != 1 {
+		t.Fatalf("expected no summary for a period without usage, got %d", len(box.sent))
+	}
+}
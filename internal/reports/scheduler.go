@@ -0,0 +1,324 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/mail"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/webhook"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// schedulerInterval is how often due schedules are checked.
+const schedulerInterval = time.Minute
+
+// webhookTimeout bounds one webhook delivery.
+const webhookTimeout = 30 * time.Second
+
+// webhookEvent names the webhook event carrying a report.
+const webhookEvent = "report.generated"
+
+// webhookPayload is the JSON body posted to report webhooks.
+type webhookPayload struct {
+	Event      string  `json:"event"`       // Always webhookEvent.
+	ScheduleID uint64  `json:"schedule_id"` // Delivered schedule; 0 for ad hoc runs.
+	Name       string  `json:"name"`        // Schedule name.
+	Format     string  `json:"format"`      // Format of Content.
+	Content    string  `json:"content"`     // Rendered report.
+	Report     *Report `json:"report"`      // Structured report data.
+}
+
+// Scheduler runs due report schedules and user usage summaries in the background.
+type Scheduler struct {
+	db       *gorm.DB
+	manager  *coreauth.Manager
+	location *time.Location
+	client   *http.Client
+	sendMail func(ctx context.Context, msg mail.Message) error
+}
+
+// NewScheduler constructs a Scheduler. The manager is optional and adds runtime credential
+// errors to the auth health section. Periods follow the server's local time, as the dashboard.
+func NewScheduler(db *gorm.DB, manager *coreauth.Manager) *Scheduler {
+	if db == nil {
+		return nil
+	}
+	return &Scheduler{
+		db:       db,
+		manager:  manager,
+		location: time.Local,
+		client:   &http.Client{Timeout: webhookTimeout},
+		sendMail: mail.SendMessage,
+	}
+}
+
+// Start launches the scheduling loop in a background goroutine.
+func (s *Scheduler) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go s.run(ctx)
+	log.Infof("report scheduler started (interval=%s)", schedulerInterval)
+}
+
+// run checks for due work until ctx is done.
+func (s *Scheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		s.Tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick delivers every schedule and user summary due at now.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	now = now.In(s.location)
+	var due []models.ReportSchedule
+	if errFind := s.db.WithContext(ctx).
+		Where("enabled = ? AND next_run_at <= ?", true, now.UTC()).
+		Order("next_run_at ASC, id ASC").
+		Find(&due).Error; errFind != nil {
+		log.WithError(errFind).Warn("reports: load due schedules failed")
+		return
+	}
+	for i := range due {
+		if !s.claim(ctx, &due[i], now) {
+			continue
+		}
+		if errRun := s.Run(ctx, &due[i], now); errRun != nil {
+			log.WithError(errRun).Warnf("reports: schedule %d delivery failed", due[i].ID)
+		}
+	}
+	if errSummaries := s.sendUserSummaries(ctx, now); errSummaries != nil {
+		log.WithError(errSummaries).Warn("reports: user usage summaries failed")
+	}
+}
+
+// claim advances a due schedule's next run so that only one instance delivers it.
+func (s *Scheduler) claim(ctx context.Context, schedule *models.ReportSchedule, now time.Time) bool {
+	next := NextRun(schedule.Frequency, now).UTC()
+	res := s.db.WithContext(ctx).Model(&models.ReportSchedule{}).
+		Where("id = ? AND next_run_at = ?", schedule.ID, schedule.NextRunAt).
+		Update("next_run_at", next)
+	if res.Error != nil {
+		log.WithError(res.Error).Warnf("reports: claim schedule %d failed", schedule.ID)
+		return false
+	}
+	schedule.NextRunAt = next
+	return res.RowsAffected == 1
+}
+
+// Run builds and delivers a schedule's report for the last complete period before now and
+// records the outcome on the schedule.
+func (s *Scheduler) Run(ctx context.Context, schedule *models.ReportSchedule, now time.Time) error {
+	now = now.In(s.location)
+	errDeliver := s.deliver(ctx, schedule, now)
+	lastError := ""
+	if errDeliver != nil {
+		lastError = errDeliver.Error()
+	}
+	ranAt := now.UTC()
+	if errUpdate := s.db.WithContext(ctx).Model(&models.ReportSchedule{}).
+		Where("id = ?", schedule.ID).
+		Updates(map[string]any{"last_run_at": ranAt, "last_error": lastError}).Error; errUpdate != nil {
+		return errors.Join(errDeliver, errUpdate)
+	}
+	schedule.LastRunAt, schedule.LastError = &ranAt, lastError
+	return errDeliver
+}
+
+// Preview builds a schedule's report for the last complete period before now without
+// delivering it.
+func (s *Scheduler) Preview(ctx context.Context, schedule *models.ReportSchedule, now time.Time) (*Report, error) {
+	var sections []string
+	if len(schedule.Sections) > 0 {
+		if errUnmarshal := json.Unmarshal(schedule.Sections, &sections); errUnmarshal != nil {
+			return nil, fmt.Errorf("reports: decode sections: %w", errUnmarshal)
+		}
+	}
+	normalized, errSections := NormalizeSections(sections)
+	if errSections != nil {
+		return nil, errSections
+	}
+	title := strings.TrimSpace(schedule.Name)
+	if title == "" {
+		title = internalsettings.SiteName() + " report"
+	}
+	return Build(ctx, s.db, Options{
+		Title:     title,
+		Frequency: schedule.Frequency,
+		Sections:  normalized,
+		Manager:   s.manager,
+	}, now.In(s.location))
+}
+
+// Render renders a report in the given format.
+func Render(report *Report, format string) (string, error) {
+	if format == FormatCSV {
+		return RenderCSV(report)
+	}
+	return RenderHTML(report)
+}
+
+// deliver builds, renders, and sends one schedule's report.
+func (s *Scheduler) deliver(ctx context.Context, schedule *models.ReportSchedule, now time.Time) error {
+	report, errBuild := s.Preview(ctx, schedule, now)
+	if errBuild != nil {
+		return errBuild
+	}
+	content, errRender := Render(report, schedule.Format)
+	if errRender != nil {
+		return errRender
+	}
+
+	switch schedule.Channel {
+	case ChannelWebhook:
+		return s.postWebhook(ctx, schedule.Webhook, webhookPayload{
+			Event:      webhookEvent,
+			ScheduleID: schedule.ID,
+			Name:       report.Title,
+			Format:     schedule.Format,
+			Content:    content,
+			Report:     report,
+		})
+	case ChannelEmail:
+		var recipients []string
+		if len(schedule.Emails) > 0 {
+			if errUnmarshal := json.Unmarshal(schedule.Emails, &recipients); errUnmarshal != nil {
+				return fmt.Errorf("reports: decode recipients: %w", errUnmarshal)
+			}
+		}
+		msg := mail.Message{
+			Subject: fmt.Sprintf("%s: %s to %s", report.Title, report.From.Format("2006-01-02"), report.To.Format("2006-01-02")),
+			Text:    RenderText(report),
+		}
+		if schedule.Format == FormatCSV {
+			msg.Attachments = []mail.Attachment{{
+				Filename:    fmt.Sprintf("report-%s-%s.csv", report.Frequency, report.From.Format("20060102")),
+				ContentType: "text/csv; charset=utf-8",
+				Data:        []byte(content),
+			}}
+		} else {
+			msg.HTML = content
+		}
+		var errs []error
+		for _, to := range recipients {
+			if to = strings.TrimSpace(to); to == "" {
+				continue
+			}
+			msg.To = to
+			if errSend := s.sendMail(ctx, msg); errSend != nil {
+				errs = append(errs, fmt.Errorf("reports: mail %s: %w", to, errSend))
+			}
+		}
+		return errors.Join(errs...)
+	default:
+		return fmt.Errorf("reports: unknown channel %q", schedule.Channel)
+	}
+}
+
+// postWebhook delivers the payload as JSON.
+func (s *Scheduler) postWebhook(ctx context.Context, targetURL string, payload webhookPayload) error {
+	if errPost := webhook.Post(ctx, s.client, targetURL, payload); errPost != nil {
+		return fmt.Errorf("reports: %w", errPost)
+	}
+	return nil
+}
+
+// sendUserSummaries emails opted-in users a summary of their usage in the last complete period
+// of their chosen frequency, once per period.
+func (s *Scheduler) sendUserSummaries(ctx context.Context, now time.Time) error {
+	if !internalsettings.UserUsageSummariesEnabled() {
+		return nil
+	}
+	var users []models.User
+	if errFind := s.db.WithContext(ctx).
+		Select("id", "username", "email", "usage_report_frequency", "usage_report_sent_at").
+		Where("usage_report_frequency <> '' AND email <> '' AND email_verification_pending = ? AND disabled = ?", false, false).
+		Find(&users).Error; errFind != nil {
+		return errFind
+	}
+	var errs []error
+	for _, user := range users {
+		if !ValidFrequency(user.UsageReportFrequency) {
+			continue
+		}
+		from, to := Period(user.UsageReportFrequency, now)
+		if user.UsageReportSentAt != nil && !user.UsageReportSentAt.Before(to) {
+			continue
+		}
+		if !s.claimUserSummary(ctx, user, now) {
+			continue
+		}
+		if errSend := s.sendUserSummary(ctx, user, from, to); errSend != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", user.ID, errSend))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// claimUserSummary records the summary as sent so that only one instance delivers it.
+func (s *Scheduler) claimUserSummary(ctx context.Context, user models.User, now time.Time) bool {
+	q := s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", user.ID)
+	if user.UsageReportSentAt == nil {
+		q = q.Where("usage_report_sent_at IS NULL")
+	} else {
+		q = q.Where("usage_report_sent_at = ?", *user.UsageReportSentAt)
+	}
+	res := q.UpdateColumn("usage_report_sent_at", now.UTC())
+	return res.Error == nil && res.RowsAffected == 1
+}
+
+// sendUserSummary emails one user's usage in [from, to); periods without usage are skipped.
+func (s *Scheduler) sendUserSummary(ctx context.Context, user models.User, from, to time.Time) error {
+	userID := user.ID
+	r := Range{From: from, To: to, UserID: &userID}
+	totals, errTotals := LoadTotals(ctx, s.db, r)
+	if errTotals != nil {
+		return errTotals
+	}
+	if totals.Requests == 0 {
+		return nil
+	}
+	topModels, errModels := TopModels(ctx, s.db, r, 5)
+	if errModels != nil {
+		return errModels
+	}
+
+	siteName := internalsettings.SiteName()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hello %s,\n\n", user.Username)
+	fmt.Fprintf(&b, "Your %s usage summary for %s to %s:\n\n", user.UsageReportFrequency, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Requests: %d (failed: %d)\n", totals.Requests, totals.FailedRequests)
+	fmt.Fprintf(&b, "Tokens: %d input, %d output, %d total\n", totals.InputTokens, totals.OutputTokens, totals.TotalTokens)
+	fmt.Fprintf(&b, "Billed: %s\n", formatMicros(totals.CostMicros))
+	if len(topModels) > 0 {
+		b.WriteString("\nTop models:\n")
+		for _, row := range topModels {
+			fmt.Fprintf(&b, "- %s: %d requests, %s\n", row.Model, row.Requests, formatMicros(row.CostMicros))
+		}
+	}
+	b.WriteString("\nYou can turn these emails off in your profile.\n")
+	return s.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("%s usage summary", siteName),
+		Text:    b.String(),
+	})
+}
@@ -0,0 +1,40 @@
+package requestlog
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Filter narrows usage queries by the per-request details. It binds from query parameters
+// and is embedded in the log endpoint queries.
+type Filter struct {
+	StatusCode    int    `form:"status_code"`     // Upstream HTTP status.
+	ErrorCode     string `form:"error_code"`      // Upstream error code.
+	Streaming     string `form:"streaming"`       // "true" or "false".
+	RequestID     string `form:"request_id"`      // Request ID.
+	MinDurationMs int64  `form:"min_duration_ms"` // Minimum total duration in milliseconds.
+}
+
+// Apply adds the set filters to a query over usages.
+func (f Filter) Apply(query *gorm.DB) *gorm.DB {
+	if f.StatusCode > 0 {
+		query = query.Where("usages.upstream_status = ?", f.StatusCode)
+	}
+	if code := strings.TrimSpace(f.ErrorCode); code != "" {
+		query = query.Where("usages.error_code = ?", code)
+	}
+	switch strings.ToLower(strings.TrimSpace(f.Streaming)) {
+	case "true", "1":
+		query = query.Where("usages.streaming = ?", true)
+	case "false", "0":
+		query = query.Where("usages.streaming = ?", false)
+	}
+	if id := strings.TrimSpace(f.RequestID); id != "" {
+		query = query.Where("usages.request_id = ?", id)
+	}
+	if f.MinDurationMs > 0 {
+		query = query.Where("usages.duration_ms >= ?", f.MinDurationMs)
+	}
+	return query
+}
@@ -0,0 +1,258 @@
+// Package requestlog collects per-request details of proxied requests — client, upstream
+// status and latency — and attaches them to the usage records the request produces.
+package requestlog
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// contextKey is the gin context key holding the request's Tracker.
+const contextKey = "requestLogTracker"
+
+// maxErrorMessageLength bounds stored upstream error messages.
+const maxErrorMessageLength = 1024
+
+// Upstream is the outcome reported for one upstream attempt.
+type Upstream struct {
+	StatusCode   int    // Upstream HTTP status; 0 when unknown.
+	ErrorCode    string // Upstream error code.
+	ErrorMessage string // Upstream error message.
+}
+
+// pendingRow is a usage row waiting for the request to complete.
+type pendingRow struct {
+	id      uint64
+	authKey string
+}
+
+// Tracker collects the details of one proxied request. It is safe for concurrent use by the
+// request goroutine, the auth result hook and the usage plugin.
+type Tracker struct {
+	mu sync.Mutex
+
+	requestID string
+	clientIP  string
+	userAgent string
+	startedAt time.Time
+
+	streaming   bool
+	firstByteAt time.Time
+	finishedAt  time.Time
+
+	upstream map[string]Upstream // Latest outcome per auth ID.
+	last     Upstream            // Latest outcome of any auth.
+
+	db      *gorm.DB
+	pending []pendingRow
+}
+
+// Begin starts tracking the request served by c and stores the tracker on it.
+func Begin(c *gin.Context, db *gorm.DB, requestID string, now time.Time) *Tracker {
+	t := &Tracker{
+		requestID: requestID,
+		clientIP:  c.ClientIP(),
+		userAgent: c.Request.UserAgent(),
+		startedAt: now,
+		upstream:  make(map[string]Upstream),
+		db:        db,
+	}
+	c.Set(contextKey, t)
+	return t
+}
+
+// FromGin returns the tracker stored on c, or nil.
+func FromGin(c *gin.Context) *Tracker {
+	if c == nil {
+		return nil
+	}
+	v, exists := c.Get(contextKey)
+	if !exists {
+		return nil
+	}
+	t, _ := v.(*Tracker)
+	return t
+}
+
+// FromContext returns the tracker of the gin request carried by ctx, or nil.
+func FromContext(ctx context.Context) *Tracker {
+	if ctx == nil {
+		return nil
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok {
+		return nil
+	}
+	return FromGin(ginCtx)
+}
+
+// RequestID returns the request ID.
+func (t *Tracker) RequestID() string {
+	if t == nil {
+		return ""
+	}
+	return t.requestID
+}
+
+// MarkFirstByte records when the first response byte was written and whether the response
+// is an event stream.
+func (t *Tracker) MarkFirstByte(now time.Time, streaming bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.firstByteAt.IsZero() {
+		t.firstByteAt = now
+		t.streaming = t.streaming || streaming
+	}
+}
+
+// RecordResult records the outcome of an upstream attempt.
+func (t *Tracker) RecordResult(result coreauth.Result) {
+	if t == nil {
+		return
+	}
+	outcome := Upstream{}
+	if result.Error != nil {
+		outcome.StatusCode = result.Error.HTTPStatus
+		outcome.ErrorCode = strings.TrimSpace(result.Error.Code)
+		outcome.ErrorMessage = truncate(strings.TrimSpace(result.Error.Message), maxErrorMessageLength)
+	} else if result.Success {
+		outcome.StatusCode = 200
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.upstream[strings.TrimSpace(result.AuthID)] = outcome
+	t.last = outcome
+}
+
+// Apply fills the request details known so far into a usage row produced by the request.
+// It reports false, leaving row untouched, when the row predates the request, which happens
+// when a gin context was reused before an asynchronous usage record was handled.
+func (t *Tracker) Apply(row *models.Usage) bool {
+	if t == nil || row == nil || row.RequestedAt.Before(t.startedAt) {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	row.RequestID = t.requestID
+	row.ClientIP = t.clientIP
+	row.UserAgent = t.userAgent
+	t.applyOutcomeLocked(row)
+	return true
+}
+
+// Attach arranges for the completion details of the request to be stored on the inserted
+// usage row: when the request finishes, or immediately if it finished after Apply ran.
+func (t *Tracker) Attach(ctx context.Context, row *models.Usage) {
+	if t == nil || row == nil || row.ID == 0 {
+		return
+	}
+	t.mu.Lock()
+	if t.finishedAt.IsZero() {
+		t.pending = append(t.pending, pendingRow{id: row.ID, authKey: row.AuthKey})
+		t.mu.Unlock()
+		return
+	}
+	if row.DurationMs > 0 {
+		t.mu.Unlock()
+		return
+	}
+	update := models.Usage{ID: row.ID, AuthKey: row.AuthKey}
+	t.applyOutcomeLocked(&update)
+	db := t.db
+	t.mu.Unlock()
+	saveCompletion(ctx, db, &update)
+}
+
+// Finish records the end of the request. The completion details of the usage rows attached
+// so far are stored in the background so the response is not held up.
+func (t *Tracker) Finish(now time.Time, streaming bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.finishedAt = now
+	t.streaming = t.streaming || streaming
+	updates := make([]models.Usage, 0, len(t.pending))
+	for _, pending := range t.pending {
+		update := models.Usage{ID: pending.id, AuthKey: pending.authKey}
+		t.applyOutcomeLocked(&update)
+		updates = append(updates, update)
+	}
+	t.pending = nil
+	db := t.db
+	t.mu.Unlock()
+	if len(updates) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for i := range updates {
+			saveCompletion(ctx, db, &updates[i])
+		}
+	}()
+}
+
+// applyOutcomeLocked fills the upstream outcome and the timing known so far into row.
+func (t *Tracker) applyOutcomeLocked(row *models.Usage) {
+	outcome, ok := t.upstream[strings.TrimSpace(row.AuthKey)]
+	if !ok {
+		outcome = t.last
+	}
+	if outcome.StatusCode != 0 || outcome.ErrorCode != "" || outcome.ErrorMessage != "" {
+		row.UpstreamStatus = outcome.StatusCode
+		row.ErrorCode = outcome.ErrorCode
+		row.ErrorMessage = outcome.ErrorMessage
+	}
+	row.Streaming = t.streaming
+	if !t.firstByteAt.IsZero() {
+		row.TimeToFirstTokenMs = t.firstByteAt.Sub(t.startedAt).Milliseconds()
+	}
+	if !t.finishedAt.IsZero() {
+		row.DurationMs = t.finishedAt.Sub(t.startedAt).Milliseconds()
+		if t.firstByteAt.IsZero() || !t.streaming {
+			row.TimeToFirstTokenMs = row.DurationMs
+		}
+	}
+}
+
+// saveCompletion stores the outcome and timing columns of row.
+func saveCompletion(ctx context.Context, db *gorm.DB, row *models.Usage) {
+	if db == nil {
+		return
+	}
+	errUpdate := db.WithContext(ctx).Model(&models.Usage{}).Where("id = ?", row.ID).Updates(map[string]any{
+		"upstream_status":        row.UpstreamStatus,
+		"error_code":             row.ErrorCode,
+		"error_message":          row.ErrorMessage,
+		"time_to_first_token_ms": row.TimeToFirstTokenMs,
+		"duration_ms":            row.DurationMs,
+		"streaming":              row.Streaming,
+	}).Error
+	if errUpdate != nil {
+		log.WithError(errUpdate).WithField("usage_id", row.ID).Warn("request log: update usage failed")
+	}
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
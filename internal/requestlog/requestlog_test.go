@@ -0,0 +1,144 @@
+package requestlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	conn, errOpen := db.Open("file:" + filepath.Join(t.TempDir(), name))
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	return conn
+}
+
+func newTracker(t *testing.T, conn *gorm.DB, started time.Time) (*gin.Context, *Tracker) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Request.Header.Set("User-Agent", "client/1.0")
+	c.Request.RemoteAddr = "203.0.113.9:4242"
+	return c, Begin(c, conn, "req-1", started)
+}
+
+func insertUsage(t *testing.T, conn *gorm.DB, tracker *Tracker, authKey string, requestedAt time.Time) models.Usage {
+	t.Helper()
+	row := models.Usage{Provider: "claude", Model: "claude-x", AuthKey: authKey, RequestedAt: requestedAt}
+	if !tracker.Apply(&row) {
+		t.Fatalf("expected tracker to apply to row")
+	}
+	if errCreate := conn.Create(&row).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	return row
+}
+
+func waitForUsage(t *testing.T, conn *gorm.DB, id uint64, done func(models.Usage) bool) models.Usage {
+	t.Helper()
+	var row models.Usage
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if errLoad := conn.First(&row, id).Error; errLoad != nil {
+			t.Fatalf("load usage: %v", errLoad)
+		}
+		if done(row) {
+			return row
+		}
+	}
+	t.Fatalf("usage %d not updated: %+v", id, row)
+	return row
+}
+
+func TestTrackerFillsRowsInsertedBeforeCompletion(t *testing.T) {
+	conn := openTestDB(t, "pending.db")
+	started := time.Now()
+	c, tracker := newTracker(t, conn, started)
+	if FromGin(c) != tracker {
+		t.Fatalf("expected tracker on gin context")
+	}
+
+	tracker.RecordResult(coreauth.Result{AuthID: "auth-a", Success: false, Error: &coreauth.Error{Code: "rate_limited", Message: "slow down", HTTPStatus: 429}})
+	tracker.RecordResult(coreauth.Result{AuthID: "auth-b", Success: true})
+	failed := insertUsage(t, conn, tracker, "auth-a", started.Add(time.Millisecond))
+	tracker.Attach(t.Context(), &failed)
+
+	tracker.MarkFirstByte(started.Add(120*time.Millisecond), true)
+	succeeded := insertUsage(t, conn, tracker, "auth-b", started.Add(2*time.Millisecond))
+	tracker.Attach(t.Context(), &succeeded)
+	tracker.Finish(started.Add(900*time.Millisecond), false)
+
+	row := waitForUsage(t, conn, failed.ID, func(u models.Usage) bool { return u.DurationMs > 0 })
+	if row.UpstreamStatus != 429 || row.ErrorCode != "rate_limited" || row.ErrorMessage != "slow down" {
+		t.Fatalf("unexpected failed outcome: %+v", row)
+	}
+	if row.RequestID != "req-1" || row.ClientIP != "203.0.113.9" || row.UserAgent != "client/1.0" {
+		t.Fatalf("unexpected client details: %+v", row)
+	}
+	row = waitForUsage(t, conn, succeeded.ID, func(u models.Usage) bool { return u.DurationMs > 0 })
+	if row.UpstreamStatus != 200 || row.ErrorCode != "" || !row.Streaming || row.TimeToFirstTokenMs != 120 || row.DurationMs != 900 {
+		t.Fatalf("unexpected completed row: %+v", row)
+	}
+}
+
+func TestTrackerAppliesCompletionToLateRows(t *testing.T) {
+	conn := openTestDB(t, "late.db")
+	started := time.Now()
+	_, tracker := newTracker(t, conn, started)
+	tracker.MarkFirstByte(started.Add(300*time.Millisecond), false)
+	tracker.Finish(started.Add(400*time.Millisecond), false)
+
+	row := insertUsage(t, conn, tracker, "auth-a", started.Add(time.Millisecond))
+	if row.DurationMs != 400 || row.TimeToFirstTokenMs != 400 || row.Streaming {
+		t.Fatalf("expected completion details on insert, got %+v", row)
+	}
+
+	stale := models.Usage{RequestedAt: started.Add(-time.Second)}
+	if tracker.Apply(&stale) || stale.RequestID != "" {
+		t.Fatalf("expected rows predating the request to be ignored: %+v", stale)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	conn := openTestDB(t, "filter.db")
+	rows := []models.Usage{
+		{Provider: "claude", Model: "a", RequestedAt: time.Now(), UpstreamStatus: 429, ErrorCode: "rate_limited", DurationMs: 50},
+		{Provider: "claude", Model: "b", RequestedAt: time.Now(), UpstreamStatus: 200, Streaming: true, RequestID: "abc", DurationMs: 5000},
+	}
+	if errCreate := conn.Create(&rows).Error; errCreate != nil {
+		t.Fatalf("create usage: %v", errCreate)
+	}
+	cases := []struct {
+		filter Filter
+		model  string
+	}{
+		{Filter{StatusCode: 429}, "a"},
+		{Filter{ErrorCode: "rate_limited"}, "a"},
+		{Filter{Streaming: "true"}, "b"},
+		{Filter{Streaming: "false"}, "a"},
+		{Filter{RequestID: "abc"}, "b"},
+		{Filter{MinDurationMs: 1000}, "b"},
+	}
+	for _, tc := range cases {
+		var found []models.Usage
+		if errFind := tc.filter.Apply(conn.Model(&models.Usage{})).Find(&found).Error; errFind != nil {
+			t.Fatalf("filter %+v: %v", tc.filter, errFind)
+		}
+		if len(found) != 1 || found[0].Model != tc.model {
+			t.Fatalf("filter %+v: expected model %s, got %+v", tc.filter, tc.model, found)
+		}
+	}
+}
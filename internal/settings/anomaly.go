@@ -0,0 +1,75 @@
+package settings
+
+import (
+	"strings"
+	"time"
+)
+
+// AnomalyPolicy controls spend anomaly detection, key suspension and alert delivery.
+type AnomalyPolicy struct {
+	Enabled              bool          // Whether detection runs.
+	Baseline             time.Duration // History compared against the last hour.
+	SpendMultiplier      float64       // Spend spike factor over the baseline hourly average; 0 disables.
+	RequestMultiplier    float64       // Request spike factor over the baseline hourly average; 0 disables.
+	MinHourlySpendMicros int64         // Hourly spend below which spend spikes are ignored.
+	MinHourlyRequests    int64         // Hourly request count below which request spikes are ignored.
+	DetectNewIPRanges    bool          // Flag keys used from client IP ranges absent from their baseline.
+	DetectNewModels      bool          // Flag keys calling models absent from their baseline.
+	AutoSuspend          bool          // Deactivate keys flagged for a spend or request spike.
+	NotifyOwners         bool          // Email the owning user of flagged keys and accounts.
+	WebhookURL           string        // Admin alert webhook URL; empty disables webhook delivery.
+	AlertEmails          []string      // Admin alert recipients; empty disables admin email delivery.
+}
+
+// LoadAnomalyPolicy reads the anomaly detection policy from the DB config snapshot.
+func LoadAnomalyPolicy() AnomalyPolicy {
+	policy := AnomalyPolicy{
+		Enabled:              DefaultAnomalyDetectionEnabled,
+		Baseline:             DefaultAnomalyBaselineDays * 24 * time.Hour,
+		SpendMultiplier:      DefaultAnomalySpendMultiplier,
+		RequestMultiplier:    DefaultAnomalyRequestMultiplier,
+		MinHourlySpendMicros: int64(DefaultAnomalyMinHourlySpend * 1_000_000),
+		MinHourlyRequests:    DefaultAnomalyMinHourlyRequests,
+		DetectNewIPRanges:    DefaultAnomalyDetectNewIPRanges,
+		DetectNewModels:      DefaultAnomalyDetectNewModels,
+		AutoSuspend:          DefaultAnomalyAutoSuspend,
+		NotifyOwners:         DefaultAnomalyNotifyOwners,
+	}
+	if v, ok := configBool(AnomalyDetectionEnabledKey); ok {
+		policy.Enabled = v
+	}
+	if v, ok := configFloat(AnomalyBaselineDaysKey); ok && v > 0 {
+		policy.Baseline = time.Duration(v) * 24 * time.Hour
+	}
+	if v, ok := configFloat(AnomalySpendMultiplierKey); ok && v >= 0 {
+		policy.SpendMultiplier = v
+	}
+	if v, ok := configFloat(AnomalyRequestMultiplierKey); ok && v >= 0 {
+		policy.RequestMultiplier = v
+	}
+	if v, ok := configFloat(AnomalyMinHourlySpendKey); ok && v >= 0 {
+		policy.MinHourlySpendMicros = int64(v * 1_000_000)
+	}
+	if v, ok := configFloat(AnomalyMinHourlyRequestsKey); ok && v >= 0 {
+		policy.MinHourlyRequests = int64(v)
+	}
+	if v, ok := configBool(AnomalyDetectNewIPRangesKey); ok {
+		policy.DetectNewIPRanges = v
+	}
+	if v, ok := configBool(AnomalyDetectNewModelsKey); ok {
+		policy.DetectNewModels = v
+	}
+	if v, ok := configBool(AnomalyAutoSuspendKey); ok {
+		policy.AutoSuspend = v
+	}
+	if v, ok := configBool(AnomalyNotifyOwnersKey); ok {
+		policy.NotifyOwners = v
+	}
+	policy.WebhookURL = configString(AnomalyAlertWebhookURLKey)
+	for _, email := range configStrings(AnomalyAlertEmailsKey) {
+		if email = strings.TrimSpace(email); email != "" {
+			policy.AlertEmails = append(policy.AlertEmails, email)
+		}
+	}
+	return policy
+}
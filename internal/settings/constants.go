@@ -10,6 +10,44 @@ const (
 	QuotaPollIntervalSecondsKey = "QUOTA_POLL_INTERVAL_SECONDS"
 	// QuotaPollMaxConcurrencyKey controls the max concurrent quota requests.
 	QuotaPollMaxConcurrencyKey = "QUOTA_POLL_MAX_CONCURRENCY"
+	// QuotaSchedulingModeKey selects how the selector treats auths low on quota (off, deprioritize, skip).
+	QuotaSchedulingModeKey = "QUOTA_SCHEDULING_MODE"
+	// QuotaSchedulingThresholdKey defines the remaining quota fraction below which an auth counts as low.
+	QuotaSchedulingThresholdKey = "QUOTA_SCHEDULING_THRESHOLD"
+	// QuotaHistoryRetentionDaysKey controls how long quota snapshots are kept (0 disables history).
+	QuotaHistoryRetentionDaysKey = "QUOTA_HISTORY_RETENTION_DAYS"
+	// QuotaAlertHorizonMinutesKey alerts when forecast exhaustion falls within this many minutes (0 disables alerts).
+	QuotaAlertHorizonMinutesKey = "QUOTA_ALERT_HORIZON_MINUTES"
+	// QuotaAlertWebhookURLKey defines the URL that receives quota exhaustion alerts.
+	QuotaAlertWebhookURLKey = "QUOTA_ALERT_WEBHOOK_URL"
+	// QuotaAlertEmailsKey lists the email addresses that receive quota exhaustion alerts.
+	QuotaAlertEmailsKey = "QUOTA_ALERT_EMAILS"
+	// ReportUserSummariesEnabledKey allows users to receive periodic usage summary emails.
+	ReportUserSummariesEnabledKey = "REPORT_USER_SUMMARIES_ENABLED"
+	// AnomalyDetectionEnabledKey toggles spend anomaly detection for API keys and users.
+	AnomalyDetectionEnabledKey = "ANOMALY_DETECTION_ENABLED"
+	// AnomalyBaselineDaysKey defines how many days of history form the spend baseline.
+	AnomalyBaselineDaysKey = "ANOMALY_BASELINE_DAYS"
+	// AnomalySpendMultiplierKey flags an hour whose spend exceeds the baseline hourly average by this factor (0 disables).
+	AnomalySpendMultiplierKey = "ANOMALY_SPEND_MULTIPLIER"
+	// AnomalyRequestMultiplierKey flags an hour whose request count exceeds the baseline hourly average by this factor (0 disables).
+	AnomalyRequestMultiplierKey = "ANOMALY_REQUEST_MULTIPLIER"
+	// AnomalyMinHourlySpendKey defines the hourly spend below which spend spikes are ignored.
+	AnomalyMinHourlySpendKey = "ANOMALY_MIN_HOURLY_SPEND"
+	// AnomalyMinHourlyRequestsKey defines the hourly request count below which request spikes are ignored.
+	AnomalyMinHourlyRequestsKey = "ANOMALY_MIN_HOURLY_REQUESTS"
+	// AnomalyDetectNewIPRangesKey flags API keys used from client IP ranges absent from their baseline.
+	AnomalyDetectNewIPRangesKey = "ANOMALY_DETECT_NEW_IP_RANGES"
+	// AnomalyDetectNewModelsKey flags API keys calling models absent from their baseline.
+	AnomalyDetectNewModelsKey = "ANOMALY_DETECT_NEW_MODELS"
+	// AnomalyAutoSuspendKey deactivates API keys flagged for a spend or request spike.
+	AnomalyAutoSuspendKey = "ANOMALY_AUTO_SUSPEND"
+	// AnomalyNotifyOwnersKey emails the owning user when one of their keys or their account is flagged.
+	AnomalyNotifyOwnersKey = "ANOMALY_NOTIFY_OWNERS"
+	// AnomalyAlertWebhookURLKey defines the URL that receives anomaly alerts.
+	AnomalyAlertWebhookURLKey = "ANOMALY_ALERT_WEBHOOK_URL"
+	// AnomalyAlertEmailsKey lists the admin email addresses that receive anomaly alerts.
+	AnomalyAlertEmailsKey = "ANOMALY_ALERT_EMAILS"
 	// AutoAssignProxyKey toggles auto assignment of proxies on create.
 	AutoAssignProxyKey = "AUTO_ASSIGN_PROXY"
 	// RateLimitKey controls the default rate limit per second.
@@ -24,14 +62,90 @@ const (
 	RateLimitRedisDBKey = "RATE_LIMIT_REDIS_DB"
 	// RateLimitRedisPrefixKey defines the Redis key prefix for rate limiting.
 	RateLimitRedisPrefixKey = "RATE_LIMIT_REDIS_PREFIX"
+	// RegistrationModeKey selects how new users may sign up (open, invite_only, closed).
+	RegistrationModeKey = "REGISTRATION_MODE"
+	// RegistrationRequireEmailVerificationKey requires a verified email before API keys can be created.
+	RegistrationRequireEmailVerificationKey = "REGISTRATION_REQUIRE_EMAIL_VERIFICATION"
+	// RegistrationAllowedEmailDomainsKey lists the email domains allowed to sign up.
+	RegistrationAllowedEmailDomainsKey = "REGISTRATION_ALLOWED_EMAIL_DOMAINS"
+	// RegistrationBlockedEmailDomainsKey lists the email domains rejected at sign up.
+	RegistrationBlockedEmailDomainsKey = "REGISTRATION_BLOCKED_EMAIL_DOMAINS"
+	// RegistrationIPLimitPerHourKey caps sign ups per client IP per hour.
+	RegistrationIPLimitPerHourKey = "REGISTRATION_IP_LIMIT_PER_HOUR"
+	// RegistrationBonusAmountKey defines the starter prepaid credit granted at sign up, or on email verification when it is required.
+	RegistrationBonusAmountKey = "REGISTRATION_BONUS_AMOUNT"
+	// RegistrationBonusValidDaysKey defines how long the sign-up credit stays valid.
+	RegistrationBonusValidDaysKey = "REGISTRATION_BONUS_VALID_DAYS"
+	// AdminMFARequiredKey requires every administrator to enroll in MFA.
+	AdminMFARequiredKey = "ADMIN_MFA_REQUIRED"
+	// PublicBaseURLKey defines the externally visible base URL used for SSO callbacks.
+	PublicBaseURLKey = "PUBLIC_BASE_URL"
+	// SMTPHostKey defines the SMTP server host for outgoing mail.
+	SMTPHostKey = "SMTP_HOST"
+	// SMTPPortKey defines the SMTP server port.
+	SMTPPortKey = "SMTP_PORT"
+	// SMTPUsernameKey defines the SMTP auth username.
+	SMTPUsernameKey = "SMTP_USERNAME"
+	// SMTPPasswordKey defines the SMTP auth password.
+	SMTPPasswordKey = "SMTP_PASSWORD"
+	// SMTPFromKey defines the sender address for outgoing mail.
+	SMTPFromKey = "SMTP_FROM"
 	// DefaultQuotaPollIntervalSeconds is the fallback poll interval (seconds).
 	DefaultQuotaPollIntervalSeconds = 180
 	// DefaultQuotaPollMaxConcurrency is the fallback max concurrency.
 	DefaultQuotaPollMaxConcurrency = 5
+	// QuotaSchedulingOff ignores polled quota when selecting auths.
+	QuotaSchedulingOff = "off"
+	// QuotaSchedulingDeprioritize uses auths low on quota only when no other auth is available.
+	QuotaSchedulingDeprioritize = "deprioritize"
+	// QuotaSchedulingSkip treats auths low on quota as cooling until their quota resets.
+	QuotaSchedulingSkip = "skip"
+	// DefaultQuotaSchedulingMode is the fallback quota scheduling mode.
+	DefaultQuotaSchedulingMode = QuotaSchedulingDeprioritize
+	// DefaultQuotaSchedulingThreshold is the fallback low quota threshold.
+	DefaultQuotaSchedulingThreshold = 0.05
+	// DefaultQuotaHistoryRetentionDays is the fallback quota history retention.
+	DefaultQuotaHistoryRetentionDays = 7
+	// DefaultQuotaAlertHorizonMinutes is the fallback quota alert horizon.
+	DefaultQuotaAlertHorizonMinutes = 60
+	// DefaultReportUserSummariesEnabled is the fallback for user usage summary emails.
+	DefaultReportUserSummariesEnabled = false
+	// DefaultAnomalyDetectionEnabled is the fallback for spend anomaly detection.
+	DefaultAnomalyDetectionEnabled = false
+	// DefaultAnomalyBaselineDays is the fallback baseline length in days.
+	DefaultAnomalyBaselineDays = 7
+	// DefaultAnomalySpendMultiplier is the fallback spend spike factor.
+	DefaultAnomalySpendMultiplier = 10
+	// DefaultAnomalyRequestMultiplier is the fallback request spike factor.
+	DefaultAnomalyRequestMultiplier = 10
+	// DefaultAnomalyMinHourlySpend is the fallback minimum hourly spend for spend spikes.
+	DefaultAnomalyMinHourlySpend = 1.0
+	// DefaultAnomalyMinHourlyRequests is the fallback minimum hourly request count for request spikes.
+	DefaultAnomalyMinHourlyRequests = 100
+	// DefaultAnomalyDetectNewIPRanges is the fallback for new IP range detection.
+	DefaultAnomalyDetectNewIPRanges = true
+	// DefaultAnomalyDetectNewModels is the fallback for new model detection.
+	DefaultAnomalyDetectNewModels = false
+	// DefaultAnomalyAutoSuspend is the fallback for suspending flagged API keys.
+	DefaultAnomalyAutoSuspend = false
+	// DefaultAnomalyNotifyOwners is the fallback for notifying owners of flagged keys.
+	DefaultAnomalyNotifyOwners = true
 	// DefaultAutoAssignProxy sets auto-assign proxy default.
 	DefaultAutoAssignProxy = false
 	// DefaultRateLimit is the fallback rate limit (0 means unlimited).
 	DefaultRateLimit = 0
 	// DefaultRateLimitRedisPrefix is the fallback Redis key prefix.
 	DefaultRateLimitRedisPrefix = "cpab:rl"
+	// RegistrationModeOpen lets anyone sign up.
+	RegistrationModeOpen = "open"
+	// RegistrationModeInviteOnly requires a valid invite code to sign up.
+	RegistrationModeInviteOnly = "invite_only"
+	// RegistrationModeClosed disables self sign up.
+	RegistrationModeClosed = "closed"
+	// DefaultRegistrationMode is the fallback registration mode.
+	DefaultRegistrationMode = RegistrationModeOpen
+	// DefaultRegistrationIPLimitPerHour is the fallback per-IP sign-up cap (0 means unlimited).
+	DefaultRegistrationIPLimitPerHour = 10
+	// DefaultSMTPPort is the fallback SMTP submission port.
+	DefaultSMTPPort = 587
 )
@@ -0,0 +1,7 @@
+package settings
+
+// AdminMFARequired reports whether administrators must enroll in MFA.
+func AdminMFARequired() bool {
+	required, _ := configBool(AdminMFARequiredKey)
+	return required
+}
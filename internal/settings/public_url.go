@@ -0,0 +1,8 @@
+package settings
+
+import "strings"
+
+// PublicBaseURL returns the configured external base URL without a trailing slash.
+func PublicBaseURL() string {
+	return strings.TrimRight(configString(PublicBaseURLKey), "/")
+}
@@ -0,0 +1,40 @@
+package settings
+
+import (
+	"strings"
+	"time"
+)
+
+// QuotaHistoryPolicy controls quota history retention and exhaustion alerts.
+type QuotaHistoryPolicy struct {
+	Retention    time.Duration // How long snapshots are kept; 0 disables history.
+	AlertHorizon time.Duration // Alert when exhaustion is forecast within this duration; 0 disables alerts.
+	WebhookURL   string        // Alert webhook URL; empty disables webhook delivery.
+	AlertEmails  []string      // Alert recipients; empty disables email delivery.
+}
+
+// LoadQuotaHistoryPolicy reads the quota history policy from the DB config snapshot.
+func LoadQuotaHistoryPolicy() QuotaHistoryPolicy {
+	policy := QuotaHistoryPolicy{
+		Retention:    DefaultQuotaHistoryRetentionDays * 24 * time.Hour,
+		AlertHorizon: DefaultQuotaAlertHorizonMinutes * time.Minute,
+	}
+	if v, ok := configFloat(QuotaHistoryRetentionDaysKey); ok && v >= 0 {
+		policy.Retention = time.Duration(v) * 24 * time.Hour
+	}
+	if v, ok := configFloat(QuotaAlertHorizonMinutesKey); ok && v >= 0 {
+		policy.AlertHorizon = time.Duration(v) * time.Minute
+	}
+	policy.WebhookURL = configString(QuotaAlertWebhookURLKey)
+	for _, email := range configStrings(QuotaAlertEmailsKey) {
+		if email = strings.TrimSpace(email); email != "" {
+			policy.AlertEmails = append(policy.AlertEmails, email)
+		}
+	}
+	return policy
+}
+
+// AlertsEnabled reports whether forecasts should be checked for alerts.
+func (p QuotaHistoryPolicy) AlertsEnabled() bool {
+	return p.AlertHorizon > 0 && (p.WebhookURL != "" || len(p.AlertEmails) > 0)
+}
@@ -0,0 +1,34 @@
+package settings
+
+import "strings"
+
+// QuotaSchedulingPolicy controls how polled quota influences auth selection.
+type QuotaSchedulingPolicy struct {
+	Mode      string  // One of QuotaSchedulingOff, QuotaSchedulingDeprioritize, QuotaSchedulingSkip.
+	Threshold float64 // Remaining fraction in [0, 1] below which an auth counts as low on quota.
+}
+
+// LoadQuotaSchedulingPolicy reads the quota scheduling policy from the DB config snapshot.
+func LoadQuotaSchedulingPolicy() QuotaSchedulingPolicy {
+	policy := QuotaSchedulingPolicy{
+		Mode:      DefaultQuotaSchedulingMode,
+		Threshold: DefaultQuotaSchedulingThreshold,
+	}
+	if mode := strings.ToLower(configString(QuotaSchedulingModeKey)); ValidQuotaSchedulingMode(mode) {
+		policy.Mode = mode
+	}
+	if v, ok := configFloat(QuotaSchedulingThresholdKey); ok && v >= 0 && v <= 1 {
+		policy.Threshold = v
+	}
+	return policy
+}
+
+// ValidQuotaSchedulingMode reports whether mode is a supported quota scheduling mode.
+func ValidQuotaSchedulingMode(mode string) bool {
+	switch mode {
+	case QuotaSchedulingOff, QuotaSchedulingDeprioritize, QuotaSchedulingSkip:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,222 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// RegistrationPolicy holds the admin-configured rules for self sign up.
+type RegistrationPolicy struct {
+	Mode                     string   // One of RegistrationModeOpen, RegistrationModeInviteOnly, RegistrationModeClosed.
+	RequireEmailVerification bool     // Whether API keys require a verified email.
+	AllowedEmailDomains      []string // Allowed email domains; empty allows all.
+	BlockedEmailDomains      []string // Rejected email domains.
+	IPLimitPerHour           int      // Max sign ups per client IP per hour; 0 means unlimited.
+	BonusAmount              float64  // Starter prepaid credit, granted after email verification when required.
+	BonusValidDays           int      // Validity of the starter credit in days; 0 means no expiry.
+}
+
+// LoadRegistrationPolicy reads the registration policy from the DB config snapshot.
+func LoadRegistrationPolicy() RegistrationPolicy {
+	policy := RegistrationPolicy{
+		Mode:           DefaultRegistrationMode,
+		IPLimitPerHour: DefaultRegistrationIPLimitPerHour,
+	}
+	if mode := strings.ToLower(configString(RegistrationModeKey)); ValidRegistrationMode(mode) {
+		policy.Mode = mode
+	}
+	if v, ok := configBool(RegistrationRequireEmailVerificationKey); ok {
+		policy.RequireEmailVerification = v
+	}
+	policy.AllowedEmailDomains = normalizeDomains(configStrings(RegistrationAllowedEmailDomainsKey))
+	policy.BlockedEmailDomains = normalizeDomains(configStrings(RegistrationBlockedEmailDomainsKey))
+	if v, ok := configFloat(RegistrationIPLimitPerHourKey); ok && v >= 0 {
+		policy.IPLimitPerHour = int(v)
+	}
+	if v, ok := configFloat(RegistrationBonusAmountKey); ok && v > 0 {
+		policy.BonusAmount = v
+	}
+	if v, ok := configFloat(RegistrationBonusValidDaysKey); ok && v > 0 {
+		policy.BonusValidDays = int(v)
+	}
+	return policy
+}
+
+// ValidRegistrationMode reports whether mode is a supported registration mode.
+func ValidRegistrationMode(mode string) bool {
+	switch mode {
+	case RegistrationModeOpen, RegistrationModeInviteOnly, RegistrationModeClosed:
+		return true
+	default:
+		return false
+	}
+}
+
+// EmailDomainAllowed reports whether the email's domain passes the allow and block lists.
+func (p RegistrationPolicy) EmailDomainAllowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return len(p.AllowedEmailDomains) == 0
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for _, blocked := range p.BlockedEmailDomains {
+		if domainMatches(domain, blocked) {
+			return false
+		}
+	}
+	if len(p.AllowedEmailDomains) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedEmailDomains {
+		if domainMatches(domain, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresEmail reports whether sign up must provide an email address.
+func (p RegistrationPolicy) RequiresEmail() bool {
+	return p.RequireEmailVerification || len(p.AllowedEmailDomains) > 0
+}
+
+// SMTPConfig holds outgoing mail server settings.
+type SMTPConfig struct {
+	Host     string // SMTP server host.
+	Port     int    // SMTP server port.
+	Username string // Auth username; empty disables auth.
+	Password string // Auth password.
+	From     string // Sender address.
+}
+
+// Configured reports whether enough settings exist to send mail.
+func (c SMTPConfig) Configured() bool {
+	return c.Host != "" && c.From != ""
+}
+
+// LoadSMTPConfig reads SMTP settings from the DB config snapshot.
+func LoadSMTPConfig() SMTPConfig {
+	cfg := SMTPConfig{
+		Host:     configString(SMTPHostKey),
+		Port:     DefaultSMTPPort,
+		Username: configString(SMTPUsernameKey),
+		Password: configString(SMTPPasswordKey),
+		From:     configString(SMTPFromKey),
+	}
+	if v, ok := configFloat(SMTPPortKey); ok && v > 0 {
+		cfg.Port = int(v)
+	}
+	return cfg
+}
+
+// domainMatches reports whether domain equals pattern or is a subdomain of it.
+func domainMatches(domain, pattern string) bool {
+	return domain == pattern || strings.HasSuffix(domain, "."+pattern)
+}
+
+// normalizeDomains lowercases domains and strips leading "@" or "*." markers.
+func normalizeDomains(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		value = strings.TrimPrefix(value, "@")
+		value = strings.TrimPrefix(value, "*.")
+		if value != "" {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// configValue returns the raw config value with any { "value": ... } wrapper removed.
+func configValue(key string) (json.RawMessage, bool) {
+	raw, ok := DBConfigValue(key)
+	if !ok {
+		return nil, false
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, false
+	}
+	if raw[0] == '{' {
+		// wrapper allows parsing values wrapped in a { "value": ... } object.
+		var wrapper struct {
+			Value json.RawMessage `json:"value"`
+		}
+		if errUnmarshal := json.Unmarshal(raw, &wrapper); errUnmarshal != nil || len(wrapper.Value) == 0 {
+			return nil, false
+		}
+		return bytes.TrimSpace(wrapper.Value), true
+	}
+	return raw, true
+}
+
+// configString reads a trimmed string setting.
+func configString(key string) string {
+	raw, ok := configValue(key)
+	if !ok {
+		return ""
+	}
+	var s string
+	if errUnmarshal := json.Unmarshal(raw, &s); errUnmarshal != nil {
+		return ""
+	}
+	return strings.TrimSpace(s)
+}
+
+// configBool reads a boolean setting, accepting JSON booleans and "true"/"false" strings.
+func configBool(key string) (bool, bool) {
+	raw, ok := configValue(key)
+	if !ok {
+		return false, false
+	}
+	var b bool
+	if errUnmarshal := json.Unmarshal(raw, &b); errUnmarshal == nil {
+		return b, true
+	}
+	var s string
+	if errUnmarshal := json.Unmarshal(raw, &s); errUnmarshal == nil {
+		if parsed, errParse := strconv.ParseBool(strings.TrimSpace(s)); errParse == nil {
+			return parsed, true
+		}
+	}
+	return false, false
+}
+
+// configFloat reads a numeric setting, accepting JSON numbers and numeric strings.
+func configFloat(key string) (float64, bool) {
+	raw, ok := configValue(key)
+	if !ok {
+		return 0, false
+	}
+	var f float64
+	if errUnmarshal := json.Unmarshal(raw, &f); errUnmarshal == nil {
+		return f, true
+	}
+	var s string
+	if errUnmarshal := json.Unmarshal(raw, &s); errUnmarshal == nil {
+		if parsed, errParse := strconv.ParseFloat(strings.TrimSpace(s), 64); errParse == nil {
+			return parsed, true
+		}
+	}
+	return 0, false
+}
+
+// configStrings reads a string list setting, accepting arrays or comma separated strings.
+func configStrings(key string) []string {
+	raw, ok := configValue(key)
+	if !ok {
+		return nil
+	}
+	var values []string
+	if errUnmarshal := json.Unmarshal(raw, &values); errUnmarshal == nil {
+		return values
+	}
+	var s string
+	if errUnmarshal := json.Unmarshal(raw, &s); errUnmarshal == nil {
+		return strings.Split(s, ",")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package settings
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLoadRegistrationPolicy(t *testing.T) {
+	t.Cleanup(func() { StoreDBConfig(time.Time{}, nil) })
+
+	StoreDBConfig(time.Now(), nil)
+	policy := LoadRegistrationPolicy()
+	if policy.Mode != RegistrationModeOpen || policy.IPLimitPerHour != DefaultRegistrationIPLimitPerHour {
+		t.Fatalf("unexpected defaults: %+v", policy)
+	}
+	if !policy.EmailDomainAllowed("someone@example.com") || policy.RequiresEmail() {
+		t.Fatalf("default policy should allow any email without requiring one")
+	}
+
+	StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		RegistrationModeKey:                     json.RawMessage(`"invite_only"`),
+		RegistrationRequireEmailVerificationKey: json.RawMessage(`{"value":true}`),
+		RegistrationAllowedEmailDomainsKey:      json.RawMessage(`["@Example.com", "*.corp.test"]`),
+		RegistrationBlockedEmailDomainsKey:      json.RawMessage(`"blocked.example.com"`),
+		RegistrationIPLimitPerHourKey:           json.RawMessage(`"0"`),
+		RegistrationBonusAmountKey:              json.RawMessage(`2.5`),
+	})
+	policy = LoadRegistrationPolicy()
+	if policy.Mode != RegistrationModeInviteOnly {
+		t.Fatalf("expected invite_only mode, got %q", policy.Mode)
+	}
+	if !policy.RequireEmailVerification || !policy.RequiresEmail() {
+		t.Fatalf("expected email verification to be required")
+	}
+	if policy.IPLimitPerHour != 0 || policy.BonusAmount != 2.5 {
+		t.Fatalf("unexpected numeric settings: %+v", policy)
+	}
+
+	cases := map[string]bool{
+		"a@example.com":         true,
+		"a@mail.EXAMPLE.com":    true,
+		"a@blocked.example.com": false,
+		"a@dev.corp.test":       true,
+		"a@other.test":          false,
+		"not-an-email":          false,
+	}
+	for email, want := range cases {
+		if got := policy.EmailDomainAllowed(email); got != want {
+			t.Fatalf("EmailDomainAllowed(%q) = %v, want %v", email, got, want)
+		}
+	}
+
+	StoreDBConfig(time.Now(), map[string]json.RawMessage{
+		RegistrationModeKey: json.RawMessage(`"bogus"`),
+	})
+	if mode := LoadRegistrationPolicy().Mode; mode != DefaultRegistrationMode {
+		t.Fatalf("invalid mode should fall back to default, got %q", mode)
+	}
+}
@@ -0,0 +1,9 @@
+package settings
+
+// UserUsageSummariesEnabled reports whether users who opted in receive usage summary emails.
+func UserUsageSummariesEnabled() bool {
+	if v, ok := configBool(ReportUserSummariesEnabledKey); ok {
+		return v
+	}
+	return DefaultReportUserSummariesEnabled
+}
@@ -0,0 +1,28 @@
+package settings
+
+import "strings"
+
+// sensitiveKeys lists settings whose values are secrets. Webhook URLs are included because
+// they commonly embed access tokens.
+var sensitiveKeys = map[string]struct{}{
+	SMTPPasswordKey:           {},
+	RateLimitRedisPasswordKey: {},
+	QuotaAlertWebhookURLKey:   {},
+	AnomalyAlertWebhookURLKey: {},
+}
+
+// IsSensitiveKey reports whether the value of a setting must not be shown in logs or audit
+// records. Keys outside the known list are matched by name, so custom secret settings are
+// covered too.
+func IsSensitiveKey(key string) bool {
+	normalized := strings.ToUpper(strings.TrimSpace(key))
+	if _, ok := sensitiveKeys[normalized]; ok {
+		return true
+	}
+	for _, fragment := range []string{"PASSWORD", "SECRET", "TOKEN", "WEBHOOK_URL"} {
+		if strings.Contains(normalized, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+package settings
+
+// SiteName returns the configured site name, used in outgoing email.
+func SiteName() string {
+	if name := configString(SiteNameKey); name != "" {
+		return name
+	}
+	return DefaultSiteName
+}
@@ -0,0 +1,429 @@
+// Package sso implements OpenID Connect single sign-on for admins and users.
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// discoveryTTL is how long discovery documents are cached.
+	discoveryTTL = time.Hour
+	// jwksTTL is how long signing keys are cached before a refresh.
+	jwksTTL = time.Hour
+	// jwksMinRefresh limits refetches triggered by unknown key IDs.
+	jwksMinRefresh = time.Minute
+	// clockSkew is the leeway applied to ID token time claims.
+	clockSkew = time.Minute
+	// maxResponseBytes bounds discovery and JWKS response bodies.
+	maxResponseBytes = 1 << 20
+)
+
+// DefaultScopes are requested when a provider configures none.
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// signingMethods lists the accepted ID token algorithms.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// ProviderConfig describes one OIDC relying-party registration.
+type ProviderConfig struct {
+	Issuer       string   // Issuer URL used for discovery and validation.
+	ClientID     string   // OAuth2 client ID.
+	ClientSecret string   // OAuth2 client secret; empty for public clients.
+	RedirectURL  string   // Callback URL registered at the provider.
+	Scopes       []string // Requested scopes.
+	GroupsClaim  string   // Claim holding group names, if any.
+}
+
+// Discovery holds the subset of the provider metadata used here.
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// Claims holds the identity claims extracted from a validated ID token.
+type Claims struct {
+	Subject           string         // Stable subject identifier.
+	Email             string         // Email address, if released.
+	EmailVerified     bool           // Whether the provider verified the email.
+	Name              string         // Display name.
+	PreferredUsername string         // Preferred username.
+	Groups            []string       // Values of the configured groups claim.
+	Raw               map[string]any // All token claims.
+}
+
+// cachedDiscovery stores a discovery document with its fetch time.
+type cachedDiscovery struct {
+	doc       *Discovery
+	fetchedAt time.Time
+}
+
+// cachedKeys stores a parsed key set with its fetch time.
+type cachedKeys struct {
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// Client performs discovery, authorization and token validation against OIDC providers.
+type Client struct {
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu        sync.Mutex
+	discovery map[string]cachedDiscovery
+	keys      map[string]cachedKeys
+}
+
+// NewClient constructs a Client; a nil httpClient uses a client with a 15 second timeout.
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &Client{
+		httpClient: httpClient,
+		now:        time.Now,
+		discovery:  make(map[string]cachedDiscovery),
+		keys:       make(map[string]cachedKeys),
+	}
+}
+
+// Discover fetches and caches the provider's discovery document.
+func (c *Client) Discover(ctx context.Context, issuer string) (*Discovery, error) {
+	issuer = strings.TrimRight(strings.TrimSpace(issuer), "/")
+	if issuer == "" {
+		return nil, errors.New("sso: missing issuer")
+	}
+	c.mu.Lock()
+	cached, ok := c.discovery[issuer]
+	c.mu.Unlock()
+	if ok && c.now().Sub(cached.fetchedAt) < discoveryTTL {
+		return cached.doc, nil
+	}
+
+	var doc Discovery
+	if errFetch := c.getJSON(ctx, issuer+"/.well-known/openid-configuration", &doc); errFetch != nil {
+		return nil, fmt.Errorf("sso: discovery: %w", errFetch)
+	}
+	if strings.TrimRight(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("sso: discovery issuer mismatch: %q", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("sso: discovery document incomplete")
+	}
+
+	c.mu.Lock()
+	c.discovery[issuer] = cachedDiscovery{doc: &doc, fetchedAt: c.now()}
+	c.mu.Unlock()
+	return &doc, nil
+}
+
+// AuthCodeURL returns the authorization URL for a PKCE authorization code flow.
+func (c *Client) AuthCodeURL(ctx context.Context, cfg ProviderConfig, state, nonce, verifier string) (string, error) {
+	oauthCfg, errCfg := c.oauthConfig(ctx, cfg)
+	if errCfg != nil {
+		return "", errCfg
+	}
+	return oauthCfg.AuthCodeURL(
+		state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	), nil
+}
+
+// Exchange redeems an authorization code and returns the validated ID token claims.
+func (c *Client) Exchange(ctx context.Context, cfg ProviderConfig, code, verifier, nonce string) (*Claims, error) {
+	oauthCfg, errCfg := c.oauthConfig(ctx, cfg)
+	if errCfg != nil {
+		return nil, errCfg
+	}
+	exchangeCtx := context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
+	token, errExchange := oauthCfg.Exchange(exchangeCtx, code, oauth2.VerifierOption(verifier))
+	if errExchange != nil {
+		return nil, fmt.Errorf("sso: exchange code: %w", errExchange)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, errors.New("sso: token response has no id_token")
+	}
+	return c.VerifyIDToken(ctx, cfg, rawIDToken, nonce)
+}
+
+// VerifyIDToken validates an ID token's signature, issuer, audience, expiry and nonce.
+func (c *Client) VerifyIDToken(ctx context.Context, cfg ProviderConfig, rawIDToken, nonce string) (*Claims, error) {
+	doc, errDiscover := c.Discover(ctx, cfg.Issuer)
+	if errDiscover != nil {
+		return nil, errDiscover
+	}
+
+	parsed, errParse := jwt.Parse(
+		rawIDToken,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return c.signingKey(ctx, doc.JWKSURI, kid)
+		},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(c.now),
+	)
+	if errParse != nil {
+		return nil, fmt.Errorf("sso: invalid id token: %w", errParse)
+	}
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("sso: invalid id token claims")
+	}
+	if nonce != "" {
+		if got, _ := mapClaims["nonce"].(string); got != nonce {
+			return nil, errors.New("sso: id token nonce mismatch")
+		}
+	}
+	if aud, _ := mapClaims.GetAudience(); len(aud) > 1 {
+		if azp, _ := mapClaims["azp"].(string); azp != cfg.ClientID {
+			return nil, errors.New("sso: id token authorized party mismatch")
+		}
+	}
+
+	claims := &Claims{Raw: map[string]any(mapClaims)}
+	claims.Subject, _ = mapClaims["sub"].(string)
+	if strings.TrimSpace(claims.Subject) == "" {
+		return nil, errors.New("sso: id token has no subject")
+	}
+	claims.Email, _ = mapClaims["email"].(string)
+	claims.EmailVerified = claimBool(mapClaims["email_verified"])
+	claims.Name, _ = mapClaims["name"].(string)
+	claims.PreferredUsername, _ = mapClaims["preferred_username"].(string)
+	if groupsClaim := strings.TrimSpace(cfg.GroupsClaim); groupsClaim != "" {
+		claims.Groups = claimStrings(mapClaims[groupsClaim])
+	}
+	return claims, nil
+}
+
+// oauthConfig builds the oauth2 configuration from discovery metadata.
+func (c *Client) oauthConfig(ctx context.Context, cfg ProviderConfig) (*oauth2.Config, error) {
+	doc, errDiscover := c.Discover(ctx, cfg.Issuer)
+	if errDiscover != nil {
+		return nil, errDiscover
+	}
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	return &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  doc.AuthorizationEndpoint,
+			TokenURL: doc.TokenEndpoint,
+		},
+	}, nil
+}
+
+// signingKey returns the key for kid, refreshing the key set when it is stale or kid is unknown.
+func (c *Client) signingKey(ctx context.Context, jwksURI, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	cached, ok := c.keys[jwksURI]
+	c.mu.Unlock()
+
+	fresh := ok && c.now().Sub(cached.fetchedAt) < jwksTTL
+	if fresh {
+		if key, found := pickKey(cached.keys, kid); found {
+			return key, nil
+		}
+		if c.now().Sub(cached.fetchedAt) < jwksMinRefresh {
+			return nil, fmt.Errorf("sso: unknown signing key %q", kid)
+		}
+	}
+
+	keys, errFetch := c.fetchKeys(ctx, jwksURI)
+	if errFetch != nil {
+		return nil, errFetch
+	}
+	c.mu.Lock()
+	c.keys[jwksURI] = cachedKeys{keys: keys, fetchedAt: c.now()}
+	c.mu.Unlock()
+	if key, found := pickKey(keys, kid); found {
+		return key, nil
+	}
+	return nil, fmt.Errorf("sso: unknown signing key %q", kid)
+}
+
+// pickKey selects the key by ID, or the only key when the token carries no kid.
+func pickKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid != "" {
+		key, ok := keys[kid]
+		return key, ok
+	}
+	if len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// jsonWebKey is a single entry of a JWKS document.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchKeys downloads and parses the provider's signing keys.
+func (c *Client) fetchKeys(ctx context.Context, jwksURI string) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if errFetch := c.getJSON(ctx, jwksURI, &doc); errFetch != nil {
+		return nil, fmt.Errorf("sso: fetch jwks: %w", errFetch)
+	}
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, errKey := parseJWK(jwk)
+		if errKey != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("sso: jwks has no usable keys")
+	}
+	return keys, nil
+}
+
+// parseJWK converts an RSA or EC JSON web key into a public key.
+func parseJWK(jwk jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := decodeBigInt(jwk.N)
+		if errN != nil {
+			return nil, errN
+		}
+		e, errE := decodeBigInt(jwk.E)
+		if errE != nil {
+			return nil, errE
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("sso: rsa exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("sso: unsupported curve %q", jwk.Crv)
+		}
+		x, errX := decodeBigInt(jwk.X)
+		if errX != nil {
+			return nil, errX
+		}
+		y, errY := decodeBigInt(jwk.Y)
+		if errY != nil {
+			return nil, errY
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("sso: unsupported key type %q", jwk.Kty)
+	}
+}
+
+// decodeBigInt decodes a base64url big-endian integer.
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, errDecode := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if errDecode != nil {
+		return nil, errDecode
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("sso: empty key component")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// getJSON performs a GET request and decodes a JSON response body.
+func (c *Client) getJSON(ctx context.Context, url string, out any) error {
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if errReq != nil {
+		return errReq
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, errDo := c.httpClient.Do(req)
+	if errDo != nil {
+		return errDo
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(out)
+}
+
+// claimBool reads a boolean claim that may be encoded as a string.
+func claimBool(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
+
+// claimStrings reads a claim holding a string or a list of strings.
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil
+		}
+		return []string{strings.TrimSpace(v)}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,252 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"gorm.io/datatypes"
+)
+
+// testIdP is a minimal OpenID provider for exercising the login flow.
+type testIdP struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu       sync.Mutex
+	pending  map[string]url.Values // Authorization requests by code.
+	audience string                // Overrides the token audience when set.
+	claims   map[string]any        // Extra ID token claims.
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, errKey := rsa.GenerateKey(rand.Reader, 2048)
+	if errKey != nil {
+		t.Fatalf("generate key: %v", errKey)
+	}
+	idp := &testIdP{key: key, clientID: "proxy-client", pending: make(map[string]url.Values)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		idp.mu.Lock()
+		auth, ok := idp.pending[r.PostForm.Get("code")]
+		delete(idp.pending, r.PostForm.Get("code"))
+		audience, extra := idp.audience, idp.claims
+		idp.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		if audience == "" {
+			audience = idp.clientID
+		}
+		now := time.Now()
+		claims := jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"aud":   audience,
+			"sub":   "subject-1",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"nonce": auth.Get("nonce"),
+		}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test-key"
+		signed, _ := token.SignedString(key)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"id_token":     signed,
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize simulates the user approving the request and returns the callback state and code.
+func (idp *testIdP) authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	parsed, errParse := url.Parse(authURL)
+	if errParse != nil {
+		t.Fatalf("parse auth url: %v", errParse)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" || query.Get("nonce") == "" {
+		t.Fatalf("auth url missing pkce or nonce: %s", authURL)
+	}
+	code := "code-" + query.Get("state")[:8]
+	idp.mu.Lock()
+	idp.pending[code] = query
+	idp.mu.Unlock()
+	return query.Get("state"), code
+}
+
+func TestServiceLoginFlow(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+	ctx := context.Background()
+	idp := newTestIdP(t)
+	idp.claims = map[string]any{
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+		"groups":         []string{"engineering"},
+	}
+
+	group := models.UserGroup{Name: "engineering"}
+	if errCreate := conn.Create(&group).Error; errCreate != nil {
+		t.Fatalf("create group: %v", errCreate)
+	}
+	mapping, _ := json.Marshal(map[string]uint64{"engineering": group.ID})
+	provider := models.OIDCProvider{
+		Slug:          "corp",
+		DisplayName:   "Corp",
+		IssuerURL:     idp.server.URL,
+		ClientID:      idp.clientID,
+		ForUsers:      true,
+		ForAdmins:     true,
+		AutoProvision: true,
+		GroupsClaim:   "groups",
+		GroupMapping:  datatypes.JSON(mapping),
+		IsEnabled:     true,
+	}
+	if errCreate := conn.Create(&provider).Error; errCreate != nil {
+		t.Fatalf("create provider: %v", errCreate)
+	}
+
+	service := NewService(conn, NewClient(idp.server.Client()))
+	const redirectURL = "http://proxy.test/v0/front/sso/corp/callback"
+	login := func(kind string, linkID *uint64) (*Result, error) {
+		loaded, errLoad := service.LoadProvider(ctx, "corp", kind)
+		if errLoad != nil {
+			t.Fatalf("load provider: %v", errLoad)
+		}
+		authURL, errStart := service.Start(ctx, loaded, kind, redirectURL, "/dashboard", linkID)
+		if errStart != nil {
+			t.Fatalf("start: %v", errStart)
+		}
+		state, code := idp.authorize(t, authURL)
+		return service.Complete(ctx, kind, "corp", state, code, redirectURL)
+	}
+
+	result, errLogin := login(models.SSOKindUser, nil)
+	if errLogin != nil {
+		t.Fatalf("complete: %v", errLogin)
+	}
+	if result.ReturnTo != "/dashboard" || result.Claims.Email != "alice@example.com" || !result.Claims.EmailVerified {
+		t.Fatalf("unexpected result: %+v %+v", result, result.Claims)
+	}
+	user, errResolve := service.ResolveUser(ctx, result.Provider, result.Claims, nil)
+	if errResolve != nil {
+		t.Fatalf("resolve user: %v", errResolve)
+	}
+	if len(user.UserGroupID) != 1 || user.UserGroupID[0] == nil || *user.UserGroupID[0] != group.ID {
+		t.Fatalf("expected mapped user group, got %v", user.UserGroupID)
+	}
+
+	result, errLogin = login(models.SSOKindUser, nil)
+	if errLogin != nil {
+		t.Fatalf("second complete: %v", errLogin)
+	}
+	again, errResolve := service.ResolveUser(ctx, result.Provider, result.Claims, nil)
+	if errResolve != nil || again.ID != user.ID {
+		t.Fatalf("expected the same user on second login, got %v %v", again, errResolve)
+	}
+
+	result, errLogin = login(models.SSOKindAdmin, nil)
+	if errLogin != nil {
+		t.Fatalf("admin complete: %v", errLogin)
+	}
+	if _, errAdmin := service.ResolveAdmin(ctx, result.Provider, result.Claims, nil); !errors.Is(errAdmin, ErrNoLinkedAccount) {
+		t.Fatalf("expected unlinked admin to be rejected, got %v", errAdmin)
+	}
+	admin := models.Admin{Username: "root", Password: "x", Active: true}
+	if errCreate := conn.Create(&admin).Error; errCreate != nil {
+		t.Fatalf("create admin: %v", errCreate)
+	}
+	result, errLogin = login(models.SSOKindAdmin, &admin.ID)
+	if errLogin != nil {
+		t.Fatalf("admin link complete: %v", errLogin)
+	}
+	if _, errAdmin := service.ResolveAdmin(ctx, result.Provider, result.Claims, result.LinkPrincipalID); errAdmin != nil {
+		t.Fatalf("link admin: %v", errAdmin)
+	}
+	result, errLogin = login(models.SSOKindAdmin, nil)
+	if errLogin != nil {
+		t.Fatalf("admin login complete: %v", errLogin)
+	}
+	if linked, errAdmin := service.ResolveAdmin(ctx, result.Provider, result.Claims, nil); errAdmin != nil || linked.ID != admin.ID {
+		t.Fatalf("expected linked admin login, got %v %v", linked, errAdmin)
+	}
+
+	if _, errReplay := service.Complete(ctx, models.SSOKindUser, "corp", "unknown-state", "code", redirectURL); !errors.Is(errReplay, ErrInvalidState) {
+		t.Fatalf("expected invalid state, got %v", errReplay)
+	}
+
+	idp.audience = "someone-else"
+	if _, errLogin = login(models.SSOKindUser, nil); errLogin == nil {
+		t.Fatalf("expected audience mismatch to be rejected")
+	}
+}
+
+func TestSanitizeReturnTo(t *testing.T) {
+	cases := map[string]string{
+		"":                      "/",
+		"/dashboard?tab=1#x":    "/dashboard?tab=1",
+		"//evil.example.com":    "/",
+		"https://evil.example":  "/",
+		"/\\evil.example.com":   "/",
+		"relative/path":         "/",
+		"/organization/members": "/organization/members",
+	}
+	for raw, want := range cases {
+		if got := SanitizeReturnTo(raw, "/"); got != want {
+			t.Fatalf("SanitizeReturnTo(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package sso
+
+import (
+	"errors"
+	"net/url"
+)
+
+// FragmentURL appends values to the return path as a URL fragment so tokens never reach server logs.
+func FragmentURL(returnTo string, values url.Values) string {
+	return returnTo + "#" + values.Encode()
+}
+
+// ErrorCode maps flow errors to stable codes for the web UI.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrProviderNotFound):
+		return "provider_not_found"
+	case errors.Is(err, ErrInvalidState):
+		return "invalid_state"
+	case errors.Is(err, ErrNoLinkedAccount):
+		return "no_linked_account"
+	case errors.Is(err, ErrAccountDisabled):
+		return "account_disabled"
+	case errors.Is(err, ErrIdentityInUse):
+		return "identity_in_use"
+	default:
+		return "login_failed"
+	}
+}
@@ -0,0 +1,535 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/security"
+	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// loginStateTTL bounds the time between starting a login and its callback.
+const loginStateTTL = 10 * time.Minute
+
+var (
+	// ErrProviderNotFound indicates the provider is unknown, disabled or not offered for the principal kind.
+	ErrProviderNotFound = errors.New("sso: provider not found")
+	// ErrInvalidState indicates the callback state is unknown, reused or expired.
+	ErrInvalidState = errors.New("sso: invalid or expired state")
+	// ErrNoLinkedAccount indicates no account is linked and provisioning is not allowed.
+	ErrNoLinkedAccount = errors.New("sso: no linked account")
+	// ErrAccountDisabled indicates the linked account cannot sign in.
+	ErrAccountDisabled = errors.New("sso: account disabled")
+	// ErrIdentityInUse indicates the identity is already linked to a different account.
+	ErrIdentityInUse = errors.New("sso: identity linked to another account")
+)
+
+// defaultClient is shared so discovery and key caches survive across handlers.
+var defaultClient = NewClient(nil)
+
+// DefaultClient returns the process-wide OIDC client.
+func DefaultClient() *Client {
+	return defaultClient
+}
+
+// Service runs SSO login flows backed by the database.
+type Service struct {
+	db     *gorm.DB
+	client *Client
+}
+
+// NewService constructs a Service; a nil client uses DefaultClient.
+func NewService(db *gorm.DB, client *Client) *Service {
+	if client == nil {
+		client = defaultClient
+	}
+	return &Service{db: db, client: client}
+}
+
+// Result carries a completed authorization callback.
+type Result struct {
+	Provider        *models.OIDCProvider // Provider that authenticated the principal.
+	Claims          *Claims              // Validated ID token claims.
+	ReturnTo        string               // Relative path requested at login start.
+	LinkPrincipalID *uint64              // Account to link, when the flow was a link request.
+}
+
+// Providers lists enabled providers offered for the principal kind.
+func (s *Service) Providers(ctx context.Context, kind string) ([]models.OIDCProvider, error) {
+	var rows []models.OIDCProvider
+	q := s.db.WithContext(ctx).Where("is_enabled = ?", true)
+	q = q.Where(kindColumn(kind)+" = ?", true)
+	if errFind := q.Order("display_name ASC").Find(&rows).Error; errFind != nil {
+		return nil, errFind
+	}
+	return rows, nil
+}
+
+// LoadProvider returns an enabled provider by slug offered for the principal kind.
+func (s *Service) LoadProvider(ctx context.Context, slug, kind string) (*models.OIDCProvider, error) {
+	var provider models.OIDCProvider
+	if errFind := s.db.WithContext(ctx).Where("slug = ?", strings.TrimSpace(slug)).Take(&provider).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, ErrProviderNotFound
+		}
+		return nil, errFind
+	}
+	if !providerOffered(&provider, kind) {
+		return nil, ErrProviderNotFound
+	}
+	return &provider, nil
+}
+
+// Start records a pending login and returns the provider authorization URL.
+func (s *Service) Start(ctx context.Context, provider *models.OIDCProvider, kind, redirectURL, returnTo string, linkPrincipalID *uint64) (string, error) {
+	state, errState := security.GenerateRandomString(32)
+	if errState != nil {
+		return "", errState
+	}
+	nonce, errNonce := security.GenerateRandomString(32)
+	if errNonce != nil {
+		return "", errNonce
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, errURL := s.client.AuthCodeURL(ctx, ProviderConfigFor(provider, redirectURL), state, nonce, verifier)
+	if errURL != nil {
+		return "", errURL
+	}
+
+	now := time.Now().UTC()
+	if errCleanup := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.SSOLoginState{}).Error; errCleanup != nil {
+		return "", errCleanup
+	}
+	row := models.SSOLoginState{
+		State:           state,
+		ProviderID:      provider.ID,
+		Kind:            kind,
+		Nonce:           nonce,
+		CodeVerifier:    verifier,
+		ReturnTo:        returnTo,
+		LinkPrincipalID: linkPrincipalID,
+		ExpiresAt:       now.Add(loginStateTTL),
+		CreatedAt:       now,
+	}
+	if errCreate := s.db.WithContext(ctx).Create(&row).Error; errCreate != nil {
+		return "", errCreate
+	}
+	return authURL, nil
+}
+
+// PeekReturnTo returns the stored return path for a state without consuming it.
+func (s *Service) PeekReturnTo(ctx context.Context, state string) (string, bool) {
+	var row models.SSOLoginState
+	if errFind := s.db.WithContext(ctx).Select("return_to").Where("state = ?", state).Take(&row).Error; errFind != nil {
+		return "", false
+	}
+	return row.ReturnTo, true
+}
+
+// Complete consumes the login state and exchanges the authorization code.
+func (s *Service) Complete(ctx context.Context, kind, slug, state, code, redirectURL string) (*Result, error) {
+	state = strings.TrimSpace(state)
+	if state == "" || strings.TrimSpace(code) == "" {
+		return nil, ErrInvalidState
+	}
+	var row models.SSOLoginState
+	if errFind := s.db.WithContext(ctx).Where("state = ?", state).Take(&row).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidState
+		}
+		return nil, errFind
+	}
+	res := s.db.WithContext(ctx).Delete(&models.SSOLoginState{}, row.ID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 || row.Kind != kind || !row.ExpiresAt.After(time.Now().UTC()) {
+		return nil, ErrInvalidState
+	}
+
+	provider, errProvider := s.LoadProvider(ctx, slug, kind)
+	if errProvider != nil {
+		return nil, errProvider
+	}
+	if provider.ID != row.ProviderID {
+		return nil, ErrInvalidState
+	}
+	claims, errExchange := s.client.Exchange(ctx, ProviderConfigFor(provider, redirectURL), code, row.CodeVerifier, row.Nonce)
+	if errExchange != nil {
+		return nil, errExchange
+	}
+	return &Result{
+		Provider:        provider,
+		Claims:          claims,
+		ReturnTo:        row.ReturnTo,
+		LinkPrincipalID: row.LinkPrincipalID,
+	}, nil
+}
+
+// ResolveUser returns the user for an SSO login, linking or provisioning as the provider allows.
+func (s *Service) ResolveUser(ctx context.Context, provider *models.OIDCProvider, claims *Claims, linkUserID *uint64) (*models.User, error) {
+	now := time.Now().UTC()
+	groups := MappedUserGroups(provider, claims.Groups)
+
+	var user *models.User
+	errTx := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		identity, errIdentity := findIdentity(tx, provider.ID, models.SSOKindUser, claims.Subject)
+		if errIdentity != nil {
+			return errIdentity
+		}
+		switch {
+		case identity != nil && identity.UserID != nil:
+			if linkUserID != nil && *linkUserID != *identity.UserID {
+				return ErrIdentityInUse
+			}
+			found, errFind := loadUser(tx, *identity.UserID)
+			if errFind != nil {
+				return errFind
+			}
+			user = found
+		case linkUserID != nil:
+			found, errFind := loadUser(tx, *linkUserID)
+			if errFind != nil {
+				return errFind
+			}
+			user = found
+		case provider.LinkByEmail && claims.EmailVerified && strings.TrimSpace(claims.Email) != "":
+			var found models.User
+			errFind := tx.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(claims.Email))).Take(&found).Error
+			if errFind == nil {
+				user = &found
+			} else if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+				return errFind
+			}
+		}
+
+		if user == nil {
+			if !provider.AutoProvision {
+				return ErrNoLinkedAccount
+			}
+			created, errProvision := provisionUser(tx, claims, groups, now)
+			if errProvision != nil {
+				return errProvision
+			}
+			user = created
+		} else if len(groups) > 0 {
+			user.UserGroupID = groups
+			if errUpdate := tx.Model(&models.User{}).Where("id = ?", user.ID).
+				Updates(map[string]any{"user_group_id": groups, "updated_at": now}).Error; errUpdate != nil {
+				return errUpdate
+			}
+		}
+		if user.Disabled || !user.Active {
+			return ErrAccountDisabled
+		}
+		userID := user.ID
+		return upsertIdentity(tx, identity, provider.ID, models.SSOKindUser, claims, &userID, nil, now)
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return user, nil
+}
+
+// ResolveAdmin returns the administrator for an SSO login; administrators are never provisioned.
+func (s *Service) ResolveAdmin(ctx context.Context, provider *models.OIDCProvider, claims *Claims, linkAdminID *uint64) (*models.Admin, error) {
+	now := time.Now().UTC()
+	var admin models.Admin
+	errTx := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		identity, errIdentity := findIdentity(tx, provider.ID, models.SSOKindAdmin, claims.Subject)
+		if errIdentity != nil {
+			return errIdentity
+		}
+		var adminID uint64
+		switch {
+		case identity != nil && identity.AdminID != nil:
+			if linkAdminID != nil && *linkAdminID != *identity.AdminID {
+				return ErrIdentityInUse
+			}
+			adminID = *identity.AdminID
+		case linkAdminID != nil:
+			adminID = *linkAdminID
+		default:
+			return ErrNoLinkedAccount
+		}
+		if errFind := tx.First(&admin, adminID).Error; errFind != nil {
+			if errors.Is(errFind, gorm.ErrRecordNotFound) {
+				return ErrNoLinkedAccount
+			}
+			return errFind
+		}
+		if !admin.Active {
+			return ErrAccountDisabled
+		}
+		return upsertIdentity(tx, identity, provider.ID, models.SSOKindAdmin, claims, nil, &admin.ID, now)
+	})
+	if errTx != nil {
+		return nil, errTx
+	}
+	return &admin, nil
+}
+
+// ProviderConfigFor converts a stored provider into a client configuration.
+func ProviderConfigFor(provider *models.OIDCProvider, redirectURL string) ProviderConfig {
+	return ProviderConfig{
+		Issuer:       provider.IssuerURL,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       strings.Fields(provider.Scopes),
+		GroupsClaim:  provider.GroupsClaim,
+	}
+}
+
+// ParseGroupMapping decodes a claim-value to user-group mapping; values may be an ID or a list of IDs.
+func ParseGroupMapping(raw []byte) (map[string][]uint64, error) {
+	out := make(map[string][]uint64)
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return out, nil
+	}
+	var decoded map[string]json.RawMessage
+	if errUnmarshal := json.Unmarshal(raw, &decoded); errUnmarshal != nil {
+		return nil, fmt.Errorf("sso: group mapping must be an object: %w", errUnmarshal)
+	}
+	for claimValue, rawIDs := range decoded {
+		claimValue = strings.TrimSpace(claimValue)
+		if claimValue == "" {
+			continue
+		}
+		var single uint64
+		if errSingle := json.Unmarshal(rawIDs, &single); errSingle == nil {
+			out[claimValue] = []uint64{single}
+			continue
+		}
+		var list []uint64
+		if errList := json.Unmarshal(rawIDs, &list); errList != nil {
+			return nil, fmt.Errorf("sso: group mapping %q must map to user group IDs", claimValue)
+		}
+		out[claimValue] = list
+	}
+	return out, nil
+}
+
+// MappedUserGroups returns the user groups mapped from the claim values, in ascending order.
+func MappedUserGroups(provider *models.OIDCProvider, claimGroups []string) models.UserGroupIDs {
+	mapping, errMapping := ParseGroupMapping(provider.GroupMapping)
+	if errMapping != nil || len(mapping) == 0 || len(claimGroups) == 0 {
+		return nil
+	}
+	seen := make(map[uint64]struct{})
+	ids := make([]uint64, 0)
+	for _, group := range claimGroups {
+		for _, id := range mapping[group] {
+			if id == 0 {
+				continue
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	out := make(models.UserGroupIDs, 0, len(ids))
+	for i := range ids {
+		out = append(out, &ids[i])
+	}
+	return out
+}
+
+// SanitizeReturnTo accepts only site-relative paths, falling back otherwise.
+func SanitizeReturnTo(raw, fallback string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") || strings.Contains(raw, "\\") {
+		return fallback
+	}
+	if idx := strings.Index(raw, "#"); idx >= 0 {
+		raw = raw[:idx]
+	}
+	return raw
+}
+
+// CallbackURL builds the absolute callback URL from PUBLIC_BASE_URL or the request.
+func CallbackURL(r *http.Request, path string) string {
+	if base := internalsettings.PublicBaseURL(); base != "" {
+		return base + path
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")[0]); forwarded != "" {
+		scheme = forwarded
+	}
+	host := r.Host
+	if forwarded := strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-Host"), ",")[0]); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host + path
+}
+
+// kindColumn returns the provider flag column for a principal kind.
+func kindColumn(kind string) string {
+	if kind == models.SSOKindAdmin {
+		return "for_admins"
+	}
+	return "for_users"
+}
+
+// providerOffered reports whether the provider is enabled for the principal kind.
+func providerOffered(provider *models.OIDCProvider, kind string) bool {
+	if !provider.IsEnabled {
+		return false
+	}
+	if kind == models.SSOKindAdmin {
+		return provider.ForAdmins
+	}
+	return provider.ForUsers
+}
+
+// findIdentity loads an identity by provider, kind and subject.
+func findIdentity(tx *gorm.DB, providerID uint64, kind, subject string) (*models.SSOIdentity, error) {
+	var identity models.SSOIdentity
+	errFind := tx.Where("provider_id = ? AND kind = ? AND subject = ?", providerID, kind, subject).Take(&identity).Error
+	if errFind == nil {
+		return &identity, nil
+	}
+	if errors.Is(errFind, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return nil, errFind
+}
+
+// loadUser loads a user by ID, mapping a missing row to ErrNoLinkedAccount.
+func loadUser(tx *gorm.DB, id uint64) (*models.User, error) {
+	var user models.User
+	if errFind := tx.First(&user, id).Error; errFind != nil {
+		if errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, ErrNoLinkedAccount
+		}
+		return nil, errFind
+	}
+	return &user, nil
+}
+
+// upsertIdentity creates or refreshes the identity link after a successful login.
+func upsertIdentity(tx *gorm.DB, identity *models.SSOIdentity, providerID uint64, kind string, claims *Claims, userID, adminID *uint64, now time.Time) error {
+	if identity != nil {
+		return tx.Model(&models.SSOIdentity{}).Where("id = ?", identity.ID).Updates(map[string]any{
+			"user_id":       userID,
+			"admin_id":      adminID,
+			"email":         claims.Email,
+			"last_login_at": now,
+		}).Error
+	}
+	return tx.Create(&models.SSOIdentity{
+		ProviderID:  providerID,
+		Kind:        kind,
+		Subject:     claims.Subject,
+		UserID:      userID,
+		AdminID:     adminID,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+		CreatedAt:   now,
+	}).Error
+}
+
+// usernameSanitizer strips characters not allowed in provisioned usernames.
+var usernameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// provisionUser creates a user from ID token claims.
+func provisionUser(tx *gorm.DB, claims *Claims, groups models.UserGroupIDs, now time.Time) (*models.User, error) {
+	base := strings.TrimSpace(claims.PreferredUsername)
+	if base == "" && claims.Email != "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = strings.Trim(usernameSanitizer.ReplaceAllString(base, "-"), "-.")
+	if base == "" {
+		base = "sso-user"
+	}
+	username, errName := uniqueUsername(tx, base)
+	if errName != nil {
+		return nil, errName
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email != "" {
+		var count int64
+		if errCount := tx.Model(&models.User{}).Where("LOWER(email) = ?", strings.ToLower(email)).Count(&count).Error; errCount != nil {
+			return nil, errCount
+		}
+		if count > 0 {
+			email = ""
+		}
+	}
+
+	secret, errSecret := security.GenerateRandomString(32)
+	if errSecret != nil {
+		return nil, errSecret
+	}
+	hash, errHash := security.HashPassword(secret)
+	if errHash != nil {
+		return nil, errHash
+	}
+
+	user := models.User{
+		Username:                 username,
+		Name:                     strings.TrimSpace(claims.Name),
+		Email:                    email,
+		Password:                 hash,
+		EmailVerificationPending: email != "" && !claims.EmailVerified && internalsettings.LoadRegistrationPolicy().RequireEmailVerification,
+		Active:                   true,
+		CreatedAt:                now,
+		UpdatedAt:                now,
+	}
+	if email != "" && claims.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+	if len(groups) > 0 {
+		user.UserGroupID = groups
+	} else {
+		var defaultGroup models.UserGroup
+		if errFind := tx.Where("is_default = ?", true).First(&defaultGroup).Error; errFind == nil {
+			user.UserGroupID = models.UserGroupIDs{&defaultGroup.ID}
+		} else if !errors.Is(errFind, gorm.ErrRecordNotFound) {
+			return nil, errFind
+		}
+	}
+	if errCreate := tx.Create(&user).Error; errCreate != nil {
+		return nil, errCreate
+	}
+	return &user, nil
+}
+
+// uniqueUsername appends a numeric suffix until the username is free.
+func uniqueUsername(tx *gorm.DB, base string) (string, error) {
+	candidate := base
+	for i := 2; i <= 100; i++ {
+		var count int64
+		if errCount := tx.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; errCount != nil {
+			return "", errCount
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = base + "-" + strconv.Itoa(i)
+	}
+	suffix, errSuffix := security.GenerateRandomString(8)
+	if errSuffix != nil {
+		return "", errSuffix
+	}
+	return base + "-" + strings.ToLower(suffix), nil
+}
@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies spans created by this module.
+const instrumentationName = "github.com/router-for-me/CLIProxyAPIBusiness"
+
+// upstreamStartKey stores when the selector handed an auth to the executor.
+const upstreamStartKey = "traceUpstreamStart"
+
+// Init installs the global tracer provider and W3C propagators; the returned func flushes and stops exporting.
+func Init(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeExporter, errExporter := newExporter(ctx, cfg)
+	if errExporter != nil {
+		return nil, errExporter
+	}
+
+	res, errResource := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if errResource != nil {
+		res = resource.Default()
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(shutdownCtx context.Context) error {
+		errShutdown := provider.Shutdown(shutdownCtx)
+		if closeExporter != nil {
+			errShutdown = errors.Join(errShutdown, closeExporter())
+		}
+		return errShutdown
+	}, nil
+}
+
+// newExporter builds the span exporter selected by the config.
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, func() error, error) {
+	switch cfg.Exporter {
+	case config.TracingExporterFile:
+		if cfg.FilePath == "" {
+			return nil, nil, fmt.Errorf("tracing: file exporter requires file-path")
+		}
+		if errMkdir := os.MkdirAll(filepath.Dir(cfg.FilePath), 0o755); errMkdir != nil {
+			return nil, nil, fmt.Errorf("tracing: create trace dir: %w", errMkdir)
+		}
+		file, errOpen := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if errOpen != nil {
+			return nil, nil, fmt.Errorf("tracing: open trace file: %w", errOpen)
+		}
+		exporter, errExporter := stdouttrace.New(stdouttrace.WithWriter(file))
+		if errExporter != nil {
+			_ = file.Close()
+			return nil, nil, fmt.Errorf("tracing: create file exporter: %w", errExporter)
+		}
+		return exporter, file.Close, nil
+	case config.TracingExporterOTLP:
+		opts := make([]otlptracehttp.Option, 0, 3)
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		exporter, errExporter := otlptracehttp.New(ctx, opts...)
+		if errExporter != nil {
+			return nil, nil, fmt.Errorf("tracing: create otlp exporter: %w", errExporter)
+		}
+		return exporter, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("tracing: unsupported exporter %q", cfg.Exporter)
+	}
+}
+
+// Tracer returns the module tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start begins a child span, falling back to the span on the gin request when ctx carries none.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(withRequestSpan(ctx), name, trace.WithAttributes(attrs...))
+}
+
+// TraceID returns the hex trace ID for ctx, or "" when no trace is active.
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	spanCtx := trace.SpanContextFromContext(withRequestSpan(ctx))
+	if !spanCtx.HasTraceID() {
+		return ""
+	}
+	return spanCtx.TraceID().String()
+}
+
+// withRequestSpan attaches the gin request span to ctx when ctx has no span of its own.
+func withRequestSpan(ctx context.Context) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil || ginCtx.Request == nil {
+		return ctx
+	}
+	span := trace.SpanFromContext(ginCtx.Request.Context())
+	if !span.SpanContext().IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, span)
+}
+
+// MarkUpstreamStart records when an upstream call begins for the request behind ctx.
+func MarkUpstreamStart(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		ginCtx.Set(upstreamStartKey, time.Now())
+	}
+}
+
+// RecordUpstream emits a span covering the upstream call that started at MarkUpstreamStart.
+func RecordUpstream(ctx context.Context, attrs ...attribute.KeyValue) trace.Span {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	started := time.Now()
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		if value, exists := ginCtx.Get(upstreamStartKey); exists {
+			if ts, okTime := value.(time.Time); okTime {
+				started = ts
+			}
+		}
+	}
+	_, span := Tracer().Start(withRequestSpan(ctx), "upstream.call",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(started),
+		trace.WithAttributes(attrs...),
+	)
+	return span
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"gorm.io/gorm"
@@ -70,7 +71,7 @@ func TestDeductBillBalanceRespectsUserGroupID(t *testing.T) {
 	amount := 5.0
 	costMicros := int64(amount * 1_000_000)
 	if errTx := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		deducted, errDeduct := deductBillBalance(ctx, tx, user.ID, &group1.ID, amount, costMicros)
+		deducted, errDeduct := deductBillBalance(ctx, tx, billing.Payer{UserID: user.ID}, &group1.ID, amount, costMicros)
 		if errDeduct != nil {
 			return errDeduct
 		}
@@ -149,7 +150,7 @@ func TestDeductPrepaidBalanceRespectsUserGroupID(t *testing.T) {
 	}
 
 	if errTx := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return deductPrepaidBalance(ctx, tx, user.ID, &group1.ID, 5)
+		return deductPrepaidBalance(ctx, tx, billing.Payer{UserID: user.ID}, &group1.ID, 5)
 	}); errTx != nil {
 		t.Fatalf("transaction: %v", errTx)
 	}
@@ -0,0 +1,41 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestHandleUsageStoresTraceID(t *testing.T) {
+	conn, errOpen := db.Open(":memory:")
+	if errOpen != nil {
+		t.Fatalf("open db: %v", errOpen)
+	}
+	if errMigrate := db.Migrate(conn); errMigrate != nil {
+		t.Fatalf("migrate db: %v", errMigrate)
+	}
+
+	provider := sdktrace.NewTracerProvider()
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	NewGormUsagePlugin(conn).HandleUsage(ctx, coreusage.Record{
+		Provider:    "openai",
+		Model:       "gpt-4o",
+		RequestedAt: time.Now().UTC(),
+	})
+
+	var row models.Usage
+	if errFind := conn.First(&row).Error; errFind != nil {
+		t.Fatalf("find usage: %v", errFind)
+	}
+	if want := span.SpanContext().TraceID().String(); row.TraceID != want {
+		t.Fatalf("expected trace_id=%q, got %q", want, row.TraceID)
+	}
+}
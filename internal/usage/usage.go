@@ -10,12 +10,18 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/billing"
 	dbutil "github.com/router-for-me/CLIProxyAPIBusiness/internal/db"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/requestlog"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -35,8 +41,16 @@ func (p *GormUsagePlugin) HandleUsage(ctx context.Context, record coreusage.Reco
 	}
 
 	meta := accessMetadataFromContext(ctx)
+	traceID := tracing.TraceID(ctx)
 
-	dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_, span := tracing.Start(ctx, "billing.record",
+		attribute.String("cpab.provider", record.Provider),
+		attribute.String("cpab.model", record.Model),
+		attribute.Bool("cpab.usage.failed", record.Failed),
+	)
+	defer span.End()
+
+	dbCtx, cancel := context.WithTimeout(trace.ContextWithSpan(context.Background(), span), 5*time.Second)
 	defer cancel()
 
 	var apiKeyID *uint64
@@ -87,6 +101,15 @@ func (p *GormUsagePlugin) HandleUsage(ctx context.Context, record coreusage.Reco
 	costMicros := calculateCost(dbCtx, p.db, apiKeyID, userID, authID, billingUserGroupID, recordForBilling)
 	amountToDeduct := float64(costMicros) / 1_000_000
 
+	var payer billing.Payer
+	if userID != nil {
+		resolved, errPayer := billing.ResolvePayer(dbCtx, p.db, *userID)
+		if errPayer != nil {
+			log.WithError(errPayer).Warn("usage plugin: failed to resolve organization payer")
+		}
+		payer = resolved
+	}
+
 	row := models.Usage{
 		Provider:        provider,
 		Model:           model,
@@ -94,6 +117,7 @@ func (p *GormUsagePlugin) HandleUsage(ctx context.Context, record coreusage.Reco
 		UserGroupID:     billingUserGroupID,
 		APIKeyID:        apiKeyID,
 		AuthID:          authID,
+		OrganizationID:  payer.OrganizationID,
 		AuthKey:         authKey,
 		AuthIndex:       strings.TrimSpace(record.AuthIndex),
 		Source:          strings.TrimSpace(record.Source),
@@ -105,8 +129,15 @@ func (p *GormUsagePlugin) HandleUsage(ctx context.Context, record coreusage.Reco
 		CachedTokens:    record.Detail.CachedTokens,
 		TotalTokens:     totalTokens,
 		CostMicros:      costMicros,
+		TraceID:         traceID,
 		CreatedAt:       time.Now().UTC(),
 	}
+	span.SetAttributes(attribute.Int64("cpab.billing.cost_micros", costMicros))
+
+	tracker := requestlog.FromContext(ctx)
+	if !tracker.Apply(&row) {
+		tracker = nil
+	}
 
 	if errTx := p.db.WithContext(dbCtx).Transaction(func(tx *gorm.DB) error {
 		if errCreate := tx.Create(&row).Error; errCreate != nil {
@@ -114,12 +145,12 @@ func (p *GormUsagePlugin) HandleUsage(ctx context.Context, record coreusage.Reco
 		}
 
 		if amountToDeduct > 0 && row.UserID != nil {
-			deducted, errDeductBill := deductBillBalance(dbCtx, tx, *row.UserID, billingUserGroupID, amountToDeduct, costMicros)
+			deducted, errDeductBill := deductBillBalance(dbCtx, tx, payer, billingUserGroupID, amountToDeduct, costMicros)
 			if errDeductBill != nil {
 				return errDeductBill
 			}
 			if !deducted {
-				if errDeductPrepaid := deductPrepaidBalance(dbCtx, tx, *row.UserID, billingUserGroupID, amountToDeduct); errDeductPrepaid != nil {
+				if errDeductPrepaid := deductPrepaidBalance(dbCtx, tx, payer, billingUserGroupID, amountToDeduct); errDeductPrepaid != nil {
 					return errDeductPrepaid
 				}
 			}
@@ -127,7 +158,22 @@ func (p *GormUsagePlugin) HandleUsage(ctx context.Context, record coreusage.Reco
 		return nil
 	}); errTx != nil {
 		log.WithError(errTx).Warn("usage plugin: failed to persist usage or deduct balance")
+		span.RecordError(errTx)
+		span.SetStatus(codes.Error, "persist usage failed")
+		return
 	}
+	tracker.Attach(dbCtx, &row)
+
+	metrics.ObserveUsage(metrics.UsageSample{
+		Provider:        row.Provider,
+		Model:           row.Model,
+		Failed:          row.Failed,
+		CostMicros:      row.CostMicros,
+		InputTokens:     row.InputTokens,
+		OutputTokens:    row.OutputTokens,
+		ReasoningTokens: row.ReasoningTokens,
+		CachedTokens:    row.CachedTokens,
+	})
 }
 
 // resolveAuthRecordID looks up the auth record ID by key.
@@ -161,8 +207,8 @@ func resolveAuthRecordID(ctx context.Context, db *gorm.DB, authKey string) *uint
 // billQuotaEpsilon defines a tolerance for quota comparisons.
 const billQuotaEpsilon = 0.000001
 
-// deductBillBalance deducts usage from active bills and updates quotas.
-func deductBillBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGroupID *uint64, amount float64, costMicros int64) (bool, error) {
+// deductBillBalance deducts usage from the payer's active bills and updates quotas.
+func deductBillBalance(ctx context.Context, tx *gorm.DB, payer billing.Payer, userGroupID *uint64, amount float64, costMicros int64) (bool, error) {
 	if tx == nil {
 		return false, errors.New("nil tx")
 	}
@@ -172,12 +218,12 @@ func deductBillBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGrou
 
 	now := time.Now().UTC()
 	var bills []models.Bill
-	q := tx.WithContext(ctx).
+	q := payer.ScopeBills(tx.WithContext(ctx).
 		Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
+		Where("is_enabled = ? AND status = ? AND left_quota > 0", true, models.BillStatusPaid).
 		Where("period_start <= ? AND period_end >= ?", now, now).
 		Order("period_end ASC, period_start ASC, id ASC").
-		Model(&models.Bill{})
+		Model(&models.Bill{}))
 	if userGroupID != nil && *userGroupID != 0 {
 		q = q.Where(dbutil.JSONArrayContainsExpr(tx, "user_group_id"), dbutil.JSONArrayContainsValue(tx, *userGroupID))
 	}
@@ -209,7 +255,7 @@ func deductBillBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGrou
 	}
 
 	if !unlimitedDaily && totalDaily > 0 {
-		usedToday, errUsage := loadTodayUsageAmount(ctx, tx, userID, userGroupID, now)
+		usedToday, errUsage := loadTodayUsageAmount(ctx, tx, payer, userGroupID, now)
 		if errUsage != nil {
 			return false, errUsage
 		}
@@ -251,54 +297,14 @@ func deductBillBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGrou
 	if remaining > billQuotaEpsilon {
 		return false, errors.New("bill quota not enough after lock")
 	}
-	if errRefresh := refreshBillUserGroupIDs(ctx, tx, userID); errRefresh != nil {
+	if errRefresh := billing.RefreshBillUserGroupIDs(ctx, tx, payer); errRefresh != nil {
 		return false, errRefresh
 	}
 	return true, nil
 }
 
-func refreshBillUserGroupIDs(ctx context.Context, tx *gorm.DB, userID uint64) error {
-	if tx == nil {
-		return errors.New("nil tx")
-	}
-	if userID == 0 {
-		return errors.New("empty user id")
-	}
-	now := time.Now().UTC()
-	var bills []models.Bill
-	if errFind := tx.WithContext(ctx).
-		Model(&models.Bill{}).
-		Select("user_group_id").
-		Where("user_id = ? AND is_enabled = ? AND status = ? AND left_quota > 0", userID, true, models.BillStatusPaid).
-		Where("period_start <= ? AND period_end >= ?", now, now).
-		Find(&bills).Error; errFind != nil {
-		return errFind
-	}
-
-	seen := make(map[uint64]struct{})
-	merged := make(models.UserGroupIDs, 0)
-	for _, bill := range bills {
-		for _, gid := range bill.UserGroupID.Clean() {
-			if gid == nil || *gid == 0 {
-				continue
-			}
-			if _, ok := seen[*gid]; ok {
-				continue
-			}
-			seen[*gid] = struct{}{}
-			idCopy := *gid
-			merged = append(merged, &idCopy)
-		}
-	}
-
-	return tx.WithContext(ctx).
-		Model(&models.User{}).
-		Where("id = ?", userID).
-		Update("bill_user_group_id", merged.Clean()).Error
-}
-
-// deductPrepaidBalance deducts usage from prepaid cards in priority order.
-func deductPrepaidBalance(ctx context.Context, tx *gorm.DB, userID uint64, userGroupID *uint64, amount float64) error {
+// deductPrepaidBalance deducts usage from the payer's prepaid cards in priority order.
+func deductPrepaidBalance(ctx context.Context, tx *gorm.DB, payer billing.Payer, userGroupID *uint64, amount float64) error {
 	if tx == nil {
 		return errors.New("nil tx")
 	}
@@ -307,12 +313,12 @@ func deductPrepaidBalance(ctx context.Context, tx *gorm.DB, userID uint64, userG
 	}
 	now := time.Now().UTC()
 	var cards []models.PrepaidCard
-	q := tx.WithContext(ctx).
+	q := payer.ScopePrepaidCards(tx.WithContext(ctx).
 		Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("redeemed_user_id = ? AND is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", userID, true).
+		Where("is_enabled = ? AND balance > 0 AND redeemed_at IS NOT NULL", true).
 		Where("(expires_at IS NULL OR expires_at >= ?)", now).
 		Order("expires_at ASC NULLS LAST, redeemed_at ASC NULLS LAST, id ASC").
-		Model(&models.PrepaidCard{})
+		Model(&models.PrepaidCard{}))
 	if userGroupID != nil && *userGroupID != 0 {
 		q = q.Where("user_group_id = ?", *userGroupID)
 	}
@@ -345,8 +351,8 @@ func deductPrepaidBalance(ctx context.Context, tx *gorm.DB, userID uint64, userG
 	return nil
 }
 
-// loadTodayUsageAmount sums today's usage cost in local time.
-func loadTodayUsageAmount(ctx context.Context, db *gorm.DB, userID uint64, userGroupID *uint64, now time.Time) (float64, error) {
+// loadTodayUsageAmount sums today's usage cost charged to the payer in local time.
+func loadTodayUsageAmount(ctx context.Context, db *gorm.DB, payer billing.Payer, userGroupID *uint64, now time.Time) (float64, error) {
 	if db == nil {
 		return 0, errors.New("nil db")
 	}
@@ -354,10 +360,10 @@ func loadTodayUsageAmount(ctx context.Context, db *gorm.DB, userID uint64, userG
 	localNow := now.In(loc)
 	todayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, loc)
 	var costMicros int64
-	q := db.WithContext(ctx).
+	q := payer.ScopeUsage(db.WithContext(ctx).
 		Model(&models.Usage{}).
-		Where("user_id = ? AND requested_at >= ?", userID, todayStart).
-		Select("COALESCE(SUM(cost_micros), 0)")
+		Where("requested_at >= ?", todayStart).
+		Select("COALESCE(SUM(cost_micros), 0)"))
 	if userGroupID != nil && *userGroupID != 0 {
 		q = q.Where("user_group_id = ?", *userGroupID)
 	}
@@ -444,6 +450,7 @@ func calculateCost(ctx context.Context, db *gorm.DB, apiKeyID, userID, authID, b
 		if rule == nil {
 			return 0
 		}
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("cpab.billing.rule_id", int64(rule.ID)))
 
 		switch rule.BillingType {
 		case models.BillingTypePerRequest:
@@ -21,9 +21,12 @@ import (
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	internalaccess "github.com/router-for-me/CLIProxyAPIBusiness/internal/access"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelcatalog"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/modelmapping"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/models"
 	"github.com/router-for-me/CLIProxyAPIBusiness/internal/providerkeys"
+	"github.com/router-for-me/CLIProxyAPIBusiness/internal/quota"
 	internalsettings "github.com/router-for-me/CLIProxyAPIBusiness/internal/settings"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
@@ -117,6 +120,18 @@ type dbWatcher struct {
 	mappingLatestID  uint64
 	mappingHasLatest bool
 
+	// model catalogue snapshot
+	catalogLatestAt time.Time
+	catalogLatestID uint64
+	catalogCount    int64
+	catalogLoaded   bool
+
+	// quota window snapshot
+	quotaLatestAt time.Time
+	quotaLatestID uint64
+	quotaCount    int64
+	quotaLoaded   bool
+
 	// provider key snapshot (stored in ProviderAPIKey + ModelMapping tables)
 	providerLatestAt  time.Time
 	providerLatestID  uint64
@@ -258,11 +273,13 @@ func (w *dbWatcher) DispatchRuntimeAuthUpdate(_ reflect.Value) bool {
 
 // run executes the periodic polling loop until the context is canceled.
 func (w *dbWatcher) run(ctx context.Context) {
-	w.pollConfig(ctx)
-	w.pollProviderKeys(ctx, true)
-	w.pollAuth(ctx, true)
-	w.pollSettings(ctx, true)
-	w.pollPayloadRules(ctx, true)
+	timePoll("config", func() { w.pollConfig(ctx) })
+	timePoll("provider_keys", func() { w.pollProviderKeys(ctx, true) })
+	timePoll("auth", func() { w.pollAuth(ctx, true) })
+	timePoll("settings", func() { w.pollSettings(ctx, true) })
+	timePoll("payload_rules", func() { w.pollPayloadRules(ctx, true) })
+	timePoll("model_catalog", func() { w.pollModelCatalog(ctx, true) })
+	timePoll("quota_windows", func() { w.pollQuotaWindows(ctx, true) })
 
 	ticker := time.NewTicker(w.pollInterval)
 	defer ticker.Stop()
@@ -272,15 +289,24 @@ func (w *dbWatcher) run(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			w.pollConfig(ctx)
-			w.pollProviderKeys(ctx, false)
-			w.pollAuth(ctx, w.consumeForceAuth())
-			w.pollSettings(ctx, false)
-			w.pollPayloadRules(ctx, false)
+			timePoll("config", func() { w.pollConfig(ctx) })
+			timePoll("provider_keys", func() { w.pollProviderKeys(ctx, false) })
+			timePoll("auth", func() { w.pollAuth(ctx, w.consumeForceAuth()) })
+			timePoll("settings", func() { w.pollSettings(ctx, false) })
+			timePoll("payload_rules", func() { w.pollPayloadRules(ctx, false) })
+			timePoll("model_catalog", func() { w.pollModelCatalog(ctx, false) })
+			timePoll("quota_windows", func() { w.pollQuotaWindows(ctx, false) })
 		}
 	}
 }
 
+// timePoll runs a poll step and records its duration under the given source label.
+func timePoll(source string, poll func()) {
+	started := time.Now()
+	poll()
+	metrics.ObserveWatcherPoll(source, time.Since(started))
+}
+
 // pollConfig reloads the config file when its contents change.
 func (w *dbWatcher) pollConfig(ctx context.Context) {
 	if w == nil || strings.TrimSpace(w.configPath) == "" {
@@ -726,6 +752,108 @@ func (w *dbWatcher) pollSettings(ctx context.Context, force bool) {
 	w.hasSettingsLatest = true
 }
 
+// pollModelCatalog reloads model catalogue entries when the newest row or the row count changes.
+func (w *dbWatcher) pollModelCatalog(ctx context.Context, force bool) {
+	if w == nil || w.db == nil {
+		return
+	}
+	qctx, cancel := context.WithTimeout(ctx, defaultQueryTimeout)
+	defer cancel()
+
+	// latestRow captures the newest catalogue entry for change detection.
+	type latestRow struct {
+		ID        uint64    `gorm:"column:id"`         // Latest catalogue entry ID.
+		UpdatedAt time.Time `gorm:"column:updated_at"` // Latest catalogue entry update time.
+	}
+	var latest latestRow
+	errLatest := w.db.WithContext(qctx).
+		Model(&models.ModelCatalogEntry{}).
+		Select("id", "updated_at").
+		Order("updated_at DESC, id DESC").
+		Limit(1).
+		Take(&latest).Error
+	if errLatest != nil && !errors.Is(errLatest, gorm.ErrRecordNotFound) {
+		if !errors.Is(errLatest, context.Canceled) {
+			log.WithError(errLatest).Warn("db watcher: query model catalog latest row failed")
+		}
+		return
+	}
+	var count int64
+	if errCount := w.db.WithContext(qctx).Model(&models.ModelCatalogEntry{}).Count(&count).Error; errCount != nil {
+		if !errors.Is(errCount, context.Canceled) {
+			log.WithError(errCount).Warn("db watcher: query model catalog count failed")
+		}
+		return
+	}
+
+	latestAt := latest.UpdatedAt.UTC()
+	if !force && w.catalogLoaded && latestAt.Equal(w.catalogLatestAt) && latest.ID == w.catalogLatestID && count == w.catalogCount {
+		return
+	}
+
+	if errReload := modelcatalog.Reload(qctx, w.db); errReload != nil {
+		if !errors.Is(errReload, context.Canceled) {
+			log.WithError(errReload).Warn("db watcher: query model catalog failed")
+		}
+		return
+	}
+	w.catalogLatestAt = latestAt
+	w.catalogLatestID = latest.ID
+	w.catalogCount = count
+	w.catalogLoaded = true
+}
+
+// pollQuotaWindows reloads polled quota windows when the newest row or the row count changes.
+func (w *dbWatcher) pollQuotaWindows(ctx context.Context, force bool) {
+	if w == nil || w.db == nil {
+		return
+	}
+	qctx, cancel := context.WithTimeout(ctx, defaultQueryTimeout)
+	defer cancel()
+
+	// latestRow captures the newest quota row for change detection.
+	type latestRow struct {
+		ID        uint64    `gorm:"column:id"`         // Latest quota row ID.
+		UpdatedAt time.Time `gorm:"column:updated_at"` // Latest quota row update time.
+	}
+	var latest latestRow
+	errLatest := w.db.WithContext(qctx).
+		Model(&models.Quota{}).
+		Select("id", "updated_at").
+		Order("updated_at DESC, id DESC").
+		Limit(1).
+		Take(&latest).Error
+	if errLatest != nil && !errors.Is(errLatest, gorm.ErrRecordNotFound) {
+		if !errors.Is(errLatest, context.Canceled) {
+			log.WithError(errLatest).Warn("db watcher: query quota latest row failed")
+		}
+		return
+	}
+	var count int64
+	if errCount := w.db.WithContext(qctx).Model(&models.Quota{}).Count(&count).Error; errCount != nil {
+		if !errors.Is(errCount, context.Canceled) {
+			log.WithError(errCount).Warn("db watcher: query quota count failed")
+		}
+		return
+	}
+
+	latestAt := latest.UpdatedAt.UTC()
+	if !force && w.quotaLoaded && latestAt.Equal(w.quotaLatestAt) && latest.ID == w.quotaLatestID && count == w.quotaCount {
+		return
+	}
+
+	if errReload := quota.ReloadWindows(qctx, w.db); errReload != nil {
+		if !errors.Is(errReload, context.Canceled) {
+			log.WithError(errReload).Warn("db watcher: query quota windows failed")
+		}
+		return
+	}
+	w.quotaLatestAt = latestAt
+	w.quotaLatestID = latest.ID
+	w.quotaCount = count
+	w.quotaLoaded = true
+}
+
 // pollPayloadRules reloads payload rules when changes are detected.
 func (w *dbWatcher) pollPayloadRules(ctx context.Context, force bool) {
 	if w == nil || w.db == nil {
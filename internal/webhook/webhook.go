@@ -0,0 +1,38 @@
+// Package webhook delivers JSON notifications to operator-configured HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Post delivers payload as a JSON POST to targetURL and fails on non-2xx responses.
+func Post(ctx context.Context, client *http.Client, targetURL string, payload any) error {
+	body, errMarshal := json.Marshal(payload)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if errReq != nil {
+		return fmt.Errorf("webhook request: %w", errReq)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, errDo := client.Do(req)
+	if errDo != nil {
+		return fmt.Errorf("webhook: %w", errDo)
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("webhook: close response body error: %v", errClose)
+		}
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook status=%d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPost(t *testing.T) {
+	var got map[string]string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	if errPost := Post(context.Background(), server.Client(), server.URL, map[string]string{"event": "test"}); errPost != nil {
+		t.Fatalf("post: %v", errPost)
+	}
+	if got["event"] != "test" {
+		t.Fatalf("unexpected payload %v", got)
+	}
+
+	status = http.StatusBadGateway
+	errPost := Post(context.Background(), server.Client(), server.URL, map[string]string{})
+	if errPost == nil || !strings.Contains(errPost.Error(), "status=502") {
+		t.Fatalf("expected status error, got %v", errPost)
+	}
+}